@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,21 +13,58 @@ type AdoptOptions struct {
 	SourceDir string   // base directory for dotfiles (e.g., ~/git/dotfiles)
 	TargetDir string   // where files currently are (default: ~)
 	Paths     []string // files to adopt (e.g., ["~/.bashrc", "~/.vimrc"])
-	DryRun    bool     // preview mode
+	Prefer    string   // conflict resolution when the repository copy differs: "", "repo", or "local"
+	LocalOnly []string // target paths that must never be adopted (from .lnklocal)
+	Sensitive []string // files that must not be stored in plaintext (from .lnksensitive)
+	// IgnorePatterns are skipped when walking an adopted directory (files named
+	// explicitly are always adopted); nil for --no-ignore
+	IgnorePatterns []string
+	Resume         bool // continue an interrupted adoption instead of refusing to start
+	DryRun         bool // preview mode
+}
+
+// Conflict preferences for adopting a file whose destination already exists
+const (
+	PreferRepo  = "repo"  // keep the repository copy and discard the local file
+	PreferLocal = "local" // overwrite the repository copy with the local file
+)
+
+// adoptResolution describes how an adoption treats the destination in the source directory
+type adoptResolution int
+
+const (
+	adoptMove      adoptResolution = iota // destination is free; move the local file into it
+	adoptIdentical                        // destination has identical content; replace local file with symlink
+	adoptKeepRepo                         // destination differs; discard local file in favor of it
+	adoptKeepLocal                        // destination differs; overwrite it with the local file
+)
+
+// note describes how the destination was handled, or "" when the file was moved into it
+func (r adoptResolution) note() string {
+	switch r {
+	case adoptIdentical:
+		return "identical to repository copy"
+	case adoptKeepRepo:
+		return "kept repository copy"
+	case adoptKeepLocal:
+		return "replaced repository copy"
+	default:
+		return ""
+	}
 }
 
 // validateAdoptSource checks if a path is already adopted (a symlink pointing into sourceDir).
 // Returns ErrAlreadyAdopted if so, nil otherwise. The caller is responsible for
 // checking existence and handling non-adopted symlinks separately.
 func validateAdoptSource(absPath, absSourceDir string) error {
-	info, err := os.Lstat(absPath)
+	info, err := fsys.Lstat(absPath)
 	if err != nil {
 		return nil
 	}
 	if info.Mode()&os.ModeSymlink == 0 {
 		return nil
 	}
-	target, err := os.Readlink(absPath)
+	target, err := fsys.Readlink(absPath)
 	if err != nil {
 		return nil
 	}
@@ -45,18 +83,33 @@ func validateAdoptSource(absPath, absSourceDir string) error {
 
 // plannedAdoption represents a file to be adopted, validated in Phase 1.
 type plannedAdoption struct {
-	absPath  string // original location (becomes symlink)
-	destPath string // destination in source dir (real file after move)
+	absPath    string          // original location (becomes symlink)
+	destPath   string          // destination in source dir (real file after move)
+	resolution adoptResolution // how an existing destination is handled
 }
 
-// Adopt adopts files into the source directory using two-phase transactional execution.
-func Adopt(opts AdoptOptions) error {
+// Adopt adopts files into the source directory using two-phase transactional
+// execution, and returns what it decided for each file. It prints each file
+// as it goes; PrintAdoptSummary prints the summary.
+func Adopt(opts AdoptOptions) (*AdoptResult, error) {
+	r, done := collect()
+	defer done()
+	err := adopt(opts)
+	return &r.adopt, err
+}
+
+// adopt adopts files for Adopt, recording each decision
+func adopt(opts AdoptOptions) error {
 	PrintCommandHeader("Adopting Files")
 
 	if len(opts.Paths) == 0 {
 		return NewValidationErrorWithHint("paths", "", "at least one file path is required",
 			"Specify which files to adopt, e.g.: lnk adopt <source-dir> ~/.bashrc ~/.vimrc")
 	}
+	if opts.Prefer != "" && opts.Prefer != PreferRepo && opts.Prefer != PreferLocal {
+		return NewValidationErrorWithHint("prefer", opts.Prefer, "must be 'repo' or 'local'",
+			"Use --prefer repo to keep repository copies or --prefer local to overwrite them")
+	}
 
 	paths, err := ResolvePaths(opts.SourceDir, opts.TargetDir)
 	if err != nil {
@@ -66,9 +119,20 @@ func Adopt(opts AdoptOptions) error {
 	PrintVerbose("Source directory: %s", absSourceDir)
 	PrintVerbose("Target directory: %s", absTargetDir)
 
+	// An interrupted adoption is picked up before planning, so the file it was
+	// moving is back in place and files it finished are skipped
+	var journal *Journal
+	if opts.Resume && !opts.DryRun {
+		if journal, err = resumeJournal("adopt", absSourceDir, absTargetDir); err != nil {
+			return err
+		}
+	}
+
 	// Phase 1: Collect and Validate
 	var planned []plannedAdoption
 	seen := make(map[string]bool)
+	local := newLocalOnlyMatcher(absTargetDir, opts.LocalOnly)
+	pm := NewPatternMatcher(opts.IgnorePatterns)
 
 	for _, path := range opts.Paths {
 		absPath, err := ExpandPath(path)
@@ -84,42 +148,79 @@ func Adopt(opts AdoptOptions) error {
 				"Check that the path is valid")
 		}
 
-		info, err := os.Lstat(absPath)
+		info, err := fsys.Lstat(absPath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				return NewPathErrorWithHint("adopt", absPath, err,
-					"Check that the file path is correct and the file exists")
+					pathHint(absPath, siblingPaths(absPath), "Check that the file path is correct and the file exists"))
 			}
 			return NewPathError("adopt", absPath, err)
 		}
 
+		if err := checkProtected("adopt", absPath); err != nil {
+			return err
+		}
+		if pattern, ok := local.matches(absPath); ok {
+			return NewPathErrorWithHint("adopt", absPath, ErrLocalOnly,
+				fmt.Sprintf("%s matches %q in %s; remove that line to manage it with lnk", ContractPath(absPath), pattern, LocalOnlyFileName))
+		}
+
+		if opts.Resume && errors.Is(validateAdoptSource(absPath, absSourceDir), ErrAlreadyAdopted) {
+			PrintSkip("Already adopted: %s", ContractPath(absPath))
+			recordAdopted(absPath, "", ResultSkipped, "already adopted")
+			continue
+		}
+
 		if info.IsDir() && info.Mode()&os.ModeSymlink == 0 {
 			// Walk directory and collect regular files
 			var files []string
-			walkErr := filepath.WalkDir(absPath, func(p string, d os.DirEntry, err error) error {
+			var ignored int
+			walkErr := walkDir(absPath, func(p string, d os.DirEntry, err error) error {
 				if err != nil {
 					return err
 				}
-				if d.Type().IsRegular() {
-					files = append(files, p)
+				if !d.Type().IsRegular() {
+					return nil
+				}
+				if _, ok := local.matches(p); ok {
+					PrintSkip("Local-only: %s", ContractPath(p))
+					recordAdopted(p, "", ResultSkipped, "local-only")
+					return nil
+				}
+				// Match as the file will be named in the source directory
+				if rel, err := filepath.Rel(absTargetDir, p); err == nil {
+					if pattern, ok := pm.MatchingPattern(rel); ok {
+						PrintSkip("Ignored: %s (matches %q)", ContractPath(p), pattern)
+						recordAdopted(p, "", ResultSkipped, fmt.Sprintf("matches ignore pattern %q", pattern))
+						ignored++
+						return nil
+					}
 				}
+				files = append(files, p)
 				return nil
 			})
 			if walkErr != nil {
 				return NewPathError("adopt", absPath, walkErr)
 			}
+			SummaryCount("ignored", ignored)
+			if len(files) == 0 && opts.Resume {
+				PrintVerbose("Nothing left to adopt in %s", ContractPath(absPath))
+				continue
+			}
 			if len(files) == 0 {
-				return WithHint(
-					fmt.Errorf("no files to adopt in %s", ContractPath(absPath)),
-					"Check that the directory contains regular files")
+				hint := "Check that the directory contains regular files"
+				if ignored > 0 {
+					hint = fmt.Sprintf("All %d file(s) match ignore patterns; use --no-ignore to adopt them anyway", ignored)
+				}
+				return WithHint(fmt.Errorf("no files to adopt in %s", ContractPath(absPath)), hint)
 			}
 			for _, f := range files {
-				if err := collectAdoption(f, absSourceDir, absTargetDir, nil, seen, &planned); err != nil {
+				if err := collectAdoption(f, absSourceDir, absTargetDir, opts.Prefer, opts.Sensitive, nil, seen, &planned); err != nil {
 					return err
 				}
 			}
 		} else {
-			if err := collectAdoption(absPath, absSourceDir, absTargetDir, info, seen, &planned); err != nil {
+			if err := collectAdoption(absPath, absSourceDir, absTargetDir, opts.Prefer, opts.Sensitive, info, seen, &planned); err != nil {
 				return err
 			}
 		}
@@ -127,55 +228,81 @@ func Adopt(opts AdoptOptions) error {
 
 	// Dry-run
 	if opts.DryRun {
-		fmt.Println()
+		fmt.Fprintln(stdout())
 		PrintDryRun("Would adopt %d file(s):", len(planned))
+		lines := newPathLines(len(planned), "Would adopt", PrintDryRun)
 		for _, p := range planned {
-			PrintDryRun("Would adopt: %s", ContractPath(p.absPath))
-			PrintDetail("Move to: %s", ContractPath(p.destPath))
+			lines.Add(p.absPath, "Would adopt: %s", ContractPath(p.absPath))
+			recordAdopted(p.absPath, p.destPath, ResultPlanned, p.resolution.note())
+			if lines.Collapsed() {
+				continue
+			}
+			switch p.resolution {
+			case adoptIdentical:
+				PrintDetail("Remove local copy (identical to %s)", ContractPath(p.destPath))
+			case adoptKeepRepo:
+				PrintDetail("Discard local copy in favor of: %s", ContractPath(p.destPath))
+			case adoptKeepLocal:
+				PrintDetail("Overwrite: %s", ContractPath(p.destPath))
+			default:
+				PrintDetail("Move to: %s", ContractPath(p.destPath))
+			}
 			PrintDetail("Create symlink: %s -> %s", ContractPath(p.absPath), ContractPath(p.destPath))
 		}
-		fmt.Println()
+		lines.Flush()
+		fmt.Fprintln(stdout())
 		PrintDryRunSummary()
 		return nil
 	}
 
-	// Phase 2: Execute with rollback
-	type completedAdoption struct {
-		absPath   string
-		destPath  string
-		moved     bool
-		symlinked bool
+	// Phase 2: Execute, recording each step in the journal so a failure, or an
+	// interruption 'lnk undo' picks up later, can be rolled back
+	if journal == nil {
+		if journal, err = beginJournal("adopt", absSourceDir, absTargetDir); err != nil {
+			return err
+		}
+	}
+
+	// Report progress of files copied across devices, which can take a while
+	var moveBytes, copied int64
+	for _, p := range planned {
+		if info, err := fsys.Lstat(p.absPath); err == nil && (p.resolution == adoptMove || p.resolution == adoptKeepLocal) {
+			moveBytes += info.Size()
+		}
+	}
+	progress := NewProgressIndicator("Copying across devices")
+	progress.SetByteTotal(moveBytes)
+	copyProgress = func(n int64) {
+		copied += n
+		progress.Update(int(copied))
 	}
-	var completed []completedAdoption
-	var createdDirs []string
+	defer func() {
+		copyProgress = nil
+		if copied > 0 {
+			progress.Stop()
+		}
+	}()
 
 	rollback := func(originalErr error) error {
-		var rollbackErrors []string
-		for i := len(completed) - 1; i >= 0; i-- {
-			c := completed[i]
-			if c.symlinked {
-				if err := os.Remove(c.absPath); err != nil {
-					rollbackErrors = append(rollbackErrors, fmt.Sprintf("remove symlink %s: %v", ContractPath(c.absPath), err))
-				}
+		if errs := journal.rollback(true); len(errs) > 0 {
+			var rollbackErrors []string
+			for _, err := range errs {
+				rollbackErrors = append(rollbackErrors, err.Error())
 			}
-			if c.moved {
-				if err := MoveFile(c.destPath, c.absPath); err != nil {
-					rollbackErrors = append(rollbackErrors, fmt.Sprintf("restore %s: %v", ContractPath(c.absPath), err))
-				}
-			}
-		}
-		if len(createdDirs) > 0 {
-			CleanEmptyDirs(createdDirs, absSourceDir)
+			return WithHint(
+				fmt.Errorf("adopt failed: %v; rollback failed: %s", originalErr, strings.Join(rollbackErrors, "; ")),
+				fmt.Sprintf("Fix the problem and run 'lnk undo %s' to finish restoring the files", ContractPath(absSourceDir)))
 		}
-		if len(rollbackErrors) > 0 {
-			return fmt.Errorf("adopt failed: %v; rollback failed: %s", originalErr, strings.Join(rollbackErrors, "; "))
+		if err := journal.finish(); err != nil {
+			PrintWarningWithHint(err)
 		}
 		return originalErr
 	}
 
+	lines := newPathLines(len(planned), "Adopted", PrintSuccess)
 	for _, p := range planned {
 		// Verify source still exists
-		if _, err := os.Lstat(p.absPath); err != nil {
+		if _, err := fsys.Lstat(p.absPath); err != nil {
 			return rollback(WithHint(
 				NewPathError("adopt", p.absPath, err),
 				"Check that the file path is correct and the file exists"))
@@ -183,44 +310,92 @@ func Adopt(opts AdoptOptions) error {
 
 		// Create parent directory, tracking if newly created
 		destDir := filepath.Dir(p.destPath)
-		_, statErr := os.Stat(destDir)
+		_, statErr := fsys.Stat(destDir)
 		dirExisted := statErr == nil
 
-		if err := os.MkdirAll(destDir, 0755); err != nil {
+		if err := fsys.MkdirAll(destDir, 0755); err != nil {
 			return rollback(NewPathError("adopt", destDir, fmt.Errorf("failed to create directory: %w", err)))
 		}
 		if !dirExisted {
-			createdDirs = append(createdDirs, destDir)
+			if err := journal.record(journalMkdir, destDir, ""); err != nil {
+				return rollback(err)
+			}
 		}
 
-		c := completedAdoption{absPath: p.absPath, destPath: p.destPath}
+		// Set aside whichever file is being replaced so rollback can restore it
+		var stashedFrom string
+		switch p.resolution {
+		case adoptIdentical, adoptKeepRepo:
+			stashedFrom = p.absPath
+		case adoptKeepLocal:
+			stashedFrom = p.destPath
+		}
+		if stashedFrom != "" {
+			stashPath, err := stashFile(stashedFrom)
+			if err != nil {
+				return rollback(NewPathError("adopt", stashedFrom, err))
+			}
+			if err := journal.record(journalStash, stashPath, stashedFrom); err != nil {
+				return rollback(err)
+			}
+		}
 
 		// Move file
-		if err := MoveFile(p.absPath, p.destPath); err != nil {
-			completed = append(completed, c)
-			return rollback(err)
+		if p.resolution == adoptMove || p.resolution == adoptKeepLocal {
+			if err := journal.record(journalMove, p.destPath, p.absPath); err != nil {
+				return rollback(err)
+			}
+			if err := MoveFile(p.absPath, p.destPath); err != nil {
+				return rollback(err)
+			}
 		}
-		c.moved = true
 
 		// Create symlink
+		if err := journal.record(journalSymlink, p.absPath, ""); err != nil {
+			return rollback(err)
+		}
 		if err := CreateSymlink(p.destPath, p.absPath); err != nil {
-			completed = append(completed, c)
 			return rollback(err)
 		}
-		c.symlinked = true
-		completed = append(completed, c)
 
-		PrintSuccess("Adopted: %s", ContractPath(p.absPath))
+		if note := p.resolution.note(); note != "" {
+			lines.Add(p.absPath, "Adopted: %s (%s)", ContractPath(p.absPath), note)
+		} else {
+			lines.Add(p.absPath, "Adopted: %s", ContractPath(p.absPath))
+		}
+	}
+	lines.Flush()
+
+	// Every adoption succeeded, including any from an interrupted run being
+	// resumed: end the transaction, then discard replaced files, which an
+	// interrupted rollback would still need
+	if err := journal.finish(); err != nil {
+		PrintWarningWithHint(err)
+	}
+	var adoptedLinks []string
+	for _, step := range journal.Steps {
+		switch step.Op {
+		case journalStash:
+			if err := fsys.Remove(step.Path); err != nil {
+				PrintVerbose("Failed to remove %s: %v", ContractPath(step.Path), err)
+			}
+		case journalSymlink:
+			adoptedLinks = append(adoptedLinks, step.Path)
+		}
+	}
+	recordCreatedLinks(absTargetDir, absSourceDir, adoptedLinks)
+	hardenPrivatePaths(absTargetDir, adoptedLinks)
+	for _, p := range planned {
+		recordAdopted(p.absPath, p.destPath, ResultAdopted, p.resolution.note())
 	}
 
-	PrintSummary("Adopted %d file(s) successfully", len(planned))
-	PrintNextStep("status", absSourceDir, "view adopted files")
+	SummaryCount("adopted", len(planned))
 	return nil
 }
 
 // collectAdoption validates a single file for adoption and adds it to the planned list.
 // Returns an error immediately if validation fails (fail-fast).
-func collectAdoption(absPath, absSourceDir, absTargetDir string, info os.FileInfo, seen map[string]bool, planned *[]plannedAdoption) error {
+func collectAdoption(absPath, absSourceDir, absTargetDir, prefer string, sensitive []string, info os.FileInfo, seen map[string]bool, planned *[]plannedAdoption) error {
 	// Deduplicate by absolute path
 	if seen[absPath] {
 		return nil
@@ -229,7 +404,7 @@ func collectAdoption(absPath, absSourceDir, absTargetDir string, info os.FileInf
 	// Get file info if not provided (files from directory walk)
 	if info == nil {
 		var err error
-		info, err = os.Lstat(absPath)
+		info, err = fsys.Lstat(absPath)
 		if err != nil {
 			return NewPathError("adopt", absPath, err)
 		}
@@ -255,14 +430,23 @@ func collectAdoption(absPath, absSourceDir, absTargetDir string, info os.FileInf
 			"Only files within the target directory can be adopted")
 	}
 
+	// Refuse files that must not be stored in the repository in plaintext
+	if pattern, ok := sensitivePattern(sensitive, relPath); ok {
+		return NewPathErrorWithHint("adopt", absPath, ErrSensitive,
+			fmt.Sprintf("%s matches %q in %s; encrypt it before adding it to the repository (e.g. with age or git-crypt)",
+				ContractPath(absPath), pattern, SensitiveFileName))
+	}
+
 	// Compute destination
 	destPath := filepath.Join(absSourceDir, relPath)
 
-	// Check destination doesn't already exist
-	if _, err := os.Stat(destPath); err == nil {
-		return WithHint(
-			fmt.Errorf("destination %s already exists", ContractPath(destPath)),
-			"Remove the existing file first or choose a different file")
+	// Resolve an existing destination by comparing contents
+	resolution := adoptMove
+	if destInfo, err := fsys.Stat(destPath); err == nil {
+		resolution, err = resolveAdoptConflict(absPath, destPath, destInfo, prefer)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Validate symlink creation (source=destPath, target=absPath per spec)
@@ -271,6 +455,60 @@ func collectAdoption(absPath, absSourceDir, absTargetDir string, info os.FileInf
 	}
 
 	seen[absPath] = true
-	*planned = append(*planned, plannedAdoption{absPath: absPath, destPath: destPath})
+	*planned = append(*planned, plannedAdoption{absPath: absPath, destPath: destPath, resolution: resolution})
 	return nil
 }
+
+// resolveAdoptConflict decides what to do when destPath already exists in the source directory.
+// Identical files are replaced silently; differing files use prefer, or ask the user when
+// stdin is a terminal. Otherwise the conflict is returned as an error.
+func resolveAdoptConflict(absPath, destPath string, destInfo os.FileInfo, prefer string) (adoptResolution, error) {
+	if !destInfo.Mode().IsRegular() {
+		return adoptMove, WithHint(
+			fmt.Errorf("destination %s already exists", ContractPath(destPath)),
+			"Remove the existing file first or choose a different file")
+	}
+
+	same, err := filesEqual(absPath, destPath)
+	if err != nil {
+		return adoptMove, NewPathErrorWithHint("compare", destPath, err,
+			"Check file permissions")
+	}
+	if same {
+		PrintVerbose("%s is identical to %s", ContractPath(absPath), ContractPath(destPath))
+		return adoptIdentical, nil
+	}
+
+	switch prefer {
+	case PreferRepo:
+		return adoptKeepRepo, nil
+	case PreferLocal:
+		return adoptKeepLocal, nil
+	}
+
+	conflictErr := WithHint(
+		fmt.Errorf("destination %s already exists with different content", ContractPath(destPath)),
+		"Use --prefer repo to keep the repository copy or --prefer local to overwrite it")
+	if !canPrompt() {
+		recordAdopted(absPath, destPath, ResultConflict, "differs from the repository copy; set Prefer to resolve it")
+		return adoptMove, conflictErr
+	}
+
+	PrintWarning("%s differs from %s", ContractPath(absPath), ContractPath(destPath))
+	for {
+		choice, err := readChoice("[d]iff, keep [l]ocal, keep [r]epo, or [a]bort?")
+		if err != nil {
+			return adoptMove, conflictErr
+		}
+		switch choice {
+		case "d", "diff":
+			printFileDiff(destPath, absPath)
+		case "l", "local":
+			return adoptKeepLocal, nil
+		case "r", "repo":
+			return adoptKeepRepo, nil
+		case "a", "abort", "q":
+			return adoptMove, conflictErr
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"bufio"
 	"errors"
 	"os"
 	"path/filepath"
@@ -32,7 +33,7 @@ func TestAdoptFailFastValidation(t *testing.T) {
 		Paths:     []string{nonexistent, validFile},
 		DryRun:    false,
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err == nil {
 		t.Fatal("expected error for nonexistent path")
 	}
@@ -71,7 +72,7 @@ func TestAdoptFileAlreadyAdopted(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{symlinkPath},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err == nil {
 		t.Fatal("expected error for already-adopted file")
 	}
@@ -100,7 +101,7 @@ func TestAdoptNonAdoptedSymlinkRejected(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{symlinkPath},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err == nil {
 		t.Fatal("expected error for non-adopted symlink")
 	}
@@ -132,7 +133,7 @@ func TestAdoptDestinationAlreadyExists(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{targetFile},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err == nil {
 		t.Fatal("expected error for destination already exists")
 	}
@@ -141,6 +142,191 @@ func TestAdoptDestinationAlreadyExists(t *testing.T) {
 	}
 }
 
+func TestAdoptDestinationIdenticalReplacedWithSymlink(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(targetDir, 0755)
+
+	targetFile := filepath.Join(targetDir, ".bashrc")
+	createTestFile(t, targetFile, "same content")
+	destFile := filepath.Join(sourceDir, ".bashrc")
+	createTestFile(t, destFile, "same content")
+
+	output := CaptureOutput(t, func() {
+		if _, err := Adopt(AdoptOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{targetFile}}); err != nil {
+			t.Fatalf("Adopt failed: %v", err)
+		}
+	})
+
+	assertSymlink(t, targetFile, destFile)
+	content, _ := os.ReadFile(destFile)
+	if string(content) != "same content" {
+		t.Errorf("repository copy changed: %q", content)
+	}
+	ContainsOutput(t, output, "identical to repository copy")
+	assertNoStashFiles(t, targetDir)
+}
+
+func TestAdoptDestinationDiffersWithoutPrefer(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(targetDir, 0755)
+
+	targetFile := filepath.Join(targetDir, ".bashrc")
+	createTestFile(t, targetFile, "local")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "repo")
+
+	origCanPrompt := canPrompt
+	canPrompt = func() bool { return false }
+	t.Cleanup(func() { canPrompt = origCanPrompt })
+
+	_, err := Adopt(AdoptOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{targetFile}})
+	if err == nil {
+		t.Fatal("expected error for differing destination")
+	}
+	if !strings.Contains(err.Error(), "different content") {
+		t.Errorf("expected 'different content' error, got: %v", err)
+	}
+	if hint := GetErrorHint(err); !strings.Contains(hint, "--prefer") {
+		t.Errorf("expected hint mentioning --prefer, got: %q", hint)
+	}
+	if info, _ := os.Lstat(targetFile); info.Mode()&os.ModeSymlink != 0 {
+		t.Error("local file was replaced despite conflict")
+	}
+}
+
+func TestAdoptPreferRepo(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(targetDir, 0755)
+
+	targetFile := filepath.Join(targetDir, ".bashrc")
+	createTestFile(t, targetFile, "local")
+	destFile := filepath.Join(sourceDir, ".bashrc")
+	createTestFile(t, destFile, "repo")
+
+	CaptureOutput(t, func() {
+		if _, err := Adopt(AdoptOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{targetFile}, Prefer: PreferRepo}); err != nil {
+			t.Fatalf("Adopt failed: %v", err)
+		}
+	})
+
+	assertSymlink(t, targetFile, destFile)
+	content, _ := os.ReadFile(destFile)
+	if string(content) != "repo" {
+		t.Errorf("expected repository copy to be kept, got %q", content)
+	}
+	assertNoStashFiles(t, targetDir)
+}
+
+func TestAdoptPreferLocal(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(targetDir, 0755)
+
+	targetFile := filepath.Join(targetDir, ".bashrc")
+	createTestFile(t, targetFile, "local")
+	destFile := filepath.Join(sourceDir, ".bashrc")
+	createTestFile(t, destFile, "repo")
+
+	CaptureOutput(t, func() {
+		if _, err := Adopt(AdoptOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{targetFile}, Prefer: PreferLocal}); err != nil {
+			t.Fatalf("Adopt failed: %v", err)
+		}
+	})
+
+	assertSymlink(t, targetFile, destFile)
+	content, _ := os.ReadFile(destFile)
+	if string(content) != "local" {
+		t.Errorf("expected repository copy to be overwritten, got %q", content)
+	}
+	assertNoStashFiles(t, sourceDir)
+}
+
+func TestAdoptPromptChoosesLocal(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(targetDir, 0755)
+
+	targetFile := filepath.Join(targetDir, ".bashrc")
+	createTestFile(t, targetFile, "local")
+	destFile := filepath.Join(sourceDir, ".bashrc")
+	createTestFile(t, destFile, "repo")
+
+	origCanPrompt, origReader := canPrompt, promptReader
+	canPrompt = func() bool { return true }
+	promptReader = bufio.NewReader(strings.NewReader("x\nl\n"))
+	t.Cleanup(func() { canPrompt, promptReader = origCanPrompt, origReader })
+
+	CaptureOutput(t, func() {
+		if _, err := Adopt(AdoptOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{targetFile}}); err != nil {
+			t.Fatalf("Adopt failed: %v", err)
+		}
+	})
+
+	content, _ := os.ReadFile(destFile)
+	if string(content) != "local" {
+		t.Errorf("expected local choice to overwrite repository copy, got %q", content)
+	}
+}
+
+func TestAdoptPromptAbort(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(targetDir, 0755)
+
+	targetFile := filepath.Join(targetDir, ".bashrc")
+	createTestFile(t, targetFile, "local")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "repo")
+
+	origCanPrompt, origReader := canPrompt, promptReader
+	canPrompt = func() bool { return true }
+	promptReader = bufio.NewReader(strings.NewReader("a\n"))
+	t.Cleanup(func() { canPrompt, promptReader = origCanPrompt, origReader })
+
+	if _, err := Adopt(AdoptOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{targetFile}}); err == nil {
+		t.Fatal("expected error when user aborts")
+	}
+	content, _ := os.ReadFile(targetFile)
+	if string(content) != "local" {
+		t.Errorf("local file changed after abort: %q", content)
+	}
+}
+
+func TestAdoptInvalidPrefer(t *testing.T) {
+	_, err := Adopt(AdoptOptions{SourceDir: "/tmp/dotfiles", TargetDir: "/tmp/target", Paths: []string{"/tmp/target/x"}, Prefer: "mine"})
+	if err == nil {
+		t.Fatal("expected error for invalid prefer value")
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("expected ValidationError, got %T", err)
+	}
+}
+
+// assertNoStashFiles verifies that no adopt stash files were left behind in dir
+func assertNoStashFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".lnk-") {
+			t.Errorf("stash file left behind: %s", e.Name())
+		}
+	}
+}
+
 func TestAdoptPathOutsideTargetDir(t *testing.T) {
 	tempDir := t.TempDir()
 	sourceDir := filepath.Join(tempDir, "dotfiles")
@@ -158,7 +344,7 @@ func TestAdoptPathOutsideTargetDir(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{outsideFile},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err == nil {
 		t.Fatal("expected error for path outside target directory")
 	}
@@ -173,7 +359,7 @@ func TestAdoptNoPaths(t *testing.T) {
 		TargetDir: "/tmp/target",
 		Paths:     []string{},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err == nil {
 		t.Fatal("expected error for empty paths")
 	}
@@ -204,7 +390,7 @@ func TestAdoptDirectoryWalksFiles(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{filepath.Join(targetDir, ".config", "nvim")},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -232,7 +418,7 @@ func TestAdoptEmptyDirectoryReturnsError(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{emptyDir},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err == nil {
 		t.Fatal("expected error for empty directory")
 	}
@@ -261,7 +447,7 @@ func TestAdoptDirectorySkipsSymlinks(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{configDir},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -277,6 +463,76 @@ func TestAdoptDirectorySkipsSymlinks(t *testing.T) {
 	}
 }
 
+func TestAdoptDirectorySkipsIgnored(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(targetDir, 0755)
+
+	configDir := filepath.Join(targetDir, ".config", "nvim")
+	createTestFile(t, filepath.Join(configDir, "init.vim"), "nvim init")
+	createTestFile(t, filepath.Join(configDir, ".DS_Store"), "junk")
+	createTestFile(t, filepath.Join(configDir, ".init.vim.swp"), "swap")
+	// A file named explicitly is adopted even when it matches a pattern
+	explicit := filepath.Join(targetDir, ".notes.tmp")
+	createTestFile(t, explicit, "notes")
+
+	opts := AdoptOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		Paths:          []string{configDir, explicit},
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+	}
+	if _, err := Adopt(opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertSymlink(t, filepath.Join(configDir, "init.vim"), filepath.Join(sourceDir, ".config", "nvim", "init.vim"))
+	assertSymlink(t, explicit, filepath.Join(sourceDir, ".notes.tmp"))
+	for _, name := range []string{".DS_Store", ".init.vim.swp"} {
+		if _, err := os.Lstat(filepath.Join(sourceDir, ".config", "nvim", name)); err == nil {
+			t.Errorf("%s matches an ignore pattern but was adopted", name)
+		}
+		info, err := os.Lstat(filepath.Join(configDir, name))
+		if err != nil || !info.Mode().IsRegular() {
+			t.Errorf("%s should be left in place as a regular file", name)
+		}
+	}
+}
+
+func TestAdoptDirectoryAllIgnored(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(targetDir, 0755)
+
+	junkDir := filepath.Join(targetDir, ".junk")
+	createTestFile(t, filepath.Join(junkDir, ".DS_Store"), "junk")
+
+	opts := AdoptOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		Paths:          []string{junkDir},
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+	}
+	_, err := Adopt(opts)
+	if err == nil || !strings.Contains(err.Error(), "no files to adopt") {
+		t.Fatalf("expected 'no files to adopt' error, got: %v", err)
+	}
+	if hint := GetErrorHint(err); !strings.Contains(hint, "--no-ignore") {
+		t.Errorf("hint should mention --no-ignore, got %q", hint)
+	}
+
+	// Without patterns (--no-ignore) the file is adopted
+	opts.IgnorePatterns = nil
+	if _, err := Adopt(opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSymlink(t, filepath.Join(junkDir, ".DS_Store"), filepath.Join(sourceDir, ".junk", ".DS_Store"))
+}
+
 // ==========================================
 // Deduplication Tests
 // ==========================================
@@ -300,7 +556,7 @@ func TestAdoptDeduplicatesByAbsolutePath(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{configDir, filePath},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -328,7 +584,7 @@ func TestAdoptSingleFile(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{bashrc},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -370,7 +626,7 @@ func TestAdoptMultipleFiles(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     paths,
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -395,7 +651,7 @@ func TestAdoptNestedFile(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{nestedFile},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -437,7 +693,7 @@ func TestAdoptRollbackOnExecutionFailure(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{file1, file2},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err == nil {
 		t.Fatal("expected error when Phase 2 execution fails")
 	}
@@ -488,7 +744,7 @@ func TestAdoptRollbackCleansEmptyDirs(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{file1, file2},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -537,7 +793,7 @@ func TestAdoptDryRunNoChanges(t *testing.T) {
 		Paths:     []string{testFile},
 		DryRun:    true,
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err != nil {
 		t.Fatalf("dry-run failed: %v", err)
 	}
@@ -631,7 +887,8 @@ func TestAdoptDryRunDirectoryShowsPerFileDetail(t *testing.T) {
 // ==========================================
 
 func TestAdoptSummaryOutput(t *testing.T) {
-	// Summary should print "Adopted N file(s) successfully" and next-step hint.
+	// The summary of the result should print "Adopted N file(s) successfully"
+	// and a next-step hint.
 	tempDir := t.TempDir()
 	sourceDir := filepath.Join(tempDir, "dotfiles")
 	targetDir := filepath.Join(tempDir, "target")
@@ -650,7 +907,11 @@ func TestAdoptSummaryOutput(t *testing.T) {
 	}
 
 	output := CaptureOutput(t, func() {
-		Adopt(opts)
+		result, err := Adopt(opts)
+		if err != nil {
+			t.Fatalf("Adopt() error = %v", err)
+		}
+		PrintAdoptSummary(result, sourceDir)
 	})
 
 	if !strings.Contains(output, "Adopted 2 file(s) successfully") {
@@ -689,7 +950,7 @@ func TestAdoptValidateSymlinkCreationArgOrder(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{testFile},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -712,7 +973,7 @@ func TestAdoptSourceDirNotExist(t *testing.T) {
 		TargetDir: targetDir,
 		Paths:     []string{testFile},
 	}
-	err := Adopt(opts)
+	_, err := Adopt(opts)
 	if err == nil {
 		t.Fatal("expected error for nonexistent source directory")
 	}
@@ -0,0 +1,95 @@
+package lnk
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Package types accepted in lnk-package.json
+const (
+	PackageTypeDotfiles = "dotfiles" // link into the target directory as-is (default)
+	PackageTypeFonts    = "fonts"    // link into the user font directory of this platform
+	PackageTypeBin      = "bin"      // link executables into ~/.local/bin
+	PackageTypeAssets   = "assets"   // link into assets_dir under the target directory
+)
+
+// refreshFontCache rebuilds the font cache after fonts change; tests replace it
+var refreshFontCache = fcCache
+
+// fontsDir returns the user font directory for goos, relative to the home directory
+func fontsDir(goos string) string {
+	if goos == "darwin" {
+		return filepath.Join(LibraryDir, "Fonts")
+	}
+	return filepath.Join(".local", "share", "fonts")
+}
+
+// packageTypeDir returns the directory under base that a package links into,
+// according to its type
+func packageTypeDir(pkgDir, base string, info *PackageInfo) (string, error) {
+	infoPath := ContractPath(filepath.Join(pkgDir, PackageInfoFileName))
+	switch info.Type {
+	case "", PackageTypeDotfiles:
+		return base, nil
+	case PackageTypeFonts:
+		if info.Target == TargetWindows {
+			return "", NewValidationErrorWithHint("type", info.Type,
+				fmt.Sprintf("fonts cannot target the Windows home in %s", infoPath),
+				"Install fonts on Windows with the Fonts settings page instead")
+		}
+		return filepath.Join(base, fontsDir(runtime.GOOS)), nil
+	case PackageTypeBin:
+		return filepath.Join(base, binDir), nil
+	case PackageTypeAssets:
+		if info.AssetsDir == "" || !filepath.IsLocal(info.AssetsDir) {
+			return "", NewValidationErrorWithHint("assets_dir", info.AssetsDir,
+				fmt.Sprintf("assets packages need a directory inside the home directory in %s", infoPath),
+				`Set "assets_dir" to a relative path such as ".local/share/backgrounds"`)
+		}
+		return filepath.Join(base, info.AssetsDir), nil
+	default:
+		return "", NewValidationErrorWithHint("type", info.Type,
+			fmt.Sprintf("unknown package type in %s", infoPath),
+			fmt.Sprintf("Valid types: %s, %s, %s, %s", PackageTypeDotfiles, PackageTypeFonts, PackageTypeBin, PackageTypeAssets))
+	}
+}
+
+// refreshFontsIfChanged rebuilds the font cache when any of the changed paths
+// is in the user font directory under targetDir. Failure is only a warning
+// because the links themselves succeeded.
+func refreshFontsIfChanged(targetDir string, changed []string) {
+	dir := filepath.Join(targetDir, fontsDir(runtime.GOOS))
+	for _, path := range changed {
+		if !isWithin(path, dir) {
+			continue
+		}
+		PrintVerbose("Refreshing font cache for %s", ContractPath(dir))
+		if err := refreshFontCache(dir); err != nil {
+			PrintWarningWithHint(WithHint(fmt.Errorf("Failed to refresh font cache: %w", err),
+				"Run 'fc-cache -f' to make new fonts available"))
+		}
+		return
+	}
+}
+
+// fcCache runs fc-cache on dir. macOS picks up fonts in ~/Library/Fonts by
+// itself, and systems without fontconfig have no cache to refresh.
+func fcCache(dir string) error {
+	if runtime.GOOS == "darwin" {
+		return nil
+	}
+	if _, err := exec.LookPath("fc-cache"); err != nil {
+		PrintVerbose("fc-cache not found, skipping font cache refresh")
+		return nil
+	}
+	if err := checkWritable("refresh font cache", dir); err != nil {
+		return err
+	}
+	if out, err := exec.Command("fc-cache", "-f", dir).CombinedOutput(); err != nil {
+		return fmt.Errorf("fc-cache: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package lnk
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeFontCache replaces the font cache refresh and records the directories refreshed
+func fakeFontCache(t *testing.T) *[]string {
+	t.Helper()
+	var refreshed []string
+	old := refreshFontCache
+	refreshFontCache = func(dir string) error {
+		refreshed = append(refreshed, dir)
+		return nil
+	}
+	t.Cleanup(func() { refreshFontCache = old })
+	return &refreshed
+}
+
+func TestCreateLinksFontsPackage(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "fonts", "FiraCode-Regular.ttf"), "font")
+	createTestFile(t, filepath.Join(sourceDir, "fonts", PackageInfoFileName), `{"type": "fonts"}`)
+	refreshed := fakeFontCache(t)
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"shell", "fonts"},
+	}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	fontDir := filepath.Join(targetDir, fontsDir(runtime.GOOS))
+	assertSymlink(t, filepath.Join(fontDir, "FiraCode-Regular.ttf"), filepath.Join(sourceDir, "fonts", "FiraCode-Regular.ttf"))
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	if len(*refreshed) != 1 || (*refreshed)[0] != fontDir {
+		t.Errorf("font cache refreshed for %v, want [%s]", *refreshed, fontDir)
+	}
+
+	// Nothing new to link: no refresh
+	*refreshed = nil
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	if len(*refreshed) != 0 {
+		t.Errorf("font cache refreshed without changes: %v", *refreshed)
+	}
+
+	// Removing the fonts refreshes the cache again
+	opts.Packages = []string{"fonts"}
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertNotExists(t, filepath.Join(fontDir, "FiraCode-Regular.ttf"))
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	if len(*refreshed) != 1 {
+		t.Errorf("font cache refreshed %d time(s) after remove, want 1", len(*refreshed))
+	}
+}
+
+func TestCreateLinksAssetsPackage(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "wallpapers", "mountains.jpg"), "jpg")
+	createTestFile(t, filepath.Join(sourceDir, "wallpapers", PackageInfoFileName),
+		`{"type": "assets", "assets_dir": ".local/share/backgrounds"}`)
+	fakeFontCache(t)
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"wallpapers"},
+	}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	assertSymlink(t, filepath.Join(targetDir, ".local", "share", "backgrounds", "mountains.jpg"),
+		filepath.Join(sourceDir, "wallpapers", "mountains.jpg"))
+}
+
+func TestPackageTypeDirErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    PackageInfo
+		wantErr string
+	}{
+		{"unknown type", PackageInfo{Type: "themes"}, "invalid type 'themes': unknown package type"},
+		{"assets without dir", PackageInfo{Type: PackageTypeAssets}, "assets packages need a directory"},
+		{"assets escaping home", PackageInfo{Type: PackageTypeAssets, AssetsDir: "../elsewhere"}, "invalid assets_dir '../elsewhere'"},
+		{"absolute assets dir", PackageInfo{Type: PackageTypeAssets, AssetsDir: "/usr/share"}, "invalid assets_dir '/usr/share'"},
+		{"windows fonts", PackageInfo{Type: PackageTypeFonts, Target: TargetWindows}, "fonts cannot target the Windows home"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := packageTypeDir("/src/pkg", "/home/me", &tt.info)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("packageTypeDir() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFontsDir(t *testing.T) {
+	if got := fontsDir("darwin"); got != filepath.Join("Library", "Fonts") {
+		t.Errorf("fontsDir(darwin) = %s", got)
+	}
+	if got := fontsDir("linux"); got != filepath.Join(".local", "share", "fonts") {
+		t.Errorf("fontsDir(linux) = %s", got)
+	}
+}
@@ -0,0 +1,54 @@
+package lnk
+
+// create makes most of its changes as one symlink after another into a few
+// directories. On Linux it takes a fast path for them (batch_linux.go): each
+// parent directory is opened once, beneath the target directory, and links
+// and directories are created relative to it with symlinkat and mkdirat. That
+// saves the Lstat before each link and the walk of every path from /, and a
+// directory swapped for a symlink after it was opened cannot redirect the
+// links made in it. Anything the fast path cannot do plainly (an existing
+// target, an error) is handed to the portable path, which reports it as
+// before.
+
+// linkBatching is whether create may use the fast path; LNK_NO_BATCH turns
+// it off
+var linkBatching = true
+
+// SetLinkBatching turns the fast path for creating links on or off
+func SetLinkBatching(on bool) {
+	linkBatching = on
+}
+
+// startLinkBatch returns the fast path for creating links under targetDir,
+// or nil to create them one path at a time: when batching is off, on other
+// platforms, and when fsys is not the real file system (tests replace it, and
+// --read-only wraps it). Close it when done.
+func startLinkBatch(targetDir string) *linkBatch {
+	if !linkBatching || !nativeFS() {
+		return nil
+	}
+	return openLinkBatch(targetDir)
+}
+
+// nativeFS reports whether fsys is the real file system, possibly guarded
+// against concurrent edits, profiled, or protecting targets. No wrapper needs
+// to see links created where nothing exists: guardFS only protects existing
+// paths, the fast path profiles its own calls, and it checks protected
+// targets itself.
+func nativeFS() bool {
+	f := fsys
+	for {
+		switch w := f.(type) {
+		case osFS:
+			return true
+		case *guardFS:
+			f = w.FileSystem
+		case profileFS:
+			f = w.FileSystem
+		case protectedFS:
+			f = w.FileSystem
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package lnk
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// openat2 and its flags, from linux/openat2.h, and O_PATH; the syscall
+// package has none of them. They are the same on every architecture Go
+// supports (openat2 is Linux 5.6).
+const (
+	oPath               = 0x200000 // open only to refer to the directory
+	sysOpenat2          = 437
+	resolveNoMagiclinks = 0x02 // no /proc/<pid>/fd style links
+	resolveBeneath      = 0x08 // fail rather than leave the directory
+)
+
+// openHow is struct open_how
+type openHow struct {
+	flags   uint64
+	mode    uint64
+	resolve uint64
+}
+
+// maxBatchDirs bounds the directories a batch keeps open; links are planned
+// in path order, so a directory is rarely needed again once the next one is
+const maxBatchDirs = 256
+
+// linkBatch creates symlinks and directories relative to open directories:
+// the target directory, and each directory below it links go into, opened
+// beneath it with openat2. Kernels without openat2 (before 5.6, or blocked
+// by seccomp) open directories by their absolute path instead.
+type linkBatch struct {
+	root      string
+	rootFD    int
+	dirs      map[string]int // open directories by path
+	noOpenat2 bool
+	links     int // links created by the fast path
+	fallbacks int // links handed to CreateSymlink
+}
+
+// openLinkBatch opens targetDir for a batch; nil if it cannot be opened
+func openLinkBatch(targetDir string) *linkBatch {
+	root := filepath.Clean(targetDir)
+	fd, err := syscall.Open(root, oPath|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		PrintVerbose("Creating links one at a time: cannot open %s: %v", ContractPath(root), err)
+		return nil
+	}
+	return &linkBatch{root: root, rootFD: fd, dirs: map[string]int{root: fd}}
+}
+
+// close closes the open directories; nil-safe
+func (b *linkBatch) close() {
+	if b == nil {
+		return
+	}
+	b.closeDirs()
+	syscall.Close(b.rootFD)
+	if b.links+b.fallbacks > 0 {
+		Trace("batch", "links", b.links, "fallbacks", b.fallbacks)
+	}
+}
+
+// closeDirs closes every open directory but the target directory
+func (b *linkBatch) closeDirs() {
+	for path, fd := range b.dirs {
+		if fd != b.rootFD {
+			syscall.Close(fd)
+		}
+		delete(b.dirs, path)
+	}
+	b.dirs[b.root] = b.rootFD
+}
+
+// openDir returns an open descriptor for the directory at path, opening it
+// if needed
+func (b *linkBatch) openDir(path string) (int, error) {
+	if fd, ok := b.dirs[path]; ok {
+		return fd, nil
+	}
+	if len(b.dirs) >= maxBatchDirs {
+		b.closeDirs()
+	}
+	const flags = oPath | syscall.O_DIRECTORY | syscall.O_CLOEXEC
+	var fd int
+	var err error
+	rel, below := b.relative(path)
+	if below && !b.noOpenat2 {
+		fd, err = openat2(b.rootFD, rel, &openHow{flags: flags, resolve: resolveBeneath | resolveNoMagiclinks})
+		switch err {
+		case syscall.ENOSYS, syscall.EPERM:
+			b.noOpenat2 = true
+		case syscall.EXDEV:
+			// A symlink on the way leads out of the target directory, as
+			// ~/.config may into a shared volume: follow it like the
+			// portable path would
+			below = false
+		}
+	}
+	if !below || b.noOpenat2 {
+		fd, err = syscall.Open(path, flags, 0)
+	}
+	if err != nil {
+		return -1, err
+	}
+	b.dirs[path] = fd
+	return fd, nil
+}
+
+// relative returns path relative to the target directory, if it is below it
+func (b *linkBatch) relative(path string) (string, bool) {
+	rel, ok := strings.CutPrefix(path, b.root+string(filepath.Separator))
+	return rel, ok && rel != ""
+}
+
+// symlink creates target pointing to source with one symlinkat in its open
+// parent directory. An existing target, or any error, is left to
+// CreateSymlink, which decides what to do about it and reports it. A
+// protected target is refused as protectedFS refuses it.
+func (b *linkBatch) symlink(source, target string) error {
+	if err := checkProtected("create symlink", target); err != nil {
+		return err
+	}
+	dirFD, err := b.openDir(filepath.Dir(target))
+	if err == nil {
+		if perf != nil {
+			defer profileOp("symlinkat", time.Now())
+		}
+		if err = symlinkat(source, dirFD, filepath.Base(target)); err == nil {
+			b.links++
+			return nil
+		}
+	}
+	b.fallbacks++
+	return CreateSymlink(source, target)
+}
+
+// mkdirAll is fsys.MkdirAll with mkdirat in the nearest open parent. Paths
+// outside the target directory, and anything but a missing directory, are
+// left to fsys.MkdirAll. A protected path is refused as protectedFS refuses
+// it.
+func (b *linkBatch) mkdirAll(path string, perm fs.FileMode) error {
+	if err := checkProtected("create directory", path); err != nil {
+		return err
+	}
+	_, err := b.openDir(path)
+	if err == nil {
+		return nil
+	}
+	parent := filepath.Dir(path)
+	if _, below := b.relative(path); !below || err != syscall.ENOENT || parent == path {
+		return fsys.MkdirAll(path, perm)
+	}
+	if err := b.mkdirAll(parent, perm); err != nil {
+		return err
+	}
+	parentFD, err := b.openDir(parent)
+	if err != nil {
+		return fsys.MkdirAll(path, perm)
+	}
+	if perf != nil {
+		defer profileOp("mkdirat", time.Now())
+	}
+	if err := syscall.Mkdirat(parentFD, filepath.Base(path), uint32(perm.Perm())); err != nil && err != syscall.EEXIST {
+		return &os.PathError{Op: "mkdir", Path: path, Err: err}
+	}
+	return nil
+}
+
+func openat2(dirFD int, path string, how *openHow) (int, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return -1, err
+	}
+	fd, _, errno := syscall.Syscall6(sysOpenat2, uintptr(dirFD), uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(how)), unsafe.Sizeof(*how), 0, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+func symlinkat(oldpath string, newDirFD int, newpath string) error {
+	o, err := syscall.BytePtrFromString(oldpath)
+	if err != nil {
+		return err
+	}
+	n, err := syscall.BytePtrFromString(newpath)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_SYMLINKAT, uintptr(unsafe.Pointer(o)),
+		uintptr(newDirFD), uintptr(unsafe.Pointer(n))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkBatch(t *testing.T) {
+	sourceDir, targetDir := t.TempDir(), t.TempDir()
+	source := filepath.Join(sourceDir, "file")
+	createTestFile(t, source, "x")
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(targetDir, ".shared")); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, filepath.Join(targetDir, ".conflict"), "local")
+
+	b := openLinkBatch(targetDir)
+	if b == nil {
+		t.Fatal("openLinkBatch() = nil")
+	}
+	defer b.close()
+
+	nested := filepath.Join(targetDir, ".config", "app", "deep")
+	if err := b.mkdirAll(nested, 0700); err != nil {
+		t.Fatalf("mkdirAll() error = %v", err)
+	}
+	if info, err := os.Stat(nested); err != nil || info.Mode().Perm() != 0700 {
+		t.Fatalf("mkdirAll() made %v, %v; want a 0700 directory", info, err)
+	}
+	for _, target := range []string{
+		filepath.Join(nested, "file"),
+		filepath.Join(targetDir, ".shared", "file"), // through a symlink out of the target directory
+	} {
+		if err := b.symlink(source, target); err != nil {
+			t.Fatalf("symlink(%s) error = %v", target, err)
+		}
+		assertSymlink(t, target, source)
+	}
+	if b.links != 2 || b.fallbacks != 0 {
+		t.Errorf("links = %d, fallbacks = %d; want both on the fast path", b.links, b.fallbacks)
+	}
+
+	// Existing targets are left to CreateSymlink
+	if _, ok := b.symlink(source, filepath.Join(nested, "file")).(LinkExistsError); !ok {
+		t.Error("symlink() over the same link should report LinkExistsError")
+	}
+	if err := b.symlink(source, filepath.Join(targetDir, ".conflict")); err == nil {
+		t.Error("symlink() over a file should fail")
+	}
+	if b.fallbacks != 2 {
+		t.Errorf("fallbacks = %d, want 2", b.fallbacks)
+	}
+}
+
+func TestStartLinkBatch(t *testing.T) {
+	targetDir := t.TempDir()
+	if b := startLinkBatch(targetDir); b == nil {
+		t.Error("startLinkBatch() = nil on the real file system")
+	} else {
+		b.close()
+	}
+
+	SetReadOnly(true)
+	if b := startLinkBatch(targetDir); b != nil {
+		b.close()
+		t.Error("startLinkBatch() should be off in read-only mode")
+	}
+	SetReadOnly(false)
+
+	SetLinkBatching(false)
+	t.Cleanup(func() { SetLinkBatching(true) })
+	if b := startLinkBatch(targetDir); b != nil {
+		b.close()
+		t.Error("startLinkBatch() should be off after SetLinkBatching(false)")
+	}
+}
+
+func TestLinkBatchProtectedTargets(t *testing.T) {
+	sourceDir, targetDir := t.TempDir(), t.TempDir()
+	source := filepath.Join(sourceDir, "file")
+	createTestFile(t, source, "x")
+	protectTargets(t, targetDir, []string{".ssh/"})
+
+	// create always runs with targets protected, so the batch must see
+	// through protectedFS
+	b := startLinkBatch(targetDir)
+	if b == nil {
+		t.Fatal("startLinkBatch() = nil with protected targets")
+	}
+	defer b.close()
+
+	target := filepath.Join(targetDir, ".config", "file")
+	if err := b.mkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatalf("mkdirAll() error = %v", err)
+	}
+	if err := b.symlink(source, target); err != nil {
+		t.Fatalf("symlink() error = %v", err)
+	}
+	assertSymlink(t, target, source)
+	if b.links != 1 {
+		t.Errorf("links = %d, want 1 on the fast path", b.links)
+	}
+
+	// The batch refuses protected targets as protectedFS does
+	if err := b.mkdirAll(filepath.Join(targetDir, ".ssh", "keys"), 0700); !errors.Is(err, ErrProtected) {
+		t.Errorf("mkdirAll() of a protected directory error = %v, want ErrProtected", err)
+	}
+	if err := b.symlink(source, filepath.Join(targetDir, ".ssh", "config")); !errors.Is(err, ErrProtected) {
+		t.Errorf("symlink() onto a protected target error = %v, want ErrProtected", err)
+	}
+	assertNotExists(t, filepath.Join(targetDir, ".ssh"))
+	if b.links != 1 || b.fallbacks != 0 {
+		t.Errorf("links = %d, fallbacks = %d; want protected targets refused before either path", b.links, b.fallbacks)
+	}
+}
+
+// benchmarkCreateLinks creates 1000 links in 50 new directories, as a large
+// first create does
+func benchmarkCreateLinks(b *testing.B, batched bool) {
+	source := filepath.Join(b.TempDir(), "file")
+	if err := os.WriteFile(source, nil, 0644); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		targetDir := b.TempDir()
+		b.StartTimer()
+
+		createLink, mkdirAll := CreateSymlink, fsys.MkdirAll
+		batch := (*linkBatch)(nil)
+		if batched {
+			batch = openLinkBatch(targetDir)
+			createLink, mkdirAll = batch.symlink, batch.mkdirAll
+		}
+		for d := 0; d < 50; d++ {
+			dir := filepath.Join(targetDir, ".config", fmt.Sprintf("app%d", d))
+			if err := mkdirAll(dir, 0755); err != nil {
+				b.Fatal(err)
+			}
+			for f := 0; f < 20; f++ {
+				if err := createLink(source, filepath.Join(dir, fmt.Sprintf("file%d", f))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		batch.close()
+	}
+}
+
+func BenchmarkCreateLinksPortable(b *testing.B) { benchmarkCreateLinks(b, false) }
+
+func BenchmarkCreateLinksBatched(b *testing.B) { benchmarkCreateLinks(b, true) }
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package lnk
+
+import "io/fs"
+
+// linkBatch is only implemented on Linux; elsewhere create always takes the
+// portable path
+type linkBatch struct{}
+
+func openLinkBatch(string) *linkBatch { return nil }
+
+func (*linkBatch) symlink(source, target string) error { return CreateSymlink(source, target) }
+
+func (*linkBatch) mkdirAll(path string, perm fs.FileMode) error { return fsys.MkdirAll(path, perm) }
+
+func (*linkBatch) close() {}
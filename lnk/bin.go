@@ -0,0 +1,130 @@
+package lnk
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// binDir is the user bin directory, relative to the home directory
+var binDir = filepath.Join(".local", "bin")
+
+// Markers around the PATH block lnk appends to a shell startup file
+const (
+	pathBlockStart = "# >>> lnk PATH >>>"
+	pathBlockEnd   = "# <<< lnk PATH <<<"
+)
+
+// prepareBinLinks finishes links created in the user bin directory: their
+// sources are made executable, and when the directory is not on PATH the user
+// is warned and, at a terminal, offered a PATH block in their shell startup file.
+func prepareBinLinks(targetDir string, links []PlannedLink) {
+	dir := filepath.Join(targetDir, binDir)
+	var found bool
+	for _, link := range links {
+		if !isWithin(link.Target, dir) {
+			continue
+		}
+		found = true
+		if err := makeExecutable(link.Source); err != nil {
+			PrintWarningWithHint(fmt.Errorf("Failed to make %s executable: %w", ContractPath(link.Source), err))
+		}
+	}
+	if !found || onPath(dir, os.Getenv("PATH")) {
+		return
+	}
+
+	rcFile, block := shellPathBlock(targetDir, os.Getenv("SHELL"))
+	if rcFile == "" || !assumeYes && !canPrompt() {
+		PrintWarningWithHint(WithHint(fmt.Errorf("%s is not on PATH", ContractPath(dir)),
+			fmt.Sprintf("Add %s to PATH in your shell startup file", ContractPath(dir))))
+		return
+	}
+	if !confirm(fmt.Sprintf("%s is not on PATH. Add it in %s? [y/N]", ContractPath(dir), ContractPath(rcFile))) {
+		PrintInfo("Add %s to PATH to run the linked commands", ContractPath(dir))
+		return
+	}
+	if err := appendPathBlock(rcFile, block); err != nil {
+		PrintWarningWithHint(err)
+		return
+	}
+	PrintSuccess("Added %s to PATH in %s", ContractPath(dir), ContractPath(rcFile))
+	PrintInfo("Start a new shell to use the linked commands")
+}
+
+// makeExecutable adds execute permission wherever the file is readable
+func makeExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	mode := info.Mode().Perm()
+	want := mode | (mode&0444)>>2
+	if want == mode {
+		return nil
+	}
+	PrintVerbose("Making %s executable", ContractPath(path))
+	return fsys.Chmod(path, want)
+}
+
+// onPath reports whether dir is one of the entries of a PATH value
+func onPath(dir, path string) bool {
+	return slices.ContainsFunc(filepath.SplitList(path), func(entry string) bool {
+		return filepath.Clean(entry) == filepath.Clean(dir)
+	})
+}
+
+// shellPathBlock returns the startup file of the given login shell and the
+// managed block that adds the user bin directory to PATH. The file is empty for
+// shells lnk does not know how to configure.
+func shellPathBlock(homeDir, shell string) (string, string) {
+	var rcFile, line string
+	switch filepath.Base(shell) {
+	case "bash":
+		rcFile, line = ".bashrc", `export PATH="$HOME/.local/bin:$PATH"`
+	case "zsh":
+		rcFile, line = ".zshrc", `export PATH="$HOME/.local/bin:$PATH"`
+	case "fish":
+		rcFile, line = filepath.Join(".config", "fish", "config.fish"), `fish_add_path "$HOME/.local/bin"`
+	default:
+		return "", ""
+	}
+	return filepath.Join(homeDir, rcFile), strings.Join([]string{pathBlockStart, line, pathBlockEnd}, "\n") + "\n"
+}
+
+// appendPathBlock appends block to rcFile unless a block is already there. A
+// startup file that is itself a lnk link is edited in the source directory.
+func appendPathBlock(rcFile, block string) error {
+	data, err := os.ReadFile(rcFile)
+	if err != nil && !os.IsNotExist(err) {
+		return NewPathErrorWithHint("read shell startup file", rcFile, err, "Check file permissions")
+	}
+	if strings.Contains(string(data), pathBlockStart) {
+		PrintVerbose("%s already has a lnk PATH block", ContractPath(rcFile))
+		return nil
+	}
+	// Separate the block from existing content by a blank line
+	if len(data) > 0 {
+		block = "\n" + block
+		if !strings.HasSuffix(string(data), "\n") {
+			block = "\n" + block
+		}
+	}
+
+	if err := fsys.MkdirAll(filepath.Dir(rcFile), 0755); err != nil {
+		return NewPathErrorWithHint("create directory", filepath.Dir(rcFile), err,
+			"Check that you have write permissions in the parent directory")
+	}
+	f, err := fsys.OpenFile(rcFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return NewPathErrorWithHint("update shell startup file", rcFile, err, "Check file permissions")
+	}
+	defer f.Close()
+	if _, err := io.WriteString(f, block); err != nil {
+		return NewPathErrorWithHint("update shell startup file", rcFile, err, "Check file permissions")
+	}
+	return nil
+}
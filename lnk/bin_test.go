@@ -0,0 +1,127 @@
+package lnk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateLinksBinPackage(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	script := filepath.Join(sourceDir, "scripts", "git-cleanup")
+	createTestFile(t, script, "#!/bin/sh\n")
+	if err := os.Chmod(script, 0644); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, filepath.Join(sourceDir, "scripts", PackageInfoFileName), `{"type": "bin"}`)
+	t.Setenv("PATH", "/usr/bin:/bin")
+	t.Setenv("SHELL", "/bin/bash")
+
+	origCanPrompt := canPrompt
+	canPrompt = func() bool { return false }
+	t.Cleanup(func() { canPrompt = origCanPrompt })
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"scripts"},
+	}
+	_, stderr := captureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	assertSymlink(t, filepath.Join(targetDir, ".local", "bin", "git-cleanup"), script)
+	info, err := os.Stat(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("script mode = %v, want 0755", info.Mode().Perm())
+	}
+	if !strings.Contains(stderr, "is not on PATH") {
+		t.Errorf("expected PATH warning, got stderr: %s", stderr)
+	}
+}
+
+func TestCreateLinksBinPackageAddsPath(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "scripts", "hello"), "#!/bin/sh\n")
+	createTestFile(t, filepath.Join(sourceDir, "scripts", PackageInfoFileName), `{"type": "bin"}`)
+	createTestFile(t, filepath.Join(targetDir, ".zshrc"), "alias ll='ls -l'")
+	t.Setenv("PATH", "/usr/bin:/bin")
+	t.Setenv("SHELL", "/usr/bin/zsh")
+
+	origCanPrompt, origReader := canPrompt, promptReader
+	canPrompt = func() bool { return true }
+	promptReader = bufio.NewReader(strings.NewReader("y\n"))
+	t.Cleanup(func() { canPrompt, promptReader = origCanPrompt, origReader })
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"scripts"},
+	}
+	stdout, _ := captureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	data, err := os.ReadFile(filepath.Join(targetDir, ".zshrc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "alias ll='ls -l'\n\n" + pathBlockStart + "\nexport PATH=\"$HOME/.local/bin:$PATH\"\n" + pathBlockEnd + "\n"
+	if string(data) != want {
+		t.Errorf(".zshrc = %q, want %q", data, want)
+	}
+	if !strings.Contains(stdout, "Added "+filepath.Join(targetDir, ".local", "bin")+" to PATH") {
+		t.Errorf("expected confirmation, got: %s", stdout)
+	}
+
+	// A second block is never added
+	if err := appendPathBlock(filepath.Join(targetDir, ".zshrc"), "again\n"); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(filepath.Join(targetDir, ".zshrc")); string(again) != want {
+		t.Errorf("appendPathBlock() changed a file that already has a block: %q", again)
+	}
+}
+
+func TestOnPath(t *testing.T) {
+	if !onPath("/home/me/.local/bin", "/usr/bin:/home/me/.local/bin/:/bin") {
+		t.Error("onPath() = false for an entry with a trailing slash")
+	}
+	if onPath("/home/me/.local/bin", "/usr/bin:/bin") {
+		t.Error("onPath() = true for a missing entry")
+	}
+}
+
+func TestShellPathBlock(t *testing.T) {
+	tests := []struct {
+		shell, rcFile, line string
+	}{
+		{"/bin/bash", ".bashrc", "export PATH="},
+		{"/usr/bin/zsh", ".zshrc", "export PATH="},
+		{"/usr/local/bin/fish", filepath.Join(".config", "fish", "config.fish"), "fish_add_path"},
+		{"/bin/tcsh", "", ""},
+	}
+	for _, tt := range tests {
+		rcFile, block := shellPathBlock("/home/me", tt.shell)
+		if tt.rcFile == "" {
+			if rcFile != "" {
+				t.Errorf("shellPathBlock(%s) = %s, want none", tt.shell, rcFile)
+			}
+			continue
+		}
+		if rcFile != filepath.Join("/home/me", tt.rcFile) || !strings.Contains(block, tt.line) {
+			t.Errorf("shellPathBlock(%s) = %s, %q", tt.shell, rcFile, block)
+		}
+	}
+}
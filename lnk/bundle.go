@@ -0,0 +1,540 @@
+package lnk
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A bundle carries what one machine needs from a source directory to another
+// that may be offline: the selected packages with the packages they require,
+// lnk's configuration files, and lnk-bundle.json describing every file. Files
+// are packed as they are stored, so encrypted files stay encrypted and are
+// only decrypted by the tool that encrypted them. 'lnk bundle apply' unpacks a
+// bundle into a source directory and links the packages it was made for.
+
+// BundleManifest is lnk-bundle.json, the first entry of every bundle
+type BundleManifest struct {
+	Version      int          `json:"version"`
+	Created      time.Time    `json:"created"`
+	Host         string       `json:"host"`               // machine the bundle was made on
+	Source       string       `json:"source"`             // source directory it was made from
+	Packages     []string     `json:"packages,omitempty"` // packages to link (empty = whole source directory)
+	PackagesFrom string       `json:"packages_from"`      // where the packages were chosen, e.g. .lnkprofiles
+	Files        []BundleFile `json:"files"`
+}
+
+// BundleFile describes one file in a bundle
+type BundleFile struct {
+	Path      string `json:"path"` // slash-separated, relative to the source directory
+	Mode      uint32 `json:"mode"` // permission bits
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256"`
+	Encrypted bool   `json:"encrypted,omitempty"` // starts with the header of a known encryption format
+}
+
+// BundleOptions holds options for creating and applying bundles
+type BundleOptions struct {
+	SourceDir      string   // source directory to bundle (create) or unpack into (apply)
+	TargetDir      string   // where to create links (apply)
+	IgnorePatterns []string // combined ignore patterns (create); --ignore patterns (apply)
+	Packages       []string // packages to bundle (empty = whole source directory) (create)
+	PackagesFrom   string   // where Packages came from, recorded in the manifest (create)
+	Sensitive      []string // files that must not be stored in plaintext (create)
+	Path           string   // bundle file to write (create) or read (apply)
+	DryRun         bool     // preview mode without writing files or links
+}
+
+// bundleVersion is the lnk-bundle.json format version
+const bundleVersion = 1
+
+// bundleFilesDir holds the source files inside a bundle
+const bundleFilesDir = "files"
+
+// bundleConfigFiles are the configuration files at the top of the source
+// directory that travel with every bundle
+var bundleConfigFiles = []string{IgnoreFileName, PackagesFileName, ProfilesFileName, LocalOnlyFileName, SensitiveFileName, DirsFileName, MapsFileName, WorkflowFileName, ProtectedFileName,
+	PrivateFileName + ".age", PrivateFileName + ".gpg", RecipientsFileName}
+
+// Bundle compressions, chosen by the bundle's file name
+const (
+	compressNone = ""
+	compressGzip = "gzip"
+	compressZstd = "zstd"
+)
+
+// runZstd runs the zstd command on data, compressing or, with -d,
+// decompressing it; tests replace it
+var runZstd = func(data []byte, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return nil, WithHint(errors.New("zstd command not found"),
+			"Install zstd, or name the bundle .tar.gz")
+	}
+	cmd := exec.Command("zstd", append([]string{"-q", "-c"}, args...)...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("zstd: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// bundleCompression returns the compression a bundle named path is written with
+func bundleCompression(path string) (string, error) {
+	switch {
+	case strings.HasSuffix(path, ".tar.zst"), strings.HasSuffix(path, ".tzst"):
+		return compressZstd, nil
+	case strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		return compressGzip, nil
+	case strings.HasSuffix(path, ".tar"):
+		return compressNone, nil
+	}
+	return "", NewValidationErrorWithHint("bundle", path, "unknown bundle format",
+		"Name the bundle .tar.zst, .tar.gz, or .tar")
+}
+
+// CreateBundle packs the selected packages of opts.SourceDir, the packages
+// they require, and the configuration files into the bundle file opts.Path
+func CreateBundle(opts BundleOptions) error {
+	PrintCommandHeader("Creating Bundle")
+
+	out, err := filepath.Abs(opts.Path)
+	if err != nil {
+		return NewPathError("resolve bundle path", opts.Path, err)
+	}
+	compression, err := bundleCompression(out)
+	if err != nil {
+		return err
+	}
+	packages, err := expandPackageDeps(opts.SourceDir, opts.Packages)
+	if err != nil {
+		return err
+	}
+	dirs, err := packageDirs(opts.SourceDir, packages)
+	if err != nil {
+		return err
+	}
+	files, err := collectBundleFiles(opts.SourceDir, dirs, opts.IgnorePatterns)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		PrintEmptyResult("files to bundle")
+		return nil
+	}
+
+	host, _ := os.Hostname()
+	manifest := &BundleManifest{
+		Version:      bundleVersion,
+		Created:      time.Now().UTC(),
+		Host:         host,
+		Source:       contractHome(opts.SourceDir),
+		Packages:     opts.Packages,
+		PackagesFrom: opts.PackagesFrom,
+	}
+	for _, rel := range files {
+		// A bundle written into the source directory does not bundle itself
+		if filepath.Join(opts.SourceDir, filepath.FromSlash(rel)) == out {
+			continue
+		}
+		file, err := describeBundleFile(opts.SourceDir, rel)
+		if err != nil {
+			return err
+		}
+		if pattern, ok := sensitiveRepoPattern(opts.Sensitive, filepath.FromSlash(rel)); ok && !file.Encrypted {
+			PrintWarningWithHint(WithHint(
+				fmt.Errorf("Sensitive file %s is bundled in plaintext (matches %s)", rel, pattern),
+				"Encrypt it (e.g. with age or git-crypt) before sharing the bundle"))
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	if opts.DryRun {
+		PrintDryRun("Would bundle %d file(s) into %s:", len(manifest.Files), ContractPath(out))
+		lines := newPathLines(len(manifest.Files), "Would bundle", PrintDryRun)
+		for _, f := range manifest.Files {
+			lines.Add(filepath.Join(opts.SourceDir, f.Path), "Would bundle: %s%s", f.Path, encryptedNote(f))
+		}
+		lines.Flush()
+		fmt.Println()
+		PrintDryRunSummary()
+		return nil
+	}
+
+	data, err := packBundle(manifest, opts.SourceDir)
+	if err != nil {
+		return err
+	}
+	switch compression {
+	case compressGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return fmt.Errorf("compressing bundle: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("compressing bundle: %w", err)
+		}
+		data = buf.Bytes()
+	case compressZstd:
+		if data, err = runZstd(data); err != nil {
+			return fmt.Errorf("compressing bundle: %w", err)
+		}
+	}
+	// Bundles may hold private keys and tokens, so only the owner can read them
+	if err := writeFileAtomic(out, data, 0600); err != nil {
+		return NewPathErrorWithHint("write bundle", out, err,
+			"Check that the directory exists and you have write permissions")
+	}
+
+	encrypted := 0
+	for _, f := range manifest.Files {
+		if f.Encrypted {
+			encrypted++
+		}
+	}
+	PrintSuccess("Bundled %d file(s) into %s", len(manifest.Files), ContractPath(out))
+	PrintDetail("Packages: %s", describeBundlePackages(manifest))
+	if encrypted > 0 {
+		PrintDetail("%d encrypted file(s) included as stored", encrypted)
+	}
+	PrintNextStep("bundle apply", "<source-dir> "+filepath.Base(out), "unpack it after copying it to the other machine")
+	return nil
+}
+
+// collectBundleFiles returns the slash-separated paths, relative to sourceDir,
+// of the files a bundle of dirs holds: the configuration files at the top of
+// sourceDir, and every regular file in dirs that is not ignored. Package
+// metadata is always kept, since ignore patterns usually hide it from linking.
+func collectBundleFiles(sourceDir string, dirs []string, ignorePatterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(rel string) {
+		if rel = filepath.ToSlash(rel); !seen[rel] {
+			seen[rel] = true
+			files = append(files, rel)
+		}
+	}
+	for _, name := range bundleConfigFiles {
+		if info, err := fsys.Lstat(filepath.Join(sourceDir, name)); err == nil && info.Mode().IsRegular() {
+			add(name)
+		}
+	}
+
+	pm := NewPatternMatcher(ignorePatterns)
+	for _, dir := range dirs {
+		err := walkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				return fmt.Errorf("failed to calculate relative path: %w", err)
+			}
+			name := d.Name()
+			if pm.Matches(relPath) && name != PackageInfoFileName && name != RequiresFileName {
+				return nil
+			}
+			rel, err := filepath.Rel(sourceDir, path)
+			if err != nil {
+				return fmt.Errorf("failed to calculate relative path: %w", err)
+			}
+			add(rel)
+			return nil
+		})
+		if err != nil {
+			return nil, NewPathErrorWithHint("read package", dir, err, "Check file permissions")
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// describeBundleFile returns the manifest entry for the file rel in sourceDir
+func describeBundleFile(sourceDir, rel string) (BundleFile, error) {
+	path := filepath.Join(sourceDir, filepath.FromSlash(rel))
+	info, err := fsys.Lstat(path)
+	if err != nil {
+		return BundleFile{}, NewPathErrorWithHint("read", path, err, "Check file permissions")
+	}
+	sum, err := hashFile(path)
+	if err != nil {
+		return BundleFile{}, NewPathErrorWithHint("read", path, err, "Check file permissions")
+	}
+	encrypted, err := isEncryptedFile(path)
+	if err != nil {
+		return BundleFile{}, NewPathErrorWithHint("read", path, err, "Check file permissions")
+	}
+	return BundleFile{
+		Path:      rel,
+		Mode:      uint32(info.Mode().Perm()),
+		Size:      info.Size(),
+		SHA256:    hex.EncodeToString(sum),
+		Encrypted: encrypted,
+	}, nil
+}
+
+// packBundle returns the uncompressed tar archive of manifest and its files
+func packBundle(manifest *BundleManifest, sourceDir string) ([]byte, error) {
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding bundle manifest: %w", err)
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	hdr := &tar.Header{Name: BundleManifestFileName, Mode: 0644, Size: int64(len(manifestData)) + 1, ModTime: manifest.Created}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, fmt.Errorf("writing bundle: %w", err)
+	}
+	if _, err := tw.Write(append(manifestData, '\n')); err != nil {
+		return nil, fmt.Errorf("writing bundle: %w", err)
+	}
+	for _, f := range manifest.Files {
+		path := filepath.Join(sourceDir, filepath.FromSlash(f.Path))
+		data, err := fsys.ReadFile(path)
+		if err != nil {
+			return nil, NewPathErrorWithHint("read", path, err, "Check file permissions")
+		}
+		hdr := &tar.Header{Name: bundleFilesDir + "/" + f.Path, Mode: int64(f.Mode), Size: int64(len(data)), ModTime: manifest.Created}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, fmt.Errorf("writing bundle: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, fmt.Errorf("writing bundle: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("writing bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ApplyBundle unpacks the bundle file opts.Path into opts.SourceDir and links
+// the packages it was made for. Files already in the source directory must
+// match the bundle; nothing is written when one differs.
+func ApplyBundle(opts BundleOptions) error {
+	PrintCommandHeader("Applying Bundle")
+
+	bundlePath, err := filepath.Abs(opts.Path)
+	if err != nil {
+		return NewPathError("resolve bundle path", opts.Path, err)
+	}
+	manifest, contents, err := readBundle(bundlePath)
+	if err != nil {
+		return err
+	}
+	PrintInfo("Bundle of %s from %s, made %s", manifest.Source, manifest.Host, manifest.Created.Local().Format("2006-01-02 15:04"))
+	PrintDetail("Packages: %s", describeBundlePackages(manifest))
+
+	var unpack []BundleFile
+	var differ []string
+	for _, f := range manifest.Files {
+		dest := filepath.Join(opts.SourceDir, filepath.FromSlash(f.Path))
+		existing, err := fsys.ReadFile(dest)
+		switch {
+		case err == nil && bytes.Equal(existing, contents[f.Path]):
+			PrintVerbose("Already unpacked: %s", f.Path)
+		case err == nil:
+			differ = append(differ, dest)
+		case os.IsNotExist(err):
+			unpack = append(unpack, f)
+		default:
+			return NewPathErrorWithHint("read", dest, err, "Check file permissions")
+		}
+	}
+	if len(differ) > 0 {
+		for _, dest := range differ {
+			PrintWarning("Differs from the bundle: %s", ContractPath(dest))
+		}
+		return NewValidationErrorWithHint("source-dir", ContractPath(opts.SourceDir),
+			fmt.Sprintf("%d file(s) differ from the bundle", len(differ)),
+			"Apply the bundle to an empty directory, or move the listed files away")
+	}
+
+	if opts.DryRun {
+		fmt.Println()
+		PrintDryRun("Would unpack %d file(s) into %s:", len(unpack), ContractPath(opts.SourceDir))
+		lines := newPathLines(len(unpack), "Would unpack", PrintDryRun)
+		for _, f := range unpack {
+			dest := filepath.Join(opts.SourceDir, filepath.FromSlash(f.Path))
+			lines.Add(dest, "Would unpack: %s%s", ContractPath(dest), encryptedNote(f))
+		}
+		lines.Flush()
+		PrintDryRun("Would then link %s", describeBundlePackages(manifest))
+		fmt.Println()
+		PrintDryRunSummary()
+		return nil
+	}
+
+	for _, f := range unpack {
+		dest := filepath.Join(opts.SourceDir, filepath.FromSlash(f.Path))
+		if err := fsys.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return NewPathErrorWithHint("create directory", filepath.Dir(dest), err,
+				"Check that you have write permissions in the source directory")
+		}
+		if err := writeFileAtomic(dest, contents[f.Path], fs.FileMode(f.Mode).Perm()); err != nil {
+			return NewPathErrorWithHint("write", dest, err,
+				"Check that you have write permissions in the source directory")
+		}
+	}
+	if len(unpack) > 0 {
+		PrintSuccess("Unpacked %d file(s) into %s", len(unpack), ContractPath(opts.SourceDir))
+	} else {
+		PrintInfo("%s already holds every file in the bundle", ContractPath(opts.SourceDir))
+	}
+	fmt.Println()
+
+	// The configuration files only exist now, so load them again
+	config, err := LoadConfig(opts.SourceDir, opts.IgnorePatterns)
+	if err != nil {
+		return err
+	}
+	if err := config.LoadPrivate(); err != nil {
+		return err
+	}
+	results, err := CreateLinks(LinkOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      opts.TargetDir,
+		IgnorePatterns: config.IgnorePatterns,
+		Packages:       manifest.Packages,
+		LocalOnly:      config.LocalOnly,
+		Dirs:           config.Dirs,
+	})
+	PrintCreateSummary(results, config.SourceDir)
+	return err
+}
+
+// readBundle reads the bundle at path and returns its manifest and the
+// contents of its files by manifest path, each checked against its hash
+func readBundle(path string) (*BundleManifest, map[string][]byte, error) {
+	invalid := func(reason string) error {
+		return NewValidationErrorWithHint("bundle", ContractPath(path), reason,
+			"Create the bundle again with 'lnk bundle create'")
+	}
+
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, nil, NewPathErrorWithHint("read bundle", path, err, "Check that the bundle file exists")
+	}
+	// Compression is recognized by its magic number, so renamed bundles work
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, invalid(fmt.Sprintf("not a gzip file: %v", err))
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, nil, invalid(fmt.Sprintf("corrupt gzip data: %v", err))
+		}
+	case bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		if data, err = runZstd(data, "-d"); err != nil {
+			return nil, nil, fmt.Errorf("decompressing bundle: %w", err)
+		}
+	}
+
+	var manifest *BundleManifest
+	contents := make(map[string][]byte)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, invalid(fmt.Sprintf("not a bundle: %v", err))
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, nil, invalid(fmt.Sprintf("unexpected entry %s", hdr.Name))
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, invalid(fmt.Sprintf("corrupt entry %s: %v", hdr.Name, err))
+		}
+		if hdr.Name == BundleManifestFileName {
+			manifest = &BundleManifest{}
+			if err := json.Unmarshal(body, manifest); err != nil {
+				return nil, nil, invalid(fmt.Sprintf("unreadable %s: %v", BundleManifestFileName, err))
+			}
+			continue
+		}
+		rel, ok := strings.CutPrefix(hdr.Name, bundleFilesDir+"/")
+		if !ok || !isBundleRelPath(rel) {
+			return nil, nil, invalid(fmt.Sprintf("unexpected entry %s", hdr.Name))
+		}
+		contents[rel] = body
+	}
+
+	if manifest == nil {
+		return nil, nil, invalid(fmt.Sprintf("missing %s", BundleManifestFileName))
+	}
+	if manifest.Version > bundleVersion {
+		return nil, nil, NewValidationErrorWithHint("bundle", ContractPath(path),
+			fmt.Sprintf("made by a newer lnk (format version %d)", manifest.Version),
+			"Upgrade lnk on this machine")
+	}
+	for _, f := range manifest.Files {
+		body, ok := contents[f.Path]
+		if !ok || !isBundleRelPath(f.Path) {
+			return nil, nil, invalid(fmt.Sprintf("%s is listed but missing", f.Path))
+		}
+		if sum := sha256.Sum256(body); hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, nil, invalid(fmt.Sprintf("%s does not match its checksum", f.Path))
+		}
+	}
+	if len(contents) != len(manifest.Files) {
+		return nil, nil, invalid(fmt.Sprintf("holds files %s does not list", BundleManifestFileName))
+	}
+	return manifest, contents, nil
+}
+
+// isBundleRelPath reports whether rel is a clean relative path that stays
+// inside the directory a bundle is unpacked into
+func isBundleRelPath(rel string) bool {
+	clean := path.Clean(rel)
+	return clean == rel && clean != "." && !path.IsAbs(clean) && clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// describeBundlePackages names the packages a bundle links, for output
+func describeBundlePackages(m *BundleManifest) string {
+	if len(m.Packages) == 0 {
+		return "whole source directory"
+	}
+	desc := strings.Join(m.Packages, ", ")
+	if m.PackagesFrom != "" {
+		desc += " (from " + m.PackagesFrom + ")"
+	}
+	return desc
+}
+
+// encryptedNote marks encrypted files in per-file output
+func encryptedNote(f BundleFile) string {
+	if f.Encrypted {
+		return " (encrypted)"
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package lnk
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	sourceDir, _ := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "shell", RequiresFileName), "work\n")
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".ssh", "id_ed25519"), "age-encryption.org/v1\nsecret")
+	createTestFile(t, filepath.Join(sourceDir, PackagesFileName), "shell\n")
+	bundle := filepath.Join(t.TempDir(), "dotfiles.tar.gz")
+
+	config, err := LoadConfig(sourceDir, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	packages, from := config.ResolvePackages(nil)
+	out := CaptureOutput(t, func() {
+		err := CreateBundle(BundleOptions{SourceDir: sourceDir, IgnorePatterns: config.IgnorePatterns,
+			Packages: packages, PackagesFrom: from, Path: bundle})
+		if err != nil {
+			t.Fatalf("CreateBundle() error = %v", err)
+		}
+	})
+	ContainsOutput(t, out, "Bundled 5 file(s)", "1 encrypted file(s)")
+
+	manifest, contents, err := readBundle(bundle)
+	if err != nil {
+		t.Fatalf("readBundle() error = %v", err)
+	}
+	var paths []string
+	for _, f := range manifest.Files {
+		paths = append(paths, f.Path)
+	}
+	want := ".lnkpackages shell/.bashrc shell/.lnkrequires shell/.ssh/id_ed25519 work/.gitconfig"
+	if got := strings.Join(paths, " "); got != want {
+		t.Errorf("bundled files = %s, want %s (nvim is not in use)", got, want)
+	}
+	if string(contents["shell/.ssh/id_ed25519"]) != "age-encryption.org/v1\nsecret" {
+		t.Error("encrypted file should be bundled as stored")
+	}
+	if strings.Join(manifest.Packages, ",") != "shell" || manifest.PackagesFrom != PackagesFileName {
+		t.Errorf("manifest packages = %v from %q", manifest.Packages, manifest.PackagesFrom)
+	}
+
+	// Apply on the other machine
+	newSource := filepath.Join(t.TempDir(), "dotfiles")
+	os.MkdirAll(newSource, 0755)
+	newTarget := filepath.Join(t.TempDir(), "home")
+	os.MkdirAll(newTarget, 0755)
+	opts := BundleOptions{SourceDir: newSource, TargetDir: newTarget, Path: bundle}
+
+	CaptureOutput(t, func() {
+		opts.DryRun = true
+		if err := ApplyBundle(opts); err != nil {
+			t.Fatalf("ApplyBundle() dry run error = %v", err)
+		}
+	})
+	assertNotExists(t, filepath.Join(newSource, "shell"))
+
+	opts.DryRun = false
+	CaptureOutput(t, func() {
+		if err := ApplyBundle(opts); err != nil {
+			t.Fatalf("ApplyBundle() error = %v", err)
+		}
+	})
+	assertSymlink(t, filepath.Join(newTarget, ".bashrc"), filepath.Join(newSource, "shell", ".bashrc"))
+	assertSymlink(t, filepath.Join(newTarget, ".gitconfig"), filepath.Join(newSource, "work", ".gitconfig"))
+
+	// Applying again is fine; a file changed since is not overwritten
+	CaptureOutput(t, func() {
+		if err := ApplyBundle(opts); err != nil {
+			t.Fatalf("ApplyBundle() again error = %v", err)
+		}
+	})
+	edited := filepath.Join(newSource, "work", ".gitconfig")
+	createTestFile(t, edited, "[user] edited")
+	CaptureOutput(t, func() {
+		if err := ApplyBundle(opts); err == nil {
+			t.Error("ApplyBundle() over a changed file succeeded")
+		}
+	})
+	if data, _ := os.ReadFile(edited); string(data) != "[user] edited" {
+		t.Errorf("changed file was overwritten: %q", data)
+	}
+}
+
+func TestBundleCompression(t *testing.T) {
+	tests := map[string]string{
+		"a.tar.zst": compressZstd,
+		"a.tzst":    compressZstd,
+		"a.tar.gz":  compressGzip,
+		"a.tgz":     compressGzip,
+		"a.tar":     compressNone,
+	}
+	for name, want := range tests {
+		if got, err := bundleCompression(name); err != nil || got != want {
+			t.Errorf("bundleCompression(%s) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := bundleCompression("a.zip"); err == nil {
+		t.Error("bundleCompression(a.zip) should be an error")
+	}
+}
+
+func TestReadBundleRejectsBadEntries(t *testing.T) {
+	write := func(t *testing.T, entries map[string]string) string {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for name, body := range entries {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body))})
+			tw.Write([]byte(body))
+		}
+		tw.Close()
+		path := filepath.Join(t.TempDir(), "bad.tar")
+		os.WriteFile(path, buf.Bytes(), 0600)
+		return path
+	}
+	manifest := `{"version":1,"files":[{"path":"a","sha256":"00"}]}`
+
+	tests := map[string]map[string]string{
+		"no manifest":    {"files/a": "x"},
+		"escaping path":  {BundleManifestFileName: manifest, "files/../a": "x"},
+		"bad checksum":   {BundleManifestFileName: manifest, "files/a": "x"},
+		"missing file":   {BundleManifestFileName: manifest},
+		"newer format":   {BundleManifestFileName: `{"version":99}`},
+		"unlisted files": {BundleManifestFileName: `{"version":1}`, "files/a": "x"},
+	}
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := readBundle(write(t, entries)); err == nil {
+				t.Error("readBundle() succeeded")
+			}
+		})
+	}
+}
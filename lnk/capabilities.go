@@ -0,0 +1,120 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+)
+
+// Policies for configured features this machine cannot honor
+const (
+	OnUnsupportedDegrade = "degrade" // report them, do without them, and continue (default)
+	OnUnsupportedAbort   = "abort"   // report them and stop before anything changes
+)
+
+// unsupportedFeature is a setting in the configuration that cannot be honored
+// on this platform or in this run, and what lnk does without it
+type unsupportedFeature struct {
+	setting  string // the setting as written, such as `"target": "windows"`
+	path     string // the file that sets it
+	reason   string // why it cannot be honored here
+	fallback string // what lnk does instead when degrading
+}
+
+// capabilityReport collects unsupported features while create plans
+type capabilityReport struct {
+	features []unsupportedFeature
+}
+
+// capabilities is the report for the create being planned; nil otherwise, so
+// status and remove, which plan the same packages, report nothing
+var capabilities *capabilityReport
+
+// add records an unsupported feature; nil-safe
+func (r *capabilityReport) add(f unsupportedFeature) {
+	if r == nil {
+		return
+	}
+	r.features = append(r.features, f)
+}
+
+// resolve prints the report, if anything is unsupported, and applies the
+// policy: OnUnsupportedAbort returns an error, anything else continues
+func (r *capabilityReport) resolve(policy string) error {
+	if r == nil || len(r.features) == 0 {
+		return nil
+	}
+	for _, f := range r.features {
+		Trace("unsupported", "setting", f.setting, "path", ContractPath(f.path), "reason", f.reason)
+	}
+	summary := fmt.Errorf("%d configured feature(s) not supported on this machine:", len(r.features))
+	if policy == OnUnsupportedAbort {
+		PrintErrorWithHint(summary)
+		r.printDetails(false)
+		return WithHint(fmt.Errorf("stopped before making changes (--on-unsupported abort)"),
+			"Use --on-unsupported degrade to continue without them, or change the configuration for this machine")
+	}
+	PrintWarningWithHint(summary)
+	r.printDetails(true)
+	return nil
+}
+
+// printDetails lists the unsupported features, with what lnk does instead
+func (r *capabilityReport) printDetails(degrading bool) {
+	for _, f := range r.features {
+		PrintDetail("%s in %s: %s", f.setting, ContractPath(f.path), f.reason)
+		if degrading {
+			PrintDetail("  -> %s", f.fallback)
+		}
+	}
+}
+
+// userGroups returns the groups of the user running lnk; tests replace it
+var userGroups = os.Getgroups
+
+// supportedDirPolicy returns the part of a .lnkdirs policy this machine can
+// apply, reporting the rest: Windows has no Unix modes or owners, only root
+// can give directories to another user, and a group must be one the user is
+// in. The policy is returned unchanged when all of it applies.
+func supportedDirPolicy(policy *DirPolicy, path string) *DirPolicy {
+	if policy == nil || policy.perm == 0 && policy.uid == -1 && policy.gid == -1 {
+		return policy
+	}
+	supported := *policy
+	if runtime.GOOS == "windows" {
+		capabilities.add(unsupportedFeature{
+			setting:  "mode, owner, and group",
+			path:     path,
+			reason:   "Windows has no Unix permissions or owners",
+			fallback: "creating directories with the defaults",
+		})
+		supported.perm, supported.uid, supported.gid = 0, -1, -1
+		return &supported
+	}
+	if runningAsRoot() {
+		return policy
+	}
+	if policy.uid != -1 && policy.uid != os.Geteuid() {
+		capabilities.add(unsupportedFeature{
+			setting:  fmt.Sprintf("owner %q", policy.Owner),
+			path:     path,
+			reason:   "only root can give directories to another user",
+			fallback: "keeping new directories owned by you",
+		})
+		supported.uid = -1
+	}
+	if policy.gid != -1 && policy.gid != os.Getegid() {
+		groups, err := userGroups()
+		if err != nil || !slices.Contains(groups, policy.gid) {
+			capabilities.add(unsupportedFeature{
+				setting:  fmt.Sprintf("group %q", policy.Group),
+				path:     path,
+				reason:   "you are not a member of the group",
+				fallback: "keeping new directories in your group",
+			})
+			supported.gid = -1
+		}
+	}
+	return &supported
+}
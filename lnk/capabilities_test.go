@@ -0,0 +1,78 @@
+package lnk
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateLinksOnUnsupported(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	origWSL := isWSL
+	isWSL = func() bool { return false }
+	t.Cleanup(func() { isWSL = origWSL })
+
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"target": "runtime"}`)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", PackageInfoFileName), `{"target": "windows"}`)
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"shell", "work", "nvim"},
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		opts.OnUnsupported = OnUnsupportedAbort
+		var err error
+		_, stderr := captureOutput(t, func() { _, err = CreateLinks(opts) })
+		if err == nil || !strings.Contains(GetErrorHint(err), "--on-unsupported degrade") {
+			t.Fatalf("CreateLinks() error = %v, want abort with a hint", err)
+		}
+		ContainsOutput(t, stderr, "2 configured feature(s) not supported")
+		assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+	})
+
+	t.Run("degrade", func(t *testing.T) {
+		opts.OnUnsupported = ""
+		var err error
+		stdout, stderr := captureOutput(t, func() { _, err = CreateLinks(opts) })
+		if err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+		ContainsOutput(t, stderr, "2 configured feature(s) not supported")
+		ContainsOutput(t, stdout, `"target": "runtime"`, "XDG_RUNTIME_DIR is not set",
+			`"target": "windows"`, "skipping the package")
+		assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+		assertNotExists(t, filepath.Join(targetDir, ".gitconfig"))
+	})
+}
+
+func TestSupportedDirPolicy(t *testing.T) {
+	origRoot, origGroups := runningAsRoot, userGroups
+	runningAsRoot = func() bool { return false }
+	userGroups = func() ([]int, error) { return []int{4242}, nil }
+	t.Cleanup(func() { runningAsRoot, userGroups = origRoot, origGroups })
+
+	capabilities = &capabilityReport{}
+	t.Cleanup(func() { capabilities = nil })
+
+	policy := &DirPolicy{Mode: "0700", Owner: "nobody", Group: "audio", perm: 0700, uid: 65534, gid: 4243}
+	got := supportedDirPolicy(policy, DirsFileName)
+	if got.perm != 0700 || got.uid != -1 || got.gid != -1 {
+		t.Errorf("supportedDirPolicy() = %+v, want mode kept, owner and group dropped", got)
+	}
+	if policy.uid != 65534 || policy.gid != 4243 {
+		t.Errorf("the loaded policy was changed: %+v", policy)
+	}
+	if len(capabilities.features) != 2 {
+		t.Errorf("reported %+v, want owner and group", capabilities.features)
+	}
+
+	// A group the user belongs to can be applied
+	capabilities = &capabilityReport{}
+	policy = &DirPolicy{Group: "wheel", uid: -1, gid: 4242}
+	if got := supportedDirPolicy(policy, DirsFileName); got.gid != 4242 || len(capabilities.features) != 0 {
+		t.Errorf("supportedDirPolicy() = %+v, reported %+v; want the group kept", got, capabilities.features)
+	}
+}
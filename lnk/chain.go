@@ -0,0 +1,96 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// DefaultMaxSymlinkDepth is how many symlinks lnk follows in one chain before
+// giving up, the same limit as the Linux kernel
+const DefaultMaxSymlinkDepth = 40
+
+// maxSymlinkDepth is the chain length allowed when validating links
+// (--max-symlink-depth)
+var maxSymlinkDepth = DefaultMaxSymlinkDepth
+
+// SetMaxSymlinkDepth sets how many symlinks lnk follows in one chain. Values
+// below 1 restore DefaultMaxSymlinkDepth.
+func SetMaxSymlinkDepth(n int) {
+	if n < 1 {
+		n = DefaultMaxSymlinkDepth
+	}
+	maxSymlinkDepth = n
+}
+
+// symlinkChain follows the symlink at path one link at a time and returns
+// every path visited, starting with path. It stops at the first path that is
+// not a symlink or does not exist. A chain that comes back to a path it
+// already visited, is longer than maxSymlinkDepth, or that the operating
+// system refuses with ELOOP is an ErrSymlinkLoop error naming the chain.
+func symlinkChain(path string) ([]string, error) {
+	path = filepath.Clean(path)
+	chain := []string{path}
+	seen := map[string]bool{path: true}
+	for {
+		info, err := fsys.Lstat(path)
+		if errors.Is(err, syscall.ELOOP) {
+			return chain, symlinkLoopError(chain, "the operating system found too many levels of symbolic links")
+		}
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			return chain, nil
+		}
+
+		dest, err := fsys.Readlink(path)
+		if err != nil {
+			return chain, NewPathError("read symlink", path, err)
+		}
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(filepath.Dir(path), dest)
+		}
+		dest = filepath.Clean(dest)
+		chain = append(chain, dest)
+
+		switch {
+		case seen[dest]:
+			return chain, symlinkLoopError(chain, "the chain leads back to "+ContractPath(dest))
+		case len(chain)-1 > maxSymlinkDepth:
+			return chain, symlinkLoopError(chain, fmt.Sprintf("more than %d links in a row", maxSymlinkDepth))
+		}
+		seen[dest] = true
+		path = dest
+	}
+}
+
+// symlinkLoopError describes a chain that could not be resolved
+func symlinkLoopError(chain []string, why string) error {
+	return NewPathErrorWithHint("resolve symlink", chain[0],
+		fmt.Errorf("%w (%s): %s", ErrSymlinkLoop, why, formatChain(chain)),
+		"Point one of the links in the chain at a real file, or raise --max-symlink-depth if the chain is intended")
+}
+
+// formatChain shows a symlink chain as "a -> b -> c", eliding the middle of
+// long chains
+func formatChain(chain []string) string {
+	const keep = 4
+	parts := make([]string, 0, 2*keep+1)
+	for i, path := range chain {
+		switch {
+		case len(chain) <= 2*keep+1 || i < keep || i >= len(chain)-keep:
+			parts = append(parts, ContractPath(path))
+		case i == keep:
+			parts = append(parts, fmt.Sprintf("... (%d more)", len(chain)-2*keep))
+		}
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// ValidateSymlinkChain checks that the symlink chain starting at path, if any,
+// ends within maxSymlinkDepth links without coming back on itself
+func ValidateSymlinkChain(path string) error {
+	_, err := symlinkChain(path)
+	return err
+}
@@ -0,0 +1,91 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestValidateSymlinkCreationRejectsLoop(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.bashrc", "bash")
+	if err := m.MkdirAll("/home", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Symlink("/home/.b", "/home/.bashrc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Symlink("/home/.bashrc", "/home/.b"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ValidateSymlinkCreation("/src/.bashrc", "/home/.bashrc")
+	if !errors.Is(err, ErrSymlinkLoop) {
+		t.Fatalf("ValidateSymlinkCreation() error = %v, want ErrSymlinkLoop", err)
+	}
+	if want := "/home/.bashrc -> /home/.b -> /home/.bashrc"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not name the chain %q", err, want)
+	}
+	if GetErrorHint(err) == "" {
+		t.Error("expected a hint on the loop error")
+	}
+}
+
+func TestValidateSymlinkChainDepth(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.bashrc", "bash")
+	// /home/l4 -> /home/l3 -> /home/l2 -> /home/l1 -> /src/.bashrc
+	if err := m.MkdirAll("/home", 0755); err != nil {
+		t.Fatal(err)
+	}
+	prev := "/src/.bashrc"
+	for i := 1; i <= 4; i++ {
+		link := fmt.Sprintf("/home/l%d", i)
+		if err := m.Symlink(prev, link); err != nil {
+			t.Fatal(err)
+		}
+		prev = link
+	}
+	t.Cleanup(func() { SetMaxSymlinkDepth(0) })
+
+	if err := ValidateSymlinkChain("/home/l4"); err != nil {
+		t.Fatalf("default depth: unexpected error %v", err)
+	}
+
+	SetMaxSymlinkDepth(3)
+	if err := ValidateSymlinkChain("/home/l3"); err != nil {
+		t.Errorf("3 links with depth 3: unexpected error %v", err)
+	}
+	if err := ValidateSymlinkChain("/home/l4"); !errors.Is(err, ErrSymlinkLoop) {
+		t.Errorf("4 links with depth 3: error = %v, want ErrSymlinkLoop", err)
+	}
+
+	SetMaxSymlinkDepth(0)
+	if maxSymlinkDepth != DefaultMaxSymlinkDepth {
+		t.Errorf("SetMaxSymlinkDepth(0) left depth %d, want %d", maxSymlinkDepth, DefaultMaxSymlinkDepth)
+	}
+}
+
+func TestFormatChain(t *testing.T) {
+	short := []string{"/a", "/b", "/c"}
+	if got, want := formatChain(short), "/a -> /b -> /c"; got != want {
+		t.Errorf("formatChain(short) = %q, want %q", got, want)
+	}
+
+	var long []string
+	for i := 0; i < 12; i++ {
+		long = append(long, fmt.Sprintf("/l%d", i))
+	}
+	want := "/l0 -> /l1 -> /l2 -> /l3 -> ... (4 more) -> /l8 -> /l9 -> /l10 -> /l11"
+	if got := formatChain(long); got != want {
+		t.Errorf("formatChain(long) = %q, want %q", got, want)
+	}
+}
+
+func TestBrokenReasonSymlinkLoop(t *testing.T) {
+	if got := brokenReason("/src/.bashrc", syscall.ELOOP); got != BrokenSymlinkLoop {
+		t.Errorf("brokenReason(ELOOP) = %q, want %q", got, BrokenSymlinkLoop)
+	}
+}
@@ -0,0 +1,143 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Clean removes empty directories that lnk created for the source directory.
+// Only directories recorded in the manifest are considered, so directories that
+// existed before lnk ran are never touched.
+func Clean(opts LinkOptions) error {
+	PrintCommandHeader("Cleaning Empty Directories")
+
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
+
+	candidates, err := emptyCreatedDirs(sourceDir, targetDir)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		if !opts.DryRun {
+			pruneStaleManifestDirs(targetDir)
+		}
+		PrintEmptyResult("empty directories to clean")
+		return nil
+	}
+
+	if opts.DryRun {
+		fmt.Println()
+		PrintDryRun("Would remove %d empty directory(ies):", len(candidates))
+		for _, dir := range candidates {
+			PrintDryRun("Would remove: %s", ContractPath(dir))
+		}
+		fmt.Println()
+		PrintDryRunSummary()
+		return nil
+	}
+
+	removed, failed := removeCreatedDirs(sourceDir, targetDir, candidates)
+	SummaryCount("removed_dirs", removed)
+	SummaryCount("failed", failed)
+	if removed > 0 {
+		PrintSummary("Removed %d empty directory(ies) successfully", removed)
+	}
+	if failed > 0 {
+		PrintWarning("Failed to remove %d directory(ies)", failed)
+		return fmt.Errorf("failed to remove %d directory(ies)", failed)
+	}
+	return nil
+}
+
+// emptyCreatedDirs returns manifest directories recorded for sourceDir that are
+// currently empty, deepest first. A directory whose only entries are other
+// candidates counts as empty, since those are removed before it.
+func emptyCreatedDirs(sourceDir, targetDir string) ([]string, error) {
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var recorded []string
+	for _, d := range m.Dirs {
+		if d.Source == sourceDir {
+			recorded = append(recorded, d.Path)
+		}
+	}
+	// Deepest first so children are considered before their parents
+	sort.Slice(recorded, func(i, j int) bool {
+		return strings.Count(recorded[i], string(os.PathSeparator)) > strings.Count(recorded[j], string(os.PathSeparator))
+	})
+
+	removable := make(map[string]bool)
+	var candidates []string
+	for _, dir := range recorded {
+		info, err := os.Lstat(dir)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			PrintVerbose("Failed to read %s: %v", ContractPath(dir), err)
+			continue
+		}
+		empty := true
+		for _, e := range entries {
+			if !removable[dir+string(os.PathSeparator)+e.Name()] {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			removable[dir] = true
+			candidates = append(candidates, dir)
+		}
+	}
+	return candidates, nil
+}
+
+// removeCreatedDirs removes the given directories (deepest first) and drops
+// manifest entries for directories that no longer exist.
+func removeCreatedDirs(sourceDir, targetDir string, dirs []string) (removed, failed int) {
+	for _, dir := range dirs {
+		if err := fsys.Remove(dir); err != nil {
+			PrintWarningWithHint(fmt.Errorf("Failed to remove %s: %w", ContractPath(dir),
+				NewPathErrorWithHint("remove directory", dir, err,
+					"Check that the directory is empty and you have write permissions")))
+			failed++
+			continue
+		}
+		PrintSuccess("Removed: %s", ContractPath(dir))
+		removed++
+	}
+	pruneStaleManifestDirs(targetDir)
+	return removed, failed
+}
+
+// pruneStaleManifestDirs drops manifest entries for directories that no longer exist.
+func pruneStaleManifestDirs(targetDir string) {
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to update manifest: %w", err))
+		return
+	}
+	kept := m.Dirs[:0]
+	for _, d := range m.Dirs {
+		if _, err := os.Lstat(d.Path); err == nil {
+			kept = append(kept, d)
+		}
+	}
+	if len(kept) == len(m.Dirs) {
+		return
+	}
+	m.Dirs = kept
+	if err := m.Save(targetDir); err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to update manifest: %w", err))
+	}
+}
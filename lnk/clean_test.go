@@ -0,0 +1,161 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupCleanTest links a nested file so lnk creates ~/.config/nvim, then
+// returns the source and target directories.
+func setupCleanTest(t *testing.T) (string, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "repo")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(sourceDir, ".config", "nvim", "init.lua"), "-- init")
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	return sourceDir, targetDir
+}
+
+func TestCreateRecordsDirs(t *testing.T) {
+	sourceDir, targetDir := setupCleanTest(t)
+
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	recorded := make(map[string]bool)
+	for _, d := range m.Dirs {
+		if d.Source != sourceDir {
+			t.Errorf("recorded source = %s, want %s", d.Source, sourceDir)
+		}
+		recorded[d.Path] = true
+	}
+	for _, dir := range []string{".config", ".config/nvim"} {
+		if !recorded[filepath.Join(targetDir, dir)] {
+			t.Errorf("expected %s to be recorded, got %v", dir, m.Dirs)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	sourceDir, targetDir := setupCleanTest(t)
+
+	// Pre-existing directory that lnk did not create
+	userDir := filepath.Join(targetDir, ".cache")
+	if err := os.MkdirAll(userDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Removing the link by hand leaves the created directories behind
+	if err := os.Remove(filepath.Join(targetDir, ".config", "nvim", "init.lua")); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}
+	output := CaptureOutput(t, func() {
+		if err := Clean(opts); err != nil {
+			t.Fatalf("Clean() error = %v", err)
+		}
+	})
+
+	assertNotExists(t, filepath.Join(targetDir, ".config", "nvim"))
+	assertNotExists(t, filepath.Join(targetDir, ".config"))
+	assertDirExists(t, userDir)
+	ContainsOutput(t, output, "Removed 2 empty directory(ies) successfully")
+
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if len(m.Dirs) != 0 {
+		t.Errorf("manifest Dirs = %v, want empty", m.Dirs)
+	}
+}
+
+func TestCleanKeepsNonEmptyDirs(t *testing.T) {
+	sourceDir, targetDir := setupCleanTest(t)
+
+	// A file the user added keeps ~/.config in place, but ~/.config/nvim is empty
+	if err := os.Remove(filepath.Join(targetDir, ".config", "nvim", "init.lua")); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, filepath.Join(targetDir, ".config", "user.conf"), "keep")
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}
+	CaptureOutput(t, func() {
+		if err := Clean(opts); err != nil {
+			t.Fatalf("Clean() error = %v", err)
+		}
+	})
+
+	assertNotExists(t, filepath.Join(targetDir, ".config", "nvim"))
+	assertDirExists(t, filepath.Join(targetDir, ".config"))
+}
+
+func TestCleanSkipsLinkedDirs(t *testing.T) {
+	sourceDir, targetDir := setupCleanTest(t)
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}
+	output := CaptureOutput(t, func() {
+		if err := Clean(opts); err != nil {
+			t.Fatalf("Clean() error = %v", err)
+		}
+	})
+
+	assertSymlink(t, filepath.Join(targetDir, ".config", "nvim", "init.lua"),
+		filepath.Join(sourceDir, ".config", "nvim", "init.lua"))
+	ContainsOutput(t, output, "No empty directories to clean")
+}
+
+func TestCleanDryRun(t *testing.T) {
+	sourceDir, targetDir := setupCleanTest(t)
+	if err := os.Remove(filepath.Join(targetDir, ".config", "nvim", "init.lua")); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, DryRun: true}
+	output := CaptureOutput(t, func() {
+		if err := Clean(opts); err != nil {
+			t.Fatalf("Clean() error = %v", err)
+		}
+	})
+
+	assertDirExists(t, filepath.Join(targetDir, ".config", "nvim"))
+	ContainsOutput(t, output, "Would remove 2 empty directory(ies)")
+}
+
+func TestRemoveLinksCleanDirs(t *testing.T) {
+	sourceDir, targetDir := setupCleanTest(t)
+
+	// An empty directory lnk created that RemoveLinks' own cleanup would not reach
+	if err := os.MkdirAll(filepath.Join(targetDir, ".config", "nvim", "after"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AddDir(filepath.Join(targetDir, ".config", "nvim", "after"), sourceDir)
+	if err := m.Save(targetDir); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, CleanDirs: true}
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+
+	assertNotExists(t, filepath.Join(targetDir, ".config"))
+}
@@ -0,0 +1,85 @@
+package lnk
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// collapseThreshold is the number of paths above which an operation's
+// per-path lines are grouped by directory. It is a package variable so tests
+// can lower it.
+var collapseThreshold = 200
+
+// maxCollapsedDirs caps the directory lines a collapsed operation prints
+const maxCollapsedDirs = 20
+
+// pathLines prints an operation's per-path lines. When the operation touches
+// more than collapseThreshold paths and --verbose is off, each line is counted
+// toward its parent directory instead, and Flush prints one line per
+// directory, so linking tens of thousands of files does not flood the output.
+type pathLines struct {
+	print    func(format string, args ...interface{})
+	action   string // what happened to the paths, e.g. "Created"
+	collapse bool
+	dirs     []string       // parent directories in first-seen order
+	counts   map[string]int // collapsed paths per parent directory
+}
+
+// newPathLines prepares the lines for an operation on total paths, printed
+// with print (PrintSuccess, PrintDryRun, ...)
+func newPathLines(total int, action string, print func(format string, args ...interface{})) *pathLines {
+	return &pathLines{
+		print:    print,
+		action:   action,
+		collapse: total > collapseThreshold && !IsVerbose(),
+		counts:   make(map[string]int),
+	}
+}
+
+// Collapsed reports whether lines are grouped by directory, so callers can
+// also drop the detail lines they print under each path
+func (l *pathLines) Collapsed() bool {
+	return l.collapse
+}
+
+// Add prints the line for path, or counts path toward its directory when the
+// lines are collapsed
+func (l *pathLines) Add(path, format string, args ...interface{}) {
+	if !l.collapse {
+		l.print(format, args...)
+		return
+	}
+	dir := filepath.Dir(path)
+	if l.counts[dir] == 0 {
+		l.dirs = append(l.dirs, dir)
+	}
+	l.counts[dir]++
+}
+
+// Flush prints the directory counts of collapsed lines, the directories with
+// the most paths first and at most maxCollapsedDirs of them
+func (l *pathLines) Flush() {
+	if !l.collapse || len(l.dirs) == 0 {
+		return
+	}
+	dirs := append([]string(nil), l.dirs...)
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return l.counts[dirs[i]] > l.counts[dirs[j]]
+	})
+
+	shown := dirs
+	if len(shown) > maxCollapsedDirs {
+		shown = shown[:maxCollapsedDirs]
+	}
+	for _, dir := range shown {
+		l.print("%s: %d path(s) in %s", l.action, l.counts[dir], ContractPath(dir))
+	}
+	if rest := dirs[len(shown):]; len(rest) > 0 {
+		var n int
+		for _, dir := range rest {
+			n += l.counts[dir]
+		}
+		l.print("%s: %d path(s) in %d other director(ies)", l.action, n, len(rest))
+	}
+	PrintDetail("Run with --verbose to list every path")
+}
@@ -0,0 +1,117 @@
+package lnk
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setCollapseThreshold lowers the collapse threshold for the test
+func setCollapseThreshold(t *testing.T, n int) {
+	t.Helper()
+	original := collapseThreshold
+	collapseThreshold = n
+	t.Cleanup(func() { collapseThreshold = original })
+}
+
+func TestPathLines(t *testing.T) {
+	setCollapseThreshold(t, 3)
+	paths := []string{"/t/a/1", "/t/b/1", "/t/b/2", "/t/b/3", "/t/a/2"}
+
+	t.Run("below threshold prints every path", func(t *testing.T) {
+		out := CaptureOutput(t, func() {
+			lines := newPathLines(3, "Created", PrintSuccess)
+			for _, p := range paths[:3] {
+				lines.Add(p, "Created: %s", p)
+			}
+			lines.Flush()
+		})
+		for _, p := range paths[:3] {
+			if !strings.Contains(out, "Created: "+p) {
+				t.Errorf("output missing %s:\n%s", p, out)
+			}
+		}
+		if strings.Contains(out, "--verbose") {
+			t.Errorf("uncollapsed output mentions --verbose:\n%s", out)
+		}
+	})
+
+	t.Run("above threshold groups by directory", func(t *testing.T) {
+		out := CaptureOutput(t, func() {
+			lines := newPathLines(len(paths), "Created", PrintSuccess)
+			for _, p := range paths {
+				lines.Add(p, "Created: %s", p)
+			}
+			lines.Flush()
+		})
+		if strings.Contains(out, "/t/b/1") {
+			t.Errorf("collapsed output lists paths:\n%s", out)
+		}
+		b := strings.Index(out, "Created: 3 path(s) in /t/b")
+		a := strings.Index(out, "Created: 2 path(s) in /t/a")
+		if a < 0 || b < 0 || b > a {
+			t.Errorf("want the fuller directory first:\n%s", out)
+		}
+		if !strings.Contains(out, "Run with --verbose to list every path") {
+			t.Errorf("output missing --verbose hint:\n%s", out)
+		}
+	})
+
+	t.Run("verbose restores every path", func(t *testing.T) {
+		SetVerbosity(VerbosityVerbose)
+		defer SetVerbosity(VerbosityNormal)
+		out := CaptureOutput(t, func() {
+			lines := newPathLines(len(paths), "Created", PrintSuccess)
+			for _, p := range paths {
+				lines.Add(p, "Created: %s", p)
+			}
+			lines.Flush()
+		})
+		if strings.Count(out, "Created: /t/") != len(paths) {
+			t.Errorf("want %d path lines:\n%s", len(paths), out)
+		}
+	})
+
+	t.Run("directory lines are capped", func(t *testing.T) {
+		out := CaptureOutput(t, func() {
+			lines := newPathLines(maxCollapsedDirs+5, "Removed", PrintSuccess)
+			for i := 0; i < maxCollapsedDirs+5; i++ {
+				p := fmt.Sprintf("/t/d%d/f", i)
+				lines.Add(p, "Removed: %s", p)
+			}
+			lines.Flush()
+		})
+		if got := strings.Count(out, "path(s) in /t/d"); got != maxCollapsedDirs {
+			t.Errorf("directory lines = %d, want %d:\n%s", got, maxCollapsedDirs, out)
+		}
+		if !strings.Contains(out, "Removed: 5 path(s) in 5 other director(ies)") {
+			t.Errorf("output missing remainder line:\n%s", out)
+		}
+	})
+}
+
+func TestCreateLinksCollapsesLargeOperations(t *testing.T) {
+	setCollapseThreshold(t, 2)
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "repo")
+	targetDir := filepath.Join(tmpDir, "home")
+	for _, name := range []string{"a", "b", "c"} {
+		createTestFile(t, filepath.Join(sourceDir, ".config", "app", name), name)
+	}
+
+	out := CaptureOutput(t, func() {
+		if _, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	if strings.Contains(out, "Created: "+filepath.Join(targetDir, ".config", "app", "a")) {
+		t.Errorf("collapsed output lists paths:\n%s", out)
+	}
+	if !strings.Contains(out, "Created: 3 path(s) in "+filepath.Join(targetDir, ".config", "app")) {
+		t.Errorf("output missing directory count:\n%s", out)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		assertSymlink(t, filepath.Join(targetDir, ".config", "app", name), filepath.Join(sourceDir, ".config", "app", name))
+	}
+}
@@ -40,29 +40,21 @@ func SetNoColor(noColor bool) {
 // 3. Whether stdout is a terminal (TTY)
 func ShouldEnableColor() bool {
 	colorEnabledOnce.Do(func() {
-		mu.RLock()
-		noColor := forceNoColor
-		mu.RUnlock()
-
-		// Check --no-color flag first
-		if noColor {
-			colorEnabled = false
-			return
-		}
-
-		// Check NO_COLOR environment variable
-		// According to https://no-color.org/, any non-empty value disables color
-		if os.Getenv("NO_COLOR") != "" {
-			colorEnabled = false
-			return
-		}
-
-		// Check if stdout is a terminal
-		colorEnabled = isTerminal()
+		colorEnabled = !colorDisabled() && isTerminal()
 	})
 	return colorEnabled
 }
 
+// colorDisabled reports whether the user turned color off with --no-color or
+// NO_COLOR (https://no-color.org/, any non-empty value), regardless of whether
+// stdout is a terminal
+func colorDisabled() bool {
+	mu.RLock()
+	noColor := forceNoColor
+	mu.RUnlock()
+	return noColor || os.Getenv("NO_COLOR") != ""
+}
+
 // Colored output helpers
 func Red(s string) string {
 	if !ShouldEnableColor() {
@@ -0,0 +1,78 @@
+package lnk
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PathComparer decides whether two absolute paths name the same file. lnk uses
+// it to recognize a link that already points at its source however the
+// destination is spelled, so correct links are not removed and recreated on
+// every run.
+type PathComparer interface {
+	SamePath(a, b string) bool
+}
+
+// CanonicalComparer compares paths after resolving symlinks in them. Paths that
+// do not exist are resolved as far as their deepest existing parent, so broken
+// links still compare by their spelled destination. With CaseInsensitive,
+// paths that differ only in letter case are the same, as on the default macOS
+// and Windows file systems.
+type CanonicalComparer struct {
+	CaseInsensitive bool
+}
+
+// SamePath reports whether a and b resolve to the same path
+func (c CanonicalComparer) SamePath(a, b string) bool {
+	a, b = canonicalPath(a), canonicalPath(b)
+	if c.CaseInsensitive {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// pathComparer is the comparer lnk uses for existing links
+var pathComparer = defaultPathComparer()
+
+// defaultPathComparer folds case on platforms whose file systems usually do
+func defaultPathComparer() PathComparer {
+	return CanonicalComparer{CaseInsensitive: runtime.GOOS == "darwin" || runtime.GOOS == "windows"}
+}
+
+// SetPathComparer sets how existing link destinations are compared with
+// sources. Passing nil restores the default CanonicalComparer for the platform.
+func SetPathComparer(c PathComparer) {
+	if c == nil {
+		c = defaultPathComparer()
+	}
+	pathComparer = c
+}
+
+// canonicalPath cleans path (dropping trailing separators) and resolves
+// symlinks in it. When path does not exist, its parent is resolved instead
+// and the base name kept as is.
+func canonicalPath(path string) string {
+	path = filepath.Clean(path)
+	if resolved, err := fsys.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path
+	}
+	return filepath.Join(canonicalPath(parent), filepath.Base(path))
+}
+
+// linkPointsTo reports whether the symlink at link points to source. A
+// relative destination is taken relative to the link's directory.
+func linkPointsTo(link, source string) bool {
+	dest, err := fsys.Readlink(link)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(filepath.Dir(link), dest)
+	}
+	return pathComparer.SamePath(dest, source)
+}
@@ -0,0 +1,91 @@
+package lnk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanonicalComparer(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/private/var/src/.bashrc", "bash")
+	if err := m.Symlink("/private/var", "/var"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		a, b            string
+		caseInsensitive bool
+		want            bool
+	}{
+		{"identical", "/var/src/.bashrc", "/var/src/.bashrc", false, true},
+		{"through symlinked directory", "/var/src/.bashrc", "/private/var/src/.bashrc", false, true},
+		{"trailing slash", "/var/src/", "/private/var/src", false, true},
+		{"dot segments", "/var/src/../src/.bashrc", "/var/src/.bashrc", false, true},
+		{"missing file under symlinked directory", "/var/src/.gone", "/private/var/src/.gone", false, true},
+		{"different files", "/var/src/.bashrc", "/var/src/.zshrc", false, false},
+		{"case differs", "/var/src/.BASHRC", "/var/src/.bashrc", false, false},
+		{"case differs, case-insensitive", "/var/src/.BASHRC", "/var/src/.bashrc", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := CanonicalComparer{CaseInsensitive: tt.caseInsensitive}
+			if got := c.SamePath(tt.a, tt.b); got != tt.want {
+				t.Errorf("SamePath(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateSymlinkKeepsEquivalentLink(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/home/u/dotfiles/.bashrc", "bash")
+
+	tests := []struct {
+		name string
+		dest string
+	}{
+		{"relative destination", "dotfiles/.bashrc"},
+		{"redundant segments", "/home/u/dotfiles/./../dotfiles/.bashrc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.RemoveAll("/home/u/.bashrc"); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Symlink(tt.dest, "/home/u/.bashrc"); err != nil {
+				t.Fatal(err)
+			}
+
+			err := CreateSymlink("/home/u/dotfiles/.bashrc", "/home/u/.bashrc")
+			if !errors.As(err, &LinkExistsError{}) {
+				t.Fatalf("CreateSymlink() error = %v, want LinkExistsError", err)
+			}
+			if got, _ := m.Readlink("/home/u/.bashrc"); got != tt.dest {
+				t.Errorf("link was rewritten to %q, want it left as %q", got, tt.dest)
+			}
+		})
+	}
+}
+
+func TestSetPathComparer(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.bashrc", "bash")
+	if err := m.MkdirAll("/home", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Symlink("/src/.bashrc", "/home/.bashrc"); err != nil {
+		t.Fatal(err)
+	}
+	SetPathComparer(neverSame{})
+	t.Cleanup(func() { SetPathComparer(nil) })
+
+	if err := CreateSymlink("/src/.bashrc", "/home/.bashrc"); err != nil {
+		t.Fatalf("CreateSymlink() error = %v, want the link recreated", err)
+	}
+}
+
+// neverSame is a PathComparer that treats every existing link as outdated
+type neverSame struct{}
+
+func (neverSame) SamePath(_, _ string) bool { return false }
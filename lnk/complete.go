@@ -0,0 +1,114 @@
+package lnk
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The completion that 'lnk shellenv' generates asks the hidden command
+// 'lnk __complete KIND <source-dir> [prefix]' for package names, mappings, and
+// managed paths. It runs on every Tab press, so candidates come from the top
+// level of the source directory and the manifest, never from walking the tree.
+
+// Completion kinds
+const (
+	CompletePackages     = "packages"      // package directories (--packages)
+	CompleteMappings     = "mappings"      // top-level source entries of recorded links (--source)
+	CompleteManagedPaths = "managed-paths" // recorded links (remove, orphan)
+)
+
+// CompletionKinds lists the kinds 'lnk __complete' accepts
+var CompletionKinds = []string{CompletePackages, CompleteMappings, CompleteManagedPaths}
+
+// Complete prints the completion candidates of kind for sourceDir that start
+// with prefix, one per line
+func Complete(kind, sourceDir, prefix string) error {
+	paths, err := ResolvePaths(sourceDir, "~")
+	if err != nil {
+		return err
+	}
+	candidates, err := completionCandidates(kind, paths.SourceDir, paths.TargetDir, prefix)
+	if err != nil {
+		return err
+	}
+	for _, c := range candidates {
+		fmt.Println(c)
+	}
+	return nil
+}
+
+// completionCandidates returns the sorted candidates of kind that start with
+// prefix. Managed paths are written the way prefix is: absolute after "/",
+// otherwise with ~ for the home directory.
+func completionCandidates(kind, sourceDir, targetDir, prefix string) ([]string, error) {
+	var candidates []string
+	switch kind {
+	case CompletePackages:
+		candidates = availablePackages(sourceDir)
+	case CompleteMappings:
+		candidates = recordedMappings(sourceDir, targetDir)
+	case CompleteManagedPaths:
+		for _, path := range recordedLinks(sourceDir, targetDir) {
+			if !filepath.IsAbs(prefix) {
+				path = ContractPath(path)
+			}
+			candidates = append(candidates, path)
+		}
+		sort.Strings(candidates)
+	default:
+		return nil, NewValidationErrorWithHint("completion", kind, "unknown completion kind",
+			fmt.Sprintf("Valid kinds: %s", strings.Join(CompletionKinds, ", ")))
+	}
+
+	var matching []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matching = append(matching, c)
+		}
+	}
+	return matching, nil
+}
+
+// recordedLinks returns the links the manifest records for sourceDir
+func recordedLinks(sourceDir, targetDir string) []string {
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		PrintVerbose("Failed to read links from manifest: %v", err)
+		return nil
+	}
+	var links []string
+	for _, l := range m.Links {
+		if l.Source == sourceDir {
+			links = append(links, l.Path)
+		}
+	}
+	return links
+}
+
+// recordedMappings returns the top-level entries of sourceDir that recorded
+// links point into, the subdirectories 'lnk prune --source' can be limited to
+func recordedMappings(sourceDir, targetDir string) []string {
+	seen := make(map[string]bool)
+	var mappings []string
+	for _, path := range recordedLinks(sourceDir, targetDir) {
+		dest, err := fsys.Readlink(path)
+		if err != nil {
+			continue
+		}
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(filepath.Dir(path), dest)
+		}
+		rel, err := filepath.Rel(sourceDir, dest)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if top := topLevelEntry(rel); top != "." && !seen[top] {
+			seen[top] = true
+			mappings = append(mappings, top)
+		}
+	}
+	sort.Strings(mappings)
+	return mappings
+}
@@ -0,0 +1,76 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompletionCandidates(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "dotfiles")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".bashrc"), "bashrc")
+	createTestFile(t, filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua"), "init")
+	createTestFile(t, filepath.Join(sourceDir, "git", ".gitconfig"), "git")
+	if err := os.MkdirAll(filepath.Join(sourceDir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim"}}); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	initLua := filepath.Join(targetDir, ".config", "nvim", "init.lua")
+
+	tests := []struct {
+		kind, prefix string
+		want         []string
+	}{
+		{CompletePackages, "", []string{"git", "nvim", "shell"}},
+		{CompletePackages, "s", []string{"shell"}},
+		{CompleteMappings, "", []string{"nvim", "shell"}},
+		{CompleteManagedPaths, "", []string{bashrc, initLua}},
+		{CompleteManagedPaths, filepath.Join(targetDir, ".c"), []string{initLua}},
+		{CompleteManagedPaths, "/nowhere", nil},
+	}
+	for _, tt := range tests {
+		got, err := completionCandidates(tt.kind, sourceDir, targetDir, tt.prefix)
+		if err != nil {
+			t.Fatalf("completionCandidates(%s, %q) error = %v", tt.kind, tt.prefix, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("completionCandidates(%s, %q) = %v, want %v", tt.kind, tt.prefix, got, tt.want)
+		}
+	}
+
+	if _, err := completionCandidates("files", sourceDir, targetDir, ""); err == nil {
+		t.Error("unknown kind should be an error")
+	}
+}
+
+func TestCompleteManagedPathsUseTilde(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	sourceDir := filepath.Join(home, "dotfiles")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "bashrc")
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: home}); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	for prefix, want := range map[string]string{
+		"":                  "~/.bashrc",
+		"~/.b":              "~/.bashrc",
+		filepath.Join(home): filepath.Join(home, ".bashrc"),
+	} {
+		got, err := completionCandidates(CompleteManagedPaths, sourceDir, home, prefix)
+		if err != nil || len(got) != 1 || got[0] != want {
+			t.Errorf("completionCandidates(%q) = %v, %v; want [%s]", prefix, got, err, want)
+		}
+	}
+}
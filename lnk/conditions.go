@@ -0,0 +1,95 @@
+package lnk
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Condition limits linking to machines where it holds. Every field that is set
+// must hold; an empty condition always holds.
+type Condition struct {
+	CommandExists []string `json:"command_exists,omitempty"` // commands that must be on PATH
+	If            string   `json:"if,omitempty"`             // expression that must be true (see expr.go)
+}
+
+// PathOverride applies a condition, or link groups, to the files of a package
+// that match a gitignore-style pattern (relative to the package directory)
+type PathOverride struct {
+	Pattern string    `json:"pattern"`
+	When    Condition `json:"when"`
+	Groups  []string  `json:"groups,omitempty"` // link groups the matching files belong to, besides the package's
+}
+
+// evaluate reports whether c holds on this machine, with a short explanation
+// for verbose output. The explanation is empty for an empty condition. An
+// invalid expression is returned as an error.
+func (c Condition) evaluate() (bool, string, error) {
+	return c.evaluateIn(defaultExprEnv())
+}
+
+// evaluateIn is evaluate against the machine described by env
+func (c Condition) evaluateIn(env exprEnv) (bool, string, error) {
+	var reasons []string
+	for _, cmd := range c.CommandExists {
+		path, err := env.lookPath(cmd)
+		if err != nil {
+			return false, fmt.Sprintf("%s not found on PATH", cmd), nil
+		}
+		reasons = append(reasons, fmt.Sprintf("%s found at %s", cmd, path))
+	}
+
+	if c.If != "" {
+		ok, bindings, err := evalExpr(c.If, env)
+		if err != nil {
+			return false, "", err
+		}
+		why := fmt.Sprintf("%s is %t", c.If, ok)
+		if len(bindings) > 0 {
+			values := make([]string, len(bindings))
+			for i, b := range bindings {
+				values[i] = b.name + "=" + b.value
+			}
+			why += " (" + strings.Join(values, ", ") + ")"
+		}
+		if !ok {
+			return false, why, nil
+		}
+		reasons = append(reasons, why)
+	}
+	return true, strings.Join(reasons, ", "), nil
+}
+
+// conditionalIgnorePatterns evaluates the conditions in a package's
+// lnk-package.json. It returns false when the package's own condition does not
+// hold, and otherwise the override patterns whose conditions do not hold, so
+// matching files can be left out of the plan.
+func conditionalIgnorePatterns(pkgDir string, info *PackageInfo) (bool, []string, error) {
+	name := ContractPath(pkgDir)
+	ok, why, err := info.When.evaluate()
+	if err != nil {
+		return false, nil, fmt.Errorf("%s: %w", ContractPath(filepath.Join(pkgDir, PackageInfoFileName)), err)
+	}
+	if !ok {
+		PrintVerbose("Skipping %s: %s", name, why)
+		return false, nil, nil
+	} else if why != "" {
+		PrintVerbose("Including %s: %s", name, why)
+	}
+
+	var skipped []string
+	for _, o := range info.Overrides {
+		ok, why, err := o.When.evaluate()
+		if err != nil {
+			return false, nil, fmt.Errorf("%s: override %s: %w",
+				ContractPath(filepath.Join(pkgDir, PackageInfoFileName)), o.Pattern, err)
+		}
+		if !ok {
+			PrintVerbose("Skipping %s: %s", filepath.Join(name, o.Pattern), why)
+			skipped = append(skipped, o.Pattern)
+		} else if why != "" {
+			PrintVerbose("Including %s: %s", filepath.Join(name, o.Pattern), why)
+		}
+	}
+	return true, skipped, nil
+}
@@ -0,0 +1,143 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeCommand puts an executable named name on a PATH of its own
+func fakeCommand(t *testing.T, name string) {
+	t.Helper()
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+}
+
+func TestConditionEvaluate(t *testing.T) {
+	fakeCommand(t, "alacritty")
+
+	tests := []struct {
+		name string
+		cond Condition
+		want bool
+	}{
+		{"empty", Condition{}, true},
+		{"command found", Condition{CommandExists: []string{"alacritty"}}, true},
+		{"command missing", Condition{CommandExists: []string{"lnk-test-missing"}}, false},
+		{"all must hold", Condition{CommandExists: []string{"alacritty", "lnk-test-missing"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, why, err := tt.cond.evaluate()
+			if err != nil {
+				t.Fatalf("evaluate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("evaluate() = %v (%s), want %v", got, why, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateLinksConditionalOverrides(t *testing.T) {
+	fakeCommand(t, "alacritty")
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".config", "alacritty", "alacritty.toml"), "# term")
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".config", "kitty", "kitty.conf"), "# term")
+	createTestFile(t, filepath.Join(sourceDir, "shell", PackageInfoFileName), `{
+		"overrides": [
+			{"pattern": ".config/alacritty/", "when": {"command_exists": ["alacritty"]}},
+			{"pattern": ".config/kitty/", "when": {"command_exists": ["kitty"]}}
+		]
+	}`)
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"shell"},
+	}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	assertSymlink(t, filepath.Join(targetDir, ".config", "alacritty", "alacritty.toml"),
+		filepath.Join(sourceDir, "shell", ".config", "alacritty", "alacritty.toml"))
+	assertNotExists(t, filepath.Join(targetDir, ".config", "kitty"))
+}
+
+func TestCreateLinksConditionalPackage(t *testing.T) {
+	fakeCommand(t, "nvim")
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", PackageInfoFileName), `{"when": {"command_exists": ["nvim"]}}`)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"when": {"command_exists": ["lnk-test-missing"]}}`)
+
+	SetVerbosity(VerbosityVerbose)
+	defer SetVerbosity(VerbosityNormal)
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"nvim", "work"}}
+	output := CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	assertSymlink(t, filepath.Join(targetDir, ".config", "nvim", "init.lua"),
+		filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua"))
+	assertNotExists(t, filepath.Join(targetDir, ".gitconfig"))
+	ContainsOutput(t, output, "lnk-test-missing not found on PATH", "nvim found at")
+}
+
+func TestStatusIgnoresUnmetConditions(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"when": {"command_exists": ["git"]}}`)
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"work"}}
+	output := CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+	})
+
+	NotContainsOutput(t, output, "unlinked")
+}
+
+func TestCreateLinksConditionExpression(t *testing.T) {
+	t.Setenv("LNK_TEST_WORK", "1")
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName),
+		`{"when": {"if": "env.LNK_TEST_WORK == \"1\""}}`)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", PackageInfoFileName),
+		`{"when": {"if": "env.LNK_TEST_WORK == \"1\" && os == \"lnk-test-os\""}}`)
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"work", "nvim"}}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	assertSymlink(t, filepath.Join(targetDir, ".gitconfig"), filepath.Join(sourceDir, "work", ".gitconfig"))
+	assertNotExists(t, filepath.Join(targetDir, ".config"))
+}
+
+func TestCreateLinksInvalidConditionExpression(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"when": {"if": "os = \"linux\""}}`)
+
+	_, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"work"}})
+	if err == nil {
+		t.Fatal("expected error for an invalid expression")
+	}
+	ContainsOutput(t, err.Error(), PackageInfoFileName, "invalid expression")
+	if GetErrorHint(err) == "" {
+		t.Error("expected a hint")
+	}
+}
@@ -8,14 +8,61 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
 // Config represents the final merged configuration from all sources
 type Config struct {
-	SourceDir      string   // Source directory (resolved absolute path)
-	TargetDir      string   // Target directory (always ~; configurable in tests)
-	IgnorePatterns []string // Combined ignore patterns from all sources
+	SourceDir      string         // Source directory (resolved absolute path)
+	TargetDir      string         // Target directory: LNK_TARGET_DIR, or ~
+	TargetFromEnv  bool           // TargetDir came from LNK_TARGET_DIR
+	IgnorePatterns []string       // Combined ignore patterns from all sources
+	Packages       []string       // Default packages from .lnkpackages (empty = whole source dir)
+	EnvPackages    []string       // Packages from LNK_PACKAGES, which override .lnkpackages
+	Profile        *ProfileRule   // Profile rule from .lnkprofiles named by LNK_PROFILE or matching this machine, or nil
+	ProfileFromEnv bool           // Profile was named by LNK_PROFILE rather than matched
+	LocalOnly      []string       // Target paths lnk never touches, from .lnklocal
+	Sensitive      []string       // Files that must not be stored in plaintext, from .lnksensitive
+	Dirs           *DirPolicy     // How missing parent directories are created, from .lnkdirs, or nil
+	Maps           []Mapping      // Saved mappings, from .lnkmaps
+	Workflow       *Workflow      // Steps of lnk up and lnk down, from .lnkworkflow, or nil
+	Protected      []string       // Target paths lnk never changes: built-in + .lnkprotected + private
+	Sources        []ConfigSource // Every source consulted, in discovery order
+
+	privateIgnoreAt int  // index in IgnorePatterns where private ignore patterns go
+	privateLoaded   bool // LoadPrivate has added the private configuration
+}
+
+// ConfigSource records one place configuration was looked for and what it
+// contributed, for 'lnk config explain'
+type ConfigSource struct {
+	Name    string   `json:"name"`    // built-in, a file path, or a flag
+	Setting string   `json:"setting"` // the setting it contributes to: "ignore", "packages", "local-only", "sensitive", "dirs", "maps", "workflow", or "protected"; "private" for a private configuration with no entries
+	Found   bool     `json:"found"`   // whether the file exists or the flag was given
+	Values  []string `json:"values"`  // entries it contributed
+}
+
+// ResolvePackages applies package precedence: --packages overrides
+// LNK_PACKAGES, which overrides the profile LNK_PROFILE names, which overrides
+// the matching profile in .lnkprofiles, which overrides .lnkpackages, and with
+// none of them the whole source directory is used. It returns the packages and the name of the
+// source they came from.
+func (c *Config) ResolvePackages(cliPackages []string) ([]string, string) {
+	switch {
+	case len(cliPackages) > 0:
+		return cliPackages, "--packages"
+	case len(c.EnvPackages) > 0:
+		return c.EnvPackages, EnvPackages
+	case c.Profile != nil && c.ProfileFromEnv:
+		return c.Profile.Packages, EnvProfile
+	case c.Profile != nil:
+		return c.Profile.Packages, ProfilesFileName
+	case len(c.Packages) > 0:
+		return c.Packages, PackagesFileName
+	default:
+		return nil, "default"
+	}
 }
 
 // parseIgnoreFile parses a .lnkignore file (gitignore syntax)
@@ -25,9 +72,14 @@ func parseIgnoreFile(filePath string) ([]string, error) {
 		return nil, fmt.Errorf("failed to read ignore file: %w", err)
 	}
 
-	patterns := []string{}
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
+	return parseLines(string(data)), nil
+}
+
+// parseLines returns the trimmed lines of a line-based config file, skipping
+// empty lines and # comments
+func parseLines(data string) []string {
+	entries := []string{}
+	for _, line := range strings.Split(data, "\n") {
 		line = strings.TrimSpace(line)
 
 		// Skip empty lines and comments
@@ -35,10 +87,9 @@ func parseIgnoreFile(filePath string) ([]string, error) {
 			continue
 		}
 
-		patterns = append(patterns, line)
+		entries = append(entries, line)
 	}
-
-	return patterns, nil
+	return entries
 }
 
 // LoadIgnoreFile loads ignore patterns from a .lnkignore file in the source directory
@@ -68,7 +119,7 @@ func LoadIgnoreFile(sourceDir string) ([]string, error) {
 
 // LoadConfig resolves sourceDir, loads ignore patterns, and returns a fully resolved Config.
 // The returned SourceDir is always an absolute, validated path.
-// Ignore pattern order: built-in defaults + .lnkignore + CLI --ignore patterns.
+// Ignore pattern order: built-in defaults + .lnkignore + LNK_IGNORE + CLI --ignore patterns.
 func LoadConfig(sourceDir string, cliIgnorePatterns []string) (*Config, error) {
 	// Resolve sourceDir: expand tilde, then make absolute
 	resolvedDir, err := ExpandPath(sourceDir)
@@ -100,24 +151,125 @@ func LoadConfig(sourceDir string, cliIgnorePatterns []string) (*Config, error) {
 
 	PrintVerbose("Source directory: %s", ContractPath(resolvedDir))
 
+	// Check the lnk version the source directory requires before reading
+	// anything that might use newer settings
+	if err := checkSourceVersion(resolvedDir); err != nil {
+		return nil, err
+	}
+
 	// Load ignore patterns from .lnkignore file (if exists)
 	ignoreFilePatterns, err := LoadIgnoreFile(resolvedDir)
 	if err != nil {
 		return nil, err
 	}
 
-	// Combine ignore patterns: built-in + .lnkignore + CLI
+	env, err := LoadEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Combine ignore patterns: built-in + .lnkignore + private + LNK_IGNORE + CLI;
+	// LoadPrivate adds the private ones
 	ignorePatterns := []string{}
 	ignorePatterns = append(ignorePatterns, getBuiltInIgnorePatterns()...)
 	ignorePatterns = append(ignorePatterns, ignoreFilePatterns...)
+	privateIgnoreAt := len(ignorePatterns)
+	ignorePatterns = append(ignorePatterns, env.IgnorePatterns...)
 	ignorePatterns = append(ignorePatterns, cliIgnorePatterns...)
 
-	PrintVerbose("Ignore patterns: %d built-in, %d from .lnkignore, %d from CLI = %d total",
-		len(getBuiltInIgnorePatterns()), len(ignoreFilePatterns),
-		len(cliIgnorePatterns), len(ignorePatterns))
+	Trace("ignore patterns", "built_in", len(getBuiltInIgnorePatterns()), "lnkignore", len(ignoreFilePatterns),
+		"env", len(env.IgnorePatterns), "cli", len(cliIgnorePatterns), "total", len(ignorePatterns))
+
+	// Load default packages from .lnkpackages file (if exists)
+	packages, err := LoadPackagesFile(resolvedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Select packages by machine from .lnkprofiles (if exists), or by the
+	// profile LNK_PROFILE names
+	profile, err := selectProfile(resolvedDir, env.Profile)
+	if err != nil {
+		return nil, err
+	}
+	var profilePackages, envProfilePackages []string
+	if profile != nil && env.Profile != "" {
+		envProfilePackages = profile.Packages
+	} else if profile != nil {
+		profilePackages = profile.Packages
+	}
+
+	// Load local-only target patterns from .lnklocal file (if exists)
+	localOnly, err := LoadLocalOnlyFile(resolvedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load sensitive file patterns from .lnksensitive file (if exists)
+	sensitive, err := LoadSensitiveFile(resolvedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the parent directory policy from .lnkdirs file (if exists)
+	dirs, err := LoadDirPolicy(resolvedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the steps of lnk up and lnk down from .lnkworkflow file (if exists)
+	workflow, err := LoadWorkflow(resolvedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load protected target patterns from .lnkprotected file (if exists)
+	protected, err := LoadProtectedFile(resolvedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load saved mappings from .lnkmaps file (if exists)
+	maps, err := LoadMapsFile(resolvedDir)
+	if err != nil {
+		return nil, err
+	}
+	var mapSpecs []string
+	for _, m := range maps {
+		mapSpecs = append(mapSpecs, m.String())
+	}
+
+	_, ignoreFileErr := os.Stat(filepath.Join(resolvedDir, IgnoreFileName))
+	_, packagesFileErr := os.Stat(filepath.Join(resolvedDir, PackagesFileName))
+	_, profilesFileErr := os.Stat(filepath.Join(resolvedDir, ProfilesFileName))
+	_, localOnlyFileErr := os.Stat(filepath.Join(resolvedDir, LocalOnlyFileName))
+	_, sensitiveFileErr := os.Stat(filepath.Join(resolvedDir, SensitiveFileName))
+	_, dirsFileErr := os.Stat(filepath.Join(resolvedDir, DirsFileName))
+	_, mapsFileErr := os.Stat(filepath.Join(resolvedDir, MapsFileName))
+	_, workflowFileErr := os.Stat(filepath.Join(resolvedDir, WorkflowFileName))
+	_, protectedFileErr := os.Stat(filepath.Join(resolvedDir, ProtectedFileName))
+	sources := []ConfigSource{
+		{Name: "built-in", Setting: "ignore", Found: true, Values: getBuiltInIgnorePatterns()},
+		{Name: filepath.Join(resolvedDir, IgnoreFileName), Setting: "ignore", Found: ignoreFileErr == nil, Values: ignoreFilePatterns},
+		{Name: filepath.Join(resolvedDir, PackagesFileName), Setting: "packages", Found: packagesFileErr == nil, Values: packages},
+		{Name: filepath.Join(resolvedDir, ProfilesFileName), Setting: "packages", Found: profilesFileErr == nil, Values: profilePackages},
+		{Name: filepath.Join(resolvedDir, LocalOnlyFileName), Setting: "local-only", Found: localOnlyFileErr == nil, Values: localOnly},
+		{Name: filepath.Join(resolvedDir, SensitiveFileName), Setting: "sensitive", Found: sensitiveFileErr == nil, Values: sensitive},
+		{Name: filepath.Join(resolvedDir, DirsFileName), Setting: "dirs", Found: dirsFileErr == nil, Values: dirs.Entries()},
+		{Name: filepath.Join(resolvedDir, MapsFileName), Setting: "maps", Found: mapsFileErr == nil, Values: mapSpecs},
+		{Name: filepath.Join(resolvedDir, WorkflowFileName), Setting: "workflow", Found: workflowFileErr == nil, Values: workflow.Entries()},
+		{Name: "built-in", Setting: "protected", Found: true, Values: getBuiltInProtectedTargets()},
+		{Name: filepath.Join(resolvedDir, ProtectedFileName), Setting: "protected", Found: protectedFileErr == nil, Values: protected},
+	}
+	sources = append(sources, []ConfigSource{
+		{Name: EnvIgnore, Setting: "ignore", Found: len(env.IgnorePatterns) > 0, Values: env.IgnorePatterns},
+		{Name: EnvProfile, Setting: "packages", Found: env.Profile != "", Values: envProfilePackages},
+		{Name: EnvPackages, Setting: "packages", Found: len(env.Packages) > 0, Values: env.Packages},
+		{Name: "--ignore", Setting: "ignore", Found: len(cliIgnorePatterns) > 0, Values: cliIgnorePatterns},
+	}...)
 
-	// Resolve target directory (always ~)
-	targetDir, err := ExpandPath("~")
+	// Resolve target directory: LNK_TARGET_DIR, or ~
+	targetDir, err := env.ResolveTargetDir()
 	if err != nil {
 		return nil, err
 	}
@@ -125,10 +277,57 @@ func LoadConfig(sourceDir string, cliIgnorePatterns []string) (*Config, error) {
 	return &Config{
 		SourceDir:      resolvedDir,
 		TargetDir:      targetDir,
+		TargetFromEnv:  env.TargetDir != "",
 		IgnorePatterns: ignorePatterns,
+		Packages:       packages,
+		EnvPackages:    env.Packages,
+		Profile:        profile,
+		ProfileFromEnv: profile != nil && env.Profile != "",
+		LocalOnly:      localOnly,
+		Sensitive:      sensitive,
+		Dirs:           dirs,
+		Maps:           maps,
+		Workflow:       workflow,
+		Protected:      append(getBuiltInProtectedTargets(), protected...),
+		Sources:        sources,
+
+		privateIgnoreAt: privateIgnoreAt,
 	}, nil
 }
 
+// LoadPrivate decrypts the private configuration (if exists) and adds its
+// sections to the settings of the files they are named after. LoadConfig
+// leaves it out because decrypting can ask for a passphrase or a hardware key;
+// only commands that use these settings call LoadPrivate. Calling it again does
+// nothing.
+func (c *Config) LoadPrivate() error {
+	if c.privateLoaded {
+		return nil
+	}
+	private, err := LoadPrivateFile(c.SourceDir)
+	if err != nil {
+		return err
+	}
+	privateMapList, err := privateMaps(private)
+	if err != nil {
+		return err
+	}
+	c.privateLoaded = true
+
+	c.IgnorePatterns = slices.Insert(c.IgnorePatterns, c.privateIgnoreAt, private.Entries("ignore")...)
+	c.LocalOnly = append(c.LocalOnly, private.Entries("local-only")...)
+	c.Sensitive = append(c.Sensitive, private.Entries("sensitive")...)
+	c.Maps = append(c.Maps, privateMapList...)
+	c.Protected = append(c.Protected, private.Entries("protected")...)
+
+	// Private sources follow the files, before the environment and flags
+	at := slices.IndexFunc(c.Sources, func(s ConfigSource) bool {
+		return s.Name == filepath.Join(c.SourceDir, ProtectedFileName)
+	}) + 1
+	c.Sources = slices.Insert(c.Sources, at, privateSources(c.SourceDir, private)...)
+	return nil
+}
+
 // getBuiltInIgnorePatterns returns the built-in default ignore patterns
 func getBuiltInIgnorePatterns() []string {
 	return []string{
@@ -141,42 +340,66 @@ func getBuiltInIgnorePatterns() []string {
 		"LICENSE*",
 		"CHANGELOG*",
 		".lnkignore",
+		".lnkpackages",
+		".lnkprofiles",
+		".lnkrequires",
+		".lnklocal",
+		".lnksensitive",
+		".lnkdirs",
+		".lnkmaps",
+		".lnkworkflow",
+		".lnkprotected",
+		".lnkprivate.age",
+		".lnkprivate.gpg",
+		".lnkrecipients",
+		"lnk-package.json",
+		".lnk",
 	}
 }
 
 // ExpandPath expands ~ to the user's home directory
 func ExpandPath(path string) (string, error) {
+	return expandPathIn("", path)
+}
+
+// expandPathIn expands ~ to home, or to the user's home directory when home is
+// empty. Callers that take a Home option use it so ~ can mean another
+// directory without changing $HOME.
+func expandPathIn(home, path string) (string, error) {
 	if path == "~" || strings.HasPrefix(path, "~/") {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return "", NewPathErrorWithHint("get home directory", path, err,
-				"Check that the HOME environment variable is set correctly")
+		if home == "" {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return "", NewPathErrorWithHint("get home directory", path, err,
+					"Check that the HOME environment variable is set correctly")
+			}
+			home = homeDir
 		}
 		if path == "~" {
-			return homeDir, nil
+			return home, nil
 		}
-		return filepath.Join(homeDir, path[2:]), nil
+		return filepath.Join(home, path[2:]), nil
 	}
 	return path, nil
 }
 
-// ContractPath contracts the home directory to ~ in paths for display
+// ContractPath shortens paths for display: the home directory becomes ~, or
+// as SetPathDisplay selects, paths in the source directory become repo:<path>,
+// paths under XDG base directories $XDG_CONFIG_HOME/<path>, or nothing is
+// shortened at all
 func ContractPath(path string) string {
-	if path == "" {
+	if path == "" || pathDisplay.Absolute {
 		return path
 	}
-
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		// If we can't get home dir, return the original path
-		return path
+	if pathDisplay.Repo {
+		if contracted, ok := contractRepo(path); ok {
+			return contracted
+		}
 	}
-
-	// Check if path starts with home directory
-	if strings.HasPrefix(path, homeDir) {
-		// Replace home directory with ~ and clean up any double slashes
-		return filepath.Clean("~" + strings.TrimPrefix(path, homeDir))
+	if pathDisplay.XDG {
+		if contracted, ok := contractXDG(path); ok {
+			return contracted
+		}
 	}
-
-	return path
+	return contractHome(path)
 }
@@ -3,6 +3,7 @@ package lnk
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -332,6 +333,79 @@ func TestLoadConfigIgnorePatternOrder(t *testing.T) {
 	}
 }
 
+func TestLoadConfigSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, PackagesFileName), []byte("shell\nnvim\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(tmpDir, []string{"cli-pattern"})
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if err := config.LoadPrivate(); err != nil {
+		t.Fatalf("LoadPrivate() error = %v", err)
+	}
+
+	want := []struct {
+		name   string
+		found  bool
+		values int
+	}{
+		{"built-in", true, len(getBuiltInIgnorePatterns())},
+		{filepath.Join(config.SourceDir, IgnoreFileName), false, 0},
+		{filepath.Join(config.SourceDir, PackagesFileName), true, 2},
+		{filepath.Join(config.SourceDir, ProfilesFileName), false, 0},
+		{filepath.Join(config.SourceDir, LocalOnlyFileName), false, 0},
+		{filepath.Join(config.SourceDir, SensitiveFileName), false, 0},
+		{filepath.Join(config.SourceDir, DirsFileName), false, 0},
+		{filepath.Join(config.SourceDir, MapsFileName), false, 0},
+		{filepath.Join(config.SourceDir, WorkflowFileName), false, 0},
+		{"built-in", true, len(getBuiltInProtectedTargets())},
+		{filepath.Join(config.SourceDir, ProtectedFileName), false, 0},
+		{filepath.Join(config.SourceDir, PrivateFileName+".age"), false, 0},
+		{EnvIgnore, false, 0},
+		{EnvProfile, false, 0},
+		{EnvPackages, false, 0},
+		{"--ignore", true, 1},
+	}
+	if len(config.Sources) != len(want) {
+		t.Fatalf("LoadConfig() Sources = %+v, want %d sources", config.Sources, len(want))
+	}
+	for i, w := range want {
+		got := config.Sources[i]
+		if got.Name != w.name || got.Found != w.found || len(got.Values) != w.values {
+			t.Errorf("Sources[%d] = %+v, want name=%s found=%v values=%d", i, got, w.name, w.found, w.values)
+		}
+	}
+}
+
+func TestResolvePackages(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     Config
+		cli        []string
+		want       []string
+		wantSource string
+	}{
+		{"flag wins", Config{Packages: []string{"shell"}}, []string{"nvim"}, []string{"nvim"}, "--packages"},
+		{"packages file", Config{Packages: []string{"shell"}}, nil, []string{"shell"}, PackagesFileName},
+		{"profile over packages file", Config{Packages: []string{"shell"}, Profile: &ProfileRule{Name: "work", Packages: []string{"work"}}},
+			nil, []string{"work"}, ProfilesFileName},
+		{"named profile", Config{Profile: &ProfileRule{Name: "work", Packages: []string{"work"}}, ProfileFromEnv: true},
+			nil, []string{"work"}, EnvProfile},
+		{"default", Config{}, nil, nil, "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source := tt.config.ResolvePackages(tt.cli)
+			if !slices.Equal(got, tt.want) || source != tt.wantSource {
+				t.Errorf("ResolvePackages() = %v, %q, want %v, %q", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
 func TestExpandPath(t *testing.T) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
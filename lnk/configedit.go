@@ -0,0 +1,480 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Config edit actions, for 'lnk config set|unset|append'
+const (
+	ConfigSet    = "set"
+	ConfigUnset  = "unset"
+	ConfigAppend = "append"
+)
+
+// configListFiles maps the config paths that name list files in the source
+// directory to those files
+var configListFiles = map[string]string{
+	"ignore":    IgnoreFileName,
+	"packages":  PackagesFileName,
+	"mappings":  MapsFileName,
+	"local":     LocalOnlyFileName,
+	"protected": ProtectedFileName,
+	"sensitive": SensitiveFileName,
+}
+
+// ConfigEdit is one change to the configuration in a source directory
+type ConfigEdit struct {
+	Action string   // ConfigSet, ConfigUnset, or ConfigAppend
+	Path   string   // a list ("packages"), or a key in lnk-package.json ("package.nvim.description", "source.keep_local")
+	Values []string // the values to set, append, or remove; none for unset removes them all
+	DryRun bool     // print the change instead of writing it
+}
+
+// EditConfig changes the configuration in sourceDir, so repositories can be
+// set up by scripts. List files keep their comments and the order of the other
+// entries; lnk-package.json keeps the order of its keys and its indentation,
+// and the result must be valid metadata.
+func EditConfig(sourceDir string, edit ConfigEdit) error {
+	PrintCommandHeader("Editing Configuration")
+
+	if file, ok := configListFiles[edit.Path]; ok {
+		return editListFile(sourceDir, filepath.Join(sourceDir, file), edit)
+	}
+	scope, key, _ := strings.Cut(edit.Path, ".")
+	var dir string
+	switch scope {
+	case "source":
+		dir = sourceDir
+	case "package":
+		var name string
+		name, key, _ = strings.Cut(key, ".")
+		pkg, err := cleanPackageName(sourceDir, name)
+		if err != nil {
+			return err
+		}
+		if dir = filepath.Join(sourceDir, pkg); !isDir(dir) {
+			return NewValidationErrorWithHint("path", edit.Path,
+				fmt.Sprintf("package %s does not exist", name), packagesHint(sourceDir))
+		}
+	}
+	if dir == "" || key == "" {
+		return NewValidationErrorWithHint("path", edit.Path, "unknown configuration path",
+			fmt.Sprintf("Use %s, package.<name>.<key>, or source.<key>", strings.Join(sortedKeys(configListFiles), ", ")))
+	}
+	return editPackageInfo(filepath.Join(dir, PackageInfoFileName), strings.Split(key, "."), edit)
+}
+
+// editListFile edits a file with one entry per line. Comments and blank lines
+// are kept; set puts its values where the first entry was.
+func editListFile(sourceDir, path string, edit ConfigEdit) error {
+	if edit.Action != ConfigUnset && len(edit.Values) == 0 {
+		return WithHint(fmt.Errorf("config %s %s needs a value", edit.Action, edit.Path),
+			fmt.Sprintf("Usage: lnk config %s <source-dir> %s <value>...", edit.Action, edit.Path))
+	}
+	values := make([]string, len(edit.Values))
+	for i, v := range edit.Values {
+		entry, err := configListEntry(sourceDir, edit.Path, v)
+		if err != nil {
+			return err
+		}
+		values[i] = entry
+	}
+
+	data, err := fsys.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return NewPathErrorWithHint("read config", path, err, "Check file permissions")
+	}
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	isEntry := func(line string) bool {
+		line = strings.TrimSpace(line)
+		return line != "" && !strings.HasPrefix(line, "#")
+	}
+
+	var kept []string
+	var changed []string
+	switch edit.Action {
+	case ConfigAppend:
+		kept = lines
+		for _, v := range values {
+			if slices.ContainsFunc(kept, func(line string) bool { return strings.TrimSpace(line) == v }) {
+				PrintSkip("Already in %s: %s", edit.Path, v)
+				continue
+			}
+			kept = append(kept, v)
+			changed = append(changed, v)
+		}
+	case ConfigUnset:
+		for _, line := range lines {
+			if isEntry(line) && (len(values) == 0 || slices.Contains(values, strings.TrimSpace(line))) {
+				changed = append(changed, strings.TrimSpace(line))
+				continue
+			}
+			kept = append(kept, line)
+		}
+		for _, v := range values {
+			if !slices.Contains(changed, v) {
+				PrintSkip("Not in %s: %s", edit.Path, v)
+			}
+		}
+	case ConfigSet:
+		inserted := false
+		for _, line := range lines {
+			if !isEntry(line) {
+				kept = append(kept, line)
+			} else if !inserted {
+				kept = append(kept, values...)
+				inserted = true
+			}
+		}
+		if !inserted {
+			kept = append(kept, values...)
+		}
+		if !slices.Equal(kept, lines) {
+			changed = values
+		}
+	}
+	if len(changed) == 0 {
+		PrintSummary("Configuration unchanged")
+		return nil
+	}
+
+	past := configEditPast(edit.Action)
+	if edit.DryRun {
+		for _, v := range changed {
+			PrintDryRun("Would %s %s: %s", edit.Action, edit.Path, v)
+		}
+		PrintDryRunSummary()
+		return nil
+	}
+	if err := checkWritable("edit config", path); err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(kept, func(line string) bool { return strings.TrimSpace(line) != "" }) {
+		if err := fsys.Remove(path); err != nil && !os.IsNotExist(err) {
+			return NewPathErrorWithHint("edit config", path, err, "Check that the source directory is writable")
+		}
+	} else if err := writeFileAtomic(path, []byte(strings.Join(kept, "\n")+"\n"), 0644); err != nil {
+		return NewPathErrorWithHint("edit config", path, err, "Check that the source directory is writable")
+	}
+	for _, v := range changed {
+		PrintSuccess("%s %s: %s", past, edit.Path, v)
+	}
+	PrintSummary("Updated %s", ContractPath(path))
+	return nil
+}
+
+// configListEntry checks a value for a list file and returns the line it is
+// written as. Mappings are given as SRC:TGT[:MODE], as --map takes them, or as
+// a JSON object with source, target, mode, and groups.
+func configListEntry(sourceDir, list, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch list {
+	case "packages":
+		return cleanPackageName(sourceDir, value)
+	case "mappings":
+		if strings.HasPrefix(value, "{") {
+			var m struct {
+				Source string   `json:"source"`
+				Target string   `json:"target"`
+				Mode   string   `json:"mode"`
+				Groups []string `json:"groups"`
+			}
+			if err := json.Unmarshal([]byte(value), &m); err != nil {
+				return "", NewValidationErrorWithHint("mappings", value, err.Error(),
+					`Example: {"source": "projects/foo/config", "target": ".config/foo"}`)
+			}
+			value = Mapping{Source: m.Source, Target: m.Target, Mode: m.Mode, Groups: m.Groups}.String()
+		}
+		m, err := ParseMapping(value)
+		if err != nil {
+			return "", err
+		}
+		return m.String(), nil
+	}
+	if value == "" || strings.HasPrefix(value, "#") {
+		return "", NewValidationErrorWithHint(list, value, "must be a non-empty entry", "Give the entry without a leading #")
+	}
+	return value, nil
+}
+
+// editPackageInfo edits the value at keys in a lnk-package.json file. Values
+// are JSON, or strings when they are not valid JSON. append adds to an array
+// (creating it); unset removes the key, or with values those array elements.
+func editPackageInfo(path string, keys []string, edit ConfigEdit) error {
+	data, err := fsys.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return NewPathErrorWithHint("read package metadata", path, err, "Check file permissions")
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = []byte("{}")
+	}
+	if edit.Action == ConfigSet && len(edit.Values) != 1 {
+		return WithHint(fmt.Errorf("config set %s takes exactly one value", edit.Path),
+			"Give arrays and objects as JSON, such as '[\"linux\", \"darwin\"]'")
+	}
+	if edit.Action == ConfigAppend && len(edit.Values) == 0 {
+		return WithHint(fmt.Errorf("config append %s needs a value", edit.Path),
+			fmt.Sprintf("Usage: lnk config append <source-dir> %s <value>...", edit.Path))
+	}
+	values := make([]json.RawMessage, len(edit.Values))
+	for i, v := range edit.Values {
+		values[i] = configJSONValue(v)
+	}
+
+	edited, changed, err := editJSONValue(data, keys, func(old json.RawMessage) (json.RawMessage, bool, error) {
+		return editJSONLeaf(old, edit.Action, values)
+	})
+	if err != nil {
+		return NewPathErrorWithHint("edit package metadata", path, err,
+			fmt.Sprintf("Check that %s names the right key and that %s is valid JSON", edit.Path, ContractPath(path)))
+	}
+	if !changed {
+		PrintSummary("Configuration unchanged")
+		return nil
+	}
+	if err := validatePackageInfo(path, edited); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, edited, "", jsonIndent(data)); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	key := strings.Join(keys, ".")
+	if edit.DryRun {
+		PrintDryRun("Would %s %s in %s", edit.Action, key, ContractPath(path))
+		PrintDryRunSummary()
+		return nil
+	}
+	if err := checkWritable("edit package metadata", path); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, out.Bytes(), 0644); err != nil {
+		return NewPathErrorWithHint("edit package metadata", path, err, "Check that the package directory is writable")
+	}
+	PrintSuccess("%s %s in %s", configEditPast(edit.Action), key, ContractPath(path))
+	PrintSummary("Updated %s", ContractPath(path))
+	return nil
+}
+
+// configJSONValue reads a value given on the command line: JSON as it is, and
+// anything else as a string
+func configJSONValue(v string) json.RawMessage {
+	if json.Valid([]byte(v)) {
+		var out bytes.Buffer
+		json.Compact(&out, []byte(v))
+		return out.Bytes()
+	}
+	data, _ := marshalNoEscape(v)
+	return data
+}
+
+// editJSONLeaf applies an action to the value at the end of a path (nil when
+// it is not set), returning the new value (nil to remove the key) and whether
+// anything changed
+func editJSONLeaf(old json.RawMessage, action string, values []json.RawMessage) (json.RawMessage, bool, error) {
+	if action == ConfigSet {
+		return values[0], !bytes.Equal(old, values[0]), nil
+	}
+	if action == ConfigUnset && len(values) == 0 {
+		return nil, old != nil, nil
+	}
+
+	var items []json.RawMessage
+	if old != nil {
+		if err := json.Unmarshal(old, &items); err != nil {
+			return nil, false, fmt.Errorf("%s only works on arrays", action)
+		}
+	}
+	changed := false
+	for _, v := range values {
+		i := slices.IndexFunc(items, func(item json.RawMessage) bool { return bytes.Equal(item, v) })
+		switch {
+		case action == ConfigAppend && i < 0:
+			items = append(items, v)
+			changed = true
+		case action == ConfigAppend:
+			PrintSkip("Already present: %s", v)
+		case i >= 0:
+			items = slices.Delete(items, i, i+1)
+			changed = true
+		default:
+			PrintSkip("Not present: %s", v)
+		}
+	}
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+	data, err := json.Marshal(items)
+	return data, changed, err
+}
+
+// editJSONValue edits the value at keys inside the JSON object data, creating
+// objects on the way for a new value, and returns the result compacted. Keys
+// keep their order and new keys go last.
+func editJSONValue(data []byte, keys []string, edit func(old json.RawMessage) (json.RawMessage, bool, error)) (json.RawMessage, bool, error) {
+	obj, err := parseJSONObject(data)
+	if err != nil {
+		return nil, false, err
+	}
+	key := keys[0]
+	old, ok := obj.values[key]
+	var value json.RawMessage
+	var changed bool
+	if len(keys) == 1 {
+		var current json.RawMessage
+		if ok {
+			var compact bytes.Buffer
+			json.Compact(&compact, old)
+			current = compact.Bytes()
+		}
+		value, changed, err = edit(current)
+	} else {
+		if !ok {
+			old = json.RawMessage("{}")
+		}
+		value, changed, err = editJSONValue(old, keys[1:], edit)
+		if err == nil && !ok && !changed {
+			value = nil // nothing to remove below a key that is not set
+		}
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", key, err)
+	}
+	obj.set(key, value)
+	out, err := obj.MarshalJSON()
+	return out, changed, err
+}
+
+// jsonObject is a JSON object that keeps the order of its keys
+type jsonObject struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+// parseJSONObject reads a JSON object, keeping its keys in order
+func parseJSONObject(data []byte) (*jsonObject, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+	obj := &jsonObject{values: map[string]json.RawMessage{}}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if _, ok := obj.values[key]; !ok {
+			obj.keys = append(obj.keys, key)
+		}
+		obj.values[key] = value
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// set sets key to value, or removes it when value is nil
+func (o *jsonObject) set(key string, value json.RawMessage) {
+	_, ok := o.values[key]
+	switch {
+	case value == nil:
+		delete(o.values, key)
+		o.keys = slices.DeleteFunc(o.keys, func(k string) bool { return k == key })
+	case !ok:
+		o.keys = append(o.keys, key)
+		fallthrough
+	default:
+		o.values[key] = value
+	}
+}
+
+// MarshalJSON writes the object compactly, in key order
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := marshalNoEscape(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		if err := json.Compact(&buf, o.values[key]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalNoEscape marshals v without escaping <, >, and &, which config files
+// have no reason to
+func marshalNoEscape(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonIndent returns the indentation of the first indented line in data, so
+// an edited file keeps it; two spaces if there is none
+func jsonIndent(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		if indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]; indent != "" && indent != line {
+			return indent
+		}
+	}
+	return "  "
+}
+
+// validatePackageInfo checks that edited metadata still decodes as
+// PackageInfo and has no unknown keys: an edit never writes a file the next
+// run would reject or ignore
+func validatePackageInfo(path string, data []byte) error {
+	var info PackageInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return NewPathErrorWithHint("edit package metadata", path, err,
+			fmt.Sprintf("See the keys and types of %s in the README", PackageInfoFileName))
+	}
+	var raw any
+	json.Unmarshal(data, &raw)
+	if errs := unknownKeys(raw, reflect.TypeOf(PackageInfo{}), "", path); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// configEditPast describes a finished edit in output
+func configEditPast(action string) string {
+	switch action {
+	case ConfigSet:
+		return "Set"
+	case ConfigUnset:
+		return "Unset"
+	}
+	return "Appended to"
+}
@@ -0,0 +1,138 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEditConfigListFile(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, name := range []string{"shell", "nvim", "git"} {
+		if err := os.Mkdir(filepath.Join(sourceDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	packages := filepath.Join(sourceDir, PackagesFileName)
+	createTestFile(t, packages, "# default packages\nshell\n\n# editors\nnvim\n")
+
+	for _, tt := range []struct {
+		name string
+		edit ConfigEdit
+		want string
+	}{
+		{"set keeps comments", ConfigEdit{Action: ConfigSet, Path: "packages", Values: []string{"git", "shell"}},
+			"# default packages\ngit\nshell\n\n# editors\n"},
+		{"append skips present values", ConfigEdit{Action: ConfigAppend, Path: "packages", Values: []string{"shell", "nvim"}},
+			"# default packages\ngit\nshell\n\n# editors\nnvim\n"},
+		{"unset values", ConfigEdit{Action: ConfigUnset, Path: "packages", Values: []string{"git"}},
+			"# default packages\nshell\n\n# editors\nnvim\n"},
+		{"dry run", ConfigEdit{Action: ConfigUnset, Path: "packages", DryRun: true},
+			"# default packages\nshell\n\n# editors\nnvim\n"},
+		{"unset all", ConfigEdit{Action: ConfigUnset, Path: "packages"},
+			"# default packages\n\n# editors\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			CaptureOutput(t, func() {
+				if err := EditConfig(sourceDir, tt.edit); err != nil {
+					t.Fatalf("EditConfig() error = %v", err)
+				}
+			})
+			assertDiskContent(t, packages, tt.want)
+		})
+	}
+
+	// Mappings are accepted as on the command line or as JSON
+	CaptureOutput(t, func() {
+		for _, v := range []string{"work/nvim:.config/nvim", `{"source": "fonts", "target": ".local/share/fonts/", "mode": "merge_into"}`} {
+			if err := EditConfig(sourceDir, ConfigEdit{Action: ConfigAppend, Path: "mappings", Values: []string{v}}); err != nil {
+				t.Fatalf("EditConfig(%s) error = %v", v, err)
+			}
+		}
+	})
+	assertDiskContent(t, filepath.Join(sourceDir, MapsFileName), "work/nvim:.config/nvim\nfonts:.local/share/fonts/:merge_into\n")
+
+	// Removing the last entry removes the file
+	CaptureOutput(t, func() {
+		if err := EditConfig(sourceDir, ConfigEdit{Action: ConfigUnset, Path: "mappings"}); err != nil {
+			t.Fatalf("EditConfig() error = %v", err)
+		}
+	})
+	if exists(filepath.Join(sourceDir, MapsFileName)) {
+		t.Error("unset of every mapping should remove .lnkmaps")
+	}
+
+	for _, edit := range []ConfigEdit{
+		{Action: ConfigAppend, Path: "packages", Values: []string{"nvim/lua"}},
+		{Action: ConfigAppend, Path: "mappings", Values: []string{"no-target"}},
+		{Action: ConfigAppend, Path: "ignore"},
+		{Action: ConfigSet, Path: "unknown", Values: []string{"x"}},
+	} {
+		if err := EditConfig(sourceDir, edit); err == nil {
+			t.Errorf("EditConfig(%+v) error = nil, want an error", edit)
+		}
+	}
+}
+
+func TestEditConfigPackageInfo(t *testing.T) {
+	sourceDir := t.TempDir()
+	info := filepath.Join(sourceDir, "nvim", PackageInfoFileName)
+	createTestFile(t, info, "{\n    \"platforms\": [\"linux\"],\n    \"description\": \"editor\"\n}\n")
+	edit := func(action, path string, values ...string) error {
+		var err error
+		CaptureOutput(t, func() {
+			err = EditConfig(sourceDir, ConfigEdit{Action: action, Path: path, Values: values})
+		})
+		return err
+	}
+
+	for _, e := range []struct {
+		action, path string
+		values       []string
+	}{
+		{ConfigSet, "package.nvim.description", []string{"Neovim <setup>"}},
+		{ConfigAppend, "package.nvim.platforms", []string{"darwin", "linux"}},
+		{ConfigSet, "package.nvim.when.command_exists", []string{`["nvim"]`}},
+		{ConfigAppend, "source.keep_local", []string{"shell/.bashrc"}},
+	} {
+		if err := edit(e.action, e.path, e.values...); err != nil {
+			t.Fatalf("%s %s error = %v", e.action, e.path, err)
+		}
+	}
+	// Keys keep their order and the file its indentation
+	assertDiskContent(t, info, `{
+    "platforms": [
+        "linux",
+        "darwin"
+    ],
+    "description": "Neovim <setup>",
+    "when": {
+        "command_exists": [
+            "nvim"
+        ]
+    }
+}
+`)
+	assertDiskContent(t, filepath.Join(sourceDir, PackageInfoFileName), "{\n  \"keep_local\": [\n    \"shell/.bashrc\"\n  ]\n}\n")
+
+	if err := edit(ConfigUnset, "package.nvim.platforms", "linux"); err != nil {
+		t.Fatal(err)
+	}
+	if err := edit(ConfigUnset, "package.nvim.when"); err != nil {
+		t.Fatal(err)
+	}
+	assertDiskContent(t, info, "{\n    \"platforms\": [\n        \"darwin\"\n    ],\n    \"description\": \"Neovim <setup>\"\n}\n")
+
+	// Edits that would leave invalid metadata are refused
+	for _, e := range [][]string{
+		{ConfigSet, "package.nvim.descriptoin", "x"},
+		{ConfigSet, "package.nvim.platforms", "linux"},
+		{ConfigAppend, "package.nvim.description", "x"},
+		{ConfigSet, "package.missing.description", "x"},
+	} {
+		if err := edit(e[0], e[1], e[2:]...); err == nil {
+			t.Errorf("%v error = nil, want an error", e)
+		}
+	}
+	assertDiskContent(t, info, "{\n    \"platforms\": [\n        \"darwin\"\n    ],\n    \"description\": \"Neovim <setup>\"\n}\n")
+}
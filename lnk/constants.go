@@ -9,9 +9,31 @@ const (
 
 // Configuration file names
 const (
-	IgnoreFileName = ".lnkignore" // Gitignore-style ignore file
+	IgnoreFileName         = ".lnkignore"       // Gitignore-style ignore file
+	PackagesFileName       = ".lnkpackages"     // Default packages to link, one per line
+	ProfilesFileName       = ".lnkprofiles"     // Rules choosing packages by machine, JSON
+	RequiresFileName       = ".lnkrequires"     // Packages a package depends on, one per line
+	LocalOnlyFileName      = ".lnklocal"        // Target paths lnk never touches, gitignore syntax
+	SensitiveFileName      = ".lnksensitive"    // Files that must not be stored in plaintext, gitignore syntax
+	DirsFileName           = ".lnkdirs"         // How lnk creates missing parent directories, JSON
+	MapsFileName           = ".lnkmaps"         // Saved mappings, one SRC:TGT[:MODE] per line
+	WorkflowFileName       = ".lnkworkflow"     // Steps lnk up and lnk down run, JSON
+	ProtectedFileName      = ".lnkprotected"    // Target paths lnk never creates, removes, or overwrites, gitignore syntax
+	PrivateFileName        = ".lnkprivate"      // Encrypted private configuration, as .lnkprivate.age or .lnkprivate.gpg
+	RecipientsFileName     = ".lnkrecipients"   // Public keys .lnkprivate.age is encrypted to, one per line
+	PackageInfoFileName    = "lnk-package.json" // Optional package metadata
+	VarsDirName            = ".lnk"             // Template variables: data.yaml and data.d/<hostname>.yaml
+	BundleManifestFileName = "lnk-bundle.json"  // Describes the contents of a bundle
+	ManifestFileName       = "manifest.json"    // State file recording what lnk created
+	JournalFileName        = "journal.json"     // State file recording an unfinished transaction
+	StatsFileName          = "stats.json"       // State file of opt-in local usage statistics
+	OnboardingFileName     = "source"           // User config file naming the source directory first-run onboarding set up
 )
 
+// LinkTagAttr is the extended attribute naming the source directory that
+// created a symlink, on platforms that allow attributes on symlinks
+const LinkTagAttr = "user.lnk.source"
+
 // Terminal output formatting
 const (
 	DryRunPrefix = "[DRY RUN]"
@@ -0,0 +1,388 @@
+package lnk
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// recordCopies marks links that 'lnk orphan --to-copy' replaced with copies:
+// their link records are dropped and the copies recorded in the manifest for
+// targetDir. Failure is a warning because the copies themselves succeeded.
+func recordCopies(targetDir, sourceDir string, copies []ManagedLink) {
+	if len(copies) == 0 {
+		return
+	}
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to record copies: %w", err))
+		return
+	}
+	paths := make([]string, len(copies))
+	for i, c := range copies {
+		paths[i] = c.Path
+		hash := copyHash(c.Path)
+		m.AddCopy(c.Path, sourceDir, c.Target, hash)
+		if _, ok := mergeBothWays(sourceDir, c.Target); ok {
+			saveCopyBase(targetDir, c.Path, hash)
+		}
+	}
+	m.RemoveLinks(paths)
+	if err := m.Save(targetDir); err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to record copies: %w", err))
+		return
+	}
+	PrintVerbose("Recorded %d copies in %s", len(copies), ContractPath(ManifestPath(targetDir)))
+}
+
+// loadCopies returns the copies recorded for sourceDir in the manifest for
+// targetDir. A manifest that cannot be read is treated as having none.
+func loadCopies(targetDir, sourceDir string) []ManifestCopy {
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		PrintVerbose("Failed to read copies from manifest: %v", err)
+		return nil
+	}
+	var copies []ManifestCopy
+	for _, c := range m.Copies {
+		if c.Source == sourceDir {
+			copies = append(copies, c)
+		}
+	}
+	return copies
+}
+
+// copyManaged reports whether target is, or is inside, a recorded copy
+func copyManaged(copies []ManifestCopy, target string) bool {
+	for _, c := range copies {
+		if target == c.Path || strings.HasPrefix(target, c.Path+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterCopyManaged drops planned links whose target is managed as a copy,
+// so create does not link over it and status does not report it as a conflict
+func filterCopyManaged(links []PlannedLink, copies []ManifestCopy) (kept, copied []PlannedLink) {
+	if len(copies) == 0 {
+		return links, nil
+	}
+	for _, link := range links {
+		if copyManaged(copies, link.Target) {
+			PrintVerbose("Copy-managed: %s", ContractPath(link.Target))
+			copied = append(copied, link)
+			continue
+		}
+		kept = append(kept, link)
+	}
+	return kept, copied
+}
+
+// printCopies lists copy-managed paths for status, marking file copies that
+// no longer match their source
+func printCopies(copies []ManifestCopy) {
+	if len(copies) == 0 {
+		return
+	}
+	stale := 0
+	if !ShouldSimplifyOutput() {
+		fmt.Println()
+	}
+	for _, c := range copies {
+		state := copyState(c)
+		if state != "copy" {
+			stale++
+		}
+		switch {
+		case ShouldSimplifyOutput():
+			fmt.Printf("%s %s\n", state, ContractPath(c.Path))
+		case state == "copy-missing":
+			PrintWarning("Copy missing: %s (copy of %s)", ContractPath(c.Path), ContractPath(c.Dest))
+		case state == "copy-stale":
+			PrintWarning("Copy out of date: %s (differs from %s)", ContractPath(c.Path), ContractPath(c.Dest))
+		default:
+			PrintInfo("Copy: %s (copy of %s)", ContractPath(c.Path), ContractPath(c.Dest))
+		}
+	}
+	SummaryCount("copies", len(copies))
+	SummaryCount("stale_copies", stale)
+}
+
+// copyState compares a copy with its source: "copy" when it matches (or is a
+// directory, which is not compared), "copy-stale" when a file copy differs,
+// and "copy-missing" when either is gone
+func copyState(c ManifestCopy) string {
+	equal, err := filesEqual(c.Path, c.Dest)
+	switch {
+	case err != nil:
+		return "copy-missing"
+	case !equal && !isDir(c.Dest):
+		return "copy-stale"
+	}
+	return "copy"
+}
+
+// isDir reports whether path is a directory
+func isDir(path string) bool {
+	info, err := fsys.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// copyHash returns the hex SHA-256 of a file copy, or "" for a directory or a
+// file that cannot be read
+func copyHash(path string) string {
+	if isDir(path) {
+		return ""
+	}
+	sum, err := hashFile(path)
+	if err != nil {
+		PrintVerbose("Failed to hash %s: %v", ContractPath(path), err)
+		return ""
+	}
+	return hex.EncodeToString(sum)
+}
+
+// backupPath is where a locally modified copy is moved before sync overwrites
+// it with --force-overwrite
+func backupPath(path string) string {
+	return fmt.Sprintf("%s.lnk-backup-%s", path, time.Now().Format("20060102-150405"))
+}
+
+// refreshCopies brings the file copies recorded for sourceDir up to date with
+// their sources after a sync. A copy whose hash no longer matches the one
+// recorded when lnk wrote it was edited locally: it is left alone and reported,
+// or with force moved to a backup and overwritten. Copies of files matching a
+// package's keep_local patterns are never overwritten, and copies of files
+// matching its merge patterns are merged both ways (see mergeCopy). Directory
+// copies are not refreshed.
+func refreshCopies(sourceDir, targetDir string, force bool) error {
+	copies := loadCopies(targetDir, sourceDir)
+	if len(copies) == 0 {
+		return nil
+	}
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to refresh copies: %w", err))
+		return nil
+	}
+
+	var updated, merged, kept, modified int
+	for _, c := range copies {
+		_, merge := mergeBothWays(sourceDir, c.Dest)
+		if merge {
+			// Copies recorded before they were marked for merging have no
+			// base yet; an unedited copy is one
+			saveCopyBase(targetDir, c.Path, c.Hash)
+		}
+		if copyState(c) != "copy-stale" {
+			continue
+		}
+		if pattern, ok := keepLocal(sourceDir, c.Dest); ok {
+			PrintSkip("Kept local: %s (keep_local %s)", ContractPath(c.Path), pattern)
+			kept++
+			continue
+		}
+		if !materializeCopySource(sourceDir, c.Dest) {
+			PrintWarningWithHint(NewPathErrorWithHint("update copy", c.Path, errLFSPointer,
+				"Run 'git lfs pull' in the repository, then run 'lnk sync' again"))
+			continue
+		}
+		if merge {
+			if hash, ok := mergeCopy(targetDir, c); ok {
+				m.AddCopy(c.Path, c.Source, c.Dest, hash)
+				dropCopyBase(targetDir, m, c.Hash)
+				merged++
+				continue
+			}
+		}
+		if copyHash(c.Path) != c.Hash {
+			if !force {
+				err := NewPathErrorWithHint("update copy", c.Path, fmt.Errorf("modified since lnk copied it; not overwritten"),
+					fmt.Sprintf("Use --force-overwrite to back it up and replace it, or add it to keep_local in %s",
+						PackageInfoFileName))
+				if merge {
+					err = NewPathErrorWithHint("merge copy", c.Path, fmt.Errorf("edited both here and in the repository; not merged"),
+						"Merge the copy and its source by hand, or use --force-overwrite to back it up and replace it")
+				}
+				PrintWarningWithHint(err)
+				modified++
+				continue
+			}
+			backup := backupPath(c.Path)
+			if err := fsys.Rename(c.Path, backup); err != nil {
+				PrintWarningWithHint(NewPathError("back up", c.Path, err))
+				modified++
+				continue
+			}
+			PrintInfo("Backed up: %s", ContractPath(backup))
+		}
+		if err := copyFile(c.Dest, c.Path); err != nil {
+			PrintWarningWithHint(NewPathErrorWithHint("update copy", c.Path, err,
+				"Check disk space and file permissions"))
+			modified++
+			continue
+		}
+		hash := copyHash(c.Path)
+		m.AddCopy(c.Path, c.Source, c.Dest, hash)
+		if merge {
+			saveCopyBase(targetDir, c.Path, hash)
+			dropCopyBase(targetDir, m, c.Hash)
+		}
+		PrintSuccess("Updated copy: %s", ContractPath(c.Path))
+		updated++
+	}
+
+	if updated+merged > 0 {
+		if err := m.Save(targetDir); err != nil {
+			PrintWarningWithHint(fmt.Errorf("Failed to record updated copies: %w", err))
+		}
+	}
+	if merged > 0 {
+		PrintInfo("Commit the changes merged into %s to share them", ContractPath(sourceDir))
+	}
+	SummaryCount("updated_copies", updated)
+	SummaryCount("merged_copies", merged)
+	SummaryCount("kept_copies", kept)
+	if modified > 0 {
+		return WithHint(fmt.Errorf("%d copy(ies) not updated", modified),
+			"Review the local changes, then rerun 'lnk sync --force-overwrite' to replace them")
+	}
+	return nil
+}
+
+// mergeCopy merges a copy that differs from its source, for a file matching a
+// merge pattern, and returns the hash both now have. The base is the copy as
+// lnk last wrote it, kept in the state directory: when only the copy changed
+// since, it is copied into the repository; when only the source changed, the
+// caller updates the copy as usual; when both changed, git merge-file merges
+// them into both. Without a base, or when the changes conflict, nothing is
+// written and false is returned, so the caller treats the copy as edited.
+func mergeCopy(targetDir string, c ManifestCopy) (string, bool) {
+	local, source := copyHash(c.Path), copyHash(c.Dest)
+	switch {
+	case c.Hash == "" || local == c.Hash:
+		return "", false
+	case source == c.Hash:
+		if err := copyFile(c.Path, c.Dest); err != nil {
+			PrintWarningWithHint(NewPathErrorWithHint("update source", c.Dest, err,
+				"Check file permissions in the source directory"))
+			return "", false
+		}
+		saveCopyBase(targetDir, c.Path, local)
+		PrintSuccess("Updated source from copy: %s", ContractPath(c.Dest))
+		return local, true
+	}
+
+	base := copyBasePath(targetDir, c.Hash)
+	if !exists(base) {
+		PrintVerbose("No merge base for %s", ContractPath(c.Path))
+		return "", false
+	}
+	merged, conflicts, err := gitMergeFile(c.Path, base, c.Dest)
+	switch {
+	case err != nil:
+		PrintVerbose("Failed to merge %s: %v", ContractPath(c.Path), err)
+		return "", false
+	case conflicts > 0:
+		PrintVerbose("%d conflict(s) merging %s with %s", conflicts, ContractPath(c.Path), ContractPath(c.Dest))
+		return "", false
+	}
+	for _, path := range []string{c.Dest, c.Path} {
+		info, err := fsys.Stat(path)
+		if err == nil {
+			err = writeFileAtomic(path, merged, info.Mode().Perm())
+		}
+		if err != nil {
+			PrintWarningWithHint(NewPathErrorWithHint("write merged copy", path, err,
+				"Check disk space and file permissions"))
+			return "", false
+		}
+	}
+	hash := copyHash(c.Path)
+	saveCopyBase(targetDir, c.Path, hash)
+	PrintSuccess("Merged copy: %s (with %s)", ContractPath(c.Path), ContractPath(c.Dest))
+	return hash, true
+}
+
+// copyBasePath is where the copy with the given hash is kept as a merge base
+func copyBasePath(targetDir, hash string) string {
+	return filepath.Join(StateDir(targetDir), "merge-bases", hash)
+}
+
+// saveCopyBase keeps the file copy at path as the merge base for its next
+// sync, if it still has the hash lnk recorded for it. Failure is verbose: the
+// next merge falls back to treating the copy as edited.
+func saveCopyBase(targetDir, path, hash string) {
+	base := copyBasePath(targetDir, hash)
+	if hash == "" || exists(base) || copyHash(path) != hash {
+		return
+	}
+	data, err := fsys.ReadFile(path)
+	if err == nil {
+		err = fsys.MkdirAll(filepath.Dir(base), 0700)
+	}
+	if err == nil {
+		err = writeFileAtomic(base, data, 0600)
+	}
+	if err != nil {
+		PrintVerbose("Failed to keep merge base for %s: %v", ContractPath(path), err)
+	}
+}
+
+// dropCopyBase removes the merge base with the given hash once no copy in m
+// records it
+func dropCopyBase(targetDir string, m *Manifest, hash string) {
+	if hash == "" {
+		return
+	}
+	for _, c := range m.Copies {
+		if c.Hash == hash {
+			return
+		}
+	}
+	if err := fsys.Remove(copyBasePath(targetDir, hash)); err != nil && !os.IsNotExist(err) {
+		PrintVerbose("Failed to remove merge base: %v", err)
+	}
+}
+
+// keepLocal reports whether dest, a file in sourceDir, matches a keep_local
+// pattern in the lnk-package.json of its package or of the source directory,
+// and returns the pattern
+func keepLocal(sourceDir, dest string) (string, bool) {
+	return packagePattern(sourceDir, dest, func(info *PackageInfo) []string { return info.KeepLocal })
+}
+
+// mergeBothWays reports whether dest, a file in sourceDir, matches a merge
+// pattern, like keepLocal, and returns the pattern
+func mergeBothWays(sourceDir, dest string) (string, bool) {
+	return packagePattern(sourceDir, dest, func(info *PackageInfo) []string { return info.Merge })
+}
+
+// packagePattern reports whether dest, a file in sourceDir, matches one of
+// the patterns from the lnk-package.json of its package or of the source
+// directory, and returns the pattern
+func packagePattern(sourceDir, dest string, patterns func(*PackageInfo) []string) (string, bool) {
+	rel, err := filepath.Rel(sourceDir, dest)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	dirs, paths := []string{sourceDir}, []string{rel}
+	if pkg, inPkg, ok := strings.Cut(rel, string(filepath.Separator)); ok {
+		dirs, paths = append(dirs, filepath.Join(sourceDir, pkg)), append(paths, inPkg)
+	}
+	for i, dir := range dirs {
+		info, err := LoadPackageInfo(dir)
+		if err != nil {
+			PrintVerbose("Failed to read package patterns: %v", err)
+			continue
+		}
+		if pattern, ok := NewPatternMatcher(patterns(info)).MatchingPattern(paths[i]); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
@@ -5,37 +5,70 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 )
 
 // PlannedLink represents a source file and its target symlink location
 type PlannedLink struct {
-	Source string
-	Target string
+	Source    string
+	Target    string
+	Ephemeral bool // target is expected to vanish on reboot (see PackageInfo.Ephemeral)
+	Copy      bool // copy the source instead of linking it (see PackageInfo.SymlinkedFiles)
+
+	Groups []string // link groups the link belongs to (see groups.go)
 }
 
 // LinkOptions holds configuration for linking operations
 type LinkOptions struct {
-	SourceDir      string   // source directory - what to link from (e.g., ~/git/dotfiles)
-	TargetDir      string   // where to create links (default: ~)
-	IgnorePatterns []string // combined ignore patterns from all sources
-	DryRun         bool     // preview mode without making changes
+	SourceDir        string     // source directory - what to link from (e.g., ~/git/dotfiles)
+	TargetDir        string     // where to create links (default: ~)
+	Home             string     // directory ~ expands to in TargetDir, Maps, and Paths (default: $HOME)
+	IgnorePatterns   []string   // combined ignore patterns from all sources
+	Scopes           []string   // subdirectories of SourceDir to limit prune to (empty = all)
+	CleanDirs        bool       // also remove empty directories lnk created (remove)
+	FailOn           []string   // status conditions that cause a non-zero exit (status)
+	SpecialFiles     string     // policy for special files in the source: "skip" (default) or "error" (create)
+	SymlinkFallback  string     // policy for targets on file systems without symlinks: "error" (default) or "copy" (create)
+	OnUnsupported    string     // policy for configured features this machine cannot honor: "degrade" (default) or "abort" (create)
+	Packages         []string   // top-level package directories to link from (empty = SourceDir itself)
+	Maps             []Mapping  // ad-hoc mappings for this run, in addition to packages (create, remove, status)
+	LocalOnly        []string   // target paths never linked over (create, status, doctor)
+	Dirs             *DirPolicy // how missing parent directories are created (create), or nil for the defaults
+	Sensitive        []string   // files that must not be stored in plaintext (lint)
+	Paths            []string   // links or directories to limit remove to (empty = all managed links)
+	AllLinks         bool       // also remove links into the source that lnk did not create (remove --all)
+	Interactive      bool       // choose the links to remove from a checklist first (remove, prune)
+	WindowsLinks     bool       // create links on Windows drives with mklink (create, WSL only)
+	ReplaceIdentical bool       // replace target files identical to their source without asking (create)
+	Reload           bool       // run the reload actions of packages whose links changed (create, up)
+	Fast             bool       // restore only ephemeral links recorded in the manifest (ensure)
+	Shallow          bool       // check only links recorded in the manifest, without walking (status)
+	AsOf             string     // report the manifest history at this time or operation ID instead (status)
+	Remote           bool       // also compare the source repository with its remote (status)
+	Groups           []string   // link groups to limit the run to (create, remove, status; empty = all)
+	Output           string     // OutputJSON to write a StatusReport instead of text (status)
+	SchemaVersion    int        // status JSON schema version to write; 0 = StatusSchemaVersion (status)
+	FullPlan         bool       // list every planned link in a dry run, not only what would change (create, up)
+	DryRun           bool       // preview mode without making changes
 }
 
 // collectPlannedLinksWithPatterns walks a source directory and collects all files that should be linked
-// Uses ignore patterns directly instead of a Config object
-func collectPlannedLinksWithPatterns(sourcePath, targetPath string, ignorePatterns []string) ([]PlannedLink, error) {
+// Uses ignore patterns directly instead of a Config object. Special files (sockets, FIFOs,
+// device nodes, hardlinked files) that are not ignored are returned separately and never planned.
+// The number of files the ignore patterns skipped is returned too. Symlinks in
+// the source are skipped, followed, or linked as policy says (see
+// sourceSymlinkPolicy).
+func collectPlannedLinksWithPatterns(sourcePath, targetPath string, ignorePatterns []string, policy sourceSymlinkPolicy) ([]PlannedLink, []specialFile, int, error) {
 	var links []PlannedLink
+	var specials []specialFile
+	ignored := 0
 
 	// Create pattern matcher once before walk for efficiency
 	pm := NewPatternMatcher(ignorePatterns)
 
-	err := filepath.WalkDir(sourcePath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Only collect regular files — skip directories, symlinks, and special entries
-		if !d.Type().IsRegular() {
+	visit := func(path string, d fs.DirEntry) error {
+		// Skip directories; everything else is either linked or reported
+		if d.IsDir() {
 			return nil
 		}
 
@@ -47,6 +80,19 @@ func collectPlannedLinksWithPatterns(sourcePath, targetPath string, ignorePatter
 
 		// Check if this file should be ignored
 		if pm.Matches(relPath) {
+			ignored++
+			return nil
+		}
+
+		kind, err := specialFileKind(d)
+		if err != nil {
+			return err
+		}
+		if kind != "" {
+			specials = append(specials, specialFile{path: path, kind: kind})
+			return nil
+		}
+		if !d.Type().IsRegular() {
 			return nil
 		}
 
@@ -59,95 +105,441 @@ func collectPlannedLinksWithPatterns(sourcePath, targetPath string, ignorePatter
 		})
 
 		return nil
-	})
+	}
+
+	// walk visits the real directory dir, reporting its entries under shownAs.
+	// inside lists the real directories the walk has entered so far.
+	var walk func(dir, shownAs string, inside []string) error
+	walk = func(dir, shownAs string, inside []string) error {
+		return walkDir(dir, func(realPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, realPath)
+			if err != nil {
+				return fmt.Errorf("failed to calculate relative path: %w", err)
+			}
+			path := filepath.Join(shownAs, rel)
+			if d.Type()&fs.ModeSymlink == 0 {
+				return visit(path, d)
+			}
+
+			relPath, err := filepath.Rel(sourcePath, path)
+			if err != nil {
+				return fmt.Errorf("failed to calculate relative path: %w", err)
+			}
+			if pm.Matches(relPath) {
+				return nil
+			}
+			dest, isDir := resolveSourceSymlink(path, realPath, policy)
+			if dest == "" {
+				return nil
+			}
+			if isDir {
+				if policy.followDir(path, realPath, dest, inside) {
+					return walk(dest, path, append(slices.Clone(inside), dest))
+				}
+				return nil
+			}
+			if link, ok := policy.fileLink(path, dest, filepath.Join(targetPath, relPath)); ok {
+				links = append(links, link)
+			}
+			return nil
+		})
+	}
+
+	root, err := fsys.EvalSymlinks(sourcePath)
+	if err != nil {
+		root = sourcePath
+	}
+	err = walk(sourcePath, sourcePath, []string{root})
+	return links, specials, ignored, err
+}
 
-	return links, err
+// CreateLinks creates symlinks using the provided options, and returns what
+// it decided for each planned link, with the error when it fails part way.
+// It prints each link as it goes; PrintCreateSummary prints the summary.
+func CreateLinks(opts LinkOptions) ([]LinkResult, error) {
+	r, done := collect()
+	defer done()
+	err := createLinks(opts)
+	return r.links, err
 }
 
-// CreateLinks creates symlinks using the provided options
-func CreateLinks(opts LinkOptions) error {
+// createLinks creates symlinks for CreateLinks, recording each decision
+func createLinks(opts LinkOptions) error {
 	PrintCommandHeader("Creating Symlinks")
 
 	// Expand and validate paths
-	paths, err := ResolvePaths(opts.SourceDir, opts.TargetDir)
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
 	if err != nil {
 		return err
 	}
 	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
 
+	wsl := isWSL()
+	if opts.WindowsLinks && !wsl {
+		return NewValidationErrorWithHint("windows-links", "true", "only supported under WSL",
+			"Remove --windows-links; regular symlinks work everywhere else")
+	}
+
 	// Phase 1: Collect all files to link
 	PrintVerbose("Starting phase 1: collecting files to link")
 	PrintVerbose("Source directory: %s", sourceDir)
 	PrintVerbose("Target directory: %s", targetDir)
+	endPlan := TracePhase("plan")
+	capabilities = &capabilityReport{}
+	defer func() { capabilities = nil }()
 
-	plannedLinks, err := collectPlannedLinksWithPatterns(sourceDir, targetDir, opts.IgnorePatterns)
+	packages, err := materializePackages(sourceDir, opts.Packages, opts.DryRun)
+	if err != nil {
+		return err
+	}
+	packages, err = expandPackageDeps(sourceDir, packages)
+	if err != nil {
+		return err
+	}
+	pkgDirs, err := packageDirs(sourceDir, packages)
+	if err != nil {
+		return err
+	}
+	if opts.Reload && !opts.DryRun {
+		defer collectReloads(sourceDir, opts.Packages)()
+	}
+	maps, err := resolveMappings(opts.Maps, opts.Home, sourceDir, targetDir)
+	if err != nil {
+		return err
+	}
+	plannedLinks, specials, plans, err := collectPackageLinks(pkgDirs, targetDir, opts.IgnorePatterns)
 	if err != nil {
 		return fmt.Errorf("collecting files to link: %w", err)
 	}
+	mapLinks, mapSpecials, mapPlans, err := collectMappedLinks(maps, opts.IgnorePatterns, plannedLinks)
+	if err != nil {
+		return fmt.Errorf("collecting files to link: %w", err)
+	}
+	plans = append(plans, mapPlans...)
+	plannedLinks = append(plannedLinks, mapLinks...)
+	specials = append(specials, mapSpecials...)
+	if len(opts.Groups) > 0 {
+		if plannedLinks, err = selectGroups(plannedLinks, opts.Groups, pkgDirs, maps); err != nil {
+			return err
+		}
+	}
+	plannedLinks, localLinks := filterLocalOnly(plannedLinks, targetDir, opts.LocalOnly)
+	for _, link := range localLinks {
+		PrintSkip("Local-only: %s", ContractPath(link.Target))
+		recordLink(link, ResultSkipped, "local-only", nil)
+	}
+	plannedLinks, copiedLinks := filterCopyManaged(plannedLinks, loadCopies(targetDir, sourceDir))
+	for _, link := range copiedLinks {
+		PrintSkip("Copy-managed: %s", ContractPath(link.Target))
+		recordLink(link, ResultSkipped, "copy-managed", nil)
+	}
+	plannedLinks = materializeLFS(plannedLinks, sourceDir, opts.DryRun)
+	if err := applySpecialFilePolicy(specials, opts.SpecialFiles); err != nil {
+		return err
+	}
+	if err := checkProtectedLinks(plannedLinks); err != nil {
+		return err
+	}
+	dirs := supportedDirPolicy(opts.Dirs, filepath.Join(sourceDir, DirsFileName))
+	if err := capabilities.resolve(opts.OnUnsupported); err != nil {
+		return err
+	}
+	endPlan("links", len(plannedLinks), "mappings", len(pkgDirs)+len(maps), "special_files", len(specials))
+	SummaryCount("planned", len(plannedLinks))
 
 	if len(plannedLinks) == 0 {
 		PrintEmptyResult("files to link")
 		return nil
 	}
 
+	// A repeated run makes no changes: when the manifest already records every
+	// planned link and each one points at its source, skip straight to the end
+	if linksUpToDate(plannedLinks, sourceDir, targetDir) {
+		PrintVerbose("Manifest records all %d link(s) in place; nothing to do", len(plannedLinks))
+		hardenPrivatePaths(targetDir, linkTargets(plannedLinks))
+		for _, link := range plannedLinks {
+			recordLink(link, ResultExisting, "", nil)
+		}
+		SummaryCount("created", 0)
+		SummaryCount("failed", 0)
+		return nil
+	}
+
+	// Fail early, or fall back to copies, where the target cannot hold
+	// symlinks, rather than failing on every file
+	copyTargets, err := applySymlinkFallback(plannedLinks, opts.SymlinkFallback)
+	if err != nil {
+		return err
+	}
+	copyTargets = addPlannedCopies(copyTargets, plannedLinks)
+
 	// Phase 2: Validate all targets
+	endValidate := TracePhase("validate")
 	for _, link := range plannedLinks {
 		if err := ValidateSymlinkCreation(link.Source, link.Target); err != nil {
 			return fmt.Errorf("validation failed for %s -> %s: %w", link.Target, link.Source, err)
 		}
 	}
+	if err := checkParentDirs(plannedLinks, targetDir, dirs); err != nil {
+		return err
+	}
+	endValidate("links", len(plannedLinks))
+
+	// Links from a Windows drive into the WSL file system are created with
+	// mklink when requested; otherwise Windows applications cannot follow them
+	mklinkTargets := make(map[string]bool)
+	if wsl {
+		crossing := crossesWindowsBoundary(plannedLinks, windowsMountPoints())
+		if opts.WindowsLinks {
+			for _, link := range crossing {
+				mklinkTargets[link.Target] = true
+			}
+		} else {
+			warnWindowsBoundary(crossing)
+		}
+	}
+
+	// Regular files identical to their source are the usual conflict after a
+	// fresh clone; they can be swapped for links without losing anything
+	replaceTargets := make(map[string]bool)
+	if identical := identicalConflicts(plannedLinks); len(identical) > 0 {
+		if opts.ReplaceIdentical || !opts.DryRun && confirmReplaceIdentical(identical) {
+			for _, link := range identical {
+				replaceTargets[link.Target] = true
+			}
+		} else {
+			PrintInfo("%d existing file(s) are identical to the repository; use --replace-identical to link them", len(identical))
+		}
+	}
 
 	// Phase 3: Execute (or show dry-run)
+	if opts.DryRun && !opts.FullPlan {
+		fmt.Fprintln(stdout())
+		printPlanDelta(plannedLinks, copyTargets, replaceTargets, mklinkTargets)
+		printMappingPlans(plans, plannedLinks)
+		fmt.Fprintln(stdout())
+		PrintDryRunSummary()
+		return nil
+	}
 	if opts.DryRun {
-		fmt.Println()
+		fmt.Fprintln(stdout())
 		PrintDryRun("Would create %d symlink(s):", len(plannedLinks))
+		lines := newPathLines(len(plannedLinks), "Would link", PrintDryRun)
 		for _, link := range plannedLinks {
-			PrintDryRun("Would link: %s -> %s", ContractPath(link.Target), ContractPath(link.Source))
+			recordLink(link, ResultPlanned, "", nil)
+			if link.Copy {
+				lines.Add(link.Target, "Would copy: %s <- %s", ContractPath(link.Target), ContractPath(link.Source))
+				continue
+			}
+			if copyTargets[link.Target] {
+				lines.Add(link.Target, "Would copy (no symlink support): %s <- %s", ContractPath(link.Target), ContractPath(link.Source))
+				continue
+			}
+			if replaceTargets[link.Target] {
+				lines.Add(link.Target, "Would replace identical file: %s -> %s", ContractPath(link.Target), ContractPath(link.Source))
+				continue
+			}
+			if mklinkTargets[link.Target] {
+				lines.Add(link.Target, "Would link with mklink: %s -> %s", ContractPath(link.Target), ContractPath(link.Source))
+				continue
+			}
+			lines.Add(link.Target, "Would link: %s -> %s", ContractPath(link.Target), ContractPath(link.Source))
 		}
-		fmt.Println()
+		lines.Flush()
+		printMappingPlans(plans, plannedLinks)
+		fmt.Fprintln(stdout())
 		PrintDryRunSummary()
 		return nil
 	}
 
 	// Execute the plan
-	return executePlannedLinks(plannedLinks, sourceDir)
+	endExecute := TracePhase("execute")
+	err = executePlannedLinks(plannedLinks, sourceDir, targetDir, dirs, mklinkTargets, replaceTargets, copyTargets)
+	endExecute("ok", err == nil)
+	return err
+}
+
+// addPlannedCopies adds the targets of links planned as copies
+// (symlinked_files: copy) to copyTargets
+func addPlannedCopies(copyTargets map[string]bool, links []PlannedLink) map[string]bool {
+	for _, link := range links {
+		if !link.Copy {
+			continue
+		}
+		if copyTargets == nil {
+			copyTargets = make(map[string]bool)
+		}
+		copyTargets[link.Target] = true
+	}
+	return copyTargets
+}
+
+// linksUpToDate reports whether creating links would change nothing: the
+// manifest records every link for sourceDir (ephemeral ones with their
+// destination) and each already points at its source.
+func linksUpToDate(links []PlannedLink, sourceDir, targetDir string) bool {
+	m, err := LoadManifest(targetDir)
+	if err != nil || !m.TracksLinks(sourceDir) {
+		return false
+	}
+	recorded := make(map[string]ManifestLink, len(m.Links))
+	for _, l := range m.Links {
+		recorded[l.Path] = l
+	}
+	for _, link := range links {
+		r, ok := recorded[link.Target]
+		if !ok || r.Source != sourceDir || r.Ephemeral != link.Ephemeral || link.Ephemeral && r.Dest != link.Source {
+			return false
+		}
+		if !linkPointsTo(link.Target, link.Source) {
+			return false
+		}
+	}
+	return true
+}
+
+// linkTargets returns the target paths of links
+func linkTargets(links []PlannedLink) []string {
+	targets := make([]string, len(links))
+	for i, link := range links {
+		targets[i] = link.Target
+	}
+	return targets
+}
+
+// identicalConflicts returns the planned links whose target is a regular file
+// with the same content as its source
+func identicalConflicts(links []PlannedLink) []PlannedLink {
+	var identical []PlannedLink
+	for _, link := range links {
+		info, err := fsys.Lstat(link.Target)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if same, err := filesEqual(link.Source, link.Target); err == nil && same {
+			PrintVerbose("%s is identical to %s", ContractPath(link.Target), ContractPath(link.Source))
+			identical = append(identical, link)
+		}
+	}
+	return identical
 }
 
-// executePlannedLinks creates the symlinks according to the plan
-func executePlannedLinks(links []PlannedLink, sourceDir string) error {
+// confirmReplaceIdentical lists the identical files and asks whether to
+// replace them with links
+func confirmReplaceIdentical(identical []PlannedLink) bool {
+	if !assumeYes && canPrompt() {
+		fmt.Fprintln(os.Stderr, "These files are identical to the repository:")
+		for _, link := range identical {
+			fmt.Fprintf(os.Stderr, "  %s\n", ContractPath(link.Target))
+		}
+	}
+	return confirm(fmt.Sprintf("Replace %d identical file(s) with symlinks? [y/N]", len(identical)))
+}
+
+// executePlannedLinks creates the symlinks according to the plan. Targets in
+// mklinkTargets are created as Windows symbolic links; files at targets in
+// replaceTargets are replaced with links if they still match their source;
+// sources for targets in copyTargets are copied and recorded as copies.
+// Missing parent directories are created as dirs sets out.
+func executePlannedLinks(links []PlannedLink, sourceDir, targetDir string, dirs *DirPolicy, mklinkTargets, replaceTargets, copyTargets map[string]bool) error {
 	// Track which directories we've created to avoid redundant checks
 	createdDirs := make(map[string]bool)
+	// Directories that did not exist before this run, recorded in the manifest
+	var newDirs []string
 
 	// Track results for summary
-	var created, failed int
+	var created, replaced, copied, failed int
+	var createdLinks, existingLinks []PlannedLink
+	var copies []ManagedLink
+	createdLines := newPathLines(len(links), "Created", PrintSuccess)
+	replacedLines := newPathLines(len(links), "Replaced identical", PrintSuccess)
+	copiedLines := newPathLines(len(copyTargets), "Copied", PrintSuccess)
+
+	// Plain links and their directories take the Linux fast path when it is
+	// available (see batch.go)
+	createLink, mkdirAll := CreateSymlink, fsys.MkdirAll
+	if batch := startLinkBatch(targetDir); batch != nil {
+		defer batch.close()
+		createLink, mkdirAll = batch.symlink, batch.mkdirAll
+	}
 
 	processLinks := func() error {
 		for _, link := range links {
 			// Create parent directory if needed
 			parentDir := filepath.Dir(link.Target)
 			if !createdDirs[parentDir] {
-				if err := os.MkdirAll(parentDir, 0755); err != nil {
-					PrintWarningWithHint(fmt.Errorf("Failed to create %s: %w", ContractPath(link.Target),
-						NewPathErrorWithHint("create directory", parentDir, err,
-							"Check that you have write permissions in the parent directory")))
+				missing := missingDirs(parentDir, targetDir)
+				if err := mkdirAll(parentDir, dirs.mkdirMode()); err != nil {
+					err = NewPathErrorWithHint("create directory", parentDir, err,
+						"Check that you have write permissions in the parent directory")
+					PrintWarningWithHint(fmt.Errorf("Failed to create %s: %w", ContractPath(link.Target), err))
+					recordLink(link, ResultFailed, "", err)
 					failed++
 					continue
 				}
+				if err := dirs.apply(missing); err != nil {
+					PrintWarningWithHint(fmt.Errorf("Failed to apply %s to %s: %w", DirsFileName, ContractPath(parentDir), err))
+				}
 				createdDirs[parentDir] = true
+				if isWithin(parentDir, targetDir) {
+					newDirs = append(newDirs, missing...)
+				}
+			}
+
+			if copyTargets[link.Target] {
+				if err := copyFile(link.Source, link.Target); err != nil {
+					PrintWarningWithHint(fmt.Errorf("Failed to copy %s: %w", ContractPath(link.Target), err))
+					recordLink(link, ResultFailed, "", err)
+					failed++
+					continue
+				}
+				copiedLines.Add(link.Target, "Copied: %s", ContractPath(link.Target))
+				recordLink(link, ResultCopied, "", nil)
+				copied++
+				copies = append(copies, ManagedLink{Path: link.Target, Target: link.Source})
+				continue
+			}
+
+			if replaceTargets[link.Target] {
+				if err := replaceIdenticalFile(link.Source, link.Target); err != nil {
+					PrintWarningWithHint(fmt.Errorf("Failed to replace %s: %w", ContractPath(link.Target), err))
+					recordLink(link, ResultFailed, "", err)
+					failed++
+					continue
+				}
+				replacedLines.Add(link.Target, "Replaced identical: %s", ContractPath(link.Target))
+				recordLink(link, ResultReplaced, "", nil)
+				created++
+				replaced++
+				createdLinks = append(createdLinks, link)
+				continue
 			}
 
 			// Create the symlink
-			if err := CreateSymlink(link.Source, link.Target); err != nil {
+			create := createLink
+			if mklinkTargets[link.Target] {
+				create = createWindowsSymlink
+			}
+			if err := create(link.Source, link.Target); err != nil {
 				if _, ok := err.(LinkExistsError); ok {
-					// Link already exists with correct target - skip silently
+					// Link already exists with correct target - skip silently,
+					// but record it as lnk's since lnk would have created it
+					existingLinks = append(existingLinks, link)
+					recordLink(link, ResultExisting, "", nil)
 					continue
 				}
 				// Print warning but continue with other links
 				PrintWarningWithHint(fmt.Errorf("Failed to create %s: %w", ContractPath(link.Target), err))
+				recordLink(link, ResultFailed, "", err)
 				failed++
 			} else {
-				PrintSuccess("Created: %s", ContractPath(link.Target))
+				createdLines.Add(link.Target, "Created: %s", ContractPath(link.Target))
+				recordLink(link, ResultCreated, "", nil)
 				created++
+				createdLinks = append(createdLinks, link)
 			}
 		}
 		return nil
@@ -157,19 +549,28 @@ func executePlannedLinks(links []PlannedLink, sourceDir string) error {
 	if err := ShowProgress("Creating symlinks", processLinks); err != nil {
 		return err
 	}
-
-	// Print summary
-	if created > 0 {
-		PrintSummary("Created %d symlink(s) successfully", created)
-		if failed == 0 {
-			PrintNextStep("status", sourceDir, "verify links")
-		}
-	} else if failed == 0 {
-		// All links were skipped (already exist)
-		PrintInfo("All symlinks already exist")
+	replacedLines.Flush()
+	createdLines.Flush()
+	copiedLines.Flush()
+	for _, link := range createdLinks {
+		reloads.add(link.Source)
 	}
+	for _, c := range copies {
+		reloads.add(c.Target)
+	}
+	recordCreatedDirs(targetDir, sourceDir, newDirs)
+	recordCopies(targetDir, sourceDir, copies)
+	recordCreatedLinks(targetDir, sourceDir, linkTargets(append(createdLinks, existingLinks...)))
+	recordEphemeralLinks(targetDir, append(createdLinks, existingLinks...))
+	refreshFontsIfChanged(targetDir, linkTargets(createdLinks))
+	prepareBinLinks(targetDir, createdLinks)
+	hardenPrivatePaths(targetDir, linkTargets(append(createdLinks, existingLinks...)))
+	SummaryCount("created", created)
+	SummaryCount("replaced", replaced)
+	SummaryCount("copied", copied)
+	SummaryCount("failed", failed)
+
 	if failed > 0 {
-		PrintWarning("Failed to create %d symlink(s)", failed)
 		return fmt.Errorf("failed to create %d symlink(s)", failed)
 	}
 
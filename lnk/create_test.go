@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"bufio"
 	"os"
 	"path/filepath"
 	"strings"
@@ -154,7 +155,7 @@ func TestCreateLinks(t *testing.T) {
 
 			configRepo, opts := tt.setup(t, tmpDir)
 
-			err := CreateLinks(opts)
+			_, err := CreateLinks(opts)
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("CreateLinks() expected error, got nil")
@@ -215,3 +216,145 @@ func TestCreateLinksPerItemWarning(t *testing.T) {
 		t.Errorf("CreateLinks() per-item failure must propagate hint via PrintWarningWithHint\nstderr: %q", stderr)
 	}
 }
+
+func TestLinkOptionsHome(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.bashrc", "bash")
+	m.writeFile(t, "/src/.vimrc", "vim")
+	opts := LinkOptions{SourceDir: "/src", TargetDir: "~", Home: "/home/u"}
+
+	if _, err := CreateLinks(opts); err != nil {
+		t.Fatalf("CreateLinks() error = %v", err)
+	}
+	if got, err := m.Readlink("/home/u/.bashrc"); err != nil || got != "/src/.bashrc" {
+		t.Fatalf("Readlink(/home/u/.bashrc) = %q, %v; want /src/.bashrc", got, err)
+	}
+
+	output := CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Errorf("Status() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "/home/u/.bashrc", "/home/u/.vimrc")
+
+	opts.Paths = []string{"~/.bashrc"}
+	if err := RemoveLinks(opts); err != nil {
+		t.Fatalf("RemoveLinks() error = %v", err)
+	}
+	if _, err := m.Lstat("/home/u/.bashrc"); !os.IsNotExist(err) {
+		t.Errorf("~/.bashrc should be removed, Lstat error = %v", err)
+	}
+	if _, err := m.Lstat("/home/u/.vimrc"); err != nil {
+		t.Errorf("~/.vimrc should remain, Lstat error = %v", err)
+	}
+}
+
+func TestCreateReplaceIdentical(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.bashrc", "bash")
+	m.writeFile(t, "/src/.vimrc", "vim")
+	m.writeFile(t, "/home/u/.bashrc", "bash")
+	m.writeFile(t, "/home/u/.vimrc", "local vim")
+	origCanPrompt := canPrompt
+	canPrompt = func() bool { return false }
+	t.Cleanup(func() { canPrompt = origCanPrompt })
+
+	// Without the flag or a terminal to ask at, nothing is replaced
+	output := CaptureOutput(t, func() {
+		CreateLinks(LinkOptions{SourceDir: "/src", TargetDir: "/home/u"})
+	})
+	ContainsOutput(t, output, "--replace-identical")
+	assertFileContent(t, m, "/home/u/.bashrc", "bash")
+
+	_, err := CreateLinks(LinkOptions{SourceDir: "/src", TargetDir: "/home/u", ReplaceIdentical: true})
+	if err == nil {
+		t.Error("CreateLinks() should still fail for the file that differs")
+	}
+	if got, err := m.Readlink("/home/u/.bashrc"); err != nil || got != "/src/.bashrc" {
+		t.Errorf("Readlink(~/.bashrc) = %q, %v; want the identical file replaced", got, err)
+	}
+	assertFileContent(t, m, "/home/u/.vimrc", "local vim")
+	if exists("/home/u/..bashrc.lnk-tmp") {
+		t.Error("temporary link should not be left behind")
+	}
+}
+
+func TestCreateReplaceIdenticalPrompt(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.bashrc", "bash")
+	m.writeFile(t, "/home/u/.bashrc", "bash")
+	origCanPrompt, origReader := canPrompt, promptReader
+	canPrompt = func() bool { return true }
+	promptReader = bufio.NewReader(strings.NewReader("y\n"))
+	t.Cleanup(func() { canPrompt, promptReader = origCanPrompt, origReader })
+
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(LinkOptions{SourceDir: "/src", TargetDir: "/home/u"}); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	if got, err := m.Readlink("/home/u/.bashrc"); err != nil || got != "/src/.bashrc" {
+		t.Errorf("Readlink(~/.bashrc) = %q, %v; want the file replaced after confirming", got, err)
+	}
+}
+
+func TestReplaceIdenticalFileRechecksContent(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.bashrc", "bash")
+	m.writeFile(t, "/home/u/.bashrc", "edited since the check")
+
+	if err := replaceIdenticalFile("/src/.bashrc", "/home/u/.bashrc"); err == nil {
+		t.Fatal("replaceIdenticalFile() should refuse a file that no longer matches")
+	}
+	assertFileContent(t, m, "/home/u/.bashrc", "edited since the check")
+}
+
+func TestCreateLinksDryRunDelta(t *testing.T) {
+	m := useMemFS(t)
+	for _, name := range []string{".bashrc", ".vimrc", ".gitconfig", ".zshrc"} {
+		m.writeFile(t, "/src/"+name, name)
+	}
+	m.writeFile(t, "/old/.vimrc", "old vim")
+	m.writeFile(t, "/home/u/.gitconfig", "local git")
+	if err := m.Symlink("/src/.bashrc", "/home/u/.bashrc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Symlink("/old/.vimrc", "/home/u/.vimrc"); err != nil {
+		t.Fatal(err)
+	}
+	opts := LinkOptions{SourceDir: "/src", TargetDir: "/home/u", DryRun: true}
+
+	stdout, stderr := captureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	ContainsOutput(t, stdout, "Would create 1, retarget 1, with 1 conflict(s); 1 already in place",
+		"Would link: /home/u/.zshrc -> /src/.zshrc",
+		"Would retarget: /home/u/.vimrc -> /src/.vimrc (was /old/.vimrc)")
+	NotContainsOutput(t, stdout, "/home/u/.bashrc")
+	ContainsOutput(t, stderr, "Conflict: /home/u/.gitconfig is a file")
+	if got, _ := m.Readlink("/home/u/.vimrc"); got != "/old/.vimrc" {
+		t.Errorf("dry run retargeted ~/.vimrc to %q", got)
+	}
+
+	t.Run("full plan", func(t *testing.T) {
+		opts := opts
+		opts.FullPlan = true
+		stdout, _ := captureOutput(t, func() { CreateLinks(opts) })
+		ContainsOutput(t, stdout, "Would create 4 symlink(s):", "Would link: /home/u/.bashrc -> /src/.bashrc")
+	})
+
+	t.Run("nothing to change", func(t *testing.T) {
+		m.Remove("/home/u/.vimrc")
+		m.Remove("/home/u/.gitconfig")
+		for _, name := range []string{".vimrc", ".gitconfig", ".zshrc"} {
+			if err := m.Symlink("/src/"+name, "/home/u/"+name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		stdout, _ := captureOutput(t, func() { CreateLinks(opts) })
+		ContainsOutput(t, stdout, "All 4 symlink(s) already exist; nothing would change")
+		NotContainsOutput(t, stdout, "Would link")
+	})
+}
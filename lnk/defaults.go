@@ -0,0 +1,265 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Value types accepted for macOS defaults settings, named after the
+// `defaults write` type flags
+var DefaultsTypes = []string{"string", "bool", "int", "float"}
+
+// DefaultsSetting is a macOS preference from the "defaults" list in
+// lnk-package.json, applied with `defaults write <domain> <key> -<type> <value>`
+type DefaultsSetting struct {
+	Domain string `json:"domain"` // e.g. com.apple.dock, or NSGlobalDomain
+	Key    string `json:"key"`
+	Type   string `json:"type"`  // one of DefaultsTypes
+	Value  any    `json:"value"` // JSON string, boolean, or number matching Type
+}
+
+// DefaultsOptions holds options for applying or comparing macOS defaults
+type DefaultsOptions struct {
+	SourceDir string   // source directory containing lnk-package.json files
+	Packages  []string // packages whose settings to use (empty = SourceDir itself)
+	DryRun    bool     // preview mode without writing settings (apply)
+}
+
+// errDefaultsUnavailable is returned when the defaults command is not installed
+var errDefaultsUnavailable = errors.New("defaults command not found")
+
+// runDefaults runs the macOS defaults command; tests replace it
+var runDefaults = func(args ...string) (string, error) {
+	if _, err := exec.LookPath("defaults"); err != nil {
+		return "", WithHint(errDefaultsUnavailable, "macOS defaults settings can only be applied on macOS")
+	}
+	out, err := exec.Command("defaults", args...).CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// String identifies the setting in output
+func (s DefaultsSetting) String() string {
+	return s.Domain + " " + s.Key
+}
+
+// readValue formats the value the way `defaults read` prints it, for comparison
+func (s DefaultsSetting) readValue() string {
+	switch v := s.Value.(type) {
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeValue formats the value as a `defaults write` argument
+func (s DefaultsSetting) writeValue() string {
+	if b, ok := s.Value.(bool); ok {
+		return strconv.FormatBool(b)
+	}
+	return s.readValue()
+}
+
+// validate checks that the setting is complete and its value matches its type
+func (s DefaultsSetting) validate() error {
+	if s.Domain == "" || s.Key == "" {
+		return NewValidationErrorWithHint("defaults", s.String(), "domain and key are required",
+			`Write settings as {"domain": "com.apple.dock", "key": "autohide", "type": "bool", "value": true}`)
+	}
+	var ok bool
+	switch s.Type {
+	case "string":
+		_, ok = s.Value.(string)
+	case "bool":
+		_, ok = s.Value.(bool)
+	case "int":
+		f, isNum := s.Value.(float64)
+		ok = isNum && f == float64(int64(f))
+	case "float":
+		_, ok = s.Value.(float64)
+	default:
+		return NewValidationErrorWithHint("defaults", s.String(), fmt.Sprintf("unknown type %q", s.Type),
+			fmt.Sprintf("Valid types: %s", strings.Join(DefaultsTypes, ", ")))
+	}
+	if !ok {
+		return NewValidationErrorWithHint("defaults", s.String(),
+			fmt.Sprintf("value %v does not match type %s", s.Value, s.Type),
+			"Use a JSON string, boolean, or number that matches the type")
+	}
+	return nil
+}
+
+// collectDefaults gathers the settings of the selected packages whose link
+// conditions hold, in package order
+func collectDefaults(sourceDir string, packages []string) ([]DefaultsSetting, error) {
+	packages, err := expandPackageDeps(sourceDir, packages)
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := packageDirs(sourceDir, packages)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []DefaultsSetting
+	for _, dir := range dirs {
+		info, err := LoadPackageInfo(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(info.Defaults) == 0 {
+			continue
+		}
+		include, _, err := conditionalIgnorePatterns(dir, info)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			continue
+		}
+		for _, s := range info.Defaults {
+			if err := s.validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", ContractPath(filepath.Join(dir, PackageInfoFileName)), err)
+			}
+			settings = append(settings, s)
+		}
+	}
+	return settings, nil
+}
+
+// defaultsChange is a setting whose current value differs from the desired one
+type defaultsChange struct {
+	setting DefaultsSetting
+	current string // empty when unset
+	isSet   bool
+}
+
+// diffDefaults reads every setting and returns those that need to be written
+func diffDefaults(settings []DefaultsSetting) ([]defaultsChange, error) {
+	var changes []defaultsChange
+	for _, s := range settings {
+		// defaults read fails when the key does not exist yet
+		current, err := runDefaults("read", s.Domain, s.Key)
+		if errors.Is(err, errDefaultsUnavailable) {
+			return nil, err
+		}
+		isSet := err == nil
+		if isSet && current == s.readValue() {
+			PrintVerbose("Already set: %s = %s", s, current)
+			continue
+		}
+		changes = append(changes, defaultsChange{setting: s, current: current, isSet: isSet})
+	}
+	return changes, nil
+}
+
+// DefaultsDiff shows the macOS defaults settings that differ from the values
+// in lnk-package.json
+func DefaultsDiff(opts DefaultsOptions) error {
+	PrintCommandHeader("Defaults Diff")
+
+	sourceDir, settings, err := loadDefaultsSettings(opts)
+	if err != nil || settings == nil {
+		return err
+	}
+
+	changes, err := diffDefaults(settings)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		PrintSuccess("All %d setting(s) match", len(settings))
+		return nil
+	}
+
+	for _, c := range changes {
+		current := c.current
+		if !c.isSet {
+			current = "(unset)"
+		}
+		if ShouldSimplifyOutput() {
+			fmt.Printf("diff %s %s %s %s\n", c.setting.Domain, c.setting.Key, current, c.setting.readValue())
+			continue
+		}
+		fmt.Printf("%s %s: %s -> %s\n", Yellow(WarningIcon), c.setting, current, c.setting.readValue())
+	}
+	PrintNextStep("defaults apply", sourceDir, "write these settings")
+	return nil
+}
+
+// DefaultsApply writes the macOS defaults settings from lnk-package.json that
+// differ from their current values. Settings that already match are left
+// alone, so applying is idempotent.
+func DefaultsApply(opts DefaultsOptions) error {
+	PrintCommandHeader("Applying Defaults")
+
+	sourceDir, settings, err := loadDefaultsSettings(opts)
+	if err != nil || settings == nil {
+		return err
+	}
+
+	changes, err := diffDefaults(settings)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		PrintInfo("All defaults already set")
+		return nil
+	}
+
+	if opts.DryRun {
+		fmt.Println()
+		for _, c := range changes {
+			PrintDryRun("Would set: %s = %s", c.setting, c.setting.writeValue())
+		}
+		fmt.Println()
+		PrintDryRunSummary()
+		return nil
+	}
+
+	if err := checkWritable("apply defaults", sourceDir); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		s := c.setting
+		PrintVerbose("Running: defaults write %s %s -%s %s", s.Domain, s.Key, s.Type, s.writeValue())
+		if out, err := runDefaults("write", s.Domain, s.Key, "-"+s.Type, s.writeValue()); err != nil {
+			return WithHint(fmt.Errorf("defaults write %s failed: %w\n%s", s, err, out),
+				"Check the domain and key, then run 'lnk defaults apply' again")
+		}
+		PrintSuccess("Set: %s = %s", s, s.writeValue())
+	}
+	PrintSummary("Applied %d setting(s)", len(changes))
+	PrintInfo("Some applications must be restarted to pick up new settings")
+	return nil
+}
+
+// loadDefaultsSettings resolves the source directory and collects its
+// settings. It returns nil settings when there are none to act on.
+func loadDefaultsSettings(opts DefaultsOptions) (string, []DefaultsSetting, error) {
+	paths, err := ResolvePaths(opts.SourceDir, "~")
+	if err != nil {
+		return "", nil, err
+	}
+	sourceDir := paths.SourceDir
+	PrintVerbose("Source directory: %s", sourceDir)
+
+	settings, err := collectDefaults(sourceDir, opts.Packages)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(settings) == 0 {
+		PrintEmptyResult("defaults settings")
+		return sourceDir, nil, nil
+	}
+	return sourceDir, settings, nil
+}
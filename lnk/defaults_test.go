@@ -0,0 +1,158 @@
+package lnk
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDefaults replaces the defaults command with an in-memory store and
+// records the writes made
+func fakeDefaults(t *testing.T, store map[string]string) *[]string {
+	t.Helper()
+	var writes []string
+	old := runDefaults
+	runDefaults = func(args ...string) (string, error) {
+		key := args[1] + " " + args[2]
+		switch args[0] {
+		case "read":
+			if v, ok := store[key]; ok {
+				return v, nil
+			}
+			return fmt.Sprintf("The domain/default pair of (%s, %s) does not exist", args[1], args[2]), fmt.Errorf("exit status 1")
+		case "write":
+			writes = append(writes, strings.Join(args[1:], " "))
+			store[key] = args[4]
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected defaults %v", args)
+	}
+	t.Cleanup(func() { runDefaults = old })
+	return &writes
+}
+
+const testDefaultsInfo = `{
+	"defaults": [
+		{"domain": "com.apple.dock", "key": "autohide", "type": "bool", "value": true},
+		{"domain": "com.apple.dock", "key": "tilesize", "type": "int", "value": 36},
+		{"domain": "NSGlobalDomain", "key": "AppleInterfaceStyle", "type": "string", "value": "Dark"}
+	]
+}`
+
+func TestDefaultsApply(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFile(t, filepath.Join(sourceDir, PackageInfoFileName), testDefaultsInfo)
+	store := map[string]string{
+		"com.apple.dock autohide": "1",
+		"com.apple.dock tilesize": "48",
+	}
+	writes := fakeDefaults(t, store)
+
+	output := CaptureOutput(t, func() {
+		if err := DefaultsApply(DefaultsOptions{SourceDir: sourceDir}); err != nil {
+			t.Fatalf("DefaultsApply() error = %v", err)
+		}
+	})
+
+	want := []string{
+		"com.apple.dock tilesize -int 36",
+		"NSGlobalDomain AppleInterfaceStyle -string Dark",
+	}
+	if strings.Join(*writes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("writes = %v, want %v", *writes, want)
+	}
+	ContainsOutput(t, output, "Set: com.apple.dock tilesize = 36", "Applied 2 setting(s)")
+
+	// Applying again changes nothing
+	*writes = nil
+	output = CaptureOutput(t, func() {
+		if err := DefaultsApply(DefaultsOptions{SourceDir: sourceDir}); err != nil {
+			t.Fatalf("DefaultsApply() error = %v", err)
+		}
+	})
+	if len(*writes) != 0 {
+		t.Errorf("second apply wrote %v", *writes)
+	}
+	ContainsOutput(t, output, "All defaults already set")
+}
+
+func TestDefaultsApplyDryRun(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFile(t, filepath.Join(sourceDir, PackageInfoFileName), testDefaultsInfo)
+	writes := fakeDefaults(t, map[string]string{})
+
+	output := CaptureOutput(t, func() {
+		if err := DefaultsApply(DefaultsOptions{SourceDir: sourceDir, DryRun: true}); err != nil {
+			t.Fatalf("DefaultsApply() error = %v", err)
+		}
+	})
+	if len(*writes) != 0 {
+		t.Errorf("dry-run wrote %v", *writes)
+	}
+	ContainsOutput(t, output, "Would set: com.apple.dock autohide = true")
+}
+
+func TestDefaultsDiff(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFile(t, filepath.Join(sourceDir, PackageInfoFileName), testDefaultsInfo)
+	fakeDefaults(t, map[string]string{
+		"com.apple.dock autohide": "1",
+		"com.apple.dock tilesize": "48",
+	})
+
+	output := CaptureOutput(t, func() {
+		if err := DefaultsDiff(DefaultsOptions{SourceDir: sourceDir}); err != nil {
+			t.Fatalf("DefaultsDiff() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output,
+		"diff com.apple.dock tilesize 48 36",
+		"diff NSGlobalDomain AppleInterfaceStyle (unset) Dark")
+	NotContainsOutput(t, output, "autohide")
+}
+
+func TestDefaultsPackages(t *testing.T) {
+	sourceDir, _ := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "shell", PackageInfoFileName), testDefaultsInfo)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName),
+		`{"defaults": [{"domain": "com.example.work", "key": "vpn", "type": "bool", "value": false}]}`)
+	writes := fakeDefaults(t, map[string]string{})
+
+	CaptureOutput(t, func() {
+		if err := DefaultsApply(DefaultsOptions{SourceDir: sourceDir, Packages: []string{"work"}}); err != nil {
+			t.Fatalf("DefaultsApply() error = %v", err)
+		}
+	})
+	if len(*writes) != 1 || (*writes)[0] != "com.example.work vpn -bool false" {
+		t.Errorf("writes = %v, want only the work package setting", *writes)
+	}
+}
+
+func TestDefaultsSettingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting DefaultsSetting
+		wantErr string
+	}{
+		{"valid float", DefaultsSetting{Domain: "d", Key: "k", Type: "float", Value: 0.5}, ""},
+		{"missing key", DefaultsSetting{Domain: "d", Type: "bool", Value: true}, "domain and key are required"},
+		{"unknown type", DefaultsSetting{Domain: "d", Key: "k", Type: "date", Value: "x"}, `unknown type "date"`},
+		{"wrong value", DefaultsSetting{Domain: "d", Key: "k", Type: "bool", Value: "yes"}, "value yes does not match type bool"},
+		{"fractional int", DefaultsSetting{Domain: "d", Key: "k", Type: "int", Value: 1.5}, "value 1.5 does not match type int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.setting.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
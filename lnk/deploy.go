@@ -0,0 +1,167 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Deploy links one source directory into the homes of several users, for
+// lab, classroom, and family machines. It runs as root: each user gets the
+// same links their own 'lnk create' would make and their own manifest under
+// their home. Linking runs with the user's own IDs, so what lnk creates is
+// theirs and root never writes through paths the user controls.
+
+// DeployOptions holds options for deploying into several users' homes
+type DeployOptions struct {
+	SourceDir       string   // source directory to link from; must be readable by every user
+	IgnorePatterns  []string // combined ignore patterns from all sources
+	Packages        []string // top-level package directories to link from (empty = SourceDir itself)
+	LocalOnly       []string // target paths never linked over, relative to each home
+	SpecialFiles    string   // policy for special files in the source (see LinkOptions)
+	SymlinkFallback string   // policy for homes without symlink support (see LinkOptions)
+	OnUnsupported   string   // policy for configured features a home cannot honor (see LinkOptions)
+	Users           []string // user names to deploy to
+	DryRun          bool     // preview mode without making changes
+}
+
+// deployUser is a user to deploy to
+type deployUser struct {
+	Name string
+	Home string
+	UID  int
+	GID  int
+}
+
+// runningAsRoot reports whether lnk runs with root privileges; tests replace it
+var runningAsRoot = func() bool { return os.Geteuid() == 0 }
+
+// lookupUser finds a user by name; tests replace it
+var lookupUser = user.Lookup
+
+// asUser runs fn as the given user; tests replace it
+var asUser = runAsUser
+
+// Deploy creates the links for each of opts.Users in their home directory,
+// acting as that user. A user that fails is reported and
+// the others are still deployed; the error then names every failed user.
+func Deploy(opts DeployOptions) error {
+	PrintCommandHeader("Deploying to Users")
+
+	if len(opts.Users) == 0 {
+		return NewValidationErrorWithHint("users", "", "no users given",
+			"Name the users to deploy to: --users alice,bob")
+	}
+	if !runningAsRoot() {
+		return WithHint(errors.New("deploy must run as root to link into other users' homes"),
+			"Run it with sudo, or run 'lnk create' as each user instead")
+	}
+
+	users, err := lookupDeployUsers(opts.Users)
+	if err != nil {
+		return err
+	}
+
+	sourceDir, err := ExpandPath(opts.SourceDir)
+	if err != nil {
+		return err
+	}
+	if dir := unreadableAncestor(sourceDir); dir != "" {
+		PrintWarningWithHint(WithHint(
+			fmt.Errorf("%s is not readable by other users; their links would not resolve", ContractPath(dir)),
+			"Keep the source directory somewhere shared, such as /srv/dotfiles, with mode 755"))
+	}
+
+	var failed []string
+	for i, u := range users {
+		if i > 0 {
+			fmt.Println()
+		}
+		PrintInfo("%s %s (%s)", Bold("User"), u.Name, u.Home)
+		if err := deployTo(u, opts); err != nil {
+			PrintErrorWithHint(err)
+			failed = append(failed, u.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return WithHint(fmt.Errorf("deploy failed for %d of %d user(s): %s", len(failed), len(users), strings.Join(failed, ", ")),
+			"Fix the errors above and run deploy again; users already deployed are left as they are")
+	}
+	if opts.DryRun {
+		return nil
+	}
+	PrintSummary("Deployed to %d user(s)", len(users))
+	return nil
+}
+
+// lookupDeployUsers resolves user names to their homes and IDs, failing
+// before anything changes when one is unknown
+func lookupDeployUsers(names []string) ([]deployUser, error) {
+	seen := make(map[string]bool)
+	var users []deployUser
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		u, err := lookupUser(name)
+		if err != nil {
+			return nil, NewValidationErrorWithHint("users", name, "unknown user",
+				"Check the name with 'id "+name+"'")
+		}
+		uid, uidErr := strconv.Atoi(u.Uid)
+		gid, gidErr := strconv.Atoi(u.Gid)
+		if uidErr != nil || gidErr != nil {
+			return nil, NewValidationErrorWithHint("users", name, "user has no numeric user and group ID",
+				"deploy only supports Unix users")
+		}
+		if u.HomeDir == "" || !filepath.IsAbs(u.HomeDir) {
+			return nil, NewValidationErrorWithHint("users", name, "user has no home directory",
+				"Create the user's home directory first")
+		}
+		users = append(users, deployUser{Name: name, Home: filepath.Clean(u.HomeDir), UID: uid, GID: gid})
+	}
+	return users, nil
+}
+
+// deployTo links into one user's home as that user
+func deployTo(u deployUser, opts DeployOptions) error {
+	if info, err := fsys.Stat(u.Home); err != nil || !info.IsDir() {
+		return NewPathErrorWithHint("deploy", u.Home, errors.New("home directory does not exist"),
+			"Create the user's home directory first")
+	}
+	return asUser(u, func() error {
+		results, err := CreateLinks(LinkOptions{
+			SourceDir:       opts.SourceDir,
+			TargetDir:       u.Home,
+			Home:            u.Home,
+			IgnorePatterns:  opts.IgnorePatterns,
+			Packages:        opts.Packages,
+			LocalOnly:       opts.LocalOnly,
+			SpecialFiles:    opts.SpecialFiles,
+			SymlinkFallback: opts.SymlinkFallback,
+			OnUnsupported:   opts.OnUnsupported,
+			DryRun:          opts.DryRun,
+		})
+		PrintCreateSummary(results, opts.SourceDir)
+		return err
+	})
+}
+
+// unreadableAncestor returns dir or the nearest of its parents that other
+// users cannot enter, or "" when they can reach dir
+func unreadableAncestor(dir string) string {
+	for path := dir; ; path = filepath.Dir(path) {
+		if info, err := fsys.Stat(path); err == nil && info.Mode().Perm()&0o001 == 0 {
+			return path
+		}
+		if filepath.Dir(path) == path {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package lnk
+
+import "errors"
+
+// runAsUser refuses to run fn; switching users is not supported on this
+// platform, so deploy cannot link as another user here
+func runAsUser(u deployUser, fn func() error) error {
+	return WithHint(errors.New("deploy cannot switch to another user on this platform"),
+		"Run 'lnk create' as each user instead")
+}
@@ -0,0 +1,133 @@
+package lnk
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDeployUsers makes deploy run as root and find the given users, each
+// with uid 12345, gid 12345, and the given home. Linking stays with the test's
+// own IDs; the returned slice lists the users it would have switched to.
+func fakeDeployUsers(t *testing.T, homes map[string]string) *[]string {
+	t.Helper()
+	origRoot, origLookup, origAsUser := runningAsRoot, lookupUser, asUser
+	runningAsRoot = func() bool { return true }
+	lookupUser = func(name string) (*user.User, error) {
+		home, ok := homes[name]
+		if !ok {
+			return nil, user.UnknownUserError(name)
+		}
+		return &user.User{Username: name, Uid: "12345", Gid: "12345", HomeDir: home}, nil
+	}
+	var switched []string
+	asUser = func(u deployUser, fn func() error) error {
+		switched = append(switched, u.Name)
+		return fn()
+	}
+	t.Cleanup(func() { runningAsRoot, lookupUser, asUser = origRoot, origLookup, origAsUser })
+	return &switched
+}
+
+func TestDeploy(t *testing.T) {
+	sourceDir, _ := setupPackagesTest(t)
+	homes := map[string]string{"alice": filepath.Join(t.TempDir(), "alice"), "bob": filepath.Join(t.TempDir(), "bob")}
+	for _, home := range homes {
+		os.MkdirAll(home, 0755)
+	}
+	switched := fakeDeployUsers(t, homes)
+
+	// A manifest entry naming a path outside the home must not be handed over
+	outside := filepath.Join(t.TempDir(), "shadow")
+	os.WriteFile(outside, []byte("root:x:0:0"), 0600)
+	hostile := &Manifest{}
+	hostile.AddLink(outside, filepath.Join(sourceDir, "shell", ".bashrc"))
+	if err := hostile.Save(homes["alice"]); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &writeRecorder{FileSystem: fsys}
+	old := fsys
+	fsys = rec
+	t.Cleanup(func() { fsys = old })
+
+	opts := DeployOptions{SourceDir: sourceDir, Packages: []string{"shell", "nvim"}, Users: []string{"alice", "bob"}}
+	out := CaptureOutput(t, func() {
+		if err := Deploy(opts); err != nil {
+			t.Fatalf("Deploy() error = %v", err)
+		}
+	})
+	ContainsOutput(t, out, "Deployed to 2 user(s)")
+
+	for name, home := range homes {
+		assertSymlink(t, filepath.Join(home, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+		assertSymlink(t, filepath.Join(home, ".config", "nvim", "init.lua"), filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua"))
+		m, err := LoadManifest(home)
+		if err != nil {
+			t.Fatal(err)
+		}
+		own := 0
+		for _, l := range m.Links {
+			if strings.HasPrefix(l.Path, home+string(filepath.Separator)) {
+				own++
+			}
+		}
+		if own != 2 {
+			t.Errorf("%s manifest = %+v; want its own 2 links", name, m)
+		}
+	}
+
+	// Each user's links are made as that user, and nothing is chowned after
+	if strings.Join(*switched, ",") != "alice,bob" {
+		t.Errorf("linked as %v, want alice then bob", *switched)
+	}
+	if writes := strings.Join(rec.writes, "\n"); strings.Contains(writes, "chown ") {
+		t.Errorf("changed owners after linking:\n%s", writes)
+	}
+	if data, err := os.ReadFile(outside); err != nil || string(data) != "root:x:0:0" {
+		t.Errorf("path outside the home changed: %q, %v", data, err)
+	}
+}
+
+func TestDeployFailures(t *testing.T) {
+	sourceDir, _ := setupPackagesTest(t)
+	alice := filepath.Join(t.TempDir(), "alice")
+	os.MkdirAll(alice, 0755)
+	fakeDeployUsers(t, map[string]string{"alice": alice, "ghost": filepath.Join(t.TempDir(), "missing")})
+	opts := DeployOptions{SourceDir: sourceDir, Packages: []string{"shell"}}
+
+	tests := []struct {
+		name  string
+		users []string
+		root  bool
+	}{
+		{"no users", nil, true},
+		{"not root", []string{"alice"}, false},
+		{"unknown user", []string{"alice", "carol"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runningAsRoot = func() bool { return tt.root }
+			opts.Users = tt.users
+			CaptureOutput(t, func() {
+				if err := Deploy(opts); err == nil || GetErrorHint(err) == "" {
+					t.Errorf("Deploy() = %v, want an error with a hint", err)
+				}
+			})
+			assertNotExists(t, filepath.Join(alice, ".bashrc"))
+		})
+	}
+
+	// A user without a home does not stop the others
+	runningAsRoot = func() bool { return true }
+	opts.Users = []string{"ghost", "alice"}
+	CaptureOutput(t, func() {
+		err := Deploy(opts)
+		if err == nil || !strings.Contains(err.Error(), "deploy failed for 1 of 2 user(s): ghost") {
+			t.Errorf("Deploy() with a missing home = %v", err)
+		}
+	})
+	assertSymlink(t, filepath.Join(alice, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+}
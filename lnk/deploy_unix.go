@@ -0,0 +1,55 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package lnk
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// runAsUser runs fn with the effective user, group, and supplementary groups
+// of u, then switches back to root. Everything fn creates belongs to u, and
+// the kernel checks each path fn touches with u's permissions, so a symlink u
+// plants in their home cannot lead root's writes elsewhere.
+func runAsUser(u deployUser, fn func() error) (err error) {
+	groups, err := syscall.Getgroups()
+	if err != nil {
+		return fmt.Errorf("read supplementary groups: %w", err)
+	}
+	gid := syscall.Getegid()
+	if err := syscall.Setgroups([]int{u.GID}); err != nil {
+		return fmt.Errorf("switch to the groups of %s: %w", u.Name, err)
+	}
+	defer restoreGroups(groups, &err)
+	if err := syscall.Setegid(u.GID); err != nil {
+		return fmt.Errorf("switch to the group of %s: %w", u.Name, err)
+	}
+	defer restoreGroup(gid, &err)
+	if err := syscall.Seteuid(u.UID); err != nil {
+		return fmt.Errorf("switch to user %s: %w", u.Name, err)
+	}
+	defer restoreUser(&err)
+	return fn()
+}
+
+// restoreUser switches the effective user back to root, reporting a failure
+// through err when fn itself succeeded
+func restoreUser(err *error) {
+	if e := syscall.Seteuid(0); e != nil && *err == nil {
+		*err = fmt.Errorf("switch back to root: %w", e)
+	}
+}
+
+// restoreGroup switches the effective group back to gid
+func restoreGroup(gid int, err *error) {
+	if e := syscall.Setegid(gid); e != nil && *err == nil {
+		*err = fmt.Errorf("switch back to group %d: %w", gid, e)
+	}
+}
+
+// restoreGroups restores root's supplementary groups
+func restoreGroups(groups []int, err *error) {
+	if e := syscall.Setgroups(groups); e != nil && *err == nil {
+		*err = fmt.Errorf("restore supplementary groups: %w", e)
+	}
+}
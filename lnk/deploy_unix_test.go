@@ -0,0 +1,49 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunAsUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("switching users needs root")
+	}
+	u := deployUser{Name: "nobody", UID: 12345, GID: 12345}
+	shared := t.TempDir()
+	os.Chmod(filepath.Dir(shared), 0755)
+	os.Chmod(shared, 0777)
+	rootOnly := t.TempDir()
+	os.Chmod(rootOnly, 0755)
+
+	err := runAsUser(u, func() error {
+		if os.Geteuid() != u.UID || os.Getegid() != u.GID {
+			t.Errorf("running as %d:%d, want %d:%d", os.Geteuid(), os.Getegid(), u.UID, u.GID)
+		}
+		if err := fsys.Symlink("/etc/passwd", filepath.Join(shared, "link")); err != nil {
+			t.Errorf("symlink in a shared directory: %v", err)
+		}
+		// A path the user cannot write stays out of reach, wherever a link points
+		if err := fsys.MkdirAll(filepath.Join(rootOnly, "dir"), 0755); err == nil {
+			t.Error("wrote to a directory only root can write")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runAsUser() error = %v", err)
+	}
+	if os.Geteuid() != 0 || os.Getegid() != 0 {
+		t.Errorf("still running as %d:%d after runAsUser", os.Geteuid(), os.Getegid())
+	}
+
+	info, err := os.Lstat(filepath.Join(shared, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid, ok := fileOwner(info); ok && uid != u.UID {
+		t.Errorf("link owned by %d, want %d", uid, u.UID)
+	}
+}
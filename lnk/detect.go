@@ -0,0 +1,319 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Profiles choose packages by machine. The profile_rules in a source
+// directory's .lnkprofiles are tried in order and the first whose hostname
+// pattern and condition match this machine selects its packages, ahead of
+// .lnkpackages. 'lnk detect' shows the machine facts and which rule matched.
+
+// ProfilesFile is the contents of .lnkprofiles
+type ProfilesFile struct {
+	ProfileRules []ProfileRule `json:"profile_rules"`
+}
+
+// ProfileRule selects packages on machines that match it. A rule with no
+// hostname pattern and an empty condition matches every machine, so it can
+// be the last rule as a fallback.
+type ProfileRule struct {
+	Name     string    `json:"name"`
+	Hostname string    `json:"hostname,omitempty"` // glob the hostname must match (path.Match syntax)
+	When     Condition `json:"when,omitempty"`     // condition that must hold (see conditions.go)
+	Packages []string  `json:"packages"`           // packages the profile selects
+}
+
+// isManaged reports whether the machine is enrolled in device management. It
+// is a package variable so tests can simulate a managed machine.
+var isManaged = detectMDM
+
+// mdmMarkers are files device management leaves behind, by GOOS. Their
+// presence is a heuristic: a machine can be managed without any of them.
+var mdmMarkers = map[string][]string{
+	"darwin": {
+		"/Library/Managed Preferences",
+		"/var/db/ConfigurationProfiles/Settings/.cloudConfigProfileInstalled",
+	},
+	"linux": {
+		"/opt/microsoft/intune",
+	},
+	"windows": {
+		`C:\ProgramData\Microsoft\DMClient`,
+	},
+}
+
+// detectMDM checks for the device management markers of this platform
+func detectMDM() bool {
+	for _, marker := range mdmMarkers[runtime.GOOS] {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// sshSession reports whether lnk runs in an SSH session rather than locally
+func sshSession(getenv func(string) string) bool {
+	return getenv("SSH_CONNECTION") != "" || getenv("SSH_CLIENT") != "" || getenv("SSH_TTY") != ""
+}
+
+// LoadProfileRules loads the profile rules from .lnkprofiles in the source
+// directory. A missing file means no rules.
+func LoadProfileRules(sourceDir string) ([]ProfileRule, error) {
+	path := filepath.Join(sourceDir, ProfilesFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			PrintVerbose("No .lnkprofiles file found at: %s", path)
+			return nil, nil
+		}
+		return nil, NewPathErrorWithHint("read profile rules", path, err, "Check file permissions")
+	}
+
+	rules, err := parseProfileRules(path, data)
+	if err != nil {
+		return nil, err
+	}
+	PrintVerbose("Loaded %d profile rules from .lnkprofiles", len(rules))
+	return rules, nil
+}
+
+// parseProfileRules decodes and checks the contents of the .lnkprofiles file
+// at path
+func parseProfileRules(path string, data []byte) ([]ProfileRule, error) {
+	var file ProfilesFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, NewPathErrorWithHint("parse profile rules", path, err,
+			fmt.Sprintf("Fix the JSON in %s; rules have name, hostname, when, and packages", ContractPath(path)))
+	}
+
+	seen := make(map[string]bool)
+	for i, rule := range file.ProfileRules {
+		hint := fmt.Sprintf("Fix profile_rules in %s", ContractPath(path))
+		switch {
+		case rule.Name == "":
+			return nil, NewValidationErrorWithHint("profile_rules", fmt.Sprintf("rule %d", i+1), "rule has no name", hint)
+		case seen[rule.Name]:
+			return nil, NewValidationErrorWithHint("profile_rules", rule.Name, "duplicate profile name", hint)
+		case len(rule.Packages) == 0:
+			return nil, NewValidationErrorWithHint("profile_rules", rule.Name, "profile selects no packages", hint)
+		}
+		if _, err := pathMatch(rule.Hostname, ""); err != nil {
+			return nil, NewValidationErrorWithHint("profile_rules", rule.Hostname, "invalid hostname pattern",
+				"Use a glob such as \"*.corp.example.com\" (*, ?, and [...] are supported)")
+		}
+		seen[rule.Name] = true
+	}
+	return file.ProfileRules, nil
+}
+
+// pathMatch is path.Match, treating an empty pattern as matching everything
+func pathMatch(pattern, name string) (bool, error) {
+	if pattern == "" {
+		return true, nil
+	}
+	return path.Match(pattern, name)
+}
+
+// matches reports whether the rule applies to the machine described by env,
+// with a short explanation
+func (r ProfileRule) matches(env exprEnv) (bool, string, error) {
+	var reasons []string
+	if r.Hostname != "" {
+		host, err := env.hostname()
+		if err != nil {
+			PrintVerbose("Failed to get hostname: %v", err)
+		}
+		ok, err := pathMatch(r.Hostname, host)
+		if err != nil {
+			return false, "", err
+		}
+		if !ok {
+			return false, fmt.Sprintf("hostname %q does not match %q", host, r.Hostname), nil
+		}
+		reasons = append(reasons, fmt.Sprintf("hostname %q matches %q", host, r.Hostname))
+	}
+
+	ok, why, err := r.When.evaluateIn(env)
+	if err != nil {
+		return false, "", err
+	}
+	if why != "" {
+		reasons = append(reasons, why)
+	}
+	if !ok {
+		return false, why, nil
+	}
+	if len(reasons) == 0 {
+		return true, "matches every machine", nil
+	}
+	return true, strings.Join(reasons, ", "), nil
+}
+
+// profileMatch is the outcome of trying one rule
+type profileMatch struct {
+	rule    ProfileRule
+	matched bool
+	why     string
+}
+
+// matchProfiles tries every rule against env and returns the outcomes in rule
+// order along with the first matching rule, or nil when none matches. sourceDir
+// names the rules file in errors.
+func matchProfiles(sourceDir string, rules []ProfileRule, env exprEnv) ([]profileMatch, *ProfileRule, error) {
+	var results []profileMatch
+	var selected *ProfileRule
+	for i, rule := range rules {
+		ok, why, err := rule.matches(env)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: profile %s: %w",
+				ContractPath(filepath.Join(sourceDir, ProfilesFileName)), rule.Name, err)
+		}
+		results = append(results, profileMatch{rule: rule, matched: ok, why: why})
+		if ok && selected == nil {
+			selected = &rules[i]
+		}
+	}
+	return results, selected, nil
+}
+
+// selectProfile returns the profile rule in sourceDir called name, or with no
+// name the first that matches this machine, or nil when there are no rules or
+// none matches
+func selectProfile(sourceDir, name string) (*ProfileRule, error) {
+	rules, err := LoadProfileRules(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		return namedProfile(sourceDir, rules, name)
+	}
+	_, selected, err := matchProfiles(sourceDir, rules, defaultExprEnv())
+	if err != nil {
+		return nil, err
+	}
+	if selected != nil {
+		PrintVerbose("Profile %s matches this machine", selected.Name)
+	}
+	return selected, nil
+}
+
+// namedProfile returns the rule called name, for LNK_PROFILE; its conditions
+// are not evaluated
+func namedProfile(sourceDir string, rules []ProfileRule, name string) (*ProfileRule, error) {
+	var names []string
+	for i := range rules {
+		if rules[i].Name == name {
+			PrintVerbose("Profile %s selected by %s", name, EnvProfile)
+			return &rules[i], nil
+		}
+		names = append(names, rules[i].Name)
+	}
+	hint := fmt.Sprintf("Add profile_rules to %s, or unset %s", ContractPath(filepath.Join(sourceDir, ProfilesFileName)), EnvProfile)
+	if match := ClosestMatch(name, names, 2); match != "" {
+		hint = fmt.Sprintf("Did you mean %s?", match)
+	} else if len(names) > 0 {
+		hint = fmt.Sprintf("Profiles: %s", strings.Join(names, ", "))
+	}
+	return nil, NewValidationErrorWithHint(EnvProfile, name, "no such profile", hint)
+}
+
+// Detect prints the machine facts profile rules can use, whether each rule in
+// the source directory's .lnkprofiles matches, and the profile selected.
+func Detect(sourceDir string) error {
+	return detect(sourceDir, defaultExprEnv())
+}
+
+// detect is Detect against the machine described by env
+func detect(sourceDir string, env exprEnv) error {
+	PrintCommandHeader("Detecting Machine Profile")
+
+	rules, err := LoadProfileRules(sourceDir)
+	if err != nil {
+		return err
+	}
+	results, selected, err := matchProfiles(sourceDir, rules, env)
+	if err != nil {
+		return err
+	}
+	envProfile := strings.TrimSpace(env.getenv(EnvProfile))
+	if envProfile != "" {
+		if selected, err = namedProfile(sourceDir, rules, envProfile); err != nil {
+			return err
+		}
+	}
+
+	host, err := env.hostname()
+	if err != nil {
+		PrintVerbose("Failed to get hostname: %v", err)
+	}
+	facts := []exprBinding{
+		{"os", exprValue{str: env.goos}.String()},
+		{"arch", exprValue{str: env.goarch}.String()},
+		{"hostname", exprValue{str: host}.String()},
+		{"wsl", boolValue(env.wsl()).String()},
+		{"ssh", boolValue(sshSession(env.getenv)).String()},
+		{"mdm", boolValue(env.mdm()).String()},
+	}
+
+	if ShouldSimplifyOutput() {
+		for _, f := range facts {
+			fmt.Printf("fact %s %s\n", f.name, f.value)
+		}
+		for _, r := range results {
+			state := "nomatch"
+			if r.matched {
+				state = "match"
+			}
+			fmt.Printf("rule %s %s\n", r.rule.Name, state)
+		}
+		if selected != nil {
+			fmt.Printf("profile %s %s\n", selected.Name, strings.Join(selected.Packages, ","))
+		}
+		return nil
+	}
+
+	for _, f := range facts {
+		PrintDetail("%s = %s", f.name, f.value)
+	}
+	fmt.Println()
+
+	if len(rules) == 0 {
+		PrintEmptyResult("profile rules")
+		PrintInfo("Add profile_rules to %s to choose packages by machine",
+			ContractPath(filepath.Join(sourceDir, ProfilesFileName)))
+		return nil
+	}
+	for _, r := range results {
+		if r.matched {
+			PrintSuccess("%s: %s", r.rule.Name, r.why)
+		} else {
+			PrintSkip("%s: %s", r.rule.Name, r.why)
+		}
+	}
+
+	if selected == nil {
+		fmt.Println()
+		PrintInfo("No profile matches this machine; packages come from --packages, %s, or %s",
+			EnvPackages, PackagesFileName)
+		return nil
+	}
+	PrintSummary("Profile %s: %s", selected.Name, strings.Join(selected.Packages, ", "))
+	if envProfile != "" {
+		PrintInfo("%s is set and selects this profile whatever its rules say", EnvProfile)
+	}
+	if len(splitList(os.Getenv(EnvPackages))) > 0 {
+		PrintInfo("%s is set and overrides the profile's packages", EnvPackages)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package lnk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeProfiles(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, ProfilesFileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadProfileRules(t *testing.T) {
+	dir := t.TempDir()
+	rules, err := LoadProfileRules(dir)
+	if err != nil || rules != nil {
+		t.Fatalf("LoadProfileRules() without file = %v, %v, want nil, nil", rules, err)
+	}
+
+	writeProfiles(t, dir, `{"profile_rules": [
+		{"name": "work", "hostname": "work-*", "when": {"if": "mdm"}, "packages": ["shell", "work"]},
+		{"name": "home", "packages": ["shell"]}
+	]}`)
+	rules, err = LoadProfileRules(dir)
+	if err != nil {
+		t.Fatalf("LoadProfileRules() error = %v", err)
+	}
+	if len(rules) != 2 || rules[0].Name != "work" || rules[0].When.If != "mdm" || !slices.Equal(rules[1].Packages, []string{"shell"}) {
+		t.Errorf("LoadProfileRules() = %+v", rules)
+	}
+
+	invalid := map[string]string{
+		"no name":       `{"profile_rules": [{"packages": ["shell"]}]}`,
+		"duplicate":     `{"profile_rules": [{"name": "a", "packages": ["x"]}, {"name": "a", "packages": ["y"]}]}`,
+		"no packages":   `{"profile_rules": [{"name": "a"}]}`,
+		"bad glob":      `{"profile_rules": [{"name": "a", "hostname": "[", "packages": ["x"]}]}`,
+		"unknown field": `{"profile_rules": [{"name": "a", "host": "x", "packages": ["x"]}]}`,
+	}
+	for name, content := range invalid {
+		t.Run(name, func(t *testing.T) {
+			writeProfiles(t, dir, content)
+			if _, err := LoadProfileRules(dir); err == nil {
+				t.Error("LoadProfileRules() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestMatchProfiles(t *testing.T) {
+	rules := []ProfileRule{
+		{Name: "server", When: Condition{If: "ssh"}, Packages: []string{"shell"}},
+		{Name: "work", Hostname: "work-*", When: Condition{If: "wsl"}, Packages: []string{"shell", "work"}},
+		{Name: "home", Packages: []string{"shell", "games"}},
+	}
+	results, selected, err := matchProfiles("/src", rules, testExprEnv())
+	if err != nil {
+		t.Fatalf("matchProfiles() error = %v", err)
+	}
+	if selected == nil || selected.Name != "work" {
+		t.Fatalf("matchProfiles() selected %+v, want work", selected)
+	}
+	matched := []bool{results[0].matched, results[1].matched, results[2].matched}
+	if !slices.Equal(matched, []bool{false, true, true}) {
+		t.Errorf("matchProfiles() matched = %v, want [false true true]", matched)
+	}
+	if !strings.Contains(results[1].why, `hostname "work-laptop" matches "work-*"`) {
+		t.Errorf("work explanation = %q", results[1].why)
+	}
+
+	rules[1].Hostname = "build-*"
+	if _, selected, _ = matchProfiles("/src", rules, testExprEnv()); selected == nil || selected.Name != "home" {
+		t.Errorf("matchProfiles() with other hostname selected %+v, want home", selected)
+	}
+
+	rules[0].When.If = "os =="
+	var ve *ValidationError
+	if _, _, err := matchProfiles("/src", rules, testExprEnv()); !errors.As(err, &ve) || !strings.Contains(err.Error(), "profile server") {
+		t.Errorf("matchProfiles() with invalid expression error = %v, want ValidationError naming the profile", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	writeProfiles(t, dir, `{"profile_rules": [
+		{"name": "managed", "when": {"if": "mdm"}, "packages": ["corp"]},
+		{"name": "work", "hostname": "work-*", "packages": ["shell", "work"]}
+	]}`)
+
+	output := CaptureOutput(t, func() {
+		if err := detect(dir, testExprEnv()); err != nil {
+			t.Fatalf("detect() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, `fact hostname "work-laptop"`, "fact wsl true", "fact mdm false",
+		"rule managed nomatch", "rule work match", "profile work shell,work")
+
+	env := testExprEnv()
+	env.getenv = func(name string) string {
+		if name == EnvProfile {
+			return "managed"
+		}
+		return ""
+	}
+	output = CaptureOutput(t, func() {
+		if err := detect(dir, env); err != nil {
+			t.Fatalf("detect() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "rule managed nomatch", "profile managed corp")
+}
+
+func TestLoadConfigProfile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, PackagesFileName), []byte("shell\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeProfiles(t, dir, `{"profile_rules": [
+		{"name": "other", "when": {"if": "os == \"plan9\""}, "packages": ["plan9"]},
+		{"name": "this", "when": {"if": "os == \"`+runtime.GOOS+`\""}, "packages": ["shell", "nvim"]}
+	]}`)
+
+	config, err := LoadConfig(dir, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if config.Profile == nil || config.Profile.Name != "this" {
+		t.Fatalf("LoadConfig() Profile = %+v, want this", config.Profile)
+	}
+	packages, from := config.ResolvePackages(nil)
+	if !slices.Equal(packages, []string{"shell", "nvim"}) || from != ProfilesFileName {
+		t.Errorf("ResolvePackages() = %v, %q, want profile packages from %s", packages, from, ProfilesFileName)
+	}
+
+	// LNK_PROFILE selects a profile whatever its rules say
+	t.Setenv(EnvProfile, "other")
+	config, err = LoadConfig(dir, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if packages, from := config.ResolvePackages(nil); !slices.Equal(packages, []string{"plan9"}) || from != EnvProfile {
+		t.Errorf("ResolvePackages() with %s = %v, %q, want the named profile's packages", EnvProfile, packages, from)
+	}
+	t.Setenv(EnvProfile, "thiss")
+	if _, err := LoadConfig(dir, nil); !strings.Contains(GetErrorHint(err), "Did you mean this?") {
+		t.Errorf("LoadConfig() with an unknown profile error = %v, want a Did you mean hint", err)
+	}
+	t.Setenv(EnvProfile, "this")
+
+	t.Setenv(EnvPackages, "nvim")
+	config, err = LoadConfig(dir, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if _, from := config.ResolvePackages(nil); from != EnvPackages {
+		t.Errorf("ResolvePackages() with %s from %q, want it to override the profile", EnvPackages, from)
+	}
+}
@@ -0,0 +1,198 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DirPolicy controls the missing parent directories lnk creates for links,
+// from .lnkdirs in the source directory. A nil policy creates them with mode
+// 0755 (less the umask), owned by the user running lnk, anywhere.
+type DirPolicy struct {
+	Mode  string   `json:"mode,omitempty"`  // octal permissions, such as "0700"; applied exactly, ignoring the umask
+	Owner string   `json:"owner,omitempty"` // user to own them; only root can give directories away
+	Group string   `json:"group,omitempty"` // group to own them
+	Allow []string `json:"allow,omitempty"` // directories relative to ~ at or under which parents may be created (empty = anywhere)
+
+	perm     os.FileMode // parsed Mode, 0 when unset
+	uid, gid int         // looked-up Owner and Group, -1 when unset
+}
+
+// lookupGroup resolves a group name; replaced in tests
+var lookupGroup = user.LookupGroup
+
+// LoadDirPolicy reads .lnkdirs from the source directory. A missing file is
+// not an error; nil is returned instead.
+func LoadDirPolicy(sourceDir string) (*DirPolicy, error) {
+	path := filepath.Join(sourceDir, DirsFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			PrintVerbose("No .lnkdirs file found at: %s", path)
+			return nil, nil
+		}
+		return nil, NewPathErrorWithHint("read directory policy", path, err, "Check file permissions")
+	}
+
+	p, err := parseDirPolicy(path, data)
+	if err != nil {
+		return nil, err
+	}
+	PrintVerbose("Loaded directory policy from .lnkdirs: %s", strings.Join(p.Entries(), ", "))
+	return p, nil
+}
+
+// parseDirPolicy decodes and resolves the contents of the .lnkdirs file at
+// path
+func parseDirPolicy(path string, data []byte) (*DirPolicy, error) {
+	var p DirPolicy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, NewPathErrorWithHint("parse directory policy", path, err,
+			fmt.Sprintf("Fix the JSON in %s; it has mode, owner, group, and allow", ContractPath(path)))
+	}
+	if err := p.resolve(path); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// resolve parses Mode, looks up Owner and Group, and cleans Allow. path is
+// the .lnkdirs file, for hints.
+func (p *DirPolicy) resolve(path string) error {
+	hint := fmt.Sprintf("Fix %s", ContractPath(path))
+	p.uid, p.gid = -1, -1
+	if p.Mode != "" {
+		perm, err := strconv.ParseUint(p.Mode, 8, 32)
+		if err != nil || perm > 0777 {
+			return NewValidationErrorWithHint("mode", p.Mode, "not octal permissions", hint+`; write the mode like "0700"`)
+		}
+		// lnk must be able to add links to the directories it creates
+		if perm&0300 != 0300 {
+			return NewValidationErrorWithHint("mode", p.Mode, "the owner cannot add links to such a directory",
+				hint+"; keep owner write and execute permission (0300)")
+		}
+		p.perm = os.FileMode(perm)
+	}
+	if p.Owner != "" {
+		u, err := lookupUser(p.Owner)
+		if err != nil {
+			return NewValidationErrorWithHint("owner", p.Owner, "no such user", hint)
+		}
+		p.uid, _ = strconv.Atoi(u.Uid)
+	}
+	if p.Group != "" {
+		g, err := lookupGroup(p.Group)
+		if err != nil {
+			return NewValidationErrorWithHint("group", p.Group, "no such group", hint)
+		}
+		p.gid, _ = strconv.Atoi(g.Gid)
+	}
+	for i, dir := range p.Allow {
+		dir = filepath.Clean(strings.TrimPrefix(dir, "~/"))
+		if !filepath.IsLocal(dir) {
+			return NewValidationErrorWithHint("allow", p.Allow[i], "not a path inside the home directory",
+				hint+`; write allowed directories relative to ~, such as ".config"`)
+		}
+		p.Allow[i] = dir
+	}
+	return nil
+}
+
+// Entries describes the policy's settings, one per entry, for 'lnk config'
+func (p *DirPolicy) Entries() []string {
+	if p == nil {
+		return nil
+	}
+	var entries []string
+	if p.Mode != "" {
+		entries = append(entries, "mode "+p.Mode)
+	}
+	if p.Owner != "" {
+		entries = append(entries, "owner "+p.Owner)
+	}
+	if p.Group != "" {
+		entries = append(entries, "group "+p.Group)
+	}
+	for _, dir := range p.Allow {
+		entries = append(entries, "allow ~/"+filepath.ToSlash(dir))
+	}
+	return entries
+}
+
+// mkdirMode returns the permissions to create directories with
+func (p *DirPolicy) mkdirMode() os.FileMode {
+	if p == nil || p.perm == 0 {
+		return 0755
+	}
+	return p.perm
+}
+
+// allows reports whether lnk may create dir, the outermost missing parent of a
+// link
+func (p *DirPolicy) allows(dir, targetDir string) bool {
+	if p == nil || len(p.Allow) == 0 {
+		return true
+	}
+	for _, allowed := range p.Allow {
+		if isWithin(dir, filepath.Join(targetDir, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply sets the mode and ownership of directories lnk just created. The mode
+// is set again because MkdirAll is subject to the umask.
+func (p *DirPolicy) apply(dirs []string) error {
+	if p == nil {
+		return nil
+	}
+	for _, dir := range dirs {
+		if p.perm != 0 {
+			if err := fsys.Chmod(dir, p.perm); err != nil {
+				return NewPathErrorWithHint("set mode", dir, err, "Check that you own the directory")
+			}
+		}
+		if p.uid != -1 || p.gid != -1 {
+			if err := fsys.Lchown(dir, p.uid, p.gid); err != nil {
+				return NewPathErrorWithHint("set owner", dir, err,
+					fmt.Sprintf("Only root can set owner in %s; a group must be one you belong to", DirsFileName))
+			}
+		}
+	}
+	return nil
+}
+
+// checkParentDirs refuses, before anything is created, links whose missing
+// parent directories are outside the directories the policy allows
+func checkParentDirs(links []PlannedLink, targetDir string, policy *DirPolicy) error {
+	if policy == nil || len(policy.Allow) == 0 {
+		return nil
+	}
+	checked := make(map[string]bool)
+	for _, link := range links {
+		parent := filepath.Dir(link.Target)
+		if checked[parent] {
+			continue
+		}
+		checked[parent] = true
+		missing := missingDirs(parent, targetDir)
+		if len(missing) == 0 {
+			continue
+		}
+		if outermost := missing[len(missing)-1]; !policy.allows(outermost, targetDir) {
+			return NewValidationErrorWithHint("allow", ContractPath(outermost),
+				fmt.Sprintf("%s needs it created, but %s does not allow it", ContractPath(link.Target), DirsFileName),
+				fmt.Sprintf("Create %s yourself, or add it to allow in %s", ContractPath(outermost), DirsFileName))
+		}
+	}
+	return nil
+}
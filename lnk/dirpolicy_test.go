@@ -0,0 +1,105 @@
+package lnk
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDirPolicy(t *testing.T) {
+	origLookup := lookupGroup
+	lookupGroup = func(name string) (*user.Group, error) {
+		if name != "staff" {
+			return nil, user.UnknownGroupError(name)
+		}
+		return &user.Group{Name: name, Gid: "20"}, nil
+	}
+	t.Cleanup(func() { lookupGroup = origLookup })
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr string
+	}{
+		{"missing", "", nil, ""},
+		{"all settings", `{"mode": "0700", "group": "staff", "allow": ["~/.config", ".local/share/"]}`,
+			[]string{"mode 0700", "group staff", "allow ~/.config", "allow ~/.local/share"}, ""},
+		{"not octal", `{"mode": "rwx"}`, nil, "not octal"},
+		{"owner cannot write", `{"mode": "0500"}`, nil, "cannot add links"},
+		{"unknown group", `{"group": "wheel2"}`, nil, "no such group"},
+		{"outside home", `{"allow": ["../elsewhere"]}`, nil, "inside the home directory"},
+		{"unknown key", `{"umask": "022"}`, nil, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceDir := t.TempDir()
+			if tt.content != "" {
+				createTestFile(t, filepath.Join(sourceDir, DirsFileName), tt.content)
+			}
+			policy, err := LoadDirPolicy(sourceDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadDirPolicy() error = %v, want %q", err, tt.wantErr)
+				}
+				if GetErrorHint(err) == "" {
+					t.Errorf("expected a hint, got none for %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadDirPolicy() error = %v", err)
+			}
+			if got := policy.Entries(); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Entries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateLinksDirPolicy(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	// Setting the group to the user's own group works without root
+	policy := &DirPolicy{Mode: "0700", Allow: []string{".config"}, perm: 0700, uid: -1, gid: os.Getgid()}
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"nvim"}, Dirs: policy}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	for _, dir := range []string{".config", filepath.Join(".config", "nvim")} {
+		info, err := os.Stat(filepath.Join(targetDir, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0700 {
+			t.Errorf("%s mode = %o, want 0700", dir, info.Mode().Perm())
+		}
+	}
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Dirs) != 2 {
+		t.Errorf("manifest dirs = %+v, want the 2 created parents", m.Dirs)
+	}
+
+	// Parents outside the allowlist are refused before anything is created
+	createTestFile(t, filepath.Join(sourceDir, "work", ".local", "bin", "tool"), "#!/bin/sh")
+	opts.Packages = []string{"work"}
+	var createErr error
+	CaptureOutput(t, func() {
+		_, createErr = CreateLinks(opts)
+	})
+	if createErr == nil || !strings.Contains(createErr.Error(), "does not allow") {
+		t.Fatalf("CreateLinks() error = %v, want refusal outside the allowlist", createErr)
+	}
+	if hint := GetErrorHint(createErr); !strings.Contains(hint, DirsFileName) {
+		t.Errorf("hint = %q, want it to mention %s", hint, DirsFileName)
+	}
+	assertNotExists(t, filepath.Join(targetDir, ".local", "bin"))
+	assertNotExists(t, filepath.Join(targetDir, ".gitconfig"))
+}
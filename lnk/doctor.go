@@ -0,0 +1,117 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Doctor checks that this machine can use the source directory: each package
+// in use must support the current platform and have the commands it lists in
+// lnk-package.json installed, and no local-only path may be linked into the
+// source. Problems are printed as warnings, and an error is returned when any
+// are found.
+func Doctor(opts LinkOptions) error {
+	PrintCommandHeader("Doctor")
+
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
+	PrintVerbose("Source directory: %s", sourceDir)
+
+	packages, err := expandPackageDeps(sourceDir, opts.Packages)
+	if err != nil {
+		return err
+	}
+	pkgDirs, err := packageDirs(sourceDir, packages)
+	if err != nil {
+		return err
+	}
+	localProblems, err := checkLocalOnly(sourceDir, pkgDirs, targetDir, opts.IgnorePatterns, opts.LocalOnly)
+	if err != nil {
+		return err
+	}
+
+	// Without a selection the whole source directory is linked, so every
+	// package's requirements apply
+	if len(packages) == 0 {
+		packages = availablePackages(sourceDir)
+	}
+
+	problems := append(checkPackages(sourceDir, packages), localProblems...)
+	for _, p := range problems {
+		PrintWarningWithHint(p)
+	}
+
+	if len(problems) == 0 {
+		PrintSuccess("No problems found")
+		return nil
+	}
+	return fmt.Errorf("doctor found %d problem(s)", len(problems))
+}
+
+// checkPackages reports packages that do not support this platform or whose
+// required commands are not installed
+func checkPackages(sourceDir string, packages []string) []error {
+	var problems []error
+	for _, pkg := range packages {
+		info, err := LoadPackageInfo(filepath.Join(sourceDir, pkg))
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		PrintVerbose("Checking package %s", pkg)
+
+		if !info.SupportsPlatform(runtime.GOOS) {
+			problems = append(problems, WithHint(
+				fmt.Errorf("Package %s does not support %s (platforms: %s)",
+					pkg, runtime.GOOS, strings.Join(info.Platforms, ", ")),
+				fmt.Sprintf("Remove %s from the selected packages on this machine", pkg)))
+		}
+		for _, action := range info.Reload {
+			if err := action.validate(); err != nil {
+				problems = append(problems, fmt.Errorf("Package %s: %w", pkg, err))
+			}
+		}
+		for _, cmd := range info.MissingCommands() {
+			problems = append(problems, WithHint(
+				fmt.Errorf("Package %s requires %s, which is not installed", pkg, cmd),
+				fmt.Sprintf("Install %s, or remove %s from the selected packages", cmd, pkg)))
+		}
+	}
+	return problems
+}
+
+// checkLocalOnly finds source files whose target is local-only. Each is noted,
+// since create skips it, and is a problem when its target is already a link to
+// it (made before the path was marked local-only).
+func checkLocalOnly(sourceDir string, pkgDirs []string, targetDir string, ignorePatterns, localOnly []string) ([]error, error) {
+	if len(localOnly) == 0 {
+		return nil, nil
+	}
+	planned, _, _, err := collectPackageLinks(pkgDirs, targetDir, ignorePatterns)
+	if err != nil {
+		return nil, err
+	}
+	_, local := filterLocalOnly(planned, targetDir, localOnly)
+
+	var problems []error
+	for _, link := range local {
+		dest, err := os.Readlink(link.Target)
+		if err == nil && !filepath.IsAbs(dest) {
+			dest = filepath.Join(filepath.Dir(link.Target), dest)
+		}
+		if err != nil || filepath.Clean(dest) != link.Source {
+			PrintInfo("Local-only: %s (not linked from %s)", ContractPath(link.Target), ContractPath(link.Source))
+			continue
+		}
+		problems = append(problems, WithHint(
+			fmt.Errorf("Local-only path %s is linked to %s", ContractPath(link.Target), ContractPath(link.Source)),
+			fmt.Sprintf("Replace the link with a local copy: lnk orphan %s %s", ContractPath(sourceDir), ContractPath(link.Target))))
+	}
+	return problems, nil
+}
@@ -0,0 +1,46 @@
+package lnk
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDoctor(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", PackageInfoFileName),
+		`{"commands": ["lnk-test-missing-command"]}`)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName),
+		`{"platforms": ["lnk-test-os"]}`)
+
+	var err error
+	_, stderr := captureOutput(t, func() {
+		err = Doctor(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir})
+	})
+
+	if err == nil {
+		t.Fatal("expected error when problems are found")
+	}
+	ContainsOutput(t, err.Error(), "2 problem(s)")
+	ContainsOutput(t, stderr,
+		"Package nvim requires lnk-test-missing-command, which is not installed",
+		"Package work does not support "+runtime.GOOS)
+}
+
+func TestDoctorSelectedPackages(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName),
+		`{"commands": ["lnk-test-missing-command"]}`)
+	createTestFile(t, filepath.Join(sourceDir, "shell", PackageInfoFileName),
+		`{"platforms": ["`+runtime.GOOS+`"]}`)
+
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = Doctor(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim"}})
+	})
+
+	if err != nil {
+		t.Fatalf("Doctor() error = %v\n%s", err, stderr)
+	}
+	ContainsOutput(t, stdout, "No problems found")
+}
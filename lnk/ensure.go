@@ -0,0 +1,100 @@
+package lnk
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Ensure brings the links for the source directory back after a login or
+// reboot. Without Fast it is CreateLinks. With Fast only the manifest is
+// read: each ephemeral link it records for the source directory that is
+// missing is recreated, and nothing is printed unless verbose or a link
+// cannot be restored, so it is cheap enough for a shell profile.
+func Ensure(opts LinkOptions) error {
+	if !opts.Fast {
+		results, err := CreateLinks(opts)
+		PrintCreateSummary(results, opts.SourceDir)
+		return err
+	}
+
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
+
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		return err
+	}
+
+	var restored, failed int
+	for _, l := range m.Links {
+		if l.Source != sourceDir || !l.Ephemeral {
+			continue
+		}
+		if _, err := fsys.Lstat(l.Path); err == nil {
+			continue
+		}
+		if _, err := fsys.Stat(l.Dest); err != nil {
+			PrintVerbose("Not restoring %s: %s no longer exists", ContractPath(l.Path), ContractPath(l.Dest))
+			continue
+		}
+		if opts.DryRun {
+			PrintDryRun("Would link: %s -> %s", ContractPath(l.Path), ContractPath(l.Dest))
+			continue
+		}
+		if err := fsys.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+			PrintWarningWithHint(fmt.Errorf("Failed to restore %s: %w", ContractPath(l.Path),
+				NewPathErrorWithHint("create directory", filepath.Dir(l.Path), err,
+					"Check that you have write permissions in the parent directory")))
+			failed++
+			continue
+		}
+		if err := CreateSymlink(l.Dest, l.Path); err != nil {
+			PrintWarningWithHint(fmt.Errorf("Failed to restore %s: %w", ContractPath(l.Path), err))
+			failed++
+			continue
+		}
+		if err := tagLink(l.Path, sourceDir); err != nil {
+			PrintVerbose("Failed to tag %s: %v", ContractPath(l.Path), err)
+		}
+		PrintVerbose("Restored: %s", ContractPath(l.Path))
+		restored++
+	}
+	SummaryCount("restored", restored)
+	SummaryCount("failed", failed)
+
+	if failed > 0 {
+		return WithHint(fmt.Errorf("failed to restore %d ephemeral link(s)", failed),
+			fmt.Sprintf("Run 'lnk ensure %s' without --fast to check every link", ContractPath(sourceDir)))
+	}
+	return nil
+}
+
+// recordEphemeralLinks stores where each ephemeral link points in the
+// manifest, so 'lnk ensure --fast' can recreate it without planning. The
+// links must already be recorded by recordCreatedLinks.
+func recordEphemeralLinks(targetDir string, links []PlannedLink) {
+	var ephemeral []PlannedLink
+	for _, link := range links {
+		if link.Ephemeral {
+			ephemeral = append(ephemeral, link)
+		}
+	}
+	if len(ephemeral) == 0 {
+		return
+	}
+
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to record ephemeral links: %w", err))
+		return
+	}
+	for _, link := range ephemeral {
+		m.MarkEphemeral(link.Target, link.Source)
+	}
+	if err := m.Save(targetDir); err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to record ephemeral links: %w", err))
+	}
+}
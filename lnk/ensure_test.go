@@ -0,0 +1,101 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnsureFast(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"target": "runtime"}`)
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"shell", "work"},
+	}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	gitconfig := filepath.Join(runtimeDir, ".gitconfig")
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	for _, l := range m.Links {
+		switch l.Path {
+		case gitconfig:
+			if !l.Ephemeral || l.Dest != filepath.Join(sourceDir, "work", ".gitconfig") {
+				t.Errorf("ephemeral link record = %+v", l)
+			}
+		case bashrc:
+			if l.Ephemeral || l.Dest != "" {
+				t.Errorf("regular link record = %+v", l)
+			}
+		}
+	}
+
+	// A reboot clears the runtime directory; the home directory keeps its
+	// links, except one the user removed on purpose
+	os.RemoveAll(runtimeDir)
+	os.Remove(bashrc)
+	opts.Fast = true
+
+	stdout, _ := captureOutput(t, func() {
+		opts.DryRun = true
+		if err := Ensure(opts); err != nil {
+			t.Fatalf("Ensure(DryRun) error = %v", err)
+		}
+	})
+	if !strings.Contains(stdout, "Would link: "+gitconfig) {
+		t.Errorf("Ensure(DryRun) output = %q", stdout)
+	}
+	assertNotExists(t, gitconfig)
+
+	stdout, stderr := captureOutput(t, func() {
+		opts.DryRun = false
+		if err := Ensure(opts); err != nil {
+			t.Fatalf("Ensure() error = %v", err)
+		}
+	})
+	if stdout != "" || stderr != "" {
+		t.Errorf("Ensure() should be quiet, got stdout %q stderr %q", stdout, stderr)
+	}
+	assertSymlink(t, gitconfig, filepath.Join(sourceDir, "work", ".gitconfig"))
+	assertNotExists(t, bashrc)
+}
+
+func TestEnsureFastSkipsRemovedSource(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"target": "runtime"}`)
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"work"}}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	gitconfig := filepath.Join(runtimeDir, ".gitconfig")
+	os.Remove(gitconfig)
+	os.Remove(filepath.Join(sourceDir, "work", ".gitconfig"))
+
+	opts.Fast = true
+	CaptureOutput(t, func() {
+		if err := Ensure(opts); err != nil {
+			t.Fatalf("Ensure() error = %v", err)
+		}
+	})
+	assertNotExists(t, gitconfig)
+}
@@ -0,0 +1,185 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Environment variables lnk reads. Most mirror a flag: the flag takes
+// precedence over the variable, and the variable over files in the source
+// directory.
+const (
+	EnvIgnore        = "LNK_IGNORE"         // extra ignore patterns, comma-separated (--ignore)
+	EnvPackages      = "LNK_PACKAGES"       // packages to use, comma-separated (--packages)
+	EnvNoColor       = "LNK_NO_COLOR"       // disable colored output (--no-color)
+	EnvYes           = "LNK_YES"            // answer yes to confirmation prompts (--yes)
+	EnvLogLevel      = "LNK_LOG_LEVEL"      // normal or verbose (--verbose)
+	EnvReadOnly      = "LNK_READ_ONLY"      // refuse file system changes (--read-only)
+	EnvPager         = "LNK_PAGER"          // pager for long output, or cat for none (--no-pager)
+	EnvPathDisplay   = "LNK_PATH_DISPLAY"   // how paths are shown, comma-separated (--path-display)
+	EnvAgeIdentity   = "LNK_AGE_IDENTITY"   // age identity file decrypting .lnkprivate.age
+	EnvNoBatch       = "LNK_NO_BATCH"       // create links one path at a time, without the Linux fast path
+	EnvNoFetch       = "LNK_NO_FETCH"       // never fetch sparse-checkout packages or LFS objects on demand
+	EnvProfile       = "LNK_PROFILE"        // profile in .lnkprofiles to use, whatever its rules say
+	EnvTargetDir     = "LNK_TARGET_DIR"     // directory links are created in (default: ~)
+	EnvConfig        = "LNK_CONFIG"         // lnk's user configuration directory (default: $XDG_CONFIG_HOME/lnk)
+	EnvTemplateFuncs = "LNK_TEMPLATE_FUNCS" // template functions to turn on, or off with a leading -, comma-separated
+)
+
+// EnvVars lists the supported environment variables
+var EnvVars = []string{EnvIgnore, EnvPackages, EnvNoColor, EnvYes, EnvLogLevel, EnvReadOnly, EnvPager, EnvPathDisplay, EnvAgeIdentity, EnvNoBatch, EnvNoFetch,
+	EnvProfile, EnvTargetDir, EnvConfig, EnvTemplateFuncs}
+
+// LogLevels lists the valid LNK_LOG_LEVEL values
+var LogLevels = []string{"normal", "verbose"}
+
+// Env holds the settings read from LNK_ environment variables
+type Env struct {
+	IgnorePatterns []string        // LNK_IGNORE
+	Packages       []string        // LNK_PACKAGES
+	NoColor        bool            // LNK_NO_COLOR
+	Yes            bool            // LNK_YES
+	Verbose        bool            // LNK_LOG_LEVEL=verbose
+	ReadOnly       bool            // LNK_READ_ONLY
+	Pager          string          // LNK_PAGER
+	PathDisplay    PathDisplay     // LNK_PATH_DISPLAY
+	NoBatch        bool            // LNK_NO_BATCH
+	NoFetch        bool            // LNK_NO_FETCH
+	Profile        string          // LNK_PROFILE
+	TargetDir      string          // LNK_TARGET_DIR, absolute
+	ConfigDir      string          // LNK_CONFIG, absolute
+	TemplateFuncs  map[string]bool // LNK_TEMPLATE_FUNCS applied to the defaults
+}
+
+// LoadEnv reads and validates the LNK_ environment variables. Unset and
+// empty variables leave their setting at its default.
+func LoadEnv() (*Env, error) {
+	env := &Env{
+		IgnorePatterns: splitList(os.Getenv(EnvIgnore)),
+		Packages:       splitList(os.Getenv(EnvPackages)),
+		Pager:          strings.TrimSpace(os.Getenv(EnvPager)),
+		Profile:        strings.TrimSpace(os.Getenv(EnvProfile)),
+	}
+
+	var err error
+	if env.NoColor, err = envBool(EnvNoColor); err != nil {
+		return nil, err
+	}
+	if env.Yes, err = envBool(EnvYes); err != nil {
+		return nil, err
+	}
+	if env.ReadOnly, err = envBool(EnvReadOnly); err != nil {
+		return nil, err
+	}
+	if env.NoBatch, err = envBool(EnvNoBatch); err != nil {
+		return nil, err
+	}
+	if env.NoFetch, err = envBool(EnvNoFetch); err != nil {
+		return nil, err
+	}
+	if env.TargetDir, err = envDir(EnvTargetDir); err != nil {
+		return nil, err
+	}
+	if env.ConfigDir, err = envDir(EnvConfig); err != nil {
+		return nil, err
+	}
+	if env.TemplateFuncs, err = ParseTemplateFuncs(splitList(os.Getenv(EnvTemplateFuncs))); err != nil {
+		return nil, err
+	}
+	if env.PathDisplay, err = ParsePathDisplay(splitList(strings.ToLower(os.Getenv(EnvPathDisplay)))); err != nil {
+		return nil, err
+	}
+
+	switch level := strings.ToLower(os.Getenv(EnvLogLevel)); level {
+	case "", "normal":
+	case "verbose":
+		env.Verbose = true
+	default:
+		return nil, NewValidationErrorWithHint(EnvLogLevel, level, "unknown log level",
+			fmt.Sprintf("Valid levels: %s", strings.Join(LogLevels, ", ")))
+	}
+	return env, nil
+}
+
+// ResolveTargetDir returns the directory links are created in: LNK_TARGET_DIR,
+// which must exist, or the home directory when it is unset
+func (e *Env) ResolveTargetDir() (string, error) {
+	if e.TargetDir == "" {
+		return ExpandPath("~")
+	}
+	if info, err := os.Stat(e.TargetDir); err != nil || !info.IsDir() {
+		return "", NewValidationErrorWithHint(EnvTargetDir, ContractPath(e.TargetDir), "directory does not exist",
+			fmt.Sprintf("Create %s, or unset %s to link into ~", ContractPath(e.TargetDir), EnvTargetDir))
+	}
+	PrintVerbose("Target directory: %s (%s)", ContractPath(e.TargetDir), EnvTargetDir)
+	return e.TargetDir, nil
+}
+
+// UnknownEnvVars returns a warning for every set LNK_ variable lnk does not
+// read, so a misspelled or unsupported variable does not silently do nothing
+func UnknownEnvVars() []error {
+	var warnings []error
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, "LNK_") || slices.Contains(EnvVars, name) {
+			continue
+		}
+		hint := fmt.Sprintf("Supported variables: %s", strings.Join(EnvVars, ", "))
+		if match := ClosestMatch(name, EnvVars, 2); match != "" {
+			hint = fmt.Sprintf("Did you mean %s?", match)
+		}
+		warnings = append(warnings, WithHint(fmt.Errorf("unknown environment variable %s", name), hint))
+	}
+	slices.SortFunc(warnings, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return warnings
+}
+
+// envBool parses a boolean environment variable: 1, true, or yes enable it,
+// and 0, false, no, or empty leave it off
+func envBool(name string) (bool, error) {
+	switch value := strings.ToLower(os.Getenv(name)); value {
+	case "", "0", "false", "no":
+		return false, nil
+	case "1", "true", "yes":
+		return true, nil
+	default:
+		return false, NewValidationErrorWithHint(name, value, "not a boolean",
+			fmt.Sprintf("Set %s=1 to enable it or unset it", name))
+	}
+}
+
+// envDir reads a directory environment variable: ~ is expanded, and the path
+// must be absolute and, if it exists, a directory. Unset or empty is "".
+func envDir(name string) (string, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return "", nil
+	}
+	path, err := ExpandPath(value)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		return "", NewValidationErrorWithHint(name, value, "not an absolute path",
+			fmt.Sprintf("Set %s to an absolute path or one starting with ~/", name))
+	}
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return "", NewValidationErrorWithHint(name, value, "not a directory",
+			fmt.Sprintf("%s names a directory; %s is a file", name, ContractPath(path)))
+	}
+	return filepath.Clean(path), nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
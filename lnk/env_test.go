@@ -0,0 +1,144 @@
+package lnk
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv(EnvIgnore, "*.bak, local/,")
+	t.Setenv(EnvPackages, "shell,nvim")
+	t.Setenv(EnvNoColor, "TRUE")
+	t.Setenv(EnvYes, "0")
+	t.Setenv(EnvLogLevel, "verbose")
+	t.Setenv(EnvReadOnly, "yes")
+	t.Setenv(EnvProfile, " work ")
+	t.Setenv(EnvTargetDir, "/srv/home/")
+	t.Setenv(EnvConfig, "~/lnk-config")
+
+	env, err := LoadEnv()
+	if err != nil {
+		t.Fatalf("LoadEnv() error = %v", err)
+	}
+	if !slices.Equal(env.IgnorePatterns, []string{"*.bak", "local/"}) {
+		t.Errorf("IgnorePatterns = %v", env.IgnorePatterns)
+	}
+	if !slices.Equal(env.Packages, []string{"shell", "nvim"}) {
+		t.Errorf("Packages = %v", env.Packages)
+	}
+	if !env.NoColor || env.Yes || !env.Verbose || !env.ReadOnly {
+		t.Errorf("got NoColor=%v Yes=%v Verbose=%v ReadOnly=%v, want true false true true",
+			env.NoColor, env.Yes, env.Verbose, env.ReadOnly)
+	}
+	home, _ := ExpandPath("~")
+	if env.Profile != "work" || env.TargetDir != "/srv/home" || env.ConfigDir != filepath.Join(home, "lnk-config") {
+		t.Errorf("got Profile=%q TargetDir=%q ConfigDir=%q", env.Profile, env.TargetDir, env.ConfigDir)
+	}
+}
+
+func TestLoadEnvInvalid(t *testing.T) {
+	tests := []struct {
+		name, value string
+	}{
+		{EnvYes, "sure"},
+		{EnvNoColor, "2"},
+		{EnvLogLevel, "debug"},
+		{EnvTargetDir, "relative/home"},
+		{EnvConfig, "/dev/null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			_, err := LoadEnv()
+			var verr *ValidationError
+			if !errors.As(err, &verr) || verr.Field != tt.name {
+				t.Errorf("LoadEnv() error = %v, want ValidationError for %s", err, tt.name)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEnvPrecedence(t *testing.T) {
+	sourceDir, _ := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, PackagesFileName), "work\n")
+	createTestFile(t, filepath.Join(sourceDir, IgnoreFileName), "file-pattern\n")
+	t.Setenv(EnvIgnore, "env-pattern")
+	t.Setenv(EnvPackages, "shell")
+
+	config, err := LoadConfig(sourceDir, []string{"cli-pattern"})
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	n := len(config.IgnorePatterns)
+	if !slices.Equal(config.IgnorePatterns[n-3:], []string{"file-pattern", "env-pattern", "cli-pattern"}) {
+		t.Errorf("IgnorePatterns = %v, want file, env, then CLI patterns last", config.IgnorePatterns)
+	}
+
+	if got, from := config.ResolvePackages(nil); !slices.Equal(got, []string{"shell"}) || from != EnvPackages {
+		t.Errorf("ResolvePackages(nil) = %v, %s, want LNK_PACKAGES over .lnkpackages", got, from)
+	}
+	if got, from := config.ResolvePackages([]string{"nvim"}); !slices.Equal(got, []string{"nvim"}) || from != "--packages" {
+		t.Errorf("ResolvePackages(nvim) = %v, %s, want --packages over LNK_PACKAGES", got, from)
+	}
+}
+
+func TestLoadConfigTargetDir(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	t.Setenv(EnvTargetDir, targetDir)
+	config, err := LoadConfig(sourceDir, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if config.TargetDir != targetDir {
+		t.Errorf("TargetDir = %s, want %s from %s", config.TargetDir, targetDir, EnvTargetDir)
+	}
+
+	t.Setenv(EnvTargetDir, filepath.Join(targetDir, "missing"))
+	var verr *ValidationError
+	if _, err := LoadConfig(sourceDir, nil); !errors.As(err, &verr) || verr.Field != EnvTargetDir {
+		t.Errorf("LoadConfig() with a missing %s error = %v, want a ValidationError", EnvTargetDir, err)
+	}
+
+	t.Setenv(EnvTargetDir, "")
+	config, err = LoadConfig(sourceDir, nil)
+	if home, _ := ExpandPath("~"); err != nil || config.TargetDir != home {
+		t.Errorf("LoadConfig() without %s = %v, %v; want the home directory", EnvTargetDir, config, err)
+	}
+}
+
+func TestUnknownEnvVars(t *testing.T) {
+	t.Setenv("LNK_PAKAGES", "shell")
+	t.Setenv("LNK_TARGETDIR", "/tmp")
+	t.Setenv(EnvTargetDir, "/tmp")
+	t.Setenv(EnvYes, "1")
+
+	var got []string
+	for _, err := range UnknownEnvVars() {
+		got = append(got, err.Error()+" | "+GetErrorHint(err))
+	}
+	want := []string{
+		"unknown environment variable LNK_PAKAGES | Did you mean LNK_PACKAGES?",
+		"unknown environment variable LNK_TARGETDIR | Did you mean LNK_TARGET_DIR?",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("UnknownEnvVars() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	origCanPrompt := canPrompt
+	canPrompt = func() bool { return false }
+	t.Cleanup(func() { canPrompt = origCanPrompt; SetAssumeYes(false) })
+
+	if confirm("Proceed? [y/N]") {
+		t.Error("confirm() = true without a terminal")
+	}
+	SetAssumeYes(true)
+	if !confirm("Proceed? [y/N]") {
+		t.Error("confirm() = false with --yes")
+	}
+}
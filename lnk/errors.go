@@ -12,6 +12,27 @@ var (
 
 	// ErrAlreadyAdopted indicates that a file is already adopted
 	ErrAlreadyAdopted = errors.New("file already adopted")
+
+	// ErrLocalOnly indicates that a target path is marked local-only in .lnklocal
+	ErrLocalOnly = errors.New("path is local-only")
+
+	// ErrSensitive indicates that a file matches a pattern in .lnksensitive
+	ErrSensitive = errors.New("file is sensitive")
+
+	// ErrReadOnly indicates that a file system change was refused by --read-only
+	ErrReadOnly = errors.New("refused in read-only mode")
+
+	// ErrSymlinkLoop indicates a symlink chain that loops or is too long to follow
+	ErrSymlinkLoop = errors.New("symlink loop")
+
+	// ErrLocked indicates that another lnk process is changing the target directory
+	ErrLocked = errors.New("another lnk is changing this directory")
+
+	// ErrProtected indicates that a change to a protected target was refused
+	ErrProtected = errors.New("target is protected")
+
+	// ErrChangedConcurrently indicates that a path changed after lnk looked at it
+	ErrChangedConcurrently = errors.New("changed by another program during this run")
 )
 
 // PathError represents an error related to a specific path
@@ -146,3 +167,70 @@ func GetErrorHint(err error) string {
 	}
 	return ""
 }
+
+// Error codes reported in ErrorRecord
+const (
+	CodeError          = "error"           // any other error
+	CodeValidation     = "validation"      // ValidationError
+	CodePath           = "path"            // PathError
+	CodeLink           = "link"            // LinkError
+	CodeNotSymlink     = "not_symlink"     // ErrNotSymlink
+	CodeAlreadyAdopted = "already_adopted" // ErrAlreadyAdopted
+	CodeLocalOnly      = "local_only"      // ErrLocalOnly
+	CodeSensitive      = "sensitive"       // ErrSensitive
+	CodeReadOnly       = "read_only"       // ErrReadOnly
+	CodeSymlinkLoop    = "symlink_loop"    // ErrSymlinkLoop
+	CodeLocked         = "locked"          // ErrLocked
+	CodeChanged        = "changed"         // ErrChangedConcurrently
+	CodeProtected      = "protected"       // ErrProtected
+)
+
+// ErrorRecord is the machine-readable form of an error or warning, written to
+// stderr as one JSON object per line with --output json
+type ErrorRecord struct {
+	Level   string `json:"level"` // "error" or "warning"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Path    string `json:"path,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// NewErrorRecord describes err for machine-readable output. Sentinel errors
+// take precedence over the error type that wraps them.
+func NewErrorRecord(level string, err error) ErrorRecord {
+	record := ErrorRecord{Level: level, Code: CodeError, Message: err.Error(), Hint: GetErrorHint(err)}
+
+	var pathErr *PathError
+	var linkErr *LinkError
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &pathErr):
+		record.Code, record.Path = CodePath, pathErr.Path
+	case errors.As(err, &linkErr):
+		record.Code, record.Path = CodeLink, linkErr.Source
+	case errors.As(err, &validationErr):
+		record.Code = CodeValidation
+	}
+
+	switch {
+	case errors.Is(err, ErrNotSymlink):
+		record.Code = CodeNotSymlink
+	case errors.Is(err, ErrAlreadyAdopted):
+		record.Code = CodeAlreadyAdopted
+	case errors.Is(err, ErrLocalOnly):
+		record.Code = CodeLocalOnly
+	case errors.Is(err, ErrSensitive):
+		record.Code = CodeSensitive
+	case errors.Is(err, ErrReadOnly):
+		record.Code = CodeReadOnly
+	case errors.Is(err, ErrSymlinkLoop):
+		record.Code = CodeSymlinkLoop
+	case errors.Is(err, ErrLocked):
+		record.Code = CodeLocked
+	case errors.Is(err, ErrChangedConcurrently):
+		record.Code = CodeChanged
+	case errors.Is(err, ErrProtected):
+		record.Code = CodeProtected
+	}
+	return record
+}
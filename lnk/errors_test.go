@@ -2,6 +2,7 @@ package lnk
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -233,3 +234,49 @@ func TestErrorWrapping(t *testing.T) {
 		t.Error("errors.Is should find wrapped custom error")
 	}
 }
+
+func TestNewErrorRecord(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorRecord
+	}{
+		{
+			name: "plain error",
+			err:  errors.New("boom"),
+			want: ErrorRecord{Level: "error", Code: CodeError, Message: "boom"},
+		},
+		{
+			name: "hinted error",
+			err:  WithHint(errors.New("boom"), "try again"),
+			want: ErrorRecord{Level: "error", Code: CodeError, Message: "boom", Hint: "try again"},
+		},
+		{
+			name: "path error",
+			err:  NewPathErrorWithHint("remove", "/home/u/.z", errors.New("missing"), "check it"),
+			want: ErrorRecord{Level: "error", Code: CodePath, Message: "remove /home/u/.z: missing", Path: "/home/u/.z", Hint: "check it"},
+		},
+		{
+			name: "wrapped link error",
+			err:  fmt.Errorf("adopting: %w", NewLinkErrorWithHint("adopt", "/a", "/b", ErrAlreadyAdopted, "")),
+			want: ErrorRecord{Level: "error", Code: CodeAlreadyAdopted, Message: "adopting: adopt /a -> /b: file already adopted", Path: "/a"},
+		},
+		{
+			name: "validation error",
+			err:  NewValidationErrorWithHint("map", "x", "expected SRC:TGT", "example"),
+			want: ErrorRecord{Level: "error", Code: CodeValidation, Message: "invalid map 'x': expected SRC:TGT", Hint: "example"},
+		},
+		{
+			name: "sentinel",
+			err:  NewPathError("orphan", "/a", ErrNotSymlink),
+			want: ErrorRecord{Level: "error", Code: CodeNotSymlink, Message: "orphan /a: not a symlink", Path: "/a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewErrorRecord("error", tt.err); got != tt.want {
+				t.Errorf("NewErrorRecord() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package lnk
+
+import "fmt"
+
+// Eval evaluates a condition expression on this machine, printing the value of
+// every identifier it used and the result. It returns whether the expression
+// is true, so callers can exit non-zero when it is false.
+func Eval(expression string) (bool, error) {
+	PrintCommandHeader("Evaluating Expression")
+
+	ok, bindings, err := evalExpr(expression, defaultExprEnv())
+	if err != nil {
+		return false, err
+	}
+
+	if ShouldSimplifyOutput() {
+		for _, b := range bindings {
+			fmt.Printf("value %s %s\n", b.name, b.value)
+		}
+		fmt.Printf("result %t\n", ok)
+		return ok, nil
+	}
+
+	for _, b := range bindings {
+		PrintDetail("%s = %s", b.name, b.value)
+	}
+	if len(bindings) > 0 {
+		fmt.Println()
+	}
+	if ok {
+		PrintSuccess("true")
+	} else {
+		fmt.Printf("%s false\n", Red(FailureIcon))
+	}
+	return ok, nil
+}
@@ -0,0 +1,35 @@
+package lnk
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	t.Setenv("LNK_TEST_EVAL", "yes")
+
+	var ok bool
+	output := CaptureOutput(t, func() {
+		var err error
+		ok, err = Eval(`os == "` + runtime.GOOS + `" && env.LNK_TEST_EVAL == "yes"`)
+		if err != nil {
+			t.Fatalf("Eval() error = %v", err)
+		}
+	})
+	if !ok {
+		t.Error("Eval() = false, want true")
+	}
+	ContainsOutput(t, output, `value os "`+runtime.GOOS+`"`, `value env.LNK_TEST_EVAL "yes"`, "result true")
+
+	output = CaptureOutput(t, func() {
+		var err error
+		ok, err = Eval(`env.LNK_TEST_EVAL == "no"`)
+		if err != nil {
+			t.Fatalf("Eval() error = %v", err)
+		}
+	})
+	if ok {
+		t.Error("Eval() = true, want false")
+	}
+	ContainsOutput(t, output, "result false")
+}
@@ -0,0 +1,136 @@
+package lnk
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ConfigExplain prints every configuration source in discovery order, whether
+// it was found and what it contributed, followed by the effective
+// configuration with the origin of each value. cliPackages are the packages
+// given with --packages, which take precedence over .lnkpackages.
+func ConfigExplain(config *Config, cliPackages []string) error {
+	PrintCommandHeader("Configuration Sources")
+
+	sources := configSources(config, cliPackages)
+	packages, packagesFrom := config.ResolvePackages(cliPackages)
+
+	for i, src := range sources {
+		name := ContractPath(src.Name)
+		overridden := src.Setting == "packages" && len(src.Values) > 0 &&
+			filepath.Base(src.Name) != packagesFrom
+
+		if ShouldSimplifyOutput() {
+			state := "missing"
+			if src.Found {
+				state = "found"
+			}
+			if overridden {
+				state = "overridden"
+			}
+			fmt.Printf("source %d %s %s %s %d\n", i+1, name, state, src.Setting, len(src.Values))
+			continue
+		}
+
+		switch {
+		case !src.Found && !filepath.IsAbs(src.Name):
+			PrintSkip("%d. %s: not set", i+1, name)
+		case !src.Found:
+			PrintSkip("%d. %s: not found", i+1, name)
+		case overridden:
+			PrintSkip("%d. %s: %s, overridden by %s", i+1, name, describeContribution(src), packagesFrom)
+		default:
+			PrintSuccess("%d. %s: %s", i+1, name, describeContribution(src))
+		}
+	}
+
+	fmt.Println()
+	PrintCommandHeader("Effective Configuration")
+
+	packagesValue := strings.Join(packages, ",")
+	if packagesFrom == "default" {
+		packagesValue = "(whole source directory)"
+	}
+	printEffective("source_dir", ContractPath(config.SourceDir), "argument")
+	targetFrom := "default"
+	if config.TargetFromEnv {
+		targetFrom = EnvTargetDir
+	}
+	printEffective("target_dir", ContractPath(config.TargetDir), targetFrom)
+	printEffective("packages", packagesValue, packagesFrom)
+	printEffectiveSources(config, "ignore", "ignore")
+	printEffectiveSources(config, "local-only", "local_only")
+	printEffectiveSources(config, "sensitive", "sensitive")
+	for _, entry := range config.Dirs.Entries() {
+		printEffective("dirs", entry, DirsFileName)
+	}
+	printEffectiveSources(config, "maps", "maps")
+	for _, entry := range config.Workflow.Entries() {
+		printEffective("workflow", entry, WorkflowFileName)
+	}
+	printEffectiveSources(config, "protected", "protected")
+	return nil
+}
+
+// printEffectiveSources prints the values every source contributed to a
+// setting, each with the source it came from
+func printEffectiveSources(config *Config, setting, name string) {
+	for _, src := range config.Sources {
+		if src.Setting != setting {
+			continue
+		}
+		for _, value := range src.Values {
+			printEffective(name, value, ContractPath(src.Name))
+		}
+	}
+}
+
+// configSources returns the sources LoadConfig consulted followed by the
+// --packages flag, with empty contributions as empty lists
+func configSources(config *Config, cliPackages []string) []ConfigSource {
+	sources := append(slices.Clone(config.Sources), ConfigSource{
+		Name: "--packages", Setting: "packages", Found: len(cliPackages) > 0, Values: cliPackages,
+	})
+	for i := range sources {
+		if sources[i].Values == nil {
+			sources[i].Values = []string{}
+		}
+	}
+	return sources
+}
+
+// describeContribution summarizes what a found source contributed
+func describeContribution(src ConfigSource) string {
+	noun := "ignore pattern(s)"
+	switch src.Setting {
+	case "packages":
+		noun = "package(s)"
+	case "local-only":
+		noun = "local-only pattern(s)"
+	case "sensitive":
+		noun = "sensitive pattern(s)"
+	case "dirs":
+		noun = "directory setting(s)"
+	case "maps":
+		noun = "mapping(s)"
+	case "workflow":
+		noun = "workflow step(s)"
+	case "protected":
+		noun = "protected target pattern(s)"
+	}
+	if len(src.Values) == 0 {
+		return "found, no entries"
+	}
+	return fmt.Sprintf("%d %s", len(src.Values), noun)
+}
+
+// printEffective prints one effective setting and where its value came from
+func printEffective(setting, value, origin string) {
+	if ShouldSimplifyOutput() {
+		fmt.Printf("effective %s %s %s\n", setting, value, origin)
+		return
+	}
+	PrintDetail("%s: %s %s", setting, value, Cyan("(from "+origin+")"))
+}
@@ -0,0 +1,73 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigExplain(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, IgnoreFileName), []byte("local/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, PackagesFileName), []byte("shell\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, LocalOnlyFileName), []byte("~/.config/secrets.local.json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(sourceDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.LoadPrivate(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("packages file", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			if err := ConfigExplain(config, nil); err != nil {
+				t.Fatalf("ConfigExplain() error = %v", err)
+			}
+		})
+		ContainsOutput(t, output,
+			"source 1 built-in found ignore",
+			"source 2 "+filepath.Join(sourceDir, IgnoreFileName)+" found ignore 1",
+			"source 3 "+filepath.Join(sourceDir, PackagesFileName)+" found packages 1",
+			"source 4 "+filepath.Join(sourceDir, ProfilesFileName)+" missing packages 0",
+			"source 5 "+filepath.Join(sourceDir, LocalOnlyFileName)+" found local-only 1",
+			"source 6 "+filepath.Join(sourceDir, SensitiveFileName)+" missing sensitive 0",
+			"source 7 "+filepath.Join(sourceDir, DirsFileName)+" missing dirs 0",
+			"source 8 "+filepath.Join(sourceDir, MapsFileName)+" missing maps 0",
+			"source 9 "+filepath.Join(sourceDir, WorkflowFileName)+" missing workflow 0",
+			"source 10 built-in found protected 2",
+			"source 11 "+filepath.Join(sourceDir, ProtectedFileName)+" missing protected 0",
+			"source 12 "+filepath.Join(sourceDir, PrivateFileName+".age")+" missing private 0",
+			"source 13 LNK_IGNORE missing ignore 0",
+			"source 14 LNK_PROFILE missing packages 0",
+			"source 15 LNK_PACKAGES missing packages 0",
+			"source 16 --ignore missing ignore 0",
+			"source 17 --packages missing packages 0",
+			"effective packages shell .lnkpackages",
+			"effective ignore .git built-in",
+			"effective ignore local/ "+filepath.Join(sourceDir, IgnoreFileName),
+			"effective local_only ~/.config/secrets.local.json "+filepath.Join(sourceDir, LocalOnlyFileName),
+			"effective protected .ssh/authorized_keys built-in",
+		)
+	})
+
+	t.Run("flag overrides packages file", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			if err := ConfigExplain(config, []string{"nvim"}); err != nil {
+				t.Fatalf("ConfigExplain() error = %v", err)
+			}
+		})
+		ContainsOutput(t, output,
+			"source 3 "+filepath.Join(sourceDir, PackagesFileName)+" overridden packages 1",
+			"source 17 --packages found packages 1",
+			"effective packages nvim --packages",
+		)
+	})
+}
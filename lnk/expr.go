@@ -0,0 +1,324 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Condition expressions describe the machines a package or path applies to:
+//
+//	os == "linux" && env.WSL == "1"
+//	!(hostname == "work-laptop") || command_exists("tmux")
+//
+// Values are strings or booleans; a string is true when it is non-empty.
+// Identifiers are os, arch, hostname, wsl, ssh, mdm, env.NAME (empty when
+// unset), true, and false. Operators are ==, !=, &&, ||, !, and parentheses. The only function
+// is command_exists("name").
+
+// exprHint describes the expression syntax for error hints
+const exprHint = `Expressions compare os, arch, hostname, wsl, ssh, mdm, env.NAME, or command_exists("cmd") ` +
+	`with ==, !=, &&, ||, ! and double-quoted strings`
+
+// exprEnv supplies the machine facts expressions are evaluated against
+type exprEnv struct {
+	goos, goarch string
+	hostname     func() (string, error)
+	getenv       func(string) string
+	lookPath     func(string) (string, error)
+	wsl          func() bool
+	mdm          func() bool
+}
+
+// defaultExprEnv describes the current machine
+func defaultExprEnv() exprEnv {
+	return exprEnv{
+		goos:     runtime.GOOS,
+		goarch:   runtime.GOARCH,
+		hostname: os.Hostname,
+		getenv:   os.Getenv,
+		lookPath: exec.LookPath,
+		wsl:      func() bool { return isWSL() },
+		mdm:      func() bool { return isManaged() },
+	}
+}
+
+// exprBinding records the value an identifier or function call had while
+// evaluating an expression, for explanations
+type exprBinding struct {
+	name  string
+	value string
+}
+
+// exprValue is a string or boolean expression value
+type exprValue struct {
+	str    string
+	isBool bool
+	b      bool
+}
+
+func (v exprValue) truthy() bool {
+	if v.isBool {
+		return v.b
+	}
+	return v.str != ""
+}
+
+func (v exprValue) String() string {
+	if v.isBool {
+		return fmt.Sprintf("%t", v.b)
+	}
+	return fmt.Sprintf("%q", v.str)
+}
+
+func boolValue(b bool) exprValue { return exprValue{isBool: true, b: b} }
+
+// exprToken is a lexical token; kind is "string", "ident", "eof", or the operator itself
+type exprToken struct {
+	kind   string
+	text   string
+	offset int
+}
+
+// tokenizeExpr splits an expression into tokens
+func tokenizeExpr(input string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(input); {
+		c, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(input) && input[j] != '"'; j++ {
+				if input[j] == '\\' && j+1 < len(input) {
+					j++
+				}
+				sb.WriteByte(input[j])
+			}
+			if j >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			tokens = append(tokens, exprToken{kind: "string", text: sb.String(), offset: i})
+			i = j + 1
+		case c < utf8.RuneSelf && (unicode.IsLetter(c) || c == '_'):
+			j := i
+			for j < len(input) && (isIdentByte(input[j])) {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: "ident", text: input[i:j], offset: i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "&&", "||", "!", "(", ")"} {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+			}
+			tokens = append(tokens, exprToken{kind: op, text: op, offset: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, exprToken{kind: "eof", offset: len(input)}), nil
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b == '.' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// exprParser evaluates an expression while parsing it (recursive descent)
+type exprParser struct {
+	tokens   []exprToken
+	pos      int
+	env      exprEnv
+	bindings []exprBinding
+	seen     map[string]bool
+}
+
+// evalExpr evaluates expr against env and returns its truth value along with
+// the values of the identifiers and calls it used. Syntax errors and unknown
+// identifiers are returned as a ValidationError.
+func evalExpr(expr string, env exprEnv) (bool, []exprBinding, error) {
+	tokens, err := tokenizeExpr(expr)
+	if err == nil {
+		p := &exprParser{tokens: tokens, env: env, seen: make(map[string]bool)}
+		var v exprValue
+		if v, err = p.parseOr(); err == nil {
+			if tok := p.peek(); tok.kind != "eof" {
+				err = fmt.Errorf("unexpected %q at position %d", tok.text, tok.offset+1)
+			} else {
+				return v.truthy(), p.bindings, nil
+			}
+		}
+	}
+	return false, nil, NewValidationErrorWithHint("expression", expr, err.Error(), exprHint)
+}
+
+func (p *exprParser) peek() exprToken { return p.tokens[p.pos] }
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "eof" {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) expect(kind string) error {
+	if tok := p.next(); tok.kind != kind {
+		if tok.kind == "eof" {
+			return fmt.Errorf("expected %q at end of expression", kind)
+		}
+		return fmt.Errorf("expected %q at position %d, found %q", kind, tok.offset+1, tok.text)
+	}
+	return nil
+}
+
+// parseOr: and ('||' and)*
+func (p *exprParser) parseOr() (exprValue, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.peek().kind == "||" {
+		p.next()
+		var right exprValue
+		if right, err = p.parseAnd(); err == nil {
+			left = boolValue(left.truthy() || right.truthy())
+		}
+	}
+	return left, err
+}
+
+// parseAnd: unary ('&&' unary)*
+func (p *exprParser) parseAnd() (exprValue, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.peek().kind == "&&" {
+		p.next()
+		var right exprValue
+		if right, err = p.parseUnary(); err == nil {
+			left = boolValue(left.truthy() && right.truthy())
+		}
+	}
+	return left, err
+}
+
+// parseUnary: '!' unary | comparison
+func (p *exprParser) parseUnary() (exprValue, error) {
+	if p.peek().kind == "!" {
+		p.next()
+		v, err := p.parseUnary()
+		return boolValue(!v.truthy()), err
+	}
+	return p.parseComparison()
+}
+
+// parseComparison: primary (('==' | '!=') primary)?
+func (p *exprParser) parseComparison() (exprValue, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return left, err
+	}
+	if op := p.peek().kind; op == "==" || op == "!=" {
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return right, err
+		}
+		equal := left.isBool == right.isBool && left.str == right.str && left.b == right.b
+		return boolValue(equal == (op == "==")), nil
+	}
+	return left, nil
+}
+
+// parsePrimary: string | identifier | call | '(' or ')'
+func (p *exprParser) parsePrimary() (exprValue, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "string":
+		return exprValue{str: tok.text}, nil
+	case "(":
+		v, err := p.parseOr()
+		if err != nil {
+			return v, err
+		}
+		return v, p.expect(")")
+	case "ident":
+		if p.peek().kind == "(" {
+			return p.parseCall(tok)
+		}
+		return p.identifier(tok)
+	case "eof":
+		return exprValue{}, fmt.Errorf("unexpected end of expression")
+	default:
+		return exprValue{}, fmt.Errorf("unexpected %q at position %d", tok.text, tok.offset+1)
+	}
+}
+
+// parseCall evaluates a function call; the only function is command_exists
+func (p *exprParser) parseCall(name exprToken) (exprValue, error) {
+	if name.text != "command_exists" {
+		return exprValue{}, fmt.Errorf("unknown function %q at position %d", name.text, name.offset+1)
+	}
+	p.next() // (
+	arg := p.next()
+	if arg.kind != "string" {
+		return exprValue{}, fmt.Errorf("command_exists expects a string at position %d", arg.offset+1)
+	}
+	if err := p.expect(")"); err != nil {
+		return exprValue{}, err
+	}
+	path, err := p.env.lookPath(arg.text)
+	v := boolValue(err == nil)
+	desc := v.String()
+	if err == nil {
+		desc += " (" + path + ")"
+	}
+	p.bind(fmt.Sprintf("command_exists(%q)", arg.text), desc)
+	return v, nil
+}
+
+// identifier resolves a variable or literal
+func (p *exprParser) identifier(tok exprToken) (exprValue, error) {
+	var v exprValue
+	switch name := tok.text; {
+	case name == "true" || name == "false":
+		return boolValue(name == "true"), nil
+	case name == "os":
+		v = exprValue{str: p.env.goos}
+	case name == "arch":
+		v = exprValue{str: p.env.goarch}
+	case name == "hostname":
+		host, err := p.env.hostname()
+		if err != nil {
+			PrintVerbose("Failed to get hostname: %v", err)
+		}
+		v = exprValue{str: host}
+	case name == "wsl":
+		v = boolValue(p.env.wsl())
+	case name == "ssh":
+		v = boolValue(sshSession(p.env.getenv))
+	case name == "mdm":
+		v = boolValue(p.env.mdm())
+	case strings.HasPrefix(name, "env.") && len(name) > len("env."):
+		v = exprValue{str: p.env.getenv(strings.TrimPrefix(name, "env."))}
+	default:
+		return exprValue{}, fmt.Errorf("unknown identifier %q at position %d", name, tok.offset+1)
+	}
+	p.bind(tok.text, v.String())
+	return v, nil
+}
+
+// bind records the first value seen for name
+func (p *exprParser) bind(name, value string) {
+	if !p.seen[name] {
+		p.seen[name] = true
+		p.bindings = append(p.bindings, exprBinding{name: name, value: value})
+	}
+}
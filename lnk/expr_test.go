@@ -0,0 +1,116 @@
+package lnk
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// testExprEnv is a fixed machine for expression tests
+func testExprEnv() exprEnv {
+	env := map[string]string{"WSL": "1", "EDITOR": "nvim"}
+	return exprEnv{
+		goos:     "linux",
+		goarch:   "amd64",
+		hostname: func() (string, error) { return "work-laptop", nil },
+		getenv:   func(name string) string { return env[name] },
+		lookPath: func(cmd string) (string, error) {
+			if cmd == "tmux" {
+				return "/usr/bin/tmux", nil
+			}
+			return "", os.ErrNotExist
+		},
+		wsl: func() bool { return true },
+		mdm: func() bool { return false },
+	}
+}
+
+func TestEvalExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`os == "linux"`, true},
+		{`os != "linux"`, false},
+		{`env.WSL == "1" && os == "linux"`, true},
+		{`env.WSL == "1" && os == "darwin"`, false},
+		{`os == "darwin" || arch == "amd64"`, true},
+		{`!(hostname == "work-laptop")`, false},
+		{`env.WSL`, true},
+		{`env.UNSET`, false},
+		{`env.UNSET == ""`, true},
+		{`command_exists("tmux")`, true},
+		{`command_exists("alacritty") || !command_exists("kitty")`, true},
+		{`true && !false`, true},
+		{`os == "linux" && (arch == "arm64" || env.EDITOR == "nvim")`, true},
+		{`"a \"quoted\" string" == "a \"quoted\" string"`, true},
+		{`false || false && true`, false},
+		{`wsl && os == "linux"`, true},
+		{`wsl == false`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, _, err := evalExpr(tt.expr, testExprEnv())
+			if err != nil {
+				t.Fatalf("evalExpr() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("evalExpr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalExprBindings(t *testing.T) {
+	_, bindings, err := evalExpr(`env.WSL == "1" && os == "linux" && env.WSL != "" && command_exists("tmux")`, testExprEnv())
+	if err != nil {
+		t.Fatalf("evalExpr() error = %v", err)
+	}
+	want := []exprBinding{
+		{"env.WSL", `"1"`},
+		{"os", `"linux"`},
+		{`command_exists("tmux")`, "true (/usr/bin/tmux)"},
+	}
+	if len(bindings) != len(want) {
+		t.Fatalf("bindings = %v, want %v", bindings, want)
+	}
+	for i := range want {
+		if bindings[i] != want[i] {
+			t.Errorf("bindings[%d] = %v, want %v", i, bindings[i], want[i])
+		}
+	}
+}
+
+func TestEvalExprErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`os = "linux"`,
+		`os == "linux`,
+		`os ==`,
+		`(os == "linux"`,
+		`os == "linux")`,
+		`platform == "linux"`,
+		`env. == "x"`,
+		`exists("tmux")`,
+		`command_exists(tmux)`,
+		`os "linux"`,
+		`é == "x"`,
+		`os == linüx`,
+		`hostname == "a" ＆＆ os == "linux"`,
+	}
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			_, _, err := evalExpr(expr, testExprEnv())
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected ValidationError, got %T", err)
+			}
+			if GetErrorHint(err) == "" {
+				t.Error("expected a hint")
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package lnk
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
@@ -26,7 +29,7 @@ func copyPath(src, dst string) error {
 		return fmt.Errorf("failed to copy: cannot copy directory into itself")
 	}
 
-	srcInfo, err := os.Stat(absSrc)
+	srcInfo, err := fsys.Stat(absSrc)
 	if err != nil {
 		return err
 	}
@@ -37,65 +40,165 @@ func copyPath(src, dst string) error {
 	return copyFile(absSrc, absDst)
 }
 
-// copyFile copies a single file
+// copyChunkSize is how much copyFile reads and writes at a time
+const copyChunkSize = 1 << 20
+
+// copyProgress, when set, is called with the number of bytes copyFile has
+// just written, so long copies can report progress
+var copyProgress func(n int64)
+
+// partialCopyPath is where copyFile writes dst until the copy is verified. A
+// partial file left by an interrupted copy is resumed by the next copy to dst.
+func partialCopyPath(dst string) string {
+	return filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".lnk-partial")
+}
+
+// copyFile copies a single file in chunks to a partial file next to dst,
+// syncs it, checks its SHA-256 against the source, and only then renames it to
+// dst. If the copy fails the partial file is kept, and a later copy of the same
+// source continues after the bytes that still match instead of starting over.
 func copyFile(src, dst string) error {
-	srcFile, err := os.Open(src)
+	srcFile, err := fsys.Open(src)
 	if err != nil {
 		return fmt.Errorf("failed to open source file: %w", err)
 	}
-	defer srcFile.Close()
+	defer func() { srcFile.Close() }()
 
 	// Get source file info before creating destination
-	srcInfo, err := os.Stat(src)
+	srcInfo, err := fsys.Stat(src)
 	if err != nil {
 		return fmt.Errorf("failed to stat source file: %w", err)
 	}
 
-	dstFile, err := os.Create(dst)
+	partial := partialCopyPath(dst)
+	srcHash := sha256.New()
+	offset, reread, err := resumeOffset(srcFile, srcHash, partial, srcInfo.Size())
+	if err != nil {
+		return fmt.Errorf("failed to check partial copy %s: %w", ContractPath(partial), err)
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		PrintVerbose("Resuming copy of %s at %s", ContractPath(src), formatSize(offset))
+		flag = os.O_WRONLY | os.O_APPEND
+	} else if reread {
+		// The prefix did not match; read the source again from the start
+		srcFile.Close()
+		if srcFile, err = fsys.Open(src); err != nil {
+			return fmt.Errorf("failed to open source file: %w", err)
+		}
+		srcHash.Reset()
+	}
+
+	dstFile, err := fsys.OpenFile(partial, flag, 0600)
 	if err != nil {
 		return fmt.Errorf("failed to create destination file: %w", err)
 	}
+	if copyProgress != nil && offset > 0 {
+		copyProgress(offset)
+	}
 
-	// Ensure destination file is closed and cleaned up on error
-	var copyErr error
-	defer func() {
-		if closeErr := dstFile.Close(); closeErr != nil && copyErr == nil {
-			copyErr = fmt.Errorf("failed to close destination file: %w", closeErr)
+	buf := make([]byte, copyChunkSize)
+	for {
+		n, readErr := srcFile.Read(buf)
+		if n > 0 {
+			srcHash.Write(buf[:n])
+			if _, err := dstFile.Write(buf[:n]); err != nil {
+				dstFile.Close()
+				return fmt.Errorf("failed to copy file contents: %w", err)
+			}
+			if copyProgress != nil {
+				copyProgress(int64(n))
+			}
+		}
+		if readErr == io.EOF {
+			break
 		}
-		// If there was an error during copy, remove the partial file
-		if copyErr != nil {
-			os.Remove(dst)
+		if readErr != nil {
+			dstFile.Close()
+			return fmt.Errorf("failed to copy file contents: %w", readErr)
 		}
-	}()
+	}
+	if err := dstFile.Sync(); err != nil {
+		dstFile.Close()
+		return fmt.Errorf("failed to sync destination file: %w", err)
+	}
+	if err := dstFile.Close(); err != nil {
+		return fmt.Errorf("failed to close destination file: %w", err)
+	}
 
-	if _, err = io.Copy(dstFile, srcFile); err != nil {
-		copyErr = fmt.Errorf("failed to copy file contents: %w", err)
-		return copyErr
+	// Read the copy back; a mismatch is not resumable, so start over next time
+	dstHash, err := hashFile(partial)
+	if err != nil {
+		return fmt.Errorf("failed to verify copy: %w", err)
+	}
+	if !bytes.Equal(dstHash, srcHash.Sum(nil)) {
+		fsys.Remove(partial)
+		return fmt.Errorf("copy of %s does not match the source (SHA-256 differs)", ContractPath(src))
 	}
 
 	// Set file permissions (best-effort — don't abort the copy)
-	if err = os.Chmod(dst, srcInfo.Mode()); err != nil {
+	if err = fsys.Chmod(partial, srcInfo.Mode()); err != nil {
 		PrintVerbose("Warning: failed to set file permissions on %s: %v", dst, err)
 	}
+	if err := fsys.Rename(partial, dst); err != nil {
+		fsys.Remove(partial)
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	return nil
+}
+
+// resumeOffset decides where copying src into partial can continue. When
+// partial holds no more than size bytes and they match the start of src, it
+// returns their count with src read up to that point and hashed into srcHash.
+// Otherwise it returns 0, and reread reports that src was read for the
+// comparison and must be read again from the start.
+func resumeOffset(src io.Reader, srcHash hash.Hash, partial string, size int64) (offset int64, reread bool, err error) {
+	info, err := fsys.Lstat(partial)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 || info.Size() > size {
+		return 0, false, nil
+	}
+	partialHash, err := hashFile(partial)
+	if err != nil {
+		return 0, false, err
+	}
+	if _, err := io.CopyN(srcHash, src, info.Size()); err != nil {
+		return 0, true, err
+	}
+	if !bytes.Equal(partialHash, srcHash.Sum(nil)) {
+		return 0, true, nil
+	}
+	return info.Size(), false, nil
+}
 
-	return copyErr
+// hashFile returns the SHA-256 of the file at path
+func hashFile(path string) ([]byte, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.CopyBuffer(h, f, make([]byte, copyChunkSize)); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
 }
 
 // copyDir recursively copies a directory
 func copyDir(src, dst string) error {
-	srcInfo, err := os.Stat(src)
+	srcInfo, err := fsys.Stat(src)
 	if err != nil {
 		return err
 	}
 
 	// Create destination directory
-	if err := os.MkdirAll(dst, srcInfo.Mode()); err != nil {
+	if err := fsys.MkdirAll(dst, srcInfo.Mode()); err != nil {
 		return err
 	}
 
-	entries, err := os.ReadDir(src)
+	entries, err := fsys.ReadDir(src)
 	if err != nil {
-		os.RemoveAll(dst) // Clean up on early failure
+		fsys.RemoveAll(dst) // Clean up on early failure
 		return err
 	}
 
@@ -105,12 +208,12 @@ func copyDir(src, dst string) error {
 
 		if entry.IsDir() {
 			if err := copyDir(srcPath, dstPath); err != nil {
-				os.RemoveAll(dst) // Clean up partial copy
+				fsys.RemoveAll(dst) // Clean up partial copy
 				return err
 			}
 		} else {
 			if err := copyFile(srcPath, dstPath); err != nil {
-				os.RemoveAll(dst) // Clean up partial copy
+				fsys.RemoveAll(dst) // Clean up partial copy
 				return err
 			}
 		}
@@ -119,6 +222,94 @@ func copyDir(src, dst string) error {
 	return nil
 }
 
+// filesEqual reports whether two regular files have byte-identical contents.
+func filesEqual(a, b string) (bool, error) {
+	infoA, err := fsys.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := fsys.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if !infoA.Mode().IsRegular() || !infoB.Mode().IsRegular() {
+		return false, nil
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	fileA, err := fsys.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fileA.Close()
+	fileB, err := fsys.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fileB.Close()
+
+	bufA := make([]byte, 32*1024)
+	bufB := make([]byte, 32*1024)
+	for {
+		nA, errA := io.ReadFull(fileA, bufA)
+		nB, errB := io.ReadFull(fileB, bufB)
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == io.EOF || errB == io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
+
+// stashFile moves path aside to a hidden sibling so it can be restored later.
+// Returns the stash location.
+func stashFile(path string) (string, error) {
+	tmp, err := fsys.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".lnk-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create stash file: %w", err)
+	}
+	stashPath := tmp.Name()
+	tmp.Close()
+	if err := fsys.Rename(path, stashPath); err != nil {
+		fsys.Remove(stashPath)
+		return "", fmt.Errorf("failed to stash %s: %w", path, err)
+	}
+	return stashPath, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := fsys.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fsys.Rename(tmpPath, path)
+	}
+	if err != nil {
+		fsys.Remove(tmpPath)
+	}
+	return err
+}
+
 // CleanEmptyDirs removes empty parent directories up to (but not including) boundaryDir.
 // Returns the number of directories removed.
 func CleanEmptyDirs(dirs []string, boundaryDir string) int {
@@ -126,11 +317,11 @@ func CleanEmptyDirs(dirs []string, boundaryDir string) int {
 	for _, dir := range dirs {
 		current := dir
 		for current != boundaryDir {
-			entries, err := os.ReadDir(current)
+			entries, err := fsys.ReadDir(current)
 			if err != nil || len(entries) > 0 {
 				break
 			}
-			if err := os.Remove(current); err != nil {
+			if err := fsys.Remove(current); err != nil {
 				PrintVerbose("Failed to remove empty directory %s: %v", ContractPath(current), err)
 				break
 			}
@@ -147,7 +338,7 @@ func CleanEmptyDirs(dirs []string, boundaryDir string) int {
 // Returns error if the move fails.
 func MoveFile(src, dst string) error {
 	// Try rename first (fast path for same filesystem)
-	if err := os.Rename(src, dst); err == nil {
+	if err := fsys.Rename(src, dst); err == nil {
 		return nil
 	}
 
@@ -162,23 +353,23 @@ func copyAndRemove(src, dst string) error {
 	}
 
 	// Verify the copy
-	srcInfo, err := os.Stat(src)
+	srcInfo, err := fsys.Stat(src)
 	if err != nil {
-		os.RemoveAll(dst)
+		fsys.RemoveAll(dst)
 		return fmt.Errorf("source disappeared during copy: %w", err)
 	}
-	dstInfo, err := os.Stat(dst)
+	dstInfo, err := fsys.Stat(dst)
 	if err != nil {
 		return fmt.Errorf("destination not created: %w", err)
 	}
 	if !srcInfo.IsDir() && srcInfo.Size() != dstInfo.Size() {
-		os.RemoveAll(dst)
+		fsys.RemoveAll(dst)
 		return fmt.Errorf("size mismatch after copy")
 	}
 
 	// Remove the original
-	if err := os.RemoveAll(src); err != nil {
-		os.RemoveAll(dst)
+	if err := fsys.RemoveAll(src); err != nil {
+		fsys.RemoveAll(dst)
 		return fmt.Errorf("failed to remove original: %w", err)
 	}
 
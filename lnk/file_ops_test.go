@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
@@ -316,3 +317,77 @@ func TestCopyPathAdditional(t *testing.T) {
 		})
 	}
 }
+
+func TestFilesEqual(t *testing.T) {
+	tempDir := t.TempDir()
+	a := filepath.Join(tempDir, "a")
+	b := filepath.Join(tempDir, "b")
+	c := filepath.Join(tempDir, "c")
+	d := filepath.Join(tempDir, "d")
+	createTestFile(t, a, "hello world")
+	createTestFile(t, b, "hello world")
+	createTestFile(t, c, "hello there")
+	createTestFile(t, d, "hello")
+
+	tests := []struct {
+		name string
+		x, y string
+		want bool
+	}{
+		{"identical", a, b, true},
+		{"same size different bytes", a, c, false},
+		{"different size", a, d, false},
+		{"directory is never equal", a, tempDir, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filesEqual(tt.x, tt.y)
+			if err != nil {
+				t.Fatalf("filesEqual error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("filesEqual(%s, %s) = %v, want %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := filesEqual(a, filepath.Join(tempDir, "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestCopyFileResumesPartial(t *testing.T) {
+	m := useMemFS(t)
+	content := bytes.Repeat([]byte("0123456789"), copyChunkSize/4) // 2.5 chunks
+	m.writeFile(t, "/src/big.bin", string(content))
+	m.writeFile(t, partialCopyPath("/dst/big.bin"), string(content[:copyChunkSize]))
+
+	var copied int64
+	copyProgress = func(n int64) { copied += n }
+	t.Cleanup(func() { copyProgress = nil })
+
+	if err := copyFile("/src/big.bin", "/dst/big.bin"); err != nil {
+		t.Fatalf("copyFile() error = %v", err)
+	}
+	assertFileContent(t, m, "/dst/big.bin", string(content))
+	if exists(partialCopyPath("/dst/big.bin")) {
+		t.Error("partial copy should be renamed into place")
+	}
+	if copied != int64(len(content)) {
+		t.Errorf("progress reported %d bytes, want %d", copied, len(content))
+	}
+}
+
+func TestCopyFileRestartsMismatchedPartial(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/file.txt", "new contents of the file")
+	m.writeFile(t, partialCopyPath("/dst/file.txt"), "old contents")
+
+	if err := copyFile("/src/file.txt", "/dst/file.txt"); err != nil {
+		t.Fatalf("copyFile() error = %v", err)
+	}
+	assertFileContent(t, m, "/dst/file.txt", "new contents of the file")
+	if exists(partialCopyPath("/dst/file.txt")) {
+		t.Error("partial copy should not be left behind")
+	}
+}
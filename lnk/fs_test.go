@@ -0,0 +1,77 @@
+package lnk
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateLinksMemFS(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.bashrc", "bash")
+	m.writeFile(t, "/src/.config/nvim/init.lua", "nvim")
+
+	if _, err := CreateLinks(LinkOptions{SourceDir: "/src", TargetDir: "/home/u"}); err != nil {
+		t.Fatalf("CreateLinks() error = %v", err)
+	}
+
+	for _, rel := range []string{".bashrc", ".config/nvim/init.lua"} {
+		got, err := m.Readlink(filepath.Join("/home/u", rel))
+		if err != nil {
+			t.Fatalf("Readlink(%s) error = %v", rel, err)
+		}
+		if want := filepath.Join("/src", rel); got != want {
+			t.Errorf("link %s -> %s, want %s", rel, got, want)
+		}
+	}
+	if info, err := m.Lstat("/home/u/.config"); err != nil || !info.IsDir() {
+		t.Errorf("/home/u/.config should be a real directory, got %v, %v", info, err)
+	}
+}
+
+func TestFindManagedLinksMemFS(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.vimrc", "vim")
+	m.writeFile(t, "/home/u/.profile", "profile")
+	if err := m.Symlink("/src/.vimrc", "/home/u/.vimrc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Symlink("/src/.gone", "/home/u/.gone"); err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := FindManagedLinks("/home/u", []string{"/src"})
+	if err != nil {
+		t.Fatalf("FindManagedLinks() error = %v", err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("found %d links, want 2: %+v", len(links), links)
+	}
+	broken := map[string]bool{}
+	for _, link := range links {
+		broken[link.Path] = link.IsBroken
+	}
+	if broken["/home/u/.vimrc"] || !broken["/home/u/.gone"] {
+		t.Errorf("broken = %v, want only .gone broken", broken)
+	}
+}
+
+func TestAdoptMemFS(t *testing.T) {
+	m := useMemFS(t)
+	if err := m.MkdirAll("/src", 0755); err != nil {
+		t.Fatal(err)
+	}
+	m.writeFile(t, "/home/u/.gitconfig", "[user]")
+
+	_, err := Adopt(AdoptOptions{SourceDir: "/src", TargetDir: "/home/u", Paths: []string{"/home/u/.gitconfig"}})
+	if err != nil {
+		t.Fatalf("Adopt() error = %v", err)
+	}
+
+	data, err := m.ReadFile("/src/.gitconfig")
+	if err != nil || string(data) != "[user]" {
+		t.Errorf("adopted file = %q, %v; want %q", data, err, "[user]")
+	}
+	if got, err := m.Readlink("/home/u/.gitconfig"); err != nil || got != "/src/.gitconfig" {
+		t.Errorf("Readlink(.gitconfig) = %q, %v; want /src/.gitconfig", got, err)
+	}
+}
@@ -0,0 +1,124 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+// Policies for planned links whose target file system cannot hold symlinks
+// (FAT, exFAT, some network mounts)
+const (
+	SymlinkFallbackError = "error" // refuse to link anything (default)
+	SymlinkFallbackCopy  = "copy"  // copy those files instead and manage them as copies
+)
+
+// symlinkProbeName is the name of the short-lived symlink that probes a directory
+const symlinkProbeName = ".lnk-symlink-probe"
+
+// probeSymlink creates and removes a symlink in dir, returning the error of
+// creating it; tests replace it
+var probeSymlink = func(dir string) error {
+	probe := filepath.Join(dir, fmt.Sprintf("%s-%d", symlinkProbeName, os.Getpid()))
+	if err := fsys.Symlink(symlinkProbeName, probe); err != nil {
+		return err
+	}
+	if err := fsys.Remove(probe); err != nil {
+		PrintVerbose("Failed to remove symlink probe %s: %v", ContractPath(probe), err)
+	}
+	return nil
+}
+
+// symlinkUnsupported reports whether err, from creating a symlink, means the
+// file system cannot hold symlinks rather than that the directory is not
+// writable. Linux returns EPERM for symlinks on FAT and exFAT.
+func symlinkUnsupported(err error) bool {
+	return errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EOPNOTSUPP)
+}
+
+// unsupportedSymlinkTargets probes the file system of each planned link's
+// nearest existing parent directory, once per file system, and returns the
+// links that cannot be symlinks and the directories whose probe failed.
+// Nothing is probed in read-only mode or on Windows, where creating symlinks
+// depends on privileges rather than the file system.
+func unsupportedSymlinkTargets(links []PlannedLink) (unsupported []PlannedLink, dirs []string) {
+	if IsReadOnly() || runtime.GOOS == "windows" {
+		return nil, nil
+	}
+	byDir := make(map[string]bool)    // nearest existing directory -> unsupported
+	byDevice := make(map[uint64]bool) // file system -> unsupported
+	for _, link := range links {
+		dir, info := existingAncestor(filepath.Dir(link.Target))
+		if info == nil {
+			continue
+		}
+		bad, ok := byDir[dir]
+		if !ok {
+			dev, hasDev := deviceID(info)
+			if bad, ok = byDevice[dev]; !ok || !hasDev {
+				err := probeSymlink(dir)
+				bad = err != nil && symlinkUnsupported(err)
+				Trace("symlink probe", "dir", ContractPath(dir), "supported", !bad)
+				if bad {
+					PrintVerbose("No symlink support in %s: %v", ContractPath(dir), err)
+					dirs = append(dirs, dir)
+				}
+				if hasDev {
+					byDevice[dev] = bad
+				}
+			}
+			byDir[dir] = bad
+		}
+		if bad {
+			unsupported = append(unsupported, link)
+		}
+	}
+	return unsupported, dirs
+}
+
+// existingAncestor returns path or its nearest ancestor that exists as a
+// directory, with its file info; nil info when none can be found
+func existingAncestor(path string) (string, os.FileInfo) {
+	for {
+		if info, err := fsys.Stat(path); err == nil && info.IsDir() {
+			return path, info
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", nil
+		}
+		path = parent
+	}
+}
+
+// applySymlinkFallback checks that the file systems of the planned links can
+// hold symlinks. Under SymlinkFallbackError (or "") any that cannot is an
+// error before anything changes; under SymlinkFallbackCopy the links it
+// returns are copied instead.
+func applySymlinkFallback(links []PlannedLink, policy string) (map[string]bool, error) {
+	unsupported, dirs := unsupportedSymlinkTargets(links)
+	if len(unsupported) == 0 {
+		return nil, nil
+	}
+	if policy != SymlinkFallbackCopy {
+		reason := fmt.Sprintf("file system does not support symlinks (%d planned link(s))", len(unsupported))
+		if len(dirs) > 1 {
+			reason = fmt.Sprintf("file system does not support symlinks (%d planned link(s) on %d file systems)", len(unsupported), len(dirs))
+		}
+		return nil, NewPathErrorWithHint("link", dirs[0], errors.New(reason),
+			"Use --symlink-fallback copy to copy these files instead, or link them onto a file system with symlinks (not FAT or exFAT)")
+	}
+	copyTargets := make(map[string]bool, len(unsupported))
+	for _, link := range unsupported {
+		copyTargets[link.Target] = true
+	}
+	for _, dir := range dirs {
+		PrintWarningWithHint(WithHint(
+			fmt.Errorf("No symlink support in %s; copying files there instead", ContractPath(dir)),
+			"The copies are managed: 'lnk sync' updates them and 'lnk status' lists them"))
+	}
+	return copyTargets, nil
+}
@@ -0,0 +1,88 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// fakeNoSymlinks makes the symlink probe fail like FAT does, counting probes
+func fakeNoSymlinks(t *testing.T, probeErr error) *int {
+	t.Helper()
+	probes := 0
+	original := probeSymlink
+	probeSymlink = func(dir string) error {
+		probes++
+		return &os.LinkError{Op: "symlink", Old: symlinkProbeName, New: dir, Err: probeErr}
+	}
+	t.Cleanup(func() { probeSymlink = original })
+	return &probes
+}
+
+func TestCreateLinksWithoutSymlinkSupport(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim"}}
+	probes := fakeNoSymlinks(t, syscall.EPERM)
+
+	// The default policy stops before changing anything
+	CaptureOutput(t, func() {
+		_, err := CreateLinks(opts)
+		if err == nil || GetErrorHint(err) == "" {
+			t.Fatalf("CreateLinks() = %v, want an error with a hint", err)
+		}
+	})
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+	if *probes != 1 {
+		t.Errorf("probed %d times, want once per file system", *probes)
+	}
+
+	// The copy policy copies the files and records them as copies
+	opts.SymlinkFallback = SymlinkFallbackCopy
+	out := CaptureOutput(t, func() {
+		results, err := CreateLinks(opts)
+		if err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+		PrintCreateSummary(results, sourceDir)
+	})
+	ContainsOutput(t, out, "Copied 2 file(s)")
+	for _, rel := range []string{".bashrc", filepath.Join(".config", "nvim", "init.lua")} {
+		info, err := os.Lstat(filepath.Join(targetDir, rel))
+		if err != nil || !info.Mode().IsRegular() {
+			t.Errorf("%s should be a copied file: %v", rel, err)
+		}
+	}
+	if copies := loadCopies(targetDir, sourceDir); len(copies) != 2 {
+		t.Errorf("recorded copies = %v, want 2", copies)
+	}
+
+	// Recorded copies are left alone afterwards
+	out = CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() again error = %v", err)
+		}
+	})
+	ContainsOutput(t, out, "Copy-managed: ")
+}
+
+func TestSymlinkProbeIgnoresUnwritableDirs(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	fakeNoSymlinks(t, syscall.EACCES)
+
+	links := []PlannedLink{{Source: filepath.Join(sourceDir, "shell", ".bashrc"), Target: filepath.Join(targetDir, ".bashrc")}}
+	if unsupported, _ := unsupportedSymlinkTargets(links); len(unsupported) != 0 {
+		t.Errorf("permission denied should not count as missing symlink support: %v", unsupported)
+	}
+}
+
+func TestSymlinkProbe(t *testing.T) {
+	dir := t.TempDir()
+	if err := probeSymlink(dir); err != nil {
+		t.Fatalf("probeSymlink() error = %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("probe left %d entries behind", len(entries))
+	}
+}
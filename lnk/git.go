@@ -0,0 +1,151 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Git integration is optional: every helper degrades to "no information" when
+// git is not installed or the source directory is not inside a work tree.
+
+// gitOutput runs git with args in dir and returns its stdout.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// gitCombinedOutput runs git with args in dir and returns its stdout and
+// stderr together, for commands whose messages are shown to the user.
+func gitCombinedOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// gitMergeFile merges the changes from base to other into current with git
+// merge-file, and returns the result and the number of conflicts left in it.
+// None of the files is changed.
+func gitMergeFile(current, base, other string) ([]byte, int, error) {
+	cmd := exec.Command("git", "merge-file", "-p", "-L", "copy", "-L", "base", "-L", "source", current, base, other)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() > 0 && exit.ExitCode() < 128 {
+		return out, exit.ExitCode(), nil // the exit status counts conflicts
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("git merge-file: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, 0, nil
+}
+
+// isGitWorkTree reports whether dir is inside a git work tree and git is available.
+func isGitWorkTree(dir string) bool {
+	if _, err := exec.LookPath("git"); err != nil {
+		return false
+	}
+	out, err := gitOutput(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// splitNul splits NUL-separated git output, dropping empty entries.
+func splitNul(out string) []string {
+	var paths []string
+	for _, p := range strings.Split(out, "\x00") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// gitDeletedSources returns the paths (relative to dir, slash-separated) that
+// commit since has and the index does not: files removed from git since then,
+// including removals staged with 'git rm --cached'. An empty since means HEAD,
+// so only staged removals count. Files removed from git but still on disk are
+// included; files removed before since are not, even when they were created
+// again. Returns nil when git information is unavailable.
+func gitDeletedSources(dir, since string) map[string]bool {
+	if !isGitWorkTree(dir) {
+		return nil
+	}
+	if since == "" {
+		since = "HEAD"
+	}
+
+	out, err := gitOutput(dir, "diff", "--cached", "--no-renames", "--diff-filter=D",
+		"--name-only", "--relative", "-z", since)
+	if err != nil {
+		// A repository without commits, or a commit since rewritten away
+		PrintVerbose("git diff %s failed in %s: %v", since, ContractPath(dir), err)
+		return nil
+	}
+	deleted := make(map[string]bool)
+	for _, p := range splitNul(out) {
+		deleted[p] = true
+	}
+	return deleted
+}
+
+// gitHead returns the commit HEAD points to, or "" when there is none or git
+// information is unavailable.
+func gitHead(dir string) string {
+	out, err := gitOutput(dir, "rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// gitRemovedBetween returns the paths (relative to dir, slash-separated) that
+// commits after from up to to deleted, each with the newest commit that deleted
+// it as "<short hash> <subject>".
+func gitRemovedBetween(dir, from, to string) (map[string]string, error) {
+	// \x01 marks commit lines, which cannot be mistaken for a path
+	out, err := gitOutput(dir, "-c", "core.quotePath=false", "log", "--diff-filter=D",
+		"--name-only", "--relative", "--format=%x01%h %s", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+	removed := make(map[string]string)
+	var commit string
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\x01"):
+			commit = strings.TrimPrefix(line, "\x01")
+		case line == "":
+		default:
+			if _, seen := removed[line]; !seen {
+				removed[line] = commit
+			}
+		}
+	}
+	return removed, nil
+}
+
+// gitChangedBetween returns the paths (relative to dir, slash-separated) that
+// differ between commits from and to: added, changed, or deleted
+func gitChangedBetween(dir, from, to string) ([]string, error) {
+	out, err := gitOutput(dir, "diff", "--name-only", "--relative", "-z", from, to)
+	if err != nil {
+		return nil, err
+	}
+	return splitNul(out), nil
+}
+
+// gitLastCommit returns the newest commit that touched path (relative to dir,
+// and possibly deleted since) as "<short hash> <date> <author>", or "" when
+// git records none or git information is unavailable.
+func gitLastCommit(dir, path string) string {
+	out, err := gitOutput(dir, "log", "-1", "--date=short", "--format=%h %ad %an", "--", path)
+	if err != nil {
+		PrintVerbose("git log failed for %s: %v", path, err)
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
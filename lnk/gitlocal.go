@@ -0,0 +1,233 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 'lnk gitconfig-local' keeps machine-specific git settings, such as a work
+// email or signing key, out of the shared .gitconfig. It renders
+// .lnk/gitconfig.local.tmpl with the template values and variables into
+// ~/.gitconfig.local, and makes sure ~/.gitconfig has an [include] for it.
+// git ignores the include on machines where the file does not exist, so the
+// linked .gitconfig stays the same everywhere.
+
+// GitLocalTemplateName is the template in VarsDirName rendered into
+// GitLocalFileName
+const GitLocalTemplateName = "gitconfig.local.tmpl"
+
+// GitLocalFileName is the file in the target directory gitconfig-local writes
+const GitLocalFileName = ".gitconfig.local"
+
+// gitLocalHeader starts every file gitconfig-local writes, so a file written
+// by hand is never replaced without asking
+const gitLocalHeader = "# Generated by 'lnk gitconfig-local' from .lnk/" + GitLocalTemplateName + "; edit that and run it again\n"
+
+// GitLocalOptions configures GenerateGitLocal
+type GitLocalOptions struct {
+	SourceDir string // source directory holding .lnk/gitconfig.local.tmpl
+	TargetDir string // directory holding .gitconfig (default: ~)
+	DryRun    bool   // print what would change without writing it
+}
+
+// GenerateGitLocal renders .lnk/gitconfig.local.tmpl into .gitconfig.local in
+// the target directory, asking once for variables no file sets, and adds an
+// [include] of it to .gitconfig unless one is there. When .gitconfig is a
+// link into the source directory, the include is added to the repository's
+// file, where it does no harm on machines without a .gitconfig.local.
+func GenerateGitLocal(opts GitLocalOptions) error {
+	PrintCommandHeader("Generating Local Git Config")
+
+	paths, err := resolvePathsIn("", opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
+	tmplName := filepath.Join(VarsDirName, GitLocalTemplateName)
+	data, err := os.ReadFile(filepath.Join(sourceDir, tmplName))
+	if os.IsNotExist(err) {
+		return NewPathErrorWithHint("read", filepath.Join(sourceDir, tmplName), errors.New("no template for .gitconfig.local"),
+			"Create it with the settings for this machine, such as:\n"+
+				"  [user]\n      email = {{.Vars.work_email}}")
+	}
+	if err != nil {
+		return NewPathError("read", filepath.Join(sourceDir, tmplName), err)
+	}
+
+	values := loadTemplateValues(true)
+	vars, err := loadTemplateVars(sourceDir)
+	if err != nil {
+		return err
+	}
+	answers, err := askTemplateVars([]templateText{{Name: tmplName, Text: string(data)}}, vars)
+	if err != nil {
+		return err
+	}
+	values["Vars"] = vars
+	text, err := renderTemplate(sourceDir, tmplName, string(data), values)
+	if err != nil {
+		return err
+	}
+	content := gitLocalHeader + text
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	localPath := filepath.Join(targetDir, GitLocalFileName)
+	writeLocal, handMade, err := gitLocalNeedsWrite(localPath, content)
+	if err != nil {
+		return err
+	}
+	configPath := filepath.Join(targetDir, ".gitconfig")
+	// git expands ~ in include paths, which keeps the repository's file the
+	// same for every user
+	includePath := localPath
+	if home, err := os.UserHomeDir(); err == nil && targetDir == home {
+		includePath = "~/" + GitLocalFileName
+	}
+	config, hasInclude, err := readGitInclude(configPath, localPath)
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		switch {
+		case handMade:
+			PrintDryRun("Would replace, after asking: %s (not written by lnk)", ContractPath(localPath))
+		case writeLocal:
+			PrintDryRun("Would write: %s", ContractPath(localPath))
+		}
+		if !hasInclude {
+			PrintDryRun("Would add [include] path = %s to %s", includePath, ContractPath(configPath))
+		}
+		if len(answers) > 0 {
+			PrintDryRun("Would save %d answer(s) to %s", len(answers), filepath.Join(VarsDirName, VarsAnswersFileName))
+		}
+		fmt.Println()
+		PrintDryRunSummary()
+		return nil
+	}
+
+	if handMade && !confirm(fmt.Sprintf("%s was not written by lnk. Replace it? [y/N]", ContractPath(localPath))) {
+		return NewPathErrorWithHint("write", localPath, errors.New("file was not written by lnk"),
+			"Move its settings into .lnk/"+GitLocalTemplateName+" and remove it, or rerun with --yes to replace it")
+	}
+	if len(answers) > 0 {
+		saved, err := loadTemplateAnswers(sourceDir)
+		if err != nil {
+			return err
+		}
+		for name, value := range answers {
+			saved[name] = value
+		}
+		if err := saveTemplateAnswers(sourceDir, saved); err != nil {
+			return err
+		}
+		PrintSuccess("Saved %d answer(s) to %s", len(answers), filepath.Join(VarsDirName, VarsAnswersFileName))
+	}
+	changed := 0
+	if writeLocal {
+		if err := checkWritable("write", localPath); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(localPath, []byte(content), 0600); err != nil {
+			return NewPathErrorWithHint("write", localPath, err, "Check that the home directory is writable")
+		}
+		PrintSuccess("Wrote: %s", ContractPath(localPath))
+		changed++
+	} else {
+		PrintSkip("Up to date: %s", ContractPath(localPath))
+	}
+	if !hasInclude {
+		if err := addGitInclude(configPath, config, includePath); err != nil {
+			return err
+		}
+		PrintSuccess("Added [include] path = %s to %s", includePath, ContractPath(configPath))
+		changed++
+	}
+
+	if changed == 0 {
+		PrintSummary("Local git config unchanged")
+		return nil
+	}
+	PrintSummary("Local git config ready; 'git config user.email' shows the result")
+	return nil
+}
+
+// gitLocalNeedsWrite reports whether path must be written to hold content,
+// and whether what is there now was written by hand rather than by lnk, so
+// replacing it needs the user's consent
+func gitLocalNeedsWrite(path, content string) (write, handMade bool, err error) {
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return true, false, nil
+	}
+	if err != nil {
+		return false, false, NewPathError("read", path, err)
+	}
+	if string(existing) == content {
+		return false, false, nil
+	}
+	return true, !strings.HasPrefix(string(existing), gitLocalHeader), nil
+}
+
+// readGitInclude reads the git config at path, following a link into the
+// source directory, and reports whether an [include] section already has a
+// path naming localPath, absolute or starting with ~. A missing file is empty.
+func readGitInclude(path, localPath string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, NewPathError("read", path, err)
+	}
+	inInclude := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			section := strings.ToLower(strings.Trim(line, "[] \t"))
+			inInclude = section == "include"
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inInclude || !ok || !strings.EqualFold(strings.TrimSpace(key), "path") {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if expanded, err := ExpandPath(value); err == nil && filepath.Clean(expanded) == localPath {
+			return string(data), true, nil
+		}
+	}
+	return string(data), false, nil
+}
+
+// addGitInclude appends an [include] of includePath to the git config at
+// path, which holds config. A link is written through, so the file it points
+// to gets the include and the link stays in place.
+func addGitInclude(path, config, includePath string) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if err := checkWritable("write", path); err != nil {
+		return err
+	}
+	if config != "" && !strings.HasSuffix(config, "\n") {
+		config += "\n"
+	}
+	if config != "" {
+		config += "\n"
+	}
+	config += "[include]\n\tpath = " + includePath + "\n"
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := writeFileAtomic(path, []byte(config), perm); err != nil {
+		return NewPathErrorWithHint("write", path, err, "Check that the file is writable")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package lnk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGitLocal(t *testing.T) {
+	sourceDir, targetDir := t.TempDir(), t.TempDir()
+	createTestFile(t, filepath.Join(sourceDir, ".lnk", GitLocalTemplateName), "[user]\n\tname = {{.Name}}\n\temail = {{.Vars.work_email}}\n")
+	createTestFile(t, filepath.Join(sourceDir, "git", ".gitconfig"), "[core]\n\teditor = vim")
+	configLink := filepath.Join(targetDir, ".gitconfig")
+	if err := os.Symlink(filepath.Join(sourceDir, "git", ".gitconfig"), configLink); err != nil {
+		t.Fatal(err)
+	}
+	localPath := filepath.Join(targetDir, GitLocalFileName)
+
+	origHas, origCanPrompt, origReader, origYes := hasCommand, canPrompt, promptReader, assumeYes
+	hasCommand = func(string) bool { return false }
+	canPrompt = func() bool { return true }
+	t.Cleanup(func() { hasCommand, canPrompt, promptReader, assumeYes = origHas, origCanPrompt, origReader, origYes })
+	opts := GitLocalOptions{SourceDir: sourceDir, TargetDir: targetDir}
+	run := func(opts GitLocalOptions, answers string) (string, error) {
+		promptReader = bufio.NewReader(strings.NewReader(answers))
+		var err error
+		out, _ := captureOutput(t, func() { err = GenerateGitLocal(opts) })
+		return out, err
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		opts := opts
+		opts.DryRun = true
+		out, err := run(opts, "Ada Lovelace\nada@example.com\nada@work.example\n")
+		if err != nil {
+			t.Fatalf("GenerateGitLocal() error = %v", err)
+		}
+		ContainsOutput(t, out, "Would write: "+localPath, "Would add [include] path = "+localPath, "Would save 1 answer(s)")
+		assertNotExists(t, localPath)
+		assertNotExists(t, filepath.Join(sourceDir, ".lnk", VarsAnswersFileName))
+	})
+
+	out, err := run(opts, "Ada Lovelace\nada@example.com\nada@work.example\n")
+	if err != nil {
+		t.Fatalf("GenerateGitLocal() error = %v", err)
+	}
+	ContainsOutput(t, out, "Wrote: "+localPath, "Added [include] path = "+localPath)
+	data, _ := os.ReadFile(localPath)
+	if want := gitLocalHeader + "[user]\n\tname = Ada Lovelace\n\temail = ada@work.example\n"; string(data) != want {
+		t.Errorf(".gitconfig.local = %q, want %q", data, want)
+	}
+	if info, err := os.Stat(localPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf(".gitconfig.local mode = %v, %v; want 0600", info, err)
+	}
+	// The include goes into the repository's file, and the link stays a link
+	assertSymlink(t, configLink, filepath.Join(sourceDir, "git", ".gitconfig"))
+	data, _ = os.ReadFile(filepath.Join(sourceDir, "git", ".gitconfig"))
+	if want := "[core]\n\teditor = vim\n\n[include]\n\tpath = " + localPath + "\n"; string(data) != want {
+		t.Errorf(".gitconfig = %q, want %q", data, want)
+	}
+	if answers, err := loadTemplateAnswers(sourceDir); err != nil || answers["work_email"] != "ada@work.example" {
+		t.Errorf("saved answers = %v, %v; want work_email", answers, err)
+	}
+
+	// A second run asks nothing and changes nothing
+	out, err = run(opts, "Ada Lovelace\nada@example.com\n")
+	if err != nil {
+		t.Fatalf("GenerateGitLocal() again error = %v", err)
+	}
+	ContainsOutput(t, out, "Local git config unchanged")
+	if data, _ := os.ReadFile(configLink); strings.Count(string(data), "[include]") != 1 {
+		t.Errorf(".gitconfig after a second run = %q, want one include", data)
+	}
+
+	// A file written by hand is replaced only with consent
+	os.WriteFile(localPath, []byte("[user]\n\temail = mine@example.com\n"), 0644)
+	if _, err := run(opts, "Ada Lovelace\nada@example.com\nn\n"); err == nil || !strings.Contains(GetErrorHint(err), "--yes") {
+		t.Errorf("GenerateGitLocal() over a hand-written file = %v, want an error suggesting --yes", err)
+	}
+	if data, _ := os.ReadFile(localPath); !strings.Contains(string(data), "mine@example.com") {
+		t.Errorf("hand-written file replaced without consent: %q", data)
+	}
+	assumeYes = true
+	if _, err := run(opts, "Ada Lovelace\nada@example.com\n"); err != nil {
+		t.Fatalf("GenerateGitLocal() with --yes error = %v", err)
+	}
+	if data, _ := os.ReadFile(localPath); !strings.HasPrefix(string(data), gitLocalHeader) {
+		t.Errorf(".gitconfig.local with --yes = %q, want it regenerated", data)
+	}
+
+	os.Remove(filepath.Join(sourceDir, ".lnk", GitLocalTemplateName))
+	if _, err := run(opts, ""); err == nil || !strings.Contains(GetErrorHint(err), "{{.Vars.work_email}}") {
+		t.Errorf("GenerateGitLocal() without a template = %v, want an error with an example", err)
+	}
+}
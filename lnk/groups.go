@@ -0,0 +1,130 @@
+package lnk
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Link groups tag links across packages and mappings, such as shell, gui, or
+// secrets. A package's "groups" in lnk-package.json tag all of its files. An
+// override's "groups" tag the files matching its pattern. A mapping's
+// "groups=" field tags the links it makes. --group limits create, remove, and
+// status to the links in any of the named groups, without moving files
+// between packages.
+
+// assignPackageGroups tags links planned from pkgDir with the package's groups
+// and those of the overrides matching each file
+func assignPackageGroups(links []PlannedLink, pkgDir string, info *PackageInfo) {
+	var overrides []PathOverride
+	for _, o := range info.Overrides {
+		if len(o.Groups) > 0 {
+			overrides = append(overrides, o)
+		}
+	}
+	if len(info.Groups) == 0 && len(overrides) == 0 {
+		return
+	}
+	matchers := make([]*PatternMatcher, len(overrides))
+	for i, o := range overrides {
+		matchers[i] = NewPatternMatcher([]string{o.Pattern})
+	}
+	for i, link := range links {
+		groups := slices.Clone(info.Groups)
+		if rel, err := filepath.Rel(pkgDir, link.Source); err == nil {
+			for j, m := range matchers {
+				if m.Matches(rel) {
+					groups = append(groups, overrides[j].Groups...)
+				}
+			}
+		}
+		slices.Sort(groups)
+		links[i].Groups = slices.Compact(groups)
+	}
+}
+
+// definedGroups returns the sorted link groups the packages in pkgDirs and the
+// mappings name, whether or not their conditions hold on this machine
+func definedGroups(pkgDirs []string, maps []Mapping) ([]string, error) {
+	var groups []string
+	for _, dir := range pkgDirs {
+		info, err := LoadPackageInfo(dir)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, info.Groups...)
+		for _, o := range info.Overrides {
+			groups = append(groups, o.Groups...)
+		}
+	}
+	for _, m := range maps {
+		groups = append(groups, m.Groups...)
+	}
+	sort.Strings(groups)
+	return slices.Compact(groups), nil
+}
+
+// selectGroups returns the links in any of groups. A group that no package or
+// mapping defines is an error, so a misspelled group does not silently select
+// nothing.
+func selectGroups(links []PlannedLink, groups []string, pkgDirs []string, maps []Mapping) ([]PlannedLink, error) {
+	defined, err := definedGroups(pkgDirs, maps)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if slices.Contains(defined, group) {
+			continue
+		}
+		hint := fmt.Sprintf(`No package or mapping defines groups; add "groups" to %s`, PackageInfoFileName)
+		if len(defined) > 0 {
+			hint = fmt.Sprintf("Defined groups: %s", strings.Join(defined, ", "))
+			if match := ClosestMatch(group, defined, 2); match != "" {
+				hint = fmt.Sprintf("Did you mean %s? %s", match, hint)
+			}
+		}
+		return nil, NewValidationErrorWithHint("group", group, "unknown group", hint)
+	}
+
+	var selected []PlannedLink
+	for _, link := range links {
+		if slices.ContainsFunc(link.Groups, func(g string) bool { return slices.Contains(groups, g) }) {
+			selected = append(selected, link)
+		}
+	}
+	PrintVerbose("Groups %s: %d of %d link(s)", strings.Join(groups, ", "), len(selected), len(links))
+	return selected, nil
+}
+
+// groupTargets plans the links of pkgDirs and maps and returns the target
+// paths of those in any of groups, for commands that start from the links
+// already in place (remove, status)
+func groupTargets(groups []string, pkgDirs []string, maps []Mapping, targetDir string, ignorePatterns []string) (map[string]bool, error) {
+	planned, _, _, err := collectPackageLinks(pkgDirs, targetDir, ignorePatterns)
+	if err != nil {
+		return nil, fmt.Errorf("collecting source files: %w", err)
+	}
+	mapLinks, _, _, err := collectMappedLinks(maps, ignorePatterns, planned)
+	if err != nil {
+		return nil, fmt.Errorf("collecting source files: %w", err)
+	}
+	selected, err := selectGroups(append(planned, mapLinks...), groups, pkgDirs, maps)
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]bool, len(selected))
+	for _, link := range selected {
+		targets[link.Target] = true
+	}
+	return targets, nil
+}
+
+// managedInGroups returns the managed links and copies whose path is in
+// targets
+func managedInGroups(links []ManagedLink, copies []ManifestCopy, targets map[string]bool) ([]ManagedLink, []ManifestCopy) {
+	links = slices.DeleteFunc(slices.Clone(links), func(l ManagedLink) bool { return !targets[l.Path] })
+	copies = slices.DeleteFunc(slices.Clone(copies), func(c ManifestCopy) bool { return !targets[c.Path] })
+	return links, copies
+}
@@ -0,0 +1,100 @@
+package lnk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupGroupsTest(t *testing.T) (string, string, LinkOptions) {
+	t.Helper()
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "shell", PackageInfoFileName), `{"groups": ["shell"]}`)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", ".config", "alacritty", "alacritty.toml"), "# alacritty")
+	createTestFile(t, filepath.Join(sourceDir, "nvim", PackageInfoFileName),
+		`{"overrides": [{"pattern": ".config/alacritty/", "groups": ["gui"]}]}`)
+	extra := filepath.Join(filepath.Dir(sourceDir), "extra")
+	createTestFile(t, filepath.Join(extra, "theme.conf"), "# theme")
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"shell", "nvim", "work"},
+		Maps:           []Mapping{{Source: extra, Target: ".config/theme/", Groups: []string{"gui"}}},
+	}
+	return sourceDir, targetDir, opts
+}
+
+func TestLinkGroupsCreate(t *testing.T) {
+	sourceDir, targetDir, opts := setupGroupsTest(t)
+	opts.Groups = []string{"shell", "gui"}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	assertSymlink(t, filepath.Join(targetDir, ".config", "alacritty", "alacritty.toml"),
+		filepath.Join(sourceDir, "nvim", ".config", "alacritty", "alacritty.toml"))
+	assertSymlink(t, filepath.Join(targetDir, ".config", "theme", "theme.conf"),
+		filepath.Join(filepath.Dir(sourceDir), "extra", "theme.conf"))
+	assertNotExists(t, filepath.Join(targetDir, ".config", "nvim", "init.lua"))
+	assertNotExists(t, filepath.Join(targetDir, ".gitconfig"))
+}
+
+func TestLinkGroupsUnknown(t *testing.T) {
+	_, targetDir, opts := setupGroupsTest(t)
+	opts.Groups = []string{"shel"}
+	var err error
+	CaptureOutput(t, func() { _, err = CreateLinks(opts) })
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("CreateLinks() error = %v, want ValidationError", err)
+	}
+	if hint := GetErrorHint(err); !strings.Contains(hint, "Did you mean shell?") || !strings.Contains(hint, "gui, shell") {
+		t.Errorf("hint = %q, want the closest and defined groups", hint)
+	}
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+}
+
+func TestLinkGroupsStatusAndRemove(t *testing.T) {
+	sourceDir, targetDir, opts := setupGroupsTest(t)
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	opts.Groups = []string{"gui"}
+
+	output := CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "alacritty.toml", "theme.conf")
+	NotContainsOutput(t, output, ".bashrc", "init.lua", ".gitconfig")
+
+	t.Run("shallow", func(t *testing.T) {
+		opts := opts
+		opts.Shallow = true
+		if err := Status(opts); err == nil {
+			t.Error("Status() with --group and --shallow should fail")
+		}
+	})
+
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertNotExists(t, filepath.Join(targetDir, ".config", "alacritty", "alacritty.toml"))
+	assertNotExists(t, filepath.Join(targetDir, ".config", "theme", "theme.conf"))
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	if _, err := os.Lstat(filepath.Join(targetDir, ".config", "nvim", "init.lua")); err != nil {
+		t.Errorf("link outside the group was removed: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package lnk
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// guardFS notes what each file and symlink looked like the first time lnk
+// looked at it (Lstat), and refuses to remove, replace, or overwrite one that
+// has changed since: another program edited it while lnk was planning, and
+// going ahead would clobber that edit. Directories are not guarded, since lnk
+// changes their contents itself. Changes lnk makes forget the note, so a path
+// lnk changed can be changed again.
+type guardFS struct {
+	FileSystem
+	mu   sync.Mutex
+	seen map[string]pathStamp
+}
+
+// pathStamp is what guardFS compares: type, permissions, size, modification
+// time, and inode
+type pathStamp struct {
+	mode  fs.FileMode
+	size  int64
+	mtime time.Time
+	id    uint64
+}
+
+// GuardConcurrentEdits wraps fsys so changes to paths another program changed
+// during this run fail with ErrChangedConcurrently
+func GuardConcurrentEdits() {
+	if _, ok := fsys.(*guardFS); !ok {
+		fsys = &guardFS{FileSystem: fsys, seen: make(map[string]pathStamp)}
+	}
+}
+
+func stampOf(info fs.FileInfo) pathStamp {
+	return pathStamp{mode: info.Mode(), size: info.Size(), mtime: info.ModTime(), id: fileID(info)}
+}
+
+func (g *guardFS) Lstat(name string) (fs.FileInfo, error) {
+	info, err := g.FileSystem.Lstat(name)
+	if err == nil && !info.IsDir() {
+		g.mu.Lock()
+		if _, ok := g.seen[name]; !ok {
+			g.seen[name] = stampOf(info)
+		}
+		g.mu.Unlock()
+	}
+	return info, err
+}
+
+// check returns an ErrChangedConcurrently PathError when name changed since
+// lnk first looked at it
+func (g *guardFS) check(op, name string) error {
+	g.mu.Lock()
+	stamp, ok := g.seen[name]
+	g.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	info, err := g.FileSystem.Lstat(name)
+	if err == nil && stampOf(info) == stamp {
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil // let the operation report it
+	}
+	return NewPathErrorWithHint(op, name, ErrChangedConcurrently,
+		"Another program changed it after lnk looked at it; check the file, then run lnk again")
+}
+
+// forget drops the notes for paths lnk just changed
+func (g *guardFS) forget(names ...string) {
+	g.mu.Lock()
+	for _, name := range names {
+		delete(g.seen, name)
+	}
+	g.mu.Unlock()
+}
+
+func (g *guardFS) Symlink(oldname, newname string) error {
+	if err := g.check("create symlink", newname); err != nil {
+		return err
+	}
+	defer g.forget(newname)
+	return g.FileSystem.Symlink(oldname, newname)
+}
+
+func (g *guardFS) Remove(name string) error {
+	if err := g.check("remove", name); err != nil {
+		return err
+	}
+	defer g.forget(name)
+	return g.FileSystem.Remove(name)
+}
+
+func (g *guardFS) RemoveAll(path string) error {
+	if err := g.check("remove", path); err != nil {
+		return err
+	}
+	defer g.forget(path)
+	return g.FileSystem.RemoveAll(path)
+}
+
+func (g *guardFS) Rename(oldpath, newpath string) error {
+	if err := g.check("rename", oldpath); err != nil {
+		return err
+	}
+	if err := g.check("replace", newpath); err != nil {
+		return err
+	}
+	defer g.forget(oldpath, newpath)
+	return g.FileSystem.Rename(oldpath, newpath)
+}
+
+func (g *guardFS) Chmod(name string, mode fs.FileMode) error {
+	defer g.forget(name)
+	return g.FileSystem.Chmod(name, mode)
+}
+
+func (g *guardFS) Lchown(name string, uid, gid int) error {
+	defer g.forget(name)
+	return g.FileSystem.Lchown(name, uid, gid)
+}
+
+func (g *guardFS) Create(name string) (WritableFile, error) {
+	if err := g.check("overwrite", name); err != nil {
+		return nil, err
+	}
+	defer g.forget(name)
+	return g.FileSystem.Create(name)
+}
+
+func (g *guardFS) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		if err := g.check("overwrite", name); err != nil {
+			return nil, err
+		}
+		defer g.forget(name)
+	}
+	return g.FileSystem.OpenFile(name, flag, perm)
+}
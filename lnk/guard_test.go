@@ -0,0 +1,51 @@
+package lnk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGuardConcurrentEdits(t *testing.T) {
+	origFS := fsys
+	t.Cleanup(func() { fsys = origFS })
+	GuardConcurrentEdits()
+
+	dir := t.TempDir()
+	edited := filepath.Join(dir, ".bashrc")
+	untouched := filepath.Join(dir, ".zshrc")
+	createTestFile(t, edited, "# bashrc")
+	createTestFile(t, untouched, "# zshrc")
+	for _, path := range []string{edited, untouched} {
+		if _, err := fsys.Lstat(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Another program edits the file after lnk looked at it
+	if err := os.WriteFile(edited, []byte("# bashrc, edited elsewhere"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := fsys.Rename(edited, filepath.Join(dir, "adopted"))
+	if !errors.Is(err, ErrChangedConcurrently) {
+		t.Fatalf("Rename() of an edited file error = %v, want ErrChangedConcurrently", err)
+	}
+	if GetErrorHint(err) == "" {
+		t.Errorf("expected a hint, got none for %v", err)
+	}
+	if data, _ := os.ReadFile(edited); string(data) != "# bashrc, edited elsewhere" {
+		t.Errorf("edited file content = %q, want the other program's edit kept", data)
+	}
+
+	// Files nobody else changed, and files lnk changed itself, can be changed
+	if err := fsys.Chmod(untouched, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Remove(untouched); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := fsys.Symlink(edited, untouched); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+}
@@ -0,0 +1,500 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// manifestHistoryLimit is how many manifest snapshots are kept; older ones are
+// removed as new operations are recorded
+const manifestHistoryLimit = 100
+
+// operationIDFormat names an operation by the UTC time it started. An
+// operation started in the same second as one already recorded gets a -N
+// suffix (snapshotID); compareOperationIDs sorts IDs in time order.
+const operationIDFormat = "20060102T150405Z"
+
+// operation identifies the run whose manifest changes are being recorded
+type operation struct {
+	ID      string
+	Command string
+	Time    time.Time
+	saved   map[string]string // history directory -> ID the snapshot is saved as there
+}
+
+// currentOperation is this run; set by SetOperation, or on the first manifest
+// save when lnk is used as a library
+var currentOperation *operation
+
+// SetOperation names the command this run performs, for the manifest history
+func SetOperation(command string) {
+	startOperation(command, time.Now())
+}
+
+// startOperation begins a new operation at t; tests use it to record several
+func startOperation(command string, t time.Time) {
+	t = t.UTC().Truncate(time.Second)
+	currentOperation = &operation{ID: t.Format(operationIDFormat), Command: command, Time: t}
+}
+
+// ManifestSnapshot is the manifest as one operation left it, with where each
+// recorded link pointed then, so later state can be compared with it
+type ManifestSnapshot struct {
+	ID       string            `json:"id"`
+	Time     time.Time         `json:"time"`
+	Command  string            `json:"command,omitempty"`
+	Manifest *Manifest         `json:"manifest"`
+	Targets  map[string]string `json:"targets,omitempty"` // link path -> what it pointed to
+}
+
+// HistoryDir returns the directory of manifest snapshots for targetDir
+func HistoryDir(targetDir string) string {
+	return filepath.Join(StateDir(targetDir), "history")
+}
+
+// recordManifestHistory saves m as the snapshot of the current operation,
+// replacing the one an earlier save in the same operation wrote, and removes
+// the oldest snapshots beyond manifestHistoryLimit. History is a debugging
+// aid: failures are only reported in verbose output.
+func recordManifestHistory(targetDir string, m *Manifest) {
+	if currentOperation == nil {
+		startOperation("", time.Now())
+	}
+	dir := HistoryDir(targetDir)
+	snap := snapshotOf(m, currentOperation)
+	snap.ID = currentOperation.snapshotID(dir)
+	home := filepath.Clean(targetDir)
+	stored := snap.mapPaths(func(p string) string { return homeRelative(p, home) })
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		PrintVerbose("Failed to encode manifest history: %v", err)
+		return
+	}
+	if err := fsys.MkdirAll(dir, 0755); err != nil {
+		PrintVerbose("Failed to create %s: %v", ContractPath(dir), err)
+		return
+	}
+	if err := writeFileAtomic(filepath.Join(dir, snap.ID+".json"), append(data, '\n'), 0600); err != nil {
+		PrintVerbose("Failed to record manifest history: %v", err)
+		return
+	}
+
+	ids := historyIDs(targetDir)
+	for len(ids) > manifestHistoryLimit {
+		if err := fsys.Remove(filepath.Join(dir, ids[0]+".json")); err != nil {
+			PrintVerbose("Failed to remove old manifest snapshot %s: %v", ids[0], err)
+		}
+		ids = ids[1:]
+	}
+}
+
+// snapshotID returns the ID op's snapshot is saved as in the history
+// directory dir. The first save claims op.ID, or, when another operation that
+// started in the same second already saved it, the first free ID with a -N
+// suffix, so neither snapshot replaces the other. Later saves in op replace
+// its own snapshot.
+func (op *operation) snapshotID(dir string) string {
+	if id, ok := op.saved[dir]; ok {
+		return id
+	}
+	id := op.ID
+	for n := 2; ; n++ {
+		if _, err := fsys.Lstat(filepath.Join(dir, id+".json")); err != nil {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", op.ID, n)
+	}
+	if op.saved == nil {
+		op.saved = make(map[string]string)
+	}
+	op.saved[dir] = id
+	return id
+}
+
+// splitOperationID returns the time an operation ID names and its suffix: 1
+// for the first operation in a second, N for an ID ending in -N
+func splitOperationID(id string) (time.Time, int, bool) {
+	stamp, suffix, hasSuffix := strings.Cut(id, "-")
+	t, err := time.Parse(operationIDFormat, stamp)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	n := 1
+	if hasSuffix {
+		if n, err = strconv.Atoi(suffix); err != nil || n < 2 {
+			return time.Time{}, 0, false
+		}
+	}
+	return t, n, true
+}
+
+// compareOperationIDs orders operation IDs by time, then suffix; IDs lnk did
+// not write sort after them by name
+func compareOperationIDs(a, b string) int {
+	ta, na, okA := splitOperationID(a)
+	tb, nb, okB := splitOperationID(b)
+	switch {
+	case okA && okB:
+		if c := ta.Compare(tb); c != 0 {
+			return c
+		}
+		return na - nb
+	case okA != okB:
+		if okA {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// snapshotOf records m with the current destination of each recorded link
+func snapshotOf(m *Manifest, op *operation) *ManifestSnapshot {
+	snap := &ManifestSnapshot{ID: op.ID, Time: op.Time, Command: op.Command, Manifest: m,
+		Targets: make(map[string]string, len(m.Links))}
+	for _, l := range m.Links {
+		if dest, err := fsys.Readlink(l.Path); err == nil {
+			if !filepath.IsAbs(dest) {
+				dest = filepath.Join(filepath.Dir(l.Path), dest)
+			}
+			snap.Targets[l.Path] = dest
+		}
+	}
+	return snap
+}
+
+// mapPaths returns a copy of s with fn applied to every recorded path
+func (s *ManifestSnapshot) mapPaths(fn func(string) string) *ManifestSnapshot {
+	out := *s
+	out.Manifest = s.Manifest.mapPaths(fn)
+	out.Manifest.Version = manifestVersion
+	out.Targets = make(map[string]string, len(s.Targets))
+	for path, dest := range s.Targets {
+		out.Targets[fn(path)] = fn(dest)
+	}
+	return &out
+}
+
+// historyIDs lists the recorded operations for targetDir, oldest first
+func historyIDs(targetDir string) []string {
+	entries, err := fsys.ReadDir(HistoryDir(targetDir))
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortFunc(ids, compareOperationIDs)
+	return ids
+}
+
+// LoadSnapshot reads the snapshot of operation id for targetDir
+func LoadSnapshot(targetDir, id string) (*ManifestSnapshot, error) {
+	path := filepath.Join(HistoryDir(targetDir), id+".json")
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, NewPathErrorWithHint("read manifest snapshot", path, err,
+			"Run 'lnk diff-state' to list the recorded operations")
+	}
+	var snap ManifestSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil || snap.Manifest == nil {
+		if err == nil {
+			err = fmt.Errorf("no manifest recorded")
+		}
+		return nil, NewPathErrorWithHint("parse manifest snapshot", path, err,
+			fmt.Sprintf("Remove %s; the other snapshots are not affected", ContractPath(path)))
+	}
+	home := filepath.Clean(targetDir)
+	return snap.mapPaths(func(p string) string { return expandHomeRelative(p, home) }), nil
+}
+
+// currentSnapshot describes the managed state now, as a snapshot would
+func currentSnapshot(targetDir string) (*ManifestSnapshot, error) {
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		return nil, err
+	}
+	return snapshotOf(m, &operation{ID: "now", Time: time.Now()}), nil
+}
+
+// resolveStatePoint finds the snapshot a point in history names: "now", an
+// operation ID or a unique prefix of one, a time (RFC 3339, "2006-01-02
+// 15:04", or "2006-01-02"; local time unless a zone is given), or a duration
+// ago ("90m", "36h", "3d", "2w"). A time selects the last operation at or
+// before it.
+func resolveStatePoint(targetDir, point string, now time.Time) (*ManifestSnapshot, error) {
+	if point == "now" {
+		return currentSnapshot(targetDir)
+	}
+	ids := historyIDs(targetDir)
+	if len(ids) == 0 {
+		return nil, WithHint(fmt.Errorf("no manifest history for %s", ContractPath(targetDir)),
+			"History is recorded from now on, each time lnk changes the manifest")
+	}
+	if slices.Contains(ids, point) {
+		return LoadSnapshot(targetDir, point)
+	}
+	var matches []string
+	for _, id := range ids {
+		if strings.HasPrefix(id, point) {
+			matches = append(matches, id)
+		}
+	}
+	if len(matches) == 1 {
+		return LoadSnapshot(targetDir, matches[0])
+	}
+
+	t, ok := parseStateTime(point, now)
+	if !ok {
+		if len(matches) > 1 {
+			return nil, NewValidationErrorWithHint("as-of", point, fmt.Sprintf("matches %d operations", len(matches)),
+				"Give more of the operation ID; 'lnk diff-state' lists them")
+		}
+		return nil, NewValidationErrorWithHint("as-of", point, "not an operation ID or time",
+			`Use an operation ID from 'lnk diff-state', a time such as "2025-06-01 18:00", or a duration ago such as "3d"`)
+	}
+	i := sort.Search(len(ids), func(i int) bool {
+		started, _, ok := splitOperationID(ids[i])
+		return !ok || started.After(t)
+	})
+	if i == 0 {
+		oldest, _, _ := splitOperationID(ids[0])
+		return nil, WithHint(fmt.Errorf("no manifest history at %s", t.Local().Format(time.DateTime)),
+			fmt.Sprintf("The oldest recorded operation is from %s", oldest.Local().Format(time.DateTime)))
+	}
+	return LoadSnapshot(targetDir, ids[i-1])
+}
+
+// parseStateTime parses an absolute time or a duration before now
+func parseStateTime(s string, now time.Time) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	if n, unit := strings.TrimRight(s, "dw"), strings.TrimLeft(s, "0123456789"); unit == "d" || unit == "w" {
+		days, err := strconv.Atoi(n)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if unit == "w" {
+			days *= 7
+		}
+		return now.AddDate(0, 0, -days), true
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), true
+	}
+	return time.Time{}, false
+}
+
+// Kinds of StateChange
+const (
+	StateAdded      = "added"      // managed in the later state only
+	StateRemoved    = "removed"    // managed in the earlier state only
+	StateRetargeted = "retargeted" // managed in both, pointing or copied from elsewhere
+)
+
+// StateChange is one managed path that differs between two states
+type StateChange struct {
+	Kind   string
+	Path   string
+	Before string // what it pointed to, or "copy of <dest>"; "" when added
+	After  string // likewise afterwards; "" when removed
+}
+
+// managedEntries maps each path a snapshot manages to its destination: the
+// link's target, or for a copy "copy of <dest>". Recorded links that were
+// already gone when the snapshot was taken are left out.
+func (s *ManifestSnapshot) managedEntries(sourceDir string) map[string]string {
+	entries := make(map[string]string)
+	for _, l := range s.Manifest.Links {
+		if dest, ok := s.Targets[l.Path]; ok && (sourceDir == "" || l.Source == sourceDir) {
+			entries[l.Path] = dest
+		}
+	}
+	for _, c := range s.Manifest.Copies {
+		if sourceDir == "" || c.Source == sourceDir {
+			entries[c.Path] = "copy of " + c.Dest
+		}
+	}
+	return entries
+}
+
+// diffStates lists the managed paths that differ from before to after, by
+// path; sourceDir limits both to one source directory ("" for all)
+func diffStates(before, after *ManifestSnapshot, sourceDir string) []StateChange {
+	a, b := before.managedEntries(sourceDir), after.managedEntries(sourceDir)
+	var changes []StateChange
+	for path, dest := range a {
+		switch now, ok := b[path]; {
+		case !ok:
+			changes = append(changes, StateChange{Kind: StateRemoved, Path: path, Before: dest})
+		case now != dest:
+			changes = append(changes, StateChange{Kind: StateRetargeted, Path: path, Before: dest, After: now})
+		}
+	}
+	for path, dest := range b {
+		if _, ok := a[path]; !ok {
+			changes = append(changes, StateChange{Kind: StateAdded, Path: path, After: dest})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// DiffState compares the managed state of targetDir at two points in its
+// manifest history (see resolveStatePoint; b defaults to "now"). With no
+// points, the recorded operations are listed instead.
+func DiffState(targetDir string, points []string) error {
+	if len(points) == 0 {
+		return listHistory(targetDir)
+	}
+	a, err := resolveStatePoint(targetDir, points[0], time.Now())
+	if err != nil {
+		return err
+	}
+	bPoint := "now"
+	if len(points) > 1 {
+		bPoint = points[1]
+	}
+	b, err := resolveStatePoint(targetDir, bPoint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	PrintCommandHeader("Managed State Changes")
+	PrintInfo("From: %s", describeSnapshot(a))
+	PrintInfo("To:   %s", describeSnapshot(b))
+	fmt.Fprintln(stdout())
+	changes := diffStates(a, b, "")
+	printStateChanges(changes)
+	if len(changes) == 0 {
+		PrintInfo("No changes to managed links or copies")
+	}
+	return nil
+}
+
+// describeSnapshot names a snapshot for headers: its time, ID, and command
+func describeSnapshot(s *ManifestSnapshot) string {
+	if s.ID == "now" {
+		return "now"
+	}
+	desc := fmt.Sprintf("%s (%s", s.Time.Local().Format(time.DateTime), s.ID)
+	if s.Command != "" {
+		desc += ", lnk " + s.Command
+	}
+	return desc + ")"
+}
+
+// listHistory prints the recorded operations, newest first
+func listHistory(targetDir string) error {
+	ids := historyIDs(targetDir)
+	if len(ids) == 0 {
+		PrintEmptyResult("recorded operations")
+		return nil
+	}
+	PrintCommandHeader("Manifest History")
+	for i := len(ids) - 1; i >= 0; i-- {
+		id := ids[i]
+		snap, err := LoadSnapshot(targetDir, id)
+		if err != nil {
+			PrintWarningWithHint(err)
+			continue
+		}
+		if ShouldSimplifyOutput() {
+			fmt.Fprintf(stdout(), "%s %s %d\n", id, snap.Command, len(snap.Manifest.Links))
+			continue
+		}
+		command := snap.Command
+		if command == "" {
+			command = "-"
+		}
+		fmt.Fprintf(stdout(), "%s  %s  %-8s %d link(s)\n", id, snap.Time.Local().Format(time.DateTime), command, len(snap.Manifest.Links))
+	}
+	return nil
+}
+
+// printStateChanges prints one line per change
+func printStateChanges(changes []StateChange) {
+	for _, c := range changes {
+		if ShouldSimplifyOutput() {
+			fmt.Fprintf(stdout(), "%s %s\n", c.Kind, ContractPath(c.Path))
+			continue
+		}
+		switch c.Kind {
+		case StateAdded:
+			PrintSuccess("Added: %s -> %s", ContractPath(c.Path), describeDest(c.After))
+		case StateRemoved:
+			fmt.Fprintf(stdout(), "%s Removed: %s (was -> %s)\n", Yellow(WarningIcon), ContractPath(c.Path), describeDest(c.Before))
+		case StateRetargeted:
+			fmt.Fprintf(stdout(), "%s Retargeted: %s -> %s (was -> %s)\n", Yellow(WarningIcon),
+				ContractPath(c.Path), describeDest(c.After), describeDest(c.Before))
+		}
+	}
+}
+
+// describeDest shows a link target or "copy of <dest>" for display
+func describeDest(dest string) string {
+	if rest, ok := strings.CutPrefix(dest, "copy of "); ok {
+		return "copy of " + ContractPath(rest)
+	}
+	return ContractPath(dest)
+}
+
+// historicalStatus reports the links and copies the manifest recorded for
+// sourceDir at a point in its history, then how they changed since
+func historicalStatus(opts LinkOptions, sourceDir, targetDir string) error {
+	then, err := resolveStatePoint(targetDir, opts.AsOf, time.Now())
+	if err != nil {
+		return err
+	}
+	now, err := currentSnapshot(targetDir)
+	if err != nil {
+		return err
+	}
+
+	PrintInfo("As of %s", describeSnapshot(then))
+	entries := then.managedEntries(sourceDir)
+	paths := make([]string, 0, len(entries))
+	for path := range entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	SummaryCount("managed", len(paths))
+	for _, path := range paths {
+		if ShouldSimplifyOutput() {
+			fmt.Fprintf(stdout(), "managed %s\n", ContractPath(path))
+			continue
+		}
+		PrintSuccess("Managed: %s -> %s", ContractPath(path), describeDest(entries[path]))
+	}
+	if len(paths) == 0 {
+		PrintEmptyResult(fmt.Sprintf("links recorded for %s then", ContractPath(sourceDir)))
+	}
+
+	changes := diffStates(then, now, sourceDir)
+	SummaryCount("changed", len(changes))
+	if len(changes) == 0 {
+		if !ShouldSimplifyOutput() {
+			fmt.Fprintln(stdout())
+			PrintInfo("Unchanged since then")
+		}
+		return nil
+	}
+	if !ShouldSimplifyOutput() {
+		fmt.Fprintln(stdout())
+		PrintInfo("Changed since then:")
+	}
+	printStateChanges(changes)
+	return nil
+}
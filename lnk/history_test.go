@@ -0,0 +1,175 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifestHistory(t *testing.T) {
+	t.Cleanup(func() { currentOperation = nil })
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim"}}
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	initLua := filepath.Join(targetDir, ".config", "nvim", "init.lua")
+
+	first := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	startOperation("create", first)
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	startOperation("remove", first.Add(48*time.Hour))
+	opts.Packages = []string{"nvim"}
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+
+	ids := historyIDs(targetDir)
+	if strings.Join(ids, ",") != "20250601T180000Z,20250603T180000Z" {
+		t.Fatalf("historyIDs() = %v, want one snapshot per operation", ids)
+	}
+	snap, err := LoadSnapshot(targetDir, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Command != "create" || snap.Targets[initLua] != filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua") {
+		t.Errorf("snapshot = %+v, want the create with link destinations", snap)
+	}
+
+	now := first.Add(72 * time.Hour)
+	for _, point := range []string{"20250601T180000Z", "20250601", "2025-06-02 12:00", "2025-06-01T18:00:00Z", "60h", "2d"} {
+		got, err := resolveStatePoint(targetDir, point, now)
+		if err != nil || got.ID != ids[0] {
+			t.Errorf("resolveStatePoint(%q) = %v, %v; want %s", point, got, err, ids[0])
+		}
+	}
+	for _, point := range []string{"2025-05-31", "2025", "yesterday"} {
+		if _, err := resolveStatePoint(targetDir, point, now); err == nil {
+			t.Errorf("resolveStatePoint(%q) should fail", point)
+		}
+	}
+
+	// The nvim link was removed and .bashrc now points elsewhere
+	if err := os.Remove(bashrc); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(sourceDir, "work", ".gitconfig"), bashrc); err != nil {
+		t.Fatal(err)
+	}
+	then, _ := LoadSnapshot(targetDir, ids[0])
+	current, err := currentSnapshot(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := diffStates(then, current, sourceDir)
+	if len(changes) != 2 || changes[0].Kind != StateRetargeted || changes[0].Path != bashrc ||
+		changes[1].Kind != StateRemoved || changes[1].Path != initLua {
+		t.Errorf("diffStates() = %+v, want .bashrc retargeted and init.lua removed", changes)
+	}
+
+	t.Run("status as of", func(t *testing.T) {
+		opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, AsOf: ids[0]}
+		out := CaptureOutput(t, func() {
+			if err := Status(opts); err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+		})
+		ContainsOutput(t, out, "lnk create", "managed "+ContractPath(initLua), "removed "+ContractPath(initLua),
+			"retargeted "+ContractPath(bashrc))
+
+		opts.Shallow = true
+		if err := Status(opts); err == nil {
+			t.Error("Status() with --as-of and --shallow should fail")
+		}
+	})
+
+	t.Run("diff-state", func(t *testing.T) {
+		out := CaptureOutput(t, func() {
+			if err := DiffState(targetDir, []string{ids[0], ids[1]}); err != nil {
+				t.Fatalf("DiffState() error = %v", err)
+			}
+		})
+		ContainsOutput(t, out, "removed "+ContractPath(initLua))
+		if strings.Contains(out, ".bashrc") {
+			t.Errorf("DiffState() between the recorded operations should not show the later .bashrc change:\n%s", out)
+		}
+
+		out = CaptureOutput(t, func() {
+			if err := DiffState(targetDir, nil); err != nil {
+				t.Fatalf("DiffState() error = %v", err)
+			}
+		})
+		if strings.Index(out, ids[1]) > strings.Index(out, ids[0]) {
+			t.Errorf("history should list the newest operation first:\n%s", out)
+		}
+	})
+}
+
+func TestManifestHistoryLimit(t *testing.T) {
+	t.Cleanup(func() { currentOperation = nil })
+	targetDir := t.TempDir()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := &Manifest{Version: manifestVersion}
+	for i := 0; i < manifestHistoryLimit+3; i++ {
+		startOperation("ensure", start.Add(time.Duration(i)*time.Minute))
+		if err := m.Save(targetDir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := historyIDs(targetDir)
+	if len(ids) != manifestHistoryLimit || ids[0] != start.Add(3*time.Minute).Format(operationIDFormat) {
+		t.Errorf("kept %d snapshots from %s, want the newest %d", len(ids), ids[0], manifestHistoryLimit)
+	}
+}
+
+func TestManifestHistorySameSecond(t *testing.T) {
+	t.Cleanup(func() { currentOperation = nil })
+	targetDir := t.TempDir()
+	start := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	m := &Manifest{Version: manifestVersion}
+	var commands []string
+	for i := 0; i < 11; i++ {
+		command := fmt.Sprintf("op%d", i)
+		commands = append(commands, command)
+		startOperation(command, start.Add(time.Duration(i)*time.Millisecond))
+		// A second save in the same operation replaces its own snapshot
+		for j := 0; j < 2; j++ {
+			if err := m.Save(targetDir); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	startOperation("later", start.Add(time.Second))
+	if err := m.Save(targetDir); err != nil {
+		t.Fatal(err)
+	}
+	commands = append(commands, "later")
+
+	ids := historyIDs(targetDir)
+	if len(ids) != len(commands) || ids[0] != "20250601T180000Z" || ids[1] != "20250601T180000Z-2" ||
+		ids[10] != "20250601T180000Z-11" || ids[11] != "20250601T180001Z" {
+		t.Fatalf("historyIDs() = %v, want one snapshot per operation in time order", ids)
+	}
+	for i, id := range ids {
+		snap, err := LoadSnapshot(targetDir, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snap.ID != id || snap.Command != commands[i] {
+			t.Errorf("snapshot %s = %s by %s, want %s", id, snap.ID, snap.Command, commands[i])
+		}
+	}
+
+	// A time selects the last operation that started in its second
+	snap, err := resolveStatePoint(targetDir, "2025-06-01T18:00:00Z", start.Add(time.Hour))
+	if err != nil || snap.Command != "op10" {
+		t.Errorf("resolveStatePoint() = %v, %v; want op10", snap, err)
+	}
+}
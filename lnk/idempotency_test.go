@@ -0,0 +1,156 @@
+package lnk
+
+import (
+	"io/fs"
+	"testing"
+)
+
+// writeRecorder is a FileSystem that records every change made through it.
+// MkdirAll of an existing directory is not a change.
+type writeRecorder struct {
+	FileSystem
+	writes []string
+}
+
+func (r *writeRecorder) record(op, path string) {
+	r.writes = append(r.writes, op+" "+path)
+}
+
+func (r *writeRecorder) MkdirAll(path string, perm fs.FileMode) error {
+	if info, err := r.FileSystem.Stat(path); err != nil || !info.IsDir() {
+		r.record("mkdir", path)
+	}
+	return r.FileSystem.MkdirAll(path, perm)
+}
+
+func (r *writeRecorder) Symlink(oldname, newname string) error {
+	r.record("symlink", newname)
+	return r.FileSystem.Symlink(oldname, newname)
+}
+
+func (r *writeRecorder) Remove(name string) error {
+	r.record("remove", name)
+	return r.FileSystem.Remove(name)
+}
+
+func (r *writeRecorder) RemoveAll(path string) error {
+	r.record("remove all", path)
+	return r.FileSystem.RemoveAll(path)
+}
+
+func (r *writeRecorder) Rename(oldpath, newpath string) error {
+	r.record("rename", oldpath+" -> "+newpath)
+	return r.FileSystem.Rename(oldpath, newpath)
+}
+
+func (r *writeRecorder) Chmod(name string, mode fs.FileMode) error {
+	r.record("chmod", name)
+	return r.FileSystem.Chmod(name, mode)
+}
+
+func (r *writeRecorder) Lchown(name string, uid, gid int) error {
+	r.record("chown", name)
+	return r.FileSystem.Lchown(name, uid, gid)
+}
+
+func (r *writeRecorder) Create(name string) (WritableFile, error) {
+	r.record("create", name)
+	return r.FileSystem.Create(name)
+}
+
+func (r *writeRecorder) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+	r.record("open", name)
+	return r.FileSystem.OpenFile(name, flag, perm)
+}
+
+func (r *writeRecorder) CreateTemp(dir, pattern string) (WritableFile, error) {
+	r.record("create temp", dir)
+	return r.FileSystem.CreateTemp(dir, pattern)
+}
+
+// TestSecondRunIsNoop runs each command twice over the same tree and requires
+// the second run to leave the file system untouched.
+func TestSecondRunIsNoop(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		opts  LinkOptions
+	}{
+		{
+			name:  "flat files",
+			files: []string{"/src/.bashrc", "/src/.vimrc"},
+		},
+		{
+			name:  "nested directories",
+			files: []string{"/src/.config/nvim/init.lua", "/src/.config/git/config", "/src/.ssh/config"},
+		},
+		{
+			name:  "mapping",
+			files: []string{"/src/.zshrc", "/opt/tool/tool.conf"},
+			opts:  LinkOptions{Maps: []Mapping{{Source: "/opt/tool/tool.conf", Target: ".config/tool/tool.conf"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := useMemFS(t)
+			for _, file := range tt.files {
+				m.writeFile(t, file, "content")
+			}
+			rec := &writeRecorder{FileSystem: m}
+			fsys = rec
+
+			opts := tt.opts
+			opts.SourceDir, opts.TargetDir = "/src", "/home/u"
+
+			steps := []struct {
+				name string
+				run  func(LinkOptions) error
+			}{
+				{"create", func(opts LinkOptions) error {
+					_, err := CreateLinks(opts)
+					return err
+				}},
+				{"status", Status},
+				{"remove", RemoveLinks},
+			}
+			for _, step := range steps {
+				for pass := 1; pass <= 2; pass++ {
+					rec.writes = nil
+					CaptureOutput(t, func() {
+						if err := step.run(opts); err != nil {
+							t.Fatalf("%s (pass %d) error = %v", step.name, pass, err)
+						}
+					})
+					if pass == 2 && len(rec.writes) > 0 {
+						t.Errorf("second %s changed the file system: %v", step.name, rec.writes)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCreateLinksUpToDateRepairs(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.bashrc", "bash")
+	m.writeFile(t, "/src/.vimrc", "vim")
+	opts := LinkOptions{SourceDir: "/src", TargetDir: "/home/u"}
+
+	if _, err := CreateLinks(opts); err != nil {
+		t.Fatalf("CreateLinks() error = %v", err)
+	}
+	if err := m.Remove("/home/u/.vimrc"); err != nil {
+		t.Fatal(err)
+	}
+
+	output := CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Errorf("CreateLinks() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "Created: /home/u/.vimrc")
+	if got, err := m.Readlink("/home/u/.vimrc"); err != nil || got != "/src/.vimrc" {
+		t.Errorf("Readlink(.vimrc) = %q, %v; want /src/.vimrc", got, err)
+	}
+}
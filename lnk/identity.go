@@ -0,0 +1,342 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Machine identities solve the chicken-and-egg of encrypted configuration on
+// a new machine: .lnkprivate.age cannot be decrypted there until it is also
+// encrypted to a key that exists there. 'lnk identity init' makes the
+// machine's keys, outside the repository, and registers their public parts in
+// .lnkrecipients; 'lnk identity rekey', run on a machine that can decrypt the
+// file, encrypts it again to every registered recipient.
+
+// IdentityOptions holds options for 'lnk identity init' and 'lnk identity rekey'
+type IdentityOptions struct {
+	SourceDir string // the dotfiles repository, holding .lnkrecipients
+	DryRun    bool   // print what would be generated or written
+}
+
+// AgeIdentityPath returns this machine's age identity file: $LNK_AGE_IDENTITY
+// when set, or identity.age in lnk's user config directory
+func AgeIdentityPath() string {
+	if identity := os.Getenv(EnvAgeIdentity); identity != "" {
+		if path, err := ExpandPath(identity); err == nil {
+			return path
+		}
+	}
+	if dir := UserConfigDir(); dir != "" {
+		return filepath.Join(dir, "identity.age")
+	}
+	return ""
+}
+
+// sshKeyPath returns the SSH key identity init makes: ~/.ssh/id_ed25519
+func sshKeyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "id_ed25519")
+}
+
+// ageKeygen runs age-keygen with args and returns its standard output;
+// tests replace it
+var ageKeygen = func(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("age-keygen", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("age-keygen: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// sshKeygen makes an ed25519 key at path with ssh-keygen. At a terminal
+// ssh-keygen asks for the passphrase; otherwise the key has none. Tests
+// replace it.
+var sshKeygen = func(path, comment string) error {
+	args := []string{"-q", "-t", "ed25519", "-f", path, "-C", comment}
+	if !canPrompt() {
+		args = append(args, "-N", "")
+	}
+	cmd := exec.Command("ssh-keygen", args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+	return cmd.Run()
+}
+
+// encryptFile encrypts plaintext with age to the recipients listed in the
+// recipients file, writing the result to out; tests replace it
+var encryptFile = func(recipients string, plaintext []byte, out string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("age", "--encrypt", "--recipients-file", recipients, "--output", out)
+	cmd.Stdin = bytes.NewReader(plaintext)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("age: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// hasCommand reports whether name is on PATH; tests replace it
+var hasCommand = func(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// InitIdentity makes this machine's age identity and SSH key, unless they
+// already exist, and registers their public keys in .lnkrecipients in the
+// source directory, under a comment naming the machine. Existing keys are
+// used as they are; the private parts never enter the repository.
+func InitIdentity(opts IdentityOptions) error {
+	PrintCommandHeader("Setting Up Machine Identity")
+
+	paths, err := ResolvePaths(opts.SourceDir, "~")
+	if err != nil {
+		return err
+	}
+	sourceDir := paths.SourceDir
+
+	var keys []string
+	ageKey, err := initAgeIdentity(opts.DryRun)
+	if err != nil {
+		return err
+	}
+	if ageKey != "" {
+		keys = append(keys, ageKey)
+	}
+	sshKey, err := initSSHKey(opts.DryRun)
+	if err != nil {
+		return err
+	}
+	if sshKey != "" {
+		keys = append(keys, sshKey)
+	}
+
+	registered, err := registerRecipients(sourceDir, keys, opts.DryRun)
+	if err != nil {
+		return err
+	}
+	if opts.DryRun {
+		fmt.Fprintln(stdout())
+		PrintDryRunSummary()
+		return nil
+	}
+
+	SummaryCount("registered", registered)
+	if registered == 0 {
+		PrintSummary("This machine's keys are already registered")
+		return nil
+	}
+	PrintSummary("Registered %d key(s) in %s", registered, RecipientsFileName)
+	if exists(filepath.Join(sourceDir, PrivateFileName+".age")) {
+		PrintInfo("Next: Commit %s, then run 'lnk identity rekey %s' on a machine that can decrypt %s.age",
+			RecipientsFileName, ContractPath(sourceDir), PrivateFileName)
+	}
+	return nil
+}
+
+// initAgeIdentity makes the age identity if it does not exist, and returns
+// its public key ("" in a dry run that would make it)
+func initAgeIdentity(dryRun bool) (string, error) {
+	path := AgeIdentityPath()
+	if path == "" {
+		return "", fmt.Errorf("cannot find the home directory for the age identity")
+	}
+	if exists(path) {
+		PrintSkip("Age identity exists: %s", ContractPath(path))
+	} else {
+		if !hasCommand("age-keygen") {
+			return "", NewPathErrorWithHint("generate age identity", path, fmt.Errorf("age-keygen command not found"),
+				"Install age (https://age-encryption.org), or set "+EnvAgeIdentity+" to an existing identity file")
+		}
+		if dryRun {
+			PrintDryRun("Would generate age identity: %s", ContractPath(path))
+			return "", nil
+		}
+		if err := fsys.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return "", NewPathErrorWithHint("generate age identity", path, err, "Check file permissions")
+		}
+		if err := checkWritable("generate age identity", path); err != nil {
+			return "", err
+		}
+		if _, err := ageKeygen("-o", path); err != nil {
+			return "", NewPathErrorWithHint("generate age identity", path, err, "Check that age-keygen works")
+		}
+		if err := fsys.Chmod(path, 0600); err != nil {
+			return "", NewPathErrorWithHint("generate age identity", path, err, "Make the identity file readable only by you")
+		}
+		PrintSuccess("Generated age identity: %s", ContractPath(path))
+	}
+	key, err := ageKeygen("-y", path)
+	if err != nil {
+		return "", NewPathErrorWithHint("read age identity", path, err,
+			"Check that it is an age identity file; passphrase-protected identities cannot be registered")
+	}
+	return key, nil
+}
+
+// initSSHKey makes ~/.ssh/id_ed25519 if it does not exist, and returns its
+// public key. Without ssh-keygen the SSH key is skipped: the age identity
+// alone is enough to decrypt.
+func initSSHKey(dryRun bool) (string, error) {
+	path := sshKeyPath()
+	switch {
+	case path == "":
+		return "", nil
+	case exists(path + ".pub"):
+		PrintSkip("SSH key exists: %s", ContractPath(path))
+	case exists(path):
+		PrintSkip("SSH key has no public key file: %s", ContractPath(path))
+		return "", nil
+	case !hasCommand("ssh-keygen"):
+		PrintSkip("No SSH key: ssh-keygen not found")
+		return "", nil
+	case dryRun:
+		PrintDryRun("Would generate SSH key: %s", ContractPath(path))
+		return "", nil
+	default:
+		if err := fsys.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return "", NewPathErrorWithHint("generate SSH key", path, err, "Check file permissions")
+		}
+		if err := checkWritable("generate SSH key", path); err != nil {
+			return "", err
+		}
+		host, _ := os.Hostname()
+		if err := sshKeygen(path, "lnk@"+host); err != nil {
+			return "", NewPathErrorWithHint("generate SSH key", path, err, "Check that ssh-keygen works")
+		}
+		PrintSuccess("Generated SSH key: %s", ContractPath(path))
+	}
+	data, err := fsys.ReadFile(path + ".pub")
+	if err != nil {
+		return "", NewPathErrorWithHint("read SSH key", path+".pub", err, "Check file permissions")
+	}
+	// The comment is not part of the recipient
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 || fields[0] != "ssh-ed25519" && fields[0] != "ssh-rsa" {
+		PrintSkip("SSH key is not an age recipient: %s", ContractPath(path))
+		return "", nil
+	}
+	return fields[0] + " " + fields[1], nil
+}
+
+// registerRecipients adds the keys not yet in .lnkrecipients to it, under a
+// comment naming this machine, and returns how many it added
+func registerRecipients(sourceDir string, keys []string, dryRun bool) (int, error) {
+	path := filepath.Join(sourceDir, RecipientsFileName)
+	data, err := fsys.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, NewPathErrorWithHint("read recipients", path, err, "Check file permissions")
+	}
+	existing := parseLines(string(data))
+
+	var added []string
+	for _, key := range keys {
+		if slices.Contains(existing, key) {
+			PrintSkip("Already registered: %s", key)
+			continue
+		}
+		added = append(added, key)
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+	if dryRun {
+		for _, key := range added {
+			PrintDryRun("Would register in %s: %s", RecipientsFileName, key)
+		}
+		return len(added), nil
+	}
+
+	host, _ := os.Hostname()
+	text := string(data)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	text += fmt.Sprintf("# %s\n%s\n", host, strings.Join(added, "\n"))
+	if err := writeFileAtomic(path, []byte(text), 0644); err != nil {
+		return 0, NewPathErrorWithHint("register recipients", path, err, "Check that the source directory is writable")
+	}
+	for _, key := range added {
+		PrintSuccess("Registered in %s: %s", RecipientsFileName, key)
+	}
+	return len(added), nil
+}
+
+// RekeyPrivate decrypts .lnkprivate.age with this machine's identity and
+// encrypts it again to every recipient in .lnkrecipients, so machines that
+// registered since can decrypt it. The plaintext is only held in memory.
+func RekeyPrivate(opts IdentityOptions) error {
+	PrintCommandHeader("Re-encrypting Private Configuration")
+
+	paths, err := ResolvePaths(opts.SourceDir, "~")
+	if err != nil {
+		return err
+	}
+	private := filepath.Join(paths.SourceDir, PrivateFileName+".age")
+	recipients := filepath.Join(paths.SourceDir, RecipientsFileName)
+	if !exists(private) {
+		return NewPathErrorWithHint("rekey", private, fmt.Errorf("no private configuration"),
+			fmt.Sprintf("Only %s.age is re-encrypted; gpg files are encrypted to gpg keys", PrivateFileName))
+	}
+	data, err := fsys.ReadFile(recipients)
+	if err != nil {
+		return NewPathErrorWithHint("rekey", recipients, err,
+			fmt.Sprintf("Run 'lnk identity init %s' to register this machine", ContractPath(paths.SourceDir)))
+	}
+	count := len(parseLines(string(data)))
+	if count == 0 {
+		return NewPathErrorWithHint("rekey", recipients, fmt.Errorf("no recipients"),
+			fmt.Sprintf("Run 'lnk identity init %s' to register this machine", ContractPath(paths.SourceDir)))
+	}
+	if !hasCommand("age") {
+		return NewPathErrorWithHint("rekey", private, fmt.Errorf("age command not found"),
+			"Install age (https://age-encryption.org)")
+	}
+
+	plaintext, err := decryptFile(private)
+	if err != nil {
+		return err
+	}
+	if opts.DryRun {
+		PrintDryRun("Would re-encrypt %s for %d recipient(s)", ContractPath(private), count)
+		fmt.Fprintln(stdout())
+		PrintDryRunSummary()
+		return nil
+	}
+	if err := checkWritable("rekey", private); err != nil {
+		return err
+	}
+
+	// Encrypt next to the file, then replace it, so a failure leaves the old one
+	tmp := private + ".lnk-rekey"
+	if err := encryptFile(recipients, plaintext, tmp); err != nil {
+		fsys.Remove(tmp)
+		return NewPathErrorWithHint("rekey", private, err, fmt.Sprintf("Check the keys in %s", RecipientsFileName))
+	}
+	if err := fsys.Rename(tmp, private); err != nil {
+		fsys.Remove(tmp)
+		return NewPathErrorWithHint("rekey", private, err, "Check that the source directory is writable")
+	}
+	PrintSuccess("Re-encrypted %s for %d recipient(s)", ContractPath(private), count)
+	PrintSummary("Commit %s.age so the new machines can decrypt it", PrivateFileName)
+	return nil
+}
+
+// needsIdentity reports whether sourceDir uses age encryption that this
+// machine has no identity for yet, as on a fresh clone
+func needsIdentity(sourceDir string) bool {
+	if !exists(filepath.Join(sourceDir, PrivateFileName+".age")) && !exists(filepath.Join(sourceDir, RecipientsFileName)) {
+		return false
+	}
+	path := AgeIdentityPath()
+	return path != "" && !exists(path)
+}
@@ -0,0 +1,117 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubKeygen replaces age-keygen and ssh-keygen with fakes that write
+// predictable keys, and reports every tool as installed
+func stubKeygen(t *testing.T) {
+	t.Helper()
+	origAge, origSSH, origHas := ageKeygen, sshKeygen, hasCommand
+	ageKeygen = func(args ...string) (string, error) {
+		if args[0] == "-o" {
+			return "", os.WriteFile(args[1], []byte("AGE-SECRET-KEY-1TEST\n"), 0644)
+		}
+		return "age1test", nil
+	}
+	sshKeygen = func(path, comment string) error {
+		os.WriteFile(path, []byte("private"), 0600)
+		return os.WriteFile(path+".pub", []byte("ssh-ed25519 AAAAtest "+comment+"\n"), 0644)
+	}
+	hasCommand = func(string) bool { return true }
+	t.Cleanup(func() { ageKeygen, sshKeygen, hasCommand = origAge, origSSH, origHas })
+}
+
+func TestInitIdentity(t *testing.T) {
+	stubKeygen(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(EnvAgeIdentity, "")
+	sourceDir := t.TempDir()
+	createTestFile(t, filepath.Join(sourceDir, PrivateFileName+".age"), "encrypted")
+	recipients := filepath.Join(sourceDir, RecipientsFileName)
+
+	if !needsIdentity(sourceDir) {
+		t.Error("needsIdentity() = false before init, want true")
+	}
+	CaptureOutput(t, func() {
+		if err := InitIdentity(IdentityOptions{SourceDir: sourceDir, DryRun: true}); err != nil {
+			t.Fatalf("InitIdentity() dry run error = %v", err)
+		}
+	})
+	if exists(AgeIdentityPath()) || exists(recipients) {
+		t.Fatal("dry run should not generate or register keys")
+	}
+
+	output := CaptureOutput(t, func() {
+		if err := InitIdentity(IdentityOptions{SourceDir: sourceDir}); err != nil {
+			t.Fatalf("InitIdentity() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "Generated age identity", "Generated SSH key", "lnk identity rekey")
+	if info, err := os.Stat(filepath.Join(home, ".config", "lnk", "identity.age")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("age identity = %v, %v; want a file only its owner can read", info, err)
+	}
+	data, err := os.ReadFile(recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := parseLines(string(data)); strings.Join(lines, ",") != "age1test,ssh-ed25519 AAAAtest" {
+		t.Errorf("%s = %q, want the age and SSH public keys", RecipientsFileName, data)
+	}
+	if needsIdentity(sourceDir) {
+		t.Error("needsIdentity() = true after init")
+	}
+
+	// Existing keys are kept and not registered twice
+	output = CaptureOutput(t, func() {
+		if err := InitIdentity(IdentityOptions{SourceDir: sourceDir}); err != nil {
+			t.Fatalf("InitIdentity() again error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "Age identity exists", "SSH key exists", "already registered")
+	assertDiskContent(t, recipients, string(data))
+}
+
+func TestRekeyPrivate(t *testing.T) {
+	stubKeygen(t)
+	stubDecrypt(t, "[maps]\nwork:~\n", nil)
+	var encrypted []string
+	origEncrypt := encryptFile
+	encryptFile = func(recipients string, plaintext []byte, out string) error {
+		encrypted = append(encrypted, string(plaintext))
+		return os.WriteFile(out, []byte("rekeyed"), 0644)
+	}
+	t.Cleanup(func() { encryptFile = origEncrypt })
+
+	sourceDir := t.TempDir()
+	private := filepath.Join(sourceDir, PrivateFileName+".age")
+	createTestFile(t, private, "encrypted")
+	if err := RekeyPrivate(IdentityOptions{SourceDir: sourceDir}); err == nil {
+		t.Error("RekeyPrivate() without .lnkrecipients should fail")
+	}
+
+	createTestFile(t, filepath.Join(sourceDir, RecipientsFileName), "# laptop\nage1one\n# desktop\nage1two\n")
+	CaptureOutput(t, func() {
+		if err := RekeyPrivate(IdentityOptions{SourceDir: sourceDir, DryRun: true}); err != nil {
+			t.Fatalf("RekeyPrivate() dry run error = %v", err)
+		}
+	})
+	assertDiskContent(t, private, "encrypted")
+
+	output := CaptureOutput(t, func() {
+		if err := RekeyPrivate(IdentityOptions{SourceDir: sourceDir}); err != nil {
+			t.Fatalf("RekeyPrivate() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "for 2 recipient(s)")
+	assertDiskContent(t, private, "rekeyed")
+	if len(encrypted) != 1 || encrypted[0] != "[maps]\nwork:~\n" {
+		t.Errorf("encrypted = %q, want the decrypted configuration once", encrypted)
+	}
+}
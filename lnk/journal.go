@@ -0,0 +1,284 @@
+package lnk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// journalVersion is the current journal file format version
+const journalVersion = 1
+
+// Steps recorded in a journal, undone in reverse order
+const (
+	journalMkdir   = "mkdir"   // Path was created
+	journalStash   = "stash"   // From was set aside at Path
+	journalMove    = "move"    // From is being moved to Path
+	journalSymlink = "symlink" // a symlink is being created at Path
+)
+
+// Journal records each file system change of a transaction while it runs, so
+// a transaction that fails or is interrupted can be rolled back, by the
+// command itself or later by 'lnk undo'. The journal file exists only while a
+// transaction is unfinished.
+type Journal struct {
+	Version   int           `json:"version"`
+	Command   string        `json:"command"`    // command that started the transaction
+	SourceDir string        `json:"source_dir"` // absolute source directory; rollback never removes it
+	Steps     []JournalStep `json:"steps"`
+
+	targetDir string
+}
+
+// JournalStep is one recorded change. Moves and symlinks are recorded before
+// they are made; directories and stashes, whose paths are only known
+// afterwards, right after.
+type JournalStep struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from,omitempty"`
+}
+
+// JournalPath returns the journal file location for targetDir
+func JournalPath(targetDir string) string {
+	return filepath.Join(StateDir(targetDir), JournalFileName)
+}
+
+// LoadJournal reads the unfinished transaction for targetDir. It returns nil
+// when there is none.
+func LoadJournal(targetDir string) (*Journal, error) {
+	path := JournalPath(targetDir)
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, NewPathErrorWithHint("read journal", path, err,
+			"Check file permissions on the lnk state directory")
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, NewPathErrorWithHint("parse journal", path, err,
+			fmt.Sprintf("The journal is corrupt; check the files it names by hand, then remove %s", ContractPath(path)))
+	}
+	if j.Version > journalVersion {
+		return nil, NewPathErrorWithHint("read journal", path,
+			fmt.Errorf("unsupported journal version %d", j.Version),
+			"This journal was written by a newer version of lnk; upgrade lnk to undo it")
+	}
+	j.targetDir = targetDir
+	return &j, nil
+}
+
+// beginJournal starts a transaction for command. An unfinished transaction
+// already in the journal is an error, since starting another would lose it.
+func beginJournal(command, sourceDir, targetDir string) (*Journal, error) {
+	pending, err := LoadJournal(targetDir)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return nil, NewPathErrorWithHint(command, JournalPath(targetDir),
+			fmt.Errorf("an interrupted %s has not been rolled back", pending.Command),
+			fmt.Sprintf("Rerun the %s with --resume to finish it, or run 'lnk undo %s' to restore the files it changed",
+				pending.Command, ContractPath(pending.SourceDir)))
+	}
+	j := &Journal{Version: journalVersion, Command: command, SourceDir: sourceDir, targetDir: targetDir}
+	return j, j.save()
+}
+
+// resumeJournal continues the unfinished transaction for command in sourceDir:
+// the step interrupted partway is rolled back and completed steps are kept. It
+// starts a new transaction when none is unfinished.
+func resumeJournal(command, sourceDir, targetDir string) (*Journal, error) {
+	pending, err := LoadJournal(targetDir)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil {
+		return beginJournal(command, sourceDir, targetDir)
+	}
+	if pending.Command != command || pending.SourceDir != sourceDir {
+		return nil, NewPathErrorWithHint(command, JournalPath(targetDir),
+			fmt.Errorf("the interrupted %s was for %s", pending.Command, ContractPath(pending.SourceDir)),
+			fmt.Sprintf("Run 'lnk undo %s' first", ContractPath(pending.SourceDir)))
+	}
+	if errs := pending.rollbackIncomplete(); len(errs) > 0 {
+		return nil, WithHint(fmt.Errorf("failed to roll back the interrupted step: %w", errors.Join(errs...)),
+			fmt.Sprintf("Fix the problem and try again, or run 'lnk undo %s' to restore every file", ContractPath(sourceDir)))
+	}
+	PrintVerbose("Resuming an interrupted %s (%d step(s) kept)", command, len(pending.Steps))
+	return pending, pending.save()
+}
+
+// rollbackIncomplete undoes the steps after the last symlink that exists —
+// the adoption in progress when the transaction was interrupted — and drops
+// them from the journal. A partial copy is kept so the copy can resume.
+func (j *Journal) rollbackIncomplete() []error {
+	keep := 0
+	for i, step := range j.Steps {
+		if step.Op == journalSymlink && isSymlink(step.Path) {
+			keep = i + 1
+		}
+	}
+	tail := &Journal{SourceDir: j.SourceDir, Steps: j.Steps[keep:]}
+	if errs := tail.rollback(false); len(errs) > 0 {
+		return errs
+	}
+	j.Steps = j.Steps[:keep]
+	return nil
+}
+
+// record appends a step and writes the journal before returning
+func (j *Journal) record(op, path, from string) error {
+	j.Steps = append(j.Steps, JournalStep{Op: op, Path: path, From: from})
+	return j.save()
+}
+
+// save writes the journal atomically
+func (j *Journal) save() error {
+	path := JournalPath(j.targetDir)
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return NewPathErrorWithHint("create state directory", filepath.Dir(path), err,
+			"Check that you have write permissions in the parent directory")
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding journal: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), 0600); err != nil {
+		return NewPathError("write journal", path, err)
+	}
+	return nil
+}
+
+// finish ends the transaction by removing the journal
+func (j *Journal) finish() error {
+	if err := fsys.Remove(JournalPath(j.targetDir)); err != nil && !os.IsNotExist(err) {
+		return NewPathError("remove journal", JournalPath(j.targetDir), err)
+	}
+	return nil
+}
+
+// rollback undoes the recorded steps in reverse order and returns the steps
+// that could not be undone. Each step checks the file system first, so steps
+// that were recorded but never made, or were already undone, are skipped and
+// rollback can safely run again. With discardPartials, partial copies left by
+// interrupted moves are removed too.
+func (j *Journal) rollback(discardPartials bool) []error {
+	var errs []error
+	var dirs []string
+	for i := len(j.Steps) - 1; i >= 0; i-- {
+		step := j.Steps[i]
+		switch step.Op {
+		case journalSymlink:
+			if isSymlink(step.Path) {
+				if err := fsys.Remove(step.Path); err != nil {
+					errs = append(errs, fmt.Errorf("remove symlink %s: %w", ContractPath(step.Path), err))
+				}
+			}
+		case journalMove:
+			if exists(step.Path) && !exists(step.From) {
+				if err := MoveFile(step.Path, step.From); err != nil {
+					errs = append(errs, fmt.Errorf("restore %s: %w", ContractPath(step.From), err))
+				}
+			}
+			if partial := partialCopyPath(step.Path); discardPartials && exists(partial) {
+				if err := fsys.Remove(partial); err != nil {
+					errs = append(errs, fmt.Errorf("remove partial copy %s: %w", ContractPath(partial), err))
+				}
+			}
+		case journalStash:
+			if exists(step.Path) && !exists(step.From) {
+				if err := fsys.Rename(step.Path, step.From); err != nil {
+					errs = append(errs, fmt.Errorf("restore %s: %w", ContractPath(step.From), err))
+				}
+			}
+		case journalMkdir:
+			dirs = append(dirs, step.Path)
+		default:
+			errs = append(errs, fmt.Errorf("unknown journal step %q", step.Op))
+		}
+	}
+	if len(dirs) > 0 {
+		CleanEmptyDirs(dirs, j.SourceDir)
+	}
+	return errs
+}
+
+// exists reports whether anything, including a broken symlink, is at path
+func exists(path string) bool {
+	_, err := fsys.Lstat(path)
+	return err == nil
+}
+
+// isSymlink reports whether path is a symlink
+func isSymlink(path string) bool {
+	info, err := fsys.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// Undo rolls back the unfinished transaction recorded for the target
+// directory, such as an adopt that was interrupted or whose own rollback
+// failed. It does nothing when no transaction is unfinished.
+func Undo(opts LinkOptions) error {
+	PrintCommandHeader("Undoing Interrupted Changes")
+
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
+
+	j, err := LoadJournal(targetDir)
+	if err != nil {
+		return err
+	}
+	if j == nil {
+		PrintEmptyResult("interrupted changes to undo")
+		return nil
+	}
+	if j.SourceDir != sourceDir {
+		return NewPathErrorWithHint("undo", JournalPath(targetDir),
+			fmt.Errorf("the interrupted %s was for %s", j.Command, ContractPath(j.SourceDir)),
+			fmt.Sprintf("Run 'lnk undo %s'", ContractPath(j.SourceDir)))
+	}
+	PrintVerbose("Journal: %s (%s, %d step(s))", ContractPath(JournalPath(targetDir)), j.Command, len(j.Steps))
+
+	if opts.DryRun {
+		fmt.Println()
+		PrintDryRun("Would undo %d step(s) of an interrupted %s:", len(j.Steps), j.Command)
+		for i := len(j.Steps) - 1; i >= 0; i-- {
+			step := j.Steps[i]
+			switch step.Op {
+			case journalSymlink:
+				PrintDryRun("Would remove symlink: %s", ContractPath(step.Path))
+			case journalMove, journalStash:
+				PrintDryRun("Would restore: %s", ContractPath(step.From))
+			case journalMkdir:
+				PrintDryRun("Would remove if empty: %s", ContractPath(step.Path))
+			}
+		}
+		fmt.Println()
+		PrintDryRunSummary()
+		return nil
+	}
+
+	if errs := j.rollback(true); len(errs) > 0 {
+		for _, err := range errs {
+			PrintWarningWithHint(err)
+		}
+		SummaryCount("failed", len(errs))
+		return WithHint(fmt.Errorf("failed to undo %d step(s): %w", len(errs), errors.Join(errs...)),
+			"Fix the problems above and run 'lnk undo' again; steps already undone are skipped")
+	}
+	if err := j.finish(); err != nil {
+		return err
+	}
+	SummaryCount("undone", len(j.Steps))
+	PrintSummary("Undid an interrupted %s (%d step(s))", j.Command, len(j.Steps))
+	PrintNextStep("status", sourceDir, "check the restored files")
+	return nil
+}
@@ -0,0 +1,191 @@
+package lnk
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// faultFS is a memFS whose Symlink and Remove fail for chosen paths
+type faultFS struct {
+	*memFS
+	failSymlink string
+	failRemove  string
+}
+
+var errInjected = errors.New("injected failure")
+
+func (f *faultFS) Symlink(oldname, newname string) error {
+	if newname == f.failSymlink {
+		return errInjected
+	}
+	return f.memFS.Symlink(oldname, newname)
+}
+
+func (f *faultFS) Remove(name string) error {
+	if name == f.failRemove {
+		return errInjected
+	}
+	return f.memFS.Remove(name)
+}
+
+// useFaultFS makes lnk use a faultFS over a new memFS until the test ends
+func useFaultFS(t *testing.T) *faultFS {
+	t.Helper()
+	f := &faultFS{memFS: useMemFS(t)}
+	fsys = f
+	return f
+}
+
+// assertFileContent checks that path is a regular file holding content
+func assertFileContent(t *testing.T, m *memFS, path, content string) {
+	t.Helper()
+	info, err := m.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("%s should be a regular file (err = %v)", path, err)
+	}
+	if data, _ := m.ReadFile(path); string(data) != content {
+		t.Errorf("%s = %q, want %q", path, data, content)
+	}
+}
+
+func TestAdoptRollsBackFromJournal(t *testing.T) {
+	f := useFaultFS(t)
+	if err := f.MkdirAll("/src", 0755); err != nil {
+		t.Fatal(err)
+	}
+	f.writeFile(t, "/home/u/.config/app/a.conf", "a")
+	f.writeFile(t, "/home/u/.config/app/b.conf", "b")
+	f.failSymlink = "/home/u/.config/app/b.conf"
+
+	_, err := Adopt(AdoptOptions{SourceDir: "/src", TargetDir: "/home/u", Paths: []string{"/home/u/.config/app"}})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("Adopt() error = %v, want the injected failure", err)
+	}
+
+	assertFileContent(t, f.memFS, "/home/u/.config/app/a.conf", "a")
+	assertFileContent(t, f.memFS, "/home/u/.config/app/b.conf", "b")
+	if exists("/src/.config") {
+		t.Error("directories created in the source should be removed by rollback")
+	}
+	if exists(JournalPath("/home/u")) {
+		t.Error("journal should be removed after a successful rollback")
+	}
+}
+
+func TestUndoFinishesFailedRollback(t *testing.T) {
+	f := useFaultFS(t)
+	if err := f.MkdirAll("/src", 0755); err != nil {
+		t.Fatal(err)
+	}
+	f.writeFile(t, "/home/u/.config/app/a.conf", "a")
+	f.writeFile(t, "/home/u/.config/app/b.conf", "b")
+	f.failSymlink = "/home/u/.config/app/b.conf"
+	f.failRemove = "/home/u/.config/app/a.conf" // the rollback cannot remove a.conf's new link
+
+	_, err := Adopt(AdoptOptions{SourceDir: "/src", TargetDir: "/home/u", Paths: []string{"/home/u/.config/app"}})
+	if err == nil || !strings.Contains(err.Error(), "rollback failed") {
+		t.Fatalf("Adopt() error = %v, want a rollback failure", err)
+	}
+	if hint := GetErrorHint(err); !strings.Contains(hint, "lnk undo") {
+		t.Errorf("hint = %q, want it to suggest 'lnk undo'", hint)
+	}
+	if !exists(JournalPath("/home/u")) {
+		t.Fatal("journal should be kept when rollback fails")
+	}
+
+	// A new adoption must not start over an unfinished one
+	f.writeFile(t, "/home/u/.zshrc", "zsh")
+	_, err = Adopt(AdoptOptions{SourceDir: "/src", TargetDir: "/home/u", Paths: []string{"/home/u/.zshrc"}})
+	if err == nil || !strings.Contains(GetErrorHint(err), "lnk undo") {
+		t.Fatalf("Adopt() with a pending journal error = %v, want a hint to run 'lnk undo'", err)
+	}
+
+	f.failSymlink, f.failRemove = "", ""
+	if err := Undo(LinkOptions{SourceDir: "/src", TargetDir: "/home/u"}); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	assertFileContent(t, f.memFS, "/home/u/.config/app/a.conf", "a")
+	assertFileContent(t, f.memFS, "/home/u/.config/app/b.conf", "b")
+	if exists("/src/.config") {
+		t.Error("Undo() should remove directories the adoption created")
+	}
+	if exists(JournalPath("/home/u")) {
+		t.Error("Undo() should remove the journal")
+	}
+
+	// Nothing left to undo
+	if err := Undo(LinkOptions{SourceDir: "/src", TargetDir: "/home/u"}); err != nil {
+		t.Errorf("Undo() with no journal error = %v", err)
+	}
+}
+
+func TestAdoptResumeFinishesInterruptedAdoption(t *testing.T) {
+	f := useFaultFS(t)
+	if err := f.MkdirAll("/src", 0755); err != nil {
+		t.Fatal(err)
+	}
+	f.writeFile(t, "/home/u/.config/app/a.conf", "a")
+	f.writeFile(t, "/home/u/.config/app/b.conf", "b")
+	f.failSymlink = "/home/u/.config/app/b.conf"
+	f.failRemove = "/home/u/.config/app/a.conf" // a.conf stays adopted
+	opts := AdoptOptions{SourceDir: "/src", TargetDir: "/home/u", Paths: []string{"/home/u/.config/app"}}
+	if _, err := Adopt(opts); err == nil {
+		t.Fatal("Adopt() should fail")
+	}
+
+	// Resuming for another source directory is refused
+	f.failSymlink, f.failRemove = "", ""
+	if err := f.MkdirAll("/other", 0755); err != nil {
+		t.Fatal(err)
+	}
+	other := opts
+	other.SourceDir, other.Resume = "/other", true
+	if _, err := Adopt(other); err == nil {
+		t.Fatal("Adopt(Resume) for another source directory should fail")
+	}
+
+	opts.Resume = true
+	if _, err := Adopt(opts); err != nil {
+		t.Fatalf("Adopt(Resume) error = %v", err)
+	}
+	for _, name := range []string{"a.conf", "b.conf"} {
+		if !isSymlink("/home/u/.config/app/" + name) {
+			t.Errorf("%s should be adopted", name)
+		}
+	}
+	assertFileContent(t, f.memFS, "/src/.config/app/a.conf", "a")
+	assertFileContent(t, f.memFS, "/src/.config/app/b.conf", "b")
+	if exists(JournalPath("/home/u")) {
+		t.Error("journal should be removed once the adoption finishes")
+	}
+	manifest, err := LoadManifest("/home/u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Links) != 2 {
+		t.Errorf("manifest records %d link(s), want 2 including the one from the interrupted run", len(manifest.Links))
+	}
+}
+
+func TestAdoptRemovesJournalOnSuccess(t *testing.T) {
+	m := useMemFS(t)
+	if err := m.MkdirAll("/src", 0755); err != nil {
+		t.Fatal(err)
+	}
+	m.writeFile(t, "/home/u/.bashrc", "bash")
+	m.writeFile(t, "/src/.bashrc", "bash")
+
+	if _, err := Adopt(AdoptOptions{SourceDir: "/src", TargetDir: "/home/u", Paths: []string{"/home/u/.bashrc"}}); err != nil {
+		t.Fatalf("Adopt() error = %v", err)
+	}
+	if exists(JournalPath("/home/u")) {
+		t.Error("journal should be removed after a successful adoption")
+	}
+	entries, _ := m.ReadDir("/home/u")
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".lnk-") {
+			t.Errorf("stashed file %s should be discarded", e.Name())
+		}
+	}
+}
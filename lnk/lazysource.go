@@ -0,0 +1,249 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// A source can be missing from the working tree even though the repository
+// has it. A package outside a sparse checkout has no directory, and in a
+// partial clone its blobs were never downloaded. A file tracked with Git LFS
+// is a small pointer file until its object is downloaded. create fetches such
+// sources before linking, unless LNK_NO_FETCH turns that off, and it never
+// links or copies an LFS pointer.
+
+// sourceFetching is whether missing sources may be fetched on demand;
+// LNK_NO_FETCH turns it off
+var sourceFetching = true
+
+// SetSourceFetching turns fetching missing sources on demand on or off
+func SetSourceFetching(on bool) {
+	sourceFetching = on
+}
+
+// lfsPointerPrefix starts every Git LFS pointer file; pointers are well under
+// lfsPointerMaxSize bytes, so larger files are never read
+const (
+	lfsPointerPrefix  = "version https://git-lfs.github.com/spec/v1\n"
+	lfsPointerMaxSize = 1024
+)
+
+// errLFSPointer is the reason an LFS pointer is not linked or copied
+var errLFSPointer = errors.New("Git LFS object not downloaded")
+
+// canFetchSources reports whether sources may be fetched now: fetching is on
+// and the source directory may be changed
+func canFetchSources() bool {
+	return sourceFetching && !IsReadOnly()
+}
+
+// materializePackages checks out selected packages, and the packages they
+// require, whose directory is missing because a sparse checkout leaves them
+// out. git downloads their blobs in a partial clone. The packages are returned
+// unchanged, except that a dry run leaves out the ones it would check out.
+// Packages that are missing for any other reason are left for packageDirs to
+// report.
+func materializePackages(sourceDir string, packages []string, dryRun bool) ([]string, error) {
+	var absent []string
+	for _, pkg := range packages {
+		name, err := cleanPackageName(sourceDir, pkg)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(filepath.Join(sourceDir, name)); os.IsNotExist(err) {
+			absent = append(absent, name)
+		}
+	}
+	if len(absent) == 0 || !isGitWorkTree(sourceDir) {
+		return packages, nil
+	}
+	if out, _ := gitOutput(sourceDir, "config", "--bool", "core.sparseCheckout"); strings.TrimSpace(out) != "true" {
+		return packages, nil
+	}
+	prefix, err := gitOutput(sourceDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, fmt.Errorf("finding repository root: %w", err)
+	}
+	prefix = strings.TrimSpace(prefix)
+
+	// Dependencies may not be checked out either, so read them from git
+	resolved, err := resolvePackageDeps(absent, func(pkg string) ([]string, error) {
+		return gitPackageRequires(sourceDir, prefix, pkg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, name := range resolved {
+		if _, err := os.Stat(filepath.Join(sourceDir, name)); !os.IsNotExist(err) {
+			continue
+		}
+		if out, err := gitOutput(sourceDir, "cat-file", "-t", "HEAD:"+path.Join(prefix, name)); err == nil && strings.TrimSpace(out) == "tree" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return packages, nil
+	}
+
+	switch {
+	case dryRun:
+		for _, name := range missing {
+			PrintDryRun("Would check out package: %s (outside the sparse checkout)", name)
+		}
+		var kept []string
+		for _, pkg := range packages {
+			if name, _ := cleanPackageName(sourceDir, pkg); !slices.Contains(missing, name) {
+				kept = append(kept, pkg)
+			}
+		}
+		return kept, nil
+	case !canFetchSources():
+		return nil, NewValidationErrorWithHint("packages", strings.Join(missing, ","), "not checked out (outside the sparse checkout)",
+			fmt.Sprintf("Run 'lnk sync --sparse' with these packages, or unset %s to check them out on demand", EnvNoFetch))
+	}
+
+	args := []string{"sparse-checkout", "add"}
+	for _, name := range missing {
+		args = append(args, path.Join(prefix, name))
+	}
+	PrintVerbose("Running: git %s", strings.Join(args, " "))
+	if out, err := gitCombinedOutput(sourceDir, args...); err != nil {
+		return nil, WithHint(fmt.Errorf("git sparse-checkout failed: %w\n%s", err, strings.TrimSpace(out)),
+			"Check the repository's remote is reachable, or check the packages out with 'lnk sync --sparse'")
+	}
+	for _, name := range missing {
+		PrintSuccess("Checked out package: %s", name)
+	}
+	SummaryCount("checked_out", len(missing))
+	return packages, nil
+}
+
+// isLFSPointer reports whether path is a Git LFS pointer file rather than the
+// content it stands for
+func isLFSPointer(path string) bool {
+	info, err := fsys.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > lfsPointerMaxSize {
+		return false
+	}
+	f, err := fsys.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(lfsPointerPrefix))
+	if _, err := io.ReadFull(f, head); err != nil {
+		return false
+	}
+	return bytes.Equal(head, []byte(lfsPointerPrefix))
+}
+
+// usesLFS reports whether a .gitattributes file in sourceDir, or in a
+// directory above it up to the repository root, routes files through Git LFS.
+// Sources are only checked for pointers then, so other repositories pay
+// nothing for the check.
+func usesLFS(sourceDir string) bool {
+	for dir := sourceDir; ; dir = filepath.Dir(dir) {
+		if data, err := os.ReadFile(filepath.Join(dir, ".gitattributes")); err == nil && bytes.Contains(data, []byte("filter=lfs")) {
+			return true
+		}
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil || filepath.Dir(dir) == dir {
+			return false
+		}
+	}
+}
+
+// fetchLFSObjects downloads the LFS objects of sources (paths in sourceDir's
+// repository) with git lfs pull. Failures are warnings: the sources stay
+// pointers and are skipped.
+func fetchLFSObjects(sourceDir string, sources []string) {
+	if !hasCommand("git-lfs") {
+		PrintWarningWithHint(WithHint(fmt.Errorf("git-lfs not found; %d LFS object(s) not downloaded", len(sources)),
+			"Install Git LFS (https://git-lfs.com), then run 'git lfs pull' in the repository"))
+		return
+	}
+	prefix, err := gitOutput(sourceDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		PrintVerbose("Could not find the repository root: %v", err)
+		return
+	}
+	var include []string
+	for _, source := range sources {
+		rel, err := filepath.Rel(sourceDir, source)
+		if err != nil || !filepath.IsLocal(rel) || strings.Contains(rel, ",") {
+			continue // --include is comma-separated
+		}
+		include = append(include, path.Join(strings.TrimSpace(prefix), filepath.ToSlash(rel)))
+	}
+	if len(include) == 0 {
+		return
+	}
+	PrintVerbose("Running: git lfs pull --include=%s", strings.Join(include, ","))
+	if out, err := gitCombinedOutput(sourceDir, "lfs", "pull", "--include="+strings.Join(include, ",")); err != nil {
+		PrintWarningWithHint(WithHint(fmt.Errorf("git lfs pull failed: %w\n%s", err, strings.TrimSpace(out)),
+			"Check the repository's LFS remote is reachable, then run 'git lfs pull'"))
+	}
+}
+
+// materializeLFS downloads the objects of planned sources that are still LFS
+// pointers and returns the links whose source is real content. Links to
+// pointers that remain are skipped with a warning, so a link never exposes a
+// pointer as the file. A dry run keeps the links it would download.
+func materializeLFS(links []PlannedLink, sourceDir string, dryRun bool) []PlannedLink {
+	if !usesLFS(sourceDir) {
+		return links
+	}
+	var pointers []string
+	for _, link := range links {
+		if isLFSPointer(link.Source) {
+			pointers = append(pointers, link.Source)
+		}
+	}
+	if len(pointers) == 0 {
+		return links
+	}
+	if dryRun && canFetchSources() {
+		for _, source := range pointers {
+			PrintDryRun("Would download LFS object: %s", ContractPath(source))
+		}
+		return links
+	}
+	if canFetchSources() {
+		fetchLFSObjects(sourceDir, pointers)
+	}
+
+	kept := links[:0:0]
+	skipped := 0
+	for _, link := range links {
+		if slices.Contains(pointers, link.Source) && isLFSPointer(link.Source) {
+			PrintWarningWithHint(NewPathErrorWithHint("link", link.Source, errLFSPointer,
+				"Run 'git lfs pull' in the repository, then run 'lnk create' again"))
+			recordLink(link, ResultSkipped, "lfs-pointer", nil)
+			skipped++
+			continue
+		}
+		kept = append(kept, link)
+	}
+	SummaryCount("lfs_skipped", skipped)
+	return kept
+}
+
+// materializeCopySource makes sure the source of a managed copy is real
+// content before it is copied, downloading its LFS object when needed, and
+// reports whether it is
+func materializeCopySource(sourceDir, source string) bool {
+	if !isLFSPointer(source) {
+		return true
+	}
+	if canFetchSources() {
+		fetchLFSObjects(sourceDir, []string{source})
+	}
+	return !isLFSPointer(source)
+}
@@ -0,0 +1,98 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testLFSPointer = lfsPointerPrefix + "oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\nsize 12345\n"
+
+func TestCreateLinksSkipsLFSPointers(t *testing.T) {
+	origHas := hasCommand
+	hasCommand = func(string) bool { return false }
+	t.Cleanup(func() { hasCommand = origHas })
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, ".gitattributes"), "*.png filter=lfs diff=lfs merge=lfs -text\n")
+	createTestFile(t, filepath.Join(sourceDir, "shell", "wallpaper.png"), testLFSPointer)
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}}
+
+	var err error
+	stdout, stderr := captureOutput(t, func() { _, err = CreateLinks(opts) })
+	if err != nil {
+		t.Fatalf("CreateLinks() error = %v\n%s", err, stderr)
+	}
+	if _, err := os.Lstat(filepath.Join(targetDir, "wallpaper.png")); !os.IsNotExist(err) {
+		t.Errorf("linked an LFS pointer: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(targetDir, ".bashrc")); err != nil {
+		t.Errorf("other sources should still be linked: %v", err)
+	}
+	ContainsOutput(t, stderr, "git-lfs not found", "Git LFS object not downloaded")
+	NotContainsOutput(t, stdout, "wallpaper.png")
+
+	t.Run("dry run", func(t *testing.T) {
+		stdout, _ := captureOutput(t, func() {
+			opts := opts
+			opts.DryRun = true
+			CreateLinks(opts)
+		})
+		ContainsOutput(t, stdout, "Would download LFS object: "+filepath.Join(sourceDir, "shell", "wallpaper.png"))
+	})
+
+	t.Run("fetching off", func(t *testing.T) {
+		SetSourceFetching(false)
+		t.Cleanup(func() { SetSourceFetching(true) })
+		_, stderr := captureOutput(t, func() { CreateLinks(opts) })
+		NotContainsOutput(t, stderr, "git-lfs not found")
+		ContainsOutput(t, stderr, "Git LFS object not downloaded")
+	})
+
+	t.Run("not a pointer", func(t *testing.T) {
+		createTestFile(t, filepath.Join(sourceDir, "shell", "notes.txt"), "version 1\n")
+		if isLFSPointer(filepath.Join(sourceDir, "shell", "notes.txt")) || !isLFSPointer(filepath.Join(sourceDir, "shell", "wallpaper.png")) {
+			t.Error("isLFSPointer() should only match pointer files")
+		}
+	})
+}
+
+func TestCreateLinksChecksOutSparsePackages(t *testing.T) {
+	_, clone := setupSyncTest(t)
+	runTestGit(t, clone, "sparse-checkout", "set", "--cone", "shell")
+	if _, err := os.Stat(filepath.Join(clone, "nvim")); !os.IsNotExist(err) {
+		t.Fatalf("nvim should be outside the sparse checkout: %v", err)
+	}
+	targetDir := t.TempDir()
+	opts := LinkOptions{SourceDir: clone, TargetDir: targetDir, Packages: []string{"nvim"}}
+
+	t.Run("dry run", func(t *testing.T) {
+		opts := opts
+		opts.DryRun = true
+		stdout, _ := captureOutput(t, func() { CreateLinks(opts) })
+		ContainsOutput(t, stdout, "Would check out package: nvim")
+		if _, err := os.Stat(filepath.Join(clone, "nvim")); !os.IsNotExist(err) {
+			t.Errorf("dry run checked out nvim: %v", err)
+		}
+	})
+
+	t.Run("fetching off", func(t *testing.T) {
+		SetSourceFetching(false)
+		t.Cleanup(func() { SetSourceFetching(true) })
+		var err error
+		CaptureOutput(t, func() { _, err = CreateLinks(opts) })
+		if err == nil || !strings.Contains(err.Error(), "outside the sparse checkout") {
+			t.Errorf("CreateLinks() error = %v, want outside the sparse checkout", err)
+		}
+	})
+
+	output := CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "Checked out package: nvim")
+	if !linkPointsTo(filepath.Join(targetDir, ".config", "nvim", "init.lua"), filepath.Join(clone, "nvim", ".config", "nvim", "init.lua")) {
+		t.Error("nvim was not linked after checking it out")
+	}
+}
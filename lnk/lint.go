@@ -0,0 +1,274 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+)
+
+// secretFilePatterns are base names of files that hold credentials and should
+// only be readable by their owner
+var secretFilePatterns = []string{
+	"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519",
+	"*.pem", "*.key", "*.p12", "*.pfx", "*.kdbx",
+	".netrc", ".pgpass", ".env", "credentials",
+}
+
+// maxLintFileSize is the largest file checked for mixed line endings
+const maxLintFileSize = 1 << 20
+
+// Lint checks the source directory itself for mistakes that linking would not
+// report: package lists naming missing directories, absolute symlinks stored in
+// the repository, world-writable files, secrets readable by others, sensitive
+// files stored in plaintext, and text files with mixed line endings. Files that will never be linked because of the
+// user's ignore rules are listed for review but are not problems. An error is
+// returned when any problem is found.
+func Lint(opts LinkOptions) error {
+	PrintCommandHeader("Lint")
+
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir := paths.SourceDir
+	PrintVerbose("Source directory: %s", sourceDir)
+
+	problems := checkPackageLists(sourceDir)
+	walked, err := lintSourceTree(sourceDir, opts.IgnorePatterns, opts.Sensitive)
+	if err != nil {
+		return fmt.Errorf("walking source directory: %w", err)
+	}
+	problems = append(problems, walked...)
+
+	ignored, err := neverLinkedFiles(sourceDir, opts.Packages, opts.IgnorePatterns)
+	if err != nil {
+		return err
+	}
+	for _, f := range ignored {
+		PrintInfo("Never linked: %s (ignored by %s)", ContractPath(f.path), f.pattern)
+	}
+
+	for _, p := range problems {
+		PrintWarningWithHint(p)
+	}
+
+	if len(problems) == 0 {
+		PrintSuccess("No problems found")
+		return nil
+	}
+	return fmt.Errorf("lint found %d problem(s)", len(problems))
+}
+
+// checkPackageLists reports entries of .lnkpackages and of each package's
+// .lnkrequires that do not name a package directory
+func checkPackageLists(sourceDir string) []error {
+	lists := []string{filepath.Join(sourceDir, PackagesFileName)}
+	for _, pkg := range availablePackages(sourceDir) {
+		lists = append(lists, filepath.Join(sourceDir, pkg, RequiresFileName))
+	}
+
+	var problems []error
+	for _, list := range lists {
+		if _, err := os.Stat(list); os.IsNotExist(err) {
+			continue
+		}
+		entries, err := parseIgnoreFile(list)
+		if err != nil {
+			problems = append(problems, NewPathError("read package list", list, err))
+			continue
+		}
+		PrintVerbose("Checking %d package(s) listed in %s", len(entries), ContractPath(list))
+		for _, entry := range entries {
+			if err := checkPackageEntry(sourceDir, list, entry); err != nil {
+				problems = append(problems, err)
+			}
+		}
+	}
+	return problems
+}
+
+// checkPackageEntry reports an entry of the package list at list that does
+// not name a package directory of sourceDir
+func checkPackageEntry(sourceDir, list, entry string) error {
+	name, err := cleanPackageName(sourceDir, entry)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(filepath.Join(sourceDir, name)); err == nil && info.IsDir() {
+		return nil
+	}
+	return WithHint(
+		fmt.Errorf("%s lists package %s, which does not exist", ContractPath(list), entry),
+		pathHint(filepath.Join(sourceDir, name), availablePackages(sourceDir),
+			fmt.Sprintf("Add the package or remove it from %s", ContractPath(list))))
+}
+
+// lintSourceTree walks the source directory (skipping .git) and reports
+// absolute symlinks, unsafe permissions, unencrypted files matching the
+// sensitive patterns (ignored or not, since they are still committed), and
+// mixed line endings in text files that are not ignored
+func lintSourceTree(sourceDir string, ignorePatterns, sensitive []string) ([]error, error) {
+	pm := NewPatternMatcher(ignorePatterns)
+	checkPerms := runtime.GOOS != "windows"
+
+	var problems []error
+	err := filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			dest, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if filepath.IsAbs(dest) {
+				problems = append(problems, WithHint(
+					fmt.Errorf("Absolute symlink %s -> %s", ContractPath(path), dest),
+					"Use a relative link target so the repository works on other machines"))
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if checkPerms {
+			problems = append(problems, checkFileMode(path, info.Mode())...)
+		}
+
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to calculate relative path: %w", err)
+		}
+		if pattern, ok := sensitiveRepoPattern(sensitive, relPath); ok {
+			encrypted, err := isEncryptedFile(path)
+			if err != nil {
+				return err
+			}
+			if !encrypted {
+				problems = append(problems, WithHint(
+					fmt.Errorf("Sensitive file %s is stored in plaintext (matches %s)", ContractPath(path), pattern),
+					"Encrypt it (e.g. with age or git-crypt), or remove it from the repository and its git history"))
+			}
+		}
+		if pm.Matches(relPath) || info.Size() > maxLintFileSize {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if hasMixedLineEndings(data) {
+			problems = append(problems, WithHint(
+				fmt.Errorf("Mixed line endings in %s", ContractPath(path)),
+				"Convert the file to LF (or CRLF) throughout, e.g. with dos2unix"))
+		}
+		return nil
+	})
+	return problems, err
+}
+
+// checkFileMode reports world-writable files and secrets others can read
+func checkFileMode(path string, mode fs.FileMode) []error {
+	var problems []error
+	if mode.Perm()&0002 != 0 {
+		problems = append(problems, WithHint(
+			fmt.Errorf("%s is world-writable", ContractPath(path)),
+			fmt.Sprintf("Any user could change it once linked; run: chmod o-w %s", ContractPath(path))))
+	}
+	if isSecretFile(path) && mode.Perm()&0077 != 0 {
+		problems = append(problems, WithHint(
+			fmt.Errorf("Secret file %s is accessible by other users (%s)", ContractPath(path), mode.Perm()),
+			fmt.Sprintf("Restrict it to its owner: chmod 600 %s", ContractPath(path))))
+	}
+	return problems
+}
+
+// isSecretFile reports whether the base name of path matches secretFilePatterns
+func isSecretFile(path string) bool {
+	name := filepath.Base(path)
+	return slices.ContainsFunc(secretFilePatterns, func(pattern string) bool {
+		matched, _ := filepath.Match(pattern, name)
+		return matched
+	})
+}
+
+// hasMixedLineEndings reports whether text data uses both CRLF and bare LF line
+// endings. Data containing a NUL byte is treated as binary and never mixed.
+func hasMixedLineEndings(data []byte) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+	crlf := bytes.Count(data, []byte("\r\n"))
+	lf := bytes.Count(data, []byte("\n"))
+	return crlf > 0 && lf > crlf
+}
+
+// ignoredFile is a file left out of linking and the pattern that ignored it
+type ignoredFile struct {
+	path    string
+	pattern string
+}
+
+// neverLinkedFiles lists files in the trees that would be linked (the given
+// packages, or the whole source directory) that are ignored by a pattern other
+// than the built-in ones. Built-in patterns only ignore repository files such
+// as README and .git, which are never meant to be linked.
+func neverLinkedFiles(sourceDir string, packages, ignorePatterns []string) ([]ignoredFile, error) {
+	builtIn := getBuiltInIgnorePatterns()
+	pm := NewPatternMatcher(ignorePatterns)
+
+	var dirs []string
+	for _, pkg := range packages {
+		if name, err := cleanPackageName(sourceDir, pkg); err == nil {
+			if info, err := os.Stat(filepath.Join(sourceDir, name)); err == nil && info.IsDir() {
+				dirs = append(dirs, filepath.Join(sourceDir, name))
+			}
+		}
+	}
+	if len(packages) == 0 {
+		dirs = []string{sourceDir}
+	}
+
+	var ignored []ignoredFile
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				return fmt.Errorf("failed to calculate relative path: %w", err)
+			}
+			if pattern, matched := pm.MatchingPattern(relPath); matched && !slices.Contains(builtIn, pattern) {
+				ignored = append(ignored, ignoredFile{path: path, pattern: pattern})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("walking source directory: %w", err)
+		}
+	}
+	return ignored, nil
+}
@@ -0,0 +1,82 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, PackagesFileName), "shell\ntmux\n")
+	createTestFile(t, filepath.Join(sourceDir, "nvim", RequiresFileName), "shel\n")
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".profile"), "export A=1\r\nexport B=2\n")
+	if err := os.Symlink("/etc/hosts", filepath.Join(sourceDir, "work", ".hosts")); err != nil {
+		t.Fatal(err)
+	}
+	key := filepath.Join(sourceDir, "work", ".ssh", "id_ed25519")
+	createTestFile(t, key, "secret")
+	if err := os.Chmod(key, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	_, stderr := captureOutput(t, func() {
+		err = Lint(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir})
+	})
+
+	if err == nil {
+		t.Fatal("expected error when problems are found")
+	}
+	ContainsOutput(t, err.Error(), "5 problem(s)")
+	ContainsOutput(t, stderr,
+		"lists package tmux, which does not exist",
+		"lists package shel, which does not exist",
+		"Absolute symlink",
+		"id_ed25519 is accessible by other users",
+		"chmod 600",
+		"Mixed line endings in",
+		".profile")
+}
+
+func TestLintClean(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".bashrc.local"), "a\r\nb\n")
+	createTestFile(t, filepath.Join(sourceDir, "README.md"), "# dotfiles\n")
+
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = Lint(LinkOptions{
+			SourceDir:      sourceDir,
+			TargetDir:      targetDir,
+			IgnorePatterns: append(getBuiltInIgnorePatterns(), "*.local"),
+		})
+	})
+
+	if err != nil {
+		t.Fatalf("Lint() error = %v\n%s", err, stderr)
+	}
+	ContainsOutput(t, stdout, "Never linked:", ".bashrc.local (ignored by *.local)", "No problems found")
+	NotContainsOutput(t, stdout, "README")
+}
+
+func TestHasMixedLineEndings(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"lf", "a\nb\n", false},
+		{"crlf", "a\r\nb\r\n", false},
+		{"mixed", "a\r\nb\n", true},
+		{"binary", "a\r\nb\n\x00", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasMixedLineEndings([]byte(tt.data)); got != tt.want {
+				t.Errorf("hasMixedLineEndings(%q) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadLocalOnlyFile loads the local-only patterns from a .lnklocal file in the
+// source directory. Each pattern names paths in the target directory that
+// belong to this machine alone: lnk never links over them and never adopts
+// them, even when a file with the same name appears in the source later.
+func LoadLocalOnlyFile(sourceDir string) ([]string, error) {
+	localFilePath := filepath.Join(sourceDir, LocalOnlyFileName)
+	if _, err := os.Stat(localFilePath); os.IsNotExist(err) {
+		PrintVerbose("No .lnklocal file found at: %s", localFilePath)
+		return nil, nil
+	}
+
+	// Same line format as .lnkignore
+	patterns, err := parseIgnoreFile(localFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .lnklocal: %w", err)
+	}
+
+	PrintVerbose("Loaded %d local-only patterns from .lnklocal", len(patterns))
+	return patterns, nil
+}
+
+// localOnlyMatcher matches target paths, relative to the target directory,
+// against local-only patterns. Patterns follow .lnkignore rules and may be
+// written with a leading ~/, which is dropped.
+type localOnlyMatcher struct {
+	targetDir string
+	pm        *PatternMatcher
+}
+
+func newLocalOnlyMatcher(targetDir string, patterns []string) *localOnlyMatcher {
+	return &localOnlyMatcher{targetDir: targetDir, pm: NewPatternMatcher(targetPatterns(patterns))}
+}
+
+// targetPatterns drops the leading ~/ from patterns written as home directory
+// paths, keeping any ! negation
+func targetPatterns(patterns []string) []string {
+	cleaned := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		negation, rest := "", pattern
+		if r, ok := strings.CutPrefix(rest, "!"); ok {
+			negation, rest = "!", r
+		}
+		cleaned[i] = negation + strings.TrimPrefix(rest, "~/")
+	}
+	return cleaned
+}
+
+// matches reports whether path, an absolute path, is local-only and the
+// pattern that made it so
+func (m *localOnlyMatcher) matches(path string) (string, bool) {
+	rel, err := filepath.Rel(m.targetDir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return m.pm.MatchingPattern(rel)
+}
+
+// filterLocalOnly splits planned links into those lnk may create and those
+// whose target is local-only
+func filterLocalOnly(links []PlannedLink, targetDir string, patterns []string) (kept, local []PlannedLink) {
+	if len(patterns) == 0 {
+		return links, nil
+	}
+	m := newLocalOnlyMatcher(targetDir, patterns)
+	for _, link := range links {
+		if pattern, ok := m.matches(link.Target); ok {
+			PrintVerbose("Local-only: %s (%s)", ContractPath(link.Target), pattern)
+			local = append(local, link)
+			continue
+		}
+		kept = append(kept, link)
+	}
+	return kept, local
+}
@@ -0,0 +1,112 @@
+package lnk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalOnlyMatcher(t *testing.T) {
+	targetDir := "/home/u"
+	m := newLocalOnlyMatcher(targetDir, []string{"~/.config/secrets.local.json", ".ssh/", "*.local", "!keep.local"})
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/home/u/.config/secrets.local.json", true},
+		{"/home/u/.ssh/config", true},
+		{"/home/u/.bashrc.local", true},
+		{"/home/u/keep.local", false},
+		{"/home/u/.bashrc", false},
+		{"/elsewhere/.bashrc.local", false},
+	}
+	for _, tt := range tests {
+		if _, got := m.matches(tt.path); got != tt.want {
+			t.Errorf("matches(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCreateLinksSkipsLocalOnly(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := LinkOptions{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Packages:  []string{"shell", "work"},
+		LocalOnly: []string{"~/.gitconfig"},
+	}
+
+	output := CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "Local-only: "+filepath.Join(targetDir, ".gitconfig"))
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	if _, err := os.Lstat(filepath.Join(targetDir, ".gitconfig")); !os.IsNotExist(err) {
+		t.Errorf("local-only .gitconfig was linked (err = %v)", err)
+	}
+
+	// A local file at the path is neither unlinked nor a conflict
+	createTestFile(t, filepath.Join(targetDir, ".gitconfig"), "[user] local")
+	output = CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+	})
+	NotContainsOutput(t, output, ".gitconfig")
+}
+
+func TestAdoptRefusesLocalOnly(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	secret := filepath.Join(targetDir, ".config", "secrets.local.json")
+	createTestFile(t, secret, "{}")
+	createTestFile(t, filepath.Join(targetDir, ".config", "app.conf"), "app")
+	opts := AdoptOptions{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Paths:     []string{secret},
+		LocalOnly: []string{"~/.config/secrets.local.json"},
+	}
+
+	var err error
+	captureOutput(t, func() { _, err = Adopt(opts) })
+	if !errors.Is(err, ErrLocalOnly) {
+		t.Fatalf("Adopt() error = %v, want ErrLocalOnly", err)
+	}
+
+	// Adopting the directory skips the local-only file
+	opts.Paths = []string{filepath.Join(targetDir, ".config")}
+	output := CaptureOutput(t, func() {
+		if _, err := Adopt(opts); err != nil {
+			t.Fatalf("Adopt() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "Local-only: "+secret)
+	assertSymlink(t, filepath.Join(targetDir, ".config", "app.conf"), filepath.Join(sourceDir, ".config", "app.conf"))
+	if info, err := os.Lstat(secret); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("local-only file was adopted (err = %v)", err)
+	}
+}
+
+func TestDoctorLocalOnly(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := LinkOptions{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Packages:  []string{"shell", "work"},
+		LocalOnly: []string{".gitconfig", ".bashrc"},
+	}
+	// .bashrc was linked before it was marked local-only
+	createTestSymlink(t, filepath.Join(sourceDir, "shell", ".bashrc"), filepath.Join(targetDir, ".bashrc"))
+
+	var err error
+	stdout, stderr := captureOutput(t, func() { err = Doctor(opts) })
+	if err == nil {
+		t.Fatal("expected error for a linked local-only path")
+	}
+	ContainsOutput(t, err.Error(), "1 problem(s)")
+	ContainsOutput(t, stderr, "Local-only path "+filepath.Join(targetDir, ".bashrc")+" is linked to")
+	ContainsOutput(t, stdout, "Local-only: "+filepath.Join(targetDir, ".gitconfig")+" (not linked from")
+}
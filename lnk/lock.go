@@ -0,0 +1,77 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Commands that change files hold an advisory lock on the state directory of
+// the target directory while they run, so two lnk processes on a shared home
+// never change it at once. The lock is taken on the directory itself: nothing
+// is written, so a run that changes nothing leaves the tree as it was, and the
+// kernel drops the lock when a process exits, so a crashed run leaves no stale
+// lock behind.
+
+var (
+	// lockPollInterval is how often a waiting command tries the lock again
+	lockPollInterval = 500 * time.Millisecond
+	// heldLock is the state directory this process holds the lock on, or nil
+	heldLock *os.File
+)
+
+// AcquireLock takes the lock on targetDir for command. When another lnk holds
+// it, AcquireLock returns an ErrLocked error, or with wait, waits until it is
+// released. Where the file system has no advisory locks, lnk runs unlocked.
+func AcquireLock(targetDir, command string, wait bool) error {
+	if heldLock != nil {
+		return nil
+	}
+	dir := StateDir(targetDir)
+	if err := fsys.MkdirAll(dir, 0755); err != nil {
+		return NewPathErrorWithHint("create state directory", dir, err,
+			"Check that you have write permissions in the target directory")
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return NewPathErrorWithHint("lock", dir, err, "Check file permissions on the lnk state directory")
+	}
+
+	start, waiting := time.Now(), false
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			PrintVerbose("Not locking %s: %v", ContractPath(dir), err)
+			f.Close()
+			return nil
+		}
+		if ok {
+			heldLock = f
+			Trace("lock", "path", ContractPath(dir), "waited_ms", time.Since(start).Milliseconds())
+			return nil
+		}
+		if !wait {
+			f.Close()
+			return NewPathErrorWithHint(command, targetDir, ErrLocked,
+				"Another lnk that changes files is running for this home directory; rerun with --wait to run after it")
+		}
+		if !waiting {
+			PrintInfo("Waiting for another lnk to finish changing %s...", ContractPath(targetDir))
+			waiting = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// ReleaseLock releases the lock this process holds. Safe to call when it
+// holds none.
+func ReleaseLock() {
+	if heldLock == nil {
+		return
+	}
+	if err := heldLock.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		PrintVerbose("Failed to release lock: %v", fmt.Errorf("closing %s: %w", ContractPath(heldLock.Name()), err))
+	}
+	heldLock = nil
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package lnk
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+	targetDir := t.TempDir()
+	if err := AcquireLock(targetDir, "create", false); err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+	held := heldLock
+	t.Cleanup(func() {
+		heldLock = held
+		ReleaseLock()
+	})
+
+	// A second process is another open file description on the directory
+	heldLock = nil
+	err := AcquireLock(targetDir, "remove", false)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("AcquireLock() while held error = %v, want ErrLocked", err)
+	}
+	if GetErrorHint(err) == "" {
+		t.Errorf("expected a hint, got none for %v", err)
+	}
+
+	// --wait runs once the holder releases the lock
+	origInterval := lockPollInterval
+	lockPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { lockPollInterval = origInterval })
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		held.Close()
+	}()
+	output := CaptureOutput(t, func() {
+		if err := AcquireLock(targetDir, "remove", true); err != nil {
+			t.Errorf("AcquireLock() with wait error = %v", err)
+		}
+	})
+	if output == "" {
+		t.Error("expected a waiting message")
+	}
+	held = heldLock
+}
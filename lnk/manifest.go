@@ -0,0 +1,389 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// manifestVersion is the current manifest file format version. Version 2
+// stores paths inside the home directory as "~/...".
+const manifestVersion = 2
+
+// Manifest records what lnk created in a target directory, so later runs can
+// tell lnk-created state apart from state that existed before.
+type Manifest struct {
+	Version int            `json:"version"`
+	Home    string         `json:"home,omitempty"` // home the recorded links were made in; changed only by rehome
+	Dirs    []ManifestDir  `json:"dirs,omitempty"`
+	Links   []ManifestLink `json:"links,omitempty"`
+	Tracked []string       `json:"tracked,omitempty"` // source directories whose links are recorded in Links
+	Copies  []ManifestCopy `json:"copies,omitempty"`
+}
+
+// ManifestDir is a directory lnk created while linking from a source directory
+type ManifestDir struct {
+	Path   string `json:"path"`   // absolute directory path
+	Source string `json:"source"` // absolute source directory whose links required it
+}
+
+// ManifestLink is a symlink lnk created while linking from a source directory
+type ManifestLink struct {
+	Path      string `json:"path"`                // absolute symlink path
+	Source    string `json:"source"`              // absolute source directory that created it
+	Dest      string `json:"dest,omitempty"`      // source file the link points to (ephemeral links only)
+	Head      string `json:"head,omitempty"`      // git HEAD of the source when the link was made; prune counts removals since
+	Ephemeral bool   `json:"ephemeral,omitempty"` // link vanishes on reboot; restored by 'lnk ensure --fast'
+}
+
+// ManifestCopy is a path managed as a copy of its source instead of a symlink
+// ('lnk orphan --to-copy'); create leaves it alone
+type ManifestCopy struct {
+	Path   string `json:"path"`           // absolute path of the copy
+	Source string `json:"source"`         // absolute source directory it was linked from
+	Dest   string `json:"dest"`           // source file or directory it is a copy of
+	Hash   string `json:"hash,omitempty"` // SHA-256 of a file copy when lnk last wrote it, to detect local edits
+}
+
+// StateDir returns the directory holding lnk state for targetDir.
+// $XDG_STATE_HOME is honored when targetDir is the user's home directory;
+// otherwise state lives under <targetDir>/.local/state/lnk.
+func StateDir(targetDir string) string {
+	if home, err := os.UserHomeDir(); err == nil && filepath.Clean(home) == filepath.Clean(targetDir) {
+		if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" && filepath.IsAbs(xdg) {
+			return filepath.Join(xdg, "lnk")
+		}
+	}
+	return filepath.Join(targetDir, ".local", "state", "lnk")
+}
+
+// ManifestPath returns the manifest file location for targetDir
+func ManifestPath(targetDir string) string {
+	return filepath.Join(StateDir(targetDir), ManifestFileName)
+}
+
+// LoadManifest reads the manifest for targetDir. A missing manifest is not an
+// error; an empty manifest is returned instead.
+func LoadManifest(targetDir string) (*Manifest, error) {
+	path := ManifestPath(targetDir)
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Manifest{Version: manifestVersion}, nil
+		}
+		return nil, NewPathErrorWithHint("read manifest", path, err,
+			"Check file permissions")
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, NewPathErrorWithHint("parse manifest", path, err,
+			fmt.Sprintf("The manifest is corrupt; remove %s to start fresh", ContractPath(path)))
+	}
+	if m.Version > manifestVersion {
+		return nil, NewPathErrorWithHint("read manifest", path,
+			fmt.Errorf("unsupported manifest version %d", m.Version),
+			"Upgrade lnk to a newer version")
+	}
+	// "~" is whichever directory holds the manifest now, so a moved home
+	// keeps its records
+	home := filepath.Clean(targetDir)
+	expanded := m.mapPaths(func(p string) string { return expandHomeRelative(p, home) })
+	expanded.Version = manifestVersion
+	return expanded, nil
+}
+
+// Save writes the manifest for targetDir atomically (write to temp file, then
+// rename), and records it in the manifest history.
+func (m *Manifest) Save(targetDir string) error {
+	path := ManifestPath(targetDir)
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return NewPathErrorWithHint("create state directory", filepath.Dir(path), err,
+			"Check that you have write permissions in the parent directory")
+	}
+
+	home := filepath.Clean(targetDir)
+	if m.Home == "" {
+		m.Home = home
+	}
+	stored := m.mapPaths(func(p string) string { return homeRelative(p, home) })
+	stored.Version = manifestVersion
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	if err := writeFileAtomic(path, append(data, '\n'), 0600); err != nil {
+		return NewPathError("write manifest", path, err)
+	}
+	recordManifestHistory(targetDir, m)
+	return nil
+}
+
+// mapPaths returns a copy of m with fn applied to every recorded path
+func (m *Manifest) mapPaths(fn func(string) string) *Manifest {
+	out := &Manifest{Version: m.Version, Home: m.Home}
+	for _, d := range m.Dirs {
+		out.Dirs = append(out.Dirs, ManifestDir{Path: fn(d.Path), Source: fn(d.Source)})
+	}
+	for _, l := range m.Links {
+		l.Path, l.Source, l.Dest = fn(l.Path), fn(l.Source), fn(l.Dest)
+		out.Links = append(out.Links, l)
+	}
+	for _, t := range m.Tracked {
+		out.Tracked = append(out.Tracked, fn(t))
+	}
+	for _, c := range m.Copies {
+		c.Path, c.Source, c.Dest = fn(c.Path), fn(c.Source), fn(c.Dest)
+		out.Copies = append(out.Copies, c)
+	}
+	return out
+}
+
+// homeRelative returns path as "~" or "~/rel" when it is inside home, and
+// unchanged otherwise
+func homeRelative(path, home string) string {
+	if path == home {
+		return "~"
+	}
+	if rel, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return "~/" + filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// expandHomeRelative reverses homeRelative for home
+func expandHomeRelative(path, home string) string {
+	if path == "~" {
+		return home
+	}
+	if rel, ok := strings.CutPrefix(path, "~/"); ok {
+		return filepath.Join(home, filepath.FromSlash(rel))
+	}
+	return path
+}
+
+// AddDir records that lnk created dir for source. Duplicate entries are ignored.
+func (m *Manifest) AddDir(dir, source string) {
+	for _, d := range m.Dirs {
+		if d.Path == dir && d.Source == source {
+			return
+		}
+	}
+	m.Dirs = append(m.Dirs, ManifestDir{Path: dir, Source: source})
+}
+
+// AddLink records that lnk created the symlink at path for source, replacing
+// any earlier record for path but keeping its Head when the source is the
+// same. A copy recorded at path is linked again, so its record is dropped.
+func (m *Manifest) AddLink(path, source string) {
+	m.RemoveCopy(path)
+	for i, l := range m.Links {
+		if l.Path == path {
+			m.Links[i] = ManifestLink{Path: path, Source: source}
+			if l.Source == source {
+				m.Links[i].Head = l.Head
+			}
+			return
+		}
+	}
+	m.Links = append(m.Links, ManifestLink{Path: path, Source: source})
+}
+
+// MarkHead records head as the source's git HEAD for the link at path, already
+// recorded with AddLink, unless one is recorded from when the link was made
+func (m *Manifest) MarkHead(path, head string) {
+	for i, l := range m.Links {
+		if l.Path == path {
+			if l.Head == "" {
+				m.Links[i].Head = head
+			}
+			return
+		}
+	}
+}
+
+// LinkHead returns the source's git HEAD recorded for the link at path, or ""
+func (m *Manifest) LinkHead(path string) string {
+	for _, l := range m.Links {
+		if l.Path == path {
+			return l.Head
+		}
+	}
+	return ""
+}
+
+// MarkEphemeral records that the link at path, already recorded with AddLink,
+// vanishes on reboot and points to dest
+func (m *Manifest) MarkEphemeral(path, dest string) {
+	for i, l := range m.Links {
+		if l.Path == path {
+			m.Links[i].Dest, m.Links[i].Ephemeral = dest, true
+			return
+		}
+	}
+}
+
+// TracksLinks reports whether links created for source are recorded. Sources
+// last linked by a version of lnk without link records are not tracked.
+func (m *Manifest) TracksLinks(source string) bool {
+	return slices.Contains(m.Tracked, source)
+}
+
+// LinkSource returns the source directory recorded for the symlink at path
+func (m *Manifest) LinkSource(path string) (string, bool) {
+	for _, l := range m.Links {
+		if l.Path == path {
+			return l.Source, true
+		}
+	}
+	return "", false
+}
+
+// RemoveLinks drops the records for the given symlink paths
+func (m *Manifest) RemoveLinks(paths []string) bool {
+	drop := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		drop[path] = true
+	}
+	kept := m.Links[:0]
+	for _, l := range m.Links {
+		if !drop[l.Path] {
+			kept = append(kept, l)
+		}
+	}
+	changed := len(kept) != len(m.Links)
+	m.Links = kept
+	return changed
+}
+
+// AddCopy records that path is managed as a copy of dest for source, with
+// the hash of the copy as written (empty for directories), replacing any
+// earlier record for path
+func (m *Manifest) AddCopy(path, source, dest, hash string) {
+	for i, c := range m.Copies {
+		if c.Path == path {
+			m.Copies[i] = ManifestCopy{Path: path, Source: source, Dest: dest, Hash: hash}
+			return
+		}
+	}
+	m.Copies = append(m.Copies, ManifestCopy{Path: path, Source: source, Dest: dest, Hash: hash})
+}
+
+// RemoveCopy drops the copy record for path
+func (m *Manifest) RemoveCopy(path string) bool {
+	for i, c := range m.Copies {
+		if c.Path == path {
+			m.Copies = slices.Delete(m.Copies, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// missingDirs returns dir and each of its ancestors that do not exist yet,
+// stopping at boundaryDir (exclusive). Deepest directories come first.
+func missingDirs(dir, boundaryDir string) []string {
+	var missing []string
+	for current := dir; current != boundaryDir; current = filepath.Dir(current) {
+		if _, err := fsys.Lstat(current); err == nil {
+			break
+		}
+		missing = append(missing, current)
+		if parent := filepath.Dir(current); parent == current {
+			break
+		}
+	}
+	return missing
+}
+
+// recordCreatedDirs adds dirs to the manifest for targetDir. Failure to update
+// the manifest is reported as a warning because the links themselves succeeded.
+func recordCreatedDirs(targetDir, sourceDir string, dirs []string) {
+	if len(dirs) == 0 {
+		return
+	}
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to record created directories: %w", err))
+		return
+	}
+	for _, dir := range dirs {
+		m.AddDir(dir, sourceDir)
+	}
+	if err := m.Save(targetDir); err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to record created directories: %w", err))
+		return
+	}
+	PrintVerbose("Recorded %d created directories in %s", len(dirs), ContractPath(ManifestPath(targetDir)))
+}
+
+// recordCreatedLinks marks links as created by lnk for sourceDir: each link is
+// tagged with the LinkTagAttr extended attribute where the platform allows it,
+// and recorded in the manifest for targetDir either way. Failures are warnings
+// because the links themselves succeeded.
+func recordCreatedLinks(targetDir, sourceDir string, links []string) {
+	if len(links) == 0 {
+		return
+	}
+	tagged := 0
+	for _, link := range links {
+		if err := tagLink(link, sourceDir); err != nil {
+			PrintVerbose("Failed to tag %s: %v", ContractPath(link), err)
+			continue
+		}
+		tagged++
+	}
+
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to record created links: %w", err))
+		return
+	}
+	head := gitHead(sourceDir)
+	for _, link := range links {
+		m.AddLink(link, sourceDir)
+		if head != "" {
+			m.MarkHead(link, head)
+		}
+	}
+	if !m.TracksLinks(sourceDir) {
+		m.Tracked = append(m.Tracked, sourceDir)
+	}
+	if err := m.Save(targetDir); err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to record created links: %w", err))
+		return
+	}
+	PrintVerbose("Recorded %d created links in %s (%d tagged)", len(links), ContractPath(ManifestPath(targetDir)), tagged)
+}
+
+// forgetLinks drops manifest records for links lnk removed or replaced
+func forgetLinks(targetDir string, links []string) {
+	if len(links) == 0 {
+		return
+	}
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to update manifest: %w", err))
+		return
+	}
+	if !m.RemoveLinks(links) {
+		return
+	}
+	if err := m.Save(targetDir); err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to update manifest: %w", err))
+	}
+}
+
+// createdByLnk reports whether the symlink at path was created by lnk for
+// sourceDir, according to its LinkTagAttr tag or, failing that, the manifest
+func createdByLnk(m *Manifest, path, sourceDir string) bool {
+	if tag, err := linkTag(path); err == nil && tag != "" {
+		return tag == sourceDir
+	}
+	source, ok := m.LinkSource(path)
+	return ok && source == sourceDir
+}
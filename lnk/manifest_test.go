@@ -0,0 +1,188 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadManifestMissing(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	m, err := LoadManifest(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if m.Version != manifestVersion {
+		t.Errorf("Version = %d, want %d", m.Version, manifestVersion)
+	}
+	if len(m.Dirs) != 0 {
+		t.Errorf("Dirs = %v, want empty", m.Dirs)
+	}
+}
+
+func TestManifestSaveLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	m := &Manifest{Version: manifestVersion}
+	m.AddDir("/home/user/.config/nvim", "/repo")
+	m.AddDir("/home/user/.config/nvim", "/repo") // duplicate is ignored
+	m.AddDir("/home/user/.ssh", "/other")
+	if err := m.Save(tmpDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := LoadManifest(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	want := []ManifestDir{
+		{Path: "/home/user/.config/nvim", Source: "/repo"},
+		{Path: "/home/user/.ssh", Source: "/other"},
+	}
+	if !reflect.DeepEqual(got.Dirs, want) {
+		t.Errorf("Dirs = %v, want %v", got.Dirs, want)
+	}
+}
+
+func TestManifestHomeRelative(t *testing.T) {
+	oldHome := filepath.Join(t.TempDir(), "old")
+	m := &Manifest{Version: manifestVersion}
+	m.AddLink(filepath.Join(oldHome, ".bashrc"), filepath.Join(oldHome, "dotfiles"))
+	m.AddLink("/etc/lnk-test", "/srv/dotfiles")
+	if err := m.Save(oldHome); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	data, _ := os.ReadFile(ManifestPath(oldHome))
+	if !strings.Contains(string(data), `"path": "~/.bashrc"`) || !strings.Contains(string(data), `"source": "~/dotfiles"`) {
+		t.Errorf("paths inside the home should be stored relative to it:\n%s", data)
+	}
+
+	// The home moves; records follow it, and the old home is remembered
+	newHome := filepath.Join(filepath.Dir(oldHome), "new")
+	if err := os.Rename(oldHome, newHome); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadManifest(newHome)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	want := []ManifestLink{
+		{Path: filepath.Join(newHome, ".bashrc"), Source: filepath.Join(newHome, "dotfiles")},
+		{Path: "/etc/lnk-test", Source: "/srv/dotfiles"},
+	}
+	if !reflect.DeepEqual(got.Links, want) || got.Home != oldHome {
+		t.Errorf("LoadManifest() = %+v, want links %v and home %s", got, want, oldHome)
+	}
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"corrupt", "{not json"},
+		{"newer version", `{"version": 99}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			createTestFile(t, ManifestPath(tmpDir), tt.content)
+
+			if _, err := LoadManifest(tmpDir); err == nil {
+				t.Error("LoadManifest() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestMissingDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, ".config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := missingDirs(filepath.Join(tmpDir, ".config", "nvim", "lua"), tmpDir)
+	want := []string{
+		filepath.Join(tmpDir, ".config", "nvim", "lua"),
+		filepath.Join(tmpDir, ".config", "nvim"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("missingDirs() = %v, want %v", got, want)
+	}
+
+	if got := missingDirs(filepath.Join(tmpDir, ".config"), tmpDir); len(got) != 0 {
+		t.Errorf("missingDirs() for existing dir = %v, want empty", got)
+	}
+}
+
+func TestManifestLinks(t *testing.T) {
+	m := &Manifest{Version: manifestVersion}
+	m.AddLink("/home/user/.bashrc", "/repo")
+	m.AddLink("/home/user/.vimrc", "/repo")
+	m.AddLink("/home/user/.bashrc", "/other") // re-recorded for another source
+
+	if source, ok := m.LinkSource("/home/user/.bashrc"); !ok || source != "/other" {
+		t.Errorf("LinkSource(.bashrc) = %q, %v, want /other, true", source, ok)
+	}
+	if _, ok := m.LinkSource("/home/user/.zshrc"); ok {
+		t.Error("LinkSource(.zshrc) should not be recorded")
+	}
+
+	if !m.RemoveLinks([]string{"/home/user/.bashrc", "/home/user/.zshrc"}) {
+		t.Error("RemoveLinks() should report a change")
+	}
+	if m.RemoveLinks([]string{"/home/user/.zshrc"}) {
+		t.Error("RemoveLinks() of an unrecorded link should report no change")
+	}
+	want := []ManifestLink{{Path: "/home/user/.vimrc", Source: "/repo"}}
+	if !reflect.DeepEqual(m.Links, want) {
+		t.Errorf("Links = %v, want %v", m.Links, want)
+	}
+}
+
+func TestCreateAndRemoveRecordLinks(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	// A link the user made into the repository by hand
+	manual := filepath.Join(targetDir, ".gitconfig")
+	if err := os.Symlink(filepath.Join(sourceDir, "work", ".gitconfig"), manual); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	if !createdByLnk(m, bashrc, sourceDir) {
+		t.Errorf("expected %s to be recorded as created by lnk, got %v", bashrc, m.Links)
+	}
+	if createdByLnk(m, manual, sourceDir) {
+		t.Errorf("manual link %s should not be recorded as created by lnk", manual)
+	}
+	if createdByLnk(m, bashrc, filepath.Join(sourceDir, "other")) {
+		t.Error("link should only be attributed to the source that created it")
+	}
+
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	if m, err = LoadManifest(targetDir); err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if _, ok := m.LinkSource(bashrc); ok {
+		t.Errorf("removed link should be dropped from the manifest, got %v", m.Links)
+	}
+}
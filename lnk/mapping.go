@@ -0,0 +1,322 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Mapping modes: how a source meets its target
+const (
+	MapMergeInto = "merge_into" // link the source's files into the target directory
+	MapLinkAs    = "link_as"    // link the source itself at the target path
+)
+
+// mapModes lists the modes a --map value may end with
+var mapModes = []string{MapMergeInto, MapLinkAs}
+
+// Mapping links a source directory (or file) into a target directory in
+// addition to the packages, for a single run (--map SRC:TGT[:MODE]) or saved
+// in .lnkmaps
+type Mapping struct {
+	Source string   // relative to the source directory, absolute, or ~/...
+	Target string   // relative to the target directory, absolute, or ~/...; a trailing slash means merge_into
+	Mode   string   // MapMergeInto, MapLinkAs, or "" to decide from the paths
+	Groups []string // link groups the mapped links belong to (see groups.go)
+}
+
+// String formats the mapping as it is written on the command line
+func (m Mapping) String() string {
+	s := m.Source + ":" + m.Target
+	if m.Mode != "" {
+		s += ":" + m.Mode
+	}
+	if len(m.Groups) > 0 {
+		s += mapGroupsField + strings.Join(m.Groups, ",")
+	}
+	return s
+}
+
+// LoadMapsFile loads the mappings saved in a .lnkmaps file in the source
+// directory, one SRC:TGT[:MODE] per line as --map takes them. A missing file
+// is not an error; nil is returned instead.
+func LoadMapsFile(sourceDir string) ([]Mapping, error) {
+	mapsFilePath := filepath.Join(sourceDir, MapsFileName)
+	if _, err := os.Stat(mapsFilePath); os.IsNotExist(err) {
+		PrintVerbose("No .lnkmaps file found at: %s", mapsFilePath)
+		return nil, nil
+	}
+
+	// Same line format as .lnkignore: one entry per line, # comments
+	lines, err := parseIgnoreFile(mapsFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .lnkmaps: %w", err)
+	}
+	maps := make([]Mapping, 0, len(lines))
+	for _, line := range lines {
+		m, err := ParseMapping(line)
+		if err != nil {
+			return nil, NewPathErrorWithHint("parse mapping", mapsFilePath, err,
+				fmt.Sprintf("Fix the line %q; each line is SRC:TGT[:MODE] as --map takes it", line))
+		}
+		maps = append(maps, m)
+	}
+
+	PrintVerbose("Loaded %d mappings from .lnkmaps", len(maps))
+	return maps, nil
+}
+
+// SaveMapping adds m to the .lnkmaps file in the source directory, creating
+// the file if needed
+func SaveMapping(sourceDir string, m Mapping) error {
+	path := filepath.Join(sourceDir, MapsFileName)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return NewPathErrorWithHint("read mappings", path, err, "Check file permissions")
+	}
+	line := m.String() + "\n"
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		line = "\n" + line
+	}
+	f, err := fsys.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return NewPathErrorWithHint("save mapping", path, err, "Check that the source directory is writable")
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte(line)); err != nil {
+		return NewPathErrorWithHint("save mapping", path, err, "Check that the source directory is writable")
+	}
+	return nil
+}
+
+// Same reports whether m and o link the same source to the same target in
+// the same mode, whatever their groups
+func (m Mapping) Same(o Mapping) bool {
+	return m.Source == o.Source && m.Target == o.Target && m.Mode == o.Mode
+}
+
+// mapGroupsField separates a mapping from the groups its links belong to
+const mapGroupsField = " groups="
+
+// ParseMapping parses a --map value of the form SRC:TGT[:MODE] [groups=LIST].
+// The first colon separates the two paths; a last field naming a mode is the
+// mode.
+func ParseMapping(spec string) (Mapping, error) {
+	var groups []string
+	if i := strings.LastIndex(spec, mapGroupsField); i >= 0 {
+		spec, groups = spec[:i], splitList(spec[i+len(mapGroupsField):])
+		if len(groups) == 0 {
+			return Mapping{}, NewValidationErrorWithHint("map", spec+mapGroupsField, "no groups given",
+				"Example: projects/foo/config:.config/foo groups=gui,work")
+		}
+	}
+	source, target, ok := strings.Cut(spec, ":")
+	var mode string
+	if i := strings.LastIndex(target, ":"); i >= 0 && slices.Contains(mapModes, target[i+1:]) {
+		target, mode = target[:i], target[i+1:]
+	}
+	if !ok || strings.TrimSpace(source) == "" || strings.TrimSpace(target) == "" {
+		return Mapping{}, NewValidationErrorWithHint("map", spec, "expected SRC:TGT",
+			"Example: --map projects/foo/config:.config/foo")
+	}
+	return Mapping{Source: source, Target: target, Mode: mode, Groups: groups}, nil
+}
+
+// resolveMappings makes mapping paths absolute: sources relative to sourceDir,
+// targets relative to targetDir. Every source must exist. Each mapping's mode
+// is settled (see mappingMode), and a file merged into a directory gets its
+// own name there.
+func resolveMappings(maps []Mapping, home, sourceDir, targetDir string) ([]Mapping, error) {
+	resolved := make([]Mapping, 0, len(maps))
+	for _, m := range maps {
+		source, err := resolveMappingPath(m.Source, home, sourceDir)
+		if err != nil {
+			return nil, err
+		}
+		target, err := resolveMappingPath(m.Target, home, targetDir)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fsys.Stat(source); err != nil {
+			return nil, NewValidationErrorWithHint("map", m.String(),
+				fmt.Sprintf("source %s does not exist", ContractPath(source)),
+				pathHint(source, siblingPaths(source),
+					fmt.Sprintf("Relative sources are resolved against %s", ContractPath(sourceDir))))
+		}
+		mode, err := mappingMode(m, source, target)
+		if err != nil {
+			return nil, err
+		}
+		if mode == MapMergeInto && !isDir(source) {
+			target = filepath.Join(target, filepath.Base(source))
+		}
+		resolved = append(resolved, Mapping{Source: source, Target: target, Mode: mode, Groups: m.Groups})
+	}
+	return resolved, nil
+}
+
+// mappingMode settles how an absolute source meets its target. A trailing
+// slash on the target, or merge_into, merges a directory's files into the
+// target and puts a file inside it; link_as links the source at the target
+// path. Without either, a file is linked as the target and a directory is
+// merged into it, which is an error when the target is not a directory, since
+// the user may have meant link_as.
+func mappingMode(m Mapping, source, target string) (string, error) {
+	slash := os.IsPathSeparator(m.Target[len(m.Target)-1])
+	info, statErr := fsys.Stat(target)
+	targetIsDir := statErr == nil && info.IsDir()
+	switch {
+	case slash && m.Mode == MapLinkAs:
+		return "", NewValidationErrorWithHint("map", m.String(),
+			"a trailing slash on TGT means merge_into, not link_as",
+			fmt.Sprintf("Drop the slash to link %s as %s, or drop :link_as to merge into it",
+				ContractPath(source), ContractPath(target)))
+	case slash || m.Mode == MapMergeInto:
+		if statErr == nil && !targetIsDir {
+			return "", NewValidationErrorWithHint("map", m.String(),
+				fmt.Sprintf("%s is not a directory to merge into", ContractPath(target)),
+				"Use :link_as to replace it with a link, or map into a directory")
+		}
+		return MapMergeInto, nil
+	case m.Mode == MapLinkAs:
+		if info, err := fsys.Lstat(target); err == nil && info.IsDir() {
+			return "", NewValidationErrorWithHint("map", m.String(),
+				fmt.Sprintf("%s is a directory, which link_as would replace", ContractPath(target)),
+				fmt.Sprintf("Merge into it with %s/ or :merge_into, or move it away first", strings.TrimSuffix(m.Target, "/")))
+		}
+		return MapLinkAs, nil
+	case !isDir(source):
+		return MapLinkAs, nil
+	}
+
+	// A directory without a mode: merge, unless the target says otherwise
+	if linked, err := fsys.EvalSymlinks(target); err == nil && linked == evalOrSelf(source) {
+		return "", NewValidationErrorWithHint("map", m.String(),
+			fmt.Sprintf("%s already links to %s as a whole, so merging into it is ambiguous",
+				ContractPath(target), ContractPath(source)),
+			"Add :link_as to keep it linked as a whole, or remove the link to merge its files")
+	}
+	if statErr == nil && !targetIsDir {
+		return "", NewValidationErrorWithHint("map", m.String(),
+			fmt.Sprintf("%s is a directory but %s is not", ContractPath(source), ContractPath(target)),
+			"Add :link_as to link the directory as the target, or end TGT with / to merge into a directory")
+	}
+	return MapMergeInto, nil
+}
+
+// evalOrSelf resolves symlinks in path, or returns it unchanged when it cannot
+func evalOrSelf(path string) string {
+	if resolved, err := fsys.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// linksWhole reports whether a resolved mapping links a directory as a whole
+func linksWhole(m Mapping) bool {
+	return m.Mode == MapLinkAs && isDir(m.Source)
+}
+
+// collectManagedMapLinks returns the links in the target of a resolved
+// mapping that point to their counterpart in its source
+func collectManagedMapLinks(m Mapping) ([]string, error) {
+	if !linksWhole(m) {
+		return collectManagedLinks(m.Source, m.Target)
+	}
+	info, err := fsys.Lstat(m.Target)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 || evalOrSelf(m.Target) != evalOrSelf(m.Source) {
+		return nil, nil
+	}
+	return []string{m.Target}, nil
+}
+
+// resolveMappingPath expands ~ to home and makes path absolute relative to base
+func resolveMappingPath(path, home, base string) (string, error) {
+	expanded, err := expandPathIn(home, path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(expanded) {
+		expanded = filepath.Join(base, expanded)
+	}
+	return filepath.Clean(expanded), nil
+}
+
+// mappingSources returns the source paths of maps
+func mappingSources(maps []Mapping) []string {
+	sources := make([]string, len(maps))
+	for i, m := range maps {
+		sources[i] = m.Source
+	}
+	return sources
+}
+
+// collectMappedLinks plans the links of each mapping. A mapping that targets
+// a path already planned (by a package or another mapping), or a directory
+// linked as a whole with planned paths inside it, is an error. Each mapping's
+// plan is returned too.
+func collectMappedLinks(maps []Mapping, ignorePatterns []string, planned []PlannedLink) ([]PlannedLink, []specialFile, []mappingPlan, error) {
+	taken := make(map[string]bool, len(planned))
+	for _, link := range planned {
+		taken[link.Target] = true
+	}
+	var whole []string
+	overlaps := func(target string) bool {
+		if taken[target] {
+			return true
+		}
+		for _, dir := range whole {
+			if strings.HasPrefix(target, dir+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var links []PlannedLink
+	var specials []specialFile
+	var plans []mappingPlan
+	for _, m := range maps {
+		var mapLinks []PlannedLink
+		var mapSpecials []specialFile
+		ignored := 0
+		if linksWhole(m) {
+			for target := range taken {
+				if strings.HasPrefix(target, m.Target+string(filepath.Separator)) {
+					return nil, nil, nil, NewValidationErrorWithHint("map", ContractPath(m.Source)+":"+ContractPath(m.Target),
+						fmt.Sprintf("%s is linked inside %s, which link_as would replace", ContractPath(target), ContractPath(m.Target)),
+						"Merge into the directory instead, or leave out the package that provides it")
+				}
+			}
+			mapLinks = []PlannedLink{{Source: m.Source, Target: m.Target, Groups: m.Groups}}
+		} else {
+			var err error
+			mapLinks, mapSpecials, ignored, err = collectPlannedLinksWithPatterns(m.Source, m.Target, ignorePatterns, sourceSymlinkPolicy{})
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			for i := range mapLinks {
+				mapLinks[i].Groups = m.Groups
+			}
+		}
+		for _, link := range mapLinks {
+			if overlaps(link.Target) {
+				return nil, nil, nil, NewValidationErrorWithHint("map", ContractPath(m.Source)+":"+ContractPath(m.Target),
+					fmt.Sprintf("%s is already linked by another package or mapping", ContractPath(link.Target)),
+					"Map to a different target, or leave out the package that provides it")
+			}
+			taken[link.Target] = true
+		}
+		if linksWhole(m) {
+			whole = append(whole, m.Target)
+		}
+		Trace("mapping", "source", ContractPath(m.Source), "target", ContractPath(m.Target), "mode", m.Mode,
+			"links", len(mapLinks), "special_files", len(mapSpecials), "ad_hoc", true)
+		plans = append(plans, mappingPlan{source: m.Source, target: m.Target, links: mapLinks, ignored: ignored})
+		links = append(links, mapLinks...)
+		specials = append(specials, mapSpecials...)
+	}
+	return links, specials, plans, nil
+}
@@ -0,0 +1,276 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Mapping
+		wantErr bool
+	}{
+		{spec: "projects/foo:.config/foo", want: Mapping{Source: "projects/foo", Target: ".config/foo"}},
+		{spec: "~/src/a:~/b:c", want: Mapping{Source: "~/src/a", Target: "~/b:c"}},
+		{spec: "nvim:.config/nvim:link_as", want: Mapping{Source: "nvim", Target: ".config/nvim", Mode: MapLinkAs}},
+		{spec: "nvim:.config/:merge_into", want: Mapping{Source: "nvim", Target: ".config/", Mode: MapMergeInto}},
+		{spec: "fonts:.local/share/fonts groups=gui,work", want: Mapping{Source: "fonts", Target: ".local/share/fonts", Groups: []string{"gui", "work"}}},
+		{spec: "nvim:.config/nvim:link_as groups=editor", want: Mapping{Source: "nvim", Target: ".config/nvim", Mode: MapLinkAs, Groups: []string{"editor"}}},
+		{spec: "fonts:.fonts groups=", wantErr: true},
+		{spec: "nvim::link_as", wantErr: true},
+		{spec: "projects/foo", wantErr: true},
+		{spec: ":.config/foo", wantErr: true},
+		{spec: "projects/foo:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseMapping(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMapping(%q) expected error", tt.spec)
+				}
+				if GetErrorHint(err) == "" {
+					t.Errorf("expected a hint, got none for %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMapping(%q) error = %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMapping(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMappings(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	external := filepath.Join(t.TempDir(), "foo")
+	createTestFile(t, filepath.Join(external, "config.toml"), "x = 1")
+
+	got, err := resolveMappings([]Mapping{
+		{Source: "shell", Target: ".config/shell"},
+		{Source: external, Target: filepath.Join(targetDir, "foo")},
+	}, "", sourceDir, targetDir)
+	if err != nil {
+		t.Fatalf("resolveMappings() error = %v", err)
+	}
+	want := []Mapping{
+		{Source: filepath.Join(sourceDir, "shell"), Target: filepath.Join(targetDir, ".config", "shell"), Mode: MapMergeInto},
+		{Source: external, Target: filepath.Join(targetDir, "foo"), Mode: MapMergeInto},
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("mapping %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	_, err = resolveMappings([]Mapping{{Source: "shel", Target: "x"}}, "", sourceDir, targetDir)
+	if err == nil {
+		t.Fatal("expected error for missing source")
+	}
+	if hint := GetErrorHint(err); !strings.Contains(hint, "shell") {
+		t.Errorf("hint = %q, want suggestion for shell", hint)
+	}
+}
+
+func TestMappingModes(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "extra", "gitignore"), "*.o")
+	createTestFile(t, filepath.Join(targetDir, ".profile"), "# file")
+	if err := os.Symlink(filepath.Join(sourceDir, "shell"), filepath.Join(targetDir, ".shell")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		mapping    Mapping
+		wantTarget string
+		wantMode   string
+		wantErr    string
+	}{
+		{"directory merges by default", Mapping{Source: "shell", Target: ".config/shell"}, ".config/shell", MapMergeInto, ""},
+		{"file links as target", Mapping{Source: "extra/gitignore", Target: ".gitignore"}, ".gitignore", MapLinkAs, ""},
+		{"file into directory with slash", Mapping{Source: "extra/gitignore", Target: ".config/"}, ".config/gitignore", MapMergeInto, ""},
+		{"file merge_into", Mapping{Source: "extra/gitignore", Target: ".config", Mode: MapMergeInto}, ".config/gitignore", MapMergeInto, ""},
+		{"directory link_as", Mapping{Source: "shell", Target: ".config/shell", Mode: MapLinkAs}, ".config/shell", MapLinkAs, ""},
+		{"directory already linked as a whole", Mapping{Source: "shell", Target: ".shell", Mode: MapLinkAs}, ".shell", MapLinkAs, ""},
+		{"slash with link_as", Mapping{Source: "shell", Target: ".config/shell/", Mode: MapLinkAs}, "", "", "trailing slash"},
+		{"link_as over a directory", Mapping{Source: "shell", Target: ".config", Mode: MapLinkAs}, "", "", "link_as would replace"},
+		{"merge into a file", Mapping{Source: "shell", Target: ".profile/"}, "", "", "not a directory to merge into"},
+		{"directory onto a file", Mapping{Source: "shell", Target: ".profile"}, "", "", "is not"},
+		{"directory onto its own link", Mapping{Source: "shell", Target: ".shell"}, "", "", "ambiguous"},
+	}
+	if err := os.MkdirAll(filepath.Join(targetDir, ".config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveMappings([]Mapping{tt.mapping}, "", sourceDir, targetDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveMappings(%s) error = %v, want %q", tt.mapping, err, tt.wantErr)
+				}
+				if GetErrorHint(err) == "" {
+					t.Errorf("expected a hint, got none for %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveMappings(%s) error = %v", tt.mapping, err)
+			}
+			if want := filepath.Join(targetDir, tt.wantTarget); got[0].Target != want || got[0].Mode != tt.wantMode {
+				t.Errorf("resolveMappings(%s) = %s %s, want %s %s", tt.mapping, got[0].Target, got[0].Mode, want, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestCreateLinksWithMapping(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	external := filepath.Join(t.TempDir(), "foo")
+	createTestFile(t, filepath.Join(external, "config.toml"), "x = 1")
+	createTestFile(t, filepath.Join(external, "themes", "dark.toml"), "bg = 0")
+	single := filepath.Join(t.TempDir(), "gitignore")
+	createTestFile(t, single, "*.o")
+
+	opts := LinkOptions{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Packages:  []string{"shell"},
+		Maps: []Mapping{
+			{Source: external, Target: ".config/foo"},
+			{Source: single, Target: ".gitignore"},
+		},
+	}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	assertSymlink(t, filepath.Join(targetDir, ".config", "foo", "config.toml"), filepath.Join(external, "config.toml"))
+	assertSymlink(t, filepath.Join(targetDir, ".config", "foo", "themes", "dark.toml"),
+		filepath.Join(external, "themes", "dark.toml"))
+	assertSymlink(t, filepath.Join(targetDir, ".gitignore"), single)
+	assertNotExists(t, filepath.Join(targetDir, ".gitconfig"))
+}
+
+func TestCreateLinksMappingDuplicateTarget(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	external := filepath.Join(t.TempDir(), "bashrc")
+	createTestFile(t, external, "# other")
+
+	opts := LinkOptions{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Packages:  []string{"shell"},
+		Maps:      []Mapping{{Source: external, Target: ".bashrc"}},
+	}
+	var err error
+	CaptureOutput(t, func() {
+		_, err = CreateLinks(opts)
+	})
+	if err == nil {
+		t.Fatal("expected error for duplicate target")
+	}
+	if !strings.Contains(err.Error(), "already linked") {
+		t.Errorf("error = %v, want duplicate target message", err)
+	}
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+}
+
+func TestStatusAndRemoveWithMapping(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	external := filepath.Join(t.TempDir(), "foo")
+	createTestFile(t, filepath.Join(external, "config.toml"), "x = 1")
+	createTestFile(t, filepath.Join(external, "extra.toml"), "y = 2")
+
+	maps := []Mapping{{Source: external, Target: ".config/foo"}}
+	linked := filepath.Join(targetDir, ".config", "foo", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(linked), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(external, "config.toml"), linked); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}, Maps: maps}
+	output := CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+	})
+	if !strings.Contains(output, "active "+linked) {
+		t.Errorf("expected mapped link to be active, got:\n%s", output)
+	}
+	if !strings.Contains(output, "unlinked "+filepath.Join(external, "extra.toml")) {
+		t.Errorf("expected unlinked mapped file, got:\n%s", output)
+	}
+
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertNotExists(t, linked)
+	if _, err := os.Stat(filepath.Join(external, "config.toml")); err != nil {
+		t.Errorf("expected mapped source to remain: %v", err)
+	}
+}
+
+func TestMappingLinkAs(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	external := filepath.Join(t.TempDir(), "nvim")
+	createTestFile(t, filepath.Join(external, "init.lua"), "-- nvim")
+
+	target := filepath.Join(targetDir, ".config", "nvim")
+	opts := LinkOptions{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Packages:  []string{"shell"},
+		Maps:      []Mapping{{Source: external, Target: ".config/nvim", Mode: MapLinkAs}},
+	}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	assertSymlink(t, target, external)
+
+	output := CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+	})
+	if strings.Contains(output, "unlinked "+external) {
+		t.Errorf("expected linked directory not to be unlinked, got:\n%s", output)
+	}
+
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertNotExists(t, target)
+	if _, err := os.Stat(filepath.Join(external, "init.lua")); err != nil {
+		t.Errorf("expected mapped source to remain: %v", err)
+	}
+
+	// A package file inside the directory would be replaced by the link
+	opts.Packages = []string{"nvim"}
+	var err error
+	CaptureOutput(t, func() {
+		_, err = CreateLinks(opts)
+	})
+	if err == nil || !strings.Contains(err.Error(), "link_as would replace") {
+		t.Fatalf("CreateLinks() error = %v, want package linked inside a link_as target", err)
+	}
+	assertNotExists(t, target)
+}
@@ -0,0 +1,381 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// memFS is an in-memory FileSystem for tests, so the linker, adopter, and
+// scanner can run without t.TempDir or HOME. Paths are absolute and use "/".
+type memFS struct {
+	nodes map[string]*memNode
+	temps int
+}
+
+type memNode struct {
+	mode    fs.FileMode // type bits and permissions
+	data    []byte
+	target  string // symlink destination
+	modTime time.Time
+}
+
+func newMemFS() *memFS {
+	return &memFS{nodes: map[string]*memNode{"/": {mode: fs.ModeDir | 0755}}}
+}
+
+// useMemFS makes lnk use a new, empty in-memory file system until the test ends
+func useMemFS(t *testing.T) *memFS {
+	t.Helper()
+	m := newMemFS()
+	old := fsys
+	fsys = m
+	t.Cleanup(func() { fsys = old })
+	return m
+}
+
+// writeFile creates a file and its parent directories
+func (m *memFS) writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := m.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll(%s) error = %v", filepath.Dir(path), err)
+	}
+	m.nodes[filepath.Clean(path)] = &memNode{mode: 0644, data: []byte(content), modTime: time.Now()}
+}
+
+// resolve returns the path name refers to with symlinks in its directories
+// resolved, and in its last element too when followLast is set. The result
+// need not exist, but its parent does.
+func (m *memFS) resolve(name string, followLast bool) (string, error) {
+	parts := strings.Split(filepath.Clean(name), "/")
+	resolved, hops := "/", 0
+	for i := 0; i < len(parts); i++ {
+		if parts[i] == "" {
+			continue
+		}
+		last := i == len(parts)-1
+		next := filepath.Join(resolved, parts[i])
+		n, ok := m.nodes[next]
+		switch {
+		case ok && n.mode&fs.ModeSymlink != 0 && (!last || followLast):
+			if hops++; hops > 40 {
+				return "", syscall.ELOOP
+			}
+			target := n.target
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(resolved, target)
+			}
+			parts = append(strings.Split(filepath.Clean(target), "/"), parts[i+1:]...)
+			resolved, i = "/", -1
+			continue
+		case !ok && !last:
+			return "", fs.ErrNotExist
+		case ok && !last && !n.mode.IsDir():
+			return "", syscall.ENOTDIR
+		}
+		resolved = next
+	}
+	return resolved, nil
+}
+
+// node returns the node at name and its resolved path
+func (m *memFS) node(op, name string, follow bool) (string, *memNode, error) {
+	path, err := m.resolve(name, follow)
+	if err != nil {
+		return "", nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	n, ok := m.nodes[path]
+	if !ok {
+		return path, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return path, n, nil
+}
+
+// parent returns where a new entry called name goes, requiring its parent to
+// be an existing directory
+func (m *memFS) parent(op, name string) (string, error) {
+	dir, n, err := m.node(op, filepath.Dir(name), true)
+	if err != nil {
+		return "", err
+	}
+	if !n.mode.IsDir() {
+		return "", &fs.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+	}
+	return filepath.Join(dir, filepath.Base(name)), nil
+}
+
+// children returns the paths directly inside dir, sorted
+func (m *memFS) children(dir string) []string {
+	var paths []string
+	for path := range m.nodes {
+		if path != "/" && filepath.Dir(path) == dir {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (m *memFS) info(path string, n *memNode) fs.FileInfo {
+	return memInfo{name: filepath.Base(path), node: n}
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	path, n, err := m.node("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return m.info(path, n), nil
+}
+
+func (m *memFS) Lstat(name string) (fs.FileInfo, error) {
+	path, n, err := m.node("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return m.info(path, n), nil
+}
+
+func (m *memFS) Readlink(name string) (string, error) {
+	_, n, err := m.node("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if n.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+	return n.target, nil
+}
+
+func (m *memFS) EvalSymlinks(path string) (string, error) {
+	resolved, _, err := m.node("lstat", path, true)
+	return resolved, err
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	dir, n, err := m.node("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdirent", Path: name, Err: syscall.ENOTDIR}
+	}
+	var entries []fs.DirEntry
+	for _, path := range m.children(dir) {
+		entries = append(entries, fs.FileInfoToDirEntry(m.info(path, m.nodes[path])))
+	}
+	return entries, nil
+}
+
+func (m *memFS) ReadFile(name string) ([]byte, error) {
+	_, n, err := m.node("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: syscall.EISDIR}
+	}
+	return bytes.Clone(n.data), nil
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	path, n, err := m.node("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &memReader{Reader: bytes.NewReader(n.data), info: m.info(path, n)}, nil
+}
+
+func (m *memFS) MkdirAll(path string, perm fs.FileMode) error {
+	if info, err := m.Stat(path); err == nil {
+		if info.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: path, Err: syscall.ENOTDIR}
+	}
+	if err := m.MkdirAll(filepath.Dir(path), perm); err != nil {
+		return err
+	}
+	dir, err := m.parent("mkdir", path)
+	if err != nil {
+		return err
+	}
+	m.nodes[dir] = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+func (m *memFS) Symlink(oldname, newname string) error {
+	path, err := m.parent("symlink", newname)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.nodes[path]; ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+	m.nodes[path] = &memNode{mode: fs.ModeSymlink | 0777, target: oldname, modTime: time.Now()}
+	return nil
+}
+
+func (m *memFS) Remove(name string) error {
+	path, n, err := m.node("remove", name, false)
+	if err != nil {
+		return err
+	}
+	if n.mode.IsDir() && len(m.children(path)) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	delete(m.nodes, path)
+	return nil
+}
+
+func (m *memFS) RemoveAll(name string) error {
+	path, _, err := m.node("remove", name, false)
+	if err != nil {
+		return nil
+	}
+	for p := range m.nodes {
+		if p == path || strings.HasPrefix(p, path+"/") {
+			delete(m.nodes, p)
+		}
+	}
+	return nil
+}
+
+func (m *memFS) Rename(oldpath, newpath string) error {
+	from, _, err := m.node("rename", oldpath, false)
+	if err != nil {
+		return err
+	}
+	to, err := m.parent("rename", newpath)
+	if err != nil {
+		return err
+	}
+	if n, ok := m.nodes[to]; ok && n.mode.IsDir() && len(m.children(to)) > 0 {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.ENOTEMPTY}
+	}
+	moved := map[string]*memNode{}
+	for p, n := range m.nodes {
+		if p == from || strings.HasPrefix(p, from+"/") {
+			moved[to+strings.TrimPrefix(p, from)] = n
+			delete(m.nodes, p)
+		}
+	}
+	for p, n := range moved {
+		m.nodes[p] = n
+	}
+	return nil
+}
+
+func (m *memFS) Chmod(name string, mode fs.FileMode) error {
+	_, n, err := m.node("chmod", name, true)
+	if err != nil {
+		return err
+	}
+	n.mode = n.mode.Type() | mode.Perm()
+	return nil
+}
+
+// Lchown only checks that name exists; memFS does not track owners
+func (m *memFS) Lchown(name string, _, _ int) error {
+	_, _, err := m.node("lchown", name, false)
+	return err
+}
+
+func (m *memFS) Create(name string) (WritableFile, error) {
+	return m.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (m *memFS) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+	path, err := m.resolve(name, true)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	n, ok := m.nodes[path]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case ok && n.mode.IsDir():
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		if path, err = m.parent("open", name); err != nil {
+			return nil, err
+		}
+		n = &memNode{mode: perm.Perm()}
+		m.nodes[path] = n
+	}
+	if flag&os.O_TRUNC != 0 {
+		n.data = nil
+	}
+	n.modTime = time.Now()
+	return &memWriter{name: name, node: n}, nil
+}
+
+func (m *memFS) CreateTemp(dir, pattern string) (WritableFile, error) {
+	m.temps++
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	name := filepath.Join(dir, fmt.Sprintf("%s%d%s", prefix, m.temps, suffix))
+	return m.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+}
+
+func (m *memFS) MkdirTemp(dir, pattern string) (string, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	m.temps++
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	name := filepath.Join(dir, fmt.Sprintf("%s%d%s", prefix, m.temps, suffix))
+	if _, err := m.Lstat(name); err == nil {
+		return "", &fs.PathError{Op: "mkdirtemp", Path: name, Err: fs.ErrExist}
+	}
+	return name, m.MkdirAll(name, 0700)
+}
+
+// memInfo describes a memNode
+type memInfo struct {
+	name string
+	node *memNode
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return int64(len(i.node.data)) }
+func (i memInfo) Mode() fs.FileMode  { return i.node.mode }
+func (i memInfo) ModTime() time.Time { return i.node.modTime }
+func (i memInfo) IsDir() bool        { return i.node.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
+
+// memReader is a memNode opened for reading
+type memReader struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (r *memReader) Stat() (fs.FileInfo, error) { return r.info, nil }
+func (r *memReader) Close() error               { return nil }
+
+// memWriter is a memNode opened for writing; writes append
+type memWriter struct {
+	name string
+	node *memNode
+}
+
+func (w *memWriter) Write(p []byte) (int, error) {
+	w.node.data = append(w.node.data, p...)
+	return len(p), nil
+}
+
+func (w *memWriter) Name() string { return w.name }
+func (w *memWriter) Sync() error  { return nil }
+func (w *memWriter) Chmod(mode fs.FileMode) error {
+	w.node.mode = w.node.mode.Type() | mode.Perm()
+	return nil
+}
+func (w *memWriter) Close() error { return nil }
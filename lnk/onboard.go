@@ -0,0 +1,441 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// First-run onboarding: bare 'lnk' at a terminal, on a machine where lnk has
+// never linked anything and onboarding has not run, walks the user through
+// getting a source directory (cloning one, importing from GNU Stow or
+// chezmoi, or starting an empty one) and ends with a dry-run preview of
+// 'lnk create'. Settings still live in the source directory; the user config
+// directory only records which source directory onboarding set up, so it is
+// not offered again.
+
+// Onboarding choices, in the order they are offered
+const (
+	onboardClone   = "1"
+	onboardStow    = "2"
+	onboardChezmoi = "3"
+	onboardNew     = "4"
+	onboardSkip    = "5"
+)
+
+// defaultSourceDir is the source directory onboarding suggests
+const defaultSourceDir = "~/dotfiles"
+
+// cloneRepo clones url into dir with git, showing git's progress; tests replace it
+var cloneRepo = func(url, dir string) error {
+	if err := checkWritable("clone into", dir); err != nil {
+		return err
+	}
+	cmd := exec.Command("git", "clone", url, dir)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	return cmd.Run()
+}
+
+// UserConfigDir returns lnk's user configuration directory: $LNK_CONFIG,
+// lnk's directory under $XDG_CONFIG_HOME, or ~/.config/lnk when neither is
+// set to an absolute path
+func UserConfigDir() string {
+	if dir := os.Getenv(EnvConfig); dir != "" {
+		if path, err := ExpandPath(dir); err == nil && filepath.IsAbs(path) {
+			return filepath.Clean(path)
+		}
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" && filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "lnk")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "lnk")
+}
+
+// OnboardingRecordPath returns the file recording the source directory
+// onboarding set up (empty when it was skipped)
+func OnboardingRecordPath() string {
+	return filepath.Join(UserConfigDir(), OnboardingFileName)
+}
+
+// NeedsOnboarding reports whether bare 'lnk' should start onboarding: the user
+// is at a terminal, onboarding has not run, and nothing is linked into the
+// home directory yet
+func NeedsOnboarding() bool {
+	if !canPrompt() || IsReadOnly() || UserConfigDir() == "" {
+		return false
+	}
+	if _, err := fsys.Stat(OnboardingRecordPath()); err == nil {
+		return false
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	m, err := LoadManifest(home)
+	return err == nil && len(m.Links) == 0 && len(m.Copies) == 0 && len(m.Tracked) == 0
+}
+
+// Onboard runs first-run onboarding. It reports false, with no error, when
+// the user skips it or input ends, so the caller can show the usage instead.
+func Onboard() (bool, error) {
+	PrintCommandHeader("Welcome to lnk")
+	fmt.Fprintln(os.Stderr, "Nothing is linked on this machine yet. How would you like to start?")
+	fmt.Fprintln(os.Stderr, "  1) Clone an existing dotfiles repository")
+	fmt.Fprintln(os.Stderr, "  2) Import from GNU Stow")
+	fmt.Fprintln(os.Stderr, "  3) Import from chezmoi")
+	fmt.Fprintln(os.Stderr, "  4) Start a new dotfiles directory")
+	fmt.Fprintln(os.Stderr, "  5) Skip, and don't ask again")
+
+	var choice string
+	for {
+		answer, err := readChoice("Choice [1-5]:")
+		if err != nil {
+			return false, nil
+		}
+		if answer >= onboardClone && answer <= onboardSkip && len(answer) == 1 {
+			choice = answer
+			break
+		}
+		fmt.Fprintln(os.Stderr, "Enter a number from 1 to 5.")
+	}
+	if choice == onboardSkip {
+		if err := recordOnboarding(""); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	sourceDir, err := onboardSource(choice)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return true, err
+	}
+	if err := recordOnboarding(sourceDir); err != nil {
+		return true, err
+	}
+	if choice == onboardNew {
+		fmt.Println()
+		PrintInfo("Next: Run 'lnk adopt %s ~/.bashrc' to move your first dotfile into it", ContractPath(sourceDir))
+		return true, nil
+	}
+	if needsIdentity(sourceDir) {
+		// Its private configuration cannot be decrypted here yet
+		fmt.Println()
+		PrintNextStep("identity init", sourceDir, "make this machine's key and register it")
+		return true, nil
+	}
+
+	// Show what 'lnk create' would do, without doing it
+	fmt.Println()
+	config, err := LoadConfig(sourceDir, nil)
+	if err != nil {
+		return true, err
+	}
+	if err := config.LoadPrivate(); err != nil {
+		return true, err
+	}
+	packages, _ := config.ResolvePackages(nil)
+	_, err = CreateLinks(LinkOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		IgnorePatterns: config.IgnorePatterns,
+		Packages:       packages,
+		LocalOnly:      config.LocalOnly,
+		Dirs:           config.Dirs,
+		DryRun:         true,
+	})
+	if err != nil {
+		return true, err
+	}
+	PrintNextStep("create", sourceDir, "create these links")
+	return true, nil
+}
+
+// onboardSource gets the source directory for choice ready and returns it
+func onboardSource(choice string) (string, error) {
+	switch choice {
+	case onboardClone:
+		url, err := readLine("Repository URL:")
+		if err != nil {
+			return "", err
+		}
+		if url == "" {
+			return "", NewValidationErrorWithHint("repository", "", "no URL given",
+				"Run 'lnk' again and enter the URL you would pass to git clone")
+		}
+		dir, err := askDir("Clone into", defaultSourceDir)
+		if err != nil {
+			return "", err
+		}
+		if err := requireEmptyDir(dir); err != nil {
+			return "", err
+		}
+		PrintInfo("Cloning %s into %s", url, ContractPath(dir))
+		if err := cloneRepo(url, dir); err != nil {
+			return "", NewPathErrorWithHint("clone", dir, err,
+				"Check the URL and your access to the repository")
+		}
+		return dir, nil
+	case onboardStow:
+		dir, err := askDir("Stow directory", defaultSourceDir)
+		if err != nil {
+			return "", err
+		}
+		return dir, importStow(dir)
+	case onboardChezmoi:
+		from, err := askDir("chezmoi source directory", "~/.local/share/chezmoi")
+		if err != nil {
+			return "", err
+		}
+		dir, err := askDir("New dotfiles directory", defaultSourceDir)
+		if err != nil {
+			return "", err
+		}
+		return dir, importChezmoi(from, dir)
+	default:
+		dir, err := askDir("New dotfiles directory", defaultSourceDir)
+		if err != nil {
+			return "", err
+		}
+		if err := requireEmptyDir(dir); err != nil {
+			return "", err
+		}
+		if err := fsys.MkdirAll(dir, 0755); err != nil {
+			return "", NewPathError("create directory", dir, err)
+		}
+		if _, err := exec.LookPath("git"); err == nil {
+			if out, err := gitCombinedOutput(dir, "init", "-q"); err != nil {
+				PrintWarning("git init failed: %s", strings.TrimSpace(out))
+			}
+		}
+		PrintSuccess("Created %s", ContractPath(dir))
+		return dir, nil
+	}
+}
+
+// askDir asks for a directory, offering def, and returns it expanded
+func askDir(question, def string) (string, error) {
+	answer, err := readLine(fmt.Sprintf("%s [%s]:", question, def))
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		answer = def
+	}
+	return ExpandPath(answer)
+}
+
+// requireEmptyDir fails unless dir is missing or an empty directory
+func requireEmptyDir(dir string) error {
+	entries, err := fsys.ReadDir(dir)
+	if err == nil && len(entries) > 0 {
+		return NewPathErrorWithHint("set up", dir, errors.New("directory is not empty"),
+			"Choose a new directory, or run 'lnk create' on it if it already holds your dotfiles")
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return NewPathError("set up", dir, err)
+	}
+	return nil
+}
+
+// recordOnboarding writes the source directory onboarding set up
+func recordOnboarding(sourceDir string) error {
+	path := OnboardingRecordPath()
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return NewPathError("create config directory", filepath.Dir(path), err)
+	}
+	var data []byte
+	if sourceDir != "" {
+		data = []byte(sourceDir + "\n")
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return NewPathError("write", path, err)
+	}
+	PrintVerbose("Recorded onboarding in %s", ContractPath(path))
+	return nil
+}
+
+// importStow adopts a GNU Stow directory as it is: its packages already have
+// lnk's layout, so it only needs a .lnkpackages listing them. Links stow made
+// point at the same files, so create treats them as already linked.
+func importStow(dir string) error {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return NewPathErrorWithHint("import", dir, err, "Enter the directory you run stow in")
+	}
+	var packages []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			packages = append(packages, e.Name())
+		}
+	}
+	if len(packages) == 0 {
+		return NewPathErrorWithHint("import", dir, errors.New("no stow packages found"),
+			"Enter the directory that holds your stow packages")
+	}
+	sort.Strings(packages)
+
+	packagesFile := filepath.Join(dir, PackagesFileName)
+	if _, err := fsys.Stat(packagesFile); err == nil {
+		PrintSkip("Keeping existing %s", ContractPath(packagesFile))
+	} else {
+		data := "# Imported from GNU Stow\n" + strings.Join(packages, "\n") + "\n"
+		if err := writeFileAtomic(packagesFile, []byte(data), 0644); err != nil {
+			return NewPathError("write", packagesFile, err)
+		}
+		PrintSuccess("Wrote %s with %d package(s): %s", ContractPath(packagesFile), len(packages), strings.Join(packages, ", "))
+	}
+	if _, err := fsys.Stat(filepath.Join(dir, ".stow-local-ignore")); err == nil {
+		PrintWarningWithHint(WithHint(errors.New(".stow-local-ignore was not converted"),
+			fmt.Sprintf("Stow uses Perl regular expressions; add the same patterns to %s in gitignore syntax", IgnoreFileName)))
+	}
+	PrintDetail("Directories stow folded into one link show up as conflicts; run 'stow -D' for those packages first")
+	return nil
+}
+
+// chezmoiUnsupported lists chezmoi source prefixes lnk has no equivalent for
+var chezmoiUnsupported = []string{"create_", "encrypted_", "external_", "modify_", "remove_", "run_", "symlink_"}
+
+// chezmoiTarget translates a chezmoi source name to the name it manages,
+// returning why it cannot be imported when lnk has no equivalent
+func chezmoiTarget(name string) (target string, private, executable bool, reason string) {
+	if strings.HasSuffix(name, ".tmpl") {
+		return "", false, false, "templates are not supported"
+	}
+	for {
+		for _, prefix := range chezmoiUnsupported {
+			if strings.HasPrefix(name, prefix) {
+				return "", false, false, fmt.Sprintf("%s entries are not supported", strings.TrimSuffix(prefix, "_"))
+			}
+		}
+		switch {
+		case strings.HasPrefix(name, "private_"):
+			name, private = strings.TrimPrefix(name, "private_"), true
+			continue
+		case strings.HasPrefix(name, "executable_"):
+			name, executable = strings.TrimPrefix(name, "executable_"), true
+			continue
+		case strings.HasPrefix(name, "readonly_"), strings.HasPrefix(name, "empty_"), strings.HasPrefix(name, "exact_"):
+			name = name[strings.Index(name, "_")+1:]
+			continue
+		}
+		break
+	}
+	if rest, ok := strings.CutPrefix(name, "literal_"); ok {
+		return strings.TrimSuffix(rest, ".literal"), private, executable, ""
+	}
+	if rest, ok := strings.CutPrefix(name, "dot_"); ok {
+		name = "." + rest
+	}
+	return strings.TrimSuffix(name, ".literal"), private, executable, ""
+}
+
+// importChezmoi copies a chezmoi source directory into a new source directory
+// dir, translating chezmoi's name prefixes into names and modes. Entries lnk
+// cannot express (templates, scripts, encrypted files, ...) are skipped with
+// a warning each.
+func importChezmoi(from, dir string) error {
+	if info, err := fsys.Stat(from); err != nil || !info.IsDir() {
+		return NewPathErrorWithHint("import", from, errors.New("not a chezmoi source directory"),
+			"Run 'chezmoi source-path' to find it")
+	}
+	if err := requireEmptyDir(dir); err != nil {
+		return err
+	}
+
+	type imported struct {
+		src, dst         string
+		private, execute bool
+	}
+	var files []imported
+	var skipped int
+	var privateDirs []string
+	targets := map[string]string{from: dir}
+	err := walkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == from {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			// .chezmoiignore, .chezmoiscripts, .git, and other source-only files
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name, private, executable, reason := chezmoiTarget(d.Name())
+		if reason != "" {
+			PrintWarning("Skipped %s: %s", ContractPath(path), reason)
+			skipped++
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dst := filepath.Join(targets[filepath.Dir(path)], name)
+		if d.IsDir() {
+			targets[path] = dst
+			if private {
+				privateDirs = append(privateDirs, dst)
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, imported{src: path, dst: dst, private: private, execute: executable})
+		}
+		return nil
+	})
+	if err != nil {
+		return NewPathError("import", from, err)
+	}
+	if len(files) == 0 {
+		return NewPathErrorWithHint("import", from, errors.New("no files to import"),
+			"Check that this is your chezmoi source directory")
+	}
+
+	lines := newPathLines(len(files), "Imported", PrintSuccess)
+	for _, f := range files {
+		mode := fs.FileMode(0644)
+		if f.execute {
+			mode = 0755
+		}
+		if f.private {
+			mode &^= 0077
+		}
+		if err := fsys.MkdirAll(filepath.Dir(f.dst), 0755); err != nil {
+			return NewPathError("create directory", filepath.Dir(f.dst), err)
+		}
+		if err := copyFile(f.src, f.dst); err != nil {
+			return NewPathError("import", f.src, err)
+		}
+		if err := fsys.Chmod(f.dst, mode); err != nil {
+			return NewPathError("import", f.dst, err)
+		}
+		lines.Add(f.dst, "Imported: %s", ContractPath(f.dst))
+	}
+	lines.Flush()
+	for _, d := range privateDirs {
+		if _, err := fsys.Stat(d); err == nil {
+			if err := fsys.Chmod(d, 0700); err != nil {
+				return NewPathError("import", d, err)
+			}
+		}
+	}
+	PrintSuccess("Imported %d file(s) from %s", len(files), ContractPath(from))
+	if skipped > 0 {
+		PrintDetail("%d item(s) skipped; manage them by hand or keep them in chezmoi", skipped)
+	}
+	PrintDetail("chezmoi wrote real files into your home; create offers to replace identical ones with links")
+	return nil
+}
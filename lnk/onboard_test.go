@@ -0,0 +1,170 @@
+package lnk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeFirstRun gives the test a fresh home and config directory and a user at
+// a terminal who types input
+func fakeFirstRun(t *testing.T, input string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv(EnvConfig, "")
+	origCanPrompt, origReader := canPrompt, promptReader
+	canPrompt = func() bool { return true }
+	promptReader = bufio.NewReader(strings.NewReader(input))
+	t.Cleanup(func() { canPrompt, promptReader = origCanPrompt, origReader })
+	return home
+}
+
+func TestNeedsOnboarding(t *testing.T) {
+	home := fakeFirstRun(t, "")
+	if !NeedsOnboarding() {
+		t.Error("NeedsOnboarding() = false on a fresh machine")
+	}
+
+	m := &Manifest{Version: manifestVersion}
+	m.AddLink(filepath.Join(home, ".bashrc"), filepath.Join(home, "dotfiles"))
+	if err := m.Save(home); err != nil {
+		t.Fatal(err)
+	}
+	if NeedsOnboarding() {
+		t.Error("NeedsOnboarding() = true with links in the manifest")
+	}
+}
+
+func TestUserConfigDir(t *testing.T) {
+	home := fakeFirstRun(t, "")
+	if got := UserConfigDir(); got != filepath.Join(home, ".config", "lnk") {
+		t.Errorf("UserConfigDir() = %s, want ~/.config/lnk", got)
+	}
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg"))
+	if got := UserConfigDir(); got != filepath.Join(home, "xdg", "lnk") {
+		t.Errorf("UserConfigDir() = %s, want lnk under $XDG_CONFIG_HOME", got)
+	}
+	t.Setenv(EnvConfig, "~/lnk-config/")
+	if got := UserConfigDir(); got != filepath.Join(home, "lnk-config") {
+		t.Errorf("UserConfigDir() = %s, want %s", got, EnvConfig)
+	}
+	if got := AgeIdentityPath(); got != filepath.Join(home, "lnk-config", "identity.age") {
+		t.Errorf("AgeIdentityPath() = %s, want it in %s", got, EnvConfig)
+	}
+}
+
+func TestOnboardSkip(t *testing.T) {
+	fakeFirstRun(t, "9\n5\n")
+	var onboarded bool
+	_, stderr := captureOutput(t, func() {
+		var err error
+		if onboarded, err = Onboard(); err != nil {
+			t.Fatalf("Onboard() error = %v", err)
+		}
+	})
+	if onboarded || !strings.Contains(stderr, "Enter a number from 1 to 5") {
+		t.Errorf("Onboard() = %v, stderr:\n%s", onboarded, stderr)
+	}
+	if NeedsOnboarding() {
+		t.Error("skipping should not ask again")
+	}
+}
+
+func TestOnboardClone(t *testing.T) {
+	home := fakeFirstRun(t, "1\nhttps://example.com/dotfiles.git\n\n")
+	orig := cloneRepo
+	cloneRepo = func(url, dir string) error {
+		createTestFile(t, filepath.Join(dir, "shell", ".bashrc"), "# bashrc")
+		createTestFile(t, filepath.Join(dir, PackagesFileName), "shell\n")
+		return nil
+	}
+	t.Cleanup(func() { cloneRepo = orig })
+
+	out := CaptureOutput(t, func() {
+		if onboarded, err := Onboard(); err != nil || !onboarded {
+			t.Fatalf("Onboard() = %v, %v", onboarded, err)
+		}
+	})
+	ContainsOutput(t, out, "Would create", ".bashrc", "lnk create ~/dotfiles")
+	assertNotExists(t, filepath.Join(home, ".bashrc"))
+	if data, _ := os.ReadFile(OnboardingRecordPath()); string(data) != filepath.Join(home, "dotfiles")+"\n" {
+		t.Errorf("onboarding record = %q", data)
+	}
+}
+
+func TestImportStow(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, filepath.Join(dir, "zsh", ".zshrc"), "")
+	createTestFile(t, filepath.Join(dir, "git", ".gitconfig"), "")
+	createTestFile(t, filepath.Join(dir, ".stow-local-ignore"), "README.*")
+
+	CaptureOutput(t, func() {
+		if err := importStow(dir); err != nil {
+			t.Fatalf("importStow() error = %v", err)
+		}
+	})
+	data, _ := os.ReadFile(filepath.Join(dir, PackagesFileName))
+	if !strings.HasSuffix(string(data), "git\nzsh\n") {
+		t.Errorf("%s = %q, want the stow packages", PackagesFileName, data)
+	}
+}
+
+func TestImportChezmoi(t *testing.T) {
+	from := t.TempDir()
+	createTestFile(t, filepath.Join(from, "dot_bashrc"), "# bashrc")
+	createTestFile(t, filepath.Join(from, "private_dot_ssh", "private_config"), "Host *")
+	createTestFile(t, filepath.Join(from, "dot_local", "bin", "executable_hello"), "#!/bin/sh")
+	createTestFile(t, filepath.Join(from, "dot_gitconfig.tmpl"), "{{ .email }}")
+	createTestFile(t, filepath.Join(from, "run_once_install.sh"), "")
+	createTestFile(t, filepath.Join(from, ".chezmoiignore"), "")
+	dir := filepath.Join(t.TempDir(), "dotfiles")
+
+	CaptureOutput(t, func() {
+		if err := importChezmoi(from, dir); err != nil {
+			t.Fatalf("importChezmoi() error = %v", err)
+		}
+	})
+	modes := map[string]os.FileMode{
+		".bashrc":                               0644,
+		filepath.Join(".ssh", "config"):         0600,
+		filepath.Join(".ssh"):                   0700,
+		filepath.Join(".local", "bin", "hello"): 0755,
+	}
+	for rel, want := range modes {
+		info, err := os.Stat(filepath.Join(dir, rel))
+		if err != nil || info.Mode().Perm() != want {
+			t.Errorf("%s: mode %v, %v; want %v", rel, info.Mode().Perm(), err, want)
+		}
+	}
+	for _, rel := range []string{".gitconfig", "dot_gitconfig.tmpl", "install.sh", ".chezmoiignore"} {
+		assertNotExists(t, filepath.Join(dir, rel))
+	}
+}
+
+func TestChezmoiTarget(t *testing.T) {
+	tests := []struct {
+		name, target string
+		private      bool
+		executable   bool
+		unsupported  bool
+	}{
+		{"dot_zshrc", ".zshrc", false, false, false},
+		{"private_executable_dot_script", ".script", true, true, false},
+		{"readonly_dot_vimrc", ".vimrc", false, false, false},
+		{"literal_dot_keep", "dot_keep", false, false, false},
+		{"encrypted_private_dot_netrc", "", false, false, true},
+		{"symlink_dot_link", "", false, false, true},
+		{"dot_profile.tmpl", "", false, false, true},
+	}
+	for _, tt := range tests {
+		target, private, executable, reason := chezmoiTarget(tt.name)
+		if target != tt.target || private != tt.private || executable != tt.executable || (reason != "") != tt.unsupported {
+			t.Errorf("chezmoiTarget(%s) = %q, %v, %v, %q", tt.name, target, private, executable, reason)
+		}
+	}
+}
@@ -12,10 +12,14 @@ type OrphanOptions struct {
 	SourceDir string   // base directory for dotfiles (e.g., ~/git/dotfiles)
 	TargetDir string   // where symlinks are (default: ~)
 	Paths     []string // symlink paths to orphan (e.g., ["~/.bashrc", "~/.vimrc"])
-	DryRun    bool     // preview mode
+	// ToCopy replaces each link with a copy of its source and records it as
+	// copy-managed, leaving the repository untouched (--to-copy)
+	ToCopy bool
+	DryRun bool // preview mode
 }
 
-// Orphan removes files from package management using two-phase transactional execution.
+// Orphan removes files from package management using two-phase transactional
+// execution. With ToCopy, links are replaced by copies that stay managed.
 func Orphan(opts OrphanOptions) error {
 	PrintCommandHeader("Orphaning Files")
 
@@ -38,6 +42,16 @@ func Orphan(opts OrphanOptions) error {
 	var managedLinks []ManagedLink
 	seen := make(map[string]bool)
 
+	// managedPaths lists the links this source manages, for suggesting the
+	// intended path when the given one is not managed
+	managedPaths := func() []string {
+		links, err := collectManagedLinks(absSourceDir, absTargetDir)
+		if err != nil {
+			PrintVerbose("Failed to collect managed links: %v", err)
+		}
+		return links
+	}
+
 	for _, path := range opts.Paths {
 		absPath, err := ExpandPath(path)
 		if err != nil {
@@ -51,7 +65,7 @@ func Orphan(opts OrphanOptions) error {
 		if err != nil {
 			if os.IsNotExist(err) {
 				return NewPathErrorWithHint("orphan", absPath, err,
-					"Check that the file path is correct")
+					pathHint(absPath, managedPaths(), "Check that the file path is correct"))
 			}
 			return NewPathError("orphan", absPath, err)
 		}
@@ -78,6 +92,11 @@ func Orphan(opts OrphanOptions) error {
 			}
 			// Reject broken links
 			for _, link := range managed {
+				if link.Broken == BrokenPermissionDenied {
+					return NewPathErrorWithHint("orphan", link.Path,
+						fmt.Errorf("permission denied checking symlink target"),
+						brokenHint(link.Broken, absSourceDir))
+				}
 				if link.IsBroken {
 					return NewPathErrorWithHint("orphan", link.Path,
 						fmt.Errorf("symlink target does not exist"),
@@ -97,7 +116,7 @@ func Orphan(opts OrphanOptions) error {
 		// Handle files
 		if linkInfo.Mode()&os.ModeSymlink == 0 {
 			return NewPathErrorWithHint("orphan", absPath, ErrNotSymlink,
-				"Only symlinks can be orphaned. Use 'rm' to remove regular files")
+				pathHint(absPath, managedPaths(), "Only symlinks can be orphaned. Use 'rm' to remove regular files"))
 		}
 
 		// Read symlink target
@@ -120,7 +139,7 @@ func Orphan(opts OrphanOptions) error {
 		if err != nil || strings.HasPrefix(relPath, "..") || relPath == "." {
 			return NewLinkErrorWithHint("orphan", absPath, rawTarget,
 				fmt.Errorf("not managed by source"),
-				"This symlink was not created by lnk from this source. Use 'rm' to remove it directly")
+				pathHint(absPath, managedPaths(), "This symlink was not created by lnk from this source. Use 'rm' to remove it directly"))
 		}
 
 		// Verify target exists (not broken)
@@ -149,14 +168,38 @@ func Orphan(opts OrphanOptions) error {
 	}
 
 	// Dry-run
+	if opts.DryRun && opts.ToCopy {
+		fmt.Println()
+		PrintDryRun("Would convert %d symlink(s) to copies:", len(managedLinks))
+		lines := newPathLines(len(managedLinks), "Would copy", PrintDryRun)
+		for _, link := range managedLinks {
+			lines.Add(link.Path, "Would copy: %s", ContractPath(link.Path))
+			if !lines.Collapsed() {
+				PrintDetail("Replace symlink with a copy of: %s", ContractPath(link.Target))
+			}
+		}
+		lines.Flush()
+		fmt.Println()
+		PrintDryRunSummary()
+		return nil
+	}
 	if opts.DryRun {
 		fmt.Println()
 		PrintDryRun("Would orphan %d symlink(s):", len(managedLinks))
+		lines := newPathLines(len(managedLinks), "Would orphan", PrintDryRun)
 		for _, link := range managedLinks {
-			PrintDryRun("Would orphan: %s", ContractPath(link.Path))
+			lines.Add(link.Path, "Would orphan: %s", ContractPath(link.Path))
+			if lines.Collapsed() {
+				continue
+			}
 			PrintDetail("Remove symlink: %s", ContractPath(link.Path))
-			PrintDetail("Move from: %s", ContractPath(link.Target))
+			if isDirLink(link) {
+				PrintDetail("Copy directory from: %s", ContractPath(link.Target))
+			} else {
+				PrintDetail("Move from: %s", ContractPath(link.Target))
+			}
 		}
+		lines.Flush()
 		fmt.Println()
 		PrintDryRunSummary()
 		return nil
@@ -167,6 +210,7 @@ func Orphan(opts OrphanOptions) error {
 		link           ManagedLink
 		symlinkRemoved bool
 		fileMoved      bool
+		copied         bool
 	}
 	var completed []completedOrphan
 
@@ -174,6 +218,12 @@ func Orphan(opts OrphanOptions) error {
 		var rollbackErrors []string
 		for i := len(completed) - 1; i >= 0; i-- {
 			c := completed[i]
+			if c.copied {
+				if err := fsys.RemoveAll(c.link.Path); err != nil {
+					rollbackErrors = append(rollbackErrors, fmt.Sprintf("remove copy %s: %v", ContractPath(c.link.Path), err))
+					continue
+				}
+			}
 			if c.fileMoved {
 				if err := MoveFile(c.link.Path, c.link.Target); err != nil {
 					rollbackErrors = append(rollbackErrors, fmt.Sprintf("restore %s: %v", ContractPath(c.link.Target), err))
@@ -181,7 +231,7 @@ func Orphan(opts OrphanOptions) error {
 				}
 			}
 			if c.symlinkRemoved {
-				if err := os.Symlink(c.link.Target, c.link.Path); err != nil {
+				if err := fsys.Symlink(c.link.Target, c.link.Path); err != nil {
 					rollbackErrors = append(rollbackErrors, fmt.Sprintf("recreate symlink %s: %v", ContractPath(c.link.Path), err))
 				}
 			}
@@ -192,6 +242,8 @@ func Orphan(opts OrphanOptions) error {
 		return originalErr
 	}
 
+	copiedLines := newPathLines(len(managedLinks), "Copied", PrintSuccess)
+	orphanedLines := newPathLines(len(managedLinks), "Orphaned", PrintSuccess)
 	for _, link := range managedLinks {
 		c := completedOrphan{link: link}
 
@@ -205,6 +257,20 @@ func Orphan(opts OrphanOptions) error {
 		}
 		originalMode := targetInfo.Mode()
 
+		// A file copy is renamed over the symlink, so the path is never missing
+		if opts.ToCopy && !targetInfo.IsDir() {
+			if err := copyFile(link.Target, link.Path); err != nil {
+				completed = append(completed, c)
+				return rollback(NewPathErrorWithHint("orphan", link.Path,
+					fmt.Errorf("failed to copy file: %w", err),
+					"Check disk space and file permissions"))
+			}
+			c.symlinkRemoved, c.copied = true, true
+			completed = append(completed, c)
+			copiedLines.Add(link.Path, "Copied: %s", ContractPath(link.Path))
+			continue
+		}
+
 		// Remove symlink
 		if err := RemoveSymlink(link.Path); err != nil {
 			completed = append(completed, c)
@@ -212,6 +278,24 @@ func Orphan(opts OrphanOptions) error {
 		}
 		c.symlinkRemoved = true
 
+		// Directory symlinks are replaced with a copy; the repository keeps its directory
+		if targetInfo.IsDir() {
+			if err := copyDir(link.Target, link.Path); err != nil {
+				completed = append(completed, c)
+				return rollback(NewPathErrorWithHint("orphan", link.Path,
+					fmt.Errorf("failed to copy directory: %w", err),
+					"Check disk space and file permissions"))
+			}
+			c.copied = true
+			completed = append(completed, c)
+			if opts.ToCopy {
+				copiedLines.Add(link.Path, "Copied: %s", ContractPath(link.Path))
+			} else {
+				orphanedLines.Add(link.Path, "Orphaned: %s (copied directory)", ContractPath(link.Path))
+			}
+			continue
+		}
+
 		// Move file from source to target
 		if err := MoveFile(link.Target, link.Path); err != nil {
 			completed = append(completed, c)
@@ -221,21 +305,47 @@ func Orphan(opts OrphanOptions) error {
 		completed = append(completed, c)
 
 		// Restore permissions (best-effort)
-		if err := os.Chmod(link.Path, originalMode); err != nil {
+		if err := fsys.Chmod(link.Path, originalMode); err != nil {
 			PrintVerbose("Failed to restore permissions for %s: %v", ContractPath(link.Path), err)
 		}
 
-		PrintSuccess("Orphaned: %s", ContractPath(link.Path))
+		orphanedLines.Add(link.Path, "Orphaned: %s", ContractPath(link.Path))
 	}
+	copiedLines.Flush()
+	orphanedLines.Flush()
 
 	// Clean empty source-side parent directories
 	var parentDirs []string
-	for _, link := range managedLinks {
-		parentDirs = append(parentDirs, filepath.Dir(link.Target))
+	for _, c := range completed {
+		if c.fileMoved {
+			parentDirs = append(parentDirs, filepath.Dir(c.link.Target))
+		}
 	}
 	CleanEmptyDirs(parentDirs, absSourceDir)
 
+	orphaned := make([]string, len(completed))
+	for i, c := range completed {
+		orphaned[i] = c.link.Path
+	}
+	if opts.ToCopy {
+		recordCopies(absTargetDir, absSourceDir, managedLinks)
+		hardenPrivatePaths(absTargetDir, orphaned)
+		SummaryCount("copied", len(managedLinks))
+		PrintSummary("Converted %d symlink(s) to copies", len(managedLinks))
+		PrintNextStep("status", absSourceDir, "see copies that fall out of date")
+		return nil
+	}
+	forgetLinks(absTargetDir, orphaned)
+	hardenPrivatePaths(absTargetDir, orphaned)
+
+	SummaryCount("orphaned", len(managedLinks))
 	PrintSummary("Orphaned %d file(s) successfully", len(managedLinks))
 	PrintNextStep("status", absSourceDir, "view remaining managed files")
 	return nil
 }
+
+// isDirLink reports whether a managed link points at a directory (a dir-mode link).
+func isDirLink(link ManagedLink) bool {
+	info, err := os.Stat(link.Target)
+	return err == nil && info.IsDir()
+}
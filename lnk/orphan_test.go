@@ -491,6 +491,95 @@ func TestOrphanSingleFile(t *testing.T) {
 	}
 }
 
+func TestOrphanDirectorySymlink(t *testing.T) {
+	// A directory that is itself a symlink into the repo is replaced with a real
+	// directory holding copies; the repository directory is left in place.
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(targetDir, 0755)
+
+	repoNvim := filepath.Join(sourceDir, ".config", "nvim")
+	createTestFile(t, filepath.Join(repoNvim, "init.lua"), "init")
+	createTestFile(t, filepath.Join(repoNvim, "lua", "plugins.lua"), "plugins")
+	os.MkdirAll(filepath.Join(targetDir, ".config"), 0755)
+	linkPath := filepath.Join(targetDir, ".config", "nvim")
+	os.Symlink(repoNvim, linkPath)
+
+	output := CaptureOutput(t, func() {
+		err := Orphan(OrphanOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{linkPath}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	info, err := os.Lstat(linkPath)
+	if err != nil {
+		t.Fatalf("orphaned directory should exist: %v", err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+		t.Fatal("expected a real directory after orphaning")
+	}
+	content, _ := os.ReadFile(filepath.Join(linkPath, "lua", "plugins.lua"))
+	if string(content) != "plugins" {
+		t.Errorf("copied content = %q, want %q", string(content), "plugins")
+	}
+	assertDirExists(t, repoNvim)
+	if _, err := os.Stat(filepath.Join(repoNvim, "init.lua")); err != nil {
+		t.Errorf("repository file should be kept: %v", err)
+	}
+	ContainsOutput(t, output, "copied directory")
+}
+
+func TestOrphanDirectoryContainingDirectorySymlink(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(targetDir, 0755)
+
+	repoNvim := filepath.Join(sourceDir, ".config", "nvim")
+	createTestFile(t, filepath.Join(repoNvim, "init.lua"), "init")
+	configDir := filepath.Join(targetDir, ".config")
+	os.MkdirAll(configDir, 0755)
+	linkPath := filepath.Join(configDir, "nvim")
+	os.Symlink(repoNvim, linkPath)
+
+	CaptureOutput(t, func() {
+		err := Orphan(OrphanOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{configDir}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	info, err := os.Lstat(linkPath)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+		t.Fatalf("expected a real directory at %s", linkPath)
+	}
+	assertDirExists(t, repoNvim)
+}
+
+func TestOrphanDirectorySymlinkDryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(targetDir, 0755)
+
+	repoDir := filepath.Join(sourceDir, ".vim")
+	createTestFile(t, filepath.Join(repoDir, "vimrc"), "vim")
+	linkPath := filepath.Join(targetDir, ".vim")
+	os.Symlink(repoDir, linkPath)
+
+	output := CaptureOutput(t, func() {
+		err := Orphan(OrphanOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{linkPath}, DryRun: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	ContainsOutput(t, output, "Copy directory from:")
+	assertSymlink(t, linkPath, repoDir)
+}
+
 func TestOrphanMultipleFiles(t *testing.T) {
 	tempDir := t.TempDir()
 	sourceDir := filepath.Join(tempDir, "dotfiles")
@@ -795,3 +884,69 @@ func TestOrphanSummaryOutput(t *testing.T) {
 		t.Errorf("output missing next-step hint with 'lnk status', got:\n%s", output)
 	}
 }
+
+func TestOrphanToCopy(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	os.MkdirAll(targetDir, 0755)
+
+	sourceFile := filepath.Join(sourceDir, ".config", "app", "settings.json")
+	createTestFile(t, sourceFile, "{}")
+	linkPath := filepath.Join(targetDir, ".config", "app", "settings.json")
+	os.MkdirAll(filepath.Dir(linkPath), 0755)
+	os.Symlink(sourceFile, linkPath)
+
+	err := Orphan(OrphanOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{linkPath}, ToCopy: true})
+	if err != nil {
+		t.Fatalf("Orphan(ToCopy) error = %v", err)
+	}
+	info, err := os.Lstat(linkPath)
+	if err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("%s should be a regular file (err = %v)", linkPath, err)
+	}
+	if data, _ := os.ReadFile(sourceFile); string(data) != "{}" {
+		t.Errorf("repository file should be left in place, got %q", data)
+	}
+	copies := loadCopies(targetDir, sourceDir)
+	if len(copies) != 1 || copies[0].Path != linkPath || copies[0].Dest != sourceFile {
+		t.Fatalf("recorded copies = %+v, want %s copied from %s", copies, linkPath, sourceFile)
+	}
+	if copies[0].Hash == "" || copies[0].Hash != copyHash(linkPath) {
+		t.Errorf("recorded copy hash = %q, want the hash of the copy", copies[0].Hash)
+	}
+
+	// create leaves the copy alone instead of failing on it
+	if _, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}); err != nil {
+		t.Fatalf("CreateLinks() error = %v", err)
+	}
+	if info, _ := os.Lstat(linkPath); info.Mode()&os.ModeSymlink != 0 {
+		t.Error("create should not link over a copy-managed path")
+	}
+
+	// Adopting the copy links it again and drops the copy record
+	if _, err := Adopt(AdoptOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{linkPath}}); err != nil {
+		t.Fatalf("Adopt() error = %v", err)
+	}
+	if len(loadCopies(targetDir, sourceDir)) != 0 {
+		t.Error("adopting a copy should drop its copy record")
+	}
+}
+
+func TestCopyManaged(t *testing.T) {
+	copies := []ManifestCopy{{Path: "/home/u/.config/app"}}
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"/home/u/.config/app", true},
+		{"/home/u/.config/app/settings.json", true},
+		{"/home/u/.config/application", false},
+		{"/home/u/.bashrc", false},
+	}
+	for _, tt := range tests {
+		if got := copyManaged(copies, tt.target); got != tt.want {
+			t.Errorf("copyManaged(%s) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
@@ -32,29 +32,74 @@ package lnk
 //    PrintNextStep("status", "verify links")
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 )
 
+// quiet discards everything the Print functions write, for the functions that
+// return results instead of printing them (see results.go). Warnings and
+// errors are still recorded for the run summary and problem report.
+var quiet bool
+
+// stdout is where output goes: os.Stdout, or nowhere when quiet
+func stdout() io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return os.Stdout
+}
+
+// stderr is where errors and warnings go: os.Stderr, or nowhere when quiet
+func stderr() io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return os.Stderr
+}
+
+// jsonErrors writes errors and warnings to stderr as JSON objects (--output json)
+var jsonErrors bool
+
+// SetJSONErrors switches errors and warnings on stderr to one JSON object per
+// line (see ErrorRecord), so wrappers can relay precise failures
+func SetJSONErrors(enabled bool) {
+	jsonErrors = enabled
+}
+
+// printErrorRecord writes err to stderr as a JSON ErrorRecord
+func printErrorRecord(level string, err error) {
+	enc := json.NewEncoder(stderr())
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(NewErrorRecord(level, err))
+}
+
 // PrintSkip prints a skip message with a neutral icon
 func PrintSkip(format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
 	if ShouldSimplifyOutput() {
 		// For piped output, use simple text marker
-		fmt.Printf("skip %s\n", message)
+		fmt.Fprintf(stdout(), "skip %s\n", message)
 	} else {
-		fmt.Printf("%s %s\n", Yellow("○"), message)
+		fmt.Fprintf(stdout(), "%s %s\n", Yellow("○"), message)
 	}
 }
 
 // PrintWarning prints a warning message to stderr with the warning icon
 func PrintWarning(format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
-	if ShouldSimplifyOutput() {
+	err := errors.New(message)
+	recordSummaryError("warning", err)
+	recordProblem("warning", err)
+	if jsonErrors {
+		printErrorRecord("warning", err)
+	} else if ShouldSimplifyOutput() {
 		// For piped output, use simple text marker
-		fmt.Fprintf(os.Stderr, "warning: %s\n", message)
+		fmt.Fprintf(stderr(), "warning: %s\n", message)
 	} else {
-		fmt.Fprintf(os.Stderr, "%s %s\n", Yellow(WarningIcon), message)
+		fmt.Fprintf(stderr(), "%s %s\n", Yellow(WarningIcon), message)
 	}
 }
 
@@ -63,9 +108,9 @@ func PrintSuccess(format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
 	if ShouldSimplifyOutput() {
 		// For piped output, use simple text marker
-		fmt.Printf("success %s\n", message)
+		fmt.Fprintf(stdout(), "success %s\n", message)
 	} else {
-		fmt.Printf("%s %s\n", Green(SuccessIcon), message)
+		fmt.Fprintf(stdout(), "%s %s\n", Green(SuccessIcon), message)
 	}
 }
 
@@ -74,51 +119,60 @@ func PrintDryRun(format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
 	if ShouldSimplifyOutput() {
 		// For piped output, use simple text marker
-		fmt.Printf("dry-run: %s\n", message)
+		fmt.Fprintf(stdout(), "dry-run: %s\n", message)
 	} else {
-		fmt.Printf("%s %s\n", Yellow(DryRunPrefix), message)
+		fmt.Fprintf(stdout(), "%s %s\n", Yellow(DryRunPrefix), message)
 	}
 }
 
 // PrintError prints an error message to stderr with the error icon
 func PrintError(format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
-	if ShouldSimplifyOutput() {
+	err := errors.New(message)
+	recordSummaryError("error", err)
+	recordProblem("error", err)
+	if jsonErrors {
+		printErrorRecord("error", err)
+	} else if ShouldSimplifyOutput() {
 		// For piped output, use simple text marker
-		fmt.Fprintf(os.Stderr, "error: %s\n", message)
+		fmt.Fprintf(stderr(), "error: %s\n", message)
 	} else {
-		fmt.Fprintf(os.Stderr, "%s Error: %s\n", Red(FailureIcon), message)
+		fmt.Fprintf(stderr(), "%s Error: %s\n", Red(FailureIcon), message)
 	}
 }
 
 // PrintErrorWithHint prints an error message with an optional hint
 func PrintErrorWithHint(err error) {
-	if ShouldSimplifyOutput() {
+	recordSummaryError("error", err)
+	recordProblem("error", err)
+	if jsonErrors {
+		printErrorRecord("error", err)
+	} else if ShouldSimplifyOutput() {
 		// For piped output, use simple format
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		fmt.Fprintf(stderr(), "error: %v\n", err)
 		if hint := GetErrorHint(err); hint != "" {
-			fmt.Fprintf(os.Stderr, "hint: %s\n", hint)
+			fmt.Fprintf(stderr(), "hint: %s\n", hint)
 		}
 	} else {
 		// First print the error message
-		fmt.Fprintf(os.Stderr, "%s Error: %v\n", Red(FailureIcon), err)
+		fmt.Fprintf(stderr(), "%s Error: %v\n", Red(FailureIcon), err)
 
 		// Check if there's a hint
 		if hint := GetErrorHint(err); hint != "" {
-			fmt.Fprintf(os.Stderr, "  %s %s\n", Cyan("Try:"), hint)
+			fmt.Fprintf(stderr(), "  %s %s\n", Cyan("Try:"), hint)
 		}
 	}
 }
 
 // PrintInfo prints an informational message without any prefix
 func PrintInfo(format string, args ...interface{}) {
-	fmt.Printf(format+"\n", args...)
+	fmt.Fprintf(stdout(), format+"\n", args...)
 }
 
 // PrintDetail prints an indented detail message (for sub-items)
 func PrintDetail(format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
-	fmt.Printf("  %s\n", message)
+	fmt.Fprintf(stdout(), "  %s\n", message)
 }
 
 // PrintVerbose prints a message only when in verbose mode
@@ -127,7 +181,7 @@ func PrintVerbose(format string, args ...interface{}) {
 		return
 	}
 	message := fmt.Sprintf(format, args...)
-	fmt.Printf("[VERBOSE] %s\n", message)
+	fmt.Fprintf(stdout(), "[VERBOSE] %s\n", message)
 }
 
 // PrintCommandHeader prints a command header with standard spacing
@@ -136,14 +190,14 @@ func PrintCommandHeader(text string) {
 	if ShouldSimplifyOutput() {
 		return
 	}
-	fmt.Println(Bold(text))
-	fmt.Println()
+	fmt.Fprintln(stdout(), Bold(text))
+	fmt.Fprintln(stdout())
 }
 
 // PrintSummary prints a summary with standard spacing
 // This ensures all summaries have consistent formatting
 func PrintSummary(format string, args ...interface{}) {
-	fmt.Println() // Standard newline before summary
+	fmt.Fprintln(stdout()) // Standard newline before summary
 	PrintSuccess(format, args...)
 }
 
@@ -155,15 +209,19 @@ func PrintEmptyResult(itemType string) {
 // PrintWarningWithHint prints a warning message with an optional hint extracted from the error.
 // Always writes to stderr. Not gated by verbosity.
 func PrintWarningWithHint(err error) {
-	if ShouldSimplifyOutput() {
-		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	recordSummaryError("warning", err)
+	recordProblem("warning", err)
+	if jsonErrors {
+		printErrorRecord("warning", err)
+	} else if ShouldSimplifyOutput() {
+		fmt.Fprintf(stderr(), "warning: %v\n", err)
 		if hint := GetErrorHint(err); hint != "" {
-			fmt.Fprintf(os.Stderr, "hint: %s\n", hint)
+			fmt.Fprintf(stderr(), "hint: %s\n", hint)
 		}
 	} else {
-		fmt.Fprintf(os.Stderr, "%s %v\n", Yellow(WarningIcon), err)
+		fmt.Fprintf(stderr(), "%s %v\n", Yellow(WarningIcon), err)
 		if hint := GetErrorHint(err); hint != "" {
-			fmt.Fprintf(os.Stderr, "  %s %s\n", Cyan("Try:"), hint)
+			fmt.Fprintf(stderr(), "  %s %s\n", Cyan("Try:"), hint)
 		}
 	}
 }
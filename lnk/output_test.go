@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -126,3 +127,38 @@ func TestPrintNextStep(t *testing.T) {
 		}
 	})
 }
+
+func TestJSONErrors(t *testing.T) {
+	SetJSONErrors(true)
+	defer SetJSONErrors(false)
+
+	stdout, stderr := captureOutput(t, func() {
+		PrintErrorWithHint(NewPathErrorWithHint("remove", "/home/u/.z", errors.New("not managed"), "Use 'lnk status'"))
+		PrintWarning("Failed to remove %d symlink(s)", 2)
+	})
+	if stdout != "" {
+		t.Errorf("JSON errors must not write to stdout, got: %q", stdout)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines, got %d:\n%s", len(lines), stderr)
+	}
+	var records []ErrorRecord
+	for _, line := range lines {
+		var record ErrorRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("stderr line is not JSON: %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	want := []ErrorRecord{
+		{Level: "error", Code: CodePath, Message: "remove /home/u/.z: not managed", Path: "/home/u/.z", Hint: "Use 'lnk status'"},
+		{Level: "warning", Code: CodeError, Message: "Failed to remove 2 symlink(s)"},
+	}
+	for i := range want {
+		if records[i] != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, records[i], want[i])
+		}
+	}
+}
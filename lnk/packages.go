@@ -0,0 +1,495 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Packages let one source directory hold several independent trees. A package
+// is a top-level directory of the source directory; its contents are linked
+// into the target directory exactly as if the package directory itself had
+// been given as <source-dir> (like GNU Stow). A package may list the packages it
+// depends on in its .lnkrequires file; selecting it selects them too.
+
+// LoadPackagesFile loads default packages from a .lnkpackages file in the
+// source directory. A missing file means no default packages.
+func LoadPackagesFile(sourceDir string) ([]string, error) {
+	packagesFilePath := filepath.Join(sourceDir, PackagesFileName)
+	if _, err := os.Stat(packagesFilePath); os.IsNotExist(err) {
+		PrintVerbose("No .lnkpackages file found at: %s", packagesFilePath)
+		return nil, nil
+	}
+
+	// Same line format as .lnkignore: one entry per line, # comments
+	packages, err := parseIgnoreFile(packagesFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .lnkpackages: %w", err)
+	}
+
+	PrintVerbose("Loaded %d default packages from .lnkpackages", len(packages))
+	return packages, nil
+}
+
+// packageDirs resolves package names to absolute directories inside sourceDir.
+// With no packages, the source directory itself is the only tree.
+func packageDirs(sourceDir string, packages []string) ([]string, error) {
+	if len(packages) == 0 {
+		return []string{sourceDir}, nil
+	}
+
+	dirs := make([]string, 0, len(packages))
+	seen := make(map[string]bool)
+	for _, pkg := range packages {
+		name, err := cleanPackageName(sourceDir, pkg)
+		if err != nil {
+			return nil, err
+		}
+		dir := filepath.Join(sourceDir, name)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, NewValidationErrorWithHint("packages", pkg, "package directory does not exist",
+				packagesHint(sourceDir))
+		}
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// cleanPackageName validates that pkg names a single top-level directory of
+// sourceDir (no nesting, no "..") and returns it cleaned.
+func cleanPackageName(sourceDir, pkg string) (string, error) {
+	clean := filepath.Clean(pkg)
+	if filepath.IsAbs(clean) || clean == "." || clean == ".." || strings.ContainsRune(clean, filepath.Separator) {
+		return "", NewValidationErrorWithHint("packages", pkg,
+			"must be the name of a top-level directory in the source directory",
+			packagesHint(sourceDir))
+	}
+	return clean, nil
+}
+
+// expandPackageDeps validates the selected packages and adds the packages they
+// require, transitively. Each dependency is placed before the first package
+// that needs it; dependency cycles are tolerated.
+func expandPackageDeps(sourceDir string, packages []string) ([]string, error) {
+	selected := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		name, err := cleanPackageName(sourceDir, pkg)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, name)
+	}
+	return resolvePackageDeps(selected, func(pkg string) ([]string, error) {
+		return packageRequires(sourceDir, pkg)
+	})
+}
+
+// resolvePackageDeps orders packages and their dependencies, as reported by
+// requires, so that every package comes after the packages it depends on.
+func resolvePackageDeps(packages []string, requires func(pkg string) ([]string, error)) ([]string, error) {
+	var resolved []string
+	visited := make(map[string]bool)
+
+	var visit func(pkg, requiredBy string) error
+	visit = func(pkg, requiredBy string) error {
+		if visited[pkg] {
+			return nil
+		}
+		visited[pkg] = true
+		if requiredBy != "" {
+			PrintVerbose("Including package %s (required by %s)", pkg, requiredBy)
+		}
+		deps, err := requires(pkg)
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			if err := visit(dep, pkg); err != nil {
+				return err
+			}
+		}
+		resolved = append(resolved, pkg)
+		return nil
+	}
+
+	for _, pkg := range packages {
+		if err := visit(pkg, ""); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// packageRequires reads the direct dependencies of pkg from its .lnkrequires
+// file. A missing file (or package directory) means no dependencies.
+func packageRequires(sourceDir, pkg string) ([]string, error) {
+	requiresPath := filepath.Join(sourceDir, pkg, RequiresFileName)
+	if _, err := os.Stat(requiresPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+	deps, err := parseIgnoreFile(requiresPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ContractPath(requiresPath), err)
+	}
+	for i, dep := range deps {
+		name, err := cleanPackageName(sourceDir, dep)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(filepath.Join(sourceDir, name)); err != nil || !info.IsDir() {
+			return nil, NewValidationErrorWithHint("packages", name,
+				fmt.Sprintf("required by %s, but package directory does not exist", pkg),
+				fmt.Sprintf("Add the package or remove it from %s", ContractPath(requiresPath)))
+		}
+		deps[i] = name
+	}
+	return deps, nil
+}
+
+// warnLinkedDependents warns about packages outside removed that depend on a
+// removed package and still have links in targetDir, since they may stop
+// working once their dependency is unlinked.
+func warnLinkedDependents(sourceDir, targetDir string, removed []string) {
+	removing := make(map[string]bool)
+	for _, pkg := range removed {
+		removing[filepath.Clean(pkg)] = true
+	}
+
+	for _, pkg := range availablePackages(sourceDir) {
+		if removing[pkg] {
+			continue
+		}
+		deps, err := packageRequires(sourceDir, pkg)
+		if err != nil {
+			PrintVerbose("Failed to read dependencies of %s: %v", pkg, err)
+			continue
+		}
+		for _, dep := range deps {
+			if !removing[dep] {
+				continue
+			}
+			if links, err := collectManagedLinks(filepath.Join(sourceDir, pkg), targetDir); err != nil || len(links) == 0 {
+				break
+			}
+			PrintWarningWithHint(WithHint(
+				fmt.Errorf("Package %s requires %s and is still linked", pkg, dep),
+				fmt.Sprintf("Remove it too with --packages %s,%s", pkg, dep)))
+			break
+		}
+	}
+}
+
+// availablePackages lists the top-level directories of sourceDir that can be
+// used as packages, skipping hidden directories such as .git.
+func availablePackages(sourceDir string) []string {
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil {
+		return nil
+	}
+	var packages []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			packages = append(packages, e.Name())
+		}
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// packagesHint suggests valid package names for error messages
+func packagesHint(sourceDir string) string {
+	available := availablePackages(sourceDir)
+	if len(available) == 0 {
+		return fmt.Sprintf("%s has no package directories", ContractPath(sourceDir))
+	}
+	return fmt.Sprintf("Available packages: %s", strings.Join(available, ", "))
+}
+
+// collectPackageLinks plans links for each package directory returned by
+// packageDirs, leaving out packages and paths whose conditions do not hold.
+// Two packages providing the same target path is an error, since only one of
+// them could be linked. Each included package's plan is returned too.
+func collectPackageLinks(dirs []string, targetDir string, ignorePatterns []string) ([]PlannedLink, []specialFile, []mappingPlan, error) {
+	var links []PlannedLink
+	var specials []specialFile
+	var plans []mappingPlan
+	owner := make(map[string]string) // target -> package dir that provides it
+	for _, dir := range dirs {
+		info, err := LoadPackageInfo(dir)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		include, skipped, err := conditionalIgnorePatterns(dir, info)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !include {
+			continue
+		}
+		pkgTarget, include, err := packageTargetDir(dir, targetDir, info)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !include {
+			continue
+		}
+		patterns := append(slices.Clone(ignorePatterns), skipped...)
+
+		policy, err := info.symlinkPolicy(dir)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		pkgLinks, pkgSpecials, ignored, err := collectPlannedLinksWithPatterns(dir, pkgTarget, patterns, policy)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if info.IsEphemeral() {
+			for i := range pkgLinks {
+				pkgLinks[i].Ephemeral = true
+			}
+		}
+		assignPackageGroups(pkgLinks, dir, info)
+		for _, link := range pkgLinks {
+			if other, ok := owner[link.Target]; ok {
+				return nil, nil, nil, NewValidationErrorWithHint("packages", filepath.Base(dir),
+					fmt.Sprintf("%s is also provided by package %s", ContractPath(link.Target), filepath.Base(other)),
+					"Select only one of these packages, or remove the file from one of them")
+			}
+			owner[link.Target] = dir
+		}
+		Trace("mapping", "source", ContractPath(dir), "target", ContractPath(pkgTarget),
+			"links", len(pkgLinks), "special_files", len(pkgSpecials), "skipped_patterns", len(skipped))
+		plans = append(plans, mappingPlan{source: dir, target: pkgTarget, links: pkgLinks, ignored: ignored})
+		links = append(links, pkgLinks...)
+		specials = append(specials, pkgSpecials...)
+	}
+	return links, specials, plans, nil
+}
+
+// packageTargetDir returns where a package's links go: its target home
+// directory, narrowed by its type (see packageTypeDir). Packages targeting the
+// Windows home are skipped (false) outside WSL, and packages targeting the
+// runtime directory are skipped when XDG_RUNTIME_DIR is not set.
+func packageTargetDir(pkgDir, targetDir string, info *PackageInfo) (string, bool, error) {
+	switch info.Target {
+	case "", TargetHome:
+		dir, err := packageTypeDir(pkgDir, targetDir, info)
+		return dir, err == nil, err
+	case TargetWindows:
+		if !isWSL() {
+			PrintVerbose("Skipping %s: Windows home target requires WSL", ContractPath(pkgDir))
+			capabilities.add(unsupportedFeature{
+				setting:  `"target": "windows"`,
+				path:     filepath.Join(pkgDir, PackageInfoFileName),
+				reason:   "the Windows home is only reachable under WSL",
+				fallback: "skipping the package",
+			})
+			return "", false, nil
+		}
+		home, err := windowsHome()
+		if err != nil {
+			return "", false, err
+		}
+		PrintVerbose("Linking %s into Windows home %s", ContractPath(pkgDir), home)
+		dir, err := packageTypeDir(pkgDir, home, info)
+		return dir, err == nil, err
+	case TargetRuntime:
+		runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+		if runtimeDir == "" {
+			PrintVerbose("Skipping %s: runtime target requires XDG_RUNTIME_DIR", ContractPath(pkgDir))
+			capabilities.add(unsupportedFeature{
+				setting:  `"target": "runtime"`,
+				path:     filepath.Join(pkgDir, PackageInfoFileName),
+				reason:   "XDG_RUNTIME_DIR is not set",
+				fallback: "skipping the package",
+			})
+			return "", false, nil
+		}
+		PrintVerbose("Linking %s into runtime directory %s", ContractPath(pkgDir), runtimeDir)
+		dir, err := packageTypeDir(pkgDir, runtimeDir, info)
+		return dir, err == nil, err
+	default:
+		return "", false, NewValidationErrorWithHint("target", info.Target,
+			fmt.Sprintf("unknown target in %s", ContractPath(filepath.Join(pkgDir, PackageInfoFileName))),
+			fmt.Sprintf("Valid targets: %s, %s, %s", TargetHome, TargetWindows, TargetRuntime))
+	}
+}
+
+// resolvePackageTarget loads a package's metadata and returns its target directory
+func resolvePackageTarget(pkgDir, targetDir string) (string, bool, error) {
+	info, err := LoadPackageInfo(pkgDir)
+	if err != nil {
+		return "", false, err
+	}
+	return packageTargetDir(pkgDir, targetDir, info)
+}
+
+// PackageInfo is the optional metadata a package describes itself with in its
+// lnk-package.json file
+type PackageInfo struct {
+	Name        string   `json:"name,omitempty"`        // display name (default: directory name)
+	Description string   `json:"description,omitempty"` // one-line summary
+	Platforms   []string `json:"platforms,omitempty"`   // GOOS values the package supports (empty = all)
+	Commands    []string `json:"commands,omitempty"`    // commands the package's files need on PATH
+
+	When      Condition      `json:"when"`                 // link the package only where this holds
+	Groups    []string       `json:"groups,omitempty"`     // link groups the package's files belong to (--group)
+	Overrides []PathOverride `json:"overrides,omitempty"`  // conditions for parts of the package
+	KeepLocal []string       `json:"keep_local,omitempty"` // patterns of files whose copies sync never overwrites
+	Merge     []string       `json:"merge,omitempty"`      // patterns of files whose copies sync merges both ways
+	Target    string         `json:"target,omitempty"`     // "home" (default), "windows" for the Windows home under WSL, or "runtime" for $XDG_RUNTIME_DIR
+	Ephemeral bool           `json:"ephemeral,omitempty"`  // links live on tmpfs and vanish on reboot; implied by "runtime"
+	Type      string         `json:"type,omitempty"`       // "dotfiles" (default), "fonts", "bin", or "assets"
+	AssetsDir string         `json:"assets_dir,omitempty"` // where an assets package links to, relative to the home directory
+
+	FollowSymlinkedDirs bool   `json:"follow_symlinked_dirs,omitempty"` // link the files in symlinked directories instead of skipping them
+	SymlinkedFiles      string `json:"symlinked_files,omitempty"`       // what to do with files that are symlinks: "skip" (default), "link", "dereference", or "copy"
+
+	Defaults []DefaultsSetting `json:"defaults,omitempty"` // macOS preferences for 'lnk defaults'
+
+	Reload []ReloadAction `json:"reload,omitempty"` // how running programs pick up changed files (--reload)
+
+	MinLnkVersion string `json:"min_lnk_version,omitempty"` // oldest lnk that understands this configuration
+}
+
+// LoadPackageInfo reads lnk-package.json from a package directory. A missing
+// file is not an error; empty metadata is returned instead.
+func LoadPackageInfo(pkgDir string) (*PackageInfo, error) {
+	path := filepath.Join(pkgDir, PackageInfoFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &PackageInfo{}, nil
+		}
+		return nil, NewPathErrorWithHint("read package metadata", path, err,
+			"Check file permissions")
+	}
+
+	info, err := parsePackageInfo(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnknownKeys(path, data); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// parsePackageInfo decodes the contents of the lnk-package.json file at path,
+// leaving unknown keys to checkUnknownKeys
+func parsePackageInfo(path string, data []byte) (*PackageInfo, error) {
+	if err := checkRequiredVersion(path, data); err != nil {
+		return nil, err
+	}
+
+	var info PackageInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, NewPathErrorWithHint("parse package metadata", path, err,
+			fmt.Sprintf("Fix the JSON in %s", ContractPath(path)))
+	}
+	return &info, nil
+}
+
+// IsEphemeral reports whether the package's links are expected to vanish on
+// reboot, so their absence is not reported as missing
+func (p *PackageInfo) IsEphemeral() bool {
+	return p.Ephemeral || p.Target == TargetRuntime
+}
+
+// SupportsPlatform reports whether the package supports the given GOOS
+func (p *PackageInfo) SupportsPlatform(goos string) bool {
+	return len(p.Platforms) == 0 || slices.Contains(p.Platforms, goos)
+}
+
+// MissingCommands returns the required commands that are not on PATH
+func (p *PackageInfo) MissingCommands() []string {
+	var missing []string
+	for _, cmd := range p.Commands {
+		if _, err := exec.LookPath(cmd); err != nil {
+			missing = append(missing, cmd)
+		}
+	}
+	return missing
+}
+
+// ListPackages lists the packages in the source directory with their metadata,
+// marking the selected ones (opts.Packages and their dependencies).
+func ListPackages(opts LinkOptions) error {
+	PrintCommandHeader("Packages")
+
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir := paths.SourceDir
+	PrintVerbose("Source directory: %s", sourceDir)
+
+	selected, err := expandPackageDeps(sourceDir, opts.Packages)
+	if err != nil {
+		return err
+	}
+
+	packages := availablePackages(sourceDir)
+	if len(packages) == 0 {
+		PrintEmptyResult("packages")
+		return nil
+	}
+
+	for _, pkg := range packages {
+		pkgDir := filepath.Join(sourceDir, pkg)
+		info, err := LoadPackageInfo(pkgDir)
+		if err != nil {
+			PrintWarningWithHint(err)
+			info = &PackageInfo{}
+		}
+		deps, err := packageRequires(sourceDir, pkg)
+		if err != nil {
+			PrintVerbose("Failed to read dependencies of %s: %v", pkg, err)
+		}
+		isSelected := slices.Contains(selected, pkg)
+
+		if ShouldSimplifyOutput() {
+			state := "available"
+			if isSelected {
+				state = "selected"
+			}
+			fmt.Println(strings.TrimSpace(fmt.Sprintf("package %s %s %s", pkg, state, info.Description)))
+			continue
+		}
+
+		title := Bold(pkg)
+		if info.Name != "" && info.Name != pkg {
+			title += " (" + info.Name + ")"
+		}
+		if isSelected {
+			title += " " + Green("[selected]")
+		}
+		if info.Description != "" {
+			title += ": " + info.Description
+		}
+		fmt.Println(title)
+		if len(info.Platforms) > 0 {
+			PrintDetail("Platforms: %s", strings.Join(info.Platforms, ", "))
+		}
+		if len(deps) > 0 {
+			PrintDetail("Requires: %s", strings.Join(deps, ", "))
+		}
+		if len(info.Commands) > 0 {
+			PrintDetail("Commands: %s", strings.Join(info.Commands, ", "))
+		}
+	}
+
+	if !ShouldSimplifyOutput() {
+		fmt.Println()
+		PrintInfo("Total: %s", Bold(fmt.Sprintf("%d packages", len(packages))))
+	}
+	return nil
+}
@@ -0,0 +1,392 @@
+package lnk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// setupPackagesTest creates a source directory with shell and nvim packages
+// and an empty target directory.
+func setupPackagesTest(t *testing.T) (string, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "repo")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".bashrc"), "# bashrc")
+	createTestFile(t, filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua"), "-- init")
+	createTestFile(t, filepath.Join(sourceDir, "work", ".gitconfig"), "[user]")
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return sourceDir, targetDir
+}
+
+func TestCreateLinksWithPackages(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim"}}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	assertSymlink(t, filepath.Join(targetDir, ".config", "nvim", "init.lua"),
+		filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua"))
+	assertNotExists(t, filepath.Join(targetDir, ".gitconfig"))
+	assertNotExists(t, filepath.Join(targetDir, "shell"))
+}
+
+func TestStatusWithPackages(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestSymlink(t, filepath.Join(sourceDir, "shell", ".bashrc"), filepath.Join(targetDir, ".bashrc"))
+	createTestSymlink(t, filepath.Join(sourceDir, "work", ".gitconfig"), filepath.Join(targetDir, ".gitconfig"))
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim"}}
+	output := CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+	})
+
+	ContainsOutput(t, output, "active "+filepath.Join(targetDir, ".bashrc"), "unlinked "+filepath.Join(sourceDir, "nvim"))
+	NotContainsOutput(t, output, ".gitconfig")
+}
+
+func TestRemoveLinksWithPackages(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestSymlink(t, filepath.Join(sourceDir, "shell", ".bashrc"), filepath.Join(targetDir, ".bashrc"))
+	createTestSymlink(t, filepath.Join(sourceDir, "work", ".gitconfig"), filepath.Join(targetDir, ".gitconfig"))
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}}
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+	assertSymlink(t, filepath.Join(targetDir, ".gitconfig"), filepath.Join(sourceDir, "work", ".gitconfig"))
+}
+
+func TestPackagesInvalid(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+
+	for _, pkg := range []string{"missing", "../other", "/abs", "nvim/.config", "."} {
+		_, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{pkg}})
+		if err == nil {
+			t.Errorf("expected error for package %q", pkg)
+			continue
+		}
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("package %q: expected ValidationError, got %T", pkg, err)
+		}
+		if hint := GetErrorHint(err); hint != "Available packages: nvim, shell, work" {
+			t.Errorf("package %q: hint = %q", pkg, hint)
+		}
+	}
+}
+
+func TestPackagesDuplicateTarget(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "work", ".bashrc"), "# work bashrc")
+
+	_, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "work"}})
+	if err == nil {
+		t.Fatal("expected error when two packages provide the same file")
+	}
+	ContainsOutput(t, err.Error(), ".bashrc is also provided by package shell")
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+}
+
+func TestLoadPackagesFile(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	packages, err := LoadPackagesFile(sourceDir)
+	if err != nil || packages != nil {
+		t.Fatalf("LoadPackagesFile() without file = %v, %v; want nil, nil", packages, err)
+	}
+
+	createTestFile(t, filepath.Join(sourceDir, PackagesFileName), "# defaults\nshell\n\nnvim\n")
+	packages, err = LoadPackagesFile(sourceDir)
+	if err != nil {
+		t.Fatalf("LoadPackagesFile() error = %v", err)
+	}
+	if len(packages) != 2 || packages[0] != "shell" || packages[1] != "nvim" {
+		t.Errorf("LoadPackagesFile() = %v, want [shell nvim]", packages)
+	}
+}
+
+func TestCreateLinksWithPackageDependencies(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", RequiresFileName), "# nvim needs the shell setup\nshell\n")
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"nvim"},
+	}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	assertSymlink(t, filepath.Join(targetDir, ".config", "nvim", "init.lua"),
+		filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua"))
+	assertNotExists(t, filepath.Join(targetDir, RequiresFileName))
+	assertNotExists(t, filepath.Join(targetDir, ".gitconfig"))
+}
+
+func TestResolvePackageDeps(t *testing.T) {
+	graph := map[string][]string{
+		"nvim":  {"shell", "fonts"},
+		"shell": {"base"},
+		"fonts": {"base"},
+		"a":     {"b"},
+		"b":     {"a"},
+	}
+	requires := func(pkg string) ([]string, error) { return graph[pkg], nil }
+
+	tests := []struct {
+		packages []string
+		want     []string
+	}{
+		{[]string{"nvim"}, []string{"base", "shell", "fonts", "nvim"}},
+		{[]string{"shell", "nvim"}, []string{"base", "shell", "fonts", "nvim"}},
+		{[]string{"a"}, []string{"b", "a"}},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		got, err := resolvePackageDeps(tt.packages, requires)
+		if err != nil {
+			t.Fatalf("resolvePackageDeps(%v) error = %v", tt.packages, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("resolvePackageDeps(%v) = %v, want %v", tt.packages, got, tt.want)
+		}
+	}
+}
+
+func TestPackagesMissingDependency(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", RequiresFileName), "fonts\n")
+
+	_, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"nvim"}})
+	if err == nil {
+		t.Fatal("expected error for a missing dependency")
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError, got %T", err)
+	}
+	ContainsOutput(t, err.Error(), "required by nvim")
+	assertNotExists(t, filepath.Join(targetDir, ".config"))
+}
+
+func TestRemoveLinksWarnsAboutDependents(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", RequiresFileName), "shell\n")
+	createTestSymlink(t, filepath.Join(sourceDir, "shell", ".bashrc"), filepath.Join(targetDir, ".bashrc"))
+	createTestSymlink(t, filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua"),
+		filepath.Join(targetDir, ".config", "nvim", "init.lua"))
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}}
+	_, stderr := captureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+
+	ContainsOutput(t, stderr, "Package nvim requires shell and is still linked", "--packages nvim,shell")
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+
+	// No warning once the dependent package is unlinked too
+	opts.Packages = []string{"nvim", "shell"}
+	_, stderr = captureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	NotContainsOutput(t, stderr, "still linked")
+}
+
+func TestLoadPackageInfo(t *testing.T) {
+	pkgDir := t.TempDir()
+
+	info, err := LoadPackageInfo(pkgDir)
+	if err != nil {
+		t.Fatalf("LoadPackageInfo() without file error = %v", err)
+	}
+	if info.Description != "" || !info.SupportsPlatform("linux") {
+		t.Errorf("LoadPackageInfo() without file = %+v, want empty metadata", info)
+	}
+
+	createTestFile(t, filepath.Join(pkgDir, PackageInfoFileName),
+		`{"name": "Tmux", "description": "Terminal multiplexer", "platforms": ["darwin"], "commands": ["tmux"]}`)
+	info, err = LoadPackageInfo(pkgDir)
+	if err != nil {
+		t.Fatalf("LoadPackageInfo() error = %v", err)
+	}
+	if info.Name != "Tmux" || info.Description != "Terminal multiplexer" || !slices.Equal(info.Commands, []string{"tmux"}) {
+		t.Errorf("LoadPackageInfo() = %+v", info)
+	}
+	if !info.SupportsPlatform("darwin") || info.SupportsPlatform("linux") {
+		t.Errorf("SupportsPlatform() does not follow platforms %v", info.Platforms)
+	}
+
+	createTestFile(t, filepath.Join(pkgDir, PackageInfoFileName), `{"name": `)
+	if _, err := LoadPackageInfo(pkgDir); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestListPackages(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", PackageInfoFileName), `{"description": "Neovim setup"}`)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", RequiresFileName), "shell\n")
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"nvim"}}
+	output := CaptureOutput(t, func() {
+		if err := ListPackages(opts); err != nil {
+			t.Fatalf("ListPackages() error = %v", err)
+		}
+	})
+
+	ContainsOutput(t, output,
+		"package nvim selected Neovim setup\n",
+		"package shell selected\n",
+		"package work available\n")
+}
+
+func TestFollowSymlinkedDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "repo")
+	targetDir := filepath.Join(tmpDir, "home")
+	outside := filepath.Join(tmpDir, "secrets")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "# bashrc")
+	createTestFile(t, filepath.Join(outside, "token"), "s3cret")
+	createTestSymlink(t, outside, filepath.Join(sourceDir, ".secrets"))
+	// A symlink back to the directory holding it would be walked forever
+	createTestSymlink(t, filepath.Join(sourceDir, ".config"), filepath.Join(sourceDir, ".config", "loop"))
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}
+	token := filepath.Join(targetDir, ".secrets", "token")
+
+	// Skipped by default
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, ".bashrc"))
+	assertNotExists(t, filepath.Join(targetDir, ".secrets"))
+
+	createTestFile(t, filepath.Join(sourceDir, PackageInfoFileName), `{"follow_symlinked_dirs": true}`)
+	var stderr string
+	_, stderr = captureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	if !strings.Contains(stderr, "leads back to") {
+		t.Errorf("expected a warning about the symlink loop, got:\n%s", stderr)
+	}
+	assertSymlink(t, token, filepath.Join(sourceDir, ".secrets", "token"))
+	assertNotExists(t, filepath.Join(targetDir, ".config", "loop"))
+
+	output := CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "active "+token)
+
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertNotExists(t, token)
+	if _, err := os.Stat(filepath.Join(outside, "token")); err != nil {
+		t.Errorf("file outside the source directory should be left alone: %v", err)
+	}
+}
+
+func TestSymlinkedFiles(t *testing.T) {
+	tests := []struct {
+		policy   string
+		wantDest func(sourceDir, outside string) string // "" = not a link
+		wantCopy bool
+	}{
+		{SymlinkedFilesSkip, nil, false},
+		{SymlinkedFilesLink, func(sourceDir, _ string) string { return filepath.Join(sourceDir, ".gitconfig.local") }, false},
+		{SymlinkedFilesDereference, func(_, outside string) string { return filepath.Join(outside, "gitconfig") }, false},
+		{SymlinkedFilesCopy, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			sourceDir := filepath.Join(tmpDir, "repo")
+			targetDir := filepath.Join(tmpDir, "home")
+			outside := filepath.Join(tmpDir, "work")
+			createTestFile(t, filepath.Join(outside, "gitconfig"), "[user]")
+			createTestSymlink(t, filepath.Join(outside, "gitconfig"), filepath.Join(sourceDir, ".gitconfig.local"))
+			createTestFile(t, filepath.Join(sourceDir, PackageInfoFileName), `{"symlinked_files": "`+tt.policy+`"}`)
+			if err := os.MkdirAll(targetDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}
+			target := filepath.Join(targetDir, ".gitconfig.local")
+
+			CaptureOutput(t, func() {
+				if _, err := CreateLinks(opts); err != nil {
+					t.Fatalf("CreateLinks() error = %v", err)
+				}
+			})
+			switch {
+			case tt.wantDest != nil:
+				assertSymlink(t, target, tt.wantDest(sourceDir, outside))
+			case tt.wantCopy:
+				info, err := os.Lstat(target)
+				if err != nil || !info.Mode().IsRegular() {
+					t.Fatalf("expected a copy at %s: %v", target, err)
+				}
+			default:
+				assertNotExists(t, target)
+			}
+
+			CaptureOutput(t, func() {
+				if err := RemoveLinks(opts); err != nil {
+					t.Fatalf("RemoveLinks() error = %v", err)
+				}
+			})
+			if tt.wantDest != nil {
+				assertNotExists(t, target)
+			}
+			if _, err := os.Stat(filepath.Join(outside, "gitconfig")); err != nil {
+				t.Errorf("file outside the source directory should be left alone: %v", err)
+			}
+		})
+	}
+
+	t.Run("unknown policy", func(t *testing.T) {
+		sourceDir := t.TempDir()
+		createTestFile(t, filepath.Join(sourceDir, PackageInfoFileName), `{"symlinked_files": "follow"}`)
+		_, _, _, err := collectPackageLinks([]string{sourceDir}, t.TempDir(), nil)
+		var ve *ValidationError
+		if !errors.As(err, &ve) || ve.Field != "symlinked_files" {
+			t.Errorf("collectPackageLinks() error = %v, want a symlinked_files ValidationError", err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package lnk
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Commands with long read-only output (status on thousands of links, report,
+// ...) send stdout through a pager when it is a terminal, as git does. Errors
+// and prompts stay on stderr and are not paged.
+
+// pagerEnvDefaults are set for the pager unless the user set them: less
+// passes color codes through (-R), exits when the output fits one screen
+// (-F), and leaves the output on screen (-X); lv keeps colors too
+var pagerEnvDefaults = map[string]string{"LESS": "FRX", "LV": "-c"}
+
+var (
+	pager    *exec.Cmd // running pager, nil when output is not paged
+	pagerTTY *os.File  // the terminal stdout pointed at before paging
+)
+
+// PagerCommand returns the pager to use: LNK_PAGER, then $PAGER, then less;
+// "" when it is "cat", which turns paging off
+func PagerCommand(envPager string) string {
+	command := envPager
+	if command == "" {
+		command = os.Getenv("PAGER")
+	}
+	if command == "" {
+		command = "less"
+	}
+	if strings.TrimSpace(command) == "cat" {
+		return ""
+	}
+	return command
+}
+
+// StartPager sends stdout through command until StopPager. Nothing happens
+// when command is empty, stdout is not a terminal, or the pager cannot be
+// started; output then goes to stdout as usual.
+func StartPager(command string) {
+	if command == "" || pager != nil || !isTerminal() {
+		return
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		PrintVerbose("Pager %s not found; not paging: %v", fields[0], err)
+		return
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command(fields[0], fields[1:]...)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = os.Environ()
+	for name, value := range pagerEnvDefaults {
+		if _, ok := os.LookupEnv(name); !ok {
+			cmd.Env = append(cmd.Env, name+"="+value)
+		}
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		PrintVerbose("Not paging: %v", err)
+		return
+	}
+	cmd.Stdin = r
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		w.Close()
+		PrintVerbose("Pager %s failed to start; not paging: %v", command, err)
+		return
+	}
+	r.Close()
+	// Decide on colors while stdout is still the terminal
+	ShouldEnableColor()
+	pager, pagerTTY = cmd, os.Stdout
+	os.Stdout = w
+}
+
+// StopPager ends paging: it closes the pager's input and waits until the
+// user quits it. Safe to call when no pager is running.
+func StopPager() {
+	if pager == nil {
+		return
+	}
+	w := os.Stdout
+	os.Stdout = pagerTTY
+	w.Close()
+	pager.Wait()
+	pager = nil
+}
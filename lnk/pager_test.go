@@ -0,0 +1,38 @@
+package lnk
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPagerCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		lnkPager string
+		pager    string
+		want     string
+	}{
+		{"default", "", "", "less"},
+		{"PAGER", "", "more", "more"},
+		{"LNK_PAGER over PAGER", "less -S", "more", "less -S"},
+		{"cat turns paging off", "", "cat", ""},
+		{"LNK_PAGER cat over PAGER", "cat", "more", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PAGER", tt.pager)
+			if got := PagerCommand(tt.lnkPager); got != tt.want {
+				t.Errorf("PagerCommand(%q) with PAGER=%q = %q, want %q", tt.lnkPager, tt.pager, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartPagerNotTerminal(t *testing.T) {
+	stdout := os.Stdout
+	StartPager("less")
+	defer StopPager()
+	if pager != nil || os.Stdout != stdout {
+		t.Error("StartPager() paged output that is not a terminal")
+	}
+}
@@ -0,0 +1,111 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PathDisplay selects how ContractPath shows paths. The zero value contracts
+// the home directory to ~.
+type PathDisplay struct {
+	XDG      bool // paths under a set XDG base directory as $XDG_CONFIG_HOME/...
+	Repo     bool // paths inside the source directory as repo:<path>
+	Absolute bool // full paths, for scripts; overrides the others
+}
+
+// PathDisplays lists the values --path-display accepts
+var PathDisplays = []string{"xdg", "repo", "absolute"}
+
+// xdgVars lists the XDG base directory variables ContractPath shows
+var xdgVars = []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME"}
+
+var (
+	// pathDisplay is the display set by SetPathDisplay
+	pathDisplay PathDisplay
+	// displayRepoRoot is the source directory repo: paths are relative to
+	displayRepoRoot string
+)
+
+// ParsePathDisplay parses --path-display values (or LNK_PATH_DISPLAY entries)
+func ParsePathDisplay(values []string) (PathDisplay, error) {
+	var d PathDisplay
+	for _, value := range values {
+		switch value {
+		case "xdg":
+			d.XDG = true
+		case "repo":
+			d.Repo = true
+		case "absolute":
+			d.Absolute = true
+		default:
+			return PathDisplay{}, NewValidationErrorWithHint("path display", value, "unknown path display",
+				fmt.Sprintf("Valid values: %s", strings.Join(PathDisplays, ", ")))
+		}
+	}
+	return d, nil
+}
+
+// SetPathDisplay sets how ContractPath shows paths for the rest of the run
+func SetPathDisplay(d PathDisplay) {
+	pathDisplay = d
+}
+
+// SetDisplayRepoRoot sets the source directory that repo: paths are relative
+// to, once it is known
+func SetDisplayRepoRoot(dir string) {
+	displayRepoRoot = dir
+}
+
+// contractRepo shows path relative to the source directory, as repo:<path>.
+// The source directory itself is left alone, so commands printed for the user
+// to run stay runnable.
+func contractRepo(path string) (string, bool) {
+	if displayRepoRoot == "" || !filepath.IsAbs(path) || path == displayRepoRoot || !isWithin(path, displayRepoRoot) {
+		return "", false
+	}
+	rel, err := filepath.Rel(displayRepoRoot, path)
+	if err != nil {
+		return "", false
+	}
+	return "repo:" + filepath.ToSlash(rel), true
+}
+
+// contractXDG shows path relative to the innermost set XDG base directory
+// containing it, as $XDG_CONFIG_HOME/<path>
+func contractXDG(path string) (string, bool) {
+	best, bestDir := "", ""
+	for _, name := range xdgVars {
+		dir := filepath.Clean(os.Getenv(name))
+		if !filepath.IsAbs(dir) || !isWithin(path, dir) || len(dir) <= len(bestDir) {
+			continue
+		}
+		best, bestDir = name, dir
+	}
+	if best == "" {
+		return "", false
+	}
+	if path == bestDir {
+		return "$" + best, true
+	}
+	return "$" + best + strings.TrimPrefix(path, bestDir), true
+}
+
+// contractHome contracts the home directory to ~, whatever the display. Use
+// it for paths that are stored rather than shown.
+func contractHome(path string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		// If we can't get home dir, return the original path
+		return path
+	}
+
+	// Check if path starts with home directory
+	if strings.HasPrefix(path, homeDir) {
+		// Replace home directory with ~ and clean up any double slashes
+		return filepath.Clean("~" + strings.TrimPrefix(path, homeDir))
+	}
+
+	return path
+}
@@ -0,0 +1,58 @@
+package lnk
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContractPathDisplay(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DATA_HOME", "relative/ignored")
+	repo := filepath.Join(home, "git", "dotfiles")
+	SetDisplayRepoRoot(repo)
+	t.Cleanup(func() {
+		SetPathDisplay(PathDisplay{})
+		SetDisplayRepoRoot("")
+	})
+
+	paths := []string{
+		filepath.Join(home, ".config", "nvim", "init.lua"),
+		filepath.Join(repo, "home", ".bashrc"),
+		repo,
+		filepath.Join(home, ".local", "share", "fonts"),
+		"/etc/hosts",
+	}
+	tests := []struct {
+		name    string
+		display []string
+		want    []string
+	}{
+		{"default", nil,
+			[]string{"~/.config/nvim/init.lua", "~/git/dotfiles/home/.bashrc", "~/git/dotfiles", "~/.local/share/fonts", "/etc/hosts"}},
+		{"xdg and repo", []string{"xdg", "repo"},
+			[]string{"$XDG_CONFIG_HOME/nvim/init.lua", "repo:home/.bashrc", "~/git/dotfiles", "~/.local/share/fonts", "/etc/hosts"}},
+		{"absolute wins", []string{"repo", "absolute"}, paths},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			display, err := ParsePathDisplay(tt.display)
+			if err != nil {
+				t.Fatal(err)
+			}
+			SetPathDisplay(display)
+			for i, path := range paths {
+				if got := ContractPath(path); got != tt.want[i] {
+					t.Errorf("ContractPath(%q) = %q, want %q", path, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	_, err := ParsePathDisplay([]string{"relative"})
+	if err == nil || !strings.Contains(GetErrorHint(err), "xdg, repo, absolute") {
+		t.Errorf("ParsePathDisplay(relative) error = %v, want one listing the valid values", err)
+	}
+}
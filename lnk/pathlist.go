@@ -0,0 +1,105 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StdinPath is the path argument that reads the path list from standard input
+const StdinPath = "-"
+
+// ReadPathList reads a list of paths, one per line. When the input contains a
+// NUL byte it is split on NUL instead (as written by 'fd -0' or 'find -print0'),
+// so paths containing newlines survive. Empty entries are skipped.
+func ReadPathList(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sep := []byte("\n")
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = []byte{0}
+	}
+
+	var paths []string
+	for _, entry := range bytes.Split(data, sep) {
+		if sep[0] == '\n' {
+			entry = bytes.TrimSuffix(entry, []byte("\r"))
+		}
+		if len(entry) > 0 {
+			paths = append(paths, string(entry))
+		}
+	}
+	return paths, nil
+}
+
+// ExpandPathArgs replaces each "-" in args with the paths read from stdin and
+// appends the paths read from pathsFrom ("-" for stdin, "" for none). Standard
+// input can only be read once, and a list that yields no paths is an error so
+// an empty pipe never turns into "all paths".
+func ExpandPathArgs(args []string, pathsFrom string, stdin io.Reader) ([]string, error) {
+	stdinRead := false
+	readStdin := func(source string) ([]string, error) {
+		if stdinRead {
+			return nil, NewValidationErrorWithHint("paths", source, "standard input can only be read once",
+				"Pass '-' or '--paths-from -' once")
+		}
+		stdinRead = true
+		paths, err := ReadPathList(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading paths from standard input: %w", err)
+		}
+		if len(paths) == 0 {
+			return nil, WithHint(fmt.Errorf("no paths read from standard input"),
+				"Pipe one path per line, or NUL-separated paths (e.g. fd -0 | lnk remove . -)")
+		}
+		return paths, nil
+	}
+
+	var paths []string
+	for _, arg := range args {
+		if arg != StdinPath {
+			paths = append(paths, arg)
+			continue
+		}
+		list, err := readStdin(arg)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, list...)
+	}
+
+	switch pathsFrom {
+	case "":
+	case StdinPath:
+		list, err := readStdin("--paths-from " + pathsFrom)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, list...)
+	default:
+		expanded, err := ExpandPath(pathsFrom)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Open(expanded)
+		if err != nil {
+			return nil, NewPathErrorWithHint("read path list", expanded, err,
+				"Check that the --paths-from file exists")
+		}
+		defer f.Close()
+		list, err := ReadPathList(f)
+		if err != nil {
+			return nil, NewPathError("read path list", expanded, err)
+		}
+		if len(list) == 0 {
+			return nil, WithHint(fmt.Errorf("no paths read from %s", ContractPath(expanded)),
+				"List one path per line, or separate paths with NUL bytes")
+		}
+		paths = append(paths, list...)
+	}
+	return paths, nil
+}
@@ -0,0 +1,79 @@
+package lnk
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPathList(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "newline separated", input: "~/.bashrc\n~/.vimrc\n", want: []string{"~/.bashrc", "~/.vimrc"}},
+		{name: "crlf and blank lines", input: "~/.bashrc\r\n\r\n~/.vimrc", want: []string{"~/.bashrc", "~/.vimrc"}},
+		{name: "nul separated", input: "~/a b\x00~/odd\nname\x00", want: []string{"~/a b", "~/odd\nname"}},
+		{name: "empty", input: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadPathList(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ReadPathList() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadPathList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandPathArgs(t *testing.T) {
+	listFile := filepath.Join(t.TempDir(), "paths.txt")
+	createTestFile(t, listFile, "~/.gitconfig\n")
+	emptyFile := filepath.Join(t.TempDir(), "empty.txt")
+	createTestFile(t, emptyFile, "\n")
+
+	tests := []struct {
+		name      string
+		args      []string
+		pathsFrom string
+		stdin     string
+		want      []string
+		wantErr   string
+	}{
+		{name: "no lists", args: []string{"~/.bashrc"}, want: []string{"~/.bashrc"}},
+		{name: "dash in place", args: []string{"~/.bashrc", "-", "~/.zshrc"}, stdin: "~/.vimrc\n",
+			want: []string{"~/.bashrc", "~/.vimrc", "~/.zshrc"}},
+		{name: "paths-from stdin", pathsFrom: "-", stdin: "~/.vimrc\x00", want: []string{"~/.vimrc"}},
+		{name: "paths-from file", args: []string{"~/.bashrc"}, pathsFrom: listFile,
+			want: []string{"~/.bashrc", "~/.gitconfig"}},
+		{name: "stdin twice", args: []string{"-"}, pathsFrom: "-", stdin: "~/.vimrc\n", wantErr: "only be read once"},
+		{name: "empty stdin", args: []string{"-"}, wantErr: "no paths read from standard input"},
+		{name: "empty file", pathsFrom: emptyFile, wantErr: "no paths read"},
+		{name: "missing file", pathsFrom: filepath.Join(t.TempDir(), "missing"), wantErr: "read path list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandPathArgs(tt.args, tt.pathsFrom, strings.NewReader(tt.stdin))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExpandPathArgs() error = %v, want %q", err, tt.wantErr)
+				}
+				if GetErrorHint(err) == "" {
+					t.Errorf("expected a hint for %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandPathArgs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandPathArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
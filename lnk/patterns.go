@@ -12,6 +12,7 @@ type PatternMatcher struct {
 
 // compiledPattern represents a parsed pattern with its properties
 type compiledPattern struct {
+	raw        string // pattern as written, for reporting
 	pattern    string
 	isNegation bool
 	isDir      bool
@@ -36,12 +37,20 @@ func NewPatternMatcher(patterns []string) *PatternMatcher {
 
 // Matches checks if a path matches any of the patterns
 func (pm *PatternMatcher) Matches(path string) bool {
+	_, matched := pm.MatchingPattern(path)
+	return matched
+}
+
+// MatchingPattern reports whether path is ignored and, if so, returns the
+// pattern (as written) that ignored it: the last matching non-negated pattern.
+func (pm *PatternMatcher) MatchingPattern(path string) (string, bool) {
 	// Normalize the path
 	path = normalizePathForMatching(path)
 
 	// Check each pattern in order, tracking match state
 	// Patterns are processed sequentially, with later patterns overriding earlier ones
 	matched := false
+	var matchedBy string
 
 	for _, pattern := range pm.patterns {
 		if pattern.isNegation {
@@ -54,16 +63,18 @@ func (pm *PatternMatcher) Matches(path string) bool {
 				isNegation: false, // Treat as non-negated for matching
 			}) {
 				matched = false
+				matchedBy = ""
 			}
 		} else {
 			// Regular patterns
 			if matchesPattern(path, pattern) {
 				matched = true
+				matchedBy = pattern.raw
 			}
 		}
 	}
 
-	return matched
+	return matchedBy, matched
 }
 
 // compilePattern parses a pattern string into a compiledPattern
@@ -76,6 +87,7 @@ func compilePattern(pattern string) *compiledPattern {
 	}
 
 	cp := &compiledPattern{
+		raw:     pattern,
 		pattern: pattern,
 	}
 
@@ -418,3 +418,26 @@ func BenchmarkPatternMatcherReuse(b *testing.B) {
 		}
 	}
 }
+
+func TestMatchingPattern(t *testing.T) {
+	pm := NewPatternMatcher([]string{"*.swp", ".git", "local/", "!keep.swp"})
+
+	tests := []struct {
+		path        string
+		wantPattern string
+		wantMatched bool
+	}{
+		{"notes.swp", "*.swp", true},
+		{".git/config", ".git", true},
+		{"local/file", "local/", true},
+		{"keep.swp", "", false},
+		{".bashrc", "", false},
+	}
+	for _, tt := range tests {
+		pattern, matched := pm.MatchingPattern(tt.path)
+		if pattern != tt.wantPattern || matched != tt.wantMatched {
+			t.Errorf("MatchingPattern(%q) = (%q, %v), want (%q, %v)",
+				tt.path, pattern, matched, tt.wantPattern, tt.wantMatched)
+		}
+	}
+}
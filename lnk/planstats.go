@@ -0,0 +1,171 @@
+package lnk
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// mappingPlan is what planning found for one package or --map mapping: the
+// links it provides and how many files the ignore patterns left out
+type mappingPlan struct {
+	source  string
+	target  string
+	links   []PlannedLink
+	ignored int
+}
+
+// mappingCounts tallies a mapping's planned links by what creating them would do
+type mappingCounts struct {
+	link, ignored, conflicts, linked int
+}
+
+// What is at a planned link's target (see plannedLinkState)
+const (
+	stateToLink   = "to link"        // nothing yet
+	stateLinked   = "already linked" // a symlink to the link's source
+	stateConflict = "conflict"       // something else is in the way
+)
+
+// plannedLinkState reports what is at link's target now
+func plannedLinkState(link PlannedLink) string {
+	info, err := fsys.Lstat(link.Target)
+	switch {
+	case err != nil:
+		return stateToLink
+	case info.Mode()&fs.ModeSymlink != 0 && linkPointsTo(link.Target, link.Source):
+		return stateLinked
+	default:
+		return stateConflict
+	}
+}
+
+// countMappingPlan sorts the links of plan that are still planned into new
+// links, conflicts, and links already in place
+func countMappingPlan(plan mappingPlan, planned map[string]bool) mappingCounts {
+	counts := mappingCounts{ignored: plan.ignored}
+	for _, link := range plan.links {
+		if !planned[link.Target] {
+			continue // local-only or copy-managed, reported on its own
+		}
+		switch plannedLinkState(link) {
+		case stateToLink:
+			counts.link++
+		case stateLinked:
+			counts.linked++
+		default:
+			counts.conflicts++
+		}
+	}
+	return counts
+}
+
+// printMappingPlans prints a dry run's plan as one row per mapping, so a new
+// mapping can be checked at a glance before anything is linked
+func printMappingPlans(plans []mappingPlan, plannedLinks []PlannedLink) {
+	if len(plans) == 0 {
+		return
+	}
+	planned := make(map[string]bool, len(plannedLinks))
+	for _, link := range plannedLinks {
+		planned[link.Target] = true
+	}
+
+	headers := []string{"Mapping", "To link", "Ignored", "Conflicts", "Already linked"}
+	rows := make([][]string, 0, len(plans))
+	for _, plan := range plans {
+		counts := countMappingPlan(plan, planned)
+		rows = append(rows, []string{
+			ContractPath(plan.source) + " -> " + ContractPath(plan.target),
+			fmt.Sprint(counts.link), fmt.Sprint(counts.ignored), fmt.Sprint(counts.conflicts), fmt.Sprint(counts.linked),
+		})
+	}
+	widths := make([]int, len(headers))
+	for _, row := range append([][]string{headers}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	format := func(row []string) string {
+		cells := []string{fmt.Sprintf("%-*s", widths[0], row[0])}
+		for i, cell := range row[1:] {
+			cells = append(cells, fmt.Sprintf("%*s", widths[i+1], cell))
+		}
+		return strings.Join(cells, "  ")
+	}
+
+	fmt.Fprintln(stdout())
+	PrintDryRun("Plan by mapping:")
+	PrintDetail("%s", format(headers))
+	for _, row := range rows {
+		PrintDetail("%s", format(row))
+	}
+}
+
+// printPlanDelta prints a dry run's plan as what would change: links to
+// create, symlinks to point at a new source, and conflicts that would fail.
+// Links already in place are only counted, so a re-run on a configured
+// machine prints just what differs. The marks say how a link is made (see
+// CreateLinks).
+func printPlanDelta(links []PlannedLink, copyTargets, replaceTargets, mklinkTargets map[string]bool) {
+	var create, retarget, conflicts []PlannedLink
+	unchanged := 0
+	for _, link := range links {
+		if link.Copy || copyTargets[link.Target] || replaceTargets[link.Target] {
+			create = append(create, link)
+			recordLink(link, ResultPlanned, "", nil)
+			continue
+		}
+		switch plannedLinkState(link) {
+		case stateToLink:
+			create = append(create, link)
+			recordLink(link, ResultPlanned, "", nil)
+		case stateLinked:
+			unchanged++
+			recordLink(link, ResultExisting, "", nil)
+		default:
+			if info, err := fsys.Lstat(link.Target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+				retarget = append(retarget, link)
+				recordLink(link, ResultPlanned, "retarget", nil)
+			} else {
+				conflicts = append(conflicts, link)
+				recordLink(link, ResultPlanned, "conflict", nil)
+			}
+		}
+	}
+	SummaryCount("to_create", len(create))
+	SummaryCount("to_retarget", len(retarget))
+	SummaryCount("conflicts", len(conflicts))
+	SummaryCount("unchanged", unchanged)
+
+	if len(create)+len(retarget)+len(conflicts) == 0 {
+		PrintInfo("All %d symlink(s) already exist; nothing would change", unchanged)
+		return
+	}
+	PrintDryRun("Would create %d, retarget %d, with %d conflict(s); %d already in place",
+		len(create), len(retarget), len(conflicts), unchanged)
+
+	lines := newPathLines(len(create), "Would link", PrintDryRun)
+	for _, link := range create {
+		switch {
+		case link.Copy:
+			lines.Add(link.Target, "Would copy: %s <- %s", ContractPath(link.Target), ContractPath(link.Source))
+		case copyTargets[link.Target]:
+			lines.Add(link.Target, "Would copy (no symlink support): %s <- %s", ContractPath(link.Target), ContractPath(link.Source))
+		case replaceTargets[link.Target]:
+			lines.Add(link.Target, "Would replace identical file: %s -> %s", ContractPath(link.Target), ContractPath(link.Source))
+		case mklinkTargets[link.Target]:
+			lines.Add(link.Target, "Would link with mklink: %s -> %s", ContractPath(link.Target), ContractPath(link.Source))
+		default:
+			lines.Add(link.Target, "Would link: %s -> %s", ContractPath(link.Target), ContractPath(link.Source))
+		}
+	}
+	lines.Flush()
+	for _, link := range retarget {
+		dest, _ := fsys.Readlink(link.Target)
+		PrintDryRun("Would retarget: %s -> %s (was %s)", ContractPath(link.Target), ContractPath(link.Source), ContractPath(dest))
+	}
+	for _, link := range conflicts {
+		PrintWarning("Conflict: %s %s", ContractPath(link.Target), describeObstacle(link.Target))
+	}
+}
@@ -0,0 +1,48 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateLinksDryRunMappingPlans(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".profile"), "# profile")
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".bashrc.swp"), "swap")
+	createTestFile(t, filepath.Join(targetDir, ".profile"), "# local profile")
+	initLua := filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua")
+	if err := os.MkdirAll(filepath.Join(targetDir, ".config", "nvim"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(initLua, filepath.Join(targetDir, ".config", "nvim", "init.lua")); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := LinkOptions{
+		SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim"},
+		IgnorePatterns: []string{"*.swp"}, DryRun: true,
+	}
+	output := CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	rows := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		if source, counts, ok := strings.Cut(strings.TrimSpace(line), " -> "); ok && strings.HasPrefix(source, sourceDir) {
+			fields := strings.Fields(counts)
+			rows[filepath.Base(source)] = strings.Join(fields[1:], " ")
+		}
+	}
+	// To link, ignored, conflicts, already linked
+	want := map[string]string{"shell": "1 1 1 0", "nvim": "0 0 0 1"}
+	for pkg, counts := range want {
+		if rows[pkg] != counts {
+			t.Errorf("plan row for %s = %q, want %q\noutput:\n%s", pkg, rows[pkg], counts, output)
+		}
+	}
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+}
@@ -0,0 +1,115 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// privateDirs are directories in the target directory whose contents must be
+// accessible only by their owner; ssh and gpg refuse or warn about keys and
+// configuration that others can read
+var privateDirs = []string{".ssh", ".gnupg"}
+
+// hardenPrivatePaths enforces owner-only permissions for paths lnk placed
+// inside a private directory. The private directory and every directory below
+// it on the way to each path become 0700, and regular files (including those
+// in a copied directory) lose their group and other permissions (600 for
+// ordinary files). A symlink takes its
+// permissions from its source, which is left alone but reported when others
+// can access it. Failures are warnings. Nothing is checked on Windows, where
+// permission bits are synthetic.
+func hardenPrivatePaths(targetDir string, paths []string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	checked := make(map[string]bool)
+	for _, path := range paths {
+		root := privateDirOf(targetDir, path)
+		if root == "" {
+			continue
+		}
+		for dir := filepath.Dir(path); isWithin(dir, root); dir = filepath.Dir(dir) {
+			if checked[dir] {
+				continue
+			}
+			checked[dir] = true
+			restrictMode(dir)
+		}
+
+		info, err := fsys.Lstat(path)
+		if err != nil {
+			PrintVerbose("Failed to check %s: %v", ContractPath(path), err)
+			continue
+		}
+		switch {
+		case info.Mode().IsRegular():
+			restrictMode(path)
+		case info.IsDir():
+			_ = walkDir(path, func(p string, d os.DirEntry, err error) error {
+				if err == nil && (d.IsDir() || d.Type().IsRegular()) {
+					restrictMode(p)
+				}
+				return nil
+			})
+		case info.Mode()&os.ModeSymlink != 0:
+			warnAccessibleSource(path)
+		}
+	}
+}
+
+// privateDirOf returns the private directory containing path, or "" when path
+// is not inside one
+func privateDirOf(targetDir, path string) string {
+	for _, name := range privateDirs {
+		dir := filepath.Join(targetDir, name)
+		if path != dir && isWithin(path, dir) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// restrictMode removes group and other permissions from path, keeping the
+// owner's (and giving directories full owner access)
+func restrictMode(path string) {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		PrintVerbose("Failed to check %s: %v", ContractPath(path), err)
+		return
+	}
+	perm := info.Mode().Perm()
+	want := perm &^ 0077
+	if info.IsDir() {
+		want |= 0700
+	}
+	if perm == want {
+		return
+	}
+	if err := fsys.Chmod(path, want); err != nil {
+		PrintWarningWithHint(WithHint(
+			fmt.Errorf("Failed to restrict permissions of %s: %w", ContractPath(path), err),
+			fmt.Sprintf("Run: chmod %o %s", want, ContractPath(path))))
+		return
+	}
+	PrintInfo("Restricted permissions: %s (%o -> %o)", ContractPath(path), perm, want)
+}
+
+// warnAccessibleSource warns when the file a private link points to can be
+// accessed by other users
+func warnAccessibleSource(link string) {
+	source, err := fsys.EvalSymlinks(link)
+	if err != nil {
+		PrintVerbose("Failed to resolve %s: %v", ContractPath(link), err)
+		return
+	}
+	info, err := fsys.Stat(source)
+	if err != nil || info.IsDir() || info.Mode().Perm()&0077 == 0 {
+		return
+	}
+	PrintWarningWithHint(WithHint(
+		fmt.Errorf("%s links to %s, which other users can access (%s)",
+			ContractPath(link), ContractPath(source), info.Mode().Perm()),
+		fmt.Sprintf("Restrict it to its owner: chmod 600 %s", ContractPath(source))))
+}
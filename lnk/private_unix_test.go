@@ -0,0 +1,76 @@
+//go:build unix
+
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateLinksHardensPrivateDirs(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "repo")
+	targetDir := t.TempDir()
+	config := filepath.Join(sourceDir, "ssh", ".ssh", "config")
+	key := filepath.Join(sourceDir, "ssh", ".ssh", "keys", "id_ed25519")
+	createTestFile(t, config, "Host *")
+	createTestFile(t, key, "secret")
+	if err := os.Chmod(config, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	// An existing ~/.ssh that others can read
+	if err := os.Mkdir(filepath.Join(targetDir, ".ssh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		_, err = CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"ssh"}})
+	})
+	if err != nil {
+		t.Fatalf("CreateLinks() error = %v\n%s", err, stderr)
+	}
+
+	for _, dir := range []string{".ssh", filepath.Join(".ssh", "keys")} {
+		info, err := os.Stat(filepath.Join(targetDir, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0700 {
+			t.Errorf("%s mode = %o, want 700", dir, perm)
+		}
+	}
+	ContainsOutput(t, stdout, "Restricted permissions: "+filepath.Join(targetDir, ".ssh")+" (755 -> 700)")
+	ContainsOutput(t, stderr, "links to "+config+", which other users can access", "chmod 600 "+config)
+	NotContainsOutput(t, stderr, "id_ed25519")
+
+	// The source file is reported, not changed
+	if info, _ := os.Stat(config); info.Mode().Perm() != 0644 {
+		t.Errorf("source mode changed to %o", info.Mode().Perm())
+	}
+}
+
+func TestHardenPrivatePathsFiles(t *testing.T) {
+	targetDir := t.TempDir()
+	gpgConf := filepath.Join(targetDir, ".gnupg", "gpg.conf")
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	createTestFile(t, gpgConf, "use-agent")
+	createTestFile(t, bashrc, "# bashrc")
+	for _, path := range []string{gpgConf, bashrc} {
+		if err := os.Chmod(path, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	CaptureOutput(t, func() { hardenPrivatePaths(targetDir, []string{gpgConf, bashrc}) })
+
+	if info, _ := os.Stat(gpgConf); info.Mode().Perm() != 0600 {
+		t.Errorf("gpg.conf mode = %o, want 600", info.Mode().Perm())
+	}
+	if info, _ := os.Stat(bashrc); info.Mode().Perm() != 0644 {
+		t.Errorf(".bashrc mode = %o, want unchanged 644", info.Mode().Perm())
+	}
+}
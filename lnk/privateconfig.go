@@ -0,0 +1,183 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// The private configuration is an encrypted file in the source directory,
+// .lnkprivate.age or .lnkprivate.gpg, holding the entries a user would rather
+// not keep in plaintext: mappings and patterns that name work projects, other
+// machines, or secrets' locations. It is decrypted with age or gpg each time
+// the configuration is loaded and never written back to disk in plaintext.
+
+// Private configuration sections, named after the settings they add to
+var privateSections = []string{"ignore", "local-only", "sensitive", "maps", "protected"}
+
+// PrivateConfig holds the entries of the private configuration by section
+type PrivateConfig struct {
+	Path     string              // the encrypted file
+	Sections map[string][]string // entries by section, in file order
+}
+
+// Entries returns the entries of section, or nil for a nil config
+func (p *PrivateConfig) Entries(section string) []string {
+	if p == nil {
+		return nil
+	}
+	return p.Sections[section]
+}
+
+// privateFilePaths returns the encrypted private configuration files lnk
+// looks for in sourceDir, in the order they are looked for
+func privateFilePaths(sourceDir string) []string {
+	return []string{
+		filepath.Join(sourceDir, PrivateFileName+".age"),
+		filepath.Join(sourceDir, PrivateFileName+".gpg"),
+	}
+}
+
+// decryptFile runs the age or gpg command decrypting path and returns the
+// plaintext. Passphrase and pinentry prompts use the terminal. Tests replace
+// it.
+var decryptFile = func(path string) ([]byte, error) {
+	var name string
+	var args []string
+	var hint string
+	if strings.HasSuffix(path, ".age") {
+		name, args = "age", []string{"--decrypt"}
+		if identity := os.Getenv(EnvAgeIdentity); identity != "" {
+			identity, err := ExpandPath(identity)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, "--identity", identity)
+		} else if identity := AgeIdentityPath(); exists(identity) {
+			args = append(args, "--identity", identity) // made by 'lnk identity init'
+		}
+		hint = fmt.Sprintf("Set %s to the age identity file that can decrypt it, enter its passphrase when asked, "+
+			"or run 'lnk identity init' here and 'lnk identity rekey' where it can be decrypted", EnvAgeIdentity)
+	} else {
+		name, args = "gpg", []string{"--quiet", "--decrypt"}
+		hint = "Check that the gpg secret key it is encrypted to is available (gpg --list-secret-keys)"
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, NewPathErrorWithHint("decrypt", path, fmt.Errorf("%s command not found", name),
+			fmt.Sprintf("Install %s to use %s", name, filepath.Base(path)))
+	}
+
+	cmd := exec.Command(name, append(args, path)...)
+	cmd.Stdin = os.Stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%s: %v: %s", name, err, msg)
+		}
+		return nil, NewPathErrorWithHint("decrypt", path, err, hint)
+	}
+	return out, nil
+}
+
+// LoadPrivateFile decrypts and parses the private configuration in the source
+// directory. A missing file is not an error; nil is returned instead.
+func LoadPrivateFile(sourceDir string) (*PrivateConfig, error) {
+	var found []string
+	for _, path := range privateFilePaths(sourceDir) {
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		PrintVerbose("No %s.age or %s.gpg file found in: %s", PrivateFileName, PrivateFileName, sourceDir)
+		return nil, nil
+	case 1:
+	default:
+		return nil, NewValidationErrorWithHint("private", ContractPath(sourceDir),
+			"both "+filepath.Base(found[0])+" and "+filepath.Base(found[1])+" exist",
+			"Keep one private configuration file and remove the other")
+	}
+
+	path := found[0]
+	data, err := decryptFile(path)
+	if err != nil {
+		return nil, err
+	}
+	private, err := parsePrivateConfig(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	for _, entries := range private.Sections {
+		total += len(entries)
+	}
+	PrintVerbose("Loaded %d private entries from %s", total, filepath.Base(path))
+	return private, nil
+}
+
+// parsePrivateConfig parses the decrypted private configuration: [section]
+// headers, each followed by entries in the format of the file the section is
+// named after, with # comments
+func parsePrivateConfig(path string, data []byte) (*PrivateConfig, error) {
+	private := &PrivateConfig{Path: path, Sections: make(map[string][]string)}
+	section := ""
+	for _, line := range parseLines(string(data)) {
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if !slices.Contains(privateSections, section) {
+				return nil, NewPathErrorWithHint("parse private configuration", path,
+					fmt.Errorf("unknown section [%s]", section),
+					fmt.Sprintf("Valid sections: %s", strings.Join(privateSections, ", ")))
+			}
+			continue
+		}
+		if section == "" {
+			return nil, NewPathErrorWithHint("parse private configuration", path,
+				errors.New("entry before the first [section]"),
+				fmt.Sprintf("Put each entry under a section header such as [maps]; valid sections: %s", strings.Join(privateSections, ", ")))
+		}
+		private.Sections[section] = append(private.Sections[section], line)
+	}
+	return private, nil
+}
+
+// privateMaps parses the [maps] section of the private configuration
+func privateMaps(private *PrivateConfig) ([]Mapping, error) {
+	var maps []Mapping
+	for _, line := range private.Entries("maps") {
+		m, err := ParseMapping(line)
+		if err != nil {
+			return nil, NewPathErrorWithHint("parse mapping", private.Path, err,
+				"Fix the entry in [maps]; each line is SRC:TGT[:MODE] as --map takes it")
+		}
+		maps = append(maps, m)
+	}
+	return maps, nil
+}
+
+// privateSources returns the configuration sources for the private
+// configuration: one per section it has, or a single missing source
+func privateSources(sourceDir string, private *PrivateConfig) []ConfigSource {
+	if private == nil {
+		return []ConfigSource{{Name: privateFilePaths(sourceDir)[0], Setting: "private", Found: false}}
+	}
+	var sources []ConfigSource
+	for _, section := range privateSections {
+		if entries := private.Entries(section); len(entries) > 0 {
+			sources = append(sources, ConfigSource{Name: private.Path, Setting: section, Found: true, Values: entries})
+		}
+	}
+	if len(sources) == 0 {
+		sources = append(sources, ConfigSource{Name: private.Path, Setting: "private", Found: true})
+	}
+	return sources
+}
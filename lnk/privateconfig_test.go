@@ -0,0 +1,111 @@
+package lnk
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// stubDecrypt replaces decryption with returning plaintext, or err, for the
+// rest of the test
+func stubDecrypt(t *testing.T, plaintext string, err error) {
+	t.Helper()
+	saved := decryptFile
+	decryptFile = func(string) ([]byte, error) { return []byte(plaintext), err }
+	t.Cleanup(func() { decryptFile = saved })
+}
+
+func TestLoadPrivateFile(t *testing.T) {
+	sourceDir := t.TempDir()
+	private, err := LoadPrivateFile(sourceDir)
+	if err != nil || private != nil {
+		t.Fatalf("LoadPrivateFile() = %v, %v, want nil for a missing file", private, err)
+	}
+
+	createTestFile(t, filepath.Join(sourceDir, PrivateFileName+".age"), "age-encryption.org/v1")
+	stubDecrypt(t, "# work\n[maps]\nwork/acme:~\n[local-only]\n~/.config/acme/\n", nil)
+	private, err = LoadPrivateFile(sourceDir)
+	if err != nil {
+		t.Fatalf("LoadPrivateFile() error = %v", err)
+	}
+	if !slices.Equal(private.Entries("maps"), []string{"work/acme:~"}) ||
+		!slices.Equal(private.Entries("local-only"), []string{"~/.config/acme/"}) {
+		t.Errorf("LoadPrivateFile() sections = %v", private.Sections)
+	}
+
+	for name, plaintext := range map[string]string{
+		"unknown section": "[packages]\nshell\n",
+		"no section":      "work/acme:~\n",
+	} {
+		stubDecrypt(t, plaintext, nil)
+		if _, err := LoadPrivateFile(sourceDir); err == nil || GetErrorHint(err) == "" {
+			t.Errorf("%s: LoadPrivateFile() error = %v, want an error with a hint", name, err)
+		}
+	}
+
+	createTestFile(t, filepath.Join(sourceDir, PrivateFileName+".gpg"), "-----BEGIN PGP MESSAGE-----")
+	if _, err := LoadPrivateFile(sourceDir); err == nil {
+		t.Error("LoadPrivateFile() should refuse both .age and .gpg files")
+	}
+}
+
+func TestLoadConfigPrivate(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFile(t, filepath.Join(sourceDir, LocalOnlyFileName), "~/.gitconfig.local\n")
+	createTestFile(t, filepath.Join(sourceDir, IgnoreFileName), "*.bak\n")
+	createTestFile(t, filepath.Join(sourceDir, PrivateFileName+".gpg"), "-----BEGIN PGP MESSAGE-----")
+	stubDecrypt(t, "[ignore]\nwork/\n[local-only]\n~/.config/acme/\n[maps]\nwork/acme:~\n[protected]\n~/.netrc\n", nil)
+
+	// LoadConfig leaves decrypting to commands that need the private settings
+	saved := decryptFile
+	decryptFile = func(string) ([]byte, error) {
+		t.Error("LoadConfig() decrypted the private configuration")
+		return nil, errors.New("unexpected")
+	}
+	config, err := LoadConfig(sourceDir, []string{"*.cli"})
+	decryptFile = saved
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if !slices.Equal(config.LocalOnly, []string{"~/.gitconfig.local"}) {
+		t.Errorf("LocalOnly before LoadPrivate = %v", config.LocalOnly)
+	}
+	if err := config.LoadPrivate(); err != nil {
+		t.Fatalf("LoadPrivate() error = %v", err)
+	}
+	if err := config.LoadPrivate(); err != nil {
+		t.Fatalf("LoadPrivate() again error = %v", err)
+	}
+	if got := config.IgnorePatterns[len(config.IgnorePatterns)-3:]; !slices.Equal(got, []string{"*.bak", "work/", "*.cli"}) {
+		t.Errorf("IgnorePatterns end = %v, want private patterns between .lnkignore and --ignore", got)
+	}
+	if !slices.Equal(config.LocalOnly, []string{"~/.gitconfig.local", "~/.config/acme/"}) {
+		t.Errorf("LocalOnly = %v", config.LocalOnly)
+	}
+	if len(config.Maps) != 1 || config.Maps[0].Source != "work/acme" {
+		t.Errorf("Maps = %+v", config.Maps)
+	}
+	if !slices.Contains(config.Protected, "~/.netrc") {
+		t.Errorf("Protected = %v, want ~/.netrc", config.Protected)
+	}
+	var settings []string
+	for _, src := range config.Sources {
+		if src.Name == filepath.Join(config.SourceDir, PrivateFileName+".gpg") {
+			settings = append(settings, src.Setting)
+		}
+	}
+	if !slices.Equal(settings, []string{"ignore", "local-only", "maps", "protected"}) {
+		t.Errorf("private sources = %v, want one per section", settings)
+	}
+
+	// A file that cannot be decrypted fails loading rather than dropping its entries
+	stubDecrypt(t, "", NewPathErrorWithHint("decrypt", sourceDir, errors.New("no identity matched"), "Set "+EnvAgeIdentity))
+	config, err = LoadConfig(sourceDir, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if err := config.LoadPrivate(); err == nil {
+		t.Error("LoadPrivate() should fail when the private configuration cannot be decrypted")
+	}
+}
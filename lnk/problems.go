@@ -0,0 +1,110 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Per-path warnings and errors are printed as they happen, and scroll away in
+// a long run. Each one is also recorded here, and WriteProblemReport repeats
+// them at the end, grouped by kind with a count and one example path.
+
+// problemGroup is one kind of error or warning seen during the run
+type problemGroup struct {
+	level   string // "error" or "warning"
+	kind    string // the innermost error's message, e.g. "permission denied"
+	example string // the first path (or message) of this kind
+	count   int    // distinct problems of this kind
+	order   int    // when the kind was first seen
+}
+
+var (
+	problemGroups = map[string]*problemGroup{}
+	problemSeen   = map[string]bool{}
+)
+
+// recordProblem adds an error or warning to the end-of-run report. The same
+// message printed twice is counted once.
+func recordProblem(level string, err error) {
+	record := NewErrorRecord(level, err)
+	if problemSeen[level+"\x00"+record.Message] {
+		return
+	}
+	problemSeen[level+"\x00"+record.Message] = true
+
+	kind := problemKind(err)
+	group, ok := problemGroups[level+"\x00"+kind]
+	if !ok {
+		group = &problemGroup{level: level, kind: kind, order: len(problemGroups)}
+		// A link fails where the link goes, so the target is the example
+		var linkErr *LinkError
+		switch {
+		case errors.As(err, &linkErr):
+			group.example = ContractPath(linkErr.Target)
+		case record.Path != "":
+			group.example = ContractPath(record.Path)
+		case record.Message != kind:
+			group.example = record.Message
+		}
+		problemGroups[level+"\x00"+kind] = group
+	}
+	group.count++
+}
+
+// problemKind names what went wrong, without the path: the message of the
+// innermost wrapped error, such as "permission denied" or "not a symlink"
+func problemKind(err error) string {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return err.Error()
+		}
+		err = inner
+	}
+}
+
+// WriteProblemReport writes the errors and warnings of the run, grouped by
+// kind, errors first. It writes nothing for fewer than two problems, which
+// are still on screen, or with --output json, whose records are already
+// complete.
+func WriteProblemReport(w io.Writer) {
+	total := len(problemSeen)
+	if total < 2 || jsonErrors {
+		return
+	}
+	groups := make([]*problemGroup, 0, len(problemGroups))
+	for _, group := range problemGroups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].level != groups[j].level {
+			return groups[i].level == "error"
+		}
+		if groups[i].count != groups[j].count {
+			return groups[i].count > groups[j].count
+		}
+		return groups[i].order < groups[j].order
+	})
+
+	if ShouldSimplifyOutput() {
+		fmt.Fprintf(w, "problems: %d in %d kind(s)\n", total, len(groups))
+	} else {
+		fmt.Fprintf(w, "\n%s\n", Bold(fmt.Sprintf("Problems (%d in %d kind(s)):", total, len(groups))))
+	}
+	for _, group := range groups {
+		line := fmt.Sprintf("%dx %s", group.count, group.kind)
+		if group.example != "" {
+			line += ", e.g. " + group.example
+		}
+		switch {
+		case ShouldSimplifyOutput():
+			fmt.Fprintf(w, "%s: %s\n", group.level, line)
+		case group.level == "error":
+			fmt.Fprintf(w, "  %s %s\n", Red(FailureIcon), line)
+		default:
+			fmt.Fprintf(w, "  %s %s\n", Yellow(WarningIcon), line)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package lnk
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestWriteProblemReport(t *testing.T) {
+	resetProblems := func() {
+		problemGroups = map[string]*problemGroup{}
+		problemSeen = map[string]bool{}
+	}
+	resetProblems()
+	t.Cleanup(resetProblems)
+
+	denied := func(path string) error {
+		return NewPathErrorWithHint("create symlink", path,
+			&fs.PathError{Op: "symlink", Path: path, Err: syscall.EACCES}, "Check permissions")
+	}
+	captureOutput(t, func() {
+		PrintWarningWithHint(denied("/home/u/.zshrc"))
+		PrintWarningWithHint(denied("/home/u/.bashrc"))
+		PrintWarningWithHint(denied("/home/u/.zshrc")) // printed twice, counted once
+		PrintWarningWithHint(NewPathErrorWithHint("remove", "/home/u/.vimrc", ErrNotSymlink, ""))
+		PrintError("failed to create 2 symlink(s)")
+	})
+
+	var report strings.Builder
+	WriteProblemReport(&report)
+	want := "problems: 4 in 3 kind(s)\n" +
+		"error: 1x failed to create 2 symlink(s)\n" +
+		"warning: 2x permission denied, e.g. /home/u/.zshrc\n" +
+		"warning: 1x not a symlink, e.g. /home/u/.vimrc\n"
+	if report.String() != want {
+		t.Errorf("WriteProblemReport() =\n%s\nwant\n%s", report.String(), want)
+	}
+
+	// A single problem is still on screen
+	resetProblems()
+	captureOutput(t, func() { PrintWarning("one thing") })
+	report.Reset()
+	WriteProblemReport(&report)
+	if report.String() != "" {
+		t.Errorf("WriteProblemReport() with one problem = %q, want nothing", report.String())
+	}
+
+	if got := problemKind(errors.New("plain")); got != "plain" {
+		t.Errorf("problemKind() = %q, want the message of an unwrapped error", got)
+	}
+}
@@ -0,0 +1,184 @@
+package lnk
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// The performance profile (--profile-perf) records how long each command
+// phase took (from TracePhase) and how many file system operations of each
+// kind ran and how long they took, and prints it to stderr when the command
+// finishes.
+
+// perf is the profile being recorded; nil when profiling is off
+var perf *perfProfile
+
+type perfProfile struct {
+	started time.Time
+	phases  []perfEntry
+	ops     map[string]*perfEntry
+}
+
+type perfEntry struct {
+	name  string
+	count int
+	total time.Duration
+}
+
+// StartProfile starts recording where time goes. File system operations are
+// timed by wrapping fsys, so call it after SetReadOnly.
+func StartProfile() {
+	perf = &perfProfile{started: time.Now(), ops: map[string]*perfEntry{}}
+	fsys = profileFS{fsys}
+}
+
+// profilePhase records a finished phase
+func profilePhase(name string, d time.Duration) {
+	if perf != nil {
+		perf.phases = append(perf.phases, perfEntry{name: name, count: 1, total: d})
+	}
+}
+
+// profileOp records a file system operation that started at start
+func profileOp(op string, start time.Time) {
+	e, ok := perf.ops[op]
+	if !ok {
+		e = &perfEntry{name: op}
+		perf.ops[op] = e
+	}
+	e.count++
+	e.total += time.Since(start)
+}
+
+// WriteProfile prints the profile to w: total time, then each phase in the
+// order it ran, then file system operations by time spent. It does nothing
+// when no profile was started.
+func WriteProfile(w io.Writer) {
+	if perf == nil {
+		return
+	}
+	fmt.Fprintf(w, "Performance profile (total %s)\n", formatDuration(time.Since(perf.started)))
+	if len(perf.phases) > 0 {
+		fmt.Fprintln(w, "  Phases:")
+		for _, e := range perf.phases {
+			fmt.Fprintf(w, "    %-16s %10s\n", e.name, formatDuration(e.total))
+		}
+	}
+
+	ops := make([]*perfEntry, 0, len(perf.ops))
+	var calls int
+	var spent time.Duration
+	for _, e := range perf.ops {
+		ops = append(ops, e)
+		calls += e.count
+		spent += e.total
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].total > ops[j].total })
+	fmt.Fprintf(w, "  File system (%d calls, %s):\n", calls, formatDuration(spent))
+	for _, e := range ops {
+		fmt.Fprintf(w, "    %-16s %10s %8d calls\n", e.name, formatDuration(e.total), e.count)
+	}
+}
+
+// formatDuration prints d in milliseconds with two decimals
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d.Microseconds())/1000)
+}
+
+// profileFS times every call to the wrapped FileSystem
+type profileFS struct {
+	FileSystem
+}
+
+func (p profileFS) Stat(name string) (fs.FileInfo, error) {
+	defer profileOp("stat", time.Now())
+	return p.FileSystem.Stat(name)
+}
+
+func (p profileFS) Lstat(name string) (fs.FileInfo, error) {
+	defer profileOp("lstat", time.Now())
+	return p.FileSystem.Lstat(name)
+}
+
+func (p profileFS) Readlink(name string) (string, error) {
+	defer profileOp("readlink", time.Now())
+	return p.FileSystem.Readlink(name)
+}
+
+func (p profileFS) EvalSymlinks(path string) (string, error) {
+	defer profileOp("evalsymlinks", time.Now())
+	return p.FileSystem.EvalSymlinks(path)
+}
+
+func (p profileFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	defer profileOp("readdir", time.Now())
+	return p.FileSystem.ReadDir(name)
+}
+
+func (p profileFS) ReadFile(name string) ([]byte, error) {
+	defer profileOp("readfile", time.Now())
+	return p.FileSystem.ReadFile(name)
+}
+
+func (p profileFS) Open(name string) (fs.File, error) {
+	defer profileOp("open", time.Now())
+	return p.FileSystem.Open(name)
+}
+
+func (p profileFS) MkdirAll(path string, perm fs.FileMode) error {
+	defer profileOp("mkdirall", time.Now())
+	return p.FileSystem.MkdirAll(path, perm)
+}
+
+func (p profileFS) Symlink(oldname, newname string) error {
+	defer profileOp("symlink", time.Now())
+	return p.FileSystem.Symlink(oldname, newname)
+}
+
+func (p profileFS) Remove(name string) error {
+	defer profileOp("remove", time.Now())
+	return p.FileSystem.Remove(name)
+}
+
+func (p profileFS) RemoveAll(path string) error {
+	defer profileOp("removeall", time.Now())
+	return p.FileSystem.RemoveAll(path)
+}
+
+func (p profileFS) Rename(oldpath, newpath string) error {
+	defer profileOp("rename", time.Now())
+	return p.FileSystem.Rename(oldpath, newpath)
+}
+
+func (p profileFS) Chmod(name string, mode fs.FileMode) error {
+	defer profileOp("chmod", time.Now())
+	return p.FileSystem.Chmod(name, mode)
+}
+
+func (p profileFS) Lchown(name string, uid, gid int) error {
+	defer profileOp("lchown", time.Now())
+	return p.FileSystem.Lchown(name, uid, gid)
+}
+
+func (p profileFS) Create(name string) (WritableFile, error) {
+	defer profileOp("create", time.Now())
+	return p.FileSystem.Create(name)
+}
+
+func (p profileFS) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+	defer profileOp("openfile", time.Now())
+	return p.FileSystem.OpenFile(name, flag, perm)
+}
+
+func (p profileFS) CreateTemp(dir, pattern string) (WritableFile, error) {
+	defer profileOp("createtemp", time.Now())
+	return p.FileSystem.CreateTemp(dir, pattern)
+}
+
+func (p profileFS) MkdirTemp(dir, pattern string) (string, error) {
+	defer profileOp("mkdirtemp", time.Now())
+	return p.FileSystem.MkdirTemp(dir, pattern)
+}
@@ -0,0 +1,34 @@
+package lnk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	m := useMemFS(t)
+	m.writeFile(t, "/src/.bashrc", "bash")
+	StartProfile()
+	t.Cleanup(func() { perf = nil })
+
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(LinkOptions{SourceDir: "/src", TargetDir: "/home/u"}); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	var buf bytes.Buffer
+	WriteProfile(&buf)
+	ContainsOutput(t, buf.String(), "Performance profile (total ", "Phases:", "plan", "execute", "File system (", "symlink", "1 calls")
+	if perf.ops["lstat"] == nil || perf.ops["lstat"].count == 0 {
+		t.Errorf("lstat calls were not counted: %+v", perf.ops)
+	}
+}
+
+func TestWriteProfileOff(t *testing.T) {
+	var buf bytes.Buffer
+	WriteProfile(&buf)
+	if buf.Len() != 0 {
+		t.Errorf("WriteProfile() without StartProfile wrote %q", buf.String())
+	}
+}
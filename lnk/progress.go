@@ -18,6 +18,7 @@ type ProgressIndicator struct {
 	active     bool
 	spinner    int
 	done       chan struct{}
+	bytes      bool // total and current are byte counts
 }
 
 var spinnerChars = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
@@ -55,7 +56,7 @@ func (p *ProgressIndicator) Start() {
 			p.mu.Unlock()
 
 			// Clear line and print spinner
-			fmt.Printf("\r%s %s %s", spinner, p.message, strings.Repeat(" ", 20))
+			fmt.Fprintf(stdout(), "\r%s %s %s", spinner, p.message, strings.Repeat(" ", 20))
 			time.Sleep(100 * time.Millisecond)
 		}
 	}()
@@ -78,7 +79,7 @@ func (p *ProgressIndicator) Stop() {
 	}
 
 	// Clear the line
-	fmt.Printf("\r%s\r", strings.Repeat(" ", 80))
+	fmt.Fprintf(stdout(), "\r%s\r", strings.Repeat(" ", 80))
 }
 
 // SetTotal sets the total number of items for determinate progress
@@ -88,6 +89,15 @@ func (p *ProgressIndicator) SetTotal(total int) {
 	p.total = total
 }
 
+// SetByteTotal sets the total number of bytes for determinate progress;
+// counts are then shown as sizes
+func (p *ProgressIndicator) SetByteTotal(total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = int(total)
+	p.bytes = true
+}
+
 // Update updates the progress with current count
 func (p *ProgressIndicator) Update(current int) {
 	if !isTerminal() {
@@ -107,7 +117,10 @@ func (p *ProgressIndicator) Update(current int) {
 
 	// Calculate progress
 	var progressStr string
-	if p.total > 0 {
+	if p.total > 0 && p.bytes {
+		percentage := float64(p.current) * 100 / float64(p.total)
+		progressStr = fmt.Sprintf(" (%s of %s, %.0f%%)", formatSize(int64(p.current)), formatSize(int64(p.total)), percentage)
+	} else if p.total > 0 {
 		percentage := float64(p.current) * 100 / float64(p.total)
 		progressStr = fmt.Sprintf(" (%d/%d, %.0f%%)", p.current, p.total, percentage)
 	} else if p.current > 0 {
@@ -119,7 +132,7 @@ func (p *ProgressIndicator) Update(current int) {
 	p.mu.Unlock()
 
 	// Clear line and print progress
-	fmt.Printf("\r%s %s%s%s", spinner, p.message, progressStr, strings.Repeat(" ", 20))
+	fmt.Fprintf(stdout(), "\r%s %s%s%s", spinner, p.message, progressStr, strings.Repeat(" ", 20))
 }
 
 // ShowProgress runs a function with a progress indicator
@@ -0,0 +1,173 @@
+package lnk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Interactive prompts are written to stderr so stdout stays machine-readable.
+// Both hooks are package variables so tests can simulate a user at a terminal.
+var (
+	// promptReader is where interactive answers are read from
+	promptReader = bufio.NewReader(os.Stdin)
+
+	// canPrompt reports whether the user can answer interactive prompts
+	canPrompt = isInputTerminal
+)
+
+// assumeYes answers yes to confirmation prompts without asking (--yes, LNK_YES)
+var assumeYes bool
+
+// SetAssumeYes sets whether confirmation prompts are answered yes automatically
+func SetAssumeYes(yes bool) {
+	assumeYes = yes
+}
+
+// confirm asks a yes/no question and reports whether the answer was yes.
+// With --yes it returns true without asking; without a terminal to ask at,
+// it returns false.
+func confirm(question string) bool {
+	if assumeYes {
+		PrintVerbose("%s yes (--yes)", question)
+		return true
+	}
+	if !canPrompt() {
+		return false
+	}
+	answer, err := readChoice(question)
+	return err == nil && (answer == "y" || answer == "yes")
+}
+
+// readChoice prints question to stderr and returns the user's trimmed,
+// lower-cased answer. Returns io.EOF if input ends before an answer is given.
+func readChoice(question string) (string, error) {
+	answer, err := readLine(question)
+	return strings.ToLower(answer), err
+}
+
+// readLine prints question to stderr and returns the user's trimmed answer
+// with its case preserved. Returns io.EOF if input ends before an answer is given.
+func readLine(question string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s ", question)
+	line, err := promptReader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Fprintln(os.Stderr)
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// readSecret is readLine without echoing the answer, for passwords and
+// tokens. Echo is turned off with stty when stdin is a terminal that has it.
+func readSecret(question string) (string, error) {
+	if !isInputTerminal() || !hasCommand("stty") {
+		return readLine(question)
+	}
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		return cmd.Run()
+	}
+	if err := stty("-echo"); err != nil {
+		return readLine(question)
+	}
+	defer func() {
+		stty("echo")
+		fmt.Fprintln(os.Stderr)
+	}()
+	return readLine(question)
+}
+
+// errNoTerminal is returned by chooseItems when there is no terminal to ask at
+var errNoTerminal = WithHint(fmt.Errorf("--interactive needs a terminal to choose from"),
+	"Run the command in a terminal, or leave out --interactive")
+
+// chooseItems shows items as a numbered checklist with every item selected and
+// lets the user toggle items by number until they press Enter. It returns the
+// indexes still selected, in order; nothing is selected if input ends. verb
+// names what happens to the selected items in the prompt, e.g. "prune".
+func chooseItems(items []string, verb string) ([]int, error) {
+	if !canPrompt() {
+		return nil, errNoTerminal
+	}
+	selected := make([]bool, len(items))
+	for i := range selected {
+		selected[i] = true
+	}
+	for {
+		for i, item := range items {
+			mark := "[ ]"
+			if selected[i] {
+				mark = "[" + Green("x") + "]"
+			}
+			fmt.Fprintf(os.Stderr, "  %s %3d) %s\n", mark, i+1, item)
+		}
+		answer, err := readChoice(fmt.Sprintf("Toggle items to %s (e.g. 2,4-6, 'all', 'none'), or Enter to continue:", verb))
+		if err != nil {
+			return nil, nil
+		}
+		switch answer {
+		case "":
+			var chosen []int
+			for i, ok := range selected {
+				if ok {
+					chosen = append(chosen, i)
+				}
+			}
+			return chosen, nil
+		case "a", "all", "none":
+			for i := range selected {
+				selected[i] = answer != "none"
+			}
+			continue
+		}
+		toggle, err := parseSelection(answer, len(items))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", Yellow(WarningIcon), err)
+			continue
+		}
+		for _, i := range toggle {
+			selected[i] = !selected[i]
+		}
+	}
+}
+
+// pick returns the items at indexes
+func pick[T any](items []T, indexes []int) []T {
+	picked := make([]T, 0, len(indexes))
+	for _, i := range indexes {
+		picked = append(picked, items[i])
+	}
+	return picked
+}
+
+// printFileDiff writes a unified diff between two files to stderr.
+func printFileDiff(oldPath, newPath string) {
+	writeFileDiff(os.Stderr, oldPath, newPath)
+}
+
+// writeFileDiff writes a unified diff between two files to w. Uses the system
+// diff tool when available; otherwise reports sizes only.
+func writeFileDiff(w io.Writer, oldPath, newPath string) {
+	if diffPath, err := exec.LookPath("diff"); err == nil {
+		cmd := exec.Command(diffPath, "-u", oldPath, newPath)
+		cmd.Stdout = w
+		cmd.Stderr = w
+		// diff exits 1 when files differ; that is expected here
+		_ = cmd.Run()
+		return
+	}
+
+	oldInfo, oldErr := os.Stat(oldPath)
+	newInfo, newErr := os.Stat(newPath)
+	if oldErr != nil || newErr != nil {
+		fmt.Fprintln(w, "  (unable to compare files)")
+		return
+	}
+	fmt.Fprintf(w, "  %s: %d bytes\n  %s: %d bytes\n",
+		ContractPath(oldPath), oldInfo.Size(), ContractPath(newPath), newInfo.Size())
+}
@@ -0,0 +1,98 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PromptStatusOptions holds configuration for prompt-status
+type PromptStatusOptions struct {
+	SourceDir string // source directory whose links to check
+	TargetDir string // where links are created (default: ~)
+	Shell     string // shell the token is embedded in (default: ShellPlain)
+}
+
+// PromptStatus prints a single token for shell prompts: "lnk:✓" when every link
+// lnk recorded for the source directory is intact, "lnk:N!" when N of them are
+// missing, replaced, pointing elsewhere, or broken, and "lnk:?" when no links
+// are recorded. Missing ephemeral links are expected after a reboot and do not
+// count. Only the manifest's records are checked, so neither the home
+// directory nor the source directory is walked.
+func PromptStatus(opts PromptStatusOptions) error {
+	paths, err := ResolvePaths(opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
+
+	token, color := "lnk:?", ColorYellow
+	m, err := LoadManifest(targetDir)
+	switch {
+	case err != nil:
+		PrintVerbose("Failed to load manifest: %v", err)
+	case !m.TracksLinks(sourceDir):
+		PrintVerbose("No links recorded for %s; run 'lnk create' to record them", ContractPath(sourceDir))
+	default:
+		drifted := 0
+		for _, l := range m.Links {
+			if l.Source != sourceDir {
+				continue
+			}
+			if _, err := os.Lstat(l.Path); err != nil && l.Ephemeral {
+				PrintVerbose("Ephemeral link not present: %s", ContractPath(l.Path))
+				continue
+			}
+			if linkDrifted(l.Path, sourceDir) {
+				PrintVerbose("Drifted: %s", ContractPath(l.Path))
+				drifted++
+			}
+		}
+		token, color = "lnk:"+SuccessIcon, ColorGreen
+		if drifted > 0 {
+			token, color = fmt.Sprintf("lnk:%d%s", drifted, WarningIcon), ColorRed
+		}
+	}
+
+	fmt.Println(promptColor(token, color, opts.Shell))
+	return nil
+}
+
+// linkDrifted reports whether the recorded link at path is no longer a working
+// symlink into sourceDir
+func linkDrifted(path, sourceDir string) bool {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return true
+	}
+	dest, err := os.Readlink(path)
+	if err != nil {
+		return true
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(filepath.Dir(path), dest)
+	}
+	if !isWithin(dest, sourceDir) {
+		return true
+	}
+	_, err = os.Stat(path)
+	return err != nil
+}
+
+// promptColor colors token for a prompt. Prompts capture lnk's output, so
+// color is used unless --no-color or NO_COLOR says otherwise, whether or not
+// stdout is a terminal. Escapes are marked non-printing for bash and zsh so
+// the shell measures the prompt width correctly.
+func promptColor(token, color, shell string) string {
+	if colorDisabled() {
+		return token
+	}
+	start, end := color, ColorReset
+	switch shell {
+	case ShellBash:
+		start, end = "\001"+start+"\002", "\001"+end+"\002"
+	case ShellZsh:
+		start, end = "%{"+start+"%}", "%{"+end+"%}"
+	}
+	return start + token + end
+}
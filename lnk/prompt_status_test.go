@@ -0,0 +1,93 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptStatus(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := PromptStatusOptions{SourceDir: sourceDir, TargetDir: targetDir}
+
+	promptStatus := func() string {
+		t.Helper()
+		return strings.TrimSpace(CaptureOutput(t, func() {
+			if err := PromptStatus(opts); err != nil {
+				t.Fatalf("PromptStatus() error = %v", err)
+			}
+		}))
+	}
+
+	if got := promptStatus(); got != "lnk:?" {
+		t.Errorf("before create: got %q, want lnk:?", got)
+	}
+
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "work"}}); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	if got := promptStatus(); got != "lnk:"+SuccessIcon {
+		t.Errorf("after create: got %q, want lnk:%s", got, SuccessIcon)
+	}
+
+	// One link replaced by a real file, one pointing at a deleted source
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	os.Remove(bashrc)
+	createTestFile(t, bashrc, "local edit")
+	os.Remove(filepath.Join(sourceDir, "work", ".gitconfig"))
+	if got := promptStatus(); got != "lnk:2"+WarningIcon {
+		t.Errorf("after drift: got %q, want lnk:2%s", got, WarningIcon)
+	}
+}
+
+func TestPromptColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	tests := []struct {
+		shell string
+		want  string
+	}{
+		{ShellPlain, ColorGreen + "lnk:x" + ColorReset},
+		{ShellBash, "\001" + ColorGreen + "\002lnk:x\001" + ColorReset + "\002"},
+		{ShellZsh, "%{" + ColorGreen + "%}lnk:x%{" + ColorReset + "%}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			if got := promptColor("lnk:x", ColorGreen, tt.shell); got != tt.want {
+				t.Errorf("promptColor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if got := promptColor("lnk:x", ColorGreen, ShellZsh); got != "lnk:x" {
+		t.Errorf("with NO_COLOR: promptColor() = %q, want plain token", got)
+	}
+}
+
+func TestPromptStatusIgnoresMissingEphemeralLinks(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	sourceDir, targetDir := setupPackagesTest(t)
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"target": "runtime"}`)
+
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "work"}}); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	os.Remove(filepath.Join(runtimeDir, ".gitconfig"))
+
+	got := strings.TrimSpace(CaptureOutput(t, func() {
+		if err := PromptStatus(PromptStatusOptions{SourceDir: sourceDir, TargetDir: targetDir}); err != nil {
+			t.Fatalf("PromptStatus() error = %v", err)
+		}
+	}))
+	if got != "lnk:"+SuccessIcon {
+		t.Errorf("got %q, want lnk:%s", got, SuccessIcon)
+	}
+}
@@ -0,0 +1,154 @@
+package lnk
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Protected targets are a last line of defense: target paths lnk never
+// creates, removes, or overwrites, whatever the mappings say. A mapping that
+// plans a link onto one fails during planning, and every file system change
+// lnk makes is checked again at execution by wrapping fsys, so a path missed
+// by planning is still refused.
+
+// protection matches protected targets, or is nil before ProtectTargets
+var protection *localOnlyMatcher
+
+// getBuiltInProtectedTargets returns the target paths protected on every
+// machine. A ! pattern in .lnkprotected lifts one.
+func getBuiltInProtectedTargets() []string {
+	return []string{
+		".ssh/authorized_keys",
+		"Library/Keychains/**",
+	}
+}
+
+// LoadProtectedFile loads protected target patterns from a .lnkprotected file
+// in the source directory. The format is that of .lnklocal: gitignore-style
+// patterns relative to the home directory, optionally written with ~/.
+func LoadProtectedFile(sourceDir string) ([]string, error) {
+	protectedFilePath := filepath.Join(sourceDir, ProtectedFileName)
+	if _, err := os.Stat(protectedFilePath); os.IsNotExist(err) {
+		PrintVerbose("No .lnkprotected file found at: %s", protectedFilePath)
+		return nil, nil
+	}
+
+	patterns, err := parseIgnoreFile(protectedFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .lnkprotected: %w", err)
+	}
+
+	PrintVerbose("Loaded %d protected target patterns from .lnkprotected", len(patterns))
+	return patterns, nil
+}
+
+// ProtectTargets makes the paths under targetDir matching patterns protected
+// for the rest of the run: planning refuses them, and fsys is wrapped so
+// changes to them return ErrProtected. Calling it again replaces the patterns.
+func ProtectTargets(targetDir string, patterns []string) {
+	protection = newLocalOnlyMatcher(targetDir, patterns)
+	if _, ok := fsys.(protectedFS); !ok {
+		fsys = protectedFS{fsys}
+	}
+}
+
+// checkProtected returns an ErrProtected PathError for op on path when path
+// is a protected target
+func checkProtected(op, path string) error {
+	if protection == nil {
+		return nil
+	}
+	pattern, ok := protection.matches(path)
+	if !ok {
+		return nil
+	}
+	return NewPathErrorWithHint(op, path, ErrProtected,
+		fmt.Sprintf("%s matches the protected target %q; lnk never changes it. Remove the mapping that reaches it, or add !%s to %s if lnk should manage it",
+			ContractPath(path), pattern, pattern, ProtectedFileName))
+}
+
+// checkProtectedLinks fails planning when a planned link's target is protected
+func checkProtectedLinks(links []PlannedLink) error {
+	for _, link := range links {
+		if err := checkProtected("link", link.Target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protectedFS passes everything through to the wrapped FileSystem except
+// changes to protected targets, which return ErrProtected
+type protectedFS struct {
+	FileSystem
+}
+
+func (p protectedFS) MkdirAll(path string, perm fs.FileMode) error {
+	if err := checkProtected("create directory", path); err != nil {
+		return err
+	}
+	return p.FileSystem.MkdirAll(path, perm)
+}
+
+func (p protectedFS) Symlink(oldname, newname string) error {
+	if err := checkProtected("create symlink", newname); err != nil {
+		return err
+	}
+	return p.FileSystem.Symlink(oldname, newname)
+}
+
+func (p protectedFS) Remove(name string) error {
+	if err := checkProtected("remove", name); err != nil {
+		return err
+	}
+	return p.FileSystem.Remove(name)
+}
+
+func (p protectedFS) RemoveAll(path string) error {
+	if err := checkProtected("remove", path); err != nil {
+		return err
+	}
+	return p.FileSystem.RemoveAll(path)
+}
+
+func (p protectedFS) Rename(oldpath, newpath string) error {
+	if err := checkProtected("rename", oldpath); err != nil {
+		return err
+	}
+	if err := checkProtected("rename", newpath); err != nil {
+		return err
+	}
+	return p.FileSystem.Rename(oldpath, newpath)
+}
+
+func (p protectedFS) Chmod(name string, mode fs.FileMode) error {
+	if err := checkProtected("change mode", name); err != nil {
+		return err
+	}
+	return p.FileSystem.Chmod(name, mode)
+}
+
+func (p protectedFS) Lchown(name string, uid, gid int) error {
+	if err := checkProtected("change owner", name); err != nil {
+		return err
+	}
+	return p.FileSystem.Lchown(name, uid, gid)
+}
+
+func (p protectedFS) Create(name string) (WritableFile, error) {
+	if err := checkProtected("create", name); err != nil {
+		return nil, err
+	}
+	return p.FileSystem.Create(name)
+}
+
+func (p protectedFS) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		if err := checkProtected("open for writing", name); err != nil {
+			return nil, err
+		}
+	}
+	return p.FileSystem.OpenFile(name, flag, perm)
+}
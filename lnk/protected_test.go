@@ -0,0 +1,89 @@
+package lnk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// protectTargets protects patterns under targetDir for the rest of the test
+func protectTargets(t *testing.T, targetDir string, patterns []string) {
+	t.Helper()
+	saved := fsys
+	ProtectTargets(targetDir, patterns)
+	t.Cleanup(func() {
+		fsys = saved
+		protection = nil
+	})
+}
+
+func TestProtectedTargets(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".ssh", "authorized_keys"), "ssh-ed25519 AAAA")
+	protectTargets(t, targetDir, append(getBuiltInProtectedTargets(), "~/.config/nvim/"))
+
+	// Planning refuses a mapping that reaches a protected target
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}}
+	var err error
+	CaptureOutput(t, func() {
+		_, err = CreateLinks(opts)
+	})
+	if !errors.Is(err, ErrProtected) || GetErrorHint(err) == "" {
+		t.Fatalf("CreateLinks() error = %v, want ErrProtected with a hint", err)
+	}
+	if record := NewErrorRecord("error", err); record.Code != CodeProtected {
+		t.Errorf("error code = %q, want %q", record.Code, CodeProtected)
+	}
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+
+	// Execution refuses changes planning did not catch
+	keys := filepath.Join(targetDir, ".ssh", "authorized_keys")
+	createTestFile(t, keys, "ssh-ed25519 BBBB")
+	if err := fsys.Remove(keys); !errors.Is(err, ErrProtected) {
+		t.Errorf("Remove() error = %v, want ErrProtected", err)
+	}
+	if err := fsys.Symlink(filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua"),
+		filepath.Join(targetDir, ".config", "nvim", "init.lua")); !errors.Is(err, ErrProtected) {
+		t.Errorf("Symlink() error = %v, want ErrProtected", err)
+	}
+	if err := fsys.Rename(keys, keys+".bak"); !errors.Is(err, ErrProtected) {
+		t.Errorf("Rename() error = %v, want ErrProtected", err)
+	}
+	if _, err := os.Stat(keys); err != nil {
+		t.Errorf("protected file should be left alone: %v", err)
+	}
+
+	// Other targets are unaffected
+	if err := fsys.Symlink(filepath.Join(sourceDir, "shell", ".bashrc"), filepath.Join(targetDir, ".bashrc")); err != nil {
+		t.Errorf("Symlink() error = %v", err)
+	}
+}
+
+func TestProtectedTargetsNegation(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".ssh", "authorized_keys"), "ssh-ed25519 AAAA")
+	protectTargets(t, targetDir, append(getBuiltInProtectedTargets(), "!.ssh/authorized_keys"))
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	assertSymlink(t, filepath.Join(targetDir, ".ssh", "authorized_keys"),
+		filepath.Join(sourceDir, "shell", ".ssh", "authorized_keys"))
+}
+
+func TestLoadProtectedFile(t *testing.T) {
+	sourceDir := t.TempDir()
+	patterns, err := LoadProtectedFile(sourceDir)
+	if err != nil || patterns != nil {
+		t.Fatalf("LoadProtectedFile() = %v, %v, want nil for a missing file", patterns, err)
+	}
+	createTestFile(t, filepath.Join(sourceDir, ProtectedFileName), "# never touch\n~/.netrc\n.gnupg/**\n")
+	patterns, err = LoadProtectedFile(sourceDir)
+	if err != nil || len(patterns) != 2 || patterns[0] != "~/.netrc" {
+		t.Errorf("LoadProtectedFile() = %v, %v", patterns, err)
+	}
+}
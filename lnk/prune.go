@@ -3,19 +3,47 @@ package lnk
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 )
 
-// Prune removes broken symlinks managed by the source directory
-func Prune(opts LinkOptions) error {
+// pruneReasonGitDeleted marks links whose source git deleted but that still
+// exist on disk; broken links are pruned with the reason they are broken
+const pruneReasonGitDeleted = "removed from git"
+
+// pruneCandidate is a managed link selected for pruning
+type pruneCandidate struct {
+	link    ManagedLink
+	reason  string // why the link is pruned
+	mapping string // top-level entry of the link's source within SourceDir ("." for top-level files)
+}
+
+// Prune removes broken symlinks managed by the source directory, along with
+// symlinks whose source file was deleted from git but still exists on disk,
+// and returns what it decided for each. It prints each link as it goes;
+// PrintPruneSummary prints the summary.
+func Prune(opts LinkOptions) (*PruneResult, error) {
+	r, done := collect()
+	defer done()
+	err := prune(opts)
+	return &r.prune, err
+}
+
+// prune removes links for Prune, recording each decision
+func prune(opts LinkOptions) error {
 	PrintCommandHeader("Pruning Broken Symlinks")
 
 	// Expand and validate paths
-	paths, err := ResolvePaths(opts.SourceDir, opts.TargetDir)
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
 	if err != nil {
 		return err
 	}
 	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
 
+	scopes, err := normalizeScopes(opts.Scopes)
+	if err != nil {
+		return err
+	}
+
 	// Find all managed links for the source directory
 	PrintVerbose("Searching for managed links in %s", targetDir)
 	links, err := FindManagedLinks(targetDir, []string{sourceDir})
@@ -23,61 +51,196 @@ func Prune(opts LinkOptions) error {
 		return fmt.Errorf("failed to find managed links: %w", err)
 	}
 
-	// Filter to only broken links
-	var brokenLinks []ManagedLink
+	// Links into sources removed from git since the link was made are pruned
+	// even though the file remains on disk. Without a recorded HEAD only
+	// removals staged in the index count.
+	gitDeleted := map[string]map[string]bool{"": gitDeletedSources(sourceDir, "")}
+	var manifest *Manifest
+	if gitDeleted[""] == nil {
+		PrintVerbose("Git information unavailable for %s; only missing sources are pruned", ContractPath(sourceDir))
+	} else if manifest, err = LoadManifest(targetDir); err != nil {
+		PrintVerbose("Could not read the manifest; only staged git removals are pruned: %v", err)
+	}
+	removedFromGit := func(link ManagedLink, rel string) bool {
+		head := ""
+		if manifest != nil {
+			head = manifest.LinkHead(link.Path)
+		}
+		deleted, ok := gitDeleted[head]
+		if !ok {
+			if deleted = gitDeletedSources(sourceDir, head); deleted == nil {
+				deleted = gitDeleted[""]
+			}
+			gitDeleted[head] = deleted
+		}
+		return deleted[filepath.ToSlash(rel)]
+	}
+
+	// Select broken and git-deleted links within scope. Links whose source
+	// could not be checked may still work, so they are reported, not pruned.
+	var candidates []pruneCandidate
+	var unchecked int
 	for _, link := range links {
-		if link.IsBroken {
-			brokenLinks = append(brokenLinks, link)
+		rel := linkSourceRel(link, sourceDir)
+		if !inScopes(rel, scopes) {
+			continue
+		}
+		candidate := pruneCandidate{link: link, mapping: topLevelEntry(rel)}
+		switch {
+		case link.Broken == BrokenPermissionDenied:
+			PrintWarningWithHint(NewPathErrorWithHint("check", link.Path,
+				fmt.Errorf("permission denied reading its source; not pruned"),
+				brokenHint(link.Broken, sourceDir)))
+			recordPruned(link, candidate.mapping, ResultSkipped, "permission denied reading its source", nil)
+			unchecked++
+			continue
+		case link.Broken == BrokenSymlinkLoop:
+			PrintWarningWithHint(NewPathErrorWithHint("check", link.Path,
+				fmt.Errorf("its source is a symlink loop; not pruned"),
+				brokenHint(link.Broken, sourceDir)))
+			recordPruned(link, candidate.mapping, ResultSkipped, "its source is a symlink loop", nil)
+			unchecked++
+			continue
+		case link.IsBroken:
+			candidate.reason = describeBroken(link.Broken)
+		case gitDeleted[""] != nil && removedFromGit(link, rel):
+			candidate.reason = pruneReasonGitDeleted
+		default:
+			continue
 		}
+		candidates = append(candidates, candidate)
 	}
 
-	if len(brokenLinks) == 0 {
+	SummaryCount("skipped", unchecked)
+
+	if len(candidates) == 0 {
 		PrintEmptyResult("broken symlinks")
 		return nil
 	}
 
+	// Let the user leave links out before anything changes
+	if opts.Interactive {
+		labels := make([]string, len(candidates))
+		for i, c := range candidates {
+			labels[i] = describePruneCandidate(c)
+		}
+		chosen, err := chooseItems(labels, "prune")
+		if err != nil {
+			return err
+		}
+		if candidates = pick(candidates, chosen); len(candidates) == 0 {
+			PrintInfo("Nothing selected.")
+			return nil
+		}
+		if !opts.DryRun && !confirm(fmt.Sprintf("Prune %d symlink(s)? [y/N]", len(candidates))) {
+			PrintInfo("Nothing pruned.")
+			return nil
+		}
+	}
+
 	// Show what will be pruned in dry-run mode
 	if opts.DryRun {
-		fmt.Println()
-		PrintDryRun("Would prune %d broken symlink(s):", len(brokenLinks))
-		for _, link := range brokenLinks {
-			PrintDryRun("Would prune: %s", ContractPath(link.Path))
+		fmt.Fprintln(stdout())
+		PrintDryRun("Would prune %d symlink(s):", len(candidates))
+		lines := newPathLines(len(candidates), "Would prune", PrintDryRun)
+		for _, c := range candidates {
+			lines.Add(c.link.Path, "Would prune: %s", describePruneCandidate(c))
+			recordPruned(c.link, c.mapping, ResultPlanned, c.reason, nil)
 		}
-		fmt.Println()
+		lines.Flush()
+		fmt.Fprintln(stdout())
 		PrintDryRunSummary()
 		return nil
 	}
 
 	// Track results for summary
 	var pruned, failed int
-	var removedParents []string
+	var removedParents, prunedPaths []string
 
-	// Remove the broken links
-	for _, link := range brokenLinks {
-		if err := RemoveSymlink(link.Path); err != nil {
-			PrintWarningWithHint(fmt.Errorf("Failed to prune %s: %w", ContractPath(link.Path), err))
+	// Remove the selected links
+	lines := newPathLines(len(candidates), "Pruned", PrintSuccess)
+	for _, c := range candidates {
+		if err := RemoveSymlink(c.link.Path); err != nil {
+			PrintWarningWithHint(fmt.Errorf("Failed to prune %s: %w", ContractPath(c.link.Path), err))
+			recordPruned(c.link, c.mapping, ResultFailed, "", err)
 			failed++
 			continue
 		}
-		PrintSuccess("Pruned: %s", ContractPath(link.Path))
+		lines.Add(c.link.Path, "Pruned: %s", describePruneCandidate(c))
+		recordPruned(c.link, c.mapping, ResultPruned, c.reason, nil)
 		pruned++
-		removedParents = append(removedParents, filepath.Dir(link.Path))
+		removedParents = append(removedParents, filepath.Dir(c.link.Path))
+		prunedPaths = append(prunedPaths, c.link.Path)
 	}
+	lines.Flush()
+	forgetLinks(targetDir, prunedPaths)
 
 	// Clean empty parent directories
 	CleanEmptyDirs(removedParents, targetDir)
+	SummaryCount("pruned", pruned)
+	SummaryCount("failed", failed)
 
-	// Print summary
-	if pruned > 0 {
-		PrintSummary("Pruned %d broken symlink(s) successfully", pruned)
-	}
 	if failed > 0 {
-		PrintWarning("Failed to prune %d symlink(s)", failed)
 		return fmt.Errorf("failed to prune %d symlink(s)", failed)
 	}
-	if failed == 0 {
-		PrintNextStep("status", sourceDir, "view remaining managed files")
-	}
 
 	return nil
 }
+
+// describePruneCandidate formats a candidate for display with the reason it is pruned.
+func describePruneCandidate(c pruneCandidate) string {
+	return fmt.Sprintf("%s (%s)", ContractPath(c.link.Path), c.reason)
+}
+
+// linkSourceRel returns the link's target relative to sourceDir. Link targets are
+// resolved by FindManagedLinks, so sourceDir is resolved the same way first.
+func linkSourceRel(link ManagedLink, sourceDir string) string {
+	for _, base := range []string{sourceDir, link.Source} {
+		if resolved, err := filepath.EvalSymlinks(base); err == nil {
+			if rel, err := filepath.Rel(resolved, link.Target); err == nil && !strings.HasPrefix(rel, "..") {
+				return rel
+			}
+		}
+		if rel, err := filepath.Rel(base, link.Target); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return filepath.Base(link.Target)
+}
+
+// topLevelEntry returns the first component of a relative path, or "." when the
+// path has a single component (a file at the top of the source directory).
+func topLevelEntry(rel string) string {
+	if i := strings.IndexRune(rel, filepath.Separator); i > 0 {
+		return rel[:i]
+	}
+	return "."
+}
+
+// normalizeScopes cleans scope subdirectories, rejecting paths outside the source directory.
+func normalizeScopes(scopes []string) ([]string, error) {
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		clean := filepath.Clean(scope)
+		if filepath.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return nil, NewValidationErrorWithHint("source", scope,
+				"must be a subdirectory of the source directory",
+				"Use a path relative to <source-dir>, e.g.: --source home")
+		}
+		normalized = append(normalized, clean)
+	}
+	return normalized, nil
+}
+
+// inScopes reports whether rel lies within any scope. An empty scope list matches everything.
+func inScopes(rel string, scopes []string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		if rel == scope || strings.HasPrefix(rel, scope+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
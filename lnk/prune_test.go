@@ -1,7 +1,10 @@
 package lnk
 
 import (
+	"bufio"
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -244,7 +247,7 @@ func TestPrune(t *testing.T) {
 
 			configRepo, opts := tt.setup(t, tmpDir)
 
-			err := Prune(opts)
+			_, err := Prune(opts)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Prune() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -312,17 +315,150 @@ func TestPruneNextStep(t *testing.T) {
 	}
 
 	stdout, _ := captureOutput(t, func() {
-		if err := Prune(opts); err != nil {
+		result, err := Prune(opts)
+		if err != nil {
 			t.Fatalf("Prune() unexpected error: %v", err)
 		}
+		PrintPruneSummary(result, configRepo)
 	})
 
 	if !strings.Contains(stdout, "Next:") {
 		t.Errorf("Prune() should print next-step hint after successful pruning\nstdout: %q", stdout)
 	}
+	ContainsOutput(t, stdout, "Pruned: ", ".missing (source deleted)")
 }
 
 // createTestSymlink creates a symlink for testing
+func TestPruneScopes(t *testing.T) {
+	tmpDir := t.TempDir()
+	configRepo := filepath.Join(tmpDir, "repo")
+	homeDir := filepath.Join(tmpDir, "home")
+	os.MkdirAll(configRepo, 0755)
+
+	createTestSymlink(t, filepath.Join(configRepo, "home", ".missing"), filepath.Join(homeDir, ".missing"))
+	createTestSymlink(t, filepath.Join(configRepo, "private", ".secret"), filepath.Join(homeDir, ".secret"))
+
+	output := CaptureOutput(t, func() {
+		result, err := Prune(LinkOptions{SourceDir: configRepo, TargetDir: homeDir, Scopes: []string{"private"}})
+		if err != nil {
+			t.Fatalf("Prune failed: %v", err)
+		}
+		PrintPruneSummary(result, configRepo)
+	})
+
+	if _, err := os.Lstat(filepath.Join(homeDir, ".secret")); !os.IsNotExist(err) {
+		t.Error("link within scope should be pruned")
+	}
+	if _, err := os.Lstat(filepath.Join(homeDir, ".missing")); err != nil {
+		t.Error("link outside scope should be kept")
+	}
+	ContainsOutput(t, output, filepath.Join(configRepo, "private")+": 1")
+	NotContainsOutput(t, output, filepath.Join(configRepo, "home")+": ")
+}
+
+func TestPruneInvalidScope(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, scope := range []string{"../other", "/abs", "."} {
+		_, err := Prune(LinkOptions{SourceDir: tmpDir, TargetDir: tmpDir, Scopes: []string{scope}})
+		if err == nil {
+			t.Errorf("expected error for scope %q", scope)
+			continue
+		}
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("scope %q: expected ValidationError, got %T", scope, err)
+		}
+	}
+}
+
+func TestPruneSummaryByMapping(t *testing.T) {
+	tmpDir := t.TempDir()
+	configRepo := filepath.Join(tmpDir, "repo")
+	homeDir := filepath.Join(tmpDir, "home")
+	os.MkdirAll(configRepo, 0755)
+
+	createTestSymlink(t, filepath.Join(configRepo, "home", ".a"), filepath.Join(homeDir, ".a"))
+	createTestSymlink(t, filepath.Join(configRepo, "home", ".b"), filepath.Join(homeDir, ".b"))
+	createTestSymlink(t, filepath.Join(configRepo, ".top"), filepath.Join(homeDir, ".top"))
+
+	output := CaptureOutput(t, func() {
+		result, err := Prune(LinkOptions{SourceDir: configRepo, TargetDir: homeDir})
+		if err != nil {
+			t.Fatalf("Prune failed: %v", err)
+		}
+		PrintPruneSummary(result, configRepo)
+	})
+
+	ContainsOutput(t, output,
+		filepath.Join(configRepo, "home")+": 2",
+		configRepo+": 1")
+}
+
+func TestPruneGitDeletedSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tmpDir := t.TempDir()
+	configRepo := filepath.Join(tmpDir, "repo")
+	homeDir := filepath.Join(tmpDir, "home")
+
+	createTestFile(t, filepath.Join(configRepo, ".kept"), "kept")
+	createTestFile(t, filepath.Join(configRepo, ".dropped"), "dropped")
+	createTestFile(t, filepath.Join(configRepo, ".staged"), "staged")
+	createTestFile(t, filepath.Join(configRepo, ".recreated"), "recreated")
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", configRepo,
+			"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	link := func(name string) string {
+		path := filepath.Join(homeDir, name)
+		createTestSymlink(t, filepath.Join(configRepo, name), path)
+		return path
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	// .recreated was removed from git long ago and is an untracked file now
+	git("rm", "-q", "--cached", ".recreated")
+	git("commit", "-q", "-m", "drop recreated")
+
+	// Links made at this commit; only removals after it count
+	recordCreatedLinks(homeDir, configRepo, []string{link(".kept"), link(".dropped"), link(".recreated")})
+	git("rm", "-q", "--cached", ".dropped")
+	git("commit", "-q", "-m", "drop")
+
+	// A link without a recorded HEAD is pruned for a staged removal only
+	link(".staged")
+	git("rm", "-q", "--cached", ".staged")
+
+	output := CaptureOutput(t, func() {
+		if _, err := Prune(LinkOptions{SourceDir: configRepo, TargetDir: homeDir}); err != nil {
+			t.Fatalf("Prune failed: %v", err)
+		}
+	})
+
+	if _, err := os.Lstat(filepath.Join(homeDir, ".dropped")); !os.IsNotExist(err) {
+		t.Error("link to source deleted from git should be pruned")
+	}
+	if _, err := os.Lstat(filepath.Join(homeDir, ".staged")); !os.IsNotExist(err) {
+		t.Error("link to source staged for removal should be pruned")
+	}
+	if _, err := os.Lstat(filepath.Join(homeDir, ".kept")); err != nil {
+		t.Error("link to tracked source should be kept")
+	}
+	if _, err := os.Lstat(filepath.Join(homeDir, ".recreated")); err != nil {
+		t.Error("link to a file removed from git before it was linked should be kept")
+	}
+	if _, err := os.Stat(filepath.Join(configRepo, ".dropped")); err != nil {
+		t.Error("prune must not delete the source file")
+	}
+	ContainsOutput(t, output, "removed from git")
+}
+
 func createTestSymlink(t *testing.T, source, target string) {
 	t.Helper()
 
@@ -336,3 +472,39 @@ func createTestSymlink(t *testing.T, source, target string) {
 		t.Fatalf("Failed to create symlink %s -> %s: %v", target, source, err)
 	}
 }
+
+func TestPruneInteractive(t *testing.T) {
+	tmpDir := t.TempDir()
+	configRepo := filepath.Join(tmpDir, "repo")
+	homeDir := filepath.Join(tmpDir, "home")
+	os.MkdirAll(configRepo, 0755)
+	createTestSymlink(t, filepath.Join(configRepo, ".missing"), filepath.Join(homeDir, ".missing"))
+	createTestSymlink(t, filepath.Join(configRepo, ".secret"), filepath.Join(homeDir, ".secret"))
+	opts := LinkOptions{SourceDir: configRepo, TargetDir: homeDir, Interactive: true}
+
+	origCanPrompt, origReader := canPrompt, promptReader
+	t.Cleanup(func() { canPrompt, promptReader = origCanPrompt, origReader })
+
+	canPrompt = func() bool { return false }
+	if _, err := Prune(opts); err == nil || GetErrorHint(err) == "" {
+		t.Fatalf("Prune() without a terminal = %v, want an error with a hint", err)
+	}
+
+	// Untick the first link, then confirm
+	canPrompt = func() bool { return true }
+	promptReader = bufio.NewReader(strings.NewReader("1\n\ny\n"))
+	_, stderr := captureOutput(t, func() {
+		if _, err := Prune(opts); err != nil {
+			t.Fatalf("Prune() error = %v", err)
+		}
+	})
+	if !strings.Contains(stderr, "[ ]   1) "+ContractPath(filepath.Join(homeDir, ".missing"))) {
+		t.Errorf("checklist missing unticked first item:\n%s", stderr)
+	}
+	if _, err := os.Lstat(filepath.Join(homeDir, ".missing")); err != nil {
+		t.Error("unselected link should be kept")
+	}
+	if _, err := os.Lstat(filepath.Join(homeDir, ".secret")); !os.IsNotExist(err) {
+		t.Error("selected link should be pruned")
+	}
+}
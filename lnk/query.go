@@ -0,0 +1,468 @@
+package lnk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// 'lnk query --stdin-json' lets editor plugins ask lnk about the file being
+// edited: what happens to a path, what a mapping would link, and what is wrong
+// with an unsaved configuration file. Requests and responses are JSON, one per
+// line, so a plugin keeps one lnk running instead of starting one per question.
+
+// maxQueryLine is the longest request line read; validate requests carry a
+// whole configuration file
+const maxQueryLine = 16 << 20
+
+// Query methods
+const (
+	QueryExplain  = "explain"  // what lnk does with a path
+	QueryPlan     = "plan"     // the links a mapping would make
+	QueryValidate = "validate" // problems in an unsaved configuration file
+)
+
+// queryMethods lists the methods in the order help shows them
+var queryMethods = []string{QueryExplain, QueryPlan, QueryValidate}
+
+// States of a path in query results
+const (
+	QueryLinked    = "linked"       // the target is a symlink to the source
+	QueryUnlinked  = "unlinked"     // nothing is at the target yet
+	QueryRetarget  = "retarget"     // a symlink to somewhere else is at the target
+	QueryConflict  = "conflict"     // a file or directory is at the target
+	QueryLocalOnly = "local-only"   // a local-only pattern keeps lnk off the target
+	QueryCopy      = "copy-managed" // the target is a managed copy (orphan --to-copy)
+	QueryIgnored   = "ignored"      // an ignore pattern leaves the source file out
+	QueryUnmanaged = "unmanaged"    // no package or mapping plans the path
+)
+
+// QueryOptions holds configuration for answering editor queries
+type QueryOptions struct {
+	SourceDir      string    // source directory to answer for
+	TargetDir      string    // where links are created (default: ~)
+	IgnorePatterns []string  // combined ignore patterns from all sources
+	Packages       []string  // packages to plan (empty = SourceDir itself)
+	Maps           []Mapping // saved and ad-hoc mappings to plan
+	LocalOnly      []string  // target paths lnk never touches
+}
+
+// QueryRequest is one line of query input. Which field is needed depends on
+// the method.
+type QueryRequest struct {
+	ID      json.RawMessage `json:"id,omitempty"`      // echoed in the response
+	Method  string          `json:"method"`            // QueryExplain, QueryPlan, or QueryValidate
+	Path    string          `json:"path,omitempty"`    // explain: a source file or target path
+	Mapping string          `json:"mapping,omitempty"` // plan: SRC:TGT[:MODE] as --map takes it
+	File    string          `json:"file,omitempty"`    // validate: the configuration file being edited
+	Content string          `json:"content,omitempty"` // validate: its unsaved contents
+}
+
+// QueryResponse answers one QueryRequest: Result when it succeeded, Error
+// when it did not
+type QueryResponse struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Result any             `json:"result,omitempty"` // *QueryExplanation, *QueryMappingPlan, or *QueryValidation
+	Error  *ErrorRecord    `json:"error,omitempty"`
+}
+
+// QueryExplanation is what lnk does with a path. Paths are absolute.
+type QueryExplanation struct {
+	Path   string `json:"path"`             // the path asked about
+	State  string `json:"state"`            // a Query* state
+	Source string `json:"source,omitempty"` // source file of the planned link
+	Target string `json:"target,omitempty"` // where the source is linked
+	From   string `json:"from,omitempty"`   // package directory or mapping source that plans the link
+	Reason string `json:"reason,omitempty"` // the pattern that matched, or what is at the target
+}
+
+// QueryMappingPlan is what a mapping would link
+type QueryMappingPlan struct {
+	Mapping string      `json:"mapping"` // the mapping with absolute paths and its mode
+	Links   []QueryLink `json:"links"`
+	Ignored int         `json:"ignored"` // source files ignore patterns leave out
+}
+
+// QueryLink is a planned link and what is at its target
+type QueryLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	State  string `json:"state"`            // a Query* state
+	Reason string `json:"reason,omitempty"` // the pattern that matched, or what is at the target
+}
+
+// QueryValidation lists the problems in a configuration file's contents
+type QueryValidation struct {
+	File        string            `json:"file"`
+	Valid       bool              `json:"valid"` // no diagnostic is an error
+	Diagnostics []QueryDiagnostic `json:"diagnostics"`
+}
+
+// QueryDiagnostic is one problem in a configuration file
+type QueryDiagnostic struct {
+	Line int `json:"line,omitempty"` // 1-based; 0 when the problem is not on one line
+	ErrorRecord
+}
+
+// Query answers editor requests: it reads one JSON QueryRequest per line from
+// r and writes one JSON QueryResponse per line to w, in order, until r ends. A
+// request that fails gets a response with an error; only failing to read or
+// write stops Query. Nothing else is printed, so w carries only responses.
+// Configuration is read when Query starts; files an editor saves later are
+// seen by the next run.
+func Query(opts QueryOptions, r io.Reader, w io.Writer) error {
+	paths, err := ResolvePaths(opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	opts.SourceDir, opts.TargetDir = paths.SourceDir, paths.TargetDir
+
+	savedQuiet := quiet
+	quiet = true
+	defer func() { quiet = savedQuiet }()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxQueryLine)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	answered := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := enc.Encode(answerQuery(opts, line)); err != nil {
+			return fmt.Errorf("writing query response: %w", err)
+		}
+		answered++
+	}
+	SummaryCount("queries", answered)
+	if err := scanner.Err(); err != nil {
+		return WithHint(fmt.Errorf("reading query requests: %w", err),
+			fmt.Sprintf("Send one JSON request per line, each under %d MiB", maxQueryLine>>20))
+	}
+	return nil
+}
+
+// answerQuery decodes one request line and answers it
+func answerQuery(opts QueryOptions, line []byte) QueryResponse {
+	var req QueryRequest
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return queryError(req.ID, WithHint(fmt.Errorf("invalid query request: %w", err),
+			`Send one JSON object per line, e.g. {"id": 1, "method": "explain", "path": "~/.bashrc"}`))
+	}
+
+	var result any
+	var err error
+	switch req.Method {
+	case QueryExplain:
+		if req.Path == "" {
+			return queryError(req.ID, NewValidationErrorWithHint("path", "", "explain needs a path",
+				"Give the source file or target path to explain"))
+		}
+		result, err = explainPath(opts, req.Path)
+	case QueryPlan:
+		if req.Mapping == "" {
+			return queryError(req.ID, NewValidationErrorWithHint("mapping", "", "plan needs a mapping",
+				"Give the mapping as --map takes it, e.g. projects/foo/config:.config/foo"))
+		}
+		result, err = planMapping(opts, req.Mapping)
+	case QueryValidate:
+		if req.File == "" {
+			return queryError(req.ID, NewValidationErrorWithHint("file", "", "validate needs a file",
+				"Give the path of the configuration file the content is for"))
+		}
+		result, err = validateConfigBuffer(opts, req.File, req.Content)
+	default:
+		hint := fmt.Sprintf("Methods: %s", strings.Join(queryMethods, ", "))
+		if match := ClosestMatch(req.Method, queryMethods, 2); match != "" {
+			hint = fmt.Sprintf("Did you mean %s? %s", match, hint)
+		}
+		return queryError(req.ID, NewValidationErrorWithHint("method", req.Method, "unknown query method", hint))
+	}
+	if err != nil {
+		return queryError(req.ID, err)
+	}
+	return QueryResponse{ID: req.ID, Result: result}
+}
+
+// queryError is the response to a request that failed with err
+func queryError(id json.RawMessage, err error) QueryResponse {
+	record := NewErrorRecord("error", err)
+	return QueryResponse{ID: id, Error: &record}
+}
+
+// queryPath makes a path from a request absolute: ~ is the home directory, and
+// relative paths are relative to the working directory
+func queryPath(path string) (string, error) {
+	expanded, err := ExpandPath(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(expanded)
+}
+
+// planConfigured plans the links of the configured packages and of maps, like
+// create does, before local-only and copy-managed links are set aside. Each
+// package's and mapping's plan is returned too.
+func planConfigured(opts QueryOptions, maps []Mapping) ([]PlannedLink, []mappingPlan, error) {
+	packages, err := expandPackageDeps(opts.SourceDir, opts.Packages)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkgDirs, err := packageDirs(opts.SourceDir, packages)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolved, err := resolveMappings(maps, "", opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	links, _, plans, err := collectPackageLinks(pkgDirs, opts.TargetDir, opts.IgnorePatterns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("collecting files to link: %w", err)
+	}
+	mapLinks, _, mapPlans, err := collectMappedLinks(resolved, opts.IgnorePatterns, links)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(links, mapLinks...), append(plans, mapPlans...), nil
+}
+
+// explainPath reports what lnk does with path: the link it is the source or
+// target of and that link's state, or why it is not linked
+func explainPath(opts QueryOptions, path string) (*QueryExplanation, error) {
+	abs, err := queryPath(path)
+	if err != nil {
+		return nil, err
+	}
+	links, plans, err := planConfigured(opts, opts.Maps)
+	if err != nil {
+		return nil, err
+	}
+	local := newLocalOnlyMatcher(opts.TargetDir, opts.LocalOnly)
+	_, copied := filterCopyManaged(links, loadCopies(opts.TargetDir, opts.SourceDir))
+
+	sep := string(filepath.Separator)
+	for _, link := range links {
+		// A directory linked as a whole also explains the paths inside it
+		var rel string
+		switch {
+		case abs == link.Source || abs == link.Target:
+		case strings.HasPrefix(abs, link.Source+sep):
+			rel = strings.TrimPrefix(abs, link.Source)
+		case strings.HasPrefix(abs, link.Target+sep):
+			rel = strings.TrimPrefix(abs, link.Target)
+		default:
+			continue
+		}
+		e := &QueryExplanation{Path: abs, Source: link.Source + rel, Target: link.Target + rel, From: planSource(plans, link)}
+		switch pattern, ok := local.matches(link.Target); {
+		case ok:
+			e.State, e.Reason = QueryLocalOnly, pattern
+		case slices.ContainsFunc(copied, func(c PlannedLink) bool { return c.Target == link.Target }):
+			e.State = QueryCopy
+		default:
+			e.State, e.Reason = queryLinkState(link)
+		}
+		return e, nil
+	}
+
+	pm := NewPatternMatcher(opts.IgnorePatterns)
+	for _, plan := range plans {
+		rel, err := filepath.Rel(plan.source, abs)
+		if err != nil || rel == "." || strings.HasPrefix(rel, ".."+sep) || rel == ".." {
+			continue
+		}
+		if pattern, ok := pm.MatchingPattern(rel); ok {
+			return &QueryExplanation{Path: abs, State: QueryIgnored, From: plan.source, Reason: pattern}, nil
+		}
+	}
+	return &QueryExplanation{Path: abs, State: QueryUnmanaged}, nil
+}
+
+// planSource returns the package directory or mapping source whose plan has
+// link
+func planSource(plans []mappingPlan, link PlannedLink) string {
+	for _, plan := range plans {
+		if slices.ContainsFunc(plan.links, func(l PlannedLink) bool { return l.Target == link.Target }) {
+			return plan.source
+		}
+	}
+	return ""
+}
+
+// queryLinkState reports what is at link's target, as printPlanDelta sorts
+// it, with what is in the way of a link that cannot be made
+func queryLinkState(link PlannedLink) (string, string) {
+	switch plannedLinkState(link) {
+	case stateToLink:
+		return QueryUnlinked, ""
+	case stateLinked:
+		return QueryLinked, ""
+	}
+	if info, err := fsys.Lstat(link.Target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		dest, _ := fsys.Readlink(link.Target)
+		return QueryRetarget, "points to " + dest
+	}
+	return QueryConflict, describeObstacle(link.Target)
+}
+
+// planMapping plans the links spec would make alongside the configured
+// packages and the other mappings, without making any. A mapping already saved
+// is planned as itself, not as an overlap with its saved copy.
+func planMapping(opts QueryOptions, spec string) (*QueryMappingPlan, error) {
+	m, err := ParseMapping(spec)
+	if err != nil {
+		return nil, err
+	}
+	others := slices.DeleteFunc(slices.Clone(opts.Maps), m.Same)
+	planned, _, err := planConfigured(opts, others)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := resolveMappings([]Mapping{m}, "", opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return nil, err
+	}
+	links, _, plans, err := collectMappedLinks(resolved, opts.IgnorePatterns, planned)
+	if err != nil {
+		return nil, err
+	}
+
+	local := newLocalOnlyMatcher(opts.TargetDir, opts.LocalOnly)
+	plan := &QueryMappingPlan{Mapping: resolved[0].String(), Links: []QueryLink{}, Ignored: plans[0].ignored}
+	for _, link := range links {
+		l := QueryLink{Source: link.Source, Target: link.Target}
+		if pattern, ok := local.matches(link.Target); ok {
+			l.State, l.Reason = QueryLocalOnly, pattern
+		} else {
+			l.State, l.Reason = queryLinkState(link)
+		}
+		plan.Links = append(plan.Links, l)
+	}
+	return plan, nil
+}
+
+// validateConfigBuffer checks content as the configuration file file would be
+// read, without reading or writing file. The file's name says what it is; its
+// directory is the source directory (the package directory for .lnkrequires).
+func validateConfigBuffer(opts QueryOptions, file, content string) (*QueryValidation, error) {
+	path, err := queryPath(file)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	data := []byte(content)
+
+	var problems []QueryDiagnostic
+	add := func(line int, err error) {
+		if err != nil {
+			problems = append(problems, QueryDiagnostic{Line: line, ErrorRecord: NewErrorRecord("error", err)})
+		}
+	}
+	switch filepath.Base(path) {
+	case MapsFileName:
+		for _, entry := range configLines(content) {
+			m, err := ParseMapping(entry.text)
+			if err == nil {
+				_, err = resolveMappings([]Mapping{m}, "", dir, opts.TargetDir)
+			}
+			add(entry.line, err)
+		}
+	case PackagesFileName:
+		for _, entry := range configLines(content) {
+			add(entry.line, checkPackageEntry(dir, path, entry.text))
+		}
+	case RequiresFileName:
+		for _, entry := range configLines(content) {
+			add(entry.line, checkPackageEntry(filepath.Dir(dir), path, entry.text))
+		}
+	case PackageInfoFileName:
+		if _, err := parsePackageInfo(path, data); err != nil {
+			add(jsonErrorLine(content, err), err)
+			break
+		}
+		// Unknown keys are warnings, as loading the file reports them, unless
+		// --strict-config makes them errors
+		var raw any
+		json.Unmarshal(data, &raw)
+		for _, err := range unknownKeys(raw, reflect.TypeOf(PackageInfo{}), "", path) {
+			level := "warning"
+			if strictConfig {
+				level = "error"
+			}
+			problems = append(problems, QueryDiagnostic{ErrorRecord: NewErrorRecord(level, err)})
+		}
+	case DirsFileName:
+		_, err := parseDirPolicy(path, data)
+		add(jsonErrorLine(content, err), err)
+	case WorkflowFileName:
+		_, err := parseWorkflow(path, data)
+		add(jsonErrorLine(content, err), err)
+	case ProfilesFileName:
+		_, err := parseProfileRules(path, data)
+		add(jsonErrorLine(content, err), err)
+	case IgnoreFileName, LocalOnlyFileName, SensitiveFileName, ProtectedFileName:
+		// Every line is a pattern; there is nothing to get wrong
+	default:
+		return nil, NewValidationErrorWithHint("file", file, "not an lnk configuration file",
+			fmt.Sprintf("Configuration files are %s", strings.Join(queryConfigFiles, ", ")))
+	}
+	valid := !slices.ContainsFunc(problems, func(d QueryDiagnostic) bool { return d.Level == "error" })
+	return &QueryValidation{File: path, Valid: valid, Diagnostics: append([]QueryDiagnostic{}, problems...)}, nil
+}
+
+// queryConfigFiles lists the configuration files validate checks
+var queryConfigFiles = []string{
+	IgnoreFileName, PackagesFileName, ProfilesFileName, RequiresFileName, LocalOnlyFileName,
+	SensitiveFileName, DirsFileName, MapsFileName, WorkflowFileName, ProtectedFileName, PackageInfoFileName,
+}
+
+// configLine is an entry of a line-based configuration file
+type configLine struct {
+	line int // 1-based
+	text string
+}
+
+// configLines returns the entries of a line-based configuration file with
+// their line numbers, skipping what parseLines skips
+func configLines(content string) []configLine {
+	var entries []configLine
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, configLine{line: i + 1, text: line})
+	}
+	return entries
+}
+
+// jsonErrorLine returns the line of content a JSON syntax or type error
+// points at, or 0 for other errors
+func jsonErrorLine(content string, err error) int {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return 0
+	}
+	offset = min(offset, int64(len(content)))
+	return strings.Count(content[:offset], "\n") + 1
+}
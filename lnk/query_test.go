@@ -0,0 +1,216 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// queryReply is a QueryResponse as an editor reads it
+type queryReply struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *ErrorRecord    `json:"error"`
+}
+
+// runQuery sends requests to Query, one per line, and decodes the responses
+func runQuery(t *testing.T, opts QueryOptions, requests ...string) []queryReply {
+	t.Helper()
+	var out bytes.Buffer
+	stdout := CaptureOutput(t, func() {
+		if err := Query(opts, strings.NewReader(strings.Join(requests, "\n")+"\n"), &out); err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+	})
+	if stdout != "" {
+		t.Errorf("Query() printed %q besides its responses", stdout)
+	}
+	var replies []queryReply
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		var r queryReply
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("decoding response %q: %v", line, err)
+		}
+		replies = append(replies, r)
+	}
+	if len(replies) != len(requests) {
+		t.Fatalf("got %d responses to %d requests", len(replies), len(requests))
+	}
+	return replies
+}
+
+// queryRequest formats a request line
+func queryRequest(t *testing.T, fields map[string]any) string {
+	t.Helper()
+	data, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// decodeResult decodes a successful reply's result into v
+func decodeResult(t *testing.T, r queryReply, v any) {
+	t.Helper()
+	if r.Error != nil {
+		t.Fatalf("reply %s error = %+v", r.ID, r.Error)
+	}
+	if err := json.Unmarshal(r.Result, v); err != nil {
+		t.Fatalf("decoding result %s: %v", r.Result, err)
+	}
+}
+
+func setupQueryTest(t *testing.T) (string, string, QueryOptions) {
+	t.Helper()
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "shell", ".bashrc.swp"), "swap")
+	createTestFile(t, filepath.Join(sourceDir, "fonts", "Hack.ttf"), "font")
+	createTestFile(t, filepath.Join(sourceDir, "fonts", "Mono.ttf"), "font")
+	createTestFile(t, filepath.Join(targetDir, ".fonts", "Mono.ttf"), "older font")
+	if err := os.Symlink(filepath.Join(sourceDir, "shell", ".bashrc"), filepath.Join(targetDir, ".bashrc")); err != nil {
+		t.Fatal(err)
+	}
+	opts := QueryOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: append(getBuiltInIgnorePatterns(), "*.swp"),
+		Packages:       []string{"shell", "nvim"},
+		LocalOnly:      []string{".config/nvim/"},
+	}
+	return sourceDir, targetDir, opts
+}
+
+func TestQueryExplain(t *testing.T) {
+	sourceDir, targetDir, opts := setupQueryTest(t)
+	tests := []struct {
+		path   string
+		state  string
+		reason string
+	}{
+		{filepath.Join(sourceDir, "shell", ".bashrc"), QueryLinked, ""},
+		{filepath.Join(targetDir, ".bashrc"), QueryLinked, ""},
+		{filepath.Join(sourceDir, "shell", ".bashrc.swp"), QueryIgnored, "*.swp"},
+		{filepath.Join(targetDir, ".config", "nvim", "init.lua"), QueryLocalOnly, ".config/nvim/"},
+		{filepath.Join(sourceDir, "work", ".gitconfig"), QueryUnmanaged, ""},
+	}
+	var requests []string
+	for i, tt := range tests {
+		requests = append(requests, queryRequest(t, map[string]any{"id": i, "method": QueryExplain, "path": tt.path}))
+	}
+	for i, r := range runQuery(t, opts, requests...) {
+		var e QueryExplanation
+		decodeResult(t, r, &e)
+		if string(r.ID) != strconv.Itoa(i) || e.State != tests[i].state || e.Reason != tests[i].reason {
+			t.Errorf("explain %s = id %s, %+v; want state %s, reason %q", tests[i].path, r.ID, e, tests[i].state, tests[i].reason)
+		}
+	}
+}
+
+func TestQueryPlan(t *testing.T) {
+	sourceDir, targetDir, opts := setupQueryTest(t)
+	replies := runQuery(t, opts,
+		`{"method": "plan", "mapping": "fonts:.fonts/"}`,
+		`{"method": "plan", "mapping": "fonts:.config/nvim/"}`,
+		`{"method": "plan", "mapping": "missing:.fonts/"}`,
+	)
+
+	var plan QueryMappingPlan
+	decodeResult(t, replies[0], &plan)
+	want := map[string]string{
+		filepath.Join(targetDir, ".fonts", "Hack.ttf"): QueryUnlinked,
+		filepath.Join(targetDir, ".fonts", "Mono.ttf"): QueryConflict,
+	}
+	if len(plan.Links) != len(want) {
+		t.Fatalf("plan links = %+v, want %d", plan.Links, len(want))
+	}
+	for _, link := range plan.Links {
+		if want[link.Target] != link.State || !strings.HasPrefix(link.Source, filepath.Join(sourceDir, "fonts")) {
+			t.Errorf("planned %+v, want state %s", link, want[link.Target])
+		}
+	}
+
+	decodeResult(t, replies[1], &plan)
+	if len(plan.Links) != 2 {
+		t.Errorf("plan links = %+v, want 2", plan.Links)
+	}
+	for _, link := range plan.Links {
+		if link.State != QueryLocalOnly {
+			t.Errorf("planned %+v, want local-only", link)
+		}
+	}
+	if r := replies[2]; r.Error == nil || r.Error.Code != CodeValidation {
+		t.Errorf("plan of a missing source = %+v, want a validation error", r)
+	}
+}
+
+func TestQueryValidate(t *testing.T) {
+	sourceDir, _, opts := setupQueryTest(t)
+	replies := runQuery(t, opts,
+		queryRequest(t, map[string]any{"method": "validate", "file": filepath.Join(sourceDir, MapsFileName),
+			"content": "# fonts\nfonts:.fonts/\nno-colon\nmissing:.x/\n"}),
+		queryRequest(t, map[string]any{"method": "validate", "file": filepath.Join(sourceDir, PackagesFileName),
+			"content": "shell\nshel\n"}),
+		queryRequest(t, map[string]any{"method": "validate", "file": filepath.Join(sourceDir, "shell", PackageInfoFileName),
+			"content": "{\n  \"groups\": [\"gui\"],\n  \"overide\": []\n}\n"}),
+		queryRequest(t, map[string]any{"method": "validate", "file": filepath.Join(sourceDir, WorkflowFileName),
+			"content": "{\n  \"up\": {\"sync\": tru}\n}\n"}),
+		queryRequest(t, map[string]any{"method": "validate", "file": filepath.Join(sourceDir, "notes.txt")}),
+	)
+
+	lines := func(v QueryValidation) []int {
+		var got []int
+		for _, d := range v.Diagnostics {
+			got = append(got, d.Line)
+		}
+		return got
+	}
+	var v QueryValidation
+	decodeResult(t, replies[0], &v)
+	if v.Valid || len(v.Diagnostics) != 2 || lines(v)[0] != 3 || lines(v)[1] != 4 {
+		t.Errorf(".lnkmaps validation = %+v, want errors on lines 3 and 4", v)
+	}
+
+	decodeResult(t, replies[1], &v)
+	if v.Valid || len(v.Diagnostics) != 1 || v.Diagnostics[0].Line != 2 {
+		t.Errorf(".lnkpackages validation = %+v, want an error on line 2", v)
+	}
+
+	decodeResult(t, replies[2], &v)
+	if !v.Valid || len(v.Diagnostics) != 1 || v.Diagnostics[0].Level != "warning" {
+		t.Errorf("lnk-package.json validation = %+v, want valid with an unknown key warning", v)
+	}
+
+	decodeResult(t, replies[3], &v)
+	if v.Valid || len(v.Diagnostics) != 1 || v.Diagnostics[0].Line != 2 {
+		t.Errorf(".lnkworkflow validation = %+v, want a syntax error on line 2", v)
+	}
+
+	if r := replies[4]; r.Error == nil || !strings.Contains(r.Error.Hint, MapsFileName) {
+		t.Errorf("validate of another file = %+v, want an error listing configuration files", r)
+	}
+}
+
+func TestQueryBadRequests(t *testing.T) {
+	_, _, opts := setupQueryTest(t)
+	replies := runQuery(t, opts,
+		`not json`,
+		`{"id": "a", "method": "explian", "path": "x"}`,
+		`{"id": "b", "method": "explain"}`,
+		`{"id": "c", "method": "explain", "paths": "x"}`,
+	)
+	for i, r := range replies {
+		if r.Error == nil || r.Result != nil {
+			t.Errorf("reply %d = %+v, want an error", i, r)
+		}
+	}
+	if hint := replies[1].Error.Hint; !strings.Contains(hint, "Did you mean explain?") {
+		t.Errorf("unknown method hint = %q", hint)
+	}
+	if string(replies[2].ID) != `"b"` {
+		t.Errorf("reply id = %s, want the request's", replies[2].ID)
+	}
+}
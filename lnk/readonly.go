@@ -0,0 +1,89 @@
+package lnk
+
+import (
+	"io/fs"
+	"os"
+)
+
+// readOnly refuses every file system change (--read-only)
+var readOnly bool
+
+// SetReadOnly sets whether file system changes are refused. In read-only mode
+// fsys is wrapped so every write returns ErrReadOnly.
+func SetReadOnly(ro bool) {
+	readOnly = ro
+	if wrapped, ok := fsys.(readOnlyFS); ok && !ro {
+		fsys = wrapped.FileSystem
+	} else if !ok && ro {
+		fsys = readOnlyFS{fsys}
+	}
+}
+
+// IsReadOnly reports whether file system changes are refused
+func IsReadOnly() bool {
+	return readOnly
+}
+
+// checkWritable returns an ErrReadOnly PathError for op on path in read-only
+// mode. Commands that change files through external programs (git, defaults,
+// fc-cache, mklink) call it before running them.
+func checkWritable(op, path string) error {
+	if !readOnly {
+		return nil
+	}
+	return NewPathErrorWithHint(op, path, ErrReadOnly, "Run without --read-only to make changes")
+}
+
+// readOnlyFS passes reads through to the wrapped FileSystem and refuses every
+// write. Every change lnk makes goes through fsys, so this is the one place
+// read-only mode is enforced (see TestWritesAreGuarded).
+type readOnlyFS struct {
+	FileSystem
+}
+
+func (readOnlyFS) MkdirAll(path string, _ fs.FileMode) error {
+	return checkWritable("create directory", path)
+}
+
+func (readOnlyFS) Symlink(_, newname string) error {
+	return checkWritable("create symlink", newname)
+}
+
+func (readOnlyFS) Remove(name string) error {
+	return checkWritable("remove", name)
+}
+
+func (readOnlyFS) RemoveAll(path string) error {
+	return checkWritable("remove", path)
+}
+
+func (readOnlyFS) Rename(oldpath, _ string) error {
+	return checkWritable("rename", oldpath)
+}
+
+func (readOnlyFS) Chmod(name string, _ fs.FileMode) error {
+	return checkWritable("change mode", name)
+}
+
+func (readOnlyFS) Lchown(name string, _, _ int) error {
+	return checkWritable("change owner", name)
+}
+
+func (readOnlyFS) Create(name string) (WritableFile, error) {
+	return nil, checkWritable("create", name)
+}
+
+func (r readOnlyFS) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, checkWritable("open for writing", name)
+	}
+	return r.FileSystem.OpenFile(name, flag, perm)
+}
+
+func (readOnlyFS) CreateTemp(dir, _ string) (WritableFile, error) {
+	return nil, checkWritable("create temporary file", dir)
+}
+
+func (readOnlyFS) MkdirTemp(dir, _ string) (string, error) {
+	return "", checkWritable("create temporary directory", dir)
+}
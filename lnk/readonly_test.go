@@ -0,0 +1,87 @@
+package lnk
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadOnlyRefusesWrites(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	SetReadOnly(true)
+	t.Cleanup(func() { SetReadOnly(false) })
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}}
+	var err error
+	CaptureOutput(t, func() {
+		_, err = CreateLinks(opts)
+	})
+	if err == nil {
+		t.Fatal("CreateLinks() in read-only mode succeeded")
+	}
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+	assertNotExists(t, ManifestPath(targetDir))
+
+	// Read-only commands still work
+	CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Errorf("Status() in read-only mode error = %v", err)
+		}
+	})
+
+	err = fsys.MkdirAll(filepath.Join(targetDir, "new"), 0755)
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("MkdirAll() error = %v, want ErrReadOnly", err)
+	}
+	if record := NewErrorRecord("error", err); record.Code != CodeReadOnly {
+		t.Errorf("error code = %q, want %q", record.Code, CodeReadOnly)
+	}
+	if _, err := fsys.ReadFile(filepath.Join(sourceDir, "shell", ".bashrc")); err != nil {
+		t.Errorf("ReadFile() error = %v", err)
+	}
+
+	SetReadOnly(false)
+	if _, ok := fsys.(readOnlyFS); ok {
+		t.Error("SetReadOnly(false) left fsys wrapped")
+	}
+}
+
+// TestWritesAreGuarded keeps --read-only honest: outside the FileSystem
+// implementations, no package code may call an os function that changes the
+// file system.
+func TestWritesAreGuarded(t *testing.T) {
+	writes := map[string]bool{
+		"Chmod": true, "Chown": true, "Chtimes": true, "Create": true, "CreateTemp": true,
+		"Lchown": true, "Link": true, "Mkdir": true, "MkdirAll": true, "MkdirTemp": true,
+		"OpenFile": true, "Remove": true, "RemoveAll": true, "Rename": true,
+		"Symlink": true, "Truncate": true, "WriteFile": true,
+	}
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || file == "fs.go" {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", file, err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "os" && writes[sel.Sel.Name] {
+				t.Errorf("%s: os.%s bypasses read-only mode; use fsys.%s", fset.Position(sel.Pos()), sel.Sel.Name, sel.Sel.Name)
+			}
+			return true
+		})
+	}
+}
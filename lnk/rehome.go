@@ -0,0 +1,149 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Rehome points managed links back into the home directory after it moved,
+// as after a username change or a macOS migration. Absolute links made under
+// the old home point into a path that no longer exists; the manifest, whose
+// paths are stored relative to the home, still lists them, together with the
+// home they were made in. The old home need not exist.
+func Rehome(opts LinkOptions) error {
+	PrintCommandHeader("Rehoming Links")
+
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
+
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		return err
+	}
+	oldHome := m.Home
+	if oldHome == "" {
+		if len(m.Links) == 0 {
+			PrintEmptyResult("managed links")
+			return nil
+		}
+		return NewPathErrorWithHint("rehome", ManifestPath(targetDir),
+			errors.New("manifest does not record the home its links were made in"),
+			fmt.Sprintf("Run 'lnk prune %s' to remove the broken links, then 'lnk create %s' to link again",
+				ContractPath(sourceDir), ContractPath(sourceDir)))
+	}
+	if oldHome == targetDir {
+		PrintInfo("Links already point into %s; nothing to rehome.", ContractPath(targetDir))
+		return nil
+	}
+	PrintVerbose("Rehoming links from %s to %s", oldHome, targetDir)
+
+	// Plan: managed links whose destination is inside the old home
+	type rehomed struct {
+		path, dest, newDest, source string
+	}
+	var planned []rehomed
+	var missing int
+	for _, l := range m.Links {
+		dest, err := fsys.Readlink(l.Path)
+		if err != nil {
+			PrintVerbose("Skipping %s: %v", ContractPath(l.Path), err)
+			continue
+		}
+		rel, ok := strings.CutPrefix(dest, oldHome+string(filepath.Separator))
+		if !ok {
+			continue
+		}
+		newDest := filepath.Join(targetDir, rel)
+		if _, err := fsys.Stat(newDest); err != nil {
+			PrintWarningWithHint(WithHint(
+				fmt.Errorf("Not rehoming %s: %s does not exist", ContractPath(l.Path), ContractPath(newDest)),
+				"Restore the file, or run 'lnk prune' for the source directory it belongs to"))
+			missing++
+			continue
+		}
+		planned = append(planned, rehomed{path: l.Path, dest: dest, newDest: newDest, source: l.Source})
+	}
+
+	if opts.DryRun {
+		fmt.Println()
+		PrintDryRun("Would rehome %d link(s) from %s to %s", len(planned), oldHome, ContractPath(targetDir))
+		lines := newPathLines(len(planned), "Would rehome", PrintDryRun)
+		for _, r := range planned {
+			lines.Add(r.path, "Would rehome: %s -> %s", ContractPath(r.path), ContractPath(r.newDest))
+		}
+		lines.Flush()
+		fmt.Println()
+		PrintDryRunSummary()
+		return nil
+	}
+
+	var done, failed int
+	lines := newPathLines(len(planned), "Rehomed", PrintSuccess)
+	for _, r := range planned {
+		if err := replaceSymlink(r.newDest, r.path); err != nil {
+			PrintWarningWithHint(fmt.Errorf("Failed to rehome %s: %w", ContractPath(r.path), err))
+			failed++
+			continue
+		}
+		if err := tagLink(r.path, r.source); err != nil {
+			PrintVerbose("Failed to tag %s: %v", ContractPath(r.path), err)
+		}
+		lines.Add(r.path, "Rehomed: %s -> %s", ContractPath(r.path), ContractPath(r.newDest))
+		done++
+	}
+	lines.Flush()
+	SummaryCount("rehomed", done)
+	SummaryCount("failed", failed)
+
+	// The manifest keeps the old home until every link is rehomed, so a
+	// second run can finish the job
+	if failed == 0 && missing == 0 {
+		m.Home = targetDir
+		if err := m.Save(targetDir); err != nil {
+			return err
+		}
+	}
+
+	if done > 0 {
+		PrintSummary("Rehomed %d link(s) from %s", done, oldHome)
+	} else if failed == 0 {
+		PrintInfo("No links point into %s.", oldHome)
+	}
+	if failed > 0 {
+		PrintWarning("Failed to rehome %d link(s)", failed)
+		return fmt.Errorf("failed to rehome %d link(s)", failed)
+	}
+	PrintNextStep("status", sourceDir, "verify links")
+	return nil
+}
+
+// replaceSymlink points the symlink at path to dest. The new link is created
+// beside path and renamed over it, so path is never missing.
+func replaceSymlink(dest, path string) error {
+	info, err := fsys.Lstat(path)
+	if err != nil {
+		return NewPathError("rehome", path, err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return NewPathErrorWithHint("rehome", path, ErrNotSymlink,
+			"The link was replaced by a file; leave it or remove it by hand")
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lnk-tmp")
+	fsys.Remove(tmp)
+	if err := fsys.Symlink(dest, tmp); err != nil {
+		return NewLinkErrorWithHint("create symlink", dest, path, err,
+			"Check that you have write permissions in the target directory")
+	}
+	if err := fsys.Rename(tmp, path); err != nil {
+		fsys.Remove(tmp)
+		return NewLinkErrorWithHint("replace symlink", dest, path, err,
+			"Check that you have write permissions in the target directory")
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRehome(t *testing.T) {
+	root := t.TempDir()
+	oldHome, newHome := filepath.Join(root, "old"), filepath.Join(root, "new")
+	sourceDir := filepath.Join(oldHome, "dotfiles")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "# bashrc")
+	createTestFile(t, filepath.Join(sourceDir, ".config", "nvim", "init.lua"), "-- init")
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: oldHome}); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	// A link lnk does not manage is left alone
+	unmanaged := filepath.Join(oldHome, ".profile")
+	os.Symlink(filepath.Join(oldHome, "dotfiles", ".bashrc"), unmanaged)
+
+	if err := os.Rename(oldHome, newHome); err != nil {
+		t.Fatal(err)
+	}
+	newSource := filepath.Join(newHome, "dotfiles")
+	opts := LinkOptions{SourceDir: newSource, TargetDir: newHome, DryRun: true}
+
+	out := CaptureOutput(t, func() {
+		if err := Rehome(opts); err != nil {
+			t.Fatalf("Rehome() dry run error = %v", err)
+		}
+	})
+	ContainsOutput(t, out, "Would rehome 2 link(s)")
+	if dest, _ := os.Readlink(filepath.Join(newHome, ".bashrc")); dest != filepath.Join(sourceDir, ".bashrc") {
+		t.Errorf("dry run changed .bashrc to %s", dest)
+	}
+
+	opts.DryRun = false
+	out = CaptureOutput(t, func() {
+		if err := Rehome(opts); err != nil {
+			t.Fatalf("Rehome() error = %v", err)
+		}
+	})
+	ContainsOutput(t, out, "Rehomed 2 link(s)")
+	assertSymlink(t, filepath.Join(newHome, ".bashrc"), filepath.Join(newSource, ".bashrc"))
+	assertSymlink(t, filepath.Join(newHome, ".config", "nvim", "init.lua"), filepath.Join(newSource, ".config", "nvim", "init.lua"))
+	assertSymlink(t, filepath.Join(newHome, ".profile"), filepath.Join(oldHome, "dotfiles", ".bashrc"))
+
+	if m, err := LoadManifest(newHome); err != nil || m.Home != newHome {
+		t.Errorf("manifest home = %v, %v; want %s", m, err, newHome)
+	}
+	out = CaptureOutput(t, func() {
+		if err := Rehome(opts); err != nil {
+			t.Fatalf("Rehome() again error = %v", err)
+		}
+	})
+	ContainsOutput(t, out, "nothing to rehome")
+}
+
+func TestRehomeMissingFile(t *testing.T) {
+	root := t.TempDir()
+	oldHome, newHome := filepath.Join(root, "old"), filepath.Join(root, "new")
+	sourceDir := filepath.Join(oldHome, "dotfiles")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "# bashrc")
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(LinkOptions{SourceDir: sourceDir, TargetDir: oldHome}); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	os.Rename(oldHome, newHome)
+	os.Remove(filepath.Join(newHome, "dotfiles", ".bashrc"))
+
+	opts := LinkOptions{SourceDir: filepath.Join(newHome, "dotfiles"), TargetDir: newHome}
+	CaptureOutput(t, func() {
+		if err := Rehome(opts); err != nil {
+			t.Fatalf("Rehome() error = %v", err)
+		}
+	})
+	// The old home stays recorded until every link could be rehomed
+	if m, _ := LoadManifest(newHome); m.Home != oldHome {
+		t.Errorf("manifest home = %s, want %s", m.Home, oldHome)
+	}
+}
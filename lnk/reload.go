@@ -0,0 +1,224 @@
+package lnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReloadAction makes a running program pick up changed configuration, from
+// "reload" in lnk-package.json: a shell command, optionally only while a
+// process runs, or a signal sent to every process of that name the user owns
+type ReloadAction struct {
+	Run     string `json:"run,omitempty"`     // shell command, run in the package directory
+	Signal  string `json:"signal,omitempty"`  // signal to send to Process, such as "USR1" or "HUP"
+	Process string `json:"process,omitempty"` // exact process name: receives Signal, or must be running for Run
+}
+
+// ReloadSignals lists the signals a reload action can send
+var ReloadSignals = []string{"HUP", "USR1", "USR2", "INT", "TERM", "WINCH", "CONT"}
+
+// reloadTimeout bounds each reload command, so a command waiting for input
+// cannot hang lnk
+const reloadTimeout = 30 * time.Second
+
+// String describes the action in output
+func (a ReloadAction) String() string {
+	if a.Signal != "" {
+		return fmt.Sprintf("SIG%s to %s", a.signal(), a.Process)
+	}
+	if a.Process != "" {
+		return fmt.Sprintf("%s (if %s is running)", a.Run, a.Process)
+	}
+	return a.Run
+}
+
+// signal returns Signal without a SIG prefix, in upper case
+func (a ReloadAction) signal() string {
+	return strings.TrimPrefix(strings.ToUpper(a.Signal), "SIG")
+}
+
+// validate checks that the action either runs a command or signals a process
+func (a ReloadAction) validate() error {
+	const hint = `Write reload actions as {"run": "tmux source-file ~/.tmux.conf"} or {"signal": "USR1", "process": "kitty"}`
+	switch {
+	case a.Run != "" && a.Signal != "":
+		return NewValidationErrorWithHint("reload", a.String(), "run and signal cannot be combined", hint)
+	case a.Run == "" && a.Signal == "":
+		return NewValidationErrorWithHint("reload", a.Process, "run or signal is required", hint)
+	case a.Signal != "" && a.Process == "":
+		return NewValidationErrorWithHint("reload", a.Signal, "a signal needs a process", hint)
+	case a.Signal != "" && !slices.Contains(ReloadSignals, a.signal()):
+		return NewValidationErrorWithHint("reload", a.Signal, "unknown signal",
+			fmt.Sprintf("Valid signals: %s", strings.Join(ReloadSignals, ", ")))
+	}
+	return nil
+}
+
+// runReloadCommand runs command with the shell in dir; tests replace it
+var runReloadCommand = func(dir, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// signalProcesses sends signal to the user's processes named name, with
+// pkill, and reports whether any matched; tests replace it. With an empty
+// signal nothing is sent (pgrep), to check that one is running.
+var signalProcesses = func(name, signal string) (bool, error) {
+	args := []string{"-u", strconv.Itoa(os.Geteuid()), "-x", name}
+	tool := "pgrep"
+	if signal != "" {
+		tool = "pkill"
+		args = append([]string{"-" + signal}, args...)
+	}
+	out, err := exec.Command(tool, args...).CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 1 {
+		return false, nil // no process matched
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w: %s", tool, err, strings.TrimSpace(string(out)))
+	}
+	return true, nil
+}
+
+// reloadQueue collects the source files a run changed, so the reload actions
+// of their packages run once, after everything else
+type reloadQueue struct {
+	changed []string
+}
+
+// reloads is the queue of the run collecting changes for --reload; nil
+// otherwise
+var reloads *reloadQueue
+
+// add notes changed source files; nil-safe
+func (q *reloadQueue) add(paths ...string) {
+	if q == nil {
+		return
+	}
+	q.changed = append(q.changed, paths...)
+}
+
+// collectReloads starts collecting changed sources for --reload, and returns
+// the function that runs the reload actions of the packages they are in. When
+// a caller is already collecting (up, around sync and create), the returned
+// function does nothing and the caller runs them once at its end.
+func collectReloads(sourceDir string, packages []string) func() {
+	if reloads != nil {
+		return func() {}
+	}
+	reloads = &reloadQueue{}
+	return func() {
+		q := reloads
+		reloads = nil
+		q.run(sourceDir, packages)
+	}
+}
+
+// run runs the reload actions of each selected package a changed file is in,
+// in package order. Failures are only warnings: the links are in place, and
+// the program can be reloaded by hand.
+func (q *reloadQueue) run(sourceDir string, packages []string) {
+	if len(q.changed) == 0 {
+		return
+	}
+	packages, err := expandPackageDeps(sourceDir, packages)
+	if err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to reload packages: %w", err))
+		return
+	}
+	dirs, err := packageDirs(sourceDir, packages)
+	if err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to reload packages: %w", err))
+		return
+	}
+
+	var reloaded, failed int
+	header := false
+	for _, dir := range dirs {
+		if !slices.ContainsFunc(q.changed, func(path string) bool { return isWithin(path, dir) }) {
+			continue
+		}
+		info, err := LoadPackageInfo(dir)
+		if err != nil {
+			PrintWarningWithHint(err)
+			failed++
+			continue
+		}
+		for _, action := range info.Reload {
+			if !header {
+				fmt.Fprintln(stdout())
+				PrintInfo("Reloading changed packages:")
+				header = true
+			}
+			ran, err := runReloadAction(dir, action)
+			name := packageName(sourceDir, dir)
+			switch {
+			case err != nil:
+				PrintWarningWithHint(WithHint(fmt.Errorf("Failed to reload %s: %s: %w", name, action, err),
+					"Reload the program by hand to pick up the new configuration"))
+				failed++
+			case ran:
+				PrintSuccess("Reloaded %s: %s", name, action)
+				reloaded++
+			default:
+				PrintSkip("Not running, not reloaded: %s (%s)", action.Process, name)
+			}
+		}
+	}
+	SummaryCount("reloaded", reloaded)
+	SummaryCount("reload_failed", failed)
+}
+
+// runReloadAction runs one action for the package in dir, reporting whether
+// it ran: a process that is not running needs no reload
+func runReloadAction(dir string, a ReloadAction) (bool, error) {
+	if err := a.validate(); err != nil {
+		return false, err
+	}
+	Trace("reload", "package", ContractPath(dir), "action", a.String())
+	if a.Process != "" && runtime.GOOS == "windows" {
+		return false, fmt.Errorf("process names and signals are not supported on Windows")
+	}
+	if a.Signal != "" {
+		return signalProcesses(a.Process, a.signal())
+	}
+	if a.Process != "" {
+		running, err := signalProcesses(a.Process, "")
+		if err != nil || !running {
+			return false, err
+		}
+	}
+	if out, err := runReloadCommand(dir, a.Run); err != nil {
+		if out != "" {
+			err = fmt.Errorf("%w: %s", err, out)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// packageName names the package in dir for output: its directory name, or
+// the source directory itself when packages are not used
+func packageName(sourceDir, dir string) string {
+	if dir == sourceDir {
+		return ContractPath(sourceDir)
+	}
+	return filepath.Base(dir)
+}
@@ -0,0 +1,122 @@
+package lnk
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubReloads replaces the reload commands and signals, recording each as
+// "<package> <command>" or "signal <process> <signal>". Processes in running
+// are running.
+func stubReloads(t *testing.T, running ...string) *[]string {
+	t.Helper()
+	var calls []string
+	origRun, origSignal := runReloadCommand, signalProcesses
+	runReloadCommand = func(dir, command string) (string, error) {
+		calls = append(calls, filepath.Base(dir)+" "+command)
+		return "", nil
+	}
+	signalProcesses = func(name, signal string) (bool, error) {
+		calls = append(calls, strings.TrimSpace("signal "+name+" "+signal))
+		for _, r := range running {
+			if r == name {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	t.Cleanup(func() { runReloadCommand, signalProcesses = origRun, origSignal })
+	return &calls
+}
+
+func TestCreateLinksReload(t *testing.T) {
+	calls := stubReloads(t, "tmux")
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "shell", PackageInfoFileName), `{"reload": [
+		{"run": "tmux source-file ~/.tmux.conf", "process": "tmux"},
+		{"signal": "sigusr1", "process": "kitty"}
+	]}`)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", PackageInfoFileName), `{"reload": [{"run": "nvim-reload"}]}`)
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}, Reload: true}
+
+	out := CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	want := "signal tmux,shell tmux source-file ~/.tmux.conf,signal kitty USR1"
+	if got := strings.Join(*calls, ","); got != want {
+		t.Errorf("reload calls = %s, want %s", got, want)
+	}
+	ContainsOutput(t, out, "Reloaded shell: tmux source-file", "Not running, not reloaded: kitty")
+
+	// Nothing changed, so nothing reloads
+	*calls = nil
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	if len(*calls) != 0 {
+		t.Errorf("reload calls = %v, want none for an unchanged package", *calls)
+	}
+
+	t.Run("once for nested runs", func(t *testing.T) {
+		*calls = nil
+		opts.Packages = []string{"nvim"}
+		run := collectReloads(sourceDir, opts.Packages)
+		CaptureOutput(t, func() {
+			if _, err := CreateLinks(opts); err != nil {
+				t.Fatalf("CreateLinks() error = %v", err)
+			}
+		})
+		if len(*calls) != 0 {
+			t.Errorf("reload calls = %v, want none before the outer run ends", *calls)
+		}
+		CaptureOutput(t, run)
+		if strings.Join(*calls, ",") != "nvim nvim-reload" || reloads != nil {
+			t.Errorf("reload calls = %v, want nvim reloaded once", *calls)
+		}
+	})
+}
+
+func TestSyncReload(t *testing.T) {
+	calls := stubReloads(t)
+	origin, clone := setupSyncTest(t)
+	createTestFile(t, filepath.Join(origin, "shell", PackageInfoFileName), `{"reload": [{"run": "exec zsh"}]}`)
+	createTestFile(t, filepath.Join(origin, "nvim", PackageInfoFileName), `{"reload": [{"run": "nvim-reload"}]}`)
+	runTestGit(t, origin, "add", ".")
+	runTestGit(t, origin, "commit", "-q", "-m", "add reload actions")
+	runTestGit(t, clone, "pull", "-q")
+	createTestFile(t, filepath.Join(origin, "shell", ".bashrc"), "# changed")
+	runTestGit(t, origin, "commit", "-q", "-am", "change bashrc")
+
+	CaptureOutput(t, func() {
+		if err := Sync(SyncOptions{SourceDir: clone, TargetDir: t.TempDir(), Packages: []string{"shell", "nvim"}, Reload: true}); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+	if got := strings.Join(*calls, ","); got != "shell exec zsh" {
+		t.Errorf("reload calls = %s, want only the changed package", got)
+	}
+}
+
+func TestReloadActionValidate(t *testing.T) {
+	for _, tt := range []struct {
+		action ReloadAction
+		valid  bool
+	}{
+		{ReloadAction{Run: "tmux source-file ~/.tmux.conf"}, true},
+		{ReloadAction{Run: "hyprctl reload", Process: "Hyprland"}, true},
+		{ReloadAction{Signal: "SIGHUP", Process: "waybar"}, true},
+		{ReloadAction{}, false},
+		{ReloadAction{Run: "x", Signal: "HUP", Process: "y"}, false},
+		{ReloadAction{Signal: "USR1"}, false},
+		{ReloadAction{Signal: "KILL", Process: "kitty"}, false},
+	} {
+		if err := tt.action.validate(); (err == nil) != tt.valid {
+			t.Errorf("validate(%+v) = %v, want valid = %v", tt.action, err, tt.valid)
+		}
+	}
+}
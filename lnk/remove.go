@@ -5,6 +5,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -13,14 +14,14 @@ import (
 func collectManagedLinks(sourceDir, targetDir string) ([]string, error) {
 	// Resolve sourceDir so comparisons work when EvalSymlinks resolves OS-level
 	// symlinks (e.g., macOS /var -> /private/var)
-	resolvedSourceDir, err := filepath.EvalSymlinks(sourceDir)
+	resolvedSourceDir, err := fsys.EvalSymlinks(sourceDir)
 	if err != nil {
 		return nil, fmt.Errorf("resolving source directory: %w", err)
 	}
 
 	var managed []string
 
-	err = filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
+	err = walkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -36,13 +37,13 @@ func collectManagedLinks(sourceDir, targetDir string) ([]string, error) {
 		targetPath := filepath.Join(targetDir, relPath)
 
 		// Check if target is a symlink
-		info, err := os.Lstat(targetPath)
+		info, err := fsys.Lstat(targetPath)
 		if err != nil || info.Mode()&os.ModeSymlink == 0 {
 			return nil // doesn't exist or not a symlink — skip
 		}
 
 		// Verify the symlink points into sourceDir
-		resolved, err := filepath.EvalSymlinks(targetPath)
+		resolved, err := fsys.EvalSymlinks(targetPath)
 		if err != nil {
 			return nil // broken or inaccessible — skip
 		}
@@ -58,36 +59,170 @@ func collectManagedLinks(sourceDir, targetDir string) ([]string, error) {
 	return managed, err
 }
 
+// collectSymlinkedLinks returns target paths linked through symlinks in a
+// package that follows symlinked directories (follow_symlinked_dirs) or links
+// symlinked files (symlinked_files). collectManagedLinks does not see them: it
+// does not follow the symlinks, and the files resolve outside the source
+// directory.
+func collectSymlinkedLinks(pkgDir, targetDir string) ([]string, error) {
+	info, err := LoadPackageInfo(pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := info.symlinkPolicy(pkgDir)
+	if err != nil || !policy.followDirs && policy.skipsFiles() {
+		return nil, err
+	}
+	resolvedPkgDir, err := fsys.EvalSymlinks(pkgDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving source directory: %w", err)
+	}
+	planned, _, _, err := collectPlannedLinksWithPatterns(pkgDir, targetDir, nil, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	var managed []string
+	for _, link := range planned {
+		if link.Copy {
+			continue // copies are not links
+		}
+		source, err := fsys.EvalSymlinks(link.Source)
+		if err != nil || isWithin(source, resolvedPkgDir) {
+			continue // collectManagedLinks already has links to files in the source
+		}
+		if info, err := fsys.Lstat(link.Target); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		if resolved, err := fsys.EvalSymlinks(link.Target); err == nil && resolved == source {
+			managed = append(managed, link.Target)
+		}
+	}
+	return managed, nil
+}
+
 // RemoveLinks removes symlinks managed by the source directory
 func RemoveLinks(opts LinkOptions) error {
 	PrintCommandHeader("Removing Symlinks")
 
 	// Expand and validate paths
-	paths, err := ResolvePaths(opts.SourceDir, opts.TargetDir)
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
 	if err != nil {
 		return err
 	}
 	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
 
-	// Walk source dir to find managed links
-	PrintVerbose("Walking source directory %s to find managed links", sourceDir)
-	managed, err := collectManagedLinks(sourceDir, targetDir)
+	pkgDirs, err := packageDirs(sourceDir, opts.Packages)
+	if err != nil {
+		return err
+	}
+
+	// Walk each package (or the whole source dir) to find managed links
+	endPlan := TracePhase("plan")
+	var managed []string
+	for _, dir := range pkgDirs {
+		pkgTarget, include, err := resolvePackageTarget(dir, targetDir)
+		if err != nil {
+			return err
+		}
+		if !include {
+			continue
+		}
+		PrintVerbose("Walking source directory %s to find managed links", dir)
+		links, err := collectManagedLinks(dir, pkgTarget)
+		if err != nil {
+			return fmt.Errorf("walking source directory: %w", err)
+		}
+		symlinked, err := collectSymlinkedLinks(dir, pkgTarget)
+		if err != nil {
+			return fmt.Errorf("walking source directory: %w", err)
+		}
+		links = append(links, symlinked...)
+		Trace("mapping", "source", ContractPath(dir), "target", ContractPath(pkgTarget), "managed_links", len(links))
+		managed = append(managed, links...)
+	}
+	maps, err := resolveMappings(opts.Maps, opts.Home, sourceDir, targetDir)
 	if err != nil {
-		return fmt.Errorf("walking source directory: %w", err)
+		return err
+	}
+	for _, m := range maps {
+		links, err := collectManagedMapLinks(m)
+		if err != nil {
+			return fmt.Errorf("walking mapped source: %w", err)
+		}
+		Trace("mapping", "source", ContractPath(m.Source), "target", ContractPath(m.Target), "managed_links", len(links), "ad_hoc", true)
+		managed = append(managed, links...)
 	}
+	if len(opts.Paths) > 0 {
+		managed, err = selectManagedLinks(opts.Paths, opts.Home, managed)
+		if err != nil {
+			return err
+		}
+	}
+	if len(opts.Groups) > 0 {
+		inGroups, err := groupTargets(opts.Groups, pkgDirs, maps, targetDir, opts.IgnorePatterns)
+		if err != nil {
+			return err
+		}
+		managed = slices.DeleteFunc(managed, func(path string) bool { return !inGroups[path] })
+	}
+	// Links the user made into the source by hand are left alone unless --all
+	var manual []string
+	if !opts.AllLinks {
+		managed, manual, err = splitCreatedLinks(managed, sourceDir, targetDir)
+		if err != nil {
+			return err
+		}
+	}
+	endPlan("links", len(managed), "mappings", len(pkgDirs)+len(maps), "manual", len(manual))
+	SummaryCount("planned", len(managed))
+	SummaryCount("skipped", len(manual))
+
+	// Dependencies are not removed with a package; warn about packages left
+	// linked whose dependency is going away
+	if len(opts.Packages) > 0 {
+		warnLinkedDependents(sourceDir, targetDir, opts.Packages)
+	}
+
+	printManualLinks(manual, opts.DryRun)
 
 	if len(managed) == 0 {
 		PrintEmptyResult("symlinks to remove")
+		if opts.CleanDirs && !opts.DryRun {
+			return cleanCreatedDirsAfterRemove(sourceDir, targetDir)
+		}
 		return nil
 	}
 
+	// Let the user leave links out before anything changes
+	if opts.Interactive {
+		labels := make([]string, len(managed))
+		for i, path := range managed {
+			labels[i] = ContractPath(path)
+		}
+		chosen, err := chooseItems(labels, "remove")
+		if err != nil {
+			return err
+		}
+		if managed = pick(managed, chosen); len(managed) == 0 {
+			PrintInfo("Nothing selected.")
+			return nil
+		}
+		if !opts.DryRun && !confirm(fmt.Sprintf("Remove %d symlink(s)? [y/N]", len(managed))) {
+			PrintInfo("Nothing removed.")
+			return nil
+		}
+	}
+
 	// Show what will be removed in dry-run mode
 	if opts.DryRun {
 		fmt.Println()
 		PrintDryRun("Would remove %d symlink(s):", len(managed))
+		lines := newPathLines(len(managed), "Would remove", PrintDryRun)
 		for _, path := range managed {
-			PrintDryRun("Would remove: %s", ContractPath(path))
+			lines.Add(path, "Would remove: %s", ContractPath(path))
 		}
+		lines.Flush()
 		fmt.Println()
 		PrintDryRunSummary()
 		return nil
@@ -95,23 +230,38 @@ func RemoveLinks(opts LinkOptions) error {
 
 	// Track results for summary
 	var removed, failed int
-	var removedParents []string
+	var removedParents, removedPaths []string
 
 	// Remove links
+	endExecute := TracePhase("execute")
+	lines := newPathLines(len(managed), "Removed", PrintSuccess)
 	for _, path := range managed {
 		if err := RemoveSymlink(path); err != nil {
 			PrintWarningWithHint(fmt.Errorf("Failed to remove %s: %w", ContractPath(path), err))
 			failed++
 			continue
 		}
-		PrintSuccess("Removed: %s", ContractPath(path))
+		lines.Add(path, "Removed: %s", ContractPath(path))
 		removed++
 		removedParents = append(removedParents, filepath.Dir(path))
+		removedPaths = append(removedPaths, path)
 	}
+	lines.Flush()
+	refreshFontsIfChanged(targetDir, removedPaths)
+	forgetLinks(targetDir, removedPaths)
+	endExecute("removed", removed, "failed", failed)
+	SummaryCount("removed", removed)
+	SummaryCount("failed", failed)
 
 	// Clean empty parent directories
 	CleanEmptyDirs(removedParents, targetDir)
 
+	// Optionally remove any other empty directories lnk created for this source
+	var cleanErr error
+	if opts.CleanDirs {
+		cleanErr = cleanCreatedDirsAfterRemove(sourceDir, targetDir)
+	}
+
 	// Print summary
 	if removed > 0 {
 		PrintSummary("Removed %d symlink(s) successfully", removed)
@@ -120,9 +270,103 @@ func RemoveLinks(opts LinkOptions) error {
 		PrintWarning("Failed to remove %d symlink(s)", failed)
 		return fmt.Errorf("failed to remove %d symlink(s)", failed)
 	}
+	if cleanErr != nil {
+		return cleanErr
+	}
 	if failed == 0 {
 		PrintNextStep("status", sourceDir, "verify links")
 	}
 
 	return nil
 }
+
+// selectManagedLinks narrows managed to the given paths. A path must be a
+// managed link or a directory containing managed links; anything else is an
+// error so a mistyped path never goes unnoticed in a long list.
+func selectManagedLinks(paths []string, home string, managed []string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, path := range paths {
+		absPath, err := expandPathIn(home, path)
+		if err != nil {
+			return nil, err
+		}
+		if absPath, err = filepath.Abs(absPath); err != nil {
+			return nil, NewPathError("remove", path, err)
+		}
+
+		found := false
+		for _, link := range managed {
+			if link == absPath || isWithin(link, absPath) {
+				selected[link] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, NewPathErrorWithHint("remove", absPath, fmt.Errorf("not a link managed by this source"),
+				pathHint(absPath, managed, "Use 'lnk status' to see managed links"))
+		}
+	}
+
+	var links []string
+	for _, link := range managed {
+		if selected[link] {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+// splitCreatedLinks separates links lnk created for sourceDir from links that
+// point into it but were made by hand (see createdByLnk). When the manifest does
+// not track links for sourceDir yet (last linked by an older lnk), every link is
+// treated as lnk's.
+func splitCreatedLinks(links []string, sourceDir, targetDir string) (created, manual []string, err error) {
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		return nil, nil, WithHint(fmt.Errorf("cannot tell which links lnk created: %w", err),
+			"Fix or delete the manifest, or use --all to remove every link into the source directory")
+	}
+	if !m.TracksLinks(sourceDir) {
+		PrintVerbose("No links recorded for %s; treating every link into it as created by lnk", ContractPath(sourceDir))
+		return links, nil, nil
+	}
+	for _, link := range links {
+		if createdByLnk(m, link, sourceDir) {
+			created = append(created, link)
+		} else {
+			manual = append(manual, link)
+		}
+	}
+	return created, manual, nil
+}
+
+// printManualLinks reports links kept because lnk did not create them
+func printManualLinks(manual []string, dryRun bool) {
+	if len(manual) == 0 {
+		return
+	}
+	for _, path := range manual {
+		if dryRun {
+			PrintDryRun("Would skip: %s (not created by lnk)", ContractPath(path))
+		} else {
+			PrintSkip("Skipped: %s (not created by lnk)", ContractPath(path))
+		}
+	}
+	PrintInfo("Use --all to also remove %d link(s) not created by lnk", len(manual))
+}
+
+// cleanCreatedDirsAfterRemove removes empty lnk-created directories for --clean-empty-dirs.
+func cleanCreatedDirsAfterRemove(sourceDir, targetDir string) error {
+	candidates, err := emptyCreatedDirs(sourceDir, targetDir)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	if _, failed := removeCreatedDirs(sourceDir, targetDir, candidates); failed > 0 {
+		PrintWarning("Failed to remove %d directory(ies)", failed)
+		return fmt.Errorf("failed to remove %d directory(ies)", failed)
+	}
+	return nil
+}
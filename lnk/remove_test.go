@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"bufio"
 	"os"
 	"path/filepath"
 	"strings"
@@ -376,3 +377,144 @@ func TestRemoveLinksNextStep(t *testing.T) {
 		t.Errorf("RemoveLinks() should print next-step hint after successful removal\nstdout: %q", stdout)
 	}
 }
+
+func TestRemoveLinksWithPaths(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim", "work"}}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	opts.Paths = []string{filepath.Join(targetDir, ".bashrc"), filepath.Join(targetDir, ".config")}
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+	assertNotExists(t, filepath.Join(targetDir, ".config", "nvim", "init.lua"))
+	assertSymlink(t, filepath.Join(targetDir, ".gitconfig"), filepath.Join(sourceDir, "work", ".gitconfig"))
+}
+
+func TestRemoveLinksWithUnmanagedPath(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "work"}}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	createTestFile(t, filepath.Join(targetDir, ".profile"), "# local")
+
+	opts.Paths = []string{filepath.Join(targetDir, ".bashrc"), filepath.Join(targetDir, ".profile")}
+	var err error
+	CaptureOutput(t, func() {
+		err = RemoveLinks(opts)
+	})
+	if err == nil || !strings.Contains(err.Error(), "not a link managed by this source") {
+		t.Fatalf("RemoveLinks() error = %v, want unmanaged path error", err)
+	}
+	// Nothing is removed when any path is invalid
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	if _, err := os.Lstat(filepath.Join(targetDir, ".profile")); err != nil {
+		t.Errorf("expected .profile to remain: %v", err)
+	}
+}
+
+func TestRemoveLinksSkipsManualLinks(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	// A link the user made into the repository by hand after linking
+	manual := filepath.Join(targetDir, ".gitconfig")
+	if err := os.Symlink(filepath.Join(sourceDir, "work", ".gitconfig"), manual); err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Packages = []string{"shell", "work"}
+	output := CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+	assertSymlink(t, manual, filepath.Join(sourceDir, "work", ".gitconfig"))
+	if !strings.Contains(output, "not created by lnk") || !strings.Contains(output, "--all") {
+		t.Errorf("expected the manual link to be reported as skipped, got:\n%s", output)
+	}
+
+	// Still skipped once no recorded links remain for the source
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertSymlink(t, manual, filepath.Join(sourceDir, "work", ".gitconfig"))
+
+	opts.AllLinks = true
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertNotExists(t, manual)
+}
+
+func TestRemoveLinksUntrackedSource(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	// Links made before lnk recorded links: nothing is tracked for the source
+	link := filepath.Join(targetDir, ".bashrc")
+	if err := os.Symlink(filepath.Join(sourceDir, "shell", ".bashrc"), link); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}}
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertNotExists(t, link)
+}
+
+func TestRemoveLinksInteractive(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "work"}}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	bashrc, gitconfig := filepath.Join(targetDir, ".bashrc"), filepath.Join(targetDir, ".gitconfig")
+
+	origCanPrompt, origReader := canPrompt, promptReader
+	canPrompt = func() bool { return true }
+	t.Cleanup(func() { canPrompt, promptReader = origCanPrompt, origReader })
+	opts.Interactive = true
+
+	// Declining the confirmation keeps everything
+	promptReader = bufio.NewReader(strings.NewReader("\nn\n"))
+	captureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertSymlink(t, bashrc, filepath.Join(sourceDir, "shell", ".bashrc"))
+	assertSymlink(t, gitconfig, filepath.Join(sourceDir, "work", ".gitconfig"))
+
+	// Select only the second link
+	promptReader = bufio.NewReader(strings.NewReader("none\n2\n\ny\n"))
+	captureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertSymlink(t, bashrc, filepath.Join(sourceDir, "shell", ".bashrc"))
+	assertNotExists(t, gitconfig)
+}
@@ -0,0 +1,233 @@
+package lnk
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// largeFileThreshold is the size above which a source file is reported as a problem
+const largeFileThreshold = 100 * 1024 * 1024
+
+// reportLargestCount is how many of the largest files the report lists
+const reportLargestCount = 5
+
+// sourceReport summarizes a source directory as create would see it
+type sourceReport struct {
+	mappings map[string]*mappingStats // top-level entry -> linkable file stats
+	ignored  map[string]int           // ignore pattern -> files it ignored
+	largest  []reportFile             // largest linkable files, biggest first
+	problems []reportProblem          // entries likely to cause trouble
+	files    int                      // linkable files
+	size     int64                    // total size of linkable files
+}
+
+// mappingStats counts linkable files under one top-level entry
+type mappingStats struct {
+	files int
+	size  int64
+}
+
+// reportFile is a linkable source file and its size
+type reportFile struct {
+	path string
+	size int64
+}
+
+// reportProblem is a source entry that should be fixed before linking or committing
+type reportProblem struct {
+	kind string // a special file kind (see specialFileKind) or "large file"
+	path string
+	size int64
+}
+
+// Report summarizes the source directory: linkable files per mapping, total
+// size, ignored files by pattern, the largest files, and entries that are
+// likely to cause problems (sockets, FIFOs, device nodes, hardlinked files,
+// files over 100 MB).
+func Report(opts LinkOptions) error {
+	PrintCommandHeader("Source Report")
+
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir := paths.SourceDir
+	PrintVerbose("Source directory: %s", sourceDir)
+
+	report, err := buildSourceReport(sourceDir, opts.IgnorePatterns)
+	if err != nil {
+		return err
+	}
+
+	if ShouldSimplifyOutput() {
+		printReportPiped(report, sourceDir)
+		return nil
+	}
+
+	if report.files == 0 {
+		PrintEmptyResult("files to link")
+	} else {
+		PrintInfo("Mappings:")
+		for _, m := range sortedKeys(report.mappings) {
+			stats := report.mappings[m]
+			PrintDetail("%s: %d file(s), %s", ContractPath(filepath.Join(sourceDir, m)), stats.files, formatSize(stats.size))
+		}
+		fmt.Println()
+		PrintInfo("Total: %s", Bold(fmt.Sprintf("%d file(s), %s", report.files, formatSize(report.size))))
+	}
+
+	if len(report.ignored) > 0 {
+		fmt.Println()
+		total := 0
+		for _, n := range report.ignored {
+			total += n
+		}
+		PrintInfo("Ignored: %d file(s)", total)
+		for _, pattern := range sortedKeys(report.ignored) {
+			PrintDetail("%s: %d", pattern, report.ignored[pattern])
+		}
+	}
+
+	if len(report.largest) > 0 {
+		fmt.Println()
+		PrintInfo("Largest files:")
+		for _, f := range report.largest {
+			PrintDetail("%s (%s)", ContractPath(f.path), formatSize(f.size))
+		}
+	}
+
+	fmt.Println()
+	if len(report.problems) == 0 {
+		PrintSuccess("No problems found")
+		return nil
+	}
+	PrintInfo("Problems:")
+	for _, p := range report.problems {
+		if p.kind == "large file" {
+			fmt.Printf("%s %s: %s (%s)\n", Yellow(WarningIcon), p.kind, ContractPath(p.path), formatSize(p.size))
+		} else {
+			fmt.Printf("%s %s: %s\n", Yellow(WarningIcon), p.kind, ContractPath(p.path))
+		}
+	}
+	fmt.Println()
+	PrintInfo("Next: Add these paths to .lnkignore or remove them from %s", ContractPath(sourceDir))
+	return nil
+}
+
+// buildSourceReport walks sourceDir with the same ignore rules as create
+func buildSourceReport(sourceDir string, ignorePatterns []string) (*sourceReport, error) {
+	pm := NewPatternMatcher(ignorePatterns)
+	report := &sourceReport{
+		mappings: make(map[string]*mappingStats),
+		ignored:  make(map[string]int),
+	}
+
+	err := filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to calculate relative path: %w", err)
+		}
+		if pattern, ignored := pm.MatchingPattern(relPath); ignored {
+			report.ignored[pattern]++
+			return nil
+		}
+
+		kind, err := specialFileKind(d)
+		if err != nil {
+			return err
+		}
+		if kind != "" {
+			report.problems = append(report.problems, reportProblem{kind: kind, path: path})
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size := info.Size()
+		mapping := topLevelEntry(relPath)
+		if report.mappings[mapping] == nil {
+			report.mappings[mapping] = &mappingStats{}
+		}
+		report.mappings[mapping].files++
+		report.mappings[mapping].size += size
+		report.files++
+		report.size += size
+		report.largest = append(report.largest, reportFile{path: path, size: size})
+		if size > largeFileThreshold {
+			report.problems = append(report.problems, reportProblem{kind: "large file", path: path, size: size})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning source directory: %w", err)
+	}
+
+	sort.SliceStable(report.largest, func(i, j int) bool {
+		return report.largest[i].size > report.largest[j].size
+	})
+	if len(report.largest) > reportLargestCount {
+		report.largest = report.largest[:reportLargestCount]
+	}
+	return report, nil
+}
+
+// printReportPiped prints the report as space-separated records for scripts
+func printReportPiped(report *sourceReport, sourceDir string) {
+	for _, m := range sortedKeys(report.mappings) {
+		stats := report.mappings[m]
+		fmt.Printf("mapping %s %d %d\n", ContractPath(filepath.Join(sourceDir, m)), stats.files, stats.size)
+	}
+	fmt.Printf("total %d %d\n", report.files, report.size)
+	for _, pattern := range sortedKeys(report.ignored) {
+		fmt.Printf("ignored %s %d\n", pattern, report.ignored[pattern])
+	}
+	for _, f := range report.largest {
+		fmt.Printf("largest %s %d\n", ContractPath(f.path), f.size)
+	}
+	for _, p := range report.problems {
+		if p.kind == "large file" {
+			fmt.Printf("problem large %s %d\n", ContractPath(p.path), p.size)
+		} else {
+			fmt.Printf("problem %s %s\n", strings.Fields(p.kind)[0], ContractPath(p.path))
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in ascending order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatSize formats a byte count for display (e.g. "512 B", "1.5 KB", "120.0 MB")
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,92 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildSourceReport(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFile(t, filepath.Join(sourceDir, "home", ".bashrc"), "12345")
+	createTestFile(t, filepath.Join(sourceDir, "home", ".config", "app", "big"), strings.Repeat("x", 100))
+	createTestFile(t, filepath.Join(sourceDir, "private", ".ssh", "config"), "123")
+	createTestFile(t, filepath.Join(sourceDir, "README.md"), "docs")
+	createTestFile(t, filepath.Join(sourceDir, "home", ".vimrc.swp"), "swap")
+	createTestFile(t, filepath.Join(sourceDir, "home", "keep.swp"), "kept")
+
+	// A sparse file over the threshold is reported without using the disk space
+	big, err := os.Create(filepath.Join(sourceDir, "home", "blob.iso"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := big.Truncate(largeFileThreshold + 1); err != nil {
+		t.Fatal(err)
+	}
+	big.Close()
+
+	report, err := buildSourceReport(sourceDir, []string{"README*", "*.swp", "!keep.swp"})
+	if err != nil {
+		t.Fatalf("buildSourceReport() error = %v", err)
+	}
+
+	if got := report.mappings["home"]; got == nil || got.files != 4 {
+		t.Errorf("home mapping = %+v, want 4 files", got)
+	}
+	if got := report.mappings["private"]; got == nil || got.files != 1 || got.size != 3 {
+		t.Errorf("private mapping = %+v, want 1 file of 3 bytes", got)
+	}
+	if report.files != 5 {
+		t.Errorf("files = %d, want 5", report.files)
+	}
+	if report.ignored["README*"] != 1 || report.ignored["*.swp"] != 1 {
+		t.Errorf("ignored = %v, want README*: 1, *.swp: 1", report.ignored)
+	}
+	if len(report.largest) == 0 || filepath.Base(report.largest[0].path) != "blob.iso" {
+		t.Errorf("largest = %v, want blob.iso first", report.largest)
+	}
+	if len(report.problems) != 1 || report.problems[0].kind != "large file" {
+		t.Errorf("problems = %v, want one large file", report.problems)
+	}
+}
+
+func TestReportOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "dotfiles")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(sourceDir, "home", ".bashrc"), "12345")
+	createTestFile(t, filepath.Join(sourceDir, "home", "notes.swp"), "swap")
+	os.MkdirAll(targetDir, 0755)
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, IgnorePatterns: []string{"*.swp"}}
+	output := CaptureOutput(t, func() {
+		if err := Report(opts); err != nil {
+			t.Fatalf("Report() error = %v", err)
+		}
+	})
+
+	ContainsOutput(t, output,
+		"mapping "+filepath.Join(sourceDir, "home")+" 1 5",
+		"total 1 5",
+		"ignored *.swp 1",
+		"largest "+filepath.Join(sourceDir, "home", ".bashrc")+" 5")
+	NotContainsOutput(t, output, "problem")
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KB"},
+		{150 * 1024 * 1024, "150.0 MB"},
+	}
+	for _, tt := range tests {
+		if got := formatSize(tt.size); got != tt.want {
+			t.Errorf("formatSize(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package lnk
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildSourceReportSpecialFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+	createTestFile(t, filepath.Join(sourceDir, "home", ".bashrc"), "# bashrc")
+	createTestFIFO(t, filepath.Join(sourceDir, "home", "pipe"))
+	createTestSocket(t, filepath.Join(sourceDir, "home", "sock"))
+
+	report, err := buildSourceReport(sourceDir, nil)
+	if err != nil {
+		t.Fatalf("buildSourceReport() error = %v", err)
+	}
+
+	kinds := make(map[string]string)
+	for _, p := range report.problems {
+		kinds[filepath.Base(p.path)] = p.kind
+	}
+	if kinds["pipe"] != "FIFO" || kinds["sock"] != "socket" {
+		t.Errorf("problems = %v, want pipe=FIFO and sock=socket", kinds)
+	}
+	if report.files != 1 {
+		t.Errorf("files = %d, want 1 (special files are not linkable)", report.files)
+	}
+}
@@ -0,0 +1,246 @@
+package lnk
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// CreateLinks, Adopt, and Prune return what they decided for each path and
+// why, and leave the summary to the caller: the CLI prints it with
+// PrintCreateSummary, PrintAdoptSummary, and PrintPruneSummary. They still
+// print each path and any plan as they go; programs using lnk as a library
+// call CreateLinksResults, AdoptResults, and PruneResults instead, which run
+// them printing nothing. The operations record their decisions with the
+// record functions below. Nothing is asked by the Results functions: a
+// question a prompt would settle is answered no, and recorded as the path's
+// result.
+//
+// Like the rest of the package, the operations keep their state in package
+// variables (collected, quiet, canPrompt) for the length of the call. They
+// may be nested, since each restores what it found, but must not run
+// concurrently with each other or with any other lnk operation.
+
+// Outcomes of a path in an operation's results
+const (
+	ResultCreated  = "created"  // link made (create)
+	ResultExisting = "existing" // link already in place (create)
+	ResultReplaced = "replaced" // identical file replaced with a link (create)
+	ResultCopied   = "copied"   // file copied instead of linked (create)
+	ResultAdopted  = "adopted"  // file moved into the source and linked (adopt)
+	ResultPruned   = "pruned"   // link removed (prune)
+	ResultPlanned  = "planned"  // would be done, in a dry run
+	ResultSkipped  = "skipped"  // left alone on purpose; Reason says why
+	ResultConflict = "conflict" // differs from the repository copy; nothing done (adopt)
+	ResultFailed   = "failed"   // attempted and failed; Err says why
+)
+
+// LinkResult is what create decided for one planned link
+type LinkResult struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Result string `json:"result"`           // a Result* outcome
+	Reason string `json:"reason,omitempty"` // why it was skipped, or the error
+	Err    error  `json:"-"`                // the error, for ResultFailed
+}
+
+// AdoptResult is what adopt decided for each file
+type AdoptResult struct {
+	Files []AdoptedFile `json:"files"`
+}
+
+// AdoptedFile is what adopt decided for one file
+type AdoptedFile struct {
+	Path   string `json:"path"`             // where the file was, and where the link is
+	Dest   string `json:"dest,omitempty"`   // where it is in the source directory
+	Result string `json:"result"`           // ResultAdopted, ResultPlanned, ResultSkipped, or ResultConflict
+	Reason string `json:"reason,omitempty"` // why it was skipped, or how a conflict was or can be resolved
+}
+
+// PruneResult is what prune decided for each candidate link
+type PruneResult struct {
+	Links []PrunedLink `json:"links"`
+}
+
+// PrunedLink is what prune decided for one link
+type PrunedLink struct {
+	Path    string `json:"path"`             // the link
+	Source  string `json:"source"`           // the file it pointed to
+	Mapping string `json:"mapping"`          // top-level entry of the source directory holding Source ("." for top-level files)
+	Result  string `json:"result"`           // ResultPruned, ResultPlanned, ResultSkipped, or ResultFailed
+	Reason  string `json:"reason,omitempty"` // why it was pruned or skipped, or the error
+	Err     error  `json:"-"`                // the error, for ResultFailed
+}
+
+// collected gathers the results of the operation running now; nil otherwise
+var collected *collectedResults
+
+type collectedResults struct {
+	links []LinkResult
+	adopt AdoptResult
+	prune PruneResult
+}
+
+// collect starts collecting the results of an operation. The returned func
+// stops, restoring the collector of any operation this one runs within.
+func collect() (*collectedResults, func()) {
+	saved := collected
+	r := &collectedResults{}
+	collected = r
+	return r, func() { collected = saved }
+}
+
+// quietly runs op printing nothing and without prompting
+func quietly(op func() error) error {
+	savedQuiet, savedCanPrompt := quiet, canPrompt
+	quiet, canPrompt = true, func() bool { return false }
+	defer func() { quiet, canPrompt = savedQuiet, savedCanPrompt }()
+	return op()
+}
+
+// CreateLinksResults runs CreateLinks without printing and returns what it
+// decided for each planned link. The results are returned with the error
+// when it fails part way. Identical files are only replaced with
+// opts.ReplaceIdentical, since nothing is asked; otherwise their links fail.
+func CreateLinksResults(opts LinkOptions) ([]LinkResult, error) {
+	var results []LinkResult
+	err := quietly(func() (err error) {
+		results, err = CreateLinks(opts)
+		return err
+	})
+	return results, err
+}
+
+// AdoptResults runs Adopt without printing and returns what it decided for
+// each file. Conflicts need opts.Prefer, since nothing is asked; without it
+// the first is recorded as ResultConflict and returned as the error.
+func AdoptResults(opts AdoptOptions) (*AdoptResult, error) {
+	var result *AdoptResult
+	err := quietly(func() (err error) {
+		result, err = Adopt(opts)
+		return err
+	})
+	return result, err
+}
+
+// PruneResults runs Prune without printing and returns what it decided for
+// each broken or git-deleted link
+func PruneResults(opts LinkOptions) (*PruneResult, error) {
+	var result *PruneResult
+	err := quietly(func() (err error) {
+		result, err = Prune(opts)
+		return err
+	})
+	return result, err
+}
+
+// PrintCreateSummary prints the summary of what CreateLinks did for
+// sourceDir. Nothing is printed for a dry run or when nothing was planned.
+func PrintCreateSummary(results []LinkResult, sourceDir string) {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Result]++
+	}
+	if counts[ResultPlanned] > 0 {
+		return
+	}
+	created, copied, failed := counts[ResultCreated]+counts[ResultReplaced], counts[ResultCopied], counts[ResultFailed]
+	if created > 0 {
+		PrintSummary("Created %d symlink(s) successfully", created)
+		if failed == 0 {
+			PrintNextStep("status", sourceDir, "verify links")
+		}
+	} else if failed == 0 && copied == 0 && counts[ResultExisting] > 0 {
+		PrintInfo("All symlinks already exist")
+	}
+	if copied > 0 {
+		PrintSummary("Copied %d file(s) where symlinks are not supported", copied)
+	}
+	if failed > 0 {
+		PrintWarning("Failed to create %d symlink(s)", failed)
+	}
+}
+
+// PrintAdoptSummary prints the summary of what Adopt did for sourceDir.
+// Nothing is printed for a dry run or when nothing was adopted.
+func PrintAdoptSummary(result *AdoptResult, sourceDir string) {
+	if result == nil {
+		return
+	}
+	adopted := 0
+	for _, f := range result.Files {
+		if f.Result == ResultAdopted {
+			adopted++
+		}
+	}
+	if adopted == 0 {
+		return
+	}
+	PrintSummary("Adopted %d file(s) successfully", adopted)
+	PrintNextStep("status", sourceDir, "view adopted files")
+}
+
+// PrintPruneSummary prints the summary of what Prune did for sourceDir, with
+// the links pruned from each mapping. Nothing is printed for a dry run or
+// when nothing was pruned.
+func PrintPruneSummary(result *PruneResult, sourceDir string) {
+	if result == nil {
+		return
+	}
+	prunedByMapping := make(map[string]int)
+	var pruned, failed int
+	for _, link := range result.Links {
+		switch link.Result {
+		case ResultPruned:
+			pruned++
+			prunedByMapping[link.Mapping]++
+		case ResultFailed:
+			failed++
+		}
+	}
+	if pruned > 0 {
+		PrintSummary("Pruned %d symlink(s) successfully", pruned)
+		mappings := make([]string, 0, len(prunedByMapping))
+		for m := range prunedByMapping {
+			mappings = append(mappings, m)
+		}
+		sort.Strings(mappings)
+		for _, m := range mappings {
+			PrintDetail("%s: %d", ContractPath(filepath.Join(sourceDir, m)), prunedByMapping[m])
+		}
+	}
+	if failed > 0 {
+		PrintWarning("Failed to prune %d symlink(s)", failed)
+	} else if pruned > 0 {
+		PrintNextStep("status", sourceDir, "view remaining managed files")
+	}
+}
+
+// recordLink records create's decision for link
+func recordLink(link PlannedLink, result, reason string, err error) {
+	if collected == nil {
+		return
+	}
+	if err != nil && reason == "" {
+		reason = err.Error()
+	}
+	collected.links = append(collected.links, LinkResult{Source: link.Source, Target: link.Target, Result: result, Reason: reason, Err: err})
+}
+
+// recordAdopted records adopt's decision for the file at path
+func recordAdopted(path, dest, result, reason string) {
+	if collected == nil {
+		return
+	}
+	collected.adopt.Files = append(collected.adopt.Files, AdoptedFile{Path: path, Dest: dest, Result: result, Reason: reason})
+}
+
+// recordPruned records prune's decision for link, from mapping
+func recordPruned(link ManagedLink, mapping, result, reason string, err error) {
+	if collected == nil {
+		return
+	}
+	if err != nil {
+		reason = err.Error()
+	}
+	collected.prune.Links = append(collected.prune.Links, PrunedLink{Path: link.Path, Source: link.Target, Mapping: mapping, Result: result, Reason: reason, Err: err})
+}
@@ -0,0 +1,174 @@
+package lnk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateLinksResults(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim"}}
+
+	var results []LinkResult
+	var err error
+	output := CaptureOutput(t, func() {
+		opts.DryRun = true
+		results, err = CreateLinksResults(opts)
+	})
+	if err != nil || len(results) != 2 || results[0].Result != ResultPlanned {
+		t.Fatalf("dry run = %+v, %v, want 2 planned links", results, err)
+	}
+	if output != "" {
+		t.Errorf("CreateLinksResults() printed %q", output)
+	}
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+
+	opts.DryRun = false
+	if results, err = CreateLinksResults(opts); err != nil {
+		t.Fatalf("CreateLinksResults() error = %v", err)
+	}
+	for _, r := range results {
+		if r.Result != ResultCreated {
+			t.Errorf("%s: result = %q, want %q", r.Target, r.Result, ResultCreated)
+		}
+	}
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+
+	// A second run finds every link in place
+	if results, err = CreateLinksResults(opts); err != nil || len(results) != 2 || results[1].Result != ResultExisting {
+		t.Errorf("second run = %+v, %v, want 2 existing links", results, err)
+	}
+	if quiet || collected != nil {
+		t.Error("CreateLinksResults() should restore printing")
+	}
+}
+
+func TestAdoptResults(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "repo")
+	targetDir := filepath.Join(tmpDir, "home")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	createTestFile(t, bashrc, "# bashrc")
+
+	result, err := AdoptResults(AdoptOptions{SourceDir: sourceDir, TargetDir: targetDir, Paths: []string{bashrc}})
+	if err != nil {
+		t.Fatalf("AdoptResults() error = %v", err)
+	}
+	if len(result.Files) != 1 || result.Files[0].Result != ResultAdopted || result.Files[0].Path != bashrc ||
+		!strings.HasPrefix(result.Files[0].Dest, sourceDir) {
+		t.Fatalf("AdoptResults() = %+v", result.Files)
+	}
+	assertSymlink(t, bashrc, result.Files[0].Dest)
+}
+
+func TestPruneResults(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "repo")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "# bashrc")
+	createTestSymlink(t, filepath.Join(sourceDir, ".bashrc"), filepath.Join(targetDir, ".bashrc"))
+	createTestSymlink(t, filepath.Join(sourceDir, ".missing"), filepath.Join(targetDir, ".missing"))
+
+	var result *PruneResult
+	var err error
+	output := CaptureOutput(t, func() {
+		result, err = PruneResults(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir})
+	})
+	if err != nil {
+		t.Fatalf("PruneResults() error = %v", err)
+	}
+	if output != "" {
+		t.Errorf("PruneResults() printed %q", output)
+	}
+	if len(result.Links) != 1 || result.Links[0].Result != ResultPruned ||
+		result.Links[0].Path != filepath.Join(targetDir, ".missing") || result.Links[0].Reason == "" {
+		t.Fatalf("PruneResults() = %+v, want .missing pruned with a reason", result.Links)
+	}
+	assertNotExists(t, filepath.Join(targetDir, ".missing"))
+}
+
+func TestResultsDoNotPrompt(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	repoBashrc := filepath.Join(sourceDir, "shell", ".bashrc")
+	content, err := os.ReadFile(repoBashrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, bashrc, string(content))
+
+	// A user at a terminal who would answer yes, and keep the local copy
+	const answers = "y\nl\n"
+	origCanPrompt, origReader := canPrompt, promptReader
+	canPrompt = func() bool { return true }
+	promptReader = bufio.NewReader(strings.NewReader(answers))
+	t.Cleanup(func() { canPrompt, promptReader = origCanPrompt, origReader })
+
+	// The identical file is not replaced without ReplaceIdentical
+	results, err := CreateLinksResults(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}})
+	if err == nil || len(results) != 1 || results[0].Result != ResultFailed {
+		t.Errorf("CreateLinksResults() = %+v, %v; want the identical file's link to fail", results, err)
+	}
+	if info, err := os.Lstat(bashrc); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("%s should still be a regular file", bashrc)
+	}
+
+	// A differing file is a conflict without Prefer
+	createTestFile(t, bashrc, "# local")
+	result, err := AdoptResults(AdoptOptions{SourceDir: filepath.Join(sourceDir, "shell"), TargetDir: targetDir, Paths: []string{bashrc}})
+	if err == nil || len(result.Files) != 1 || result.Files[0].Result != ResultConflict || result.Files[0].Dest != repoBashrc {
+		t.Errorf("AdoptResults() = %+v, %v; want the conflict recorded and returned", result.Files, err)
+	}
+	if got, _ := os.ReadFile(repoBashrc); string(got) != string(content) {
+		t.Errorf("repository copy = %q, want it unchanged", got)
+	}
+
+	if rest, _ := promptReader.ReadString(0); rest != answers {
+		t.Errorf("the Results functions read %q from the terminal", strings.TrimSuffix(answers, rest))
+	}
+	if !canPrompt() {
+		t.Error("the Results functions should restore prompting")
+	}
+}
+
+func TestPrintCreateSummary(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim"}}
+
+	// CreateLinks prints each link; the caller prints the summary
+	var results []LinkResult
+	output := CaptureOutput(t, func() {
+		var err error
+		if results, err = CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "Created: ")
+	NotContainsOutput(t, output, "successfully")
+	if len(results) != 2 || collected != nil {
+		t.Errorf("CreateLinks() = %+v, want 2 results and collecting stopped", results)
+	}
+	output = CaptureOutput(t, func() { PrintCreateSummary(results, sourceDir) })
+	ContainsOutput(t, output, "Created 2 symlink(s) successfully", "lnk status")
+
+	results, _ = CreateLinksResults(opts)
+	output = CaptureOutput(t, func() { PrintCreateSummary(results, sourceDir) })
+	ContainsOutput(t, output, "All symlinks already exist")
+
+	failed := []LinkResult{{Result: ResultCreated}, {Result: ResultFailed}}
+	stdout, stderr := captureOutput(t, func() { PrintCreateSummary(failed, sourceDir) })
+	ContainsOutput(t, stdout, "Created 1 symlink(s) successfully")
+	NotContainsOutput(t, stdout, "lnk status")
+	ContainsOutput(t, stderr, "Failed to create 1 symlink(s)")
+
+	planned := []LinkResult{{Result: ResultPlanned}}
+	if output := CaptureOutput(t, func() { PrintCreateSummary(planned, sourceDir) }); output != "" {
+		t.Errorf("PrintCreateSummary() for a dry run printed %q", output)
+	}
+}
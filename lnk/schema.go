@@ -0,0 +1,104 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// strictConfig makes unknown keys in lnk-package.json an error instead of a
+// warning
+var strictConfig bool
+
+// warnedUnknownKeys remembers the files already warned about, since a
+// command can load the same lnk-package.json more than once
+var warnedUnknownKeys = map[string]bool{}
+
+// SetStrictConfig sets whether unknown configuration keys are errors
+func SetStrictConfig(strict bool) {
+	strictConfig = strict
+}
+
+// checkUnknownKeys compares the keys in a lnk-package.json file with the
+// PackageInfo schema. encoding/json silently drops unknown keys, so a typo
+// like "overide" would otherwise do nothing. Unknown keys are warned about,
+// or with strict config the first one is returned as an error.
+func checkUnknownKeys(path string, data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil // syntax errors are reported by the caller
+	}
+	errs := unknownKeys(raw, reflect.TypeOf(PackageInfo{}), "", path)
+	if len(errs) == 0 {
+		return nil
+	}
+	if strictConfig {
+		return errs[0]
+	}
+	if !warnedUnknownKeys[path] {
+		warnedUnknownKeys[path] = true
+		for _, err := range errs {
+			PrintWarningWithHint(err)
+		}
+	}
+	return nil
+}
+
+// unknownKeys walks raw JSON alongside the struct type it decodes into and
+// returns an error for every object key no field accepts. Keys match json
+// tags case-insensitively, as encoding/json does.
+func unknownKeys(raw any, t reflect.Type, prefix, path string) []error {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return unknownKeys(raw, t.Elem(), prefix, path)
+	case reflect.Slice:
+		items, ok := raw.([]any)
+		if !ok {
+			return nil
+		}
+		var errs []error
+		for i, item := range items {
+			errs = append(errs, unknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i), path)...)
+		}
+		return errs
+	case reflect.Struct:
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := map[string]reflect.Type{}
+		var names []string
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			fields[strings.ToLower(name)] = t.Field(i).Type
+			names = append(names, name)
+		}
+
+		var errs []error
+		for _, key := range sortedKeys(obj) {
+			keyPath := key
+			if prefix != "" {
+				keyPath = prefix + "." + key
+			}
+			fieldType, ok := fields[strings.ToLower(key)]
+			if !ok {
+				errs = append(errs, unknownKeyError(keyPath, key, names, path))
+				continue
+			}
+			errs = append(errs, unknownKeys(obj[key], fieldType, keyPath, path)...)
+		}
+		return errs
+	}
+	return nil
+}
+
+// unknownKeyError reports an unknown key, suggesting the closest valid one
+func unknownKeyError(keyPath, key string, valid []string, path string) error {
+	hint := fmt.Sprintf("Valid keys here: %s", strings.Join(valid, ", "))
+	if match := ClosestMatch(key, valid, max(1, len(key)/3)); match != "" {
+		hint = fmt.Sprintf("Did you mean %q?", match)
+	}
+	return NewValidationErrorWithHint("key", keyPath,
+		fmt.Sprintf("unknown key in %s", ContractPath(path)), hint)
+}
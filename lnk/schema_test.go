@@ -0,0 +1,78 @@
+package lnk
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	data := `{
+		"Description": "case-insensitive match",
+		"descripton": "typo",
+		"overrides": [{"pattern": "a/", "when": {"comand_exists": ["x"]}}],
+		"defaults": [{"domain": "d", "key": "k", "type": "bool", "value": {"any": "thing"}}],
+		"zzz": 1
+	}`
+	var raw any
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := unknownKeys(raw, reflect.TypeOf(PackageInfo{}), "", "/repo/lnk-package.json")
+	want := []struct{ key, hint string }{
+		{"descripton", `Did you mean "description"?`},
+		{"overrides[0].when.comand_exists", `Did you mean "command_exists"?`},
+		{"zzz", "Valid keys here: name, description"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("unknownKeys() = %v, want %d errors", errs, len(want))
+	}
+	for i, w := range want {
+		var verr *ValidationError
+		if !errors.As(errs[i], &verr) || verr.Value != w.key {
+			t.Errorf("errs[%d] = %v, want unknown key %s", i, errs[i], w.key)
+			continue
+		}
+		if !strings.HasPrefix(verr.Hint, w.hint) {
+			t.Errorf("errs[%d] hint = %q, want prefix %q", i, verr.Hint, w.hint)
+		}
+	}
+}
+
+func TestLoadPackageInfoUnknownKeys(t *testing.T) {
+	t.Cleanup(func() { SetStrictConfig(false) })
+
+	pkgDir := t.TempDir()
+	createTestFile(t, filepath.Join(pkgDir, PackageInfoFileName), `{"descripton": "x"}`)
+
+	t.Run("warning", func(t *testing.T) {
+		var info *PackageInfo
+		_, stderr := captureOutput(t, func() {
+			var err error
+			if info, err = LoadPackageInfo(pkgDir); err != nil {
+				t.Fatalf("LoadPackageInfo() error = %v", err)
+			}
+			// A second load of the same file does not warn again
+			if _, err = LoadPackageInfo(pkgDir); err != nil {
+				t.Fatalf("LoadPackageInfo() error = %v", err)
+			}
+		})
+		if info == nil || strings.Count(stderr, "unknown key") != 1 {
+			t.Errorf("stderr = %q, want one unknown key warning", stderr)
+		}
+		ContainsOutput(t, stderr, `Did you mean "description"?`)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		SetStrictConfig(true)
+		_, err := LoadPackageInfo(pkgDir)
+		var verr *ValidationError
+		if !errors.As(err, &verr) || verr.Value != "descripton" {
+			t.Errorf("LoadPackageInfo() error = %v, want unknown key error", err)
+		}
+	})
+}
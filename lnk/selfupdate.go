@@ -0,0 +1,399 @@
+package lnk
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Release channels for self-update
+const (
+	ChannelStable     = "stable"     // releases only (default)
+	ChannelPrerelease = "prerelease" // releases and release candidates, whichever is newer
+)
+
+// Channels lists the valid --channel values
+var Channels = []string{ChannelStable, ChannelPrerelease}
+
+// releaseAPI lists lnk's GitHub releases; tests replace it
+var releaseAPI = "https://api.github.com/repos/cpplain/lnk/releases"
+
+// releaseClient downloads release metadata and artifacts; tests replace it
+var releaseClient = &http.Client{Timeout: 2 * time.Minute}
+
+// releaseSigningKey is the base64 ed25519 public key the checksums of each
+// release are signed with. Release builds set it with
+// -ldflags "-X github.com/cpplain/lnk/lnk.releaseSigningKey=..." ('make
+// release'); builds without it refuse to update unless told to skip the
+// signature.
+var releaseSigningKey = ""
+
+// maxBinarySize bounds the binary extracted from a release archive
+const maxBinarySize = 200 << 20
+
+// SelfUpdateOptions configures SelfUpdate
+type SelfUpdateOptions struct {
+	CurrentVersion string // version of the running binary, as set by ldflags
+	Channel        string // ChannelStable (default) or ChannelPrerelease
+	Executable     string // binary to replace; "" for the running one
+	DryRun         bool   // report the update without installing it
+
+	InsecureSkipSignature bool // trust the checksums file without checking its signature
+}
+
+// githubRelease is the part of a GitHub release lnk uses
+type githubRelease struct {
+	TagName    string        `json:"tag_name"`
+	Draft      bool          `json:"draft"`
+	Prerelease bool          `json:"prerelease"`
+	Assets     []githubAsset `json:"assets"`
+}
+
+type githubAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// SelfUpdate replaces the lnk binary with the newest release on the channel,
+// when it is newer than the running version. The release archive for this OS
+// and architecture is checked against the release's checksums file, whose
+// signature is checked first (unless InsecureSkipSignature); the binary
+// is then written next to the one it replaces and renamed over it, so an
+// interrupted update leaves the old binary in place.
+func SelfUpdate(opts SelfUpdateOptions) error {
+	PrintCommandHeader("Updating lnk")
+
+	channel := opts.Channel
+	if channel == "" {
+		channel = ChannelStable
+	}
+	if channel != ChannelStable && channel != ChannelPrerelease {
+		return NewValidationErrorWithHint("channel", channel, "unknown release channel",
+			fmt.Sprintf("Valid channels: %s", strings.Join(Channels, ", ")))
+	}
+
+	release, version, err := latestRelease(channel)
+	if err != nil {
+		return err
+	}
+	current, isRelease := parseVersion(opts.CurrentVersion)
+	if isRelease && current.compare(version) >= 0 {
+		PrintInfo("lnk %s is up to date (newest on the %s channel: %s)", current, channel, version)
+		return nil
+	}
+	if !isRelease {
+		PrintVerbose("Running a development build (%s); any release replaces it", opts.CurrentVersion)
+	}
+
+	archive, ok := releaseArchive(release, runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return WithHint(fmt.Errorf("release %s has no archive for %s/%s", release.TagName, runtime.GOOS, runtime.GOARCH),
+			"Build lnk from source with 'go install github.com/cpplain/lnk@latest'")
+	}
+	checksums, ok := releaseChecksums(release)
+	if !ok {
+		return WithHint(fmt.Errorf("release %s has no checksums file", release.TagName),
+			"The release cannot be verified; download it manually from https://github.com/cpplain/lnk/releases")
+	}
+
+	exe, err := selfUpdateTarget(opts.Executable)
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		fmt.Fprintln(stdout())
+		PrintDryRun("Would update lnk %s -> %s (%s channel)", opts.CurrentVersion, version, channel)
+		PrintDetail("Download: %s", archive.URL)
+		PrintDetail("Replace: %s", ContractPath(exe))
+		fmt.Fprintln(stdout())
+		PrintDryRunSummary()
+		return nil
+	}
+
+	sums, err := downloadVerifiedChecksums(release, checksums, opts.InsecureSkipSignature)
+	if err != nil {
+		return err
+	}
+	PrintVerbose("Downloading %s", archive.URL)
+	data, err := download(archive.URL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if want, ok := sums[archive.Name]; !ok || want != hex.EncodeToString(sum[:]) {
+		return WithHint(fmt.Errorf("checksum mismatch for %s", archive.Name),
+			"The download may be corrupt or tampered with; nothing was replaced. Try again later")
+	}
+	binary, err := extractBinary(archive.Name, data)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceExecutable(exe, binary, version); err != nil {
+		return err
+	}
+	PrintSuccess("Updated lnk %s -> %s", opts.CurrentVersion, version)
+	return nil
+}
+
+// latestRelease returns the newest published release on channel and its version
+func latestRelease(channel string) (*githubRelease, semver, error) {
+	PrintVerbose("Checking %s", releaseAPI)
+	data, err := download(releaseAPI)
+	if err != nil {
+		return nil, semver{}, err
+	}
+	var releases []githubRelease
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, semver{}, WithHint(fmt.Errorf("failed to parse the release list: %w", err),
+			"GitHub returned something unexpected; try again later")
+	}
+
+	var newest *githubRelease
+	var newestVersion semver
+	for i, r := range releases {
+		if r.Draft || (r.Prerelease && channel != ChannelPrerelease) {
+			continue
+		}
+		v, ok := parseVersion(r.TagName)
+		if !ok || (v.pre != "" && channel != ChannelPrerelease) {
+			continue
+		}
+		if newest == nil || v.compare(newestVersion) > 0 {
+			newest, newestVersion = &releases[i], v
+		}
+	}
+	if newest == nil {
+		return nil, semver{}, WithHint(fmt.Errorf("no releases found on the %s channel", channel),
+			"See https://github.com/cpplain/lnk/releases")
+	}
+	return newest, newestVersion, nil
+}
+
+// releaseArchive returns the release archive for goos and goarch, named
+// lnk_<version>_<goos>_<goarch>.tar.gz (.zip on Windows)
+func releaseArchive(release *githubRelease, goos, goarch string) (githubAsset, bool) {
+	suffix := "_" + goos + "_" + goarch
+	for _, asset := range release.Assets {
+		name := asset.Name
+		for _, ext := range []string{".tar.gz", ".zip"} {
+			if strings.HasPrefix(name, "lnk_") && strings.HasSuffix(name, suffix+ext) {
+				return asset, true
+			}
+		}
+	}
+	return githubAsset{}, false
+}
+
+// releaseChecksums returns the release's checksums file
+func releaseChecksums(release *githubRelease) (githubAsset, bool) {
+	for _, asset := range release.Assets {
+		if strings.HasSuffix(asset.Name, "checksums.txt") {
+			return asset, true
+		}
+	}
+	return githubAsset{}, false
+}
+
+// downloadVerifiedChecksums downloads the checksums file and its .sig
+// signature (base64 ed25519), and returns the checksums by file name. Without a
+// signing key in the build it fails unless skipSignature, which trusts the
+// checksums file as downloaded.
+func downloadVerifiedChecksums(release *githubRelease, checksums githubAsset, skipSignature bool) (map[string]string, error) {
+	if releaseSigningKey == "" && !skipSignature {
+		return nil, WithHint(fmt.Errorf("this build has no release signing key, so the release cannot be verified"),
+			"Install a release build of lnk, or pass --insecure-skip-signature to trust the checksums alone")
+	}
+	data, err := download(checksums.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if skipSignature {
+		PrintWarning("Not checking the signature of %s (--insecure-skip-signature); verifying checksums only", checksums.Name)
+	} else {
+		key, err := base64.StdEncoding.DecodeString(releaseSigningKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid release signing key in this build")
+		}
+		var sig []byte
+		for _, asset := range release.Assets {
+			if asset.Name == checksums.Name+".sig" {
+				encoded, err := download(asset.URL)
+				if err != nil {
+					return nil, err
+				}
+				if sig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded))); err != nil {
+					sig = nil
+				}
+			}
+		}
+		if sig == nil || !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+			return nil, WithHint(fmt.Errorf("the signature of %s does not verify", checksums.Name),
+				"The release may have been tampered with; nothing was replaced")
+		}
+		PrintVerbose("Verified the signature of %s", checksums.Name)
+	}
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+	return sums, nil
+}
+
+// download fetches url
+func download(url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "lnk-self-update")
+	req.Header.Set("Accept", "application/vnd.github+json, application/octet-stream")
+	resp, err := releaseClient.Do(req)
+	if err != nil {
+		return nil, WithHint(fmt.Errorf("failed to download %s: %w", url, err),
+			"Check your network connection")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		hint := "Try again later"
+		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+			hint = "GitHub's rate limit for unauthenticated requests was reached; try again in an hour"
+		}
+		return nil, WithHint(fmt.Errorf("failed to download %s: %s", url, resp.Status), hint)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extractBinary returns the lnk binary from a release archive
+func extractBinary(name string, data []byte) ([]byte, error) {
+	binaryName := "lnk"
+	if runtime.GOOS == "windows" {
+		binaryName = "lnk.exe"
+	}
+	notFound := fmt.Errorf("%s not found in %s", binaryName, name)
+
+	if strings.HasSuffix(name, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		for _, f := range zr.File {
+			if path.Base(f.Name) == binaryName && !f.FileInfo().IsDir() {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read %s: %w", name, err)
+				}
+				defer rc.Close()
+				return io.ReadAll(io.LimitReader(rc, maxBinarySize))
+			}
+		}
+		return nil, notFound
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, notFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if hdr.Typeflag == tar.TypeReg && path.Base(hdr.Name) == binaryName {
+			return io.ReadAll(io.LimitReader(tr, maxBinarySize))
+		}
+	}
+}
+
+// selfUpdateTarget returns the binary to replace, following symlinks to the
+// real file, and refuses binaries a package manager owns
+func selfUpdateTarget(exe string) (string, error) {
+	if exe == "" {
+		var err error
+		if exe, err = os.Executable(); err != nil {
+			return "", WithHint(fmt.Errorf("failed to find the running binary: %w", err),
+				"Download the release manually from https://github.com/cpplain/lnk/releases")
+		}
+	}
+	if resolved, err := fsys.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	if strings.Contains(filepath.ToSlash(exe), "/Cellar/") {
+		return "", NewPathErrorWithHint("update", exe, errors.New("installed by Homebrew"),
+			"Run 'brew upgrade lnk' instead, so Homebrew keeps track of the version")
+	}
+	return exe, nil
+}
+
+// replaceExecutable writes binary next to exe, checks that it runs and
+// reports want, and renames it over exe. On Windows, where a running binary
+// cannot be replaced, exe is first moved aside to exe.old.
+func replaceExecutable(exe string, binary []byte, want semver) error {
+	dir := filepath.Dir(exe)
+	tmp, err := fsys.CreateTemp(dir, ".lnk-update-*")
+	if err != nil {
+		return NewPathErrorWithHint("update", exe, err,
+			fmt.Sprintf("Check that %s is writable, or rerun with sudo if lnk was installed system-wide", ContractPath(dir)))
+	}
+	tmpName := tmp.Name()
+	defer fsys.Remove(tmpName)
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return NewPathError("update", tmpName, err)
+	}
+	if err := tmp.Chmod(0755); err != nil {
+		tmp.Close()
+		return NewPathError("update", tmpName, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return NewPathError("update", tmpName, err)
+	}
+
+	// The new binary must run here and be the release it claims to be
+	out, err := exec.Command(tmpName, "--version").Output()
+	if err != nil || !strings.Contains(string(out), want.String()) {
+		return WithHint(fmt.Errorf("the downloaded binary does not run as lnk %s on this machine", want),
+			"Nothing was replaced; report this at https://github.com/cpplain/lnk/issues")
+	}
+
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		_ = fsys.Remove(old)
+		if err := fsys.Rename(exe, old); err != nil {
+			return NewPathError("update", exe, err)
+		}
+	}
+	if err := fsys.Rename(tmpName, exe); err != nil {
+		return NewPathErrorWithHint("update", exe, err,
+			fmt.Sprintf("Check that %s is writable, or rerun with sudo if lnk was installed system-wide", ContractPath(dir)))
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+//go:build unix
+
+package lnk
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// serveReleases serves a release list with v0.5.0 and v0.6.0-rc.1 and the
+// v0.5.0 artifacts, signed with a key the test build trusts. It returns the
+// new binary and a function that corrupts the checksums signature.
+func serveReleases(t *testing.T) ([]byte, func()) {
+	t.Helper()
+	binary := []byte("#!/bin/sh\necho 'lnk v0.5.0'\n")
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "lnk", Mode: 0755, Size: int64(len(binary)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(binary)
+	tw.Close()
+	gz.Close()
+
+	archiveName := fmt.Sprintf("lnk_0.5.0_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	sum := sha256.Sum256(archive.Bytes())
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  " + archiveName + "\n")
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, checksums))
+
+	files := map[string][]byte{
+		archiveName:            archive.Bytes(),
+		"checksums.txt":        checksums,
+		"checksums.txt.sig":    []byte(sig),
+		"lnk_0.6.0-rc.1_x.zip": nil,
+	}
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	asset := func(name string) githubAsset { return githubAsset{Name: name, URL: server.URL + "/dl/" + name} }
+	releases := []githubRelease{
+		{TagName: "v0.6.0-rc.1", Prerelease: true, Assets: []githubAsset{asset("lnk_0.6.0-rc.1_x.zip")}},
+		{TagName: "v0.5.0", Assets: []githubAsset{asset(archiveName), asset("checksums.txt"), asset("checksums.txt.sig")}},
+		{TagName: "v0.4.0"},
+	}
+	mux.HandleFunc("/releases", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(releases)
+	})
+	mux.HandleFunc("/dl/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(files[filepath.Base(r.URL.Path)])
+	})
+
+	savedAPI, savedClient, savedKey := releaseAPI, releaseClient, releaseSigningKey
+	releaseAPI, releaseClient = server.URL+"/releases", server.Client()
+	releaseSigningKey = base64.StdEncoding.EncodeToString(pub)
+	t.Cleanup(func() { releaseAPI, releaseClient, releaseSigningKey = savedAPI, savedClient, savedKey })
+
+	return binary, func() {
+		files["checksums.txt.sig"] = []byte(base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize)))
+	}
+}
+
+func TestSelfUpdate(t *testing.T) {
+	binary, corruptSignature := serveReleases(t)
+	exe := filepath.Join(t.TempDir(), "lnk")
+	createTestFile(t, exe, "old")
+
+	run := func(opts SelfUpdateOptions) (string, error) {
+		opts.Executable = exe
+		var err error
+		output := CaptureOutput(t, func() { err = SelfUpdate(opts) })
+		return output, err
+	}
+	assertContent := func(want string) {
+		t.Helper()
+		if data, _ := os.ReadFile(exe); string(data) != want {
+			t.Errorf("binary = %q, want %q", data, want)
+		}
+	}
+
+	// Up to date on the stable channel
+	output, err := run(SelfUpdateOptions{CurrentVersion: "v0.5.0"})
+	if err != nil {
+		t.Fatalf("SelfUpdate() error = %v", err)
+	}
+	ContainsOutput(t, output, "up to date")
+	assertContent("old")
+
+	// The prerelease channel picks the release candidate, which has no
+	// archive for this platform
+	output, err = run(SelfUpdateOptions{CurrentVersion: "v0.5.0", Channel: ChannelPrerelease, DryRun: true})
+	if err == nil {
+		t.Errorf("SelfUpdate() should fail without an archive for this platform, output:\n%s", output)
+	}
+
+	// A dry run only reports the update
+	output, err = run(SelfUpdateOptions{CurrentVersion: "v0.4.0", DryRun: true})
+	if err != nil {
+		t.Fatalf("SelfUpdate() dry run error = %v", err)
+	}
+	ContainsOutput(t, output, "Would update lnk v0.4.0 -> 0.5.0")
+	assertContent("old")
+
+	if _, err := run(SelfUpdateOptions{CurrentVersion: "v0.4.0"}); err != nil {
+		t.Fatalf("SelfUpdate() error = %v", err)
+	}
+	assertContent(string(binary))
+
+	// A bad signature replaces nothing
+	createTestFile(t, exe, "old")
+	corruptSignature()
+	if _, err := run(SelfUpdateOptions{CurrentVersion: "v0.4.0"}); err == nil {
+		t.Error("SelfUpdate() should refuse a checksums file whose signature does not verify")
+	}
+	assertContent("old")
+
+	// Skipping the signature is explicit and trusts the checksums alone
+	_, stderr := captureOutput(t, func() {
+		err = SelfUpdate(SelfUpdateOptions{CurrentVersion: "v0.4.0", Executable: exe, InsecureSkipSignature: true})
+	})
+	if err != nil {
+		t.Fatalf("SelfUpdate(InsecureSkipSignature) error = %v", err)
+	}
+	ContainsOutput(t, stderr, "Not checking the signature")
+	assertContent(string(binary))
+
+	// A build without a signing key refuses to update
+	createTestFile(t, exe, "old")
+	releaseSigningKey = ""
+	if _, err := run(SelfUpdateOptions{CurrentVersion: "v0.4.0"}); err == nil || GetErrorHint(err) == "" {
+		t.Errorf("SelfUpdate() without a signing key error = %v, want an error with a hint", err)
+	}
+	assertContent("old")
+
+	if _, err := run(SelfUpdateOptions{Channel: "nightly"}); err == nil {
+		t.Error("SelfUpdate() should refuse an unknown channel")
+	}
+}
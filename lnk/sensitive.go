@@ -0,0 +1,86 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// encryptedHeaders are the leading bytes of files written by common encryption
+// tools: age (binary and armored), OpenPGP (armored), git-crypt, and Ansible Vault
+var encryptedHeaders = [][]byte{
+	[]byte("age-encryption.org/v1"),
+	[]byte("-----BEGIN AGE ENCRYPTED FILE-----"),
+	[]byte("-----BEGIN PGP MESSAGE-----"),
+	[]byte("\x00GITCRYPT\x00"),
+	[]byte("$ANSIBLE_VAULT;"),
+}
+
+// LoadSensitiveFile loads the sensitive patterns from a .lnksensitive file in
+// the source directory. Files matching them must not be stored in the
+// repository in plaintext: adopt refuses them and lint reports them.
+func LoadSensitiveFile(sourceDir string) ([]string, error) {
+	sensitiveFilePath := filepath.Join(sourceDir, SensitiveFileName)
+	if _, err := os.Stat(sensitiveFilePath); os.IsNotExist(err) {
+		PrintVerbose("No .lnksensitive file found at: %s", sensitiveFilePath)
+		return nil, nil
+	}
+
+	// Same line format as .lnkignore
+	patterns, err := parseIgnoreFile(sensitiveFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .lnksensitive: %w", err)
+	}
+
+	PrintVerbose("Loaded %d sensitive patterns from .lnksensitive", len(patterns))
+	return patterns, nil
+}
+
+// sensitivePattern returns the sensitive pattern matching relPath, a path
+// relative to the target directory (or to a package directory, which mirrors
+// it). Patterns follow .lnkignore rules; a leading ~/ is dropped.
+func sensitivePattern(patterns []string, relPath string) (string, bool) {
+	if len(patterns) == 0 {
+		return "", false
+	}
+	return NewPatternMatcher(targetPatterns(patterns)).MatchingPattern(relPath)
+}
+
+// sensitiveRepoPattern matches a file in the source directory, whose path is
+// relative to the source directory. Files in a package directory are also
+// matched by their path inside the package, which is where they are linked.
+func sensitiveRepoPattern(patterns []string, relPath string) (string, bool) {
+	if pattern, ok := sensitivePattern(patterns, relPath); ok {
+		return pattern, true
+	}
+	if _, inPackage, ok := strings.Cut(filepath.ToSlash(relPath), "/"); ok {
+		return sensitivePattern(patterns, inPackage)
+	}
+	return "", false
+}
+
+// isEncryptedFile reports whether path starts with the header of a known
+// encryption format
+func isEncryptedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 64)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, err
+	}
+	head = head[:n]
+	for _, header := range encryptedHeaders {
+		if bytes.HasPrefix(head, header) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,203 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Shells lnk generates code or prompt output for (--shell)
+const (
+	ShellBash  = "bash"
+	ShellZsh   = "zsh"
+	ShellFish  = "fish"
+	ShellPlain = "plain" // no shell-specific markup (prompt-status only)
+)
+
+// Shells lists the valid --shell values
+var Shells = []string{ShellBash, ShellZsh, ShellFish, ShellPlain}
+
+// ShellenvOptions holds configuration for shellenv
+type ShellenvOptions struct {
+	SourceDir      string              // source directory the prompt hook checks
+	TargetDir      string              // where links are created (default: ~)
+	Shell          string              // bash, zsh, or fish (default: from $SHELL)
+	Packages       []string            // exported as LNK_PACKAGES when set
+	IgnorePatterns []string            // exported as LNK_IGNORE when set
+	NoColor        bool                // exported as LNK_NO_COLOR when set
+	Commands       []string            // commands offered by completion
+	Actions        map[string][]string // actions offered by completion after a command (e.g. packages list)
+	Flags          []string            // flags offered by completion
+}
+
+// Shellenv prints shell code to evaluate in a startup file: it exports the LNK_
+// variables for the settings given on its command line, puts the user bin
+// directory on PATH, and defines completion for lnk and a prompt hook that
+// shows 'lnk prompt-status'. Evaluating it more than once is harmless.
+func Shellenv(opts ShellenvOptions) error {
+	paths, err := ResolvePaths(opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+
+	shell := opts.Shell
+	if shell == "" {
+		shell = filepath.Base(os.Getenv("SHELL"))
+	}
+	switch shell {
+	case ShellBash, ShellZsh, ShellFish:
+	default:
+		return NewValidationErrorWithHint("shell", shell, "shellenv supports bash, zsh, and fish",
+			"Choose the shell with --shell, e.g. lnk shellenv --shell zsh ~/git/dotfiles")
+	}
+
+	fmt.Print(shellenvScript(shell, paths.SourceDir, opts))
+	return nil
+}
+
+// flagCompletions maps flags to the 'lnk __complete' kind of their values
+var flagCompletions = map[string]string{
+	"--packages": CompletePackages,
+	"--source":   CompleteMappings,
+}
+
+// shellenvScript generates the shellenv code for shell
+func shellenvScript(shell, sourceDir string, opts ShellenvOptions) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# lnk shellenv for %s\n", shell)
+
+	// Environment: the settings given to shellenv, as their LNK_ variables
+	export := func(name, value string) {
+		if shell == ShellFish {
+			fmt.Fprintf(&sb, "set -gx %s %s\n", name, fishQuote(value))
+		} else {
+			fmt.Fprintf(&sb, "export %s=%s\n", name, shQuote(value))
+		}
+	}
+	if len(opts.Packages) > 0 {
+		export(EnvPackages, strings.Join(opts.Packages, ","))
+	}
+	if len(opts.IgnorePatterns) > 0 {
+		export(EnvIgnore, strings.Join(opts.IgnorePatterns, ","))
+	}
+	if opts.NoColor {
+		export(EnvNoColor, "1")
+	}
+
+	// Completion word lists; commands, actions, and flags never need quoting
+	words := strings.Join(opts.Commands, " ")
+	withActions := strings.Join(sortedKeys(opts.Actions), " ")
+	var actionList []string
+	for _, list := range opts.Actions {
+		for _, a := range list {
+			if !slices.Contains(actionList, a) {
+				actionList = append(actionList, a)
+			}
+		}
+	}
+	slices.Sort(actionList)
+	actions := strings.Join(actionList, " ")
+	flags := strings.Join(opts.Flags, " ")
+
+	switch shell {
+	case ShellBash:
+		fmt.Fprintf(&sb, `case ":$PATH:" in
+  *":$HOME/%[1]s:"*) ;;
+  *) export PATH="$HOME/%[1]s:$PATH" ;;
+esac
+_lnk_complete() {
+  local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]} IFS=$' \t\n'
+  if [ "$COMP_CWORD" -eq 1 ]; then
+    COMPREPLY=($(compgen -W %[2]s -- "$cur"))
+  elif [ "$prev" = --packages ] || [ "$prev" = --source ]; then
+    local kind=packages
+    [ "$prev" = --source ] && kind=mappings
+    IFS=$'\n'
+    COMPREPLY=($(lnk __complete "$kind" %[5]s "$cur" 2>/dev/null))
+  elif [ "$COMP_CWORD" -eq 2 ] && [[ " %[6]s " == *" ${COMP_WORDS[1]} "* ]]; then
+    COMPREPLY=($(compgen -W %[3]s -- "$cur"))
+  elif [[ $cur == -* ]]; then
+    COMPREPLY=($(compgen -W %[4]s -- "$cur"))
+  elif [ "$COMP_CWORD" -ge 3 ] && [[ ${COMP_WORDS[1]} == remove || ${COMP_WORDS[1]} == orphan ]]; then
+    IFS=$'\n'
+    COMPREPLY=($(lnk __complete managed-paths %[5]s "$cur" 2>/dev/null))
+  else
+    COMPREPLY=($(compgen -f -- "$cur"))
+  fi
+}
+complete -o filenames -F _lnk_complete lnk
+__lnk_prompt() { lnk prompt-status --shell bash %[5]s 2>/dev/null; }
+case "$PS1" in
+  *__lnk_prompt*) ;;
+  *) PS1='$(__lnk_prompt) '"$PS1" ;;
+esac
+`, filepath.ToSlash(binDir), shQuote(words), shQuote(actions), shQuote(flags), shQuote(sourceDir), withActions)
+	case ShellZsh:
+		fmt.Fprintf(&sb, `case ":$PATH:" in
+  *":$HOME/%[1]s:"*) ;;
+  *) export PATH="$HOME/%[1]s:$PATH" ;;
+esac
+_lnk() {
+  if (( CURRENT == 2 )); then
+    compadd -- %[2]s
+  elif [[ ${words[CURRENT-1]} == --packages ]]; then
+    compadd -- ${(f)"$(lnk __complete packages %[5]s 2>/dev/null)"}
+  elif [[ ${words[CURRENT-1]} == --source ]]; then
+    compadd -- ${(f)"$(lnk __complete mappings %[5]s 2>/dev/null)"}
+  elif (( CURRENT == 3 )) && [[ " %[6]s " == *" ${words[2]} "* ]]; then
+    compadd -- %[3]s
+  elif [[ $PREFIX == -* ]]; then
+    compadd -- %[4]s
+  elif (( CURRENT >= 4 )) && [[ ${words[2]} == (remove|orphan) ]]; then
+    compadd -Q -- ${(f)"$(lnk __complete managed-paths %[5]s "$PREFIX" 2>/dev/null)"}
+  else
+    _files
+  fi
+}
+(( $+functions[compdef] )) && compdef _lnk lnk
+__lnk_prompt() { lnk prompt-status --shell zsh %[5]s 2>/dev/null }
+setopt prompt_subst
+[[ $PROMPT == *__lnk_prompt* ]] || PROMPT='$(__lnk_prompt) '$PROMPT
+`, filepath.ToSlash(binDir), words, actions, flags, shQuote(sourceDir), withActions)
+	case ShellFish:
+		fmt.Fprintf(&sb, "fish_add_path -g \"$HOME/%s\"\n", filepath.ToSlash(binDir))
+		fmt.Fprintf(&sb, "complete -c lnk -f -n __fish_use_subcommand -a %s\n", fishQuote(words))
+		fmt.Fprintf(&sb, "complete -c lnk -f -n '__fish_seen_subcommand_from %s' -a %s\n", withActions, fishQuote(actions))
+		for _, flag := range opts.Flags {
+			if name, ok := strings.CutPrefix(flag, "--"); ok {
+				fmt.Fprintf(&sb, "complete -c lnk -l %s", name)
+				if kind, ok := flagCompletions[flag]; ok {
+					fmt.Fprintf(&sb, " -x -a %s", fishQuote(fmt.Sprintf("(lnk __complete %s %s 2>/dev/null)", kind, fishQuote(sourceDir))))
+				}
+				sb.WriteString("\n")
+			}
+		}
+		fmt.Fprintf(&sb, "complete -c lnk -n '__fish_seen_subcommand_from remove orphan' -a %s\n",
+			fishQuote(fmt.Sprintf("(lnk __complete %s %s (commandline -ct) 2>/dev/null)", CompleteManagedPaths, fishQuote(sourceDir))))
+		fmt.Fprintf(&sb, `function __lnk_prompt
+    lnk prompt-status --shell fish %s 2>/dev/null
+end
+if not functions -q __lnk_original_prompt; and functions -q fish_prompt
+    functions -c fish_prompt __lnk_original_prompt
+    function fish_prompt
+        echo -n (__lnk_prompt)' '
+        __lnk_original_prompt
+    end
+end
+`, fishQuote(sourceDir))
+	}
+	return sb.String()
+}
+
+// shQuote quotes s for bash and zsh
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for fish, where backslash and quote are escaped inside
+// single quotes
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
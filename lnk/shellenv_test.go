@@ -0,0 +1,86 @@
+package lnk
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestShellenvScript(t *testing.T) {
+	opts := ShellenvOptions{
+		Packages: []string{"shell", "nvim"},
+		NoColor:  true,
+		Commands: []string{"create", "packages"},
+		Actions:  map[string][]string{"packages": {"list"}},
+		Flags:    []string{"--dry-run", "--packages", "--shell"},
+	}
+	sourceDir := "/home/u/it's dotfiles"
+
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{ShellBash, []string{
+			"export LNK_PACKAGES='shell,nvim'",
+			"export LNK_NO_COLOR='1'",
+			`*) export PATH="$HOME/.local/bin:$PATH" ;;`,
+			"compgen -W 'create packages'",
+			"compgen -W 'list'",
+			"compgen -W '--dry-run --packages --shell'",
+			`COMPREPLY=($(lnk __complete "$kind" '/home/u/it'\''s dotfiles' "$cur" 2>/dev/null))`,
+			`COMPREPLY=($(lnk __complete managed-paths '/home/u/it'\''s dotfiles' "$cur" 2>/dev/null))`,
+			"complete -o filenames -F _lnk_complete lnk",
+			`lnk prompt-status --shell bash '/home/u/it'\''s dotfiles'`,
+			"*__lnk_prompt*) ;;",
+		}},
+		{ShellZsh, []string{
+			"export LNK_PACKAGES='shell,nvim'",
+			`*) export PATH="$HOME/.local/bin:$PATH" ;;`,
+			"compadd -- create packages",
+			"compdef _lnk lnk",
+			`compadd -- ${(f)"$(lnk __complete packages '/home/u/it'\''s dotfiles' 2>/dev/null)"}`,
+			`compadd -Q -- ${(f)"$(lnk __complete managed-paths '/home/u/it'\''s dotfiles' "$PREFIX" 2>/dev/null)"}`,
+			`lnk prompt-status --shell zsh '/home/u/it'\''s dotfiles'`,
+			"setopt prompt_subst",
+		}},
+		{ShellFish, []string{
+			"set -gx LNK_PACKAGES 'shell,nvim'",
+			"set -gx LNK_NO_COLOR '1'",
+			`fish_add_path -g "$HOME/.local/bin"`,
+			"complete -c lnk -f -n __fish_use_subcommand -a 'create packages'",
+			"complete -c lnk -f -n '__fish_seen_subcommand_from packages' -a 'list'",
+			"complete -c lnk -l dry-run\n",
+			`complete -c lnk -l packages -x -a '(lnk __complete packages \'/home/u/it\\\'s dotfiles\' 2>/dev/null)'`,
+			`complete -c lnk -n '__fish_seen_subcommand_from remove orphan' -a '(lnk __complete managed-paths`,
+			`lnk prompt-status --shell fish '/home/u/it\'s dotfiles'`,
+			"functions -c fish_prompt __lnk_original_prompt",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			got := shellenvScript(tt.shell, sourceDir, opts)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("script missing %q:\n%s", want, got)
+				}
+			}
+			if strings.Contains(got, EnvIgnore) {
+				t.Errorf("script exports %s without --ignore:\n%s", EnvIgnore, got)
+			}
+		})
+	}
+}
+
+func TestShellenvUnsupportedShell(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	for _, shell := range []string{ShellPlain, "tcsh"} {
+		err := Shellenv(ShellenvOptions{SourceDir: sourceDir, TargetDir: targetDir, Shell: shell})
+		var valErr *ValidationError
+		if !errors.As(err, &valErr) {
+			t.Fatalf("Shellenv(%q) error = %v, want ValidationError", shell, err)
+		}
+		if GetErrorHint(err) == "" {
+			t.Errorf("Shellenv(%q) error has no hint", shell)
+		}
+	}
+}
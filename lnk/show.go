@@ -0,0 +1,236 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output formats for 'lnk config show'
+const (
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// OutputFormats lists the valid --output values
+var OutputFormats = []string{OutputJSON, OutputYAML}
+
+// ConfigShowOptions holds options for printing configuration
+type ConfigShowOptions struct {
+	Packages  []string // packages given with --packages
+	Effective bool     // print the merged configuration instead of each source
+	Output    string   // OutputJSON (default) or OutputYAML
+}
+
+// EffectiveConfig is the fully merged configuration every command runs with.
+// Paths are absolute and packages include their dependencies.
+type EffectiveConfig struct {
+	SourceDir      string   `json:"source_dir"`
+	TargetDir      string   `json:"target_dir"`
+	Packages       []string `json:"packages"`        // empty = whole source directory
+	PackagesFrom   string   `json:"packages_from"`   // --packages, .lnkpackages, or default
+	PackageDirs    []string `json:"package_dirs"`    // directories that are linked
+	IgnorePatterns []string `json:"ignore_patterns"` // in the order they are applied
+	LocalOnly      []string `json:"local_only"`      // target paths lnk never touches
+	Sensitive      []string `json:"sensitive"`       // files that must not be stored in plaintext
+	Dirs           []string `json:"dirs"`            // how missing parent directories are created
+	Maps           []string `json:"maps"`            // saved mappings, as SRC:TGT[:MODE]
+	Workflow       []string `json:"workflow"`        // steps of lnk up and lnk down turned on or off
+	Protected      []string `json:"protected"`       // target paths lnk never creates, removes, or overwrites
+}
+
+// ConfigShow prints the configuration as JSON or YAML: the entries of each
+// source as written, or with opts.Effective the merged result.
+func ConfigShow(config *Config, opts ConfigShowOptions) error {
+	var v any = struct {
+		Sources []ConfigSource `json:"sources"`
+	}{configSources(config, opts.Packages)}
+
+	if opts.Effective {
+		effective, err := effectiveConfig(config, opts.Packages)
+		if err != nil {
+			return err
+		}
+		v = effective
+	}
+
+	if opts.Output == OutputYAML {
+		return writeYAML(os.Stdout, v)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// effectiveConfig merges config with the --packages selection and expands
+// package dependencies
+func effectiveConfig(config *Config, cliPackages []string) (*EffectiveConfig, error) {
+	packages, from := config.ResolvePackages(cliPackages)
+	packages, err := expandPackageDeps(config.SourceDir, packages)
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := packageDirs(config.SourceDir, packages)
+	if err != nil {
+		return nil, err
+	}
+	if packages == nil {
+		packages = []string{}
+	}
+	localOnly, sensitive := config.LocalOnly, config.Sensitive
+	if localOnly == nil {
+		localOnly = []string{}
+	}
+	if sensitive == nil {
+		sensitive = []string{}
+	}
+	dirPolicy := config.Dirs.Entries()
+	if dirPolicy == nil {
+		dirPolicy = []string{}
+	}
+	maps := []string{}
+	for _, m := range config.Maps {
+		maps = append(maps, m.String())
+	}
+	workflow := config.Workflow.Entries()
+	if workflow == nil {
+		workflow = []string{}
+	}
+	protected := config.Protected
+	if protected == nil {
+		protected = []string{}
+	}
+	return &EffectiveConfig{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		Packages:       packages,
+		PackagesFrom:   from,
+		PackageDirs:    dirs,
+		IgnorePatterns: config.IgnorePatterns,
+		LocalOnly:      localOnly,
+		Sensitive:      sensitive,
+		Dirs:           dirPolicy,
+		Maps:           maps,
+		Workflow:       workflow,
+		Protected:      protected,
+	}, nil
+}
+
+// writeYAML writes v as block-style YAML. It supports what configuration and
+// template variables are made of: structs (keyed by their json tags), maps
+// with string keys (sorted), slices, strings, and bools.
+func writeYAML(w io.Writer, v any) error {
+	var sb strings.Builder
+	yamlValue(&sb, reflect.ValueOf(v), 0, false)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// yamlValue appends v at the given indent. inList is true for the first field
+// of a struct that is a list item, which goes on the "- " line.
+func yamlValue(sb *strings.Builder, v reflect.Value, indent int, inList bool) {
+	pad := strings.Repeat("  ", indent)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		yamlValue(sb, v.Elem(), indent, inList)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+			prefix := pad
+			if inList && i == 0 {
+				prefix = ""
+			}
+			field := v.Field(i)
+			switch {
+			case field.Kind() == reflect.Slice && field.Len() == 0:
+				fmt.Fprintf(sb, "%s%s: []\n", prefix, name)
+			case field.Kind() == reflect.Slice || field.Kind() == reflect.Struct:
+				fmt.Fprintf(sb, "%s%s:\n", prefix, name)
+				yamlValue(sb, field, indent+1, false)
+			default:
+				fmt.Fprintf(sb, "%s%s: %s\n", prefix, name, yamlScalar(field))
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for i, key := range keys {
+			prefix := pad
+			if inList && i == 0 {
+				prefix = ""
+			}
+			value := v.MapIndex(key)
+			for value.Kind() == reflect.Interface || value.Kind() == reflect.Pointer {
+				value = value.Elem()
+			}
+			switch {
+			case value.Kind() == reflect.Slice && value.Len() == 0:
+				fmt.Fprintf(sb, "%s%s: []\n", prefix, yamlScalar(key))
+			case value.Kind() == reflect.Map && value.Len() == 0:
+				fmt.Fprintf(sb, "%s%s: {}\n", prefix, yamlScalar(key))
+			case value.Kind() == reflect.Slice || value.Kind() == reflect.Map || value.Kind() == reflect.Struct:
+				fmt.Fprintf(sb, "%s%s:\n", prefix, yamlScalar(key))
+				yamlValue(sb, value, indent+1, false)
+			default:
+				fmt.Fprintf(sb, "%s%s: %s\n", prefix, yamlScalar(key), yamlScalar(value))
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			for item.Kind() == reflect.Interface || item.Kind() == reflect.Pointer {
+				item = item.Elem()
+			}
+			if item.Kind() == reflect.Struct || item.Kind() == reflect.Map && item.Len() > 0 {
+				sb.WriteString(pad + "- ")
+				yamlValue(sb, item, indent+1, true)
+				continue
+			}
+			switch {
+			case item.Kind() == reflect.Map:
+				fmt.Fprintf(sb, "%s- {}\n", pad)
+				continue
+			case item.Kind() == reflect.Slice && item.Len() == 0:
+				fmt.Fprintf(sb, "%s- []\n", pad)
+				continue
+			}
+			fmt.Fprintf(sb, "%s- %s\n", pad, yamlScalar(item))
+		}
+	}
+}
+
+// yamlPlain matches strings that YAML reads back unchanged without quotes
+var yamlPlain = regexp.MustCompile(`^[A-Za-z_/.][A-Za-z0-9_/.~-]*$`)
+
+// yamlReserved are plain words YAML would read as booleans or null
+var yamlReserved = []string{"true", "false", "yes", "no", "on", "off", "y", "n", "null"}
+
+// yamlScalar formats a string, bool, or number, quoting strings that are not
+// plain
+func yamlScalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface())
+	case reflect.Invalid:
+		return "null"
+	}
+	s := v.String()
+	for _, word := range yamlReserved {
+		if strings.EqualFold(s, word) {
+			return strconv.Quote(s)
+		}
+	}
+	if yamlPlain.MatchString(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
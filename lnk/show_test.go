@@ -0,0 +1,123 @@
+package lnk
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConfigShow(t *testing.T) {
+	sourceDir, _ := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", RequiresFileName), "shell\n")
+
+	config, err := LoadConfig(sourceDir, []string{"*.bak"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.LoadPrivate(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("sources", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			if err := ConfigShow(config, ConfigShowOptions{}); err != nil {
+				t.Fatalf("ConfigShow() error = %v", err)
+			}
+		})
+		var got struct {
+			Sources []ConfigSource `json:"sources"`
+		}
+		if err := json.Unmarshal([]byte(output), &got); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, output)
+		}
+		if len(got.Sources) != 17 || got.Sources[16].Name != "--packages" || got.Sources[16].Values == nil {
+			t.Errorf("Sources = %+v, want 17 sources ending with --packages", got.Sources)
+		}
+		if !slices.Equal(got.Sources[15].Values, []string{"*.bak"}) {
+			t.Errorf("--ignore values = %v, want [*.bak]", got.Sources[15].Values)
+		}
+	})
+
+	t.Run("effective", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			err := ConfigShow(config, ConfigShowOptions{Packages: []string{"nvim"}, Effective: true})
+			if err != nil {
+				t.Fatalf("ConfigShow() error = %v", err)
+			}
+		})
+		var got EffectiveConfig
+		if err := json.Unmarshal([]byte(output), &got); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, output)
+		}
+		if got.SourceDir != sourceDir || got.PackagesFrom != "--packages" {
+			t.Errorf("got %+v", got)
+		}
+		if !slices.Equal(got.Packages, []string{"shell", "nvim"}) {
+			t.Errorf("Packages = %v, want dependencies expanded", got.Packages)
+		}
+		wantDirs := []string{filepath.Join(sourceDir, "shell"), filepath.Join(sourceDir, "nvim")}
+		if !slices.Equal(got.PackageDirs, wantDirs) {
+			t.Errorf("PackageDirs = %v, want %v", got.PackageDirs, wantDirs)
+		}
+		if got.IgnorePatterns[len(got.IgnorePatterns)-1] != "*.bak" {
+			t.Errorf("IgnorePatterns = %v, want --ignore last", got.IgnorePatterns)
+		}
+	})
+
+	t.Run("unknown package", func(t *testing.T) {
+		CaptureOutput(t, func() {
+			err := ConfigShow(config, ConfigShowOptions{Packages: []string{"nope"}, Effective: true})
+			if err == nil {
+				t.Error("ConfigShow() expected error for unknown package")
+			}
+		})
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		output := CaptureOutput(t, func() {
+			if err := ConfigShow(config, ConfigShowOptions{Effective: true, Output: OutputYAML}); err != nil {
+				t.Fatalf("ConfigShow() error = %v", err)
+			}
+		})
+		ContainsOutput(t, output,
+			"source_dir: "+sourceDir+"\n",
+			"packages: []\n",
+			"packages_from: default\n",
+			"  - "+sourceDir+"\n",
+			`  - "*.bak"`,
+		)
+	})
+}
+
+func TestWriteYAML(t *testing.T) {
+	type item struct {
+		Name  string   `json:"name"`
+		On    bool     `json:"on"`
+		Items []string `json:"items"`
+	}
+	v := struct {
+		Title string `json:"title"`
+		List  []item `json:"list"`
+	}{"--x", []item{{"true", true, []string{"a b", ".git"}}, {"plain", false, nil}}}
+
+	var sb strings.Builder
+	if err := writeYAML(&sb, v); err != nil {
+		t.Fatal(err)
+	}
+	want := `title: "--x"
+list:
+  - name: "true"
+    on: true
+    items:
+      - "a b"
+      - .git
+  - name: plain
+    on: false
+    items: []
+`
+	if sb.String() != want {
+		t.Errorf("writeYAML() =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
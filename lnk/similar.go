@@ -0,0 +1,85 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LevenshteinDistance computes the edit distance between two strings.
+func LevenshteinDistance(a, b string) int {
+	la, lb := len(a), len(b)
+	if la == 0 {
+		return lb
+	}
+	if lb == 0 {
+		return la
+	}
+
+	// Use single-row optimization
+	prev := make([]int, lb+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= la; i++ {
+		curr := make([]int, lb+1)
+		curr[0] = i
+		for j := 1; j <= lb; j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(curr[j-1]+1, min(prev[j]+1, prev[j-1]+cost))
+		}
+		prev = curr
+	}
+	return prev[lb]
+}
+
+// ClosestMatch returns the candidate with the smallest edit distance to input,
+// or an empty string if none is within maxDist. Ties go to the earlier candidate.
+func ClosestMatch(input string, candidates []string, maxDist int) string {
+	best, bestDist := "", maxDist+1
+	for _, c := range candidates {
+		if d := LevenshteinDistance(input, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// suggestPath returns the candidate path closest to path, allowing one edit
+// for every four characters of the file name (at least one). Paths are longer
+// than command names, so this is stricter than command suggestions.
+func suggestPath(path string, candidates []string) string {
+	match := ClosestMatch(path, candidates, max(1, len(filepath.Base(path))/4))
+	if match == path {
+		return ""
+	}
+	return match
+}
+
+// pathHint returns a "Did you mean" hint for the candidate closest to path, or
+// hint when no candidate is close enough
+func pathHint(path string, candidates []string, hint string) string {
+	if match := suggestPath(path, candidates); match != "" {
+		PrintVerbose("Closest match for %s: %s", ContractPath(path), ContractPath(match))
+		return fmt.Sprintf("Did you mean %s?", ContractPath(match))
+	}
+	return hint
+}
+
+// siblingPaths lists the entries of the directory containing path
+func siblingPaths(path string) []string {
+	dir := filepath.Dir(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	paths := make([]string, len(entries))
+	for i, e := range entries {
+		paths[i] = filepath.Join(dir, e.Name())
+	}
+	return paths
+}
@@ -0,0 +1,94 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLevenshteinDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"create", "create", 0},
+		{"craete", "create", 2},
+		{"stauts", "status", 2},
+		{".bashr", ".bashrc", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := LevenshteinDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("LevenshteinDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestPath(t *testing.T) {
+	candidates := []string{"/home/me/.bashrc", "/home/me/.config/nvim/init.lua", "/home/me/.gitconfig"}
+	tests := []struct {
+		path, want string
+	}{
+		{"/home/me/.bashr", "/home/me/.bashrc"},
+		{"/home/me/.config/nvim/init.lau", "/home/me/.config/nvim/init.lua"},
+		{"/home/me/.gitconfg", "/home/me/.gitconfig"},
+		{"/home/me/.bashrc", ""},
+		{"/home/me/.zshrc", ""},
+		{"/home/me/.config/alacritty/alacritty.toml", ""},
+	}
+	for _, tt := range tests {
+		if got := suggestPath(tt.path, candidates); got != tt.want {
+			t.Errorf("suggestPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOrphanSuggestsManagedPath(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "bash config")
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	createTestSymlink(t, filepath.Join(sourceDir, ".bashrc"), filepath.Join(targetDir, ".bashrc"))
+
+	err := Orphan(OrphanOptions{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Paths:     []string{filepath.Join(targetDir, ".bashr")},
+	})
+	if err == nil {
+		t.Fatal("expected error for nonexistent path")
+	}
+	want := "Did you mean " + filepath.Join(targetDir, ".bashrc") + "?"
+	if hint := GetErrorHint(err); hint != want {
+		t.Errorf("hint = %q, want %q", hint, want)
+	}
+}
+
+func TestAdoptSuggestsSiblingPath(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "target")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, filepath.Join(targetDir, ".config", "starship.toml"), "format = '$all'")
+
+	_, err := Adopt(AdoptOptions{
+		SourceDir: sourceDir,
+		TargetDir: targetDir,
+		Paths:     []string{filepath.Join(targetDir, ".config", "starship.tml")},
+	})
+	if err == nil {
+		t.Fatal("expected error for nonexistent path")
+	}
+	if hint := GetErrorHint(err); !strings.Contains(hint, "Did you mean "+filepath.Join(targetDir, ".config", "starship.toml")) {
+		t.Errorf("hint = %q", hint)
+	}
+}
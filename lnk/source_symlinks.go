@@ -0,0 +1,113 @@
+package lnk
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+// Policies for files in the source directory that are themselves symlinks
+// (symlinked_files in lnk-package.json)
+const (
+	SymlinkedFilesSkip        = "skip"        // leave them unlinked (default)
+	SymlinkedFilesLink        = "link"        // link to the symlink in the source directory
+	SymlinkedFilesDereference = "dereference" // link to the file the symlink finally leads to
+	SymlinkedFilesCopy        = "copy"        // copy the file the symlink leads to, managed as a copy
+)
+
+// sourceSymlinkPolicy says what the walk of a source directory does with the
+// symlinks it finds there
+type sourceSymlinkPolicy struct {
+	followDirs bool   // walk symlinked directories (follow_symlinked_dirs)
+	files      string // a SymlinkedFiles* policy for symlinked files; "" = skip
+}
+
+// symlinkPolicy returns the package's policy for symlinks in its directory,
+// or a ValidationError for an unknown symlinked_files value
+func (p *PackageInfo) symlinkPolicy(pkgDir string) (sourceSymlinkPolicy, error) {
+	switch p.SymlinkedFiles {
+	case "", SymlinkedFilesSkip, SymlinkedFilesLink, SymlinkedFilesDereference, SymlinkedFilesCopy:
+		return sourceSymlinkPolicy{followDirs: p.FollowSymlinkedDirs, files: p.SymlinkedFiles}, nil
+	default:
+		return sourceSymlinkPolicy{}, NewValidationErrorWithHint("symlinked_files", p.SymlinkedFiles,
+			fmt.Sprintf("unknown policy in %s", ContractPath(filepath.Join(pkgDir, PackageInfoFileName))),
+			fmt.Sprintf("Valid policies: %s, %s, %s, %s", SymlinkedFilesSkip, SymlinkedFilesLink,
+				SymlinkedFilesDereference, SymlinkedFilesCopy))
+	}
+}
+
+// skipsFiles reports whether symlinked files are left unlinked
+func (p sourceSymlinkPolicy) skipsFiles() bool {
+	return p.files == "" || p.files == SymlinkedFilesSkip
+}
+
+// resolveSourceSymlink returns where the symlink at path (realPath on disk)
+// finally leads and whether that is a directory. It returns "" for symlinks
+// there is nothing to do with: broken ones, loops, and ones leading to
+// anything but a directory or regular file. Broken symlinks are warned about
+// when the policy would have linked them.
+func resolveSourceSymlink(path, realPath string, policy sourceSymlinkPolicy) (string, bool) {
+	dest, err := fsys.EvalSymlinks(realPath)
+	if err != nil {
+		if !policy.skipsFiles() {
+			PrintWarningWithHint(NewPathErrorWithHint("link symlinked file", path, err,
+				"Fix or remove the symlink in the source directory, or ignore it in .lnkignore"))
+		}
+		return "", false
+	}
+	info, err := fsys.Stat(dest)
+	if err != nil {
+		return "", false
+	}
+	switch {
+	case info.IsDir():
+		return dest, true
+	case info.Mode().IsRegular():
+		return dest, false
+	default:
+		return "", false
+	}
+}
+
+// followDir reports whether the walk should enter dest, the directory the
+// symlink at path (realPath on disk) leads to. Symlinked directories are
+// followed only when the policy says so, and never when dest is a directory
+// the walk is already inside (one of inside, or a parent of the symlink),
+// which would be walked forever.
+func (p sourceSymlinkPolicy) followDir(path, realPath, dest string, inside []string) bool {
+	if !p.followDirs {
+		PrintVerbose("Skipping symlinked directory: %s -> %s", ContractPath(path), ContractPath(dest))
+		return false
+	}
+	parent, err := fsys.EvalSymlinks(filepath.Dir(realPath))
+	if err != nil {
+		parent = filepath.Dir(realPath)
+	}
+	for _, dir := range append(slices.Clone(inside), parent) {
+		if isWithin(dir, dest) {
+			PrintWarningWithHint(NewPathErrorWithHint("follow symlinked directory", path,
+				fmt.Errorf("leads back to %s, which is already being linked", ContractPath(dest)),
+				"Remove the symlink from the source directory, or ignore it in .lnkignore"))
+			return false
+		}
+	}
+	PrintVerbose("Following symlinked directory: %s -> %s", ContractPath(path), ContractPath(dest))
+	return true
+}
+
+// fileLink plans target for the symlinked file at path, which leads to dest,
+// as the policy says. It returns false when the policy skips symlinked files.
+func (p sourceSymlinkPolicy) fileLink(path, dest, target string) (PlannedLink, bool) {
+	switch p.files {
+	case SymlinkedFilesLink:
+		return PlannedLink{Source: path, Target: target}, true
+	case SymlinkedFilesDereference:
+		PrintVerbose("Dereferencing symlinked file: %s -> %s", ContractPath(path), ContractPath(dest))
+		return PlannedLink{Source: dest, Target: target}, true
+	case SymlinkedFilesCopy:
+		return PlannedLink{Source: path, Target: target, Copy: true}, true
+	default:
+		PrintVerbose("Skipping symlinked file: %s -> %s", ContractPath(path), ContractPath(dest))
+		return PlannedLink{}, false
+	}
+}
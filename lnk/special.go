@@ -0,0 +1,65 @@
+package lnk
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// Policies for special files (sockets, FIFOs, device nodes, hardlinked files)
+// found in the source tree
+const (
+	SpecialFilesSkip  = "skip"  // skip them with a warning (default)
+	SpecialFilesError = "error" // refuse to link anything while they are present
+)
+
+// specialFile is a source entry that lnk does not link
+type specialFile struct {
+	path string // absolute path in the source directory
+	kind string // "socket", "FIFO", "device", or "hardlinked file"
+}
+
+// specialFileKind classifies a source entry that should not be linked.
+// Returns "" for ordinary regular files and for entries lnk never links anyway
+// (directories, symlinks).
+func specialFileKind(d fs.DirEntry) (string, error) {
+	mode := d.Type()
+	switch {
+	case mode&fs.ModeSocket != 0:
+		return "socket", nil
+	case mode&fs.ModeNamedPipe != 0:
+		return "FIFO", nil
+	case mode&fs.ModeDevice != 0:
+		return "device", nil
+	case !mode.IsRegular():
+		return "", nil
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		return "", err
+	}
+	if hardLinkCount(info) > 1 {
+		return "hardlinked file", nil
+	}
+	return "", nil
+}
+
+// applySpecialFilePolicy reports special files according to policy: a warning
+// per file for SpecialFilesSkip (or ""), or an error for SpecialFilesError.
+func applySpecialFilePolicy(specials []specialFile, policy string) error {
+	if len(specials) == 0 {
+		return nil
+	}
+	if policy == SpecialFilesError {
+		first := specials[0]
+		return NewPathErrorWithHint("link", first.path,
+			fmt.Errorf("%s in source directory (%d special file(s) found)", first.kind, len(specials)),
+			"Remove it, add it to .lnkignore, or use --special-files skip")
+	}
+	for _, s := range specials {
+		PrintWarningWithHint(WithHint(
+			fmt.Errorf("Skipping %s: %s", s.kind, ContractPath(s.path)),
+			"Add it to .lnkignore to silence this warning"))
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build !unix
+
+package lnk
+
+import (
+	"io/fs"
+	"os"
+)
+
+// hardLinkCount returns 1; hard link counts are not available on this platform
+func hardLinkCount(info fs.FileInfo) uint64 {
+	return 1
+}
+
+// deviceID reports false; file system IDs are not available on this platform
+func deviceID(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// fileOwner reports false; file owners are not available on this platform
+func fileOwner(info fs.FileInfo) (int, bool) {
+	return 0, false
+}
+
+// fileID returns 0; inode numbers are not available on this platform
+func fileID(info fs.FileInfo) uint64 {
+	return 0
+}
+
+// tryLock reports true; advisory locks are not available on this platform
+func tryLock(f *os.File) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,52 @@
+//go:build unix
+
+package lnk
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// hardLinkCount returns the number of hard links to the file described by info
+func hardLinkCount(info fs.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Nlink)
+	}
+	return 1
+}
+
+// deviceID returns the ID of the file system holding the file described by info
+func deviceID(info fs.FileInfo) (uint64, bool) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev), true
+	}
+	return 0, false
+}
+
+// fileOwner returns the user ID owning the file described by info
+func fileOwner(info fs.FileInfo) (int, bool) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid), true
+	}
+	return 0, false
+}
+
+// fileID returns the inode number of the file described by info
+func fileID(info fs.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
+
+// tryLock takes an exclusive advisory lock on f without blocking. It reports
+// false when another process holds it. The kernel drops the lock when the
+// process exits, however it exits.
+func tryLock(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,113 @@
+//go:build unix
+
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestCreateLinksSpecialFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  string
+		setup func(t *testing.T, path string)
+	}{
+		{
+			name:  "socket",
+			kind:  "socket",
+			setup: createTestSocket,
+		},
+		{
+			name:  "FIFO",
+			kind:  "FIFO",
+			setup: createTestFIFO,
+		},
+		{
+			name: "device node",
+			kind: "device",
+			setup: func(t *testing.T, path string) {
+				// Character device with the same numbers as /dev/null
+				if err := syscall.Mknod(path, syscall.S_IFCHR|0644, 1<<8|3); err != nil {
+					t.Skipf("cannot create device node (requires root): %v", err)
+				}
+			},
+		},
+		{
+			name: "hardlinked file",
+			kind: "hardlinked file",
+			setup: func(t *testing.T, path string) {
+				other := filepath.Join(t.TempDir(), "original")
+				createTestFile(t, other, "shared")
+				if err := os.Link(other, path); err != nil {
+					t.Skipf("cannot create hard link: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			sourceDir := filepath.Join(tmpDir, "dotfiles")
+			targetDir := filepath.Join(tmpDir, "home")
+			createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "# bashrc")
+			os.MkdirAll(targetDir, 0755)
+			special := filepath.Join(sourceDir, "special")
+			tt.setup(t, special)
+
+			t.Run("skip by default", func(t *testing.T) {
+				opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}
+				_, stderr := captureOutput(t, func() {
+					if _, err := CreateLinks(opts); err != nil {
+						t.Fatalf("CreateLinks() error = %v", err)
+					}
+				})
+
+				if !strings.Contains(stderr, "Skipping "+tt.kind) {
+					t.Errorf("expected warning for %s, stderr: %q", tt.kind, stderr)
+				}
+				assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, ".bashrc"))
+				if _, err := os.Lstat(filepath.Join(targetDir, "special")); !os.IsNotExist(err) {
+					t.Errorf("special file should not be linked")
+				}
+			})
+
+			t.Run("ignored special file is silent", func(t *testing.T) {
+				opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, IgnorePatterns: []string{"special"}}
+				_, stderr := captureOutput(t, func() {
+					if _, err := CreateLinks(opts); err != nil {
+						t.Fatalf("CreateLinks() error = %v", err)
+					}
+				})
+
+				if strings.Contains(stderr, "Skipping") {
+					t.Errorf("unexpected warning for ignored file, stderr: %q", stderr)
+				}
+			})
+
+			t.Run("error policy", func(t *testing.T) {
+				os.Remove(filepath.Join(targetDir, ".bashrc"))
+				opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, SpecialFiles: SpecialFilesError}
+
+				var err error
+				captureOutput(t, func() {
+					_, err = CreateLinks(opts)
+				})
+
+				if err == nil || !strings.Contains(err.Error(), tt.kind) {
+					t.Fatalf("CreateLinks() error = %v, want mention of %s", err, tt.kind)
+				}
+				if GetErrorHint(err) == "" {
+					t.Errorf("expected hint on error")
+				}
+				if _, err := os.Lstat(filepath.Join(targetDir, ".bashrc")); !os.IsNotExist(err) {
+					t.Errorf("no links should be created under the error policy")
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Usage statistics are strictly opt-in and never leave the machine. Nothing
+// is recorded until 'lnk stats enable' creates stats.json in the state
+// directory; from then on each command adds its run, exit status, and
+// duration. Only command names are stored, never arguments or paths.
+// 'lnk stats' shows the totals, so users can share them when reporting
+// performance problems.
+
+// UsageStats is the contents of stats.json
+type UsageStats struct {
+	Version  int                      `json:"version"`
+	Since    time.Time                `json:"since"`    // when recording was enabled or last reset
+	Commands map[string]*CommandStats `json:"commands"` // by command name
+}
+
+// CommandStats are the totals for one command
+type CommandStats struct {
+	Runs     int       `json:"runs"`
+	Failures int       `json:"failures"` // runs that exited non-zero
+	TotalMS  float64   `json:"total_ms"`
+	MaxMS    float64   `json:"max_ms"`
+	LastRun  time.Time `json:"last_run"`
+}
+
+// statsVersion is the stats.json format version
+const statsVersion = 1
+
+// processStarted approximates when lnk started, so recorded durations include
+// loading the configuration
+var processStarted = time.Now()
+
+// statsCommand and statsTargetDir identify the run RecordStats records; empty
+// until StartStats is called
+var statsCommand, statsTargetDir string
+
+// StatsPath returns the stats file location for targetDir
+func StatsPath(targetDir string) string {
+	return filepath.Join(StateDir(targetDir), StatsFileName)
+}
+
+// LoadStats reads the usage statistics for targetDir. It returns nil when
+// recording is not enabled.
+func LoadStats(targetDir string) (*UsageStats, error) {
+	path := StatsPath(targetDir)
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, NewPathErrorWithHint("read usage statistics", path, err, "Check file permissions")
+	}
+	var stats UsageStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, NewPathErrorWithHint("parse usage statistics", path, err,
+			"Run 'lnk stats reset <source-dir>' to start over")
+	}
+	if stats.Commands == nil {
+		stats.Commands = map[string]*CommandStats{}
+	}
+	return &stats, nil
+}
+
+// save writes the statistics atomically
+func (s *UsageStats) save(targetDir string) error {
+	path := StatsPath(targetDir)
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return NewPathErrorWithHint("create state directory", filepath.Dir(path), err,
+			"Check that you have write permissions in the parent directory")
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding usage statistics: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), 0600); err != nil {
+		return NewPathError("write usage statistics", path, err)
+	}
+	return nil
+}
+
+// newStats returns empty statistics starting now
+func newStats() *UsageStats {
+	return &UsageStats{Version: statsVersion, Since: time.Now().UTC(), Commands: map[string]*CommandStats{}}
+}
+
+// StartStats names the command RecordStats records and the target directory
+// whose state directory holds the statistics
+func StartStats(command, targetDir string) {
+	statsCommand, statsTargetDir = command, targetDir
+}
+
+// RecordStats adds this run to the usage statistics when recording is
+// enabled. Statistics must never get in the way of a command, so failures
+// are only reported in verbose mode, and nothing is written in read-only mode.
+func RecordStats(exitCode int) {
+	if statsCommand == "" || IsReadOnly() {
+		return
+	}
+	stats, err := LoadStats(statsTargetDir)
+	if err != nil {
+		PrintVerbose("Failed to record usage statistics: %v", err)
+		return
+	}
+	if stats == nil {
+		return
+	}
+
+	c, ok := stats.Commands[statsCommand]
+	if !ok {
+		c = &CommandStats{}
+		stats.Commands[statsCommand] = c
+	}
+	ms := float64(time.Since(processStarted).Microseconds()) / 1000
+	c.Runs++
+	if exitCode != 0 {
+		c.Failures++
+	}
+	c.TotalMS += ms
+	if ms > c.MaxMS {
+		c.MaxMS = ms
+	}
+	c.LastRun = time.Now().UTC()
+	if err := stats.save(statsTargetDir); err != nil {
+		PrintVerbose("Failed to record usage statistics: %v", err)
+	}
+}
+
+// EnableStats starts recording usage statistics for targetDir. Statistics
+// already recorded are kept.
+func EnableStats(targetDir string) error {
+	stats, err := LoadStats(targetDir)
+	if err != nil {
+		return err
+	}
+	if stats != nil {
+		PrintInfo("Usage statistics are already being recorded in %s", ContractPath(StatsPath(targetDir)))
+		return nil
+	}
+	if err := newStats().save(targetDir); err != nil {
+		return err
+	}
+	PrintSuccess("Recording usage statistics in %s", ContractPath(StatsPath(targetDir)))
+	PrintDetail("Only command names, exit status, and durations are kept; nothing is sent anywhere")
+	return nil
+}
+
+// DisableStats stops recording usage statistics and deletes those recorded
+func DisableStats(targetDir string) error {
+	path := StatsPath(targetDir)
+	if err := fsys.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			PrintInfo("Usage statistics are not being recorded")
+			return nil
+		}
+		return NewPathErrorWithHint("delete usage statistics", path, err, "Check file permissions")
+	}
+	PrintSuccess("Stopped recording usage statistics and deleted %s", ContractPath(path))
+	return nil
+}
+
+// ResetStats clears the recorded usage statistics, leaving recording enabled
+func ResetStats(targetDir string) error {
+	// A file that no longer parses is replaced too
+	if _, err := fsys.Lstat(StatsPath(targetDir)); os.IsNotExist(err) {
+		return WithHint(fmt.Errorf("usage statistics are not being recorded"),
+			"Run 'lnk stats enable <source-dir>' to start recording")
+	}
+	if err := newStats().save(targetDir); err != nil {
+		return err
+	}
+	PrintSuccess("Reset usage statistics")
+	return nil
+}
+
+// ShowStats prints the recorded usage statistics, the most used commands first
+func ShowStats(targetDir string) error {
+	PrintCommandHeader("Usage Statistics")
+
+	stats, err := LoadStats(targetDir)
+	if err != nil {
+		return err
+	}
+	if stats == nil {
+		PrintInfo("Usage statistics are off.")
+		PrintInfo("Run 'lnk stats enable <source-dir>' to record command counts and durations on this machine")
+		return nil
+	}
+
+	names := make([]string, 0, len(stats.Commands))
+	for name := range stats.Commands {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := stats.Commands[names[i]], stats.Commands[names[j]]
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return names[i] < names[j]
+	})
+
+	if ShouldSimplifyOutput() {
+		for _, name := range names {
+			c := stats.Commands[name]
+			fmt.Printf("%s %d %d %.2f %.2f\n", name, c.Runs, c.Failures, c.TotalMS/float64(c.Runs), c.MaxMS)
+		}
+		return nil
+	}
+
+	PrintInfo("Recorded since %s in %s", stats.Since.Local().Format("2006-01-02"), ContractPath(StatsPath(targetDir)))
+	fmt.Println()
+	if len(names) == 0 {
+		PrintEmptyResult("recorded runs")
+		return nil
+	}
+	var runs int
+	for _, name := range names {
+		c := stats.Commands[name]
+		runs += c.Runs
+		line := fmt.Sprintf("%-14s %5d run(s)  avg %10s  max %10s", name, c.Runs,
+			formatDuration(time.Duration(c.TotalMS/float64(c.Runs)*float64(time.Millisecond))),
+			formatDuration(time.Duration(c.MaxMS*float64(time.Millisecond))))
+		if c.Failures > 0 {
+			line += fmt.Sprintf("  %d failed", c.Failures)
+		}
+		PrintDetail("%s", line)
+	}
+	PrintSummary("%d run(s) of %d command(s)", runs, len(names))
+	return nil
+}
@@ -0,0 +1,112 @@
+package lnk
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// resetStatsRun forgets the run StartStats named when the test ends
+func resetStatsRun(t *testing.T) {
+	t.Cleanup(func() { statsCommand, statsTargetDir = "", "" })
+}
+
+func TestRecordStatsIsOptIn(t *testing.T) {
+	targetDir := t.TempDir()
+	resetStatsRun(t)
+
+	StartStats("status", targetDir)
+	RecordStats(0)
+	if _, err := os.Stat(StatsPath(targetDir)); !os.IsNotExist(err) {
+		t.Fatalf("stats recorded without being enabled: %v", err)
+	}
+
+	CaptureOutput(t, func() {
+		if err := EnableStats(targetDir); err != nil {
+			t.Fatalf("EnableStats() error = %v", err)
+		}
+	})
+	RecordStats(0)
+	RecordStats(ExitError)
+	StartStats("create", targetDir)
+	RecordStats(0)
+
+	stats, err := LoadStats(targetDir)
+	if err != nil || stats == nil {
+		t.Fatalf("LoadStats() = %v, %v", stats, err)
+	}
+	status := stats.Commands["status"]
+	if status == nil || status.Runs != 2 || status.Failures != 1 {
+		t.Errorf("status stats = %+v, want 2 runs, 1 failure", status)
+	}
+	if status != nil && (status.TotalMS <= 0 || status.MaxMS > status.TotalMS || status.LastRun.IsZero()) {
+		t.Errorf("status durations = %+v", status)
+	}
+	if c := stats.Commands["create"]; c == nil || c.Runs != 1 {
+		t.Errorf("create stats = %+v, want 1 run", c)
+	}
+
+	// Nothing is written in read-only mode
+	SetReadOnly(true)
+	RecordStats(0)
+	SetReadOnly(false)
+	if stats, _ := LoadStats(targetDir); stats.Commands["create"].Runs != 1 {
+		t.Errorf("read-only run was recorded: %+v", stats.Commands["create"])
+	}
+}
+
+func TestStatsCommands(t *testing.T) {
+	targetDir := t.TempDir()
+	resetStatsRun(t)
+
+	out := CaptureOutput(t, func() {
+		if err := ShowStats(targetDir); err != nil {
+			t.Fatalf("ShowStats() error = %v", err)
+		}
+	})
+	if !strings.Contains(out, "lnk stats enable") {
+		t.Errorf("disabled stats should explain how to enable them:\n%s", out)
+	}
+	if err := ResetStats(targetDir); err == nil {
+		t.Error("ResetStats() succeeded while disabled")
+	}
+
+	CaptureOutput(t, func() {
+		if err := EnableStats(targetDir); err != nil {
+			t.Fatalf("EnableStats() error = %v", err)
+		}
+	})
+	StartStats("create", targetDir)
+	RecordStats(0)
+	StartStats("status", targetDir)
+	RecordStats(0)
+	RecordStats(0)
+
+	out = CaptureOutput(t, func() {
+		if err := ShowStats(targetDir); err != nil {
+			t.Fatalf("ShowStats() error = %v", err)
+		}
+	})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "status 2 0 ") || !strings.HasPrefix(lines[1], "create 1 0 ") {
+		t.Errorf("ShowStats() piped output = %q, want status then create", lines)
+	}
+
+	CaptureOutput(t, func() {
+		if err := ResetStats(targetDir); err != nil {
+			t.Fatalf("ResetStats() error = %v", err)
+		}
+	})
+	if stats, _ := LoadStats(targetDir); stats == nil || len(stats.Commands) != 0 {
+		t.Errorf("after reset stats = %+v, want enabled and empty", stats)
+	}
+
+	CaptureOutput(t, func() {
+		if err := DisableStats(targetDir); err != nil {
+			t.Fatalf("DisableStats() error = %v", err)
+		}
+	})
+	if _, err := os.Stat(StatsPath(targetDir)); !os.IsNotExist(err) {
+		t.Errorf("stats file still exists after disable: %v", err)
+	}
+}
@@ -2,44 +2,214 @@ package lnk
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
+	"time"
 )
 
+// Conditions accepted by --fail-on
+const (
+	FailOnUnlinked = "unlinked" // source files with nothing at their target path
+)
+
+// FailOnConditions lists the valid --fail-on values
+var FailOnConditions = []string{FailOnUnlinked}
+
 // Status displays the status of managed symlinks for the source directory
 func Status(opts LinkOptions) error {
 	// Expand and validate paths
-	paths, err := ResolvePaths(opts.SourceDir, opts.TargetDir)
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
 	if err != nil {
 		return err
 	}
 	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
 
+	for _, cond := range opts.FailOn {
+		if !slices.Contains(FailOnConditions, cond) {
+			return NewValidationErrorWithHint("fail-on", cond, "unknown condition",
+				fmt.Sprintf("Valid conditions: %v", FailOnConditions))
+		}
+	}
+
+	packages, err := expandPackageDeps(sourceDir, opts.Packages)
+	if err != nil {
+		return err
+	}
+	pkgDirs, err := packageDirs(sourceDir, packages)
+	if err != nil {
+		return err
+	}
+	maps, err := resolveMappings(opts.Maps, opts.Home, sourceDir, targetDir)
+	if err != nil {
+		return err
+	}
+
+	if len(opts.Groups) > 0 && (opts.Shallow || opts.AsOf != "") {
+		return NewValidationErrorWithHint("group", strings.Join(opts.Groups, ","), "not supported with --shallow or --as-of",
+			"Drop --shallow and --as-of to limit status to groups")
+	}
+
+	if opts.AsOf != "" && (opts.Shallow || opts.Output == OutputJSON) {
+		return NewValidationErrorWithHint("as-of", opts.AsOf, "not supported with --shallow or --output json",
+			"Drop --shallow and --output to see the recorded state")
+	}
+
+	if opts.Remote {
+		if opts.AsOf != "" {
+			return NewValidationErrorWithHint("as-of", opts.AsOf, "not supported with --remote",
+				"Drop --remote to see the recorded state")
+		}
+		if err := checkRemoteRepo(sourceDir); err != nil {
+			return err
+		}
+	}
+
+	if opts.Output == OutputJSON {
+		if opts.Shallow {
+			return NewValidationErrorWithHint("output", opts.Output, "not supported with --shallow",
+				"Drop --shallow to write status as JSON")
+		}
+		unlinked, err := statusJSON(os.Stdout, opts, sourceDir, targetDir, pkgDirs, maps)
+		if err != nil {
+			return err
+		}
+		return failOnUnlinked(opts, sourceDir, unlinked)
+	}
+
 	PrintCommandHeader("Symlink Status")
 	PrintVerbose("Source directory: %s", sourceDir)
 	PrintVerbose("Target directory: %s", targetDir)
 
-	// Find all symlinks for the source directory
-	managedLinks, err := FindManagedLinks(targetDir, []string{sourceDir})
+	if opts.AsOf != "" {
+		return historicalStatus(opts, sourceDir, targetDir)
+	}
+	var health *RepoHealth
+	if opts.Remote {
+		if health, err = repoHealth(sourceDir); err != nil {
+			return err
+		}
+	}
+	if opts.Shallow {
+		err := shallowStatus(opts, sourceDir, targetDir, append(slices.Clone(pkgDirs), mappingSources(maps)...))
+		if health != nil {
+			printRepoHealth(health, sourceDir)
+		}
+		return err
+	}
+
+	// Find all symlinks for the selected packages (or the whole source directory)
+	endScan := TracePhase("scan")
+	managedLinks, err := findPackageLinks(targetDir, pkgDirs, mappingSources(maps))
+	if err != nil {
+		return err
+	}
+	endScan("managed_links", len(managedLinks))
+	copies := loadCopies(targetDir, sourceDir)
+	if len(opts.Groups) > 0 {
+		inGroups, err := groupTargets(opts.Groups, pkgDirs, maps, targetDir, opts.IgnorePatterns)
+		if err != nil {
+			return err
+		}
+		managedLinks, copies = managedInGroups(managedLinks, copies, inGroups)
+	}
+	SummaryCount("managed", len(managedLinks))
+
+	printManagedLinks(managedLinks, sourceDir)
+	printCopies(copies)
+
+	endPlan := TracePhase("plan")
+	unlinked, conflicts, err := classifyPlannedLinks(pkgDirs, maps, targetDir, opts.IgnorePatterns, opts.LocalOnly, opts.Groups, copies)
 	if err != nil {
-		return fmt.Errorf("failed to find managed links: %w", err)
+		return err
+	}
+	endPlan("unlinked", len(unlinked), "conflicts", len(conflicts))
+	SummaryCount("unlinked", len(unlinked))
+	SummaryCount("conflicts", len(conflicts))
+	printUnlinkedSources(unlinked)
+	printConflicts(conflicts, sourceDir)
+	if health != nil {
+		printRepoHealth(health, sourceDir)
+	}
+
+	return failOnUnlinked(opts, sourceDir, unlinked)
+}
+
+// findPackageLinks finds the managed links of the selected packages and of
+// extraSources in targetDir. A package whose target lies outside targetDir,
+// such as one linked into the Windows home under WSL, is searched where create
+// links it.
+func findPackageLinks(targetDir string, pkgDirs, extraSources []string) ([]ManagedLink, error) {
+	sources := slices.Clone(extraSources)
+	outside := make(map[string][]string) // package target outside targetDir -> its packages
+	var roots []string
+	for _, dir := range pkgDirs {
+		pkgTarget, include, err := resolvePackageTarget(dir, targetDir)
+		if err != nil {
+			return nil, err
+		}
+		if !include || isWithin(pkgTarget, targetDir) {
+			sources = append(sources, dir)
+			continue
+		}
+		if _, ok := outside[pkgTarget]; !ok {
+			roots = append(roots, pkgTarget)
+		}
+		outside[pkgTarget] = append(outside[pkgTarget], dir)
+	}
+
+	links, err := FindManagedLinks(targetDir, sources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find managed links: %w", err)
+	}
+	for _, root := range roots {
+		more, err := FindManagedLinks(root, outside[root])
+		if err != nil {
+			return nil, fmt.Errorf("failed to find managed links: %w", err)
+		}
+		for _, link := range more {
+			// Package targets narrowed by type may lie inside one another
+			if !slices.ContainsFunc(links, func(l ManagedLink) bool { return l.Path == link.Path }) {
+				links = append(links, link)
+			}
+		}
 	}
+	return links, nil
+}
+
+// failOnUnlinked returns an error when there are unlinked sources and
+// --fail-on unlinked was given
+func failOnUnlinked(opts LinkOptions, sourceDir string, unlinked []PlannedLink) error {
+	if len(unlinked) > 0 && slices.Contains(opts.FailOn, FailOnUnlinked) {
+		return WithHint(
+			fmt.Errorf("%d unlinked source file(s)", len(unlinked)),
+			fmt.Sprintf("Run 'lnk create %s' to link them, or add them to .lnkignore", ContractPath(sourceDir)))
+	}
+	return nil
+}
 
+// printManagedLinks displays links sorted by path, active ones first, with a
+// total and hints for broken ones
+func printManagedLinks(links []ManagedLink, sourceDir string) {
 	// Sort by link path
-	sort.Slice(managedLinks, func(i, j int) bool {
-		return managedLinks[i].Path < managedLinks[j].Path
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Path < links[j].Path
 	})
 
 	// Display links
-	if len(managedLinks) > 0 {
+	if len(links) > 0 {
 		// Separate active and broken links
 		var activeLinks, brokenLinks []ManagedLink
-		for _, link := range managedLinks {
+		for _, link := range links {
 			if link.IsBroken {
 				brokenLinks = append(brokenLinks, link)
 			} else {
 				activeLinks = append(activeLinks, link)
 			}
 		}
+		SummaryCount("broken", len(brokenLinks))
 
 		// Display active links
 		if len(activeLinks) > 0 {
@@ -58,13 +228,15 @@ func Status(opts LinkOptions) error {
 			if len(activeLinks) > 0 && !ShouldSimplifyOutput() {
 				fmt.Println()
 			}
+			annotate := commitAnnotator(sourceDir)
 			for _, link := range brokenLinks {
 				if ShouldSimplifyOutput() {
 					// For piped output, use simple format
-					fmt.Printf("broken %s\n", ContractPath(link.Path))
+					fmt.Printf("broken %s %s\n", ContractPath(link.Path), link.Broken)
 				} else {
-					fmt.Printf("%s Broken: %s\n", Red(FailureIcon), ContractPath(link.Path))
+					fmt.Printf("%s Broken: %s (%s)\n", Red(FailureIcon), ContractPath(link.Path), describeBroken(link.Broken))
 				}
+				printLastCommit(annotate, link.Target)
 			}
 		}
 
@@ -72,13 +244,168 @@ func Status(opts LinkOptions) error {
 		if !ShouldSimplifyOutput() {
 			fmt.Println()
 			PrintInfo("Total: %s (%s active, %s broken)",
-				Bold(fmt.Sprintf("%d links", len(managedLinks))),
+				Bold(fmt.Sprintf("%d links", len(links))),
 				Green(fmt.Sprintf("%d", len(activeLinks))),
 				Red(fmt.Sprintf("%d", len(brokenLinks))))
+			printBrokenHints(brokenLinks, sourceDir)
 		}
 	} else {
 		PrintInfo("No managed links found.")
 	}
+}
 
-	return nil
+// statusConflict is a target path occupied by a real file or directory where
+// the plan wants a link
+type statusConflict struct {
+	link PlannedLink
+	info os.FileInfo
+}
+
+// classifyPlannedLinks walks the package directories and mappings and returns
+// the planned links whose target path does not exist (unlinked) and those
+// whose target path is occupied by something other than a symlink (conflicts).
+// Links to local-only or copy-managed targets are neither, and a missing
+// ephemeral link is not unlinked, since its target is expected to vanish on
+// reboot. With groups, only links in those groups are classified.
+func classifyPlannedLinks(pkgDirs []string, maps []Mapping, targetDir string, ignorePatterns, localOnly, groups []string, copies []ManifestCopy) ([]PlannedLink, []statusConflict, error) {
+	planned, _, _, err := collectPackageLinks(pkgDirs, targetDir, ignorePatterns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("collecting source files: %w", err)
+	}
+	mapLinks, _, _, err := collectMappedLinks(maps, ignorePatterns, planned)
+	if err != nil {
+		return nil, nil, fmt.Errorf("collecting source files: %w", err)
+	}
+	planned = append(planned, mapLinks...)
+	if len(groups) > 0 {
+		if planned, err = selectGroups(planned, groups, pkgDirs, maps); err != nil {
+			return nil, nil, err
+		}
+	}
+	planned, _ = filterLocalOnly(planned, targetDir, localOnly)
+	planned, _ = filterCopyManaged(planned, copies)
+
+	var unlinked []PlannedLink
+	var conflicts []statusConflict
+	for _, link := range planned {
+		info, err := fsys.Lstat(link.Target)
+		switch {
+		case os.IsNotExist(err) && link.Ephemeral:
+			PrintVerbose("Ephemeral link not present: %s", ContractPath(link.Target))
+		case os.IsNotExist(err):
+			unlinked = append(unlinked, link)
+		case err != nil:
+			PrintVerbose("Failed to check %s: %v", ContractPath(link.Target), err)
+		case info.Mode()&os.ModeSymlink == 0:
+			conflicts = append(conflicts, statusConflict{link: link, info: info})
+		}
+	}
+	return unlinked, conflicts, nil
+}
+
+// describeBroken turns a Broken* reason into words for display
+func describeBroken(reason string) string {
+	return strings.ReplaceAll(reason, "-", " ")
+}
+
+// printBrokenHints suggests a fix for each kind of broken link found, since
+// each needs a different remedy
+func printBrokenHints(broken []ManagedLink, sourceDir string) {
+	for _, reason := range []string{BrokenSourceDeleted, BrokenParentMissing, BrokenPermissionDenied, BrokenSymlinkLoop} {
+		if slices.ContainsFunc(broken, func(link ManagedLink) bool { return link.Broken == reason }) {
+			PrintInfo("Next (%s): %s", describeBroken(reason), brokenHint(reason, sourceDir))
+		}
+	}
+}
+
+// printUnlinkedSources displays source files that have no link in the target
+func printUnlinkedSources(unlinked []PlannedLink) {
+	if len(unlinked) == 0 {
+		return
+	}
+
+	if ShouldSimplifyOutput() {
+		for _, link := range unlinked {
+			fmt.Printf("unlinked %s\n", ContractPath(link.Source))
+		}
+		return
+	}
+
+	fmt.Println()
+	PrintInfo("Unlinked sources:")
+	for _, link := range unlinked {
+		fmt.Printf("%s Unlinked: %s\n", Yellow(WarningIcon), ContractPath(link.Source))
+	}
+	fmt.Println()
+	PrintInfo("Total: %s", Yellow(fmt.Sprintf("%d unlinked", len(unlinked))))
+}
+
+// printConflicts displays target paths where a real file blocks a planned link,
+// with the size and modification time of the blocking file for triage
+func printConflicts(conflicts []statusConflict, sourceDir string) {
+	if len(conflicts) == 0 {
+		return
+	}
+
+	annotate := commitAnnotator(sourceDir)
+	if ShouldSimplifyOutput() {
+		for _, c := range conflicts {
+			fmt.Printf("conflict %s %d %s\n", ContractPath(c.link.Target),
+				c.info.Size(), c.info.ModTime().UTC().Format(time.RFC3339))
+			printLastCommit(annotate, c.link.Source)
+		}
+		return
+	}
+
+	fmt.Println()
+	PrintInfo("Conflicts:")
+	for _, c := range conflicts {
+		fmt.Printf("%s Conflict: %s (%s)\n", Red(FailureIcon), ContractPath(c.link.Target), describeConflict(c.info))
+		printLastCommit(annotate, c.link.Source)
+	}
+	fmt.Println()
+	PrintInfo("Total: %s", Red(fmt.Sprintf("%d conflicts", len(conflicts))))
+	PrintNextStep("adopt", sourceDir, "move them into the source directory, or remove them and run 'lnk create'")
+}
+
+// commitAnnotator returns a function naming the newest commit that touched a
+// source file, or nil unless output is verbose and sourceDir is in a git work
+// tree, so status only runs git when asked to
+func commitAnnotator(sourceDir string) func(source string) string {
+	if !IsVerbose() || !isGitWorkTree(sourceDir) {
+		return nil
+	}
+	return func(source string) string {
+		rel, err := filepath.Rel(sourceDir, source)
+		if err != nil || !filepath.IsLocal(rel) {
+			return "" // mapped from outside the source directory
+		}
+		return gitLastCommit(sourceDir, filepath.ToSlash(rel))
+	}
+}
+
+// printLastCommit shows the newest commit that touched source under a broken
+// or conflicting entry, to point at the repository change behind it. Piped
+// output keeps one entry per line, so the commit is a verbose line there.
+func printLastCommit(annotate func(string) string, source string) {
+	if annotate == nil || source == "" {
+		return
+	}
+	commit := annotate(source)
+	switch {
+	case commit == "":
+	case ShouldSimplifyOutput():
+		PrintVerbose("Last commit to %s: %s", ContractPath(source), commit)
+	default:
+		PrintDetail("Last commit: %s", commit)
+	}
+}
+
+// describeConflict summarizes a conflicting target entry for display
+func describeConflict(info os.FileInfo) string {
+	modified := info.ModTime().Format("2006-01-02 15:04")
+	if info.IsDir() {
+		return fmt.Sprintf("directory, modified %s", modified)
+	}
+	return fmt.Sprintf("%d bytes, modified %s", info.Size(), modified)
 }
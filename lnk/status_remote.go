@@ -0,0 +1,209 @@
+package lnk
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RepoHealth is the state of the source directory's git repository against
+// its remote, reported by 'lnk status --remote'
+type RepoHealth struct {
+	Branch        string        `json:"branch"`             // checked-out branch; "" when HEAD is detached
+	Upstream      string        `json:"upstream,omitempty"` // remote-tracking branch it follows; "" when none
+	Ahead         int           `json:"ahead"`              // commits on Branch not on Upstream
+	Behind        int           `json:"behind"`             // commits on Upstream not on Branch
+	Fetched       bool          `json:"fetched"`            // whether Upstream was fetched by this run
+	Uncommitted   []string      `json:"uncommitted"`        // changed or untracked paths, relative to the repository root
+	StaleBranches []StaleBranch `json:"stale_branches"`     // other local branches that can be deleted
+}
+
+// StaleBranch is a local branch whose work is gone from or already in the remote
+type StaleBranch struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"` // "gone" (its upstream was deleted) or "merged" (into Upstream)
+}
+
+// Reasons a local branch is stale
+const (
+	StaleGone   = "gone"
+	StaleMerged = "merged"
+)
+
+// checkRemoteRepo returns an error unless sourceDir is in a git work tree, so
+// --remote fails before anything is printed
+func checkRemoteRepo(sourceDir string) error {
+	if !isGitWorkTree(sourceDir) {
+		return NewPathErrorWithHint("status --remote", sourceDir, fmt.Errorf("not a git repository"),
+			"Clone your dotfiles with git to compare them with a remote, or drop --remote")
+	}
+	return nil
+}
+
+// repoHealth fetches the upstream of the checked-out branch (unless read-only)
+// and reports how the repository differs from it. A failed fetch is a warning:
+// the counts are then as of the last fetch.
+func repoHealth(sourceDir string) (*RepoHealth, error) {
+	health := &RepoHealth{Uncommitted: []string{}, StaleBranches: []StaleBranch{}}
+	if out, err := gitOutput(sourceDir, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+		health.Branch = strings.TrimSpace(out)
+	}
+
+	if IsReadOnly() {
+		PrintVerbose("Read-only: not fetching; comparing with the last fetch")
+	} else {
+		PrintVerbose("Running: git fetch --prune --quiet")
+		if out, err := gitCombinedOutput(sourceDir, "fetch", "--prune", "--quiet"); err != nil {
+			PrintWarning("Could not fetch, comparing with the last fetch: %s", firstLine(strings.TrimSpace(out), err))
+		} else {
+			health.Fetched = true
+		}
+	}
+
+	// One line per local branch: name, upstream, and "ahead N, behind M" or "gone"
+	out, err := gitOutput(sourceDir, "for-each-ref",
+		"--format=%(refname:short)%00%(upstream:short)%00%(upstream:track,nobracket)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("git for-each-ref failed: %w", err)
+	}
+	type branch struct{ upstream, track string }
+	branches := make(map[string]branch)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) == 3 {
+			branches[fields[0]] = branch{fields[1], fields[2]}
+		}
+	}
+	if b, ok := branches[health.Branch]; ok && b.track != StaleGone {
+		health.Upstream = b.upstream
+		health.Ahead, health.Behind = parseTrack(b.track)
+	}
+
+	var merged map[string]bool
+	if health.Upstream != "" {
+		out, err := gitOutput(sourceDir, "for-each-ref", "--format=%(refname:short)", "--merged", health.Upstream, "refs/heads")
+		if err != nil {
+			return nil, fmt.Errorf("git for-each-ref failed: %w", err)
+		}
+		merged = make(map[string]bool)
+		for _, name := range strings.Fields(out) {
+			merged[name] = true
+		}
+	}
+	for name, b := range branches {
+		switch {
+		case name == health.Branch:
+		case b.track == StaleGone:
+			health.StaleBranches = append(health.StaleBranches, StaleBranch{name, StaleGone})
+		case merged[name]:
+			health.StaleBranches = append(health.StaleBranches, StaleBranch{name, StaleMerged})
+		}
+	}
+	sort.Slice(health.StaleBranches, func(i, j int) bool { return health.StaleBranches[i].Name < health.StaleBranches[j].Name })
+
+	out, err = gitOutput(sourceDir, "-c", "core.quotePath=false", "status", "--porcelain", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("git status failed: %w", err)
+	}
+	entries := splitNul(out)
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		health.Uncommitted = append(health.Uncommitted, entry[3:])
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++ // the path it was renamed or copied from follows
+		}
+	}
+
+	SummaryCount("ahead", health.Ahead)
+	SummaryCount("behind", health.Behind)
+	SummaryCount("uncommitted", len(health.Uncommitted))
+	SummaryCount("stale_branches", len(health.StaleBranches))
+	return health, nil
+}
+
+// parseTrack reads the ahead and behind counts from %(upstream:track,nobracket),
+// such as "ahead 2, behind 1"
+func parseTrack(track string) (ahead, behind int) {
+	for _, part := range strings.Split(track, ", ") {
+		word, n, _ := strings.Cut(part, " ")
+		count, _ := strconv.Atoi(n)
+		switch word {
+		case "ahead":
+			ahead = count
+		case "behind":
+			behind = count
+		}
+	}
+	return ahead, behind
+}
+
+// firstLine returns the first line of git's message, or err when it printed none
+func firstLine(message string, err error) string {
+	if message == "" {
+		return err.Error()
+	}
+	line, _, _ := strings.Cut(message, "\n")
+	return line
+}
+
+// printRepoHealth displays the repository section of 'lnk status --remote'
+func printRepoHealth(health *RepoHealth, sourceDir string) {
+	if ShouldSimplifyOutput() {
+		switch {
+		case health.Branch == "":
+			fmt.Println("detached")
+		case health.Upstream == "":
+			fmt.Printf("no-upstream %s\n", health.Branch)
+		default:
+			fmt.Printf("ahead %d\nbehind %d\n", health.Ahead, health.Behind)
+		}
+		for _, path := range health.Uncommitted {
+			fmt.Printf("uncommitted %s\n", path)
+		}
+		for _, b := range health.StaleBranches {
+			fmt.Printf("stale-branch %s %s\n", b.Name, b.Reason)
+		}
+		return
+	}
+
+	fmt.Println()
+	PrintInfo("Repository:")
+	switch {
+	case health.Branch == "":
+		fmt.Printf("%s HEAD is detached, not on a branch\n", Yellow(WarningIcon))
+	case health.Upstream == "":
+		fmt.Printf("%s %s has no upstream branch\n", Yellow(WarningIcon), health.Branch)
+	case health.Ahead == 0 && health.Behind == 0:
+		PrintSuccess("%s is up to date with %s", health.Branch, health.Upstream)
+	default:
+		fmt.Printf("%s %s is %d ahead, %d behind %s\n", Yellow(WarningIcon),
+			health.Branch, health.Ahead, health.Behind, health.Upstream)
+	}
+	for _, path := range health.Uncommitted {
+		fmt.Printf("%s Uncommitted: %s\n", Yellow(WarningIcon), path)
+	}
+	for _, b := range health.StaleBranches {
+		if b.Reason == StaleGone {
+			fmt.Printf("%s Stale branch: %s (deleted from the remote)\n", Yellow(WarningIcon), b.Name)
+		} else {
+			fmt.Printf("%s Stale branch: %s (merged into %s)\n", Yellow(WarningIcon), b.Name, health.Upstream)
+		}
+	}
+	if !health.Fetched {
+		PrintDetail("Compared with the last fetch")
+	}
+
+	if health.Behind > 0 {
+		PrintInfo("Next: Run 'lnk sync %s' to pull the remote changes", ContractPath(sourceDir))
+	}
+	if health.Ahead > 0 || len(health.Uncommitted) > 0 {
+		PrintInfo("Next: Commit and push the local changes with git so other machines get them")
+	}
+	if len(health.StaleBranches) > 0 {
+		PrintInfo("Next: Delete stale branches with 'git branch -d <branch>'")
+	}
+}
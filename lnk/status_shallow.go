@@ -0,0 +1,136 @@
+package lnk
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// shallowStatus reports the links the manifest records for sourceDir without
+// walking the target or source directories. Recorded links are grouped by
+// directory so each directory is listed once, and only entries that are
+// symlinks are read and followed. Links that point outside sources (other
+// packages) are left out; missing links are reported whichever package they
+// belonged to, since nothing is left to tell.
+func shallowStatus(opts LinkOptions, sourceDir, targetDir string, sources []string) error {
+	endScan := TracePhase("scan")
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		return err
+	}
+	if !m.TracksLinks(sourceDir) {
+		return WithHint(fmt.Errorf("no link records for %s", ContractPath(sourceDir)),
+			fmt.Sprintf("Run 'lnk create %s' to record its links, or run 'lnk status' without --shallow",
+				ContractPath(sourceDir)))
+	}
+
+	byDir := make(map[string][]ManifestLink)
+	for _, l := range m.Links {
+		if l.Source == sourceDir {
+			dir := filepath.Dir(l.Path)
+			byDir[dir] = append(byDir[dir], l)
+		}
+	}
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var links []ManagedLink
+	var missing []string
+	var conflicts []statusConflict
+	for _, dir := range dirs {
+		entries, err := fsys.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			PrintVerbose("Failed to list %s: %v", ContractPath(dir), err)
+		}
+		present := make(map[string]fs.DirEntry, len(entries))
+		for _, entry := range entries {
+			present[entry.Name()] = entry
+		}
+
+		for _, record := range byDir[dir] {
+			entry, ok := present[filepath.Base(record.Path)]
+			switch {
+			case !ok && record.Ephemeral:
+				PrintVerbose("Ephemeral link not present: %s", ContractPath(record.Path))
+			case !ok:
+				missing = append(missing, record.Path)
+			case entry.Type()&fs.ModeSymlink == 0:
+				if info, err := entry.Info(); err == nil {
+					conflicts = append(conflicts, statusConflict{link: PlannedLink{Target: record.Path}, info: info})
+				}
+			default:
+				if link, ok := recordedLinkState(record.Path, sources); ok {
+					links = append(links, link)
+				}
+			}
+		}
+	}
+	endScan("managed_links", len(links), "directories", len(dirs))
+	SummaryCount("managed", len(links))
+	SummaryCount("missing", len(missing))
+	SummaryCount("conflicts", len(conflicts))
+
+	printManagedLinks(links, sourceDir)
+	printMissingLinks(missing, sourceDir)
+	printConflicts(conflicts, sourceDir)
+
+	if len(missing) > 0 && slices.Contains(opts.FailOn, FailOnUnlinked) {
+		return WithHint(
+			fmt.Errorf("%d missing link(s)", len(missing)),
+			fmt.Sprintf("Run 'lnk create %s' to restore them", ContractPath(sourceDir)))
+	}
+	return nil
+}
+
+// recordedLinkState reads the symlink at path and reports whether it points
+// into one of sources, and if so whether its destination exists
+func recordedLinkState(path string, sources []string) (ManagedLink, bool) {
+	dest, err := fsys.Readlink(path)
+	if err != nil {
+		PrintVerbose("Failed to read symlink %s: %v", ContractPath(path), err)
+		return ManagedLink{}, false
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(filepath.Dir(path), dest)
+	}
+	i := slices.IndexFunc(sources, func(source string) bool { return isWithin(dest, source) && dest != source })
+	if i < 0 {
+		return ManagedLink{}, false
+	}
+
+	link := ManagedLink{Path: path, Target: dest, Source: sources[i]}
+	if _, err := fsys.Stat(path); err != nil {
+		link.Broken = brokenReason(dest, err)
+		link.IsBroken = link.Broken != ""
+	}
+	return link, true
+}
+
+// printMissingLinks displays recorded links that no longer exist
+func printMissingLinks(missing []string, sourceDir string) {
+	if len(missing) == 0 {
+		return
+	}
+
+	if ShouldSimplifyOutput() {
+		for _, path := range missing {
+			fmt.Printf("missing %s\n", ContractPath(path))
+		}
+		return
+	}
+
+	fmt.Println()
+	PrintInfo("Missing links:")
+	for _, path := range missing {
+		fmt.Printf("%s Missing: %s\n", Yellow(WarningIcon), ContractPath(path))
+	}
+	fmt.Println()
+	PrintInfo("Total: %s", Yellow(fmt.Sprintf("%d missing", len(missing))))
+	PrintInfo("Next: Run 'lnk create %s' to restore them", ContractPath(sourceDir))
+}
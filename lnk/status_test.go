@@ -1,10 +1,14 @@
 package lnk
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestStatusBrokenLinksToStdout verifies broken links are printed to stdout, not stderr.
@@ -244,3 +248,347 @@ func TestStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestStatusUnlinkedSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "dotfiles")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "test")
+	createTestFile(t, filepath.Join(sourceDir, ".zshrc"), "test")
+	createTestFile(t, filepath.Join(sourceDir, "notes.swp"), "test")
+	os.MkdirAll(targetDir, 0755)
+	createTestSymlink(t, filepath.Join(sourceDir, ".bashrc"), filepath.Join(targetDir, ".bashrc"))
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, IgnorePatterns: []string{"*.swp"}}
+
+	stdout, _ := captureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() unexpected error: %v", err)
+		}
+	})
+
+	if !strings.Contains(stdout, "unlinked "+filepath.Join(sourceDir, ".zshrc")) {
+		t.Errorf("Status() missing unlinked .zshrc\nstdout: %q", stdout)
+	}
+	if strings.Contains(stdout, "unlinked "+filepath.Join(sourceDir, ".bashrc")) {
+		t.Errorf("Status() reported linked .bashrc as unlinked\nstdout: %q", stdout)
+	}
+	if strings.Contains(stdout, "notes.swp") {
+		t.Errorf("Status() reported ignored file as unlinked\nstdout: %q", stdout)
+	}
+}
+
+func TestStatusFailOnUnlinked(t *testing.T) {
+	tests := []struct {
+		name    string
+		linked  bool
+		failOn  []string
+		wantErr string
+	}{
+		{name: "unlinked without fail-on", linked: false},
+		{name: "unlinked with fail-on", linked: false, failOn: []string{FailOnUnlinked}, wantErr: "1 unlinked source file(s)"},
+		{name: "all linked with fail-on", linked: true, failOn: []string{FailOnUnlinked}},
+		{name: "unknown condition", linked: true, failOn: []string{"bogus"}, wantErr: "unknown condition"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			sourceDir := filepath.Join(tmpDir, "dotfiles")
+			targetDir := filepath.Join(tmpDir, "home")
+			createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "test")
+			os.MkdirAll(targetDir, 0755)
+			if tt.linked {
+				createTestSymlink(t, filepath.Join(sourceDir, ".bashrc"), filepath.Join(targetDir, ".bashrc"))
+			}
+
+			opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, FailOn: tt.failOn}
+
+			var err error
+			captureOutput(t, func() {
+				err = Status(opts)
+			})
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Status() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Status() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusConflicts(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "dotfiles")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "repo")
+	createTestFile(t, filepath.Join(sourceDir, ".vimrc"), "repo")
+	createTestFile(t, filepath.Join(targetDir, ".bashrc"), "local content")
+	createTestSymlink(t, filepath.Join(sourceDir, ".vimrc"), filepath.Join(targetDir, ".vimrc"))
+
+	modTime := time.Date(2026, 3, 14, 9, 26, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(targetDir, ".bashrc"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}
+
+	stdout, _ := captureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() unexpected error: %v", err)
+		}
+	})
+
+	want := "conflict " + filepath.Join(targetDir, ".bashrc") + " 13 2026-03-14T09:26:00Z"
+	if !strings.Contains(stdout, want) {
+		t.Errorf("Status() missing %q\nstdout: %q", want, stdout)
+	}
+	if strings.Contains(stdout, "unlinked") {
+		t.Errorf("Status() reported conflicting file as unlinked\nstdout: %q", stdout)
+	}
+	if strings.Contains(stdout, "conflict "+filepath.Join(targetDir, ".vimrc")) {
+		t.Errorf("Status() reported active link as conflict\nstdout: %q", stdout)
+	}
+}
+
+func TestDescribeConflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "file")
+	createTestFile(t, file, "12345")
+	modTime := time.Date(2026, 3, 14, 9, 26, 0, 0, time.Local)
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Lstat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := describeConflict(info), "5 bytes, modified 2026-03-14 09:26"; got != want {
+		t.Errorf("describeConflict() = %q, want %q", got, want)
+	}
+
+	info, err = os.Lstat(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describeConflict(info); !strings.HasPrefix(got, "directory, modified ") {
+		t.Errorf("describeConflict() for dir = %q", got)
+	}
+}
+
+func TestStatusBrokenReasons(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "dotfiles")
+	targetDir := filepath.Join(tmpDir, "home")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(targetDir, 0755)
+
+	// The source file is gone, but its directory is still there
+	createTestSymlink(t, filepath.Join(sourceDir, ".missing"), filepath.Join(targetDir, ".missing"))
+	// The whole directory that held the source is gone
+	createTestSymlink(t, filepath.Join(sourceDir, ".config", "foo", "config"), filepath.Join(targetDir, ".config", "foo", "config"))
+
+	stdout, _ := captureOutput(t, func() {
+		if err := Status(LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}); err != nil {
+			t.Fatalf("Status() unexpected error: %v", err)
+		}
+	})
+
+	ContainsOutput(t, stdout,
+		".missing "+BrokenSourceDeleted,
+		"config "+BrokenParentMissing)
+}
+
+func TestStatusVerboseLastCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "dotfiles")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "repo")
+	createTestFile(t, filepath.Join(sourceDir, ".old"), "old")
+	runTestGit(t, sourceDir, "init", "-q")
+	runTestGit(t, sourceDir, "add", ".")
+	runTestGit(t, sourceDir, "commit", "-q", "-m", "initial")
+	runTestGit(t, sourceDir, "rm", "-q", ".old")
+	runTestGit(t, sourceDir, "commit", "-q", "-m", "drop old")
+	createTestFile(t, filepath.Join(targetDir, ".bashrc"), "local")
+	createTestSymlink(t, filepath.Join(sourceDir, ".old"), filepath.Join(targetDir, ".old"))
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}
+
+	stdout, _ := captureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() unexpected error: %v", err)
+		}
+	})
+	NotContainsOutput(t, stdout, "Last commit")
+
+	SetVerbosity(VerbosityVerbose)
+	t.Cleanup(func() { SetVerbosity(VerbosityNormal) })
+	stdout, _ = captureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() unexpected error: %v", err)
+		}
+	})
+	date := time.Now().Format("2006-01-02")
+	ContainsOutput(t, stdout,
+		"Last commit to "+filepath.Join(sourceDir, ".old")+": ",
+		"Last commit to "+filepath.Join(sourceDir, ".bashrc")+": ",
+		" "+date+" test")
+}
+
+func TestStatusEphemeralPackage(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"target": "runtime"}`)
+	createTestFile(t, filepath.Join(sourceDir, "nvim", PackageInfoFileName), `{"ephemeral": true}`)
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"shell", "nvim", "work"},
+		FailOn:         []string{FailOnUnlinked},
+	}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	gitconfig := filepath.Join(runtimeDir, ".gitconfig")
+	initLua := filepath.Join(targetDir, ".config", "nvim", "init.lua")
+	assertSymlink(t, gitconfig, filepath.Join(sourceDir, "work", ".gitconfig"))
+	assertSymlink(t, initLua, filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua"))
+
+	// A reboot clears the ephemeral targets; status does not count them
+	os.Remove(gitconfig)
+	os.Remove(initLua)
+	stdout, _ := captureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Errorf("Status() error = %v", err)
+		}
+	})
+	if strings.Contains(stdout, "unlinked") {
+		t.Errorf("Status() reported ephemeral links as unlinked\nstdout: %q", stdout)
+	}
+
+	// create recreates them
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	assertSymlink(t, gitconfig, filepath.Join(sourceDir, "work", ".gitconfig"))
+	assertSymlink(t, initLua, filepath.Join(sourceDir, "nvim", ".config", "nvim", "init.lua"))
+
+	// Other packages are still reported
+	os.Remove(filepath.Join(targetDir, ".bashrc"))
+	captureOutput(t, func() {
+		if err := Status(opts); err == nil || !strings.Contains(err.Error(), "1 unlinked source file(s)") {
+			t.Errorf("Status() error = %v, want 1 unlinked", err)
+		}
+	})
+}
+
+func TestStatusShallow(t *testing.T) {
+	m := useMemFS(t)
+	for _, name := range []string{".bashrc", ".vimrc", ".zshrc", ".config/git/config"} {
+		m.writeFile(t, "/src/"+name, "content")
+	}
+	opts := LinkOptions{SourceDir: "/src", TargetDir: "/home/u", Shallow: true}
+
+	err := Status(opts)
+	if err == nil || !strings.Contains(err.Error(), "no link records") {
+		t.Fatalf("Status() before create error = %v, want no link records", err)
+	}
+
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(LinkOptions{SourceDir: "/src", TargetDir: "/home/u"}); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	if err := m.Remove("/home/u/.vimrc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("/src/.zshrc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("/home/u/.config/git/config"); err != nil {
+		t.Fatal(err)
+	}
+	m.writeFile(t, "/home/u/.config/git/config", "local")
+
+	output := CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Errorf("Status() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output,
+		"active /home/u/.bashrc",
+		"broken /home/u/.zshrc source-deleted",
+		"missing /home/u/.vimrc",
+		"conflict /home/u/.config/git/config")
+
+	opts.FailOn = []string{FailOnUnlinked}
+	CaptureOutput(t, func() {
+		err = Status(opts)
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 missing link(s)") {
+		t.Errorf("Status() with --fail-on unlinked error = %v, want 1 missing link(s)", err)
+	}
+}
+
+func TestStatusRemote(t *testing.T) {
+	origin, clone := setupSyncTest(t)
+	runTestGit(t, origin, "branch", "feature")
+	runTestGit(t, clone, "fetch", "-q")
+	runTestGit(t, clone, "branch", "-q", "--track", "feature", "origin/feature")
+	runTestGit(t, clone, "branch", "old")
+	runTestGit(t, origin, "branch", "-D", "feature")
+	createTestFile(t, filepath.Join(origin, "shell", ".bashrc"), "# upstream")
+	runTestGit(t, origin, "commit", "-q", "-am", "upstream change")
+	createTestFile(t, filepath.Join(clone, "nvim", ".config", "nvim", "init.lua"), "-- local")
+	runTestGit(t, clone, "commit", "-q", "-am", "local change")
+	createTestFile(t, filepath.Join(clone, "shell", ".zshrc"), "# new")
+	opts := LinkOptions{SourceDir: clone, TargetDir: t.TempDir(), Remote: true}
+
+	output := CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output,
+		"ahead 1", "behind 1",
+		"uncommitted shell/.zshrc",
+		"stale-branch feature gone",
+		"stale-branch old merged")
+
+	var out bytes.Buffer
+	if _, err := statusJSON(&out, opts, clone, opts.TargetDir, []string{clone}, nil); err != nil {
+		t.Fatalf("statusJSON() error = %v", err)
+	}
+	var report StatusReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if r := report.Repository; r == nil || !r.Fetched || r.Ahead != 1 || r.Behind != 1 ||
+		len(r.Uncommitted) != 1 || len(r.StaleBranches) != 2 || !strings.HasPrefix(r.Upstream, "origin/") {
+		t.Errorf("repository = %+v, want 1 ahead, 1 behind, 1 uncommitted, 2 stale branches", r)
+	}
+
+	t.Run("not a repository", func(t *testing.T) {
+		sourceDir := t.TempDir()
+		err := Status(LinkOptions{SourceDir: sourceDir, TargetDir: t.TempDir(), Remote: true})
+		if err == nil || !strings.Contains(err.Error(), "not a git repository") {
+			t.Errorf("Status() error = %v, want not a git repository", err)
+		}
+	})
+}
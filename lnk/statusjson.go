@@ -0,0 +1,181 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatusSchemaVersion is the newest version of the status JSON schema. Within
+// a version fields are only ever added; renaming or removing one, or changing
+// what it means, needs a new version.
+const StatusSchemaVersion = 1
+
+// StatusSchemaVersions lists the status JSON schema versions lnk can write
+var StatusSchemaVersions = []int{1}
+
+// StatusReport is the status of a source directory as written by
+// 'lnk status --output json'. Paths are absolute.
+type StatusReport struct {
+	SchemaVersion int              `json:"schema_version"`
+	SourceDir     string           `json:"source_dir"`
+	TargetDir     string           `json:"target_dir"`
+	Links         []StatusLink     `json:"links"`     // managed links, sorted by path
+	Unlinked      []StatusUnlinked `json:"unlinked"`  // source files with nothing at their target
+	Conflicts     []StatusConflict `json:"conflicts"` // targets occupied by a real file or directory
+	Copies        []StatusCopy     `json:"copies"`    // paths managed as copies (orphan --to-copy)
+
+	Repository *RepoHealth `json:"repository,omitempty"` // the source repository against its remote (--remote)
+}
+
+// StatusLink is a managed symlink
+type StatusLink struct {
+	Path   string `json:"path"`
+	Source string `json:"source"`           // source file the link points to
+	State  string `json:"state"`            // "active" or "broken"
+	Reason string `json:"reason,omitempty"` // Broken* reason for broken links
+}
+
+// StatusUnlinked is a source file whose target path does not exist
+type StatusUnlinked struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// StatusConflict is a target path where a real file or directory blocks a link
+type StatusConflict struct {
+	Target   string `json:"target"`
+	Source   string `json:"source"`
+	Type     string `json:"type"` // "file" or "directory"
+	Size     int64  `json:"size"`
+	Modified string `json:"modified"` // RFC 3339, UTC
+}
+
+// StatusCopy is a path managed as a copy of its source
+type StatusCopy struct {
+	Path   string `json:"path"`
+	Source string `json:"source"` // source file or directory it is a copy of
+	State  string `json:"state"`  // "copy", "copy-stale", or "copy-missing"
+}
+
+// ParseOutputFormat splits an --output value into its format and pinned
+// schema version: "json" is the newest status schema (version 0), "json=v1"
+// pins version 1. Only json output can be pinned.
+func ParseOutputFormat(value string) (format string, version int, err error) {
+	format, pin, pinned := strings.Cut(value, "=")
+	if !slices.Contains(OutputFormats, format) {
+		return "", 0, NewValidationErrorWithHint("output", value, "unknown output format",
+			fmt.Sprintf("Valid formats: %s, or json=v%d to pin the status schema version", strings.Join(OutputFormats, ", "), StatusSchemaVersion))
+	}
+	if !pinned {
+		return format, 0, nil
+	}
+	n, convErr := strconv.Atoi(strings.TrimPrefix(pin, "v"))
+	if format != OutputJSON || !strings.HasPrefix(pin, "v") || convErr != nil {
+		return "", 0, NewValidationErrorWithHint("output", value, "invalid schema version",
+			fmt.Sprintf("Pin a version as json=v%d", StatusSchemaVersion))
+	}
+	if !slices.Contains(StatusSchemaVersions, n) {
+		return "", 0, NewValidationErrorWithHint("output", value, "unsupported status schema version",
+			fmt.Sprintf("This version of lnk writes status schema versions %s", formatVersions(StatusSchemaVersions)))
+	}
+	return format, n, nil
+}
+
+// formatVersions lists schema versions as "v1, v2"
+func formatVersions(versions []int) string {
+	names := make([]string, len(versions))
+	for i, v := range versions {
+		names[i] = fmt.Sprintf("v%d", v)
+	}
+	return strings.Join(names, ", ")
+}
+
+// statusJSON writes the status report for Status in the requested schema
+// version and returns the unlinked sources for --fail-on
+func statusJSON(w io.Writer, opts LinkOptions, sourceDir, targetDir string, pkgDirs []string, maps []Mapping) ([]PlannedLink, error) {
+	version := opts.SchemaVersion
+	if version == 0 {
+		version = StatusSchemaVersion
+	}
+	report := StatusReport{
+		SchemaVersion: version,
+		SourceDir:     sourceDir,
+		TargetDir:     targetDir,
+		Links:         []StatusLink{},
+		Unlinked:      []StatusUnlinked{},
+		Conflicts:     []StatusConflict{},
+		Copies:        []StatusCopy{},
+	}
+
+	links, err := findPackageLinks(targetDir, pkgDirs, mappingSources(maps))
+	if err != nil {
+		return nil, err
+	}
+	copies := loadCopies(targetDir, sourceDir)
+	if len(opts.Groups) > 0 {
+		inGroups, err := groupTargets(opts.Groups, pkgDirs, maps, targetDir, opts.IgnorePatterns)
+		if err != nil {
+			return nil, err
+		}
+		links, copies = managedInGroups(links, copies, inGroups)
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Path < links[j].Path })
+	broken := 0
+	for _, link := range links {
+		l := StatusLink{Path: link.Path, Source: link.Target, State: "active"}
+		if link.IsBroken {
+			l.State, l.Reason = "broken", link.Broken
+			broken++
+		}
+		report.Links = append(report.Links, l)
+	}
+
+	for _, c := range copies {
+		report.Copies = append(report.Copies, StatusCopy{Path: c.Path, Source: c.Dest, State: copyState(c)})
+	}
+
+	unlinked, conflicts, err := classifyPlannedLinks(pkgDirs, maps, targetDir, opts.IgnorePatterns, opts.LocalOnly, opts.Groups, copies)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range unlinked {
+		report.Unlinked = append(report.Unlinked, StatusUnlinked{Source: link.Source, Target: link.Target})
+	}
+	for _, c := range conflicts {
+		kind := "file"
+		if c.info.IsDir() {
+			kind = "directory"
+		}
+		report.Conflicts = append(report.Conflicts, StatusConflict{
+			Target:   c.link.Target,
+			Source:   c.link.Source,
+			Type:     kind,
+			Size:     c.info.Size(),
+			Modified: c.info.ModTime().UTC().Format(time.RFC3339),
+		})
+	}
+
+	if opts.Remote {
+		if report.Repository, err = repoHealth(sourceDir); err != nil {
+			return nil, err
+		}
+	}
+
+	SummaryCount("managed", len(links))
+	SummaryCount("broken", broken)
+	SummaryCount("unlinked", len(unlinked))
+	SummaryCount("conflicts", len(conflicts))
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return nil, fmt.Errorf("encoding status: %w", err)
+	}
+	return unlinked, nil
+}
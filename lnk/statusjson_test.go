@@ -0,0 +1,173 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// statusSchemaFile is the documented status JSON contract for a version
+func statusSchemaFile(version int) string {
+	return filepath.Join("..", "docs", "design", "schemas", fmt.Sprintf("status.v%d.json", version))
+}
+
+// checkSchema validates value against the parts of JSON Schema the status
+// contract uses (type, required, properties, items, enum, const) and returns
+// every violation found
+func checkSchema(schema map[string]any, value any, at string) []string {
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: want an object, got %T", at, value)}
+		}
+		for _, key := range schema["required"].([]any) {
+			if _, ok := obj[key.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required field %q", at, key))
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for key, sub := range props {
+			if v, ok := obj[key]; ok {
+				problems = append(problems, checkSchema(sub.(map[string]any), v, at+"."+key)...)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: want an array, got %T", at, value)}
+		}
+		for i, item := range items {
+			problems = append(problems, checkSchema(schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			problems = append(problems, fmt.Sprintf("%s: want a string, got %T", at, value))
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			problems = append(problems, fmt.Sprintf("%s: want an integer, got %v", at, value))
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", at, value, enum))
+	}
+	if c, ok := schema["const"]; ok && c != value {
+		problems = append(problems, fmt.Sprintf("%s: %v, want %v", at, value, c))
+	}
+	return problems
+}
+
+// TestStatusJSONSchemaCompatibility checks that status output for every
+// supported schema version still satisfies its documented contract, so
+// fields scripts rely on are never renamed or dropped within a version
+func TestStatusJSONSchemaCompatibility(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "home")
+	os.MkdirAll(targetDir, 0755)
+
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "bash")
+	os.Symlink(filepath.Join(sourceDir, ".bashrc"), filepath.Join(targetDir, ".bashrc"))
+	os.Symlink(filepath.Join(sourceDir, ".gone"), filepath.Join(targetDir, ".gone"))
+	createTestFile(t, filepath.Join(sourceDir, ".vimrc"), "vim")
+	createTestFile(t, filepath.Join(sourceDir, ".zshrc"), "zsh")
+	createTestFile(t, filepath.Join(targetDir, ".zshrc"), "local zsh")
+	createTestFile(t, filepath.Join(sourceDir, ".inputrc"), "new")
+	createTestFile(t, filepath.Join(targetDir, ".inputrc"), "old")
+	m := &Manifest{Version: manifestVersion}
+	m.AddCopy(filepath.Join(targetDir, ".inputrc"), sourceDir, filepath.Join(sourceDir, ".inputrc"), "")
+	if err := m.Save(targetDir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range StatusSchemaVersions {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			data, err := os.ReadFile(statusSchemaFile(version))
+			if err != nil {
+				t.Fatalf("every supported version needs a documented schema: %v", err)
+			}
+			var schema map[string]any
+			if err := json.Unmarshal(data, &schema); err != nil {
+				t.Fatalf("parse %s: %v", statusSchemaFile(version), err)
+			}
+
+			var out bytes.Buffer
+			opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, SchemaVersion: version}
+			if _, err := statusJSON(&out, opts, sourceDir, targetDir, []string{sourceDir}, nil); err != nil {
+				t.Fatalf("statusJSON() error = %v", err)
+			}
+			var report map[string]any
+			if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+				t.Fatalf("status output is not JSON: %v\n%s", err, out.String())
+			}
+			for _, problem := range checkSchema(schema, report, "$") {
+				t.Error(problem)
+			}
+
+			// Every kind of entry is present, so the whole contract was checked
+			for _, key := range []string{"links", "unlinked", "conflicts", "copies"} {
+				if items, _ := report[key].([]any); len(items) == 0 {
+					t.Errorf("fixture should produce %s entries", key)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusJSONEmptyListsAreArrays(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "dotfiles")
+	targetDir := filepath.Join(tempDir, "home")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(targetDir, 0755)
+
+	var out bytes.Buffer
+	if _, err := statusJSON(&out, LinkOptions{}, sourceDir, targetDir, []string{sourceDir}, nil); err != nil {
+		t.Fatalf("statusJSON() error = %v", err)
+	}
+	var report map[string]any
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report["schema_version"] != float64(StatusSchemaVersion) {
+		t.Errorf("schema_version = %v, want %d", report["schema_version"], StatusSchemaVersion)
+	}
+	for _, key := range []string{"links", "unlinked", "conflicts", "copies"} {
+		if _, ok := report[key].([]any); !ok {
+			t.Errorf("%s = %v, want an empty array rather than null", key, report[key])
+		}
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		format  string
+		version int
+		wantErr bool
+	}{
+		{"json", OutputJSON, 0, false},
+		{"yaml", OutputYAML, 0, false},
+		{"json=v1", OutputJSON, 1, false},
+		{"json=v99", "", 0, true},
+		{"json=1", "", 0, true},
+		{"yaml=v1", "", 0, true},
+		{"xml", "", 0, true},
+	}
+	for _, tt := range tests {
+		format, version, err := ParseOutputFormat(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseOutputFormat(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if format != tt.format || version != tt.version {
+			t.Errorf("ParseOutputFormat(%q) = %q, %d, want %q, %d", tt.value, format, version, tt.format, tt.version)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package lnk
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SuggestOptions holds options for suggesting files to adopt
+type SuggestOptions struct {
+	SourceDir      string   // base directory for dotfiles (e.g., ~/git/dotfiles)
+	TargetDir      string   // where to look for unmanaged files (default: ~)
+	IgnorePatterns []string // suggestions matching these patterns are skipped
+	LocalOnly      []string // target paths never suggested or adopted (from .lnklocal)
+	Sensitive      []string // files adopt refuses to store in plaintext (from .lnksensitive)
+	DryRun         bool     // preview the adoption instead of performing it
+}
+
+// wellKnownDotfiles lists common dotfiles relative to the home directory, in
+// ranking order. Entries under .config are discovered separately.
+var wellKnownDotfiles = []string{
+	".bashrc",
+	".bash_profile",
+	".bash_aliases",
+	".zshrc",
+	".zprofile",
+	".zshenv",
+	".profile",
+	".gitconfig",
+	".gitignore_global",
+	".vimrc",
+	".tmux.conf",
+	".inputrc",
+	".editorconfig",
+	".ssh/config",
+	".npmrc",
+	".curlrc",
+	".wgetrc",
+}
+
+// maxSuggestCount caps how many files are counted inside a suggested directory
+const maxSuggestCount = 1000
+
+// suggestion is an unmanaged file or directory that could be adopted
+type suggestion struct {
+	path  string      // absolute path in the target directory
+	info  os.FileInfo // Lstat result
+	files int         // regular files inside a directory (capped at maxSuggestCount)
+}
+
+// Suggest scans well-known dotfile locations for unmanaged files, lists them
+// ranked by relevance, and lets the user pick which to adopt and into which
+// subdirectory of the source directory.
+func Suggest(opts SuggestOptions) error {
+	PrintCommandHeader("Suggested Files to Adopt")
+
+	paths, err := ResolvePaths(opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
+	PrintVerbose("Source directory: %s", sourceDir)
+	PrintVerbose("Target directory: %s", targetDir)
+
+	suggestions := findSuggestions(sourceDir, targetDir, opts.IgnorePatterns)
+	local := newLocalOnlyMatcher(targetDir, opts.LocalOnly)
+	suggestions = slices.DeleteFunc(suggestions, func(s suggestion) bool {
+		_, ok := local.matches(s.path)
+		return ok
+	})
+	if len(suggestions) == 0 {
+		PrintEmptyResult("unmanaged dotfiles")
+		return nil
+	}
+
+	if ShouldSimplifyOutput() {
+		for _, s := range suggestions {
+			fmt.Printf("unmanaged %s\n", ContractPath(s.path))
+		}
+	} else {
+		for i, s := range suggestions {
+			fmt.Printf("%3d) %s %s\n", i+1, ContractPath(s.path), Cyan("("+describeSuggestion(s)+")"))
+		}
+	}
+
+	if !canPrompt() {
+		if !ShouldSimplifyOutput() {
+			fmt.Println()
+			PrintInfo("Next: Run 'lnk adopt %s <path...>' to adopt files", ContractPath(sourceDir))
+		}
+		return nil
+	}
+
+	fmt.Println()
+	selected, err := promptSelection(len(suggestions))
+	if err != nil || len(selected) == 0 {
+		PrintInfo("Nothing selected.")
+		return nil
+	}
+	mapping, err := promptMapping(sourceDir)
+	if err != nil {
+		PrintInfo("Nothing selected.")
+		return nil
+	}
+
+	var adoptPaths []string
+	for _, idx := range selected {
+		adoptPaths = append(adoptPaths, suggestions[idx].path)
+	}
+
+	mappingDir := filepath.Join(sourceDir, mapping)
+	if _, err := os.Stat(mappingDir); os.IsNotExist(err) {
+		if opts.DryRun {
+			fmt.Println()
+			PrintDryRun("Would create directory: %s", ContractPath(mappingDir))
+			for _, p := range adoptPaths {
+				PrintDryRun("Would adopt: %s", ContractPath(p))
+			}
+			fmt.Println()
+			PrintDryRunSummary()
+			return nil
+		}
+		if err := fsys.MkdirAll(mappingDir, 0755); err != nil {
+			return NewPathErrorWithHint("create directory", mappingDir, err,
+				"Check that you have write permissions in the source directory")
+		}
+	}
+
+	fmt.Println()
+	result, err := Adopt(AdoptOptions{
+		SourceDir: mappingDir,
+		TargetDir: targetDir,
+		Paths:     adoptPaths,
+		LocalOnly: opts.LocalOnly,
+		Sensitive: opts.Sensitive,
+		DryRun:    opts.DryRun,
+	})
+	PrintAdoptSummary(result, mappingDir)
+	return err
+}
+
+// findSuggestions returns unmanaged well-known dotfiles and entries under
+// ~/.config, ranked: well-known files in list order, then .config entries by
+// most recently modified. Symlinks, ignored paths, and anything containing or
+// inside the source directory are skipped.
+func findSuggestions(sourceDir, targetDir string, ignorePatterns []string) []suggestion {
+	pm := NewPatternMatcher(ignorePatterns)
+
+	consider := func(path string) (suggestion, bool) {
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink != 0 {
+			return suggestion{}, false
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return suggestion{}, false
+		}
+		if rel, err := filepath.Rel(targetDir, path); err != nil || pm.Matches(rel) {
+			return suggestion{}, false
+		}
+		if isWithin(path, sourceDir) || isWithin(sourceDir, path) {
+			return suggestion{}, false
+		}
+		s := suggestion{path: path, info: info}
+		if info.IsDir() {
+			s.files = countFiles(path)
+			if s.files == 0 {
+				return suggestion{}, false
+			}
+		}
+		return s, true
+	}
+
+	var suggestions []suggestion
+	for _, rel := range wellKnownDotfiles {
+		if s, ok := consider(filepath.Join(targetDir, rel)); ok {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	configDir := filepath.Join(targetDir, ".config")
+	entries, err := os.ReadDir(configDir)
+	if err != nil && !os.IsNotExist(err) {
+		PrintVerbose("Failed to read %s: %v", ContractPath(configDir), err)
+	}
+	var configSuggestions []suggestion
+	for _, entry := range entries {
+		if s, ok := consider(filepath.Join(configDir, entry.Name())); ok {
+			configSuggestions = append(configSuggestions, s)
+		}
+	}
+	sort.SliceStable(configSuggestions, func(i, j int) bool {
+		return configSuggestions[i].info.ModTime().After(configSuggestions[j].info.ModTime())
+	})
+
+	return append(suggestions, configSuggestions...)
+}
+
+// isWithin reports whether path is dir or lies inside it
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// countFiles counts regular files under dir, stopping at maxSuggestCount
+func countFiles(dir string) int {
+	count := 0
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			count++
+			if count >= maxSuggestCount {
+				return fs.SkipAll
+			}
+		}
+		return nil
+	})
+	return count
+}
+
+// describeSuggestion summarizes a suggestion for display
+func describeSuggestion(s suggestion) string {
+	if !s.info.IsDir() {
+		return fmt.Sprintf("%d bytes", s.info.Size())
+	}
+	if s.files >= maxSuggestCount {
+		return fmt.Sprintf("%d+ files", maxSuggestCount)
+	}
+	return fmt.Sprintf("%d file(s)", s.files)
+}
+
+// promptSelection asks which of n numbered suggestions to adopt and returns
+// their zero-based indexes. An empty answer selects nothing.
+func promptSelection(n int) ([]int, error) {
+	for {
+		answer, err := readChoice("Select files to adopt (e.g. 1,3-5, 'all', or Enter to skip):")
+		if err != nil {
+			return nil, err
+		}
+		selected, err := parseSelection(answer, n)
+		if err == nil {
+			return selected, nil
+		}
+		fmt.Fprintf(os.Stderr, "%s %v\n", Yellow(WarningIcon), err)
+	}
+}
+
+// parseSelection parses a selection like "1,3-5" or "all" against n items and
+// returns sorted, de-duplicated zero-based indexes.
+func parseSelection(answer string, n int) ([]int, error) {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return nil, nil
+	}
+	if answer == "a" || answer == "all" {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+
+	seen := make(map[int]bool)
+	for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+		lo, hi, isRange := strings.Cut(field, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q", field)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("invalid selection %q", field)
+			}
+		}
+		if start < 1 || end > n || start > end {
+			return nil, fmt.Errorf("selection %q is out of range 1-%d", field, n)
+		}
+		for i := start; i <= end; i++ {
+			seen[i-1] = true
+		}
+	}
+
+	selected := make([]int, 0, len(seen))
+	for i := range seen {
+		selected = append(selected, i)
+	}
+	sort.Ints(selected)
+	return selected, nil
+}
+
+// promptMapping asks which top-level directory of the source directory to
+// adopt into. An empty answer or "." selects the source directory itself.
+func promptMapping(sourceDir string) (string, error) {
+	for {
+		answer, err := readLine("Adopt into which directory of the source directory? [.]:")
+		if err != nil {
+			return "", err
+		}
+		if answer == "" || filepath.Clean(answer) == "." {
+			return ".", nil
+		}
+		name, err := cleanPackageName(sourceDir, answer)
+		if err == nil {
+			return name, nil
+		}
+		fmt.Fprintf(os.Stderr, "%s %s must be the name of a top-level directory in the source directory\n", Yellow(WarningIcon), answer)
+	}
+}
@@ -0,0 +1,134 @@
+package lnk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindSuggestions(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetDir := filepath.Join(tmpDir, "home")
+	sourceDir := filepath.Join(targetDir, ".config", "dotfiles")
+
+	createTestFile(t, filepath.Join(targetDir, ".gitconfig"), "[user]")
+	createTestFile(t, filepath.Join(targetDir, ".bashrc"), "# bashrc")
+	createTestFile(t, filepath.Join(targetDir, ".unknownrc"), "not well known")
+	createTestFile(t, filepath.Join(sourceDir, ".vimrc"), "\" vimrc")
+	createTestSymlink(t, filepath.Join(sourceDir, ".vimrc"), filepath.Join(targetDir, ".vimrc"))
+	createTestFile(t, filepath.Join(targetDir, ".config", "old", "config"), "old")
+	createTestFile(t, filepath.Join(targetDir, ".config", "nvim", "init.lua"), "-- init")
+	createTestFile(t, filepath.Join(targetDir, ".config", "app.swp"), "ignored")
+	if err := os.MkdirAll(filepath.Join(targetDir, ".config", "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(targetDir, ".config", "old"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, s := range findSuggestions(sourceDir, targetDir, []string{"*.swp"}) {
+		got = append(got, s.path)
+	}
+	want := []string{
+		filepath.Join(targetDir, ".bashrc"),
+		filepath.Join(targetDir, ".gitconfig"),
+		filepath.Join(targetDir, ".config", "nvim"),
+		filepath.Join(targetDir, ".config", "old"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findSuggestions() = %v, want %v", got, want)
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		answer  string
+		want    []int
+		wantErr bool
+	}{
+		{answer: "", want: nil},
+		{answer: "2", want: []int{1}},
+		{answer: "1,3-4", want: []int{0, 2, 3}},
+		{answer: "3 1 3", want: []int{0, 2}},
+		{answer: "all", want: []int{0, 1, 2, 3}},
+		{answer: "0", wantErr: true},
+		{answer: "5", wantErr: true},
+		{answer: "3-2", wantErr: true},
+		{answer: "x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			got, err := parseSelection(tt.answer, 4)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSelection(%q) error = %v, wantErr %v", tt.answer, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSelection(%q) = %v, want %v", tt.answer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestAdoptsSelection(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "dotfiles")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(targetDir, ".bashrc"), "# bashrc")
+	createTestFile(t, filepath.Join(targetDir, ".gitconfig"), "[user]")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	origCanPrompt, origReader := canPrompt, promptReader
+	canPrompt = func() bool { return true }
+	// Invalid answers are asked again; then pick .gitconfig into the "Home" mapping
+	promptReader = bufio.NewReader(strings.NewReader("9\n2\n../escape\nHome/nested\nHome\n"))
+	t.Cleanup(func() { canPrompt, promptReader = origCanPrompt, origReader })
+
+	opts := SuggestOptions{SourceDir: sourceDir, TargetDir: targetDir}
+	_, stderr := captureOutput(t, func() {
+		if err := Suggest(opts); err != nil {
+			t.Fatalf("Suggest() error = %v", err)
+		}
+	})
+
+	ContainsOutput(t, stderr, "out of range", "../escape must be the name", "Home/nested must be the name")
+	assertSymlink(t, filepath.Join(targetDir, ".gitconfig"), filepath.Join(sourceDir, "Home", ".gitconfig"))
+	if info, err := os.Lstat(filepath.Join(targetDir, ".bashrc")); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("expected ~/.bashrc to be left alone")
+	}
+}
+
+func TestSuggestNonInteractive(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "dotfiles")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(targetDir, ".bashrc"), "# bashrc")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	origCanPrompt := canPrompt
+	canPrompt = func() bool { return false }
+	t.Cleanup(func() { canPrompt = origCanPrompt })
+
+	opts := SuggestOptions{SourceDir: sourceDir, TargetDir: targetDir}
+	output := CaptureOutput(t, func() {
+		if err := Suggest(opts); err != nil {
+			t.Fatalf("Suggest() error = %v", err)
+		}
+	})
+
+	ContainsOutput(t, output, "unmanaged", ".bashrc")
+	if info, err := os.Lstat(filepath.Join(targetDir, ".bashrc")); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("expected ~/.bashrc to be left alone")
+	}
+}
@@ -0,0 +1,85 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RunSummary is the end-of-run summary written by --summary-file, so
+// provisioning systems can collect results without parsing stdout
+type RunSummary struct {
+	Command    string         `json:"command"`
+	SourceDir  string         `json:"source_dir,omitempty"`
+	DryRun     bool           `json:"dry_run"`
+	ExitCode   int            `json:"exit_code"`
+	DurationMS float64        `json:"duration_ms"`
+	Counts     map[string]int `json:"counts"` // per-command totals, e.g. created, failed
+	Errors     []ErrorRecord  `json:"errors"` // errors and warnings printed during the run
+}
+
+// runSummary collects the summary for --summary-file; nil when there is none
+var (
+	runSummary     *RunSummary
+	summaryPath    string
+	summaryStarted time.Time
+)
+
+// StartSummary starts collecting a run summary to be written to path by
+// WriteSummary
+func StartSummary(path, command string, dryRun bool) error {
+	expanded, err := ExpandPath(path)
+	if err != nil {
+		return err
+	}
+	summaryPath = expanded
+	summaryStarted = time.Now()
+	runSummary = &RunSummary{
+		Command: command,
+		DryRun:  dryRun,
+		Counts:  map[string]int{},
+		Errors:  []ErrorRecord{},
+	}
+	return nil
+}
+
+// SetSummarySourceDir records the resolved source directory in the run summary
+func SetSummarySourceDir(dir string) {
+	if runSummary != nil {
+		runSummary.SourceDir = dir
+	}
+}
+
+// SummaryCount adds n to a count in the run summary
+func SummaryCount(key string, n int) {
+	if runSummary != nil {
+		runSummary.Counts[key] += n
+	}
+}
+
+// recordSummaryError adds an error or warning to the run summary
+func recordSummaryError(level string, err error) {
+	if runSummary != nil {
+		runSummary.Errors = append(runSummary.Errors, NewErrorRecord(level, err))
+	}
+}
+
+// WriteSummary finishes the run summary with exitCode and writes it
+// atomically. It does nothing when no summary was started.
+func WriteSummary(exitCode int) error {
+	if runSummary == nil {
+		return nil
+	}
+	runSummary.ExitCode = exitCode
+	runSummary.DurationMS = float64(time.Since(summaryStarted).Microseconds()) / 1000
+
+	data, err := json.MarshalIndent(runSummary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding summary: %w", err)
+	}
+	if err := writeFileAtomic(summaryPath, append(data, '\n'), 0644); err != nil {
+		return NewPathErrorWithHint("write summary file", summaryPath, err,
+			"Check that the directory exists and is writable")
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package lnk
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readSummary(t *testing.T, path string) RunSummary {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading summary: %v", err)
+	}
+	var summary RunSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("summary is not JSON: %v\n%s", err, data)
+	}
+	return summary
+}
+
+func TestWriteSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := StartSummary(path, "remove", true); err != nil {
+		t.Fatalf("StartSummary() error = %v", err)
+	}
+	defer func() { runSummary = nil }()
+
+	SetSummarySourceDir("/home/u/dotfiles")
+	SummaryCount("removed", 2)
+	SummaryCount("removed", 1)
+	captureOutput(t, func() {
+		PrintWarningWithHint(WithHint(errors.New("Failed to remove ~/.zshrc"), "Check permissions"))
+	})
+
+	if err := WriteSummary(ExitError); err != nil {
+		t.Fatalf("WriteSummary() error = %v", err)
+	}
+	summary := readSummary(t, path)
+	if summary.Command != "remove" || summary.SourceDir != "/home/u/dotfiles" || !summary.DryRun {
+		t.Errorf("summary header = %+v", summary)
+	}
+	if summary.ExitCode != ExitError {
+		t.Errorf("ExitCode = %d, want %d", summary.ExitCode, ExitError)
+	}
+	if summary.Counts["removed"] != 3 {
+		t.Errorf("Counts = %v, want removed=3", summary.Counts)
+	}
+	want := ErrorRecord{Level: "warning", Code: CodeError, Message: "Failed to remove ~/.zshrc", Hint: "Check permissions"}
+	if len(summary.Errors) != 1 || summary.Errors[0] != want {
+		t.Errorf("Errors = %+v, want [%+v]", summary.Errors, want)
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the summary file, got %d entries", len(entries))
+	}
+}
+
+func TestWriteSummaryWithoutStart(t *testing.T) {
+	if err := WriteSummary(0); err != nil {
+		t.Errorf("WriteSummary() without StartSummary error = %v", err)
+	}
+}
+
+func TestWriteSummaryUnwritable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "summary.json")
+	if err := StartSummary(path, "create", false); err != nil {
+		t.Fatalf("StartSummary() error = %v", err)
+	}
+	defer func() { runSummary = nil }()
+
+	err := WriteSummary(0)
+	if err == nil {
+		t.Fatal("expected error writing to a missing directory")
+	}
+	if GetErrorHint(err) == "" {
+		t.Errorf("expected a hint for %v", err)
+	}
+}
+
+func TestCreateLinksSummaryCounts(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := StartSummary(path, "create", false); err != nil {
+		t.Fatalf("StartSummary() error = %v", err)
+	}
+	defer func() { runSummary = nil }()
+
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell", "nvim"}}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	if err := WriteSummary(0); err != nil {
+		t.Fatalf("WriteSummary() error = %v", err)
+	}
+
+	counts := readSummary(t, path).Counts
+	if counts["planned"] != 2 || counts["created"] != 2 || counts["failed"] != 0 {
+		t.Errorf("Counts = %v, want planned=2 created=2 failed=0", counts)
+	}
+}
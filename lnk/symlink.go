@@ -1,11 +1,13 @@
 package lnk
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 )
 
 // ManagedLink represents a symlink managed by lnk
@@ -13,9 +15,54 @@ type ManagedLink struct {
 	Path     string // The symlink path
 	Target   string // The target path (what the symlink points to)
 	IsBroken bool   // Whether the link is broken
+	Broken   string // Why the link is broken (one of the Broken* reasons; empty when not broken)
 	Source   string // Source mapping name (e.g., "home", "work")
 }
 
+// Reasons a managed link is broken, as reported by status and prune
+const (
+	BrokenSourceDeleted    = "source-deleted"    // the source file is gone; its directory remains
+	BrokenParentMissing    = "parent-missing"    // the directory that held the source is gone
+	BrokenPermissionDenied = "permission-denied" // the source could not be checked
+	BrokenSymlinkLoop      = "symlink-loop"      // the source is a symlink chain that loops or is too long
+)
+
+// brokenReason classifies why the source a link points to could not be
+// stat'ed. It returns "" for errors that do not make the link broken.
+func brokenReason(source string, statErr error) string {
+	switch {
+	case errors.Is(statErr, fs.ErrPermission):
+		return BrokenPermissionDenied
+	case errors.Is(statErr, syscall.ELOOP):
+		return BrokenSymlinkLoop
+	case !os.IsNotExist(statErr):
+		return ""
+	}
+	if _, err := fsys.Stat(filepath.Dir(source)); err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return BrokenPermissionDenied
+		}
+		return BrokenParentMissing
+	}
+	return BrokenSourceDeleted
+}
+
+// brokenHint suggests how to fix links broken for reason
+func brokenHint(reason, sourceDir string) string {
+	switch reason {
+	case BrokenSourceDeleted:
+		return fmt.Sprintf("Restore the deleted source files (e.g. with git checkout), or run 'lnk prune %s' to remove the links", ContractPath(sourceDir))
+	case BrokenParentMissing:
+		return fmt.Sprintf("A directory in the source was moved or deleted; restore it, or run 'lnk prune %s' and then 'lnk create %s'",
+			ContractPath(sourceDir), ContractPath(sourceDir))
+	case BrokenPermissionDenied:
+		return "Check the permissions of the source directories; lnk could not tell whether the sources exist"
+	case BrokenSymlinkLoop:
+		return "A symlink in the source leads back to itself; run 'lnk status -v' to see the chain and fix it in the repository"
+	}
+	return ""
+}
+
 // FindManagedLinks finds all symlinks in startPath that point to any of the specified source directories.
 // sources should be absolute paths (use ExpandPath first if needed).
 func FindManagedLinks(startPath string, sources []string) ([]ManagedLink, error) {
@@ -23,7 +70,7 @@ func FindManagedLinks(startPath string, sources []string) ([]ManagedLink, error)
 	// when EvalSymlinks resolves path components (e.g., /var → /private/var on macOS)
 	resolvedSources := make([]string, len(sources))
 	for i, s := range sources {
-		resolved, err := filepath.EvalSymlinks(s)
+		resolved, err := fsys.EvalSymlinks(s)
 		if err != nil {
 			resolvedSources[i] = s
 		} else {
@@ -34,7 +81,7 @@ func FindManagedLinks(startPath string, sources []string) ([]ManagedLink, error)
 	var links []ManagedLink
 	var walkErrors []error
 
-	err := filepath.WalkDir(startPath, func(path string, d fs.DirEntry, err error) error {
+	err := walkDir(startPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			PrintVerbose("Error walking path %s: %v", path, err)
 			walkErrors = append(walkErrors, err)
@@ -57,16 +104,15 @@ func FindManagedLinks(startPath string, sources []string) ([]ManagedLink, error)
 		}
 
 		// Try filepath.EvalSymlinks first for non-broken links
-		var resolvedTarget string
-		var isBroken bool
+		var resolvedTarget, broken string
 
-		evalTarget, evalErr := filepath.EvalSymlinks(path)
+		evalTarget, evalErr := fsys.EvalSymlinks(path)
 		if evalErr == nil {
 			// EvalSymlinks succeeded — link is not broken
 			resolvedTarget = evalTarget
 		} else {
 			// EvalSymlinks failed — likely a broken link; fall back to manual resolution
-			rawTarget, err := os.Readlink(path)
+			rawTarget, err := fsys.Readlink(path)
 			if err != nil {
 				PrintVerbose("Failed to read symlink %s: %v", path, err)
 				return nil
@@ -83,20 +129,22 @@ func FindManagedLinks(startPath string, sources []string) ([]ManagedLink, error)
 			}
 			// Resolve the parent directory to handle path symlinks (e.g., /var → /private/var)
 			parentDir := filepath.Dir(cleanTarget)
-			if resolvedParent, err := filepath.EvalSymlinks(parentDir); err == nil {
+			if resolvedParent, err := fsys.EvalSymlinks(parentDir); err == nil {
 				resolvedTarget = filepath.Join(resolvedParent, filepath.Base(cleanTarget))
 			} else {
 				resolvedTarget = cleanTarget
 			}
 
-			// Verify the target is genuinely missing vs other error
-			if _, statErr := os.Stat(resolvedTarget); statErr != nil {
-				if os.IsNotExist(statErr) {
-					isBroken = true
-				} else {
-					// Other error (e.g., permission denied) — skip, can't classify
+			// Classify why the target could not be reached; other errors skip the link
+			if _, statErr := fsys.Stat(resolvedTarget); statErr != nil {
+				if broken = brokenReason(resolvedTarget, statErr); broken == "" {
 					return nil
 				}
+				if broken == BrokenSymlinkLoop {
+					if _, err := symlinkChain(path); err != nil {
+						PrintVerbose("%v", err)
+					}
+				}
 			}
 		}
 
@@ -122,6 +170,23 @@ func FindManagedLinks(startPath string, sources []string) ([]ManagedLink, error)
 			}
 		}
 
+		// Links through a symlinked directory the package follows resolve
+		// outside the source; the link itself still points into it
+		if managedBySource == "" && evalErr == nil {
+			if rawTarget, err := fsys.Readlink(path); err == nil {
+				if !filepath.IsAbs(rawTarget) {
+					rawTarget = filepath.Join(filepath.Dir(path), rawTarget)
+				}
+				for _, source := range sources {
+					if isWithin(rawTarget, source) && filepath.Clean(rawTarget) != source {
+						managedBySource = source
+						resolvedTarget = filepath.Clean(rawTarget)
+						break
+					}
+				}
+			}
+		}
+
 		if managedBySource == "" {
 			return nil
 		}
@@ -129,7 +194,8 @@ func FindManagedLinks(startPath string, sources []string) ([]ManagedLink, error)
 		links = append(links, ManagedLink{
 			Path:     path,
 			Target:   resolvedTarget,
-			IsBroken: isBroken,
+			IsBroken: broken != "",
+			Broken:   broken,
 			Source:   managedBySource,
 		})
 		return nil
@@ -155,14 +221,14 @@ func (e LinkExistsError) Error() string {
 // CreateSymlink creates a single symlink, handling existing files/links
 func CreateSymlink(source, target string) error {
 	// Check if target exists
-	if info, err := os.Lstat(target); err == nil {
+	if info, err := fsys.Lstat(target); err == nil {
 		// If it's already a symlink pointing to our source, nothing to do
 		if info.Mode()&os.ModeSymlink != 0 {
-			if existingTarget, err := os.Readlink(target); err == nil && existingTarget == source {
+			if linkPointsTo(target, source) {
 				return LinkExistsError{target: target}
 			}
 			// Remove existing symlink pointing elsewhere
-			if err := os.Remove(target); err != nil {
+			if err := fsys.Remove(target); err != nil {
 				return NewLinkErrorWithHint("remove existing link", source, target, err,
 					"Check file permissions and ensure you have write access to the target directory")
 			}
@@ -175,7 +241,7 @@ func CreateSymlink(source, target string) error {
 	}
 
 	// Create new symlink
-	if err := os.Symlink(source, target); err != nil {
+	if err := fsys.Symlink(source, target); err != nil {
 		return NewLinkErrorWithHint("create symlink", source, target, err,
 			"Check that the parent directory exists and you have write permissions")
 	}
@@ -183,10 +249,37 @@ func CreateSymlink(source, target string) error {
 	return nil
 }
 
+// replaceIdenticalFile replaces the regular file at target with a symlink to
+// source, after checking again that its content matches source. The link is
+// created beside target and renamed over it, so target is never missing.
+func replaceIdenticalFile(source, target string) error {
+	same, err := filesEqual(source, target)
+	if err != nil {
+		return NewPathError("compare", target, err)
+	}
+	if !same {
+		return NewLinkErrorWithHint("replace file", source, target,
+			fmt.Errorf("file changed and no longer matches the source"),
+			fmt.Sprintf("Compare the files, then use 'lnk adopt %s <source-dir>' to resolve the difference", ContractPath(target)))
+	}
+	tmp := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".lnk-tmp")
+	fsys.Remove(tmp)
+	if err := fsys.Symlink(source, tmp); err != nil {
+		return NewLinkErrorWithHint("create symlink", source, target, err,
+			"Check that you have write permissions in the target directory")
+	}
+	if err := fsys.Rename(tmp, target); err != nil {
+		fsys.Remove(tmp)
+		return NewLinkErrorWithHint("replace file", source, target, err,
+			"Check that you have write permissions in the target directory")
+	}
+	return nil
+}
+
 // RemoveSymlink removes a symlink at the given path.
 // Returns error if path is not a symlink or removal fails.
 func RemoveSymlink(path string) error {
-	info, err := os.Lstat(path)
+	info, err := fsys.Lstat(path)
 	if err != nil {
 		return NewPathError("remove symlink", path, err)
 	}
@@ -194,5 +287,5 @@ func RemoveSymlink(path string) error {
 		return NewPathErrorWithHint("remove symlink", path, ErrNotSymlink,
 			"Only symlinks can be removed with this operation")
 	}
-	return os.Remove(path)
+	return fsys.Remove(path)
 }
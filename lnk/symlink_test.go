@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -408,3 +409,27 @@ func TestFindManagedLinks(t *testing.T) {
 		})
 	}
 }
+
+func TestBrokenReason(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestFile(t, filepath.Join(tmpDir, "dir", "file"), "x")
+
+	tests := []struct {
+		name    string
+		source  string
+		statErr error
+		want    string
+	}{
+		{"source deleted", filepath.Join(tmpDir, "dir", "missing"), fs.ErrNotExist, BrokenSourceDeleted},
+		{"parent missing", filepath.Join(tmpDir, "gone", "missing"), fs.ErrNotExist, BrokenParentMissing},
+		{"permission denied", filepath.Join(tmpDir, "dir", "file"), fs.ErrPermission, BrokenPermissionDenied},
+		{"other error", filepath.Join(tmpDir, "dir", "file"), fs.ErrInvalid, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := brokenReason(tt.source, tt.statErr); got != tt.want {
+				t.Errorf("brokenReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package lnk
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SyncOptions holds options for updating the source directory from its remote
+type SyncOptions struct {
+	SourceDir string   // dotfiles repository (or a directory inside one)
+	TargetDir string   // where links into SourceDir live (default ~)
+	Packages  []string // packages to keep checked out with Sparse
+	Sparse    bool     // restrict the checkout to Packages with git sparse-checkout
+	DryRun    bool     // print the git commands instead of running them
+
+	Reload bool // run the reload actions of packages whose files the pull changed
+
+	// ForceOverwrite backs up and replaces copies (orphan --to-copy) that were
+	// edited locally, instead of leaving them out of date
+	ForceOverwrite bool
+}
+
+// Sync pulls the latest changes into the source directory's git repository.
+// With Sparse, it first limits the checkout to the selected packages so
+// unused packages are never materialized on this machine. Afterwards it offers
+// to prune the links recorded in the manifest whose source files the pull
+// deleted, and updates managed copies that were not edited locally.
+func Sync(opts SyncOptions) error {
+	PrintCommandHeader("Syncing Source Directory")
+
+	targetDir := opts.TargetDir
+	if targetDir == "" {
+		targetDir = "~"
+	}
+	paths, err := ResolvePaths(opts.SourceDir, targetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir := paths.SourceDir
+	PrintVerbose("Source directory: %s", sourceDir)
+
+	if !isGitWorkTree(sourceDir) {
+		return NewPathErrorWithHint("sync", sourceDir, fmt.Errorf("not a git repository"),
+			"Clone your dotfiles with git to use 'lnk sync', or update them manually")
+	}
+
+	var commands [][]string
+	var checkedOut []string
+	if opts.Sparse {
+		var args []string
+		checkedOut, args, err = sparseCheckoutArgs(sourceDir, opts.Packages)
+		if err != nil {
+			return err
+		}
+		commands = append(commands, args)
+	}
+	commands = append(commands, []string{"pull", "--ff-only"})
+
+	if opts.DryRun {
+		fmt.Println()
+		for _, args := range commands {
+			PrintDryRun("Would run: git %s", strings.Join(args, " "))
+		}
+		fmt.Println()
+		PrintDryRunSummary()
+		return nil
+	}
+
+	if err := checkWritable("sync", sourceDir); err != nil {
+		return err
+	}
+	if opts.Reload {
+		defer collectReloads(sourceDir, opts.Packages)()
+	}
+	before := gitHead(sourceDir)
+	for _, args := range commands {
+		PrintVerbose("Running: git %s", strings.Join(args, " "))
+		out, err := gitCombinedOutput(sourceDir, args...)
+		if err != nil {
+			return WithHint(
+				fmt.Errorf("git %s failed: %w\n%s", args[0], err, strings.TrimSpace(out)),
+				"Resolve the problem in the repository with git, then run 'lnk sync' again")
+		}
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if line != "" {
+				PrintDetail("%s", line)
+			}
+		}
+	}
+
+	if opts.Sparse {
+		PrintSuccess("Checked out packages: %s", strings.Join(checkedOut, ", "))
+	}
+	if after := gitHead(sourceDir); before != "" && after != before {
+		if err := pruneRemovedSources(sourceDir, paths.TargetDir, before, after); err != nil {
+			return err
+		}
+		if reloads != nil {
+			queueChangedSources(sourceDir, before, after)
+		}
+	}
+	if err := refreshCopies(sourceDir, paths.TargetDir, opts.ForceOverwrite); err != nil {
+		return err
+	}
+	PrintSummary("Source directory is up to date")
+	PrintNextStep("create", sourceDir, "link new files")
+	return nil
+}
+
+// queueChangedSources notes the files the pull changed for --reload
+func queueChangedSources(sourceDir, before, after string) {
+	changed, err := gitChangedBetween(sourceDir, before, after)
+	if err != nil {
+		PrintVerbose("Could not list files changed by the pull: %v", err)
+		return
+	}
+	for _, rel := range changed {
+		reloads.add(filepath.Join(sourceDir, filepath.FromSlash(rel)))
+	}
+}
+
+// removedLink is a recorded link whose source file a pull deleted
+type removedLink struct {
+	path   string // the symlink
+	commit string // "<short hash> <subject>" of the commit that deleted dest
+}
+
+// pruneRemovedSources finds the links the manifest records for sourceDir whose
+// source files the commits between before and after deleted, and offers to
+// remove exactly those. Links whose source still exists, such as a file that
+// was deleted and added back, are left alone.
+func pruneRemovedSources(sourceDir, targetDir, before, after string) error {
+	removed, err := gitRemovedBetween(sourceDir, before, after)
+	if err != nil {
+		PrintVerbose("Could not list files removed by the pull: %v", err)
+		return nil
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		PrintWarningWithHint(fmt.Errorf("Failed to check links to removed files: %w", err))
+		return nil
+	}
+
+	var links []removedLink
+	for _, l := range m.Links {
+		if l.Source != sourceDir {
+			continue
+		}
+		dest, err := fsys.Readlink(l.Path)
+		if err != nil {
+			continue
+		}
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(filepath.Dir(l.Path), dest)
+		}
+		rel, err := filepath.Rel(sourceDir, dest)
+		if err != nil || exists(dest) {
+			continue
+		}
+		if commit, ok := removed[filepath.ToSlash(rel)]; ok {
+			links = append(links, removedLink{path: l.Path, commit: commit})
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].path < links[j].path })
+
+	fmt.Println()
+	PrintInfo("%d link(s) point to files removed by this pull:", len(links))
+	for _, l := range links {
+		PrintDetail("%s (removed in %s)", ContractPath(l.path), l.commit)
+	}
+	if !confirm(fmt.Sprintf("Prune %d link(s) to removed files? [y/N]", len(links))) {
+		PrintInfo("Run 'lnk prune %s' to remove them later", ContractPath(sourceDir))
+		return nil
+	}
+
+	var pruned, parents []string
+	var failed int
+	for _, l := range links {
+		if err := RemoveSymlink(l.path); err != nil {
+			PrintWarningWithHint(fmt.Errorf("Failed to prune %s: %w", ContractPath(l.path), err))
+			failed++
+			continue
+		}
+		PrintSuccess("Pruned: %s", ContractPath(l.path))
+		pruned = append(pruned, l.path)
+		parents = append(parents, filepath.Dir(l.path))
+	}
+	forgetLinks(targetDir, pruned)
+	CleanEmptyDirs(parents, targetDir)
+	SummaryCount("pruned", len(pruned))
+	SummaryCount("failed", failed)
+	if failed > 0 {
+		return fmt.Errorf("failed to prune %d symlink(s)", failed)
+	}
+	return nil
+}
+
+// sparseCheckoutArgs builds the git sparse-checkout command that keeps only the
+// selected packages and their dependencies, and returns those packages with it.
+// Package paths are relative to the repository root, so a source directory
+// inside a larger repository is accounted for.
+func sparseCheckoutArgs(sourceDir string, packages []string) ([]string, []string, error) {
+	if len(packages) == 0 {
+		return nil, nil, WithHint(fmt.Errorf("--sparse requires packages to check out"),
+			fmt.Sprintf("Use --packages or list packages in %s", PackagesFileName))
+	}
+
+	prefix, err := gitOutput(sourceDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, nil, fmt.Errorf("finding repository root: %w", err)
+	}
+	prefix = strings.TrimSpace(prefix)
+
+	selected := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		name, err := cleanPackageName(sourceDir, pkg)
+		if err != nil {
+			return nil, nil, err
+		}
+		selected = append(selected, name)
+	}
+
+	// Dependencies may not be checked out yet, so read them from git
+	resolved, err := resolvePackageDeps(selected, func(pkg string) ([]string, error) {
+		return gitPackageRequires(sourceDir, prefix, pkg)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	args := []string{"sparse-checkout", "set", "--cone"}
+	for _, name := range resolved {
+		args = append(args, path.Join(prefix, name))
+	}
+	return resolved, args, nil
+}
+
+// gitPackageRequires reads the dependencies of pkg from its .lnkrequires file
+// as committed at HEAD, whether or not the package is checked out.
+func gitPackageRequires(sourceDir, prefix, pkg string) ([]string, error) {
+	out, err := gitOutput(sourceDir, "show", "HEAD:"+path.Join(prefix, pkg, RequiresFileName))
+	if err != nil {
+		return nil, nil // no .lnkrequires committed for this package
+	}
+	deps := parseLines(out)
+	for i, dep := range deps {
+		name, err := cleanPackageName(sourceDir, dep)
+		if err != nil {
+			return nil, err
+		}
+		deps[i] = name
+	}
+	return deps, nil
+}
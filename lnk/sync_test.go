@@ -0,0 +1,347 @@
+package lnk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// setupSyncTest creates an origin repository with shell and nvim packages and
+// returns a clone of it. Skips the test when git is not available.
+func setupSyncTest(t *testing.T) (origin, clone string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tmpDir := t.TempDir()
+	origin = filepath.Join(tmpDir, "origin")
+	clone = filepath.Join(tmpDir, "clone")
+
+	createTestFile(t, filepath.Join(origin, "shell", ".bashrc"), "# bashrc")
+	createTestFile(t, filepath.Join(origin, "nvim", ".config", "nvim", "init.lua"), "-- init")
+	runTestGit(t, origin, "init", "-q")
+	runTestGit(t, origin, "add", ".")
+	runTestGit(t, origin, "commit", "-q", "-m", "initial")
+	runTestGit(t, tmpDir, "clone", "-q", origin, clone)
+	return origin, clone
+}
+
+// runTestGit runs git in dir with a fixed identity, failing the test on error
+func runTestGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir,
+		"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestSyncPulls(t *testing.T) {
+	origin, clone := setupSyncTest(t)
+	createTestFile(t, filepath.Join(origin, "shell", ".zshrc"), "# zshrc")
+	runTestGit(t, origin, "add", ".")
+	runTestGit(t, origin, "commit", "-q", "-m", "add zshrc")
+
+	output := CaptureOutput(t, func() {
+		if err := Sync(SyncOptions{SourceDir: clone}); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+
+	if _, err := os.Stat(filepath.Join(clone, "shell", ".zshrc")); err != nil {
+		t.Errorf("expected pulled file: %v", err)
+	}
+	ContainsOutput(t, output, "Source directory is up to date")
+}
+
+func TestSyncSparse(t *testing.T) {
+	_, clone := setupSyncTest(t)
+
+	CaptureOutput(t, func() {
+		if err := Sync(SyncOptions{SourceDir: clone, Packages: []string{"shell"}, Sparse: true}); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+
+	if _, err := os.Stat(filepath.Join(clone, "shell", ".bashrc")); err != nil {
+		t.Errorf("selected package should be checked out: %v", err)
+	}
+	assertNotExists(t, filepath.Join(clone, "nvim"))
+}
+
+func TestSyncSparseDryRun(t *testing.T) {
+	_, clone := setupSyncTest(t)
+
+	output := CaptureOutput(t, func() {
+		err := Sync(SyncOptions{SourceDir: clone, Packages: []string{"shell"}, Sparse: true, DryRun: true})
+		if err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+
+	ContainsOutput(t, output, "Would run: git sparse-checkout set --cone shell", "Would run: git pull --ff-only")
+	assertDirExists(t, filepath.Join(clone, "nvim"))
+}
+
+func TestSyncSparseRequiresPackages(t *testing.T) {
+	_, clone := setupSyncTest(t)
+
+	err := Sync(SyncOptions{SourceDir: clone, Sparse: true})
+	if err == nil {
+		t.Fatal("expected error for --sparse without packages")
+	}
+	ContainsOutput(t, GetErrorHint(err), PackagesFileName)
+}
+
+func TestSyncNotGitRepository(t *testing.T) {
+	err := Sync(SyncOptions{SourceDir: t.TempDir()})
+	if err == nil {
+		t.Fatal("expected error outside a git repository")
+	}
+	ContainsOutput(t, err.Error(), "not a git repository")
+}
+
+func TestSyncSparseIncludesDependencies(t *testing.T) {
+	origin, clone := setupSyncTest(t)
+	createTestFile(t, filepath.Join(origin, "tmux", ".tmux.conf"), "# tmux")
+	createTestFile(t, filepath.Join(origin, "nvim", RequiresFileName), "shell\n")
+	runTestGit(t, origin, "add", ".")
+	runTestGit(t, origin, "commit", "-q", "-m", "add tmux and nvim deps")
+	runTestGit(t, clone, "pull", "-q")
+
+	output := CaptureOutput(t, func() {
+		if err := Sync(SyncOptions{SourceDir: clone, Packages: []string{"nvim"}, Sparse: true}); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+
+	if _, err := os.Stat(filepath.Join(clone, "shell", ".bashrc")); err != nil {
+		t.Errorf("dependency should be checked out: %v", err)
+	}
+	assertNotExists(t, filepath.Join(clone, "tmux"))
+	ContainsOutput(t, output, "shell, nvim")
+}
+
+// setupRemovedSourceTest links both packages of a clone into a target
+// directory, records the links in the manifest, and deletes shell/.bashrc in
+// the origin. It returns the clone, the target directory, and the two links.
+func setupRemovedSourceTest(t *testing.T) (clone, targetDir, bashrc, initLua string) {
+	t.Helper()
+	origin, clone := setupSyncTest(t)
+	targetDir = t.TempDir()
+	bashrc = filepath.Join(targetDir, ".bashrc")
+	initLua = filepath.Join(targetDir, ".config", "nvim", "init.lua")
+	if err := os.Symlink(filepath.Join(clone, "shell", ".bashrc"), bashrc); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(initLua), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(clone, "nvim", ".config", "nvim", "init.lua"), initLua); err != nil {
+		t.Fatal(err)
+	}
+	recordCreatedLinks(targetDir, clone, []string{bashrc, initLua})
+
+	runTestGit(t, origin, "rm", "-q", filepath.Join("shell", ".bashrc"))
+	runTestGit(t, origin, "commit", "-q", "-m", "drop bashrc")
+	return clone, targetDir, bashrc, initLua
+}
+
+func TestSyncPrunesLinksToRemovedSources(t *testing.T) {
+	clone, targetDir, bashrc, initLua := setupRemovedSourceTest(t)
+	SetAssumeYes(true)
+	t.Cleanup(func() { SetAssumeYes(false) })
+
+	output := CaptureOutput(t, func() {
+		if err := Sync(SyncOptions{SourceDir: clone, TargetDir: targetDir}); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+
+	ContainsOutput(t, output, "Pruned: "+bashrc)
+	assertNotExists(t, bashrc)
+	if _, err := os.Lstat(initLua); err != nil {
+		t.Errorf("link to a remaining source should be kept: %v", err)
+	}
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.LinkSource(bashrc); ok {
+		t.Error("pruned link should be dropped from the manifest")
+	}
+	if _, ok := m.LinkSource(initLua); !ok {
+		t.Error("kept link should stay in the manifest")
+	}
+}
+
+func TestSyncListsLinksToRemovedSources(t *testing.T) {
+	clone, targetDir, bashrc, _ := setupRemovedSourceTest(t)
+	origCanPrompt := canPrompt
+	canPrompt = func() bool { return false }
+	t.Cleanup(func() { canPrompt = origCanPrompt })
+
+	output := CaptureOutput(t, func() {
+		if err := Sync(SyncOptions{SourceDir: clone, TargetDir: targetDir}); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+
+	ContainsOutput(t, output, "1 link(s) point to files removed by this pull", "drop bashrc", "lnk prune")
+	if _, err := os.Lstat(bashrc); err != nil {
+		t.Errorf("link should be kept without confirmation: %v", err)
+	}
+}
+
+// addTestCopy writes content to path and records it in the manifest for
+// targetDir as a copy of dest, with the hash of hashed as written by lnk
+func addTestCopy(t *testing.T, targetDir, sourceDir, path, dest, content, hashed string) {
+	t.Helper()
+	createTestFile(t, path, content)
+	sum := sha256.Sum256([]byte(hashed))
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AddCopy(path, sourceDir, dest, hex.EncodeToString(sum[:]))
+	if err := m.Save(targetDir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertDiskContent fails the test unless the file at path holds content
+func assertDiskContent(t *testing.T, path, content string) {
+	t.Helper()
+	if data, err := os.ReadFile(path); err != nil || string(data) != content {
+		t.Errorf("%s = %q (err = %v), want %q", path, data, err, content)
+	}
+}
+
+func TestSyncUpdatesCopies(t *testing.T) {
+	origin, clone := setupSyncTest(t)
+	targetDir := t.TempDir()
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	initLua := filepath.Join(targetDir, ".config", "nvim", "init.lua")
+	addTestCopy(t, targetDir, clone, bashrc, filepath.Join(clone, "shell", ".bashrc"), "# bashrc", "# bashrc")
+	addTestCopy(t, targetDir, clone, initLua, filepath.Join(clone, "nvim", ".config", "nvim", "init.lua"), "-- edited here", "-- init")
+
+	createTestFile(t, filepath.Join(origin, "shell", ".bashrc"), "# bashrc v2")
+	createTestFile(t, filepath.Join(origin, "nvim", ".config", "nvim", "init.lua"), "-- init v2")
+	runTestGit(t, origin, "commit", "-q", "-am", "update")
+
+	var err error
+	output := CaptureOutput(t, func() {
+		err = Sync(SyncOptions{SourceDir: clone, TargetDir: targetDir})
+	})
+	if err == nil {
+		t.Fatal("Sync() error = nil, want an error for the edited copy")
+	}
+	ContainsOutput(t, output, "Updated copy: "+bashrc)
+	assertDiskContent(t, bashrc, "# bashrc v2")
+	assertDiskContent(t, initLua, "-- edited here")
+
+	output = CaptureOutput(t, func() {
+		if err := Sync(SyncOptions{SourceDir: clone, TargetDir: targetDir, ForceOverwrite: true}); err != nil {
+			t.Fatalf("Sync() with ForceOverwrite error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "Backed up: ", "Updated copy: "+initLua)
+	assertDiskContent(t, initLua, "-- init v2")
+	backups, _ := filepath.Glob(initLua + ".lnk-backup-*")
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one", backups)
+	}
+	assertDiskContent(t, backups[0], "-- edited here")
+
+	// The manifest now holds the hash of the new copy, so it counts as unedited
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range m.Copies {
+		if c.Hash != copyHash(c.Path) {
+			t.Errorf("copy %s hash = %s, want hash of the updated file", c.Path, c.Hash)
+		}
+	}
+}
+
+func TestSyncKeepsLocalCopies(t *testing.T) {
+	origin, clone := setupSyncTest(t)
+	targetDir := t.TempDir()
+	initLua := filepath.Join(targetDir, ".config", "nvim", "init.lua")
+	addTestCopy(t, targetDir, clone, initLua, filepath.Join(clone, "nvim", ".config", "nvim", "init.lua"), "-- init", "-- init")
+
+	createTestFile(t, filepath.Join(origin, "nvim", PackageInfoFileName), `{"keep_local": [".config/nvim/init.lua"]}`)
+	createTestFile(t, filepath.Join(origin, "nvim", ".config", "nvim", "init.lua"), "-- init v2")
+	runTestGit(t, origin, "add", ".")
+	runTestGit(t, origin, "commit", "-q", "-m", "keep init.lua local")
+
+	output := CaptureOutput(t, func() {
+		if err := Sync(SyncOptions{SourceDir: clone, TargetDir: targetDir, ForceOverwrite: true}); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "Kept local: "+initLua)
+	assertDiskContent(t, initLua, "-- init")
+}
+
+func TestSyncMergesCopies(t *testing.T) {
+	origin, clone := setupSyncTest(t)
+	createTestFile(t, filepath.Join(origin, PackageInfoFileName), `{"merge": ["shell/.bashrc"]}`)
+	createTestFile(t, filepath.Join(origin, "nvim", PackageInfoFileName), `{"merge": [".config/nvim/init.lua"]}`)
+	createTestFile(t, filepath.Join(origin, "nvim", ".config", "nvim", "init.lua"), "a\nb\nc\n")
+	runTestGit(t, origin, "add", ".")
+	runTestGit(t, origin, "commit", "-q", "-m", "merge copies")
+	runTestGit(t, clone, "pull", "-q")
+
+	targetDir := t.TempDir()
+	bashrc := filepath.Join(targetDir, ".bashrc")
+	initLua := filepath.Join(targetDir, ".config", "nvim", "init.lua")
+	sourceInit := filepath.Join(clone, "nvim", ".config", "nvim", "init.lua")
+	addTestCopy(t, targetDir, clone, bashrc, filepath.Join(clone, "shell", ".bashrc"), "# bashrc", "# bashrc")
+	addTestCopy(t, targetDir, clone, initLua, sourceInit, "a\nb\nc\n", "a\nb\nc\n")
+	sync := func() (string, error) {
+		var err error
+		output := CaptureOutput(t, func() {
+			err = Sync(SyncOptions{SourceDir: clone, TargetDir: targetDir})
+		})
+		return output, err
+	}
+	if _, err := sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !exists(copyBasePath(targetDir, copyHash(initLua))) {
+		t.Fatal("unedited copies should be kept as merge bases")
+	}
+
+	// Edited in both places: separate lines merge, the same line does not
+	createTestFile(t, initLua, "a local\nb\nc\n")
+	createTestFile(t, bashrc, "# bashrc here")
+	createTestFile(t, filepath.Join(origin, "nvim", ".config", "nvim", "init.lua"), "a\nb\nc upstream\n")
+	createTestFile(t, filepath.Join(origin, "shell", ".bashrc"), "# bashrc there")
+	runTestGit(t, origin, "commit", "-q", "-am", "update")
+	output, err := sync()
+	if err == nil {
+		t.Fatal("Sync() error = nil, want an error for the conflicting copy")
+	}
+	ContainsOutput(t, output, "Merged copy: "+initLua)
+	assertDiskContent(t, initLua, "a local\nb\nc upstream\n")
+	assertDiskContent(t, sourceInit, "a local\nb\nc upstream\n")
+	assertDiskContent(t, bashrc, "# bashrc here")
+	assertDiskContent(t, filepath.Join(clone, "shell", ".bashrc"), "# bashrc there")
+
+	// Edited only here: the copy goes into the repository
+	createTestFile(t, initLua, "a local\nb here\nc upstream\n")
+	output, _ = sync()
+	ContainsOutput(t, output, "Updated source from copy: "+sourceInit)
+	assertDiskContent(t, sourceInit, "a local\nb here\nc upstream\n")
+
+	// Only the bases of the recorded copies are kept
+	bases, _ := filepath.Glob(copyBasePath(targetDir, "*"))
+	if len(bases) != 2 {
+		t.Errorf("merge bases = %v, want one per copy", bases)
+	}
+}
@@ -0,0 +1,449 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// 'lnk init --from-template' starts a source directory from a dotfiles
+// template on GitHub. Like degit, it copies the files of the newest commit
+// without the repository's history. Files ending in .tmpl are rendered with
+// text/template, so a template can hold {{.Name}} or {{.Email}} where a user's
+// details belong, or {{.Vars.NAME}} for a value from its .lnk/data.yaml, and
+// lnk-template.json can list mappings and packages to set up.
+
+// TemplateConfigFileName is the optional file at the top of a template listing
+// what to set up besides the files; it is not copied
+const TemplateConfigFileName = "lnk-template.json"
+
+// templateSuffix marks files rendered with the placeholder values
+const templateSuffix = ".tmpl"
+
+// InitOptions configures InitFromTemplate
+type InitOptions struct {
+	SourceDir string // the new source directory; must be missing or empty
+	Template  string // GitHub repository: OWNER/REPO[/SUBDIR][#REF]
+	DryRun    bool   // fetch and render, but write nothing
+}
+
+// templateRepo is a parsed --from-template value
+type templateRepo struct {
+	Owner, Repo string
+	Subdir      string // directory within the repository to use; "" for all of it
+	Ref         string // branch or tag; "" for the default branch
+}
+
+// URL returns the repository's clone URL
+func (r templateRepo) URL() string {
+	return fmt.Sprintf("https://github.com/%s/%s.git", r.Owner, r.Repo)
+}
+
+// String returns the repository as OWNER/REPO[/SUBDIR][#REF]
+func (r templateRepo) String() string {
+	s := r.Owner + "/" + r.Repo
+	if r.Subdir != "" {
+		s += "/" + r.Subdir
+	}
+	if r.Ref != "" {
+		s += "#" + r.Ref
+	}
+	return s
+}
+
+// templateConfig is lnk-template.json
+type templateConfig struct {
+	Mappings []string `json:"mappings,omitempty"` // saved to .lnkmaps, as --map takes them; placeholders are rendered
+	Packages []string `json:"packages,omitempty"` // written to .lnkpackages
+}
+
+// fetchTemplate shallow-clones ref (the default branch when empty) of url into
+// dir, showing git's errors; tests replace it
+var fetchTemplate = func(url, ref, dir string) error {
+	if !hasCommand("git") {
+		return WithHint(errors.New("git not found"), "Install git, then run lnk init again")
+	}
+	args := []string{"clone", "--depth", "1", "--quiet"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	cmd := exec.Command("git", append(args, url, dir)...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	return cmd.Run()
+}
+
+// parseTemplateRepo reads OWNER/REPO[/SUBDIR][#REF], optionally given as a
+// github.com URL
+func parseTemplateRepo(spec string) (templateRepo, error) {
+	const hint = "Give a GitHub repository as OWNER/REPO, optionally with /SUBDIR and #BRANCH"
+	var r templateRepo
+	s := strings.TrimSpace(spec)
+	s, r.Ref, _ = strings.Cut(s, "#")
+	for _, prefix := range []string{"https://", "http://", "github.com/"} {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	parts := strings.Split(strings.Trim(s, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return r, NewValidationErrorWithHint("from-template", spec, "not a GitHub repository", hint)
+	}
+	r.Owner, r.Repo = parts[0], strings.TrimSuffix(parts[1], ".git")
+	if len(parts) > 2 {
+		r.Subdir = filepath.Join(parts[2:]...)
+		if !filepath.IsLocal(r.Subdir) {
+			return r, NewValidationErrorWithHint("from-template", spec, "subdirectory must be inside the repository", hint)
+		}
+	}
+	return r, nil
+}
+
+// InitFromTemplate fetches a template repository without its history into a
+// new source directory, renders its .tmpl files, and saves the mappings and
+// packages its lnk-template.json lists. git init then starts the directory's
+// own history. Everything is fetched and rendered before anything is written,
+// so a template that fails to render leaves nothing behind.
+func InitFromTemplate(opts InitOptions) error {
+	PrintCommandHeader("Initializing From Template")
+
+	repo, err := parseTemplateRepo(opts.Template)
+	if err != nil {
+		return err
+	}
+	sourceDir, err := ExpandPath(opts.SourceDir)
+	if err != nil {
+		return fmt.Errorf("expanding source directory %s: %w", opts.SourceDir, err)
+	}
+	if err := requireEmptyDir(sourceDir); err != nil {
+		return err
+	}
+	if !opts.DryRun {
+		if err := checkWritable("init", sourceDir); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := fsys.MkdirTemp("", "lnk-template-")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer fsys.RemoveAll(tmp)
+	PrintInfo("Fetching %s", repo)
+	clone := filepath.Join(tmp, "repo")
+	if err := fetchTemplate(repo.URL(), repo.Ref, clone); err != nil {
+		return NewPathErrorWithHint("fetch template", repo.URL(), err,
+			"Check the repository name and branch, and that the repository is public or git can access it")
+	}
+	root := filepath.Join(clone, repo.Subdir)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return NewPathErrorWithHint("fetch template", repo.String(), errors.New("subdirectory not found"),
+			"Check the path after OWNER/REPO")
+	}
+
+	config, err := loadTemplateConfig(root)
+	if err != nil {
+		return err
+	}
+	files, err := collectTemplateFiles(root)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return NewPathErrorWithHint("init", repo.String(), errors.New("template has no files"),
+			"Check that this is a dotfiles template")
+	}
+
+	values := loadTemplateValues(needsTemplateValues(files, config))
+	vars, err := loadTemplateVars(root)
+	if err != nil {
+		return err
+	}
+	texts, err := readTemplateTexts(root, files)
+	if err != nil {
+		return err
+	}
+	for _, spec := range config.Mappings {
+		texts = append(texts, templateText{Name: TemplateConfigFileName, Text: spec})
+	}
+	answers, err := askTemplateVars(texts, vars)
+	if err != nil {
+		return err
+	}
+	values["Vars"] = vars
+	rendered, err := renderTemplateFiles(root, texts, values)
+	if err != nil {
+		return err
+	}
+	var maps []Mapping
+	for _, spec := range config.Mappings {
+		text, err := renderTemplate(root, TemplateConfigFileName, spec, values)
+		if err != nil {
+			return err
+		}
+		m, err := ParseMapping(text)
+		if err != nil {
+			return NewPathErrorWithHint("read", TemplateConfigFileName, err, "Fix the mapping in the template")
+		}
+		maps = append(maps, m)
+	}
+
+	if opts.DryRun {
+		fmt.Fprintln(stdout())
+		PrintDryRun("Would copy %d file(s) from %s into %s", len(files), repo, ContractPath(sourceDir))
+		for _, rel := range files {
+			if strings.HasSuffix(rel, templateSuffix) {
+				PrintDryRun("Would render: %s", strings.TrimSuffix(rel, templateSuffix))
+			}
+		}
+		for _, m := range maps {
+			PrintDryRun("Would save mapping: %s", m)
+		}
+		if len(config.Packages) > 0 {
+			PrintDryRun("Would write %s: %s", PackagesFileName, strings.Join(config.Packages, ", "))
+		}
+		if len(answers) > 0 {
+			PrintDryRun("Would save %d answer(s) to %s", len(answers), filepath.Join(VarsDirName, VarsAnswersFileName))
+		}
+		fmt.Fprintln(stdout())
+		PrintDryRunSummary()
+		return nil
+	}
+
+	if err := writeTemplateFiles(root, sourceDir, files, rendered); err != nil {
+		return err
+	}
+	for _, m := range maps {
+		if err := SaveMapping(sourceDir, m); err != nil {
+			return err
+		}
+		PrintSuccess("Saved mapping: %s", m)
+	}
+	if len(config.Packages) > 0 {
+		path := filepath.Join(sourceDir, PackagesFileName)
+		data := "# From " + repo.String() + "\n" + strings.Join(config.Packages, "\n") + "\n"
+		if err := writeFileAtomic(path, []byte(data), 0644); err != nil {
+			return NewPathError("write", path, err)
+		}
+		PrintSuccess("Wrote %s: %s", PackagesFileName, strings.Join(config.Packages, ", "))
+	}
+	if len(answers) > 0 {
+		if err := saveTemplateAnswers(sourceDir, answers); err != nil {
+			return err
+		}
+		PrintSuccess("Saved %d answer(s) to %s", len(answers), filepath.Join(VarsDirName, VarsAnswersFileName))
+	}
+	if hasCommand("git") {
+		if out, err := gitCombinedOutput(sourceDir, "init", "-q"); err != nil {
+			PrintWarning("git init failed: %s", strings.TrimSpace(out))
+		}
+	}
+	SummaryCount("files", len(files))
+	SummaryCount("rendered", len(rendered))
+	SummaryCount("mappings", len(maps))
+
+	fmt.Println()
+	PrintSummary("Created %s from %s (%d file(s), %d rendered)", ContractPath(sourceDir), repo, len(files), len(rendered))
+	PrintNextStep("create --dry-run", sourceDir, "preview the links")
+	return nil
+}
+
+// loadTemplateConfig reads lnk-template.json at the top of a template, if any
+func loadTemplateConfig(root string) (templateConfig, error) {
+	var config templateConfig
+	data, err := os.ReadFile(filepath.Join(root, TemplateConfigFileName))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, NewPathError("read", TemplateConfigFileName, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&config); err != nil {
+		return config, NewPathErrorWithHint("read", TemplateConfigFileName, err,
+			`The template's lnk-template.json takes "mappings" and "packages"`)
+	}
+	for _, pkg := range config.Packages {
+		if !filepath.IsLocal(pkg) || strings.ContainsRune(pkg, filepath.Separator) {
+			return config, NewValidationErrorWithHint("packages", pkg, "must be a top-level directory",
+				"Fix the packages in the template's lnk-template.json")
+		}
+	}
+	return config, nil
+}
+
+// collectTemplateFiles returns the sorted paths, relative to root, of the
+// regular files and symlinks to copy: all but .git and lnk-template.json
+func collectTemplateFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		rel, _ := filepath.Rel(root, path)
+		if rel == TemplateConfigFileName {
+			return nil
+		}
+		if d.Type().IsRegular() || d.Type()&fs.ModeSymlink != 0 {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// needsTemplateValues reports whether the template has anything to render
+func needsTemplateValues(files []string, config templateConfig) bool {
+	for _, rel := range files {
+		if strings.HasSuffix(rel, templateSuffix) {
+			return true
+		}
+	}
+	return len(config.Mappings) > 0
+}
+
+// loadTemplateValues gathers the placeholder values: Username (the login
+// name), Name, Email, Hostname, and Home. The name and email come
+// from git's global configuration; when it has none and ask is set, they are
+// asked for at a terminal. A value still unknown is left out, so a template
+// using it fails to render instead of rendering it empty.
+func loadTemplateValues(ask bool) map[string]any {
+	values := map[string]any{}
+	if u, err := user.Current(); err == nil {
+		values["Username"] = u.Username
+	} else if name := os.Getenv("USER"); name != "" {
+		values["Username"] = name
+	}
+	if host, err := machineHostname(); err == nil {
+		values["Hostname"] = host
+	}
+	if home, err := ExpandPath("~"); err == nil {
+		values["Home"] = home
+	}
+	for _, setting := range []struct{ key, git string }{{"Name", "user.name"}, {"Email", "user.email"}} {
+		key := setting.key
+		var value string
+		if hasCommand("git") {
+			out, _ := gitOutput(".", "config", "--global", setting.git)
+			value = strings.TrimSpace(out)
+		}
+		if value == "" && ask && canPrompt() {
+			value, _ = readLine(fmt.Sprintf("Your %s (for the template):", strings.ToLower(key)))
+		}
+		if value != "" {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// renderTemplate renders text, from the file name under root, with values
+// and the template functions
+func renderTemplate(root, name, text string, values map[string]any) (string, error) {
+	const hint = "Placeholders are {{.Username}}, {{.Name}}, {{.Email}}, {{.Hostname}}, {{.Home}}, and {{.Vars.NAME}}; " +
+		"set a missing name or email with 'git config --global user.name' or 'user.email', " +
+		"and variables in .lnk/data.yaml, with 'lnk vars set', or by answering at a terminal"
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(templateFuncs(root)).Parse(text)
+	if err != nil {
+		return "", NewPathErrorWithHint("parse template", name, err, hint)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return "", NewPathErrorWithHint("render template", name, err, hint)
+	}
+	return buf.String(), nil
+}
+
+// readTemplateTexts reads the .tmpl files among files, named by their path
+// relative to root
+func readTemplateTexts(root string, files []string) ([]templateText, error) {
+	var texts []templateText
+	for _, rel := range files {
+		if !strings.HasSuffix(rel, templateSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, rel))
+		if err != nil {
+			return nil, NewPathError("read", rel, err)
+		}
+		texts = append(texts, templateText{Name: rel, Text: string(data)})
+	}
+	return texts, nil
+}
+
+// renderTemplateFiles renders the .tmpl files among texts, under root, and
+// returns their content by relative path; mappings are rendered on their own
+func renderTemplateFiles(root string, texts []templateText, values map[string]any) (map[string][]byte, error) {
+	rendered := make(map[string][]byte)
+	for _, t := range texts {
+		if !strings.HasSuffix(t.Name, templateSuffix) {
+			continue
+		}
+		text, err := renderTemplate(root, t.Name, t.Text, values)
+		if err != nil {
+			return nil, err
+		}
+		rendered[t.Name] = []byte(text)
+	}
+	return rendered, nil
+}
+
+// writeTemplateFiles copies files from root into sourceDir, writing rendered
+// templates without their .tmpl suffix, with the template file's permissions
+func writeTemplateFiles(root, sourceDir string, files []string, rendered map[string][]byte) error {
+	lines := newPathLines(len(files), "Copied", PrintSuccess)
+	for _, rel := range files {
+		src := filepath.Join(root, rel)
+		info, err := os.Lstat(src)
+		if err != nil {
+			return NewPathError("read", rel, err)
+		}
+		dst := filepath.Join(sourceDir, rel)
+		if _, ok := rendered[rel]; ok {
+			dst = strings.TrimSuffix(dst, templateSuffix)
+		}
+		if err := fsys.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return NewPathError("create directory", filepath.Dir(dst), err)
+		}
+		switch data, ok := rendered[rel]; {
+		case ok:
+			if err := writeFileAtomic(dst, data, info.Mode().Perm()); err != nil {
+				return NewPathError("write", dst, err)
+			}
+			lines.Add(dst, "Rendered: %s", ContractPath(dst))
+		case info.Mode()&fs.ModeSymlink != 0:
+			dest, err := os.Readlink(src)
+			if err != nil {
+				return NewPathError("read", rel, err)
+			}
+			if err := fsys.Symlink(dest, dst); err != nil {
+				return NewPathError("create symlink", dst, err)
+			}
+			lines.Add(dst, "Copied: %s", ContractPath(dst))
+		default:
+			if err := copyFile(src, dst); err != nil {
+				return NewPathError("copy", dst, err)
+			}
+			if err := fsys.Chmod(dst, info.Mode().Perm()); err != nil {
+				return NewPathError("chmod", dst, err)
+			}
+			lines.Add(dst, "Copied: %s", ContractPath(dst))
+		}
+	}
+	lines.Flush()
+	return nil
+}
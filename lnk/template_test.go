@@ -0,0 +1,220 @@
+package lnk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplateRepo(t *testing.T) {
+	tests := []struct {
+		spec string
+		want templateRepo
+	}{
+		{"owner/dots", templateRepo{Owner: "owner", Repo: "dots"}},
+		{"https://github.com/owner/dots.git", templateRepo{Owner: "owner", Repo: "dots"}},
+		{"github.com/owner/dots/minimal/base#v2", templateRepo{Owner: "owner", Repo: "dots", Subdir: filepath.Join("minimal", "base"), Ref: "v2"}},
+	}
+	for _, tt := range tests {
+		got, err := parseTemplateRepo(tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("parseTemplateRepo(%q) = %+v, %v; want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+	for _, spec := range []string{"owner", "owner/", "owner/dots/../../etc"} {
+		if _, err := parseTemplateRepo(spec); err == nil {
+			t.Errorf("parseTemplateRepo(%q) should fail", spec)
+		}
+	}
+}
+
+// useTestTemplate makes fetchTemplate write a template instead of cloning, and
+// answers the name and email prompts
+func useTestTemplate(t *testing.T, files map[string]string) *[]string {
+	t.Helper()
+	var fetched []string
+	origFetch, origHas, origCanPrompt, origReader := fetchTemplate, hasCommand, canPrompt, promptReader
+	fetchTemplate = func(url, ref, dir string) error {
+		fetched = append(fetched, url+"#"+ref)
+		for name, content := range files {
+			createTestFile(t, filepath.Join(dir, name), content)
+		}
+		return nil
+	}
+	hasCommand = func(string) bool { return false }
+	canPrompt = func() bool { return true }
+	promptReader = bufio.NewReader(strings.NewReader("Ada Lovelace\nada@example.com\n"))
+	t.Cleanup(func() {
+		fetchTemplate, hasCommand, canPrompt, promptReader = origFetch, origHas, origCanPrompt, origReader
+	})
+	return &fetched
+}
+
+func TestInitFromTemplate(t *testing.T) {
+	fetched := useTestTemplate(t, map[string]string{
+		".git/HEAD":                 "ref: refs/heads/main",
+		"shell/.bashrc":             "# bashrc",
+		"git/.gitconfig.tmpl":       "[user]\n\tname = {{.Name}}\n\temail = {{.Email}}\n",
+		TemplateConfigFileName:      `{"mappings": ["fonts:.local/share/fonts/"], "packages": ["shell", "git"]}`,
+		"fonts/Hack-Regular.ttf":    "font",
+		"shell/.config/README.tmpl": "for {{.Username}}",
+	})
+	sourceDir := filepath.Join(t.TempDir(), "dotfiles")
+	opts := InitOptions{SourceDir: sourceDir, Template: "owner/dots#main"}
+
+	t.Run("dry run", func(t *testing.T) {
+		opts := opts
+		opts.DryRun = true
+		output := CaptureOutput(t, func() {
+			if err := InitFromTemplate(opts); err != nil {
+				t.Fatalf("InitFromTemplate() error = %v", err)
+			}
+		})
+		ContainsOutput(t, output, "Would copy 4 file(s) from owner/dots#main", "Would render: "+filepath.Join("git", ".gitconfig"),
+			"Would save mapping: fonts:.local/share/fonts/", "Would write .lnkpackages: shell, git")
+		assertNotExists(t, sourceDir)
+	})
+
+	promptReader = bufio.NewReader(strings.NewReader("Ada Lovelace\nada@example.com\n"))
+	CaptureOutput(t, func() {
+		if err := InitFromTemplate(opts); err != nil {
+			t.Fatalf("InitFromTemplate() error = %v", err)
+		}
+	})
+	if got := (*fetched)[len(*fetched)-1]; got != "https://github.com/owner/dots.git#main" {
+		t.Errorf("fetched %q", got)
+	}
+	data, err := os.ReadFile(filepath.Join(sourceDir, "git", ".gitconfig"))
+	if err != nil || string(data) != "[user]\n\tname = Ada Lovelace\n\temail = ada@example.com\n" {
+		t.Errorf(".gitconfig = %q, %v; want the name and email filled in", data, err)
+	}
+	for _, name := range []string{".git", TemplateConfigFileName, filepath.Join("git", ".gitconfig.tmpl")} {
+		assertNotExists(t, filepath.Join(sourceDir, name))
+	}
+	if _, err := os.Stat(filepath.Join(sourceDir, "fonts", "Hack-Regular.ttf")); err != nil {
+		t.Errorf("font not copied: %v", err)
+	}
+	maps, err := LoadMapsFile(sourceDir)
+	if err != nil || len(maps) != 1 || maps[0].Source != "fonts" {
+		t.Errorf("LoadMapsFile() = %v, %v; want the template's mapping", maps, err)
+	}
+	packages, err := LoadPackagesFile(sourceDir)
+	if err != nil || strings.Join(packages, ",") != "shell,git" {
+		t.Errorf("LoadPackagesFile() = %v, %v; want shell,git", packages, err)
+	}
+
+	t.Run("not empty", func(t *testing.T) {
+		before := len(*fetched)
+		if err := InitFromTemplate(opts); err == nil {
+			t.Error("InitFromTemplate() into a non-empty directory should fail")
+		}
+		if len(*fetched) != before {
+			t.Error("fetched the template before checking the directory")
+		}
+	})
+}
+
+func TestInitFromTemplateMissingValue(t *testing.T) {
+	useTestTemplate(t, map[string]string{"git/.gitconfig.tmpl": "email = {{.Email}}\n", "shell/.bashrc": "# bashrc"})
+	canPrompt = func() bool { return false }
+	sourceDir := filepath.Join(t.TempDir(), "dotfiles")
+
+	var err error
+	CaptureOutput(t, func() { err = InitFromTemplate(InitOptions{SourceDir: sourceDir, Template: "owner/dots"}) })
+	if err == nil || !strings.Contains(GetErrorHint(err), "git config --global") {
+		t.Errorf("InitFromTemplate() error = %v, want a render error with a git config hint", err)
+	}
+	assertNotExists(t, sourceDir)
+}
+
+func TestInitFromTemplateVars(t *testing.T) {
+	useTestTemplate(t, map[string]string{
+		".lnk/data.yaml":           "proxy: http://proxy:3128\n",
+		".lnk/data.d/work-pc.yaml": "git:\n  email: ada@work.example\n",
+		"git/.gitconfig.tmpl":      "[user]\n\temail = {{.Vars.git.email}}\n",
+		"shell/.profile.tmpl":      "export http_proxy={{.Vars.proxy}}\n",
+		"shell/.config/theme.tmpl": "{{.Vars.theme}}",
+	})
+	orig := machineHostname
+	machineHostname = func() (string, error) { return "work-pc", nil }
+	t.Cleanup(func() { machineHostname = orig })
+	sourceDir := filepath.Join(t.TempDir(), "dotfiles")
+
+	// Without a terminal, a variable no data file sets fails before anything
+	// is written
+	canPrompt = func() bool { return false }
+	var err error
+	CaptureOutput(t, func() { err = InitFromTemplate(InitOptions{SourceDir: sourceDir, Template: "owner/dots"}) })
+	if err == nil || !strings.Contains(err.Error(), "theme") {
+		t.Errorf("InitFromTemplate() error = %v, want the missing theme variable named", err)
+	}
+	assertNotExists(t, sourceDir)
+
+	canPrompt = func() bool { return true }
+	fetchTemplate = func(url, ref, dir string) error {
+		createTestFile(t, filepath.Join(dir, ".lnk", "data.yaml"), "proxy: http://proxy:3128\ngit:\n  email: ada@home.example\n")
+		createTestFile(t, filepath.Join(dir, ".lnk", "data.d", "work-pc.yaml"), "git:\n  email: ada@work.example\n")
+		createTestFile(t, filepath.Join(dir, "git", ".gitconfig.tmpl"), "[user]\n\temail = {{.Vars.git.email}}\n")
+		createTestFile(t, filepath.Join(dir, "shell", ".profile.tmpl"), "export http_proxy={{.Vars.proxy}}\n")
+		return nil
+	}
+	CaptureOutput(t, func() {
+		if err := InitFromTemplate(InitOptions{SourceDir: sourceDir, Template: "owner/dots"}); err != nil {
+			t.Fatalf("InitFromTemplate() error = %v", err)
+		}
+	})
+	for path, want := range map[string]string{
+		filepath.Join("git", ".gitconfig"): "[user]\n\temail = ada@work.example\n",
+		filepath.Join("shell", ".profile"): "export http_proxy=http://proxy:3128\n",
+		filepath.Join(".lnk", "data.yaml"): "proxy: http://proxy:3128\ngit:\n  email: ada@home.example\n",
+	} {
+		if data, err := os.ReadFile(filepath.Join(sourceDir, path)); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", path, data, err, want)
+		}
+	}
+}
+
+func TestInitFromTemplateAsksForVars(t *testing.T) {
+	useTestTemplate(t, map[string]string{
+		"git/.gitconfig.tmpl":  "[user]\n\tname = {{.Name}}\n\temail = {{.Vars.work_email}}\n",
+		"shell/.netrc.tmpl":    "password {{.Vars.api_token}}\n",
+		"shell/.bashrc.tmpl":   "# {{.Vars.work_email}}\n",
+		TemplateConfigFileName: `{"mappings": ["fonts:{{.Vars.font_dir}}/"]}`,
+		"fonts/Hack.ttf":       "font",
+	})
+	promptReader = bufio.NewReader(strings.NewReader("Ada Lovelace\nada@example.com\nada@work.example\ns3cr3t\n.fonts\n"))
+	sourceDir := filepath.Join(t.TempDir(), "dotfiles")
+
+	var stderr string
+	_, stderr = captureOutput(t, func() {
+		if err := InitFromTemplate(InitOptions{SourceDir: sourceDir, Template: "owner/dots"}); err != nil {
+			t.Fatalf("InitFromTemplate() error = %v", err)
+		}
+	})
+	// Each variable is asked for once, naming the first file that uses it
+	for _, q := range []string{"Value for work_email (used by " + filepath.Join("git", ".gitconfig.tmpl") + "):",
+		"Value for api_token (used by " + filepath.Join("shell", ".netrc.tmpl") + "):",
+		"Value for font_dir (used by " + TemplateConfigFileName + "):"} {
+		if strings.Count(stderr, q) != 1 {
+			t.Errorf("asked %q %d time(s), want once:\n%s", q, strings.Count(stderr, q), stderr)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(sourceDir, "shell", ".netrc")); string(data) != "password s3cr3t\n" {
+		t.Errorf(".netrc = %q", data)
+	}
+	answers, err := loadTemplateAnswers(sourceDir)
+	want := map[string]string{"work_email": "ada@work.example", "api_token": "s3cr3t", "font_dir": ".fonts"}
+	if err != nil || len(answers) != len(want) {
+		t.Fatalf("saved answers = %v, %v; want %v", answers, err, want)
+	}
+	for name, value := range want {
+		if answers[name] != value {
+			t.Errorf("answer %s = %q, want %q", name, answers[name], value)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(sourceDir, ".lnk", ".gitignore")); string(data) != "answers.yaml\n" {
+		t.Errorf(".lnk/.gitignore = %q, want the answers kept out of git", data)
+	}
+}
@@ -0,0 +1,169 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Templates can call a small set of functions besides their values. Those
+// that read outside the template, the environment and password managers,
+// are off by default: init writes rendered files into a repository that is
+// usually pushed somewhere. LNK_TEMPLATE_FUNCS turns functions on or off.
+
+// templateFuncNames lists the template functions, in the order help lists them
+var templateFuncNames = []string{"hostname", "lookPath", "readFile", "toJSON", "toYAML", "indent", "env", "onePassword", "pass"}
+
+// templateFuncsOff lists the functions that are off unless LNK_TEMPLATE_FUNCS
+// turns them on
+var templateFuncsOff = []string{"env", "onePassword", "pass"}
+
+// enabledTemplateFuncs holds the functions templates may call; nil means the
+// defaults
+var enabledTemplateFuncs map[string]bool
+
+// SetTemplateFuncs sets the functions templates may call, as returned by
+// ParseTemplateFuncs
+func SetTemplateFuncs(enabled map[string]bool) {
+	enabledTemplateFuncs = enabled
+}
+
+// ParseTemplateFuncs applies a LNK_TEMPLATE_FUNCS list to the defaults: a
+// name turns that function on, and a name starting with - turns it off.
+// Unknown names are a ValidationError.
+func ParseTemplateFuncs(list []string) (map[string]bool, error) {
+	enabled := make(map[string]bool, len(templateFuncNames))
+	for _, name := range templateFuncNames {
+		enabled[name] = !slices.Contains(templateFuncsOff, name)
+	}
+	for _, item := range list {
+		name, off := strings.CutPrefix(item, "-")
+		if !slices.Contains(templateFuncNames, name) {
+			hint := fmt.Sprintf("Template functions: %s", strings.Join(templateFuncNames, ", "))
+			if match := ClosestMatch(name, templateFuncNames, 2); match != "" {
+				hint = fmt.Sprintf("Did you mean %s?", match)
+			}
+			return nil, NewValidationErrorWithHint(EnvTemplateFuncs, item, "unknown template function", hint)
+		}
+		enabled[name] = !off
+	}
+	return enabled, nil
+}
+
+// runSecretCommand runs a password manager for onePassword and pass; tests
+// replace it
+var runSecretCommand = func(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return "", fmt.Errorf("%s: %s", name, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return string(out), err
+}
+
+// templateFuncs returns the functions for templates under root, where
+// readFile reads. A function that is off fails when called, naming the
+// setting that turns it on, so templates using it still parse.
+func templateFuncs(root string) template.FuncMap {
+	funcs := template.FuncMap{
+		"hostname": machineHostname,
+		"lookPath": func(name string) string {
+			path, err := exec.LookPath(name)
+			if err != nil {
+				return ""
+			}
+			return path
+		},
+		"readFile": func(name string) (string, error) {
+			return readTemplateFile(root, name)
+		},
+		"toJSON": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"toYAML": func(v any) string {
+			var sb strings.Builder
+			writeYAML(&sb, v)
+			return strings.TrimSuffix(sb.String(), "\n")
+		},
+		"indent": func(n int, text string) string {
+			pad := strings.Repeat(" ", n)
+			lines := strings.Split(text, "\n")
+			for i, line := range lines {
+				if line != "" {
+					lines[i] = pad + line
+				}
+			}
+			return strings.Join(lines, "\n")
+		},
+		"env": os.Getenv,
+		"onePassword": func(ref string) (string, error) {
+			return runPasswordManager("op", "read", ref)
+		},
+		"pass": func(name string) (string, error) {
+			out, err := runPasswordManager("pass", "show", name)
+			first, _, _ := strings.Cut(out, "\n")
+			return first, err
+		},
+	}
+	for name := range funcs {
+		if templateFuncEnabled(name) {
+			continue
+		}
+		name := name
+		funcs[name] = func(...any) (string, error) {
+			return "", fmt.Errorf("template function %s is off; set %s=%s to turn it on", name, EnvTemplateFuncs, name)
+		}
+	}
+	return funcs
+}
+
+// templateFuncEnabled reports whether templates may call the function name
+func templateFuncEnabled(name string) bool {
+	if enabledTemplateFuncs == nil {
+		return !slices.Contains(templateFuncsOff, name)
+	}
+	return enabledTemplateFuncs[name]
+}
+
+// runPasswordManager runs a password manager command and returns its output
+// without the final newline
+func runPasswordManager(name string, args ...string) (string, error) {
+	if !hasCommand(name) {
+		return "", fmt.Errorf("%s is not installed", name)
+	}
+	out, err := runSecretCommand(name, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+// readTemplateFile reads name, relative to root, for readFile. Paths leaving
+// root, directly or through a symlink, are refused, so a template reads only
+// files that come with it.
+func readTemplateFile(root, name string) (string, error) {
+	if root == "" || filepath.IsAbs(name) || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("readFile %s: path must be relative and inside the template", name)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("readFile %s: %w", name, err)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, name))
+	if err != nil {
+		return "", fmt.Errorf("readFile %s: %w", name, err)
+	}
+	if !isWithin(path, realRoot) {
+		return "", fmt.Errorf("readFile %s: path must be relative and inside the template", name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("readFile %s: %w", name, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,87 @@
+package lnk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	root := t.TempDir()
+	createTestFile(t, filepath.Join(root, "snippets", "aliases"), "alias ll='ls -l'\n")
+	outside := filepath.Join(t.TempDir(), "secret")
+	createTestFile(t, outside, "secret")
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	origHost, origHas, origRun := machineHostname, hasCommand, runSecretCommand
+	machineHostname = func() (string, error) { return "laptop", nil }
+	hasCommand = func(name string) bool { return name == "pass" }
+	runSecretCommand = func(name string, args ...string) (string, error) {
+		return "hunter2\nlogin: ada\n", nil
+	}
+	t.Cleanup(func() {
+		machineHostname, hasCommand, runSecretCommand = origHost, origHas, origRun
+		SetTemplateFuncs(nil)
+	})
+	t.Setenv("LNK_TEST_EDITOR", "vim")
+	values := map[string]any{"Vars": map[string]any{"git": map[string]any{"email": "ada@example.com", "sign": true}, "tags": []any{"a", "b"}}}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr string
+	}{
+		{"hostname", `{{hostname}}`, "laptop", ""},
+		{"lookPath missing", `{{if lookPath "lnk-no-such-command"}}yes{{else}}no{{end}}`, "no", ""},
+		{"readFile", `{{readFile "snippets/aliases"}}`, "alias ll='ls -l'\n", ""},
+		{"readFile outside", `{{readFile "../secret"}}`, "", "inside the template"},
+		{"readFile through a symlink", `{{readFile "escape"}}`, "", "inside the template"},
+		{"toJSON", `{{toJSON .Vars.tags}}`, `["a","b"]`, ""},
+		{"toYAML and indent", "git:\n{{toYAML .Vars.git | indent 2}}", "git:\n  email: \"ada@example.com\"\n  sign: true", ""},
+		{"env is off", `{{env "LNK_TEST_EDITOR"}}`, "", "LNK_TEMPLATE_FUNCS=env"},
+		{"pass is off", `{{pass "mail"}}`, "", "LNK_TEMPLATE_FUNCS=pass"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderTemplate(root, "test.tmpl", tt.text, values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("renderTemplate() = %q, %v; want an error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("renderTemplate() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	// LNK_TEMPLATE_FUNCS turns functions on and off
+	enabled, err := ParseTemplateFuncs([]string{"env", "pass", "-readFile"})
+	if err != nil {
+		t.Fatalf("ParseTemplateFuncs() error = %v", err)
+	}
+	SetTemplateFuncs(enabled)
+	if got, err := renderTemplate(root, "test.tmpl", `{{env "LNK_TEST_EDITOR"}} {{pass "mail"}}`, values); err != nil || got != "vim hunter2" {
+		t.Errorf("renderTemplate() with env and pass on = %q, %v; want %q", got, err, "vim hunter2")
+	}
+	if _, err := renderTemplate(root, "test.tmpl", `{{readFile "snippets/aliases"}}`, values); err == nil {
+		t.Error("renderTemplate() should refuse readFile once it is off")
+	}
+}
+
+func TestParseTemplateFuncs(t *testing.T) {
+	enabled, err := ParseTemplateFuncs(nil)
+	if err != nil || !enabled["readFile"] || enabled["env"] || enabled["onePassword"] {
+		t.Errorf("ParseTemplateFuncs(nil) = %v, %v; want secrets and env off", enabled, err)
+	}
+	_, err = ParseTemplateFuncs([]string{"-lookpath"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !strings.Contains(GetErrorHint(err), "lookPath") {
+		t.Errorf("ParseTemplateFuncs(-lookpath) = %v, want a ValidationError suggesting lookPath", err)
+	}
+}
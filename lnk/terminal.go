@@ -8,6 +8,11 @@ import (
 // This implementation uses a simple and portable approach that works
 // across Unix-like systems without relying on platform-specific syscalls.
 func isTerminal() bool {
+	// Output sent through the pager still ends up on the terminal
+	if pager != nil {
+		return true
+	}
+
 	// Check stdout's file info
 	fi, err := os.Stdout.Stat()
 	if err != nil {
@@ -23,3 +28,12 @@ func isTerminal() bool {
 func ShouldSimplifyOutput() bool {
 	return !isTerminal()
 }
+
+// isInputTerminal returns true if stdin is a terminal.
+func isInputTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return (fi.Mode() & os.ModeCharDevice) != 0
+}
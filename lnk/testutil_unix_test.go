@@ -0,0 +1,28 @@
+//go:build unix
+
+package lnk
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+// createTestFIFO creates a named pipe at path
+func createTestFIFO(t *testing.T, path string) {
+	t.Helper()
+	if err := syscall.Mkfifo(path, 0644); err != nil {
+		t.Fatalf("Failed to create FIFO %s: %v", path, err)
+	}
+}
+
+// createTestSocket creates a listening Unix domain socket at path; it is
+// closed (and removed) when the test ends
+func createTestSocket(t *testing.T, path string) {
+	t.Helper()
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to create socket %s: %v", path, err)
+	}
+	t.Cleanup(func() { l.Close() })
+}
@@ -0,0 +1,75 @@
+package lnk
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trace events record what lnk did and how long it took: phase timings,
+// per-mapping counts, and which configuration source won. They are printed in
+// verbose mode and, when a log file is open, appended to it as JSON lines.
+
+// traceLogger writes trace events to the log file; nil when there is none
+var traceLogger *slog.Logger
+
+// OpenLogFile appends trace events to path as JSON lines until the returned
+// closer is closed. Events are logged whether or not verbose mode is on.
+func OpenLogFile(path string) (io.Closer, error) {
+	expanded, err := ExpandPath(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fsys.OpenFile(expanded, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, NewPathErrorWithHint("open log file", expanded, err,
+			"Check that the directory exists and is writable")
+	}
+	traceLogger = slog.New(slog.NewJSONHandler(f, nil))
+	return closerFunc(func() error {
+		traceLogger = nil
+		return f.Close()
+	}), nil
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// Trace records an event with key-value attributes, like slog: Trace("mapping",
+// "source", "~/dotfiles/shell", "links", 12)
+func Trace(event string, args ...any) {
+	if traceLogger != nil {
+		traceLogger.Info(event, args...)
+	}
+	if !IsVerbose() {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(event)
+	for i := 0; i+1 < len(args); i += 2 {
+		value := fmt.Sprint(args[i+1])
+		if value == "" || strings.ContainsAny(value, " \t\"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&sb, " %v=%s", args[i], value)
+	}
+	PrintVerbose("%s", sb.String())
+}
+
+// TracePhase starts timing a phase of a command. Calling the returned function
+// ends the phase and records a "phase" event with its duration in milliseconds
+// and any extra attributes.
+func TracePhase(name string) func(args ...any) {
+	start := time.Now()
+	return func(args ...any) {
+		elapsed := time.Since(start)
+		profilePhase(name, elapsed)
+		ms := float64(elapsed.Microseconds()) / 1000
+		Trace("phase", append([]any{"name", name, "duration_ms", ms}, args...)...)
+	}
+}
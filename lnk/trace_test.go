@@ -0,0 +1,85 @@
+package lnk
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTraceVerboseFormat(t *testing.T) {
+	SetVerbosity(VerbosityVerbose)
+	t.Cleanup(func() { SetVerbosity(VerbosityNormal) })
+
+	output := CaptureOutput(t, func() {
+		Trace("mapping", "source", "~/dotfiles/shell", "links", 3, "note", "has spaces", "empty", "")
+	})
+	want := `[VERBOSE] mapping source=~/dotfiles/shell links=3 note="has spaces" empty=""`
+	if strings.TrimSpace(output) != want {
+		t.Errorf("Trace() output = %q, want %q", output, want)
+	}
+
+	SetVerbosity(VerbosityNormal)
+	if output := CaptureOutput(t, func() { Trace("mapping", "links", 3) }); output != "" {
+		t.Errorf("Trace() printed without verbose mode: %q", output)
+	}
+}
+
+func TestTraceLogFile(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "lnk.log")
+	closer, err := OpenLogFile(logPath)
+	if err != nil {
+		t.Fatalf("OpenLogFile() error = %v", err)
+	}
+
+	sourceDir, targetDir := setupPackagesTest(t)
+	CaptureOutput(t, func() {
+		_, err := CreateLinks(LinkOptions{
+			SourceDir: sourceDir,
+			TargetDir: targetDir,
+			Packages:  []string{"shell", "nvim"},
+		})
+		if err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if traceLogger != nil {
+		t.Error("traceLogger still set after Close")
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var phases, mappings []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("log line is not JSON: %q", line)
+		}
+		switch event["msg"] {
+		case "phase":
+			phases = append(phases, event)
+		case "mapping":
+			mappings = append(mappings, event)
+		}
+	}
+
+	var names []string
+	for _, p := range phases {
+		names = append(names, p["name"].(string))
+		if _, ok := p["duration_ms"].(float64); !ok {
+			t.Errorf("phase %v has no duration_ms", p["name"])
+		}
+	}
+	if strings.Join(names, ",") != "plan,validate,execute" {
+		t.Errorf("phases = %v, want plan,validate,execute", names)
+	}
+	if len(mappings) != 2 || mappings[0]["links"] != float64(1) {
+		t.Errorf("mappings = %v, want one event per package", mappings)
+	}
+}
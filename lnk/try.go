@@ -0,0 +1,139 @@
+package lnk
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// TryMapping plans m as if it were passed to create with --map, alongside the
+// packages and saved mappings in opts, and shows what would be linked and what
+// is in the way without changing anything. It then offers to save m to
+// .lnkmaps, so later runs of create, status, and remove use it.
+func TryMapping(opts LinkOptions, m Mapping) error {
+	PrintCommandHeader("Trying Mapping")
+	m = portableMapping(m)
+
+	paths, err := resolvePathsIn(opts.Home, opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	sourceDir, targetDir := paths.SourceDir, paths.TargetDir
+
+	if slices.ContainsFunc(opts.Maps, m.Same) {
+		PrintInfo("%s is already saved in %s", m, MapsFileName)
+		PrintNextStep("status", sourceDir, "see its links")
+		return nil
+	}
+
+	// Plan everything create would, so the mapping is checked against it
+	packages, err := expandPackageDeps(sourceDir, opts.Packages)
+	if err != nil {
+		return err
+	}
+	pkgDirs, err := packageDirs(sourceDir, packages)
+	if err != nil {
+		return err
+	}
+	saved, err := resolveMappings(opts.Maps, opts.Home, sourceDir, targetDir)
+	if err != nil {
+		return err
+	}
+	tried, err := resolveMappings([]Mapping{m}, opts.Home, sourceDir, targetDir)
+	if err != nil {
+		return err
+	}
+	planned, _, _, err := collectPackageLinks(pkgDirs, targetDir, opts.IgnorePatterns)
+	if err != nil {
+		return fmt.Errorf("collecting files to link: %w", err)
+	}
+	savedLinks, _, _, err := collectMappedLinks(saved, opts.IgnorePatterns, planned)
+	if err != nil {
+		return fmt.Errorf("collecting files to link: %w", err)
+	}
+	links, specials, plans, err := collectMappedLinks(tried, opts.IgnorePatterns, append(planned, savedLinks...))
+	if err != nil {
+		return err
+	}
+	links, localLinks := filterLocalOnly(links, targetDir, opts.LocalOnly)
+	for _, link := range localLinks {
+		PrintSkip("Local-only: %s", ContractPath(link.Target))
+	}
+	if err := applySpecialFilePolicy(specials, opts.SpecialFiles); err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		PrintEmptyResult("files to link")
+		return nil
+	}
+	for _, link := range links {
+		if err := ValidateSymlinkCreation(link.Source, link.Target); err != nil {
+			return fmt.Errorf("validation failed for %s -> %s: %w", link.Target, link.Source, err)
+		}
+	}
+
+	lines := newPathLines(len(links), "Would link", PrintDryRun)
+	for _, link := range links {
+		switch plannedLinkState(link) {
+		case stateToLink:
+			lines.Add(link.Target, "Would link: %s -> %s", ContractPath(link.Target), ContractPath(link.Source))
+		case stateLinked:
+			PrintSkip("Already linked: %s", ContractPath(link.Target))
+		default:
+			PrintWarning("Conflict: %s %s", ContractPath(link.Target), describeObstacle(link.Target))
+		}
+	}
+	lines.Flush()
+	printMappingPlans(plans, links)
+	fmt.Println()
+
+	if opts.DryRun {
+		PrintDryRunSummary()
+		return nil
+	}
+	if !confirm(fmt.Sprintf("Save %s to %s? [y/N]", m, MapsFileName)) {
+		PrintInfo("Not saved. To link it for one run: lnk create --map %s %s", m, ContractPath(sourceDir))
+		return nil
+	}
+	if err := SaveMapping(sourceDir, m); err != nil {
+		return err
+	}
+	PrintSuccess("Saved %s to %s", m, ContractPath(filepath.Join(sourceDir, MapsFileName)))
+	PrintNextStep("create", sourceDir, "link it")
+	return nil
+}
+
+// portableMapping writes absolute paths under the home directory as ~/..., so
+// a saved mapping keeps working when the home directory moves. A trailing
+// slash, which selects merge_into, is kept.
+func portableMapping(m Mapping) Mapping {
+	for _, path := range []*string{&m.Source, &m.Target} {
+		if !filepath.IsAbs(*path) {
+			continue
+		}
+		slash := strings.HasSuffix(*path, "/")
+		*path = contractHome(*path)
+		if slash && !strings.HasSuffix(*path, "/") {
+			*path += "/"
+		}
+	}
+	return m
+}
+
+// describeObstacle says what is at path in the way of a link
+func describeObstacle(path string) string {
+	info, err := fsys.Lstat(path)
+	switch {
+	case err != nil:
+		return "cannot be checked"
+	case info.IsDir():
+		return "is a directory"
+	case info.Mode()&fs.ModeSymlink != 0:
+		dest, _ := fsys.Readlink(path)
+		return "links to " + ContractPath(dest)
+	default:
+		return "is a file"
+	}
+}
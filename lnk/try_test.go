@@ -0,0 +1,64 @@
+package lnk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTryMapping(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	workDir := filepath.Join(filepath.Dir(sourceDir), "work")
+	createTestFile(t, filepath.Join(workDir, "a.toml"), "a")
+	createTestFile(t, filepath.Join(workDir, "b.toml"), "b")
+	createTestFile(t, filepath.Join(targetDir, ".config", "work", "b.toml"), "mine")
+
+	origCanPrompt, origReader := canPrompt, promptReader
+	t.Cleanup(func() { canPrompt, promptReader = origCanPrompt, origReader })
+	canPrompt = func() bool { return false }
+
+	m := Mapping{Source: workDir, Target: filepath.Join(targetDir, ".config", "work") + "/"}
+	opts := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir, Packages: []string{"shell"}}
+	output, stderr := captureOutput(t, func() {
+		if err := TryMapping(opts, m); err != nil {
+			t.Fatalf("TryMapping() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "Would link: "+filepath.Join(targetDir, ".config", "work", "a.toml"), "Not saved")
+	ContainsOutput(t, stderr, "Conflict: "+filepath.Join(targetDir, ".config", "work", "b.toml")+" is a file")
+	assertNotExists(t, filepath.Join(targetDir, ".config", "work", "a.toml"))
+	assertNotExists(t, filepath.Join(sourceDir, MapsFileName))
+
+	// Answering yes saves the mapping for later runs
+	canPrompt = func() bool { return true }
+	promptReader = bufio.NewReader(strings.NewReader("y\n"))
+	captureOutput(t, func() {
+		if err := TryMapping(opts, m); err != nil {
+			t.Fatalf("TryMapping() error = %v", err)
+		}
+	})
+	saved, err := LoadMapsFile(sourceDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || !saved[0].Same(m) {
+		t.Fatalf("LoadMapsFile() = %+v, want [%+v]", saved, m)
+	}
+
+	opts.Maps = saved
+	output = CaptureOutput(t, func() {
+		if err := TryMapping(opts, m); err != nil {
+			t.Fatalf("TryMapping() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "already saved")
+
+	if err := os.WriteFile(filepath.Join(sourceDir, MapsFileName), []byte("no-colon\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMapsFile(sourceDir); err == nil || !strings.Contains(GetErrorHint(err), "SRC:TGT") {
+		t.Errorf("LoadMapsFile() with a bad line error = %v, want one naming the format", err)
+	}
+}
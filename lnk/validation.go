@@ -10,7 +10,7 @@ import (
 // ValidateNoCircularSymlink checks if creating a symlink would create a circular reference
 func ValidateNoCircularSymlink(source, target string) error {
 	// Check if target is already a symlink that points back to source
-	targetInfo, err := os.Lstat(target)
+	targetInfo, err := fsys.Lstat(target)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Target doesn't exist yet, no circular link possible
@@ -21,7 +21,7 @@ func ValidateNoCircularSymlink(source, target string) error {
 
 	// If target is a symlink, check where it points
 	if targetInfo.Mode()&os.ModeSymlink != 0 {
-		linkDest, err := os.Readlink(target)
+		linkDest, err := fsys.Readlink(target)
 		if err != nil {
 			return fmt.Errorf("failed to read symlink: %w", err)
 		}
@@ -42,7 +42,7 @@ func ValidateNoCircularSymlink(source, target string) error {
 		}
 
 		// Check if the symlink points to our source - this is OK, not circular
-		if absSource == absLinkDest {
+		if pathComparer.SamePath(absSource, absLinkDest) {
 			return nil // Already points to correct location
 		}
 	}
@@ -104,6 +104,13 @@ func ValidateNoOverlappingPaths(source, target string) error {
 
 // ValidateSymlinkCreation performs all validation checks before creating a symlink
 func ValidateSymlinkCreation(source, target string) error {
+	// Check that existing symlink chains at either end can be followed
+	if err := ValidateSymlinkChain(source); err != nil {
+		return err
+	}
+	if err := ValidateSymlinkChain(target); err != nil {
+		return err
+	}
 	// Check for circular symlinks
 	if err := ValidateNoCircularSymlink(source, target); err != nil {
 		return err
@@ -121,20 +128,25 @@ type ResolvedPaths struct {
 // ResolvePaths expands and validates source and target directories.
 // Returns error if source directory doesn't exist or isn't a directory.
 func ResolvePaths(sourceDir, targetDir string) (*ResolvedPaths, error) {
+	return resolvePathsIn("", sourceDir, targetDir)
+}
+
+// resolvePathsIn is ResolvePaths with ~ expanded to home (see expandPathIn)
+func resolvePathsIn(home, sourceDir, targetDir string) (*ResolvedPaths, error) {
 	// Expand source path
-	absSource, err := ExpandPath(sourceDir)
+	absSource, err := expandPathIn(home, sourceDir)
 	if err != nil {
 		return nil, fmt.Errorf("expanding source directory %s: %w", sourceDir, err)
 	}
 
 	// Expand target path
-	absTarget, err := ExpandPath(targetDir)
+	absTarget, err := expandPathIn(home, targetDir)
 	if err != nil {
 		return nil, fmt.Errorf("expanding target directory %s: %w", targetDir, err)
 	}
 
 	// Validate source directory exists and is a directory
-	if info, err := os.Stat(absSource); err != nil {
+	if info, err := fsys.Stat(absSource); err != nil {
 		if os.IsNotExist(err) {
 			return nil, NewValidationErrorWithHint("source directory", absSource,
 				"directory does not exist",
@@ -0,0 +1,697 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Template variables let a template hold {{.Vars.NAME}} where a value differs
+// between people or machines. They come from .lnk/data.yaml, overlaid by
+// .lnk/data.d/<hostname>.yaml for the machine lnk runs on, so host-specific
+// values such as a work email or a proxy live beside the templates rather
+// than in them. A variable no file sets is asked for once at the terminal and
+// the answer kept in .lnk/answers.yaml, which git ignores; 'lnk vars' lists
+// the variables and sets or unsets answers.
+
+// Files in VarsDirName holding template variables
+const (
+	VarsDataFileName    = "data.yaml"    // Variables for every machine
+	VarsHostDirName     = "data.d"       // Per-host overlays, named <hostname>.yaml
+	VarsAnswersFileName = "answers.yaml" // This machine's answers; never committed
+)
+
+// Vars edit actions, for 'lnk vars set|unset'
+const (
+	VarsSet   = "set"
+	VarsUnset = "unset"
+)
+
+// machineHostname returns the machine's host name; tests replace it
+var machineHostname = os.Hostname
+
+// varsFile is one file template variables are read from, in merge order
+type varsFile struct {
+	Path string // absolute path
+	Name string // path relative to the directory holding .lnk, for messages
+}
+
+// templateVarsFiles lists the files template variables are read from, in the
+// order they are merged: data.yaml, then data.d/<short>.yaml for the host name
+// up to its first dot, then data.d/<hostname>.yaml when that differs, and last
+// the answers given on this machine
+func templateVarsFiles(dir string) []varsFile {
+	names := []string{VarsDataFileName}
+	if host, err := machineHostname(); err == nil && host != "" {
+		short, _, _ := strings.Cut(host, ".")
+		for _, h := range []string{short, host} {
+			name := filepath.Join(VarsHostDirName, h+".yaml")
+			if filepath.IsLocal(name) && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	names = append(names, VarsAnswersFileName)
+	files := make([]varsFile, 0, len(names))
+	for _, name := range names {
+		rel := filepath.Join(VarsDirName, name)
+		files = append(files, varsFile{Path: filepath.Join(dir, rel), Name: rel})
+	}
+	return files
+}
+
+// loadTemplateVars reads and merges the template variable files under dir.
+// Missing files are skipped; a file that is not valid is an error.
+func loadTemplateVars(dir string) (map[string]any, error) {
+	vars := map[string]any{}
+	for _, f := range templateVarsFiles(dir) {
+		data, err := os.ReadFile(f.Path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, NewPathError("read", f.Path, err)
+		}
+		values, err := parseYAML(data)
+		if err != nil {
+			return nil, NewPathErrorWithHint("read", f.Path, err,
+				"Template variables are YAML mappings of names to strings, lists, and nested mappings")
+		}
+		mergeVars(vars, values)
+		PrintVerbose("Loaded %d template variable(s) from %s", len(values), f.Name)
+	}
+	return vars, nil
+}
+
+// mergeVars copies src into dst. Mappings present in both are merged; any
+// other value in src replaces the one in dst.
+func mergeVars(dst, src map[string]any) {
+	for key, value := range src {
+		if sub, ok := value.(map[string]any); ok {
+			if existing, ok := dst[key].(map[string]any); ok {
+				mergeVars(existing, sub)
+				continue
+			}
+			copied := map[string]any{}
+			mergeVars(copied, sub)
+			value = copied
+		}
+		dst[key] = value
+	}
+}
+
+// yamlLine is a line of YAML without its indentation and comment
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// parseYAML reads the block-style YAML template variables are written in:
+// mappings, lists of scalars, plain and quoted strings, and true or false.
+// Other scalars, numbers included, are kept as strings. Flow collections
+// other than [] and {}, anchors, and multi-line strings are not supported.
+func parseYAML(data []byte) (map[string]any, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \r")
+		if i == 0 {
+			raw = strings.TrimPrefix(raw, "\ufeff")
+		}
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: indent with spaces, not tabs", i+1)
+		}
+		indent := len(raw) - len(text)
+		text = stripYAMLComment(text)
+		if text == "" || text == "---" {
+			continue
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: indent, text: text})
+	}
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+	p := &yamlParser{lines: lines}
+	value, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[p.pos].num)
+	}
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("line %d: expected NAME: VALUE", lines[0].num)
+	}
+	return m, nil
+}
+
+// stripYAMLComment removes a # comment that starts the text or follows a
+// space outside quotes, and the spaces before it
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || text[i-1] == ' ' || text[i-1] == ':' || text[i-1] == '-' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return strings.TrimRight(text[:i], " ")
+		}
+	}
+	return text
+}
+
+// yamlParser walks the lines of a YAML document
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// block parses the mapping or list whose lines start at indent
+func (p *yamlParser) block(indent int) (any, error) {
+	if first := p.lines[p.pos].text; first == "-" || strings.HasPrefix(first, "- ") {
+		return p.list(indent)
+	}
+	return p.mapping(indent)
+}
+
+// list parses list items at indent; items must be scalars
+func (p *yamlParser) list(indent int) ([]any, error) {
+	var items []any
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if line.text != "-" && !strings.HasPrefix(line.text, "- ") {
+			break
+		}
+		item := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if item == "" || strings.HasPrefix(item, "- ") || yamlKeyEnd(item) >= 0 {
+			return nil, fmt.Errorf("line %d: list items must be plain values", line.num)
+		}
+		value, err := yamlScalarValue(item)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.num, err)
+		}
+		items = append(items, value)
+		p.pos++
+	}
+	return items, nil
+}
+
+// mapping parses NAME: VALUE lines at indent. A name with nothing after the
+// colon holds the more indented block below it, or an empty string.
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		end := yamlKeyEnd(line.text)
+		if end <= 0 {
+			return nil, fmt.Errorf("line %d: expected NAME: VALUE", line.num)
+		}
+		key, err := yamlKey(line.text[:end])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.num, err)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: %s is set twice", line.num, key)
+		}
+		rest := strings.TrimSpace(line.text[end+1:])
+		p.pos++
+		switch {
+		case rest != "":
+			if m[key], err = yamlScalarValue(rest); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.num, err)
+			}
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			if m[key], err = p.block(p.lines[p.pos].indent); err != nil {
+				return nil, err
+			}
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && strings.HasPrefix(p.lines[p.pos].text, "- "):
+			// A list may sit at the same indent as its name
+			if m[key], err = p.list(indent); err != nil {
+				return nil, err
+			}
+		default:
+			m[key] = ""
+		}
+	}
+	return m, nil
+}
+
+// yamlKeyEnd returns the index of the colon ending a mapping key in text, or
+// -1 when text is not NAME: VALUE
+func yamlKeyEnd(text string) int {
+	if text[0] == '"' || text[0] == '\'' {
+		if end := closingQuote(text); end > 0 && end+1 < len(text) && text[end+1] == ':' &&
+			(end+2 == len(text) || text[end+2] == ' ') {
+			return end + 1
+		}
+		return -1
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlKey reads a plain or quoted mapping key
+func yamlKey(text string) (string, error) {
+	value, err := yamlScalarValue(strings.TrimSpace(text))
+	if err != nil {
+		return "", err
+	}
+	key, ok := value.(string)
+	if !ok || key == "" {
+		return "", fmt.Errorf("%q is not a name", text)
+	}
+	return key, nil
+}
+
+// yamlScalarValue reads a plain or quoted scalar, an empty [] or {}, or true
+// or false
+func yamlScalarValue(text string) (any, error) {
+	switch {
+	case text == "[]":
+		return []any{}, nil
+	case text == "{}":
+		return map[string]any{}, nil
+	case text[0] == '[' || text[0] == '{':
+		return nil, fmt.Errorf("flow collections are not supported; put each item on its own line")
+	case text[0] == '&' || text[0] == '*' || text[0] == '|' || text[0] == '>':
+		return nil, fmt.Errorf("anchors, aliases, and multi-line strings are not supported")
+	case text[0] == '"':
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", text)
+		}
+		return s, nil
+	case text[0] == '\'':
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case text == "true" || text == "True" || text == "TRUE":
+		return true, nil
+	case text == "false" || text == "False" || text == "FALSE":
+		return false, nil
+	}
+	return text, nil
+}
+
+// closingQuote returns the index of the quote closing the string text starts
+// with, or -1
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// varNamePattern matches the variable names templates can use as .Vars.NAME
+var varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// isSecretVar reports whether a variable holds a secret, by its name ending
+// in password, passphrase, secret, or token. Secrets are read without echo
+// and not shown by 'lnk vars list'.
+func isSecretVar(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{"password", "passphrase", "secret", "token"} {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateVarRefs returns the top-level names a template uses as .Vars.NAME
+// (or .Vars.NAME.KEY), in the order they first appear
+func templateVarRefs(name, text string) ([]string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs("")).Parse(text)
+	if err != nil {
+		return nil, NewPathError("parse template", name, err)
+	}
+	var refs []string
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n != nil {
+				for _, child := range n.Nodes {
+					walk(child)
+				}
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n != nil {
+				for _, cmd := range n.Cmds {
+					walk(cmd)
+				}
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.FieldNode:
+			if len(n.Ident) >= 2 && n.Ident[0] == "Vars" && !slices.Contains(refs, n.Ident[1]) {
+				refs = append(refs, n.Ident[1])
+			}
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root)
+		}
+	}
+	return refs, nil
+}
+
+// templateText is a template to render, by the name errors and prompts use
+type templateText struct {
+	Name string
+	Text string
+}
+
+// askTemplateVars asks at the terminal for each variable the templates use
+// that vars does not set, adding the answers to vars and returning them to be
+// saved. Without a terminal nothing is asked, and rendering then fails on the
+// first missing variable.
+func askTemplateVars(texts []templateText, vars map[string]any) (map[string]string, error) {
+	answers := map[string]string{}
+	if !canPrompt() {
+		return answers, nil
+	}
+	for _, t := range texts {
+		refs, err := templateVarRefs(t.Name, t.Text)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range refs {
+			if _, ok := vars[name]; ok {
+				continue
+			}
+			question := fmt.Sprintf("Value for %s (used by %s):", name, t.Name)
+			var value string
+			if isSecretVar(name) {
+				value, err = readSecret(question)
+			} else {
+				value, err = readLine(question)
+			}
+			if err != nil {
+				return answers, nil
+			}
+			vars[name] = value
+			answers[name] = value
+		}
+	}
+	return answers, nil
+}
+
+// loadTemplateAnswers reads the answers file in dir's .lnk directory
+func loadTemplateAnswers(dir string) (map[string]string, error) {
+	path := filepath.Join(dir, VarsDirName, VarsAnswersFileName)
+	data, err := fsys.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, NewPathError("read", path, err)
+	}
+	values, err := parseYAML(data)
+	if err != nil {
+		return nil, NewPathErrorWithHint("read", path, err, "Fix the file, or delete it and answer again")
+	}
+	answers := make(map[string]string, len(values))
+	for name, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, NewPathErrorWithHint("read", path, fmt.Errorf("%s is not a string", name),
+				"Fix the file, or delete it and answer again")
+		}
+		answers[name] = s
+	}
+	return answers, nil
+}
+
+// saveTemplateAnswers writes answers, sorted by name and readable only by the
+// user, to the answers file in dir's .lnk directory, and makes sure .lnk's
+// .gitignore keeps it out of git
+func saveTemplateAnswers(dir string, answers map[string]string) error {
+	varsDir := filepath.Join(dir, VarsDirName)
+	path := filepath.Join(varsDir, VarsAnswersFileName)
+	if err := checkWritable("save answers", path); err != nil {
+		return err
+	}
+	if err := fsys.MkdirAll(varsDir, 0755); err != nil {
+		return NewPathError("create directory", varsDir, err)
+	}
+	if err := ensureGitIgnored(varsDir, VarsAnswersFileName); err != nil {
+		return err
+	}
+	if len(answers) == 0 {
+		if err := fsys.Remove(path); err != nil && !os.IsNotExist(err) {
+			return NewPathError("remove", path, err)
+		}
+		return nil
+	}
+	var sb strings.Builder
+	sb.WriteString("# Answers for template variables on this machine; managed by 'lnk vars'\n")
+	for _, name := range sortedKeys(answers) {
+		fmt.Fprintf(&sb, "%s: %s\n", name, yamlScalar(reflect.ValueOf(answers[name])))
+	}
+	if err := writeFileAtomic(path, []byte(sb.String()), 0600); err != nil {
+		return NewPathErrorWithHint("write", path, err, "Check that the source directory is writable")
+	}
+	return nil
+}
+
+// ensureGitIgnored adds name to the .gitignore in dir unless a line already
+// names it
+func ensureGitIgnored(dir, name string) error {
+	path := filepath.Join(dir, ".gitignore")
+	data, err := fsys.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return NewPathError("read", path, err)
+	}
+	text := string(data)
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line == name || line == "/"+name {
+			return nil
+		}
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	if err := writeFileAtomic(path, []byte(text+name+"\n"), 0644); err != nil {
+		return NewPathError("write", path, err)
+	}
+	return nil
+}
+
+// templateVar is a variable as 'lnk vars list' shows it
+type templateVar struct {
+	Name  string // dotted path, such as git.email
+	Value string
+	From  string // the file that set it last, relative to the source directory
+}
+
+// flattenVars appends the scalar and list values below prefix, by dotted name
+func flattenVars(prefix string, values map[string]any, from string, out map[string]templateVar) {
+	for key, value := range values {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flattenVars(name, v, from, out)
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[name] = templateVar{Name: name, Value: "[" + strings.Join(items, ", ") + "]", From: from}
+		default:
+			out[name] = templateVar{Name: name, Value: fmt.Sprint(v), From: from}
+		}
+	}
+}
+
+// ListVars prints every template variable of the source directory with the
+// file that sets it on this machine. Secret values are masked.
+func ListVars(sourceDir string) error {
+	PrintCommandHeader("Template Variables")
+
+	dir, err := ExpandPath(sourceDir)
+	if err != nil {
+		return err
+	}
+	vars := map[string]templateVar{}
+	for _, f := range templateVarsFiles(dir) {
+		data, err := fsys.ReadFile(f.Path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return NewPathError("read", f.Path, err)
+		}
+		values, err := parseYAML(data)
+		if err != nil {
+			return NewPathErrorWithHint("read", f.Path, err,
+				"Template variables are YAML mappings of names to strings, lists, and nested mappings")
+		}
+		flattenVars("", values, f.Name, vars)
+	}
+	if len(vars) == 0 {
+		PrintEmptyResult("template variables")
+		PrintNextStep("vars set", sourceDir, "answer one, or add them to .lnk/data.yaml")
+		return nil
+	}
+
+	for _, name := range sortedKeys(vars) {
+		v := vars[name]
+		if isSecretVar(name[strings.LastIndex(name, ".")+1:]) {
+			v.Value = "********"
+		}
+		if ShouldSimplifyOutput() {
+			fmt.Printf("var %s %s %s\n", v.Name, v.From, v.Value)
+			continue
+		}
+		fmt.Printf("%s = %s\n", Bold(v.Name), v.Value)
+		PrintDetail("From %s", v.From)
+	}
+	return nil
+}
+
+// VarsEdit is one change to the answers of a source directory
+type VarsEdit struct {
+	Action string // VarsSet or VarsUnset
+	Name   string // the variable, as templates use it after .Vars.
+	Value  string // the value to set
+	Ask    bool   // set: ask for the value at the terminal instead
+	DryRun bool   // print the change instead of writing it
+}
+
+// EditVars sets or unsets an answer for a template variable on this machine.
+// Answers override the data files; unsetting one lets the data files' value
+// apply again, or has templates ask for it next time.
+func EditVars(sourceDir string, edit VarsEdit) error {
+	PrintCommandHeader("Editing Template Variables")
+
+	if !varNamePattern.MatchString(edit.Name) {
+		return NewValidationErrorWithHint("name", edit.Name, "not a variable name",
+			"Names are letters, digits, and underscores, as templates use them in {{.Vars.NAME}}")
+	}
+	dir, err := ExpandPath(sourceDir)
+	if err != nil {
+		return err
+	}
+	answers, err := loadTemplateAnswers(dir)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, VarsDirName, VarsAnswersFileName)
+
+	shown := func(value string) string {
+		if isSecretVar(edit.Name) {
+			return "********"
+		}
+		return value
+	}
+	switch edit.Action {
+	case VarsUnset:
+		if _, ok := answers[edit.Name]; !ok {
+			PrintSkip("No answer for %s", edit.Name)
+			PrintSummary("Template variables unchanged")
+			return nil
+		}
+		delete(answers, edit.Name)
+		if edit.DryRun {
+			PrintDryRun("Would unset %s", edit.Name)
+			PrintDryRunSummary()
+			return nil
+		}
+		if err := saveTemplateAnswers(dir, answers); err != nil {
+			return err
+		}
+		PrintSuccess("Unset %s", edit.Name)
+	default:
+		value := edit.Value
+		if edit.Ask {
+			if !canPrompt() {
+				return WithHint(fmt.Errorf("vars set needs a value for %s", edit.Name),
+					fmt.Sprintf("Usage: lnk vars set <source-dir> %s <value>, or run it in a terminal to be asked", edit.Name))
+			}
+			question := fmt.Sprintf("Value for %s:", edit.Name)
+			if isSecretVar(edit.Name) {
+				value, err = readSecret(question)
+			} else {
+				value, err = readLine(question)
+			}
+			if err != nil {
+				return fmt.Errorf("reading a value for %s: %w", edit.Name, err)
+			}
+		}
+		if old, ok := answers[edit.Name]; ok && old == value {
+			PrintSkip("%s is already %s", edit.Name, shown(value))
+			PrintSummary("Template variables unchanged")
+			return nil
+		}
+		answers[edit.Name] = value
+		if edit.DryRun {
+			PrintDryRun("Would set %s = %s", edit.Name, shown(value))
+			PrintDryRunSummary()
+			return nil
+		}
+		if err := saveTemplateAnswers(dir, answers); err != nil {
+			return err
+		}
+		PrintSuccess("Set %s = %s", edit.Name, shown(value))
+	}
+	PrintSummary("Updated %s", ContractPath(path))
+	return nil
+}
@@ -0,0 +1,178 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want map[string]any
+	}{
+		{"empty", "# nothing yet\n", map[string]any{}},
+		{"scalars", "email: ada@example.com  # work\nproxy: \"http://proxy:3128\"\nquote: 'it''s'\ndark: true\nport: 8080\nnone:\n",
+			map[string]any{"email": "ada@example.com", "proxy": "http://proxy:3128", "quote": "it's", "dark": true, "port": "8080", "none": ""}},
+		{"nested", "git:\n  email: a@b.c\n  signing:\n    key: ABC\ntheme: dark\n",
+			map[string]any{"git": map[string]any{"email": "a@b.c", "signing": map[string]any{"key": "ABC"}}, "theme": "dark"}},
+		{"lists", "hosts:\n  - one\n  - \"two # not a comment\"\nflat:\n- a\nempty: []\n",
+			map[string]any{"hosts": []any{"one", "two # not a comment"}, "flat": []any{"a"}, "empty": []any{}}},
+		{"document marker", "---\nname: x\n", map[string]any{"name": "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.in))
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML() = %#v, %v; want %#v", got, err, tt.want)
+			}
+		})
+	}
+
+	for _, in := range []string{
+		"- a\n",
+		"a: 1\na: 2\n",
+		"a: [1, 2]\n",
+		"a:\n\tb: 1\n",
+		"a: 1\n  b: 2\n",
+		"a: \"open\n",
+		"just text\n",
+		"a:\n  - b: 1\n",
+	} {
+		if got, err := parseYAML([]byte(in)); err == nil {
+			t.Errorf("parseYAML(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestLoadTemplateVars(t *testing.T) {
+	dir := t.TempDir()
+	orig := machineHostname
+	machineHostname = func() (string, error) { return "laptop.example.com", nil }
+	t.Cleanup(func() { machineHostname = orig })
+
+	createTestFile(t, filepath.Join(dir, ".lnk", "data.yaml"), "email: me@home.example\ngit:\n  editor: vim\n  signing: true\ntheme: light\n")
+	createTestFile(t, filepath.Join(dir, ".lnk", "data.d", "laptop.yaml"), "email: me@work.example\ngit:\n  editor: code\n")
+	createTestFile(t, filepath.Join(dir, ".lnk", "data.d", "laptop.example.com.yaml"), "theme: dark\n")
+	createTestFile(t, filepath.Join(dir, ".lnk", "data.d", "desktop.yaml"), "theme: solarized\n")
+
+	got, err := loadTemplateVars(dir)
+	if err != nil {
+		t.Fatalf("loadTemplateVars() error = %v", err)
+	}
+	want := map[string]any{
+		"email": "me@work.example",
+		"git":   map[string]any{"editor": "code", "signing": true},
+		"theme": "dark",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadTemplateVars() = %#v, want %#v", got, want)
+	}
+
+	if got, err := loadTemplateVars(t.TempDir()); err != nil || len(got) != 0 {
+		t.Errorf("loadTemplateVars() without .lnk = %v, %v; want no variables", got, err)
+	}
+
+	createTestFile(t, filepath.Join(dir, ".lnk", "data.d", "laptop.yaml"), "email: [a, b]\n")
+	if _, err := loadTemplateVars(dir); err == nil || GetErrorHint(err) == "" {
+		t.Errorf("loadTemplateVars() with a bad overlay = %v, want an error with a hint", err)
+	}
+}
+
+func TestTemplateVarRefs(t *testing.T) {
+	text := `{{.Vars.email}} {{if .Vars.work}}{{.Vars.git.signing_key | printf "%s"}}{{else}}{{.Name}}{{end}}` +
+		`{{range .Vars.hosts}}{{.}}{{end}}{{with .Vars.proxy}}{{.}}{{end}}{{.Vars.email}}`
+	got, err := templateVarRefs("t", text)
+	want := []string{"email", "work", "git", "hosts", "proxy"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("templateVarRefs() = %v, %v; want %v", got, err, want)
+	}
+	if _, err := templateVarRefs("t", "{{.Vars.x"); err == nil {
+		t.Error("templateVarRefs() of an invalid template should fail")
+	}
+}
+
+func TestEditVars(t *testing.T) {
+	dir := t.TempDir()
+	orig := machineHostname
+	machineHostname = func() (string, error) { return "laptop", nil }
+	t.Cleanup(func() { machineHostname = orig })
+	createTestFile(t, filepath.Join(dir, ".lnk", "data.yaml"), "email: me@home.example\ngit:\n  editor: vim\n")
+	answersPath := filepath.Join(dir, ".lnk", "answers.yaml")
+
+	edit := func(e VarsEdit) (string, error) {
+		var err error
+		out := CaptureOutput(t, func() { err = EditVars(dir, e) })
+		return out, err
+	}
+
+	// A dry run writes nothing
+	if _, err := edit(VarsEdit{Action: VarsSet, Name: "email", Value: "me@work.example", DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertNotExists(t, answersPath)
+
+	out, err := edit(VarsEdit{Action: VarsSet, Name: "email", Value: "me@work.example"})
+	if err != nil {
+		t.Fatalf("EditVars(set) error = %v", err)
+	}
+	ContainsOutput(t, out, "Set email = me@work.example")
+	if _, err := edit(VarsEdit{Action: VarsSet, Name: "github_token", Value: "ghp_secret"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(answersPath)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("answers file = %v, %v; want mode 0600", info, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".lnk", ".gitignore")); string(data) != "answers.yaml\n" {
+		t.Errorf(".lnk/.gitignore = %q, want the answers file ignored", data)
+	}
+
+	// Answers override the data files, and secrets are masked
+	vars, err := loadTemplateVars(dir)
+	if err != nil || vars["email"] != "me@work.example" || vars["github_token"] != "ghp_secret" {
+		t.Errorf("loadTemplateVars() = %v, %v; want the answers", vars, err)
+	}
+	out = CaptureOutput(t, func() {
+		if err := ListVars(dir); err != nil {
+			t.Errorf("ListVars() error = %v", err)
+		}
+	})
+	ContainsOutput(t, out, "var email .lnk/answers.yaml me@work.example", "var git.editor .lnk/data.yaml vim",
+		"var github_token .lnk/answers.yaml ********")
+	NotContainsOutput(t, out, "ghp_secret")
+
+	out, err = edit(VarsEdit{Action: VarsSet, Name: "email", Value: "me@work.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ContainsOutput(t, out, "email is already me@work.example")
+
+	// Unsetting lets the data file apply again
+	if _, err := edit(VarsEdit{Action: VarsUnset, Name: "email"}); err != nil {
+		t.Fatal(err)
+	}
+	if vars, _ := loadTemplateVars(dir); vars["email"] != "me@home.example" {
+		t.Errorf("email after unset = %v, want the data file's", vars["email"])
+	}
+	out, _ = edit(VarsEdit{Action: VarsUnset, Name: "email"})
+	ContainsOutput(t, out, "No answer for email")
+	if _, err := edit(VarsEdit{Action: VarsUnset, Name: "github_token"}); err != nil {
+		t.Fatal(err)
+	}
+	assertNotExists(t, answersPath)
+
+	origCanPrompt := canPrompt
+	canPrompt = func() bool { return false }
+	t.Cleanup(func() { canPrompt = origCanPrompt })
+	for _, e := range []VarsEdit{
+		{Action: VarsSet, Name: "git.email", Value: "x"},
+		{Action: VarsSet, Name: "email", Ask: true},
+	} {
+		if _, err := edit(e); err == nil || GetErrorHint(err) == "" {
+			t.Errorf("EditVars(%+v) = %v, want an error with a hint", e, err)
+		}
+	}
+}
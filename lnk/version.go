@@ -0,0 +1,152 @@
+package lnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// buildVersion is the running lnk's version, injected into main by the build
+// and passed on with SetVersion
+var buildVersion = "dev"
+
+// SetVersion sets the running lnk's version, which configuration requiring a
+// newer lnk is checked against
+func SetVersion(version string) {
+	buildVersion = version
+}
+
+// checkSourceVersion checks the min_lnk_version of the lnk-package.json at the
+// top of the source directory, which applies to the whole repository even when
+// packages are selected. A missing or unreadable file is left to
+// LoadPackageInfo.
+func checkSourceVersion(sourceDir string) error {
+	path := filepath.Join(sourceDir, PackageInfoFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return checkRequiredVersion(path, data)
+}
+
+// checkRequiredVersion fails when the lnk-package.json in data declares a
+// min_lnk_version newer than the running lnk. It reads only that key, so it
+// runs before the rest of the file is decoded: settings a newer lnk added
+// would otherwise be reported as unknown keys or invalid values instead of as
+// the real problem. Development builds are assumed to be new enough.
+func checkRequiredVersion(path string, data []byte) error {
+	var req struct {
+		MinLnkVersion string `json:"min_lnk_version"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil || req.MinLnkVersion == "" {
+		return nil // syntax errors are reported by the caller
+	}
+	required, ok := parseVersion(req.MinLnkVersion)
+	if !ok {
+		return NewValidationErrorWithHint("min_lnk_version", req.MinLnkVersion,
+			fmt.Sprintf("not a release version in %s", ContractPath(path)),
+			`Use a version such as "1.4.0"`)
+	}
+	current, ok := parseVersion(buildVersion)
+	if !ok {
+		PrintVerbose("Not checking min_lnk_version %s in %s: development build %s",
+			required, ContractPath(path), buildVersion)
+		return nil
+	}
+	if current.compare(required) >= 0 {
+		return nil
+	}
+	return WithHint(fmt.Errorf("%s requires lnk %s or newer, but this is lnk %s",
+		ContractPath(path), required, current),
+		"Upgrade lnk with 'lnk self-update', or 'brew upgrade lnk' if Homebrew installed it")
+}
+
+// semver is a parsed release version: MAJOR.MINOR.PATCH with an optional
+// -prerelease suffix. Build metadata (+...) is ignored.
+type semver struct {
+	major, minor, patch int
+	pre                 string
+}
+
+// parseVersion parses a release version such as "v1.2.3", "1.2.3", or
+// "1.2.0-rc.1". Development builds ("dev", "dev+20250101") are not release
+// versions and report false.
+func parseVersion(s string) (semver, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+	core, pre, _ := strings.Cut(s, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return semver{}, false
+	}
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return semver{}, false
+		}
+		nums[i] = n
+	}
+	return semver{major: nums[0], minor: nums[1], patch: nums[2], pre: pre}, true
+}
+
+// compare returns -1, 0, or 1 as v is older than, the same as, or newer than
+// w. A prerelease is older than its release; prereleases of the same version
+// compare by their dot-separated identifiers, numerically where both are
+// numbers.
+func (v semver) compare(w semver) int {
+	for _, d := range []int{v.major - w.major, v.minor - w.minor, v.patch - w.patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.pre == w.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case w.pre == "":
+		return -1
+	}
+	a, b := strings.Split(v.pre, "."), strings.Split(w.pre, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		x, errX := strconv.Atoi(a[i])
+		y, errY := strconv.Atoi(b[i])
+		switch {
+		case errX == nil && errY == nil:
+			return sign(x - y)
+		case errX == nil:
+			return -1
+		case errY == nil:
+			return 1
+		default:
+			return sign(strings.Compare(a[i], b[i]))
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+func (v semver) String() string {
+	s := strconv.Itoa(v.major) + "." + strconv.Itoa(v.minor) + "." + strconv.Itoa(v.patch)
+	if v.pre != "" {
+		s += "-" + v.pre
+	}
+	return s
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,89 @@
+package lnk
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+		{"1.0.0+build.5", "1.0.0", 0},
+	}
+	for _, tt := range tests {
+		a, okA := parseVersion(tt.a)
+		b, okB := parseVersion(tt.b)
+		if !okA || !okB {
+			t.Fatalf("parseVersion(%q, %q) failed", tt.a, tt.b)
+		}
+		if got := a.compare(b); got != tt.want {
+			t.Errorf("compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+	for _, dev := range []string{"dev", "dev+20250101120000", "1.2", "v1.x.0"} {
+		if _, ok := parseVersion(dev); ok {
+			t.Errorf("parseVersion(%q) should not be a release version", dev)
+		}
+	}
+}
+
+func TestRequiredVersion(t *testing.T) {
+	t.Cleanup(func() { SetVersion("dev"); SetStrictConfig(false) })
+	SetStrictConfig(true)
+
+	sourceDir := t.TempDir()
+	pkgDir := filepath.Join(sourceDir, "nvim")
+	// The unknown key is a setting from a newer lnk; the version error wins
+	createTestFile(t, filepath.Join(pkgDir, PackageInfoFileName),
+		`{"min_lnk_version": "1.4.0", "newer_setting": true}`)
+
+	tests := []struct {
+		name    string
+		version string
+		wantErr string
+	}{
+		{"older", "v1.3.9", "requires lnk 1.4.0 or newer, but this is lnk 1.3.9"},
+		{"prerelease", "1.4.0-rc.1", "requires lnk 1.4.0 or newer"},
+		{"development build", "dev+20250101120000", "unknown key"},
+		{"newer", "1.5.0", "unknown key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetVersion(tt.version)
+			_, err := LoadPackageInfo(pkgDir)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadPackageInfo() error = %v, want %q", err, tt.wantErr)
+			}
+			if strings.Contains(tt.wantErr, "requires") {
+				ContainsOutput(t, GetErrorHint(err), "lnk self-update")
+			}
+		})
+	}
+
+	t.Run("source directory", func(t *testing.T) {
+		SetVersion("1.0.0")
+		createTestFile(t, filepath.Join(sourceDir, PackageInfoFileName), `{"min_lnk_version": "v2.0.0"}`)
+		if _, err := LoadConfig(sourceDir, nil); err == nil || !strings.Contains(err.Error(), "requires lnk 2.0.0") {
+			t.Errorf("LoadConfig() error = %v, want version error", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		createTestFile(t, filepath.Join(pkgDir, PackageInfoFileName), `{"min_lnk_version": "latest"}`)
+		_, err := LoadPackageInfo(pkgDir)
+		var verr *ValidationError
+		if !errors.As(err, &verr) || verr.Field != "min_lnk_version" {
+			t.Errorf("LoadPackageInfo() error = %v, want min_lnk_version error", err)
+		}
+	})
+}
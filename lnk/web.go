@@ -0,0 +1,301 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultWebListen is the address 'lnk web' listens on without --listen
+const DefaultWebListen = "127.0.0.1:7474"
+
+// maxWebDiff is the most diff output shown for one conflict
+const maxWebDiff = 64 << 10
+
+// WebOptions holds configuration for the read-only dashboard
+type WebOptions struct {
+	SourceDir      string    // source directory to show
+	TargetDir      string    // where links are created (default: ~)
+	IgnorePatterns []string  // combined ignore patterns from all sources
+	Packages       []string  // packages to show (empty = SourceDir itself)
+	Maps           []Mapping // ad-hoc mappings to include
+	LocalOnly      []string  // target paths lnk never touches
+	Listen         string    // loopback address to listen on (default: DefaultWebListen)
+}
+
+// webState is everything the dashboard shows, gathered fresh for each request
+type webState struct {
+	SourceDir string
+	TargetDir string
+	Generated string
+	Mappings  []webMapping
+	Links     []webLink
+	Unlinked  []string
+	Conflicts []webConflict
+	History   []string // recent commits of the source directory, newest first
+}
+
+type webMapping struct {
+	Source string
+	Target string
+}
+
+type webLink struct {
+	Path   string
+	Source string
+	State  string // "active" or "broken"
+	Reason string // why a broken link is broken
+}
+
+type webConflict struct {
+	Target      string
+	Source      string
+	Description string
+	Diff        string // unified diff of source and target; empty for directories
+}
+
+// Web serves a read-only HTML dashboard of the source directory's mappings,
+// link states, recent git history, and diffs for conflicts. It only listens on
+// loopback addresses; use SSH port forwarding to view it from another machine.
+// Web runs until the server fails.
+func Web(opts WebOptions) error {
+	PrintCommandHeader("Dashboard")
+
+	if opts.Listen == "" {
+		opts.Listen = DefaultWebListen
+	}
+	if err := validateWebListen(opts.Listen); err != nil {
+		return err
+	}
+	paths, err := ResolvePaths(opts.SourceDir, opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	opts.SourceDir, opts.TargetDir = paths.SourceDir, paths.TargetDir
+
+	// Fail before listening if the dashboard cannot be built at all
+	if _, err := buildWebState(opts); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return WithHint(fmt.Errorf("listening on %s: %w", opts.Listen, err),
+			"Choose another port with --listen, e.g. --listen 127.0.0.1:7475")
+	}
+	PrintInfo("Serving read-only dashboard at http://%s/ (press Ctrl-C to stop)", ln.Addr())
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	PrintDetail("From another machine: ssh -L %s:localhost:%s <host>, then open http://localhost:%s/", port, port, port)
+
+	srv := &http.Server{Handler: newWebHandler(opts), ReadHeaderTimeout: 10 * time.Second}
+	return srv.Serve(ln)
+}
+
+// validateWebListen rejects addresses that are not loopback, so the dashboard
+// is never exposed to the network by accident
+func validateWebListen(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return NewValidationErrorWithHint("listen", addr, "must be HOST:PORT",
+			"Example: --listen 127.0.0.1:7474")
+	}
+	if !isLoopbackHost(host) {
+		return NewValidationErrorWithHint("listen", addr, "must be a loopback address",
+			"lnk web only serves localhost; use SSH port forwarding to view it remotely")
+	}
+	return nil
+}
+
+// isLoopbackHost reports whether host is localhost or a loopback IP
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newWebHandler returns the dashboard handler. Only GET and HEAD of "/" are
+// served, and requests whose Host is not a loopback name are refused so other
+// web sites cannot read the dashboard through DNS rebinding.
+func newWebHandler(opts WebOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		switch {
+		case !isLoopbackHost(host):
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		case r.Method != http.MethodGet && r.Method != http.MethodHead:
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "read-only", http.StatusMethodNotAllowed)
+			return
+		case r.URL.Path != "/":
+			http.NotFound(w, r)
+			return
+		}
+
+		state, err := buildWebState(opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var buf bytes.Buffer
+		if err := webTemplate.Execute(&buf, state); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(buf.Bytes())
+	})
+}
+
+// buildWebState gathers the dashboard contents the same way status does
+func buildWebState(opts WebOptions) (*webState, error) {
+	sourceDir, targetDir := opts.SourceDir, opts.TargetDir
+	packages, err := expandPackageDeps(sourceDir, opts.Packages)
+	if err != nil {
+		return nil, err
+	}
+	pkgDirs, err := packageDirs(sourceDir, packages)
+	if err != nil {
+		return nil, err
+	}
+	maps, err := resolveMappings(opts.Maps, "", sourceDir, targetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &webState{
+		SourceDir: ContractPath(sourceDir),
+		TargetDir: ContractPath(targetDir),
+		Generated: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	for _, dir := range pkgDirs {
+		pkgTarget, include, err := resolvePackageTarget(dir, targetDir)
+		if err != nil {
+			return nil, err
+		}
+		if include {
+			state.Mappings = append(state.Mappings, webMapping{Source: ContractPath(dir), Target: ContractPath(pkgTarget)})
+		}
+	}
+	for _, m := range maps {
+		state.Mappings = append(state.Mappings, webMapping{Source: ContractPath(m.Source), Target: ContractPath(m.Target)})
+	}
+
+	links, err := findPackageLinks(targetDir, pkgDirs, mappingSources(maps))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Path < links[j].Path })
+	for _, link := range links {
+		l := webLink{Path: ContractPath(link.Path), Source: ContractPath(link.Target), State: "active"}
+		if link.IsBroken {
+			l.State, l.Reason = "broken", describeBroken(link.Broken)
+		}
+		state.Links = append(state.Links, l)
+	}
+
+	unlinked, conflicts, err := classifyPlannedLinks(pkgDirs, maps, targetDir, opts.IgnorePatterns, opts.LocalOnly, nil, loadCopies(targetDir, sourceDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range unlinked {
+		state.Unlinked = append(state.Unlinked, ContractPath(link.Source))
+	}
+	for _, c := range conflicts {
+		wc := webConflict{
+			Target:      ContractPath(c.link.Target),
+			Source:      ContractPath(c.link.Source),
+			Description: describeConflict(c.info),
+		}
+		if c.info.Mode().IsRegular() {
+			var diff strings.Builder
+			writeFileDiff(&diff, c.link.Source, c.link.Target)
+			wc.Diff = diff.String()
+			if len(wc.Diff) > maxWebDiff {
+				wc.Diff = wc.Diff[:maxWebDiff] + "\n... (diff truncated)\n"
+			}
+		}
+		state.Conflicts = append(state.Conflicts, wc)
+	}
+
+	state.History = recentCommits(sourceDir, 10)
+	return state, nil
+}
+
+// recentCommits returns up to n one-line summaries of the latest commits in
+// dir's repository, or nil when git information is unavailable
+func recentCommits(dir string, n int) []string {
+	if !isGitWorkTree(dir) {
+		return nil
+	}
+	out, err := gitOutput(dir, "log", fmt.Sprintf("-n%d", n), "--date=short", "--format=%h %ad %s", "--", ".")
+	if err != nil {
+		return nil
+	}
+	var commits []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits
+}
+
+var webTemplate = template.Must(template.New("web").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>lnk: {{.SourceDir}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; } h2 { font-size: 1.1em; margin-top: 2em; }
+table { border-collapse: collapse; } td, th { padding: 0.2em 1em 0.2em 0; text-align: left; }
+code, pre { font-family: ui-monospace, monospace; font-size: 0.9em; }
+pre { background: #f6f6f6; padding: 0.8em; overflow-x: auto; }
+.active { color: #1a7f37; } .broken, .conflict { color: #cf222e; } .unlinked { color: #9a6700; }
+.muted { color: #666; }
+</style>
+</head>
+<body>
+<h1>lnk: <code>{{.SourceDir}}</code> &rarr; <code>{{.TargetDir}}</code></h1>
+<p class="muted">Read-only. Generated {{.Generated}}; reload to refresh.</p>
+
+<h2>Mappings</h2>
+<table>
+{{range .Mappings}}<tr><td><code>{{.Source}}</code></td><td>&rarr;</td><td><code>{{.Target}}</code></td></tr>
+{{end}}</table>
+
+<h2>Links ({{len .Links}})</h2>
+{{if .Links}}<table>
+{{range .Links}}<tr><td class="{{.State}}">{{.State}}</td><td><code>{{.Path}}</code></td><td class="muted"><code>{{.Source}}</code>{{if .Reason}} ({{.Reason}}){{end}}</td></tr>
+{{end}}</table>{{else}}<p class="muted">No managed links found.</p>{{end}}
+
+<h2>Unlinked sources ({{len .Unlinked}})</h2>
+{{if .Unlinked}}<table>
+{{range .Unlinked}}<tr><td class="unlinked">unlinked</td><td><code>{{.}}</code></td></tr>
+{{end}}</table>{{else}}<p class="muted">None.</p>{{end}}
+
+<h2>Conflicts ({{len .Conflicts}})</h2>
+{{range .Conflicts}}<h3 class="conflict"><code>{{.Target}}</code></h3>
+<p class="muted">{{.Description}}; source <code>{{.Source}}</code></p>
+{{if .Diff}}<pre>{{.Diff}}</pre>{{end}}
+{{else}}<p class="muted">None.</p>{{end}}
+
+<h2>Recent history</h2>
+{{if .History}}<pre>{{range .History}}{{.}}
+{{end}}</pre>{{else}}<p class="muted">No git history available.</p>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,86 @@
+package lnk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestWebHandler(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "dotfiles")
+	targetDir := filepath.Join(tmpDir, "home")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "bash\n")
+	createTestFile(t, filepath.Join(sourceDir, ".vimrc"), "set number\n")
+	createTestFile(t, filepath.Join(sourceDir, ".zshrc"), "zsh\n")
+	createTestFile(t, filepath.Join(sourceDir, "<b>.conf"), "x\n")
+	createTestSymlink(t, filepath.Join(sourceDir, ".bashrc"), filepath.Join(targetDir, ".bashrc"))
+	createTestSymlink(t, filepath.Join(sourceDir, ".missing"), filepath.Join(targetDir, ".missing"))
+	createTestFile(t, filepath.Join(targetDir, ".vimrc"), "set nonumber\n")
+
+	handler := newWebHandler(WebOptions{SourceDir: sourceDir, TargetDir: targetDir})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:7474/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / status = %d, body:\n%s", rec.Code, rec.Body)
+	}
+	ContainsOutput(t, rec.Body.String(),
+		"active", ".bashrc",
+		"broken", ".missing", "(source deleted)",
+		"unlinked", ".zshrc",
+		"Conflicts (1)", ".vimrc", "bytes, modified",
+		"&lt;b&gt;.conf")
+	NotContainsOutput(t, rec.Body.String(), "<b>.conf")
+	if csp := rec.Header().Get("Content-Security-Policy"); csp == "" {
+		t.Error("expected a Content-Security-Policy header")
+	}
+}
+
+func TestWebHandlerRefusesRequests(t *testing.T) {
+	tmpDir := t.TempDir()
+	handler := newWebHandler(WebOptions{SourceDir: tmpDir, TargetDir: tmpDir})
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		want   int
+	}{
+		{"post", http.MethodPost, "http://localhost:7474/", http.StatusMethodNotAllowed},
+		{"other path", http.MethodGet, "http://127.0.0.1:7474/other", http.StatusNotFound},
+		{"rebound host", http.MethodGet, "http://evil.example:7474/", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+			if rec.Code != tt.want {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.url, rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWebListen(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{"127.0.0.1:7474", false},
+		{"localhost:8080", false},
+		{"[::1]:7474", false},
+		{"0.0.0.0:7474", true},
+		{":7474", true},
+		{"192.168.1.10:7474", true},
+		{"7474", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if err := validateWebListen(tt.addr); (err != nil) != tt.wantErr {
+				t.Errorf("validateWebListen(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Steps of lnk up and lnk down
+const (
+	StepSync   = "sync"   // pull the source directory with git (up)
+	StepCreate = "create" // link new files (up)
+	StepPrune  = "prune"  // remove broken links (up)
+	StepStatus = "status" // summarize the recorded links (up)
+	StepRemove = "remove" // remove the links (down)
+	StepClean  = "clean"  // remove empty directories lnk created (down)
+)
+
+// workflowSteps lists the steps of each workflow command, in the order they run
+var workflowSteps = map[string][]string{
+	"up":   {StepSync, StepCreate, StepPrune, StepStatus},
+	"down": {StepRemove, StepClean},
+}
+
+// stepsOffByDefault lists steps that run only when turned on
+var stepsOffByDefault = []string{StepClean}
+
+// Workflow turns the steps of lnk up and lnk down on and off, from
+// .lnkworkflow in the source directory. Steps it leaves out keep their
+// defaults: every step runs except clean. A nil workflow uses the defaults.
+type Workflow struct {
+	Up   map[string]bool `json:"up,omitempty"`   // step name to whether lnk up runs it
+	Down map[string]bool `json:"down,omitempty"` // step name to whether lnk down runs it
+}
+
+// LoadWorkflow reads .lnkworkflow from the source directory. A missing file is
+// not an error; nil is returned instead.
+func LoadWorkflow(sourceDir string) (*Workflow, error) {
+	path := filepath.Join(sourceDir, WorkflowFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			PrintVerbose("No .lnkworkflow file found at: %s", path)
+			return nil, nil
+		}
+		return nil, NewPathErrorWithHint("read workflow", path, err, "Check file permissions")
+	}
+
+	w, err := parseWorkflow(path, data)
+	if err != nil {
+		return nil, err
+	}
+	PrintVerbose("Loaded workflow from .lnkworkflow: %s", strings.Join(w.Entries(), ", "))
+	return w, nil
+}
+
+// parseWorkflow decodes and checks the contents of the .lnkworkflow file at
+// path
+func parseWorkflow(path string, data []byte) (*Workflow, error) {
+	var w Workflow
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&w); err != nil {
+		return nil, NewPathErrorWithHint("parse workflow", path, err,
+			fmt.Sprintf(`Fix the JSON in %s; it has "up" and "down", each mapping step names to true or false`, ContractPath(path)))
+	}
+	for _, command := range []string{"up", "down"} {
+		for step := range w.toggles(command) {
+			if !slices.Contains(workflowSteps[command], step) {
+				return nil, NewValidationErrorWithHint(command, step, "unknown step",
+					fmt.Sprintf("Fix %s; the steps of lnk %s are %s", ContractPath(path), command,
+						strings.Join(workflowSteps[command], ", ")))
+			}
+		}
+	}
+	return &w, nil
+}
+
+// toggles returns the settings for command ("up" or "down")
+func (w *Workflow) toggles(command string) map[string]bool {
+	if w == nil {
+		return nil
+	}
+	if command == "up" {
+		return w.Up
+	}
+	return w.Down
+}
+
+// Steps returns the steps command ("up" or "down") runs, in order
+func (w *Workflow) Steps(command string) []string {
+	toggles := w.toggles(command)
+	var steps []string
+	for _, step := range workflowSteps[command] {
+		on, set := toggles[step]
+		if !set {
+			on = !slices.Contains(stepsOffByDefault, step)
+		}
+		if on {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// Entries describes the workflow's settings, one per entry, for 'lnk config'
+func (w *Workflow) Entries() []string {
+	var entries []string
+	for _, command := range []string{"up", "down"} {
+		toggles := w.toggles(command)
+		for _, step := range workflowSteps[command] {
+			if on, set := toggles[step]; set {
+				state := "off"
+				if on {
+					state = "on"
+				}
+				entries = append(entries, fmt.Sprintf("%s %s %s", command, step, state))
+			}
+		}
+	}
+	return entries
+}
+
+// WorkflowOptions holds options for lnk up and lnk down
+type WorkflowOptions struct {
+	Link     LinkOptions // options for create, prune, status, remove, and clean; DryRun previews every step
+	Sync     SyncOptions // options for sync
+	Workflow *Workflow   // steps to run, or nil for the defaults
+}
+
+// Up brings the links up to date in one run: it pulls the source directory
+// (sync), links new files (create), removes broken links (prune), and
+// summarizes the recorded links (status). Steps turned off in the workflow are
+// skipped, and sync is skipped when the source directory is not a git
+// repository. The first step that fails stops the run.
+func Up(opts WorkflowOptions) error {
+	paths, err := resolvePathsIn(opts.Link.Home, opts.Link.SourceDir, opts.Link.TargetDir)
+	if err != nil {
+		return err
+	}
+
+	// Packages changed by sync and create reload once, at the end
+	if opts.Link.Reload && !opts.Link.DryRun {
+		defer collectReloads(paths.SourceDir, opts.Link.Packages)()
+	}
+
+	steps := opts.Workflow.Steps("up")
+	for i, step := range steps {
+		Trace("step", "command", "up", "step", step)
+		var err error
+		switch step {
+		case StepSync:
+			if !isGitWorkTree(paths.SourceDir) {
+				PrintSkip("Not a git repository, not syncing: %s", ContractPath(paths.SourceDir))
+				continue
+			}
+			err = Sync(opts.Sync)
+		case StepCreate:
+			var results []LinkResult
+			results, err = CreateLinks(opts.Link)
+			PrintCreateSummary(results, paths.SourceDir)
+		case StepPrune:
+			var result *PruneResult
+			result, err = Prune(opts.Link)
+			PrintPruneSummary(result, paths.SourceDir)
+		case StepStatus:
+			err = printLinkSummary(paths.SourceDir, paths.TargetDir)
+		}
+		if err != nil {
+			return workflowStepError("up", step, err)
+		}
+		if i < len(steps)-1 {
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+// Down takes the links down: it removes them (remove) and, when turned on in
+// the workflow or with opts.Link.CleanDirs, removes the empty directories lnk
+// created (clean). The first step that fails stops the run.
+func Down(opts WorkflowOptions) error {
+	steps := opts.Workflow.Steps("down")
+	if opts.Link.CleanDirs && !slices.Contains(steps, StepClean) {
+		steps = append(steps, StepClean)
+	}
+
+	// clean runs as its own step, so remove does not clean too
+	removeOpts := opts.Link
+	removeOpts.CleanDirs = false
+	for i, step := range steps {
+		Trace("step", "command", "down", "step", step)
+		var err error
+		switch step {
+		case StepRemove:
+			err = RemoveLinks(removeOpts)
+		case StepClean:
+			err = Clean(opts.Link)
+		}
+		if err != nil {
+			return workflowStepError("down", step, err)
+		}
+		if i < len(steps)-1 {
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+// workflowStepError names the step that stopped command, keeping the step's
+// hint
+func workflowStepError(command, step string, err error) error {
+	wrapped := fmt.Errorf("%s stopped at %s: %w", command, step, err)
+	if GetErrorHint(err) != "" {
+		return wrapped
+	}
+	return WithHint(wrapped, fmt.Sprintf("Fix the problem and run 'lnk %s' again, or turn the %s step off in %s",
+		command, step, WorkflowFileName))
+}
+
+// printLinkSummary reports how many links the manifest records for sourceDir
+// and how many of them no longer work, without walking the home directory.
+// Missing ephemeral links are expected after a reboot and do not count.
+func printLinkSummary(sourceDir, targetDir string) error {
+	PrintCommandHeader("Link Summary")
+	m, err := LoadManifest(targetDir)
+	if err != nil {
+		return err
+	}
+	if !m.TracksLinks(sourceDir) {
+		PrintEmptyResult("recorded links")
+		return nil
+	}
+
+	total, drifted := 0, 0
+	for _, l := range m.Links {
+		if l.Source != sourceDir {
+			continue
+		}
+		if _, err := fsys.Lstat(l.Path); err != nil && l.Ephemeral {
+			continue
+		}
+		total++
+		if linkDrifted(l.Path, sourceDir) {
+			PrintVerbose("Drifted: %s", ContractPath(l.Path))
+			drifted++
+		}
+	}
+	SummaryCount("managed", total)
+	SummaryCount("drifted", drifted)
+	if drifted == 0 {
+		PrintSuccess("%d link(s) in place", total)
+		return nil
+	}
+	PrintWarning("%d of %d link(s) are missing, replaced, or broken", drifted, total)
+	PrintNextStep("status", sourceDir, "see which")
+	return nil
+}
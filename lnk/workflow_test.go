@@ -0,0 +1,92 @@
+package lnk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadWorkflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantUp   []string
+		wantDown []string
+		wantErr  string
+	}{
+		{"missing", "", []string{"sync", "create", "prune", "status"}, []string{"remove"}, ""},
+		{"toggled", `{"up": {"sync": false}, "down": {"clean": true}}`,
+			[]string{"create", "prune", "status"}, []string{"remove", "clean"}, ""},
+		{"unknown step", `{"up": {"clean": true}}`, nil, nil, "unknown step"},
+		{"unknown key", `{"sideways": {}}`, nil, nil, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceDir := t.TempDir()
+			if tt.content != "" {
+				createTestFile(t, filepath.Join(sourceDir, WorkflowFileName), tt.content)
+			}
+			w, err := LoadWorkflow(sourceDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || GetErrorHint(err) == "" {
+					t.Fatalf("LoadWorkflow() error = %v, want %q with a hint", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadWorkflow() error = %v", err)
+			}
+			if got := strings.Join(w.Steps("up"), ","); got != strings.Join(tt.wantUp, ",") {
+				t.Errorf("Steps(up) = %s, want %v", got, tt.wantUp)
+			}
+			if got := strings.Join(w.Steps("down"), ","); got != strings.Join(tt.wantDown, ",") {
+				t.Errorf("Steps(down) = %s, want %v", got, tt.wantDown)
+			}
+		})
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	targetDir := t.TempDir()
+	sourceDir := filepath.Join(t.TempDir(), "dotfiles")
+	createTestFile(t, filepath.Join(sourceDir, ".bashrc"), "# bashrc")
+	createTestFile(t, filepath.Join(sourceDir, ".config", "nvim", "init.lua"), "-- init")
+	link := LinkOptions{SourceDir: sourceDir, TargetDir: targetDir}
+
+	// A broken link from an earlier run is pruned
+	stale := filepath.Join(targetDir, ".old")
+	if err := os.Symlink(filepath.Join(sourceDir, ".old"), stale); err != nil {
+		t.Fatal(err)
+	}
+
+	out := CaptureOutput(t, func() {
+		if err := Up(WorkflowOptions{Link: link}); err != nil {
+			t.Fatalf("Up() error = %v", err)
+		}
+	})
+	ContainsOutput(t, out, "Not a git repository, not syncing", "Pruned: "+stale, "2 link(s) in place")
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, ".bashrc"))
+	assertNotExists(t, stale)
+
+	// Clean only runs when turned on
+	out = CaptureOutput(t, func() {
+		if err := Down(WorkflowOptions{Link: link}); err != nil {
+			t.Fatalf("Down() error = %v", err)
+		}
+	})
+	NotContainsOutput(t, out, "empty directories to clean")
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+
+	CaptureOutput(t, func() {
+		if err := Up(WorkflowOptions{Link: link, Workflow: &Workflow{Up: map[string]bool{StepPrune: false}}}); err != nil {
+			t.Fatalf("Up() error = %v", err)
+		}
+	})
+	out = CaptureOutput(t, func() {
+		if err := Down(WorkflowOptions{Link: link, Workflow: &Workflow{Down: map[string]bool{StepClean: true}}}); err != nil {
+			t.Fatalf("Down() error = %v", err)
+		}
+	})
+	ContainsOutput(t, out, "No empty directories to clean")
+}
@@ -0,0 +1,158 @@
+package lnk
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Package targets accepted in lnk-package.json
+const (
+	TargetHome    = "home"    // link into the target directory (default)
+	TargetWindows = "windows" // link into the Windows user profile (WSL only)
+	TargetRuntime = "runtime" // link into $XDG_RUNTIME_DIR (ephemeral)
+)
+
+// Windows Subsystem for Linux support. The hooks are package variables so tests
+// can simulate a WSL machine.
+var (
+	// isWSL reports whether lnk is running under WSL
+	isWSL = detectWSL
+
+	// windowsHome returns the Windows user profile directory as a WSL path
+	windowsHome = wslWindowsHome
+
+	// windowsMountPoints lists where Windows drives are mounted (e.g. /mnt/c)
+	windowsMountPoints = procWindowsMounts
+
+	// createWindowsSymlink creates a link that Windows applications can follow
+	createWindowsSymlink = mklinkSymlink
+)
+
+// detectWSL checks the WSL environment variable and the kernel release string
+func detectWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	return err == nil && strings.Contains(strings.ToLower(string(release)), "microsoft")
+}
+
+// wslWindowsHome asks Windows for %USERPROFILE% and translates it with wslpath
+func wslWindowsHome() (string, error) {
+	out, err := exec.Command("cmd.exe", "/c", "echo %USERPROFILE%").Output()
+	if err != nil {
+		return "", WithHint(fmt.Errorf("finding Windows home directory: %w", err),
+			"Make sure Windows interop is enabled so cmd.exe can run from WSL")
+	}
+	return wslpath("-u", strings.TrimSpace(string(out)))
+}
+
+// wslpath converts a path between Windows (-w) and WSL (-u) form
+func wslpath(flag, path string) (string, error) {
+	out, err := exec.Command("wslpath", flag, path).Output()
+	if err != nil {
+		return "", fmt.Errorf("wslpath %s %s: %w", flag, path, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// procWindowsMounts reads /proc/mounts for Windows drive mounts
+func procWindowsMounts() []string {
+	data, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		return nil
+	}
+	return parseWindowsMounts(string(data))
+}
+
+// parseWindowsMounts returns the mount points of drvfs file systems (mounted
+// directly or over 9p) from /proc/mounts content
+func parseWindowsMounts(procMounts string) []string {
+	var mounts []string
+	for _, line := range strings.Split(procMounts, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		mountPoint, fsType, options := fields[1], fields[2], fields[3]
+		if fsType == "drvfs" || fsType == "9p" && strings.Contains(options, "aname=drvfs") {
+			mounts = append(mounts, mountPoint)
+		}
+	}
+	return mounts
+}
+
+// onWindowsMount reports whether path lies on one of the Windows drive mounts
+func onWindowsMount(path string, mounts []string) bool {
+	for _, m := range mounts {
+		if isWithin(path, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// crossesWindowsBoundary returns the planned links whose target is on a
+// Windows drive while the source is in the Linux file system. Windows
+// applications cannot follow such links.
+func crossesWindowsBoundary(links []PlannedLink, mounts []string) []PlannedLink {
+	var crossing []PlannedLink
+	for _, link := range links {
+		if onWindowsMount(link.Target, mounts) && !onWindowsMount(link.Source, mounts) {
+			crossing = append(crossing, link)
+		}
+	}
+	return crossing
+}
+
+// warnWindowsBoundary warns once about links Windows applications cannot follow
+func warnWindowsBoundary(crossing []PlannedLink) {
+	if len(crossing) == 0 {
+		return
+	}
+	for _, link := range crossing {
+		PrintVerbose("Crosses the WSL/Windows boundary: %s -> %s", ContractPath(link.Target), ContractPath(link.Source))
+	}
+	PrintWarningWithHint(WithHint(
+		fmt.Errorf("%d link(s) on a Windows drive point into the WSL file system; Windows applications cannot follow them", len(crossing)),
+		"Use --windows-links to create them with mklink instead"))
+}
+
+// mklinkSymlink creates a Windows symbolic link with cmd.exe's mklink, so
+// Windows applications can follow it. Creating symbolic links on Windows
+// requires Developer Mode or an elevated shell.
+func mklinkSymlink(source, target string) error {
+	if _, err := fsys.Lstat(target); err == nil {
+		if pathComparer.SamePath(target, source) {
+			return LinkExistsError{target: target}
+		}
+		return NewLinkErrorWithHint("create symlink", source, target,
+			fmt.Errorf("file already exists"),
+			fmt.Sprintf("Remove %s and run 'lnk create' again", ContractPath(target)))
+	}
+
+	const interopHint = "Make sure Windows interop is enabled so wslpath and cmd.exe can run from WSL"
+	winSource, err := wslpath("-w", source)
+	if err != nil {
+		return NewLinkErrorWithHint("create symlink", source, target, err, interopHint)
+	}
+	winTarget, err := wslpath("-w", target)
+	if err != nil {
+		return NewLinkErrorWithHint("create symlink", source, target, err, interopHint)
+	}
+
+	if err := checkWritable("create symlink", target); err != nil {
+		return err
+	}
+	cmd := exec.Command("cmd.exe", "/c", "mklink", winTarget, winSource)
+	cmd.Dir = filepath.Dir(target) // cmd.exe cannot start in a WSL directory
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return NewLinkErrorWithHint("create symlink", source, target,
+			fmt.Errorf("mklink: %w: %s", err, strings.TrimSpace(string(out))),
+			"Enable Developer Mode in Windows settings, or run from an elevated shell")
+	}
+	return nil
+}
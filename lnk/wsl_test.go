@@ -0,0 +1,217 @@
+package lnk
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// simulateWSL makes lnk behave as if it were running under WSL, with the
+// Windows home at winHome on a Windows drive mounted at mount
+func simulateWSL(t *testing.T, mount, winHome string) {
+	t.Helper()
+	oldWSL, oldHome, oldMounts := isWSL, windowsHome, windowsMountPoints
+	isWSL = func() bool { return true }
+	windowsHome = func() (string, error) { return winHome, nil }
+	windowsMountPoints = func() []string { return []string{mount} }
+	t.Cleanup(func() { isWSL, windowsHome, windowsMountPoints = oldWSL, oldHome, oldMounts })
+}
+
+func TestParseWindowsMounts(t *testing.T) {
+	procMounts := `none /mnt/wsl tmpfs rw,relatime 0 0
+drvfs /mnt/c 9p rw,noatime,dirsync,aname=drvfs;path=C:\;uid=1000;gid=1000 0 0
+/dev/sdc / ext4 rw,relatime 0 0
+D:\134 /mnt/d drvfs rw,noatime 0 0
+wsl /mnt/wslg 9p rw,relatime,aname=wslg 0 0
+`
+	got := parseWindowsMounts(procMounts)
+	want := []string{"/mnt/c", "/mnt/d"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseWindowsMounts() = %v, want %v", got, want)
+	}
+}
+
+func TestCrossesWindowsBoundary(t *testing.T) {
+	mounts := []string{"/mnt/c"}
+	links := []PlannedLink{
+		{Source: "/home/me/dotfiles/.bashrc", Target: "/home/me/.bashrc"},
+		{Source: "/home/me/dotfiles/win/.wezterm.lua", Target: "/mnt/c/Users/me/.wezterm.lua"},
+		{Source: "/mnt/c/dotfiles/.gitconfig", Target: "/mnt/c/Users/me/.gitconfig"},
+	}
+	got := crossesWindowsBoundary(links, mounts)
+	if len(got) != 1 || got[0].Target != "/mnt/c/Users/me/.wezterm.lua" {
+		t.Errorf("crossesWindowsBoundary() = %v, want only the .wezterm.lua link", got)
+	}
+}
+
+func TestCreateLinksWindowsTargetSkippedOutsideWSL(t *testing.T) {
+	oldWSL := isWSL
+	isWSL = func() bool { return false }
+	t.Cleanup(func() { isWSL = oldWSL })
+
+	sourceDir, targetDir := setupPackagesTest(t)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"target": "windows"}`)
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"shell", "work"},
+	}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	assertNotExists(t, filepath.Join(targetDir, ".gitconfig"))
+
+	opts.WindowsLinks = true
+	if _, err := CreateLinks(opts); err == nil || !strings.Contains(err.Error(), "only supported under WSL") {
+		t.Errorf("CreateLinks(WindowsLinks) outside WSL error = %v", err)
+	}
+}
+
+func TestCreateLinksWindowsTarget(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	mount := t.TempDir()
+	winHome := filepath.Join(mount, "Users", "me")
+	simulateWSL(t, mount, winHome)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"target": "windows"}`)
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"shell", "work"},
+	}
+
+	// Without --windows-links the links are created but a warning explains that
+	// Windows applications cannot follow them
+	stdout, stderr := captureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+	assertSymlink(t, filepath.Join(winHome, ".gitconfig"), filepath.Join(sourceDir, "work", ".gitconfig"))
+	if !strings.Contains(stderr, "1 link(s) on a Windows drive point into the WSL file system") {
+		t.Errorf("expected boundary warning, got stderr: %s", stderr)
+	}
+	if !strings.Contains(stdout, "Created: "+filepath.Join(winHome, ".gitconfig")) {
+		t.Errorf("expected Windows home link in output, got: %s", stdout)
+	}
+
+	// remove finds links in the Windows home too
+	CaptureOutput(t, func() {
+		if err := RemoveLinks(opts); err != nil {
+			t.Fatalf("RemoveLinks() error = %v", err)
+		}
+	})
+	assertNotExists(t, filepath.Join(winHome, ".gitconfig"))
+	assertNotExists(t, filepath.Join(targetDir, ".bashrc"))
+}
+
+func TestStatusWindowsTarget(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	mount := t.TempDir()
+	winHome := filepath.Join(mount, "Users", "me")
+	simulateWSL(t, mount, winHome)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"target": "windows"}`)
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"shell", "work"},
+	}
+	CaptureOutput(t, func() {
+		if _, err := CreateLinks(opts); err != nil {
+			t.Fatalf("CreateLinks() error = %v", err)
+		}
+	})
+
+	// status looks for the links of each package where create made them
+	output := CaptureOutput(t, func() {
+		if err := Status(opts); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+	})
+	ContainsOutput(t, output, "active "+filepath.Join(winHome, ".gitconfig"), "active "+filepath.Join(targetDir, ".bashrc"))
+	NotContainsOutput(t, output, "unlinked")
+}
+
+func TestCreateLinksWindowsLinks(t *testing.T) {
+	sourceDir, targetDir := setupPackagesTest(t)
+	mount := t.TempDir()
+	winHome := filepath.Join(mount, "Users", "me")
+	simulateWSL(t, mount, winHome)
+	createTestFile(t, filepath.Join(sourceDir, "work", PackageInfoFileName), `{"target": "windows"}`)
+
+	var mklinked []string
+	oldCreate := createWindowsSymlink
+	createWindowsSymlink = func(source, target string) error {
+		mklinked = append(mklinked, target)
+		return errors.New("mklink unavailable")
+	}
+	t.Cleanup(func() { createWindowsSymlink = oldCreate })
+
+	opts := LinkOptions{
+		SourceDir:      sourceDir,
+		TargetDir:      targetDir,
+		IgnorePatterns: getBuiltInIgnorePatterns(),
+		Packages:       []string{"shell", "work"},
+		WindowsLinks:   true,
+	}
+	_, stderr := captureOutput(t, func() {
+		if _, err := CreateLinks(opts); err == nil {
+			t.Error("CreateLinks() expected error when mklink fails")
+		}
+	})
+
+	if !slices.Equal(mklinked, []string{filepath.Join(winHome, ".gitconfig")}) {
+		t.Errorf("mklink targets = %v, want only the Windows home link", mklinked)
+	}
+	if strings.Contains(stderr, "Windows applications cannot follow them") {
+		t.Errorf("unexpected boundary warning with --windows-links: %s", stderr)
+	}
+	assertSymlink(t, filepath.Join(targetDir, ".bashrc"), filepath.Join(sourceDir, "shell", ".bashrc"))
+}
+
+func TestPackageTargetDirUnknown(t *testing.T) {
+	_, _, err := packageTargetDir("/src/pkg", "/home/me", &PackageInfo{Target: "mac"})
+	if err == nil || !strings.Contains(err.Error(), "invalid target 'mac'") {
+		t.Errorf("packageTargetDir() error = %v", err)
+	}
+	if hint := GetErrorHint(err); !strings.Contains(hint, "home, windows") {
+		t.Errorf("hint = %q", hint)
+	}
+}
+
+func TestDetectWSLFromEnv(t *testing.T) {
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	if !detectWSL() {
+		t.Error("detectWSL() = false with WSL_DISTRO_NAME set")
+	}
+}
+
+func TestPackageTargetDirRuntime(t *testing.T) {
+	info := &PackageInfo{Target: TargetRuntime}
+	if !info.IsEphemeral() {
+		t.Error("runtime target should be ephemeral")
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	dir, ok, err := packageTargetDir("/src/pkg", "/home/me", info)
+	if err != nil || !ok || dir != "/run/user/1000" {
+		t.Errorf("packageTargetDir() = %q, %v, %v", dir, ok, err)
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	if _, ok, err := packageTargetDir("/src/pkg", "/home/me", info); err != nil || ok {
+		t.Errorf("packageTargetDir() without XDG_RUNTIME_DIR = %v, %v; want skipped", ok, err)
+	}
+}
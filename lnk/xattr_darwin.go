@@ -0,0 +1,56 @@
+//go:build darwin
+
+package lnk
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// xattrNoFollow operates on a symlink itself rather than its target (XATTR_NOFOLLOW)
+const xattrNoFollow = 0x0001
+
+// tagLink sets the LinkTagAttr extended attribute on the symlink at path
+func tagLink(path, source string) error {
+	if err := checkWritable("tag", path); err != nil {
+		return err
+	}
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	name, err := syscall.BytePtrFromString(LinkTagAttr)
+	if err != nil {
+		return err
+	}
+	value := []byte(source)
+	var data unsafe.Pointer
+	if len(value) > 0 {
+		data = unsafe.Pointer(&value[0])
+	}
+	_, _, errno := syscall.Syscall6(syscall.SYS_SETXATTR, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(name)),
+		uintptr(data), uintptr(len(value)), 0, xattrNoFollow)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// linkTag returns the LinkTagAttr extended attribute of the symlink at path
+func linkTag(path string) (string, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+	name, err := syscall.BytePtrFromString(LinkTagAttr)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, 4096)
+	n, _, errno := syscall.Syscall6(syscall.SYS_GETXATTR, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(name)),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0, xattrNoFollow)
+	if errno != 0 {
+		return "", errno
+	}
+	return string(buf[:n]), nil
+}
@@ -0,0 +1,20 @@
+//go:build !darwin
+
+package lnk
+
+import "errors"
+
+// errTagUnsupported is returned where symlinks cannot carry extended
+// attributes (Linux only allows user.* attributes on regular files and
+// directories); the manifest is the only record there
+var errTagUnsupported = errors.New("extended attributes on symlinks are not supported on this platform")
+
+// tagLink is not supported on this platform
+func tagLink(path, source string) error {
+	return errTagUnsupported
+}
+
+// linkTag is not supported on this platform
+func linkTag(path string) (string, error) {
+	return "", errTagUnsupported
+}
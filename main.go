@@ -5,6 +5,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/cpplain/lnk/lnk"
@@ -16,21 +19,89 @@ var (
 )
 
 // validCommands lists all recognized subcommands.
-var validCommands = []string{"create", "remove", "status", "prune", "adopt", "orphan"}
+var validCommands = []string{"create", "ensure", "remove", "status", "prune", "adopt", "orphan", "undo", "clean", "suggest", "report", "sync", "packages", "doctor", "lint", "web", "prompt-status", "shellenv", "eval", "detect", "defaults", "config", "stats", "bundle", "deploy", "rehome", "try", "up", "down", "self-update", "diff-state", "identity", "init", "vars", "query", "gitconfig-local"}
+
+// commandActions lists the actions of commands that take one before <source-dir>
+var commandActions = map[string][]string{
+	"packages": {"list"},
+	"defaults": {"apply", "diff"},
+	"config":   {"explain", "show", "set", "unset", "append"},
+	"stats":    {"show", "enable", "disable", "reset"},
+	"bundle":   {"create", "apply"},
+	"identity": {"init", "rekey"},
+	"vars":     {"list", "set", "unset"},
+}
+
+// valueFlags lists flags that take a separate value argument (--flag value).
+var valueFlags = map[string]bool{
+	"--ignore":            true,
+	"--prefer":            true,
+	"--source":            true,
+	"--fail-on":           true,
+	"--as-of":             true,
+	"--special-files":     true,
+	"--symlink-fallback":  true,
+	"--on-unsupported":    true,
+	"--packages":          true,
+	"--group":             true,
+	"--users":             true,
+	"--log-file":          true,
+	"--output":            true,
+	"--map":               true,
+	"--paths-from":        true,
+	"--summary-file":      true,
+	"--listen":            true,
+	"--shell":             true,
+	"--path-display":      true,
+	"--max-symlink-depth": true,
+	"--channel":           true,
+	"--from-template":     true,
+}
+
+// mutatingCommands lists commands that change files unless run with --dry-run
+// (defaults changes files only with its apply action)
+var mutatingCommands = []string{"create", "ensure", "remove", "prune", "adopt", "orphan", "undo", "clean", "suggest", "sync", "bundle", "deploy", "rehome", "try", "up", "down", "self-update", "identity", "init", "gitconfig-local"}
+
+// publicConfigCommands lists commands that use no setting the private
+// configuration adds to, so they never decrypt it (ensure --fast neither)
+var publicConfigCommands = []string{"prompt-status", "shellenv", "detect", "stats", "packages", "defaults"}
+
+// pagedCommands lists commands whose output goes through a pager when stdout
+// is a terminal (unless --no-pager)
+var pagedCommands = []string{"status", "report", "packages", "lint", "config"}
+
+// switchFlags lists flags that take no value, for shell completion
+var switchFlags = []string{
+	"--dry-run", "--clean-empty-dirs", "--all", "--managed-only", "--interactive", "--sparse", "--force-overwrite", "--no-ignore", "--resume", "--to-copy",
+	"--windows-links", "--replace-identical", "--reload", "--fast", "--insecure-skip-signature", "--shallow", "--remote", "--full-plan", "--stdin-json", "--profile-perf", "--effective", "--strict-config", "--read-only", "--yes", "--verbose",
+	"--no-pager", "--wait", "--absolute-paths", "--no-color", "--version", "--help",
+}
 
 func main() {
 	args := os.Args[1:]
 
+	// LNK_ environment variables; flags given below take precedence
+	env, envErr := lnk.LoadEnv()
+
 	// Extract global flags that must be handled before command dispatch
-	var noColor bool
-	for _, arg := range args {
+	noColor := envErr == nil && env.NoColor
+	jsonErrors := false
+	for i, arg := range args {
 		if arg == "--no-color" {
 			noColor = true
 		}
+		value, ok := strings.CutPrefix(arg, "--output=")
+		if !ok && arg == "--output" && i+1 < len(args) {
+			value, ok = args[i+1], true
+		}
+		if format, _, err := lnk.ParseOutputFormat(value); ok && err == nil && format == lnk.OutputJSON {
+			jsonErrors = true
+		}
 	}
 	if noColor {
 		lnk.SetNoColor(true)
 	}
+	lnk.SetJSONErrors(jsonErrors)
 
 	// Handle --version anywhere in args
 	for _, arg := range args {
@@ -40,12 +111,28 @@ func main() {
 		}
 	}
 
-	// Handle bare `lnk`
+	// Handle bare `lnk`: onboard on first run, otherwise show the usage
 	if len(args) == 0 {
+		if envErr == nil && !env.ReadOnly && lnk.NeedsOnboarding() {
+			onboarded, err := lnk.Onboard()
+			if err != nil {
+				lnk.PrintErrorWithHint(err)
+				os.Exit(lnk.ExitError)
+			}
+			if onboarded {
+				return
+			}
+		}
 		printUsage()
 		return
 	}
 
+	// The hidden __complete command lists candidates for shell completion
+	if args[0] == "__complete" {
+		handleComplete(args[1:])
+		return
+	}
+
 	// Extract the command name and remaining args
 	command, remaining := extractCommand(args)
 
@@ -65,7 +152,7 @@ func main() {
 		lnk.PrintErrorWithHint(lnk.WithHint(
 			fmt.Errorf("no command specified"),
 			"Run 'lnk --help' to see available commands"))
-		os.Exit(lnk.ExitUsage)
+		exit(lnk.ExitUsage)
 	}
 
 	// Validate command name
@@ -80,12 +167,64 @@ func main() {
 				fmt.Errorf("unknown command: %q", command),
 				"Run 'lnk --help' to see available commands"))
 		}
-		os.Exit(lnk.ExitUsage)
+		exit(lnk.ExitUsage)
+	}
+
+	if envErr != nil {
+		lnk.PrintErrorWithHint(envErr)
+		exit(lnk.ExitUsage)
 	}
 
 	// Parse flags and positional arguments from remaining args
 	var ignorePatterns []string
+	var prefer string
+	var scopes []string
+	var failOn []string
+	var specialFiles string
+	var symlinkFallback string
+	var onUnsupported string
+	var packages []string
+	var groups []string
+	var users []string
+	var maps []lnk.Mapping
+	var logFile string
+	var pathsFrom string
+	var summaryFile string
+	var listen string
+	var shell string
+	var channel string
+	var fromTemplate string
+	var pathDisplay []string
+	var absolutePaths bool
+	var output string
+	var outputVersion int
 	var dryRun bool
+	var cleanDirs bool
+	var allLinks, managedOnly bool
+	var interactive bool
+	var sparse bool
+	var forceOverwrite bool
+	var noIgnore bool
+	var resume bool
+	var toCopy bool
+	var windowsLinks bool
+	var replaceIdentical bool
+	var reload bool
+	var fast bool
+	var insecureSkipSignature bool
+	var shallow bool
+	var remote bool
+	var fullPlan bool
+	var stdinJSON bool
+	var asOf string
+	var profilePerf bool
+	var maxSymlinkDepth int
+	var effective bool
+	var strictConfig bool
+	var readOnly bool
+	var noPager bool
+	var wait bool
+	var yes bool
 	var verbose bool
 	var positional []string
 
@@ -98,8 +237,8 @@ func main() {
 			break
 		}
 
-		// Non-flag argument = positional
-		if !strings.HasPrefix(arg, "-") {
+		// Non-flag argument = positional ("-" reads paths from stdin)
+		if !strings.HasPrefix(arg, "-") || arg == lnk.StdinPath {
 			positional = append(positional, arg)
 			continue
 		}
@@ -113,12 +252,276 @@ func main() {
 				lnk.PrintErrorWithHint(lnk.WithHint(
 					fmt.Errorf("--ignore requires a pattern argument"),
 					"Example: lnk create --ignore '*.swp' ."))
-				os.Exit(lnk.ExitUsage)
+				exit(lnk.ExitUsage)
 			}
 			ignorePatterns = append(ignorePatterns, value)
 			i += consumed
+		case "--prefer":
+			if !hasValue || (value != lnk.PreferRepo && value != lnk.PreferLocal) {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--prefer requires 'repo' or 'local'"),
+					"Example: lnk adopt --prefer repo . ~/.bashrc"))
+				exit(lnk.ExitUsage)
+			}
+			prefer = value
+			i += consumed
+		case "--source":
+			if !hasValue {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--source requires a subdirectory argument"),
+					"Example: lnk prune --source home ~/git/dotfiles"))
+				exit(lnk.ExitUsage)
+			}
+			scopes = append(scopes, value)
+			i += consumed
+		case "--as-of":
+			if !hasValue || value == "" {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--as-of requires a time or operation ID"),
+					`Example: lnk status --as-of "2025-06-01 18:00" .`))
+				exit(lnk.ExitUsage)
+			}
+			asOf = value
+			i += consumed
+		case "--fail-on":
+			if !hasValue || !slices.Contains(lnk.FailOnConditions, value) {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--fail-on requires one of: %s", strings.Join(lnk.FailOnConditions, ", ")),
+					"Example: lnk status --fail-on unlinked ."))
+				exit(lnk.ExitUsage)
+			}
+			failOn = append(failOn, value)
+			i += consumed
+		case "--special-files":
+			if !hasValue || (value != lnk.SpecialFilesSkip && value != lnk.SpecialFilesError) {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--special-files requires 'skip' or 'error'"),
+					"Example: lnk create --special-files error ."))
+				exit(lnk.ExitUsage)
+			}
+			specialFiles = value
+			i += consumed
+		case "--symlink-fallback":
+			if !hasValue || (value != lnk.SymlinkFallbackError && value != lnk.SymlinkFallbackCopy) {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--symlink-fallback requires 'error' or 'copy'"),
+					"Example: lnk create --symlink-fallback copy ."))
+				exit(lnk.ExitUsage)
+			}
+			symlinkFallback = value
+			i += consumed
+		case "--on-unsupported":
+			if !hasValue || (value != lnk.OnUnsupportedDegrade && value != lnk.OnUnsupportedAbort) {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--on-unsupported requires 'degrade' or 'abort'"),
+					"Example: lnk create --on-unsupported abort ."))
+				exit(lnk.ExitUsage)
+			}
+			onUnsupported = value
+			i += consumed
+		case "--log-file":
+			if !hasValue || value == "" {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--log-file requires a file path"),
+					"Example: lnk create --log-file ~/lnk.log ."))
+				exit(lnk.ExitUsage)
+			}
+			logFile = value
+			i += consumed
+		case "--paths-from":
+			if !hasValue || value == "" {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--paths-from requires a file path, or - for standard input"),
+					"Example: fd -0 -t l . ~/.config | lnk remove --paths-from - ~/git/dotfiles"))
+				exit(lnk.ExitUsage)
+			}
+			pathsFrom = value
+			i += consumed
+		case "--summary-file":
+			if !hasValue || value == "" {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--summary-file requires a file path"),
+					"Example: lnk create --summary-file /tmp/lnk-summary.json ."))
+				exit(lnk.ExitUsage)
+			}
+			summaryFile = value
+			i += consumed
+		case "--listen":
+			if !hasValue || value == "" {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--listen requires a HOST:PORT address"),
+					"Example: lnk web --listen 127.0.0.1:7475 ."))
+				exit(lnk.ExitUsage)
+			}
+			listen = value
+			i += consumed
+		case "--max-symlink-depth":
+			depth, err := strconv.Atoi(value)
+			if !hasValue || err != nil || depth < 1 {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--max-symlink-depth requires a positive number"),
+					fmt.Sprintf("Example: lnk create --max-symlink-depth %d .", lnk.DefaultMaxSymlinkDepth)))
+				exit(lnk.ExitUsage)
+			}
+			maxSymlinkDepth = depth
+			i += consumed
+		case "--shell":
+			if !hasValue || !slices.Contains(lnk.Shells, value) {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--shell requires one of: %s", strings.Join(lnk.Shells, ", ")),
+					"Example: lnk prompt-status --shell zsh ~/git/dotfiles"))
+				exit(lnk.ExitUsage)
+			}
+			shell = value
+			i += consumed
+		case "--channel":
+			if !hasValue || !slices.Contains(lnk.Channels, value) {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--channel requires one of: %s", strings.Join(lnk.Channels, ", ")),
+					"Example: lnk self-update --channel prerelease"))
+				exit(lnk.ExitUsage)
+			}
+			channel = value
+			i += consumed
+		case "--from-template":
+			if !hasValue || strings.TrimSpace(value) == "" {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--from-template requires a GitHub repository"),
+					"Example: lnk init --from-template OWNER/REPO ~/dotfiles"))
+				exit(lnk.ExitUsage)
+			}
+			fromTemplate = value
+			i += consumed
+		case "--path-display":
+			if !hasValue || value == "" {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--path-display requires one or more of: %s", strings.Join(lnk.PathDisplays, ", ")),
+					"Example: lnk status --path-display xdg,repo ~/git/dotfiles"))
+				exit(lnk.ExitUsage)
+			}
+			pathDisplay = append(pathDisplay, strings.Split(value, ",")...)
+			i += consumed
+		case "--output":
+			if !hasValue {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--output requires one of: %s", strings.Join(lnk.OutputFormats, ", ")),
+					"Example: lnk config show --effective --output yaml ."))
+				exit(lnk.ExitUsage)
+			}
+			format, version, err := lnk.ParseOutputFormat(value)
+			if err != nil {
+				lnk.PrintErrorWithHint(err)
+				exit(lnk.ExitUsage)
+			}
+			output, outputVersion = format, version
+			i += consumed
+		case "--map":
+			if !hasValue {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--map requires a SRC:TGT argument"),
+					"Example: lnk create --map ~/projects/foo/config:.config/foo ."))
+				exit(lnk.ExitUsage)
+			}
+			m, err := lnk.ParseMapping(value)
+			if err != nil {
+				lnk.PrintErrorWithHint(err)
+				exit(lnk.ExitUsage)
+			}
+			maps = append(maps, m)
+			i += consumed
+		case "--packages":
+			if !hasValue || strings.Trim(value, ", ") == "" {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--packages requires a comma-separated list of packages"),
+					"Example: lnk create --packages shell,nvim ~/git/dotfiles"))
+				exit(lnk.ExitUsage)
+			}
+			for _, pkg := range strings.Split(value, ",") {
+				if pkg = strings.TrimSpace(pkg); pkg != "" {
+					packages = append(packages, pkg)
+				}
+			}
+			i += consumed
+		case "--group":
+			if !hasValue || strings.Trim(value, ", ") == "" {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--group requires a comma-separated list of link groups"),
+					"Example: lnk create --group shell,gui ~/git/dotfiles"))
+				exit(lnk.ExitUsage)
+			}
+			for _, group := range strings.Split(value, ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					groups = append(groups, group)
+				}
+			}
+			i += consumed
+		case "--users":
+			if !hasValue || strings.Trim(value, ", ") == "" {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("--users requires a comma-separated list of user names"),
+					"Example: sudo lnk deploy --users alice,bob /srv/dotfiles"))
+				exit(lnk.ExitUsage)
+			}
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					users = append(users, name)
+				}
+			}
+			i += consumed
 		case "-n", "--dry-run":
 			dryRun = true
+		case "--clean-empty-dirs":
+			cleanDirs = true
+		case "--all":
+			allLinks = true
+		case "--managed-only":
+			managedOnly = true
+		case "--interactive":
+			interactive = true
+		case "--sparse":
+			sparse = true
+		case "--force-overwrite":
+			forceOverwrite = true
+		case "--no-ignore":
+			noIgnore = true
+		case "--resume":
+			resume = true
+		case "--to-copy":
+			toCopy = true
+		case "--windows-links":
+			windowsLinks = true
+		case "--replace-identical":
+			replaceIdentical = true
+		case "--reload":
+			reload = true
+		case "--fast":
+			fast = true
+		case "--insecure-skip-signature":
+			insecureSkipSignature = true
+		case "--shallow":
+			shallow = true
+		case "--remote":
+			remote = true
+		case "--full-plan":
+			fullPlan = true
+		case "--stdin-json":
+			stdinJSON = true
+		case "--profile-perf":
+			profilePerf = true
+		case "--effective":
+			effective = true
+		case "--strict-config":
+			strictConfig = true
+		case "--read-only":
+			readOnly = true
+		case "--no-pager":
+			noPager = true
+		case "--wait":
+			wait = true
+		case "--absolute-paths":
+			absolutePaths = true
+		case "-y", "--yes":
+			yes = true
 		case "-v", "--verbose":
 			verbose = true
 		case "--no-color":
@@ -129,408 +532,2716 @@ func main() {
 			lnk.PrintErrorWithHint(lnk.WithHint(
 				fmt.Errorf("unknown flag: %s", flag),
 				fmt.Sprintf("Run 'lnk %s --help' to see available flags", command)))
-			os.Exit(lnk.ExitUsage)
+			exit(lnk.ExitUsage)
+		}
+	}
+
+	if allLinks && managedOnly {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("--all and --managed-only cannot be used together"),
+			"Use --all to also remove links lnk did not create"))
+		exit(lnk.ExitUsage)
+	}
+
+	// --read-only refuses every write, including lnk's own output files
+	readOnly = readOnly || env.ReadOnly
+	if readOnly && (logFile != "" || summaryFile != "") {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("--log-file and --summary-file write files, which --read-only refuses"),
+			"Drop --read-only, or leave out the output files"))
+		exit(lnk.ExitUsage)
+	}
+	lnk.SetReadOnly(readOnly)
+	lnk.SetLinkBatching(!env.NoBatch)
+	lnk.SetSourceFetching(!env.NoFetch)
+	lnk.SetTemplateFuncs(env.TemplateFuncs)
+	if profilePerf {
+		lnk.StartProfile()
+	}
+
+	if summaryFile != "" {
+		if err := lnk.StartSummary(summaryFile, command, dryRun); err != nil {
+			lnk.PrintErrorWithHint(err)
+			exit(lnk.ExitUsage)
 		}
 	}
 
 	// Set verbosity level
-	if verbose {
+	if verbose || env.Verbose {
 		lnk.SetVerbosity(lnk.VerbosityVerbose)
 	}
+	lnk.SetVersion(version)
+	lnk.SetStrictConfig(strictConfig)
+	lnk.SetMaxSymlinkDepth(maxSymlinkDepth)
+	display := env.PathDisplay
+	if pathDisplay != nil {
+		var err error
+		if display, err = lnk.ParsePathDisplay(pathDisplay); err != nil {
+			lnk.PrintErrorWithHint(err)
+			exit(lnk.ExitUsage)
+		}
+	}
+	display.Absolute = display.Absolute || absolutePaths
+	lnk.SetPathDisplay(display)
+	lnk.SetAssumeYes(yes || env.Yes)
+	for _, warning := range lnk.UnknownEnvVars() {
+		lnk.PrintWarningWithHint(warning)
+	}
+
+	// Some commands take an action before <source-dir>
+	usageCommand := command
+	var action string
+	if actions, ok := commandActions[command]; ok {
+		usageCommand = command + " " + strings.Join(actions, "|")
+		if len(positional) > 0 {
+			if !slices.Contains(actions, positional[0]) {
+				lnk.PrintErrorWithHint(lnk.WithHint(
+					fmt.Errorf("unknown %s action: %q", command, positional[0]),
+					fmt.Sprintf("Usage: lnk %s [flags] <source-dir>", usageCommand)))
+				exit(lnk.ExitUsage)
+			}
+			action, positional = positional[0], positional[1:]
+		}
+	}
+
+	// Commands that change files only run as a preview in read-only mode; the
+	// guarded writes in lnk enforce it regardless
+	if readOnly && !dryRun && (slices.Contains(mutatingCommands, command) || (command == "defaults" && action == "apply") ||
+		(command == "stats" && action != "show") || (command == "config" && action != "explain" && action != "show") ||
+		(command == "vars" && action != "list")) {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("%s changes files, which --read-only refuses", strings.TrimSpace(command+" "+action)),
+			"Preview it with --dry-run, or run without --read-only"))
+		exit(lnk.ExitUsage)
+	}
+
+	// self-update replaces the binary, not anything in a source directory
+	if command == "self-update" {
+		handleSelfUpdate(channel, dryRun, insecureSkipSignature, positional)
+		exit(0)
+	}
+
+	// eval evaluates against this machine, not anything in a source directory
+	if command == "eval" {
+		handleEval(positional)
+		exit(0)
+	}
+
+	// diff-state compares manifest history, which belongs to the home
+	// directory rather than a source directory
+	if command == "diff-state" {
+		handleDiffState(env, positional)
+		exit(0)
+	}
 
-	// All commands require source-dir as first positional argument
-	if len(positional) == 0 {
+	// All other commands require source-dir as first positional argument
+	if len(positional) == 0 || (command == "identity" || command == "vars") && action == "" {
 		lnk.PrintErrorWithHint(lnk.WithHint(
 			fmt.Errorf("missing required argument: <source-dir>"),
-			fmt.Sprintf("Usage: lnk %s [flags] <source-dir>", command)))
-		os.Exit(lnk.ExitUsage)
+			fmt.Sprintf("Usage: lnk %s [flags] <source-dir>", usageCommand)))
+		exit(lnk.ExitUsage)
 	}
 
 	sourceDir := positional[0]
-	paths := positional[1:] // remaining positional args (for adopt/orphan)
+	paths := positional[1:] // remaining positional args (for adopt/orphan/remove)
+
+	// identity runs without loading the configuration: on a new machine the
+	// private configuration cannot be decrypted until it has
+	if command == "identity" {
+		handleIdentity(action, dryRun, sourceDir, paths)
+		exit(0)
+	}
+
+	// vars only reads and writes the template variables in .lnk
+	if command == "vars" {
+		handleVars(action, dryRun, sourceDir, paths)
+		exit(0)
+	}
+
+	// init creates the source directory, so there is no configuration to load
+	if command == "init" {
+		handleInit(fromTemplate, dryRun, sourceDir, paths)
+		exit(0)
+	}
+
+	// adopt, orphan, and remove take path lists from "-" and --paths-from
+	if command == "adopt" || command == "orphan" || command == "remove" {
+		expanded, err := lnk.ExpandPathArgs(paths, pathsFrom, os.Stdin)
+		if err != nil {
+			lnk.PrintErrorWithHint(err)
+			exit(lnk.ExitUsage)
+		}
+		paths = expanded
+	} else if pathsFrom != "" {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("--paths-from is only supported by adopt, orphan, and remove"),
+			fmt.Sprintf("Usage: lnk %s [flags] <source-dir>", usageCommand)))
+		exit(lnk.ExitUsage)
+	}
+
+	if logFile != "" {
+		closer, err := lnk.OpenLogFile(logFile)
+		if err != nil {
+			lnk.PrintErrorWithHint(err)
+			exit(lnk.ExitError)
+		}
+		defer closer.Close()
+	}
+	lnk.Trace("start", "command", command, "version", version, "source_dir", sourceDir)
 
 	// Load configuration (resolves sourceDir, loads ignore patterns)
+	endConfig := lnk.TracePhase("config")
 	config, err := lnk.LoadConfig(sourceDir, ignorePatterns)
 	if err != nil {
 		lnk.PrintErrorWithHint(err)
-		os.Exit(lnk.ExitError)
+		exit(lnk.ExitError)
+	}
+	if !slices.Contains(publicConfigCommands, command) && !(command == "ensure" && fast) {
+		if err := config.LoadPrivate(); err != nil {
+			lnk.PrintErrorWithHint(err)
+			exit(lnk.ExitError)
+		}
+	}
+
+	// --packages overrides the default packages from .lnkpackages
+	cliPackages := packages
+	packages, from := config.ResolvePackages(cliPackages)
+	value := strings.Join(packages, ",")
+	if from == "default" {
+		value = "whole source directory"
+	}
+	lnk.Trace("precedence", "setting", "packages", "from", from, "value", value)
+
+	// Mappings saved in .lnkmaps apply like a --map given every time
+	saved := slices.Clone(config.Maps)
+	for _, m := range maps {
+		if !slices.ContainsFunc(saved, m.Same) {
+			saved = append(saved, m)
+		}
+	}
+	maps = saved
+	endConfig()
+	lnk.SetSummarySourceDir(config.SourceDir)
+	lnk.SetDisplayRepoRoot(config.SourceDir)
+
+	// Protected targets are refused whatever the command and its mappings
+	lnk.ProtectTargets(config.TargetDir, config.Protected)
+	if command != "stats" {
+		lnk.StartStats(command, config.TargetDir)
+	}
+	lnk.SetOperation(strings.TrimSpace(command + " " + action))
+
+	// Commands that change files hold the lock on the target directory, so two
+	// runs never change a shared home at once, and refuse to clobber paths
+	// another program changes while they run
+	if !dryRun && (slices.Contains(mutatingCommands, command) || (command == "defaults" && action == "apply")) {
+		if err := lnk.AcquireLock(config.TargetDir, command, wait); err != nil {
+			lnk.PrintErrorWithHint(err)
+			exit(lnk.ExitError)
+		}
+		lnk.GuardConcurrentEdits()
+	}
+
+	if !noPager && slices.Contains(pagedCommands, command) {
+		lnk.StartPager(lnk.PagerCommand(env.Pager))
 	}
 
 	// Dispatch to command handler
 	switch command {
 	case "create":
-		handleCreate(config, dryRun, paths)
+		handleCreate(config, dryRun, fullPlan, windowsLinks, replaceIdentical, reload, specialFiles, symlinkFallback, onUnsupported, packages, groups, maps, paths)
+	case "ensure":
+		handleEnsure(config, dryRun, fast, packages, paths)
 	case "remove":
-		handleRemove(config, dryRun, paths)
+		handleRemove(config, dryRun, cleanDirs, allLinks, interactive, packages, groups, maps, paths)
 	case "status":
-		handleStatus(config, paths)
+		handleStatus(config, shallow, remote, asOf, output, outputVersion, failOn, packages, groups, maps, paths)
 	case "prune":
-		handlePrune(config, dryRun, paths)
+		handlePrune(config, dryRun, interactive, scopes, paths)
 	case "adopt":
-		handleAdopt(config, dryRun, paths)
+		handleAdopt(config, dryRun, noIgnore, resume, prefer, paths)
 	case "orphan":
-		handleOrphan(config, dryRun, paths)
+		handleOrphan(config, dryRun, toCopy, paths)
+	case "undo":
+		handleUndo(config, dryRun, paths)
+	case "clean":
+		handleClean(config, dryRun, paths)
+	case "suggest":
+		handleSuggest(config, dryRun, paths)
+	case "report":
+		handleReport(config, paths)
+	case "sync":
+		handleSync(config, dryRun, sparse, forceOverwrite, reload, packages, paths)
+	case "packages":
+		handlePackages(config, action, packages, paths)
+	case "doctor":
+		handleDoctor(config, packages, paths)
+	case "lint":
+		handleLint(config, packages, paths)
+	case "web":
+		handleWeb(config, listen, packages, maps, paths)
+	case "query":
+		handleQuery(config, stdinJSON, packages, maps, paths)
+	case "prompt-status":
+		handlePromptStatus(config, shell, paths)
+	case "shellenv":
+		handleShellenv(config, shell, cliPackages, ignorePatterns, slices.Contains(args, "--no-color"), paths)
+	case "detect":
+		handleDetect(config, paths)
+	case "defaults":
+		handleDefaults(config, action, dryRun, packages, paths)
+	case "config":
+		handleConfig(config, action, dryRun, effective, output, outputVersion, cliPackages, paths)
+	case "stats":
+		handleStats(config, action, paths)
+	case "bundle":
+		handleBundle(config, action, dryRun, packages, from, ignorePatterns, paths)
+	case "rehome":
+		handleRehome(config, dryRun, paths)
+	case "try":
+		handleTry(config, dryRun, specialFiles, packages, paths)
+	case "up":
+		handleUp(config, dryRun, fullPlan, sparse, forceOverwrite, replaceIdentical, reload, specialFiles, symlinkFallback, onUnsupported, packages, maps, paths)
+	case "down":
+		handleDown(config, dryRun, cleanDirs, allLinks, packages, maps, paths)
+	case "deploy":
+		handleDeploy(config, dryRun, specialFiles, symlinkFallback, onUnsupported, users, packages, paths)
+	case "gitconfig-local":
+		handleGitconfigLocal(config, dryRun, paths)
+	}
+
+	lnk.StopPager()
+	lnk.ReleaseLock()
+	lnk.WriteProblemReport(os.Stderr)
+	lnk.WriteProfile(os.Stderr)
+	lnk.RecordStats(0)
+	if err := lnk.WriteSummary(0); err != nil {
+		lnk.PrintErrorWithHint(err)
+		os.Exit(lnk.ExitError)
 	}
 }
 
-func handleCreate(config *lnk.Config, dryRun bool, extra []string) {
+func handleCreate(config *lnk.Config, dryRun, fullPlan, windowsLinks, replaceIdentical, reload bool, specialFiles, symlinkFallback, onUnsupported string, packages, groups []string, maps []lnk.Mapping, extra []string) {
 	if len(extra) > 0 {
 		lnk.PrintErrorWithHint(lnk.WithHint(
 			fmt.Errorf("create takes exactly one argument: <source-dir>"),
 			"Usage: lnk create [flags] <source-dir>"))
-		os.Exit(lnk.ExitUsage)
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.LinkOptions{
+		SourceDir:        config.SourceDir,
+		TargetDir:        config.TargetDir,
+		IgnorePatterns:   config.IgnorePatterns,
+		SpecialFiles:     specialFiles,
+		SymlinkFallback:  symlinkFallback,
+		OnUnsupported:    onUnsupported,
+		Packages:         packages,
+		Groups:           groups,
+		Maps:             maps,
+		LocalOnly:        config.LocalOnly,
+		Dirs:             config.Dirs,
+		WindowsLinks:     windowsLinks,
+		ReplaceIdentical: replaceIdentical,
+		Reload:           reload,
+		FullPlan:         fullPlan,
+		DryRun:           dryRun,
+	}
+	results, err := lnk.CreateLinks(opts)
+	lnk.PrintCreateSummary(results, config.SourceDir)
+	if err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleEnsure(config *lnk.Config, dryRun, fast bool, packages []string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("ensure takes exactly one argument: <source-dir>"),
+			"Usage: lnk ensure [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
 	}
 	opts := lnk.LinkOptions{
 		SourceDir:      config.SourceDir,
 		TargetDir:      config.TargetDir,
 		IgnorePatterns: config.IgnorePatterns,
+		Packages:       packages,
+		LocalOnly:      config.LocalOnly,
+		Dirs:           config.Dirs,
+		Fast:           fast,
 		DryRun:         dryRun,
 	}
-	if err := lnk.CreateLinks(opts); err != nil {
+	if err := lnk.Ensure(opts); err != nil {
 		lnk.PrintErrorWithHint(err)
-		os.Exit(lnk.ExitError)
+		exit(lnk.ExitError)
 	}
 }
 
-func handleRemove(config *lnk.Config, dryRun bool, extra []string) {
-	if len(extra) > 0 {
-		lnk.PrintErrorWithHint(lnk.WithHint(
-			fmt.Errorf("remove takes exactly one argument: <source-dir>"),
-			"Usage: lnk remove [flags] <source-dir>"))
-		os.Exit(lnk.ExitUsage)
-	}
+func handleRemove(config *lnk.Config, dryRun, cleanDirs, allLinks, interactive bool, packages, groups []string, maps []lnk.Mapping, paths []string) {
 	opts := lnk.LinkOptions{
 		SourceDir:      config.SourceDir,
 		TargetDir:      config.TargetDir,
 		IgnorePatterns: config.IgnorePatterns,
+		CleanDirs:      cleanDirs,
+		AllLinks:       allLinks,
+		Interactive:    interactive,
+		Packages:       packages,
+		Groups:         groups,
+		Maps:           maps,
+		Paths:          paths,
 		DryRun:         dryRun,
 	}
 	if err := lnk.RemoveLinks(opts); err != nil {
 		lnk.PrintErrorWithHint(err)
-		os.Exit(lnk.ExitError)
+		exit(lnk.ExitError)
 	}
 }
 
-func handleStatus(config *lnk.Config, extra []string) {
+func handleStatus(config *lnk.Config, shallow, remote bool, asOf, output string, outputVersion int, failOn, packages, groups []string, maps []lnk.Mapping, extra []string) {
 	if len(extra) > 0 {
 		lnk.PrintErrorWithHint(lnk.WithHint(
 			fmt.Errorf("status takes exactly one argument: <source-dir>"),
 			"Usage: lnk status [flags] <source-dir>"))
-		os.Exit(lnk.ExitUsage)
+		exit(lnk.ExitUsage)
+	}
+	if output == lnk.OutputYAML {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("status supports --output json only"),
+			"Example: lnk status --output json=v1 ."))
+		exit(lnk.ExitUsage)
 	}
 	opts := lnk.LinkOptions{
 		SourceDir:      config.SourceDir,
 		TargetDir:      config.TargetDir,
 		IgnorePatterns: config.IgnorePatterns,
+		FailOn:         failOn,
+		Packages:       packages,
+		Groups:         groups,
+		Maps:           maps,
+		LocalOnly:      config.LocalOnly,
+		Shallow:        shallow,
+		Remote:         remote,
+		AsOf:           asOf,
+		Output:         output,
+		SchemaVersion:  outputVersion,
 	}
 	if err := lnk.Status(opts); err != nil {
 		lnk.PrintErrorWithHint(err)
-		os.Exit(lnk.ExitError)
+		exit(lnk.ExitError)
 	}
 }
 
-func handlePrune(config *lnk.Config, dryRun bool, extra []string) {
+func handlePrune(config *lnk.Config, dryRun, interactive bool, scopes []string, extra []string) {
 	if len(extra) > 0 {
 		lnk.PrintErrorWithHint(lnk.WithHint(
 			fmt.Errorf("prune takes exactly one argument: <source-dir>"),
 			"Usage: lnk prune [flags] <source-dir>"))
-		os.Exit(lnk.ExitUsage)
+		exit(lnk.ExitUsage)
 	}
 	opts := lnk.LinkOptions{
 		SourceDir:      config.SourceDir,
 		TargetDir:      config.TargetDir,
 		IgnorePatterns: config.IgnorePatterns,
+		Scopes:         scopes,
+		Interactive:    interactive,
 		DryRun:         dryRun,
 	}
-	if err := lnk.Prune(opts); err != nil {
+	result, err := lnk.Prune(opts)
+	lnk.PrintPruneSummary(result, config.SourceDir)
+	if err != nil {
 		lnk.PrintErrorWithHint(err)
-		os.Exit(lnk.ExitError)
+		exit(lnk.ExitError)
 	}
 }
 
-func handleAdopt(config *lnk.Config, dryRun bool, paths []string) {
+func handleAdopt(config *lnk.Config, dryRun, noIgnore, resume bool, prefer string, paths []string) {
 	if len(paths) == 0 {
 		lnk.PrintErrorWithHint(lnk.WithHint(
 			fmt.Errorf("adopt requires at least one file path after <source-dir>"),
 			"Usage: lnk adopt [flags] <source-dir> <path...>"))
-		os.Exit(lnk.ExitUsage)
+		exit(lnk.ExitUsage)
 	}
 	opts := lnk.AdoptOptions{
-		SourceDir: config.SourceDir,
-		TargetDir: config.TargetDir,
-		Paths:     paths,
-		DryRun:    dryRun,
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		Paths:          paths,
+		Prefer:         prefer,
+		LocalOnly:      config.LocalOnly,
+		Sensitive:      config.Sensitive,
+		IgnorePatterns: config.IgnorePatterns,
+		Resume:         resume,
+		DryRun:         dryRun,
+	}
+	if noIgnore {
+		opts.IgnorePatterns = nil
 	}
-	if err := lnk.Adopt(opts); err != nil {
+	result, err := lnk.Adopt(opts)
+	lnk.PrintAdoptSummary(result, config.SourceDir)
+	if err != nil {
 		lnk.PrintErrorWithHint(err)
-		os.Exit(lnk.ExitError)
+		exit(lnk.ExitError)
 	}
 }
 
-func handleOrphan(config *lnk.Config, dryRun bool, paths []string) {
+func handleOrphan(config *lnk.Config, dryRun, toCopy bool, paths []string) {
 	if len(paths) == 0 {
 		lnk.PrintErrorWithHint(lnk.WithHint(
 			fmt.Errorf("orphan requires at least one path after <source-dir>"),
 			"Usage: lnk orphan [flags] <source-dir> <path...>"))
-		os.Exit(lnk.ExitUsage)
+		exit(lnk.ExitUsage)
 	}
 	opts := lnk.OrphanOptions{
 		SourceDir: config.SourceDir,
 		TargetDir: config.TargetDir,
 		Paths:     paths,
+		ToCopy:    toCopy,
 		DryRun:    dryRun,
 	}
 	if err := lnk.Orphan(opts); err != nil {
 		lnk.PrintErrorWithHint(err)
-		os.Exit(lnk.ExitError)
+		exit(lnk.ExitError)
 	}
 }
 
-// parseFlagValue parses a flag that might be in --flag=value or --flag value format.
-// Returns the flag name, value, whether a value was found, and how many extra args were consumed.
-func parseFlagValue(arg string, args []string, index int) (flag string, value string, hasValue bool, consumed int) {
-	// Check for --flag=value format
-	if idx := strings.Index(arg, "="); idx > 0 {
-		return arg[:idx], arg[idx+1:], true, 0
+func handleUndo(config *lnk.Config, dryRun bool, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("undo takes exactly one argument: <source-dir>"),
+			"Usage: lnk undo [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
 	}
-
-	// Check for --flag value format
-	if index+1 < len(args) && !strings.HasPrefix(args[index+1], "-") {
-		return arg, args[index+1], true, 1
+	opts := lnk.LinkOptions{
+		SourceDir: config.SourceDir,
+		TargetDir: config.TargetDir,
+		DryRun:    dryRun,
 	}
-
-	return arg, "", false, 0
-}
-
-// extractCommand finds the command name in args, returning it and the remaining args.
-// The command is the first non-flag token that matches a valid command name or
-// appears to be a command (not starting with -).
-func extractCommand(args []string) (string, []string) {
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-
-		// Stop at --
-		if arg == "--" {
-			break
-		}
-
-		// Skip flags
-		if strings.HasPrefix(arg, "-") {
-			// Skip value of flags that take values (--ignore pattern or --ignore=pattern)
-			if arg == "--ignore" && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				i++ // skip the value token so it isn't mistaken for a command
-			}
-			continue
-		}
-
-		// Found a non-flag token — this is the command
-		remaining := make([]string, 0, len(args)-1)
-		remaining = append(remaining, args[:i]...)
-		remaining = append(remaining, args[i+1:]...)
-		return arg, remaining
+	if err := lnk.Undo(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
 	}
-
-	return "", args
 }
 
-// isValidCommand returns true if name is a recognized command.
-func isValidCommand(name string) bool {
-	for _, cmd := range validCommands {
-		if cmd == name {
-			return true
-		}
+func handleClean(config *lnk.Config, dryRun bool, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("clean takes exactly one argument: <source-dir>"),
+			"Usage: lnk clean [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
 	}
-	return false
-}
-
-// suggestCommand returns the closest valid command name to input, or empty string
-// if no suggestion is close enough.
-func suggestCommand(input string) string {
-	threshold := len(input)/2 + 1
-	best, bestDist := "", threshold+1
-	for _, cmd := range validCommands {
-		if d := levenshteinDistance(input, cmd); d < bestDist {
-			best, bestDist = cmd, d
-		}
+	opts := lnk.LinkOptions{
+		SourceDir: config.SourceDir,
+		TargetDir: config.TargetDir,
+		DryRun:    dryRun,
+	}
+	if err := lnk.Clean(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
 	}
-	return best
 }
 
-// levenshteinDistance computes the edit distance between two strings.
-func levenshteinDistance(a, b string) int {
-	la, lb := len(a), len(b)
-	if la == 0 {
-		return lb
+func handleRehome(config *lnk.Config, dryRun bool, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("rehome takes exactly one argument: <source-dir>"),
+			"Usage: lnk rehome [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
 	}
-	if lb == 0 {
-		return la
+	opts := lnk.LinkOptions{
+		SourceDir: config.SourceDir,
+		TargetDir: config.TargetDir,
+		DryRun:    dryRun,
 	}
+	if err := lnk.Rehome(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleGitconfigLocal(config *lnk.Config, dryRun bool, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("gitconfig-local takes exactly one argument: <source-dir>"),
+			"Usage: lnk gitconfig-local [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.GitLocalOptions{
+		SourceDir: config.SourceDir,
+		TargetDir: config.TargetDir,
+		DryRun:    dryRun,
+	}
+	if err := lnk.GenerateGitLocal(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleTry(config *lnk.Config, dryRun bool, specialFiles string, packages []string, args []string) {
+	if len(args) != 2 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("try takes three arguments: <source-dir> <src> <tgt>"),
+			"Usage: lnk try [flags] <source-dir> <src> <tgt>"))
+		exit(lnk.ExitUsage)
+	}
+	m, err := lnk.ParseMapping(args[0] + ":" + args[1])
+	if err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.LinkOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		IgnorePatterns: config.IgnorePatterns,
+		SpecialFiles:   specialFiles,
+		Packages:       packages,
+		Maps:           config.Maps,
+		LocalOnly:      config.LocalOnly,
+		DryRun:         dryRun,
+	}
+	if err := lnk.TryMapping(opts, m); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleUp(config *lnk.Config, dryRun, fullPlan, sparse, forceOverwrite, replaceIdentical, reload bool, specialFiles, symlinkFallback, onUnsupported string, packages []string, maps []lnk.Mapping, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("up takes exactly one argument: <source-dir>"),
+			"Usage: lnk up [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.WorkflowOptions{
+		Link: lnk.LinkOptions{
+			SourceDir:        config.SourceDir,
+			TargetDir:        config.TargetDir,
+			IgnorePatterns:   config.IgnorePatterns,
+			SpecialFiles:     specialFiles,
+			SymlinkFallback:  symlinkFallback,
+			OnUnsupported:    onUnsupported,
+			Packages:         packages,
+			Maps:             maps,
+			LocalOnly:        config.LocalOnly,
+			Dirs:             config.Dirs,
+			ReplaceIdentical: replaceIdentical,
+			Reload:           reload,
+			FullPlan:         fullPlan,
+			DryRun:           dryRun,
+		},
+		Sync: lnk.SyncOptions{
+			SourceDir:      config.SourceDir,
+			TargetDir:      config.TargetDir,
+			Packages:       packages,
+			Sparse:         sparse,
+			ForceOverwrite: forceOverwrite,
+			DryRun:         dryRun,
+		},
+		Workflow: config.Workflow,
+	}
+	if err := lnk.Up(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleDown(config *lnk.Config, dryRun, cleanDirs, allLinks bool, packages []string, maps []lnk.Mapping, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("down takes exactly one argument: <source-dir>"),
+			"Usage: lnk down [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.WorkflowOptions{
+		Link: lnk.LinkOptions{
+			SourceDir:      config.SourceDir,
+			TargetDir:      config.TargetDir,
+			IgnorePatterns: config.IgnorePatterns,
+			CleanDirs:      cleanDirs,
+			AllLinks:       allLinks,
+			Packages:       packages,
+			Maps:           maps,
+			DryRun:         dryRun,
+		},
+		Workflow: config.Workflow,
+	}
+	if err := lnk.Down(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleSuggest(config *lnk.Config, dryRun bool, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("suggest takes exactly one argument: <source-dir>"),
+			"Usage: lnk suggest [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.SuggestOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		IgnorePatterns: config.IgnorePatterns,
+		LocalOnly:      config.LocalOnly,
+		Sensitive:      config.Sensitive,
+		DryRun:         dryRun,
+	}
+	if err := lnk.Suggest(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleReport(config *lnk.Config, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("report takes exactly one argument: <source-dir>"),
+			"Usage: lnk report [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.LinkOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		IgnorePatterns: config.IgnorePatterns,
+	}
+	if err := lnk.Report(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleSync(config *lnk.Config, dryRun, sparse, forceOverwrite, reload bool, packages []string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("sync takes exactly one argument: <source-dir>"),
+			"Usage: lnk sync [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.SyncOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		Packages:       packages,
+		Sparse:         sparse,
+		ForceOverwrite: forceOverwrite,
+		Reload:         reload,
+		DryRun:         dryRun,
+	}
+	if err := lnk.Sync(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handlePackages(config *lnk.Config, action string, packages []string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("packages %s takes exactly one argument: <source-dir>", action),
+			"Usage: lnk packages list [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.LinkOptions{
+		SourceDir: config.SourceDir,
+		TargetDir: config.TargetDir,
+		Packages:  packages,
+	}
+	if err := lnk.ListPackages(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleDefaults(config *lnk.Config, action string, dryRun bool, packages []string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("defaults %s takes exactly one argument: <source-dir>", action),
+			fmt.Sprintf("Usage: lnk defaults %s [flags] <source-dir>", action)))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.DefaultsOptions{
+		SourceDir: config.SourceDir,
+		Packages:  packages,
+		DryRun:    dryRun,
+	}
+	apply := lnk.DefaultsDiff
+	if action == "apply" {
+		apply = lnk.DefaultsApply
+	}
+	if err := apply(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleConfig(config *lnk.Config, action string, dryRun, effective bool, output string, outputVersion int, cliPackages []string, extra []string) {
+	if action == lnk.ConfigSet || action == lnk.ConfigUnset || action == lnk.ConfigAppend {
+		if len(extra) == 0 {
+			lnk.PrintErrorWithHint(lnk.WithHint(
+				fmt.Errorf("config %s requires a path after <source-dir>", action),
+				fmt.Sprintf("Usage: lnk config %s [flags] <source-dir> <path> <value>...", action)))
+			exit(lnk.ExitUsage)
+		}
+		edit := lnk.ConfigEdit{Action: action, Path: extra[0], Values: extra[1:], DryRun: dryRun}
+		if err := lnk.EditConfig(config.SourceDir, edit); err != nil {
+			lnk.PrintErrorWithHint(err)
+			exit(lnk.ExitError)
+		}
+		return
+	}
+	if outputVersion != 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("schema versions can only be pinned for status output"),
+			"Use --output json"))
+		exit(lnk.ExitUsage)
+	}
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("config %s takes exactly one argument: <source-dir>", action),
+			fmt.Sprintf("Usage: lnk config %s [flags] <source-dir>", action)))
+		exit(lnk.ExitUsage)
+	}
+	var err error
+	if action == "show" {
+		err = lnk.ConfigShow(config, lnk.ConfigShowOptions{
+			Packages:  cliPackages,
+			Effective: effective,
+			Output:    output,
+		})
+	} else {
+		err = lnk.ConfigExplain(config, cliPackages)
+	}
+	if err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleDoctor(config *lnk.Config, packages []string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("doctor takes exactly one argument: <source-dir>"),
+			"Usage: lnk doctor [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.LinkOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		IgnorePatterns: config.IgnorePatterns,
+		Packages:       packages,
+		LocalOnly:      config.LocalOnly,
+	}
+	if err := lnk.Doctor(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleLint(config *lnk.Config, packages []string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("lint takes exactly one argument: <source-dir>"),
+			"Usage: lnk lint [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.LinkOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		IgnorePatterns: config.IgnorePatterns,
+		Packages:       packages,
+		Sensitive:      config.Sensitive,
+	}
+	if err := lnk.Lint(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleWeb(config *lnk.Config, listen string, packages []string, maps []lnk.Mapping, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("web takes exactly one argument: <source-dir>"),
+			"Usage: lnk web [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.WebOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		IgnorePatterns: config.IgnorePatterns,
+		Packages:       packages,
+		Maps:           maps,
+		LocalOnly:      config.LocalOnly,
+		Listen:         listen,
+	}
+	if err := lnk.Web(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleQuery(config *lnk.Config, stdinJSON bool, packages []string, maps []lnk.Mapping, extra []string) {
+	if len(extra) > 0 || !stdinJSON {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("query takes --stdin-json and exactly one argument: <source-dir>"),
+			"Usage: lnk query --stdin-json [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.QueryOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		IgnorePatterns: config.IgnorePatterns,
+		Packages:       packages,
+		Maps:           maps,
+		LocalOnly:      config.LocalOnly,
+	}
+	if err := lnk.Query(opts, os.Stdin, os.Stdout); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handlePromptStatus(config *lnk.Config, shell string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("prompt-status takes exactly one argument: <source-dir>"),
+			"Usage: lnk prompt-status [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.PromptStatusOptions{
+		SourceDir: config.SourceDir,
+		TargetDir: config.TargetDir,
+		Shell:     shell,
+	}
+	if err := lnk.PromptStatus(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleShellenv(config *lnk.Config, shell string, packages, ignorePatterns []string, noColor bool, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("shellenv takes exactly one argument: <source-dir>"),
+			"Usage: lnk shellenv [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	var flags []string
+	for flag := range valueFlags {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	flags = append(flags, switchFlags...)
+	opts := lnk.ShellenvOptions{
+		SourceDir:      config.SourceDir,
+		TargetDir:      config.TargetDir,
+		Shell:          shell,
+		Packages:       packages,
+		IgnorePatterns: ignorePatterns,
+		NoColor:        noColor,
+		Commands:       validCommands,
+		Actions:        commandActions,
+		Flags:          flags,
+	}
+	if err := lnk.Shellenv(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleSelfUpdate(channel string, dryRun, insecureSkipSignature bool, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("self-update takes no arguments"),
+			"Usage: lnk self-update [flags]"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.SelfUpdateOptions{
+		CurrentVersion: version,
+		Channel:        channel,
+		DryRun:         dryRun,
+
+		InsecureSkipSignature: insecureSkipSignature,
+	}
+	if err := lnk.SelfUpdate(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleDiffState(env *lnk.Env, points []string) {
+	if len(points) > 2 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("diff-state takes at most two points in history"),
+			"Usage: lnk diff-state [<from> [<to>]]"))
+		exit(lnk.ExitUsage)
+	}
+	home, err := env.ResolveTargetDir()
+	if err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+	if err := lnk.DiffState(home, points); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleIdentity(action string, dryRun bool, sourceDir string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("identity %s takes exactly one argument: <source-dir>", action),
+			fmt.Sprintf("Usage: lnk identity %s [flags] <source-dir>", action)))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.IdentityOptions{SourceDir: sourceDir, DryRun: dryRun}
+	var err error
+	if action == "rekey" {
+		err = lnk.RekeyPrivate(opts)
+	} else {
+		err = lnk.InitIdentity(opts)
+	}
+	if err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleInit(fromTemplate string, dryRun bool, sourceDir string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("init takes exactly one argument: <source-dir>"),
+			"Usage: lnk init --from-template OWNER/REPO [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	if fromTemplate == "" {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("init requires --from-template"),
+			"Example: lnk init --from-template OWNER/REPO ~/dotfiles, or run 'lnk' alone to be walked through other ways to start"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.InitOptions{SourceDir: sourceDir, Template: fromTemplate, DryRun: dryRun}
+	if err := lnk.InitFromTemplate(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleVars(action string, dryRun bool, sourceDir string, extra []string) {
+	if action == "list" {
+		if len(extra) > 0 {
+			lnk.PrintErrorWithHint(lnk.WithHint(
+				fmt.Errorf("vars list takes exactly one argument: <source-dir>"),
+				"Usage: lnk vars list [flags] <source-dir>"))
+			exit(lnk.ExitUsage)
+		}
+		if err := lnk.ListVars(sourceDir); err != nil {
+			lnk.PrintErrorWithHint(err)
+			exit(lnk.ExitError)
+		}
+		return
+	}
+	maxArgs := 1
+	if action == lnk.VarsSet {
+		maxArgs = 2
+	}
+	if len(extra) == 0 || len(extra) > maxArgs {
+		usage := fmt.Sprintf("Usage: lnk vars %s [flags] <source-dir> <name>", action)
+		if action == lnk.VarsSet {
+			usage += " [<value>]"
+		}
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("vars %s takes a variable name after <source-dir>", action), usage))
+		exit(lnk.ExitUsage)
+	}
+	edit := lnk.VarsEdit{Action: action, Name: extra[0], Ask: len(extra) == 1, DryRun: dryRun}
+	if len(extra) == 2 {
+		edit.Value = extra[1]
+	}
+	if err := lnk.EditVars(sourceDir, edit); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleEval(args []string) {
+	if len(args) != 1 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("eval takes exactly one argument: <expression>"),
+			`Usage: lnk eval [flags] <expression>`))
+		exit(lnk.ExitUsage)
+	}
+	ok, err := lnk.Eval(args[0])
+	if err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+	if !ok {
+		exit(lnk.ExitError)
+	}
+}
+
+func handleDetect(config *lnk.Config, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("detect takes exactly one argument: <source-dir>"),
+			"Usage: lnk detect [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	if err := lnk.Detect(config.SourceDir); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleStats(config *lnk.Config, action string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("stats %s takes exactly one argument: <source-dir>", action),
+			fmt.Sprintf("Usage: lnk stats %s [flags] <source-dir>", action)))
+		exit(lnk.ExitUsage)
+	}
+	var err error
+	switch action {
+	case "enable":
+		err = lnk.EnableStats(config.TargetDir)
+	case "disable":
+		err = lnk.DisableStats(config.TargetDir)
+	case "reset":
+		err = lnk.ResetStats(config.TargetDir)
+	default:
+		err = lnk.ShowStats(config.TargetDir)
+	}
+	if err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleBundle(config *lnk.Config, action string, dryRun bool, packages []string, from string, cliIgnore []string, extra []string) {
+	if len(extra) != 1 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("bundle %s takes <source-dir> and a bundle file", action),
+			fmt.Sprintf("Usage: lnk bundle %s [flags] <source-dir> <bundle>", action)))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.BundleOptions{
+		SourceDir: config.SourceDir,
+		TargetDir: config.TargetDir,
+		Path:      extra[0],
+		DryRun:    dryRun,
+	}
+	var err error
+	if action == "apply" {
+		opts.IgnorePatterns = cliIgnore
+		err = lnk.ApplyBundle(opts)
+	} else {
+		opts.IgnorePatterns = config.IgnorePatterns
+		opts.Packages = packages
+		opts.PackagesFrom = from
+		opts.Sensitive = config.Sensitive
+		err = lnk.CreateBundle(opts)
+	}
+	if err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+func handleDeploy(config *lnk.Config, dryRun bool, specialFiles, symlinkFallback, onUnsupported string, users, packages []string, extra []string) {
+	if len(extra) > 0 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("deploy takes exactly one argument: <source-dir>"),
+			"Usage: lnk deploy --users LIST [flags] <source-dir>"))
+		exit(lnk.ExitUsage)
+	}
+	opts := lnk.DeployOptions{
+		SourceDir:       config.SourceDir,
+		IgnorePatterns:  config.IgnorePatterns,
+		Packages:        packages,
+		LocalOnly:       config.LocalOnly,
+		SpecialFiles:    specialFiles,
+		SymlinkFallback: symlinkFallback,
+		OnUnsupported:   onUnsupported,
+		Users:           users,
+		DryRun:          dryRun,
+	}
+	if err := lnk.Deploy(opts); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+// handleComplete prints completion candidates for the completion shellenv
+// generates: lnk __complete KIND <source-dir> [prefix]
+func handleComplete(args []string) {
+	if len(args) < 2 || len(args) > 3 {
+		lnk.PrintErrorWithHint(lnk.WithHint(
+			fmt.Errorf("__complete takes a kind, <source-dir>, and an optional prefix"),
+			fmt.Sprintf("Usage: lnk __complete %s <source-dir> [prefix]", strings.Join(lnk.CompletionKinds, "|"))))
+		exit(lnk.ExitUsage)
+	}
+	var prefix string
+	if len(args) == 3 {
+		prefix = args[2]
+	}
+	if err := lnk.Complete(args[0], args[1], prefix); err != nil {
+		lnk.PrintErrorWithHint(err)
+		exit(lnk.ExitError)
+	}
+}
+
+// exit writes the --summary-file, if one was requested, records the run in
+// the usage statistics, if enabled, and exits with code
+func exit(code int) {
+	lnk.StopPager()
+	lnk.ReleaseLock()
+	lnk.WriteProblemReport(os.Stderr)
+	lnk.WriteProfile(os.Stderr)
+	lnk.RecordStats(code)
+	if err := lnk.WriteSummary(code); err != nil {
+		lnk.PrintErrorWithHint(err)
+		if code == 0 {
+			code = lnk.ExitError
+		}
+	}
+	os.Exit(code)
+}
+
+// parseFlagValue parses a flag that might be in --flag=value or --flag value format.
+// Returns the flag name, value, whether a value was found, and how many extra args were consumed.
+func parseFlagValue(arg string, args []string, index int) (flag string, value string, hasValue bool, consumed int) {
+	// Check for --flag=value format
+	if idx := strings.Index(arg, "="); idx > 0 {
+		return arg[:idx], arg[idx+1:], true, 0
+	}
+
+	// Check for --flag value format
+	if index+1 < len(args) && (!strings.HasPrefix(args[index+1], "-") || args[index+1] == lnk.StdinPath) {
+		return arg, args[index+1], true, 1
+	}
+
+	return arg, "", false, 0
+}
+
+// extractCommand finds the command name in args, returning it and the remaining args.
+// The command is the first non-flag token that matches a valid command name or
+// appears to be a command (not starting with -).
+func extractCommand(args []string) (string, []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		// Stop at --
+		if arg == "--" {
+			break
+		}
+
+		// Skip flags
+		if strings.HasPrefix(arg, "-") {
+			// Skip value of flags that take values (--ignore pattern or --ignore=pattern)
+			if valueFlags[arg] && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++ // skip the value token so it isn't mistaken for a command
+			}
+			continue
+		}
+
+		// Found a non-flag token — this is the command
+		remaining := make([]string, 0, len(args)-1)
+		remaining = append(remaining, args[:i]...)
+		remaining = append(remaining, args[i+1:]...)
+		return arg, remaining
+	}
+
+	return "", args
+}
+
+// isValidCommand returns true if name is a recognized command.
+func isValidCommand(name string) bool {
+	for _, cmd := range validCommands {
+		if cmd == name {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestCommand returns the closest valid command name to input, or empty string
+// if no suggestion is close enough.
+func suggestCommand(input string) string {
+	return lnk.ClosestMatch(input, validCommands, len(input)/2+1)
+}
+
+// printUsage prints the top-level usage message.
+func printUsage() {
+	fmt.Print(`Usage: lnk <command> [flags] <source-dir>
+
+An opinionated symlink manager for dotfiles and more
+
+Commands:
+  create <source-dir>           Create symlinks from source to ~
+  ensure <source-dir>           Recreate missing links at login
+  remove <source-dir> [path...] Remove managed symlinks
+  status <source-dir>           Show status of managed symlinks
+  prune  <source-dir>           Remove broken symlinks
+  adopt  <source-dir> <path...> Adopt files into source directory
+  orphan <source-dir> <path...> Remove files from management
+  undo   <source-dir>           Roll back an interrupted adopt
+  clean  <source-dir>           Remove empty directories lnk created
+  rehome <source-dir>           Point links into the home directory after it moved
+  try <source-dir> <src> <tgt>  Preview a mapping and offer to save it
+  up     <source-dir>           Sync, create, prune, and summarize in one run
+  down   <source-dir>           Remove links, and optionally empty directories
+  suggest <source-dir>          Suggest unmanaged dotfiles to adopt
+  report <source-dir>           Summarize files, sizes, and problems in source
+  sync   <source-dir>           Pull the latest changes into source with git
+  packages list <source-dir>    List packages and their metadata
+  doctor <source-dir>           Check that this machine meets package requirements
+  lint   <source-dir>           Check the source directory for mistakes
+  web    <source-dir>           Serve a read-only dashboard on localhost
+  prompt-status <source-dir>    Print a short drift token for shell prompts
+  shellenv <source-dir>         Print shell setup code for a startup file
+  eval   <expr>                 Evaluate a condition expression on this machine
+  detect <source-dir>           Show machine facts and the profile they select
+  defaults apply|diff <source-dir>
+                                Apply or compare macOS defaults from lnk-package.json
+  config explain|show|set|unset|append <source-dir>
+                                Explain, print, or edit the configuration
+  stats show|enable|disable|reset <source-dir>
+                                Show or manage opt-in local usage statistics
+  bundle create|apply <source-dir> <bundle>
+                                Pack packages for an offline machine, or install a pack
+  deploy --users LIST <source-dir>
+                                Link source into several users' homes (as root)
+  identity init|rekey <source-dir>
+                                Make this machine's keys, or re-encrypt for new ones
+  init --from-template REPO <source-dir>
+                                Start a source directory from a GitHub template
+  vars list|set|unset <source-dir> [<name> [<value>]]
+                                List template variables, or answer one for this machine
+  gitconfig-local <source-dir>  Write ~/.gitconfig.local from a template and include it
+  query --stdin-json <source-dir>
+                                Answer editor requests, one JSON object per line
+  self-update                   Replace lnk with the newest release
+  diff-state [<from> [<to>]]    Show how managed links changed between two
+                                points in history
+
+Flags:
+      --ignore PATTERN  Additional ignore pattern, repeatable
+      --prefer WHICH    Resolve adopt conflicts: repo or local
+      --no-ignore       Adopt ignored files found in directories (adopt)
+      --resume          Finish an interrupted adopt instead of refusing (adopt)
+      --to-copy         Replace links with managed copies instead (orphan)
+      --source SUBDIR   Limit prune to a subdirectory of source-dir, repeatable
+      --clean-empty-dirs
+                        Also remove empty directories lnk created (remove,
+                        down)
+      --all             Also remove links into source-dir that lnk did not
+                        create (remove; default is --managed-only)
+      --interactive     Choose the links to remove from a checklist (remove,
+                        prune)
+      --fail-on CONDITION
+                        Exit with an error when status finds CONDITION
+      --special-files POLICY
+                        Special files in source: skip (default) or error (create)
+      --symlink-fallback POLICY
+                        Targets without symlink support: error (default) or
+                        copy (create)
+      --on-unsupported POLICY
+                        Configured features this machine cannot honor:
+                        degrade (default) or abort (create)
+      --packages LIST   Only use these top-level directories of source-dir as
+                        packages (comma-separated, repeatable)
+      --group LIST      Only use links in these groups (create, remove, status;
+                        comma-separated, repeatable)
+      --users LIST      Users whose homes to link into (deploy; comma-separated)
+      --map SRC:TGT     Also link SRC into TGT for this run (create, remove,
+                        status, web, query; repeatable)
+      --sparse          Check out only the selected packages (sync, up)
+      --force-overwrite Back up and replace managed copies edited locally (sync,
+                        up)
+      --windows-links   Create links on Windows drives with mklink (create, WSL)
+      --replace-identical
+                        Replace files identical to the repository with links
+                        without asking (create)
+      --reload          Run the reload actions of changed packages (create,
+                        sync, up)
+      --full-plan       List every planned link in a dry run, including links
+                        already in place (create, up)
+      --fast            Restore only recorded ephemeral links (ensure)
+      --shallow         Check only recorded links, without walking (status)
+      --remote          Also compare the repository with its remote (status)
+      --as-of WHEN      Show the links recorded at a time or operation (status)
+      --effective       Print the merged configuration (config show)
+      --output FORMAT   Output format for config show: json (default) or yaml,
+                        and status: json or json=vN to pin the schema version;
+                        json also writes errors to stderr as JSON (all commands)
+      --log-file FILE   Append trace events (timings, counts) to FILE as JSON
+      --summary-file FILE
+                        Write the end-of-run summary to FILE as JSON
+      --paths-from FILE Read paths from FILE, or - for stdin (adopt, orphan,
+                        remove)
+      --listen ADDR     Loopback address for the dashboard (web; default
+                        127.0.0.1:7474)
+      --shell SHELL     Shell to target: bash, zsh, fish, or plain
+                        (prompt-status, shellenv; shellenv default: $SHELL)
+      --channel CHANNEL Release channel: stable (default) or prerelease
+                        (self-update)
+      --insecure-skip-signature
+                        Trust a release's checksums without checking their
+                        signature (self-update)
+      --from-template REPO
+                        GitHub template to start from: OWNER/REPO[/SUBDIR][#REF]
+                        (init)
+      --stdin-json      Read JSON requests from stdin, one per line, and answer
+                        each on stdout (query)
+      --strict-config   Treat unknown keys in lnk-package.json as errors
+      --max-symlink-depth N
+                        Follow at most N symlinks in a chain (default 40)
+      --read-only       Refuse every file system change; commands that change
+                        files need --dry-run
+      --profile-perf    Print time per phase and file system operation to
+                        stderr when the command finishes
+  -n, --dry-run         Preview changes without making them
+  -y, --yes             Answer yes to confirmation prompts
+  -v, --verbose         Enable verbose output, including trace events
+      --no-color        Disable colored output
+      --no-pager        Print long output directly instead of through a pager
+                        (status, report, packages, lint, config)
+      --wait            When another lnk is changing the home directory, wait
+                        for it instead of failing
+      --path-display LIST
+                        Show paths as $XDG_CONFIG_HOME/... (xdg), repo:...
+                        inside source-dir (repo), or in full (absolute)
+      --absolute-paths  Show full paths, for scripts (--path-display absolute)
+  -V, --version         Show version information
+  -h, --help            Show this help message
+
+Examples:
+  lnk create .                        Create links from current directory
+  lnk create ~/git/dotfiles           Create from absolute path
+  lnk create -n .                     Dry-run preview
+  lnk remove .                        Remove links
+  lnk status .                        Show status
+  lnk status --fail-on unlinked .     Fail if any source file is unlinked
+  lnk prune .                         Prune broken symlinks
+  lnk prune ~/git/dotfiles            Prune from specific source
+  lnk prune --source home ~/dotfiles  Prune only links into ~/dotfiles/home
+  lnk adopt . ~/.bashrc ~/.vimrc      Adopt files into current directory
+  lnk adopt ~/dotfiles ~/.bashrc      Adopt with explicit source
+  lnk adopt --prefer repo . ~/.bashrc Keep repo copy on conflict
+  lnk orphan . ~/.bashrc              Remove file from management
+  lnk undo .                          Roll back an interrupted adopt
+  lnk clean .                         Remove empty directories lnk created
+  lnk rehome ~/git/dotfiles           Fix links after the home directory moved
+  lnk try . ~/work/cfg ~/.config/work/ Preview linking a directory, then save it
+  lnk up ~/git/dotfiles               Pull, link, prune, and summarize
+  lnk down --clean-empty-dirs .       Remove links and the directories lnk made
+  lnk suggest .                       Pick unmanaged dotfiles to adopt
+  lnk report .                        Summarize the source directory
+  lnk create --packages shell,nvim .  Link only the shell and nvim packages
+  lnk create --group gui .            Link only the links in the gui group
+  lnk sync --sparse .                 Pull, checking out only default packages
+  lnk packages list .                 List packages
+  lnk doctor .                        Check for missing commands
+  lnk lint .                          Check the source directory for mistakes
+  lnk web .                           Browse link state at http://127.0.0.1:7474/
+  lnk prompt-status --shell zsh .     Print lnk:✓ or lnk:N! for a prompt
+  eval "$(lnk shellenv ~/dotfiles)"   Set up PATH, completion, and prompt
+  lnk eval 'os == "linux"'            Test a condition expression
+  lnk detect .                        Show which profile this machine gets
+  lnk defaults diff .                 Show macOS settings that differ
+  lnk config explain .                Show configuration sources and precedence
+  lnk config show --effective .       Print the merged configuration as JSON
+  lnk config append . ignore '*.bak'  Add an ignore pattern from a script
+  lnk stats enable .                  Record command counts and durations locally
+  lnk bundle create . dots.tar.zst    Pack this machine's packages for another one
+  lnk identity init ~/dotfiles        Make and register this machine's age key
+  lnk vars set ~/dotfiles work_email me@work.example
+                                      Answer a template variable on this machine
+  lnk gitconfig-local ~/dotfiles      Write this machine's git identity
+  sudo lnk deploy --users a,b /srv/dotfiles
+                                      Link shared dotfiles into two users' homes
+  lnk create --ignore '*.swp' .       Add ignore pattern
+
+Config Files:
+  .lnkignore in source directory
+    Format: gitignore syntax
+    Patterns are combined with built-in defaults and --ignore flags
+  .lnkpackages in source directory
+    Format: one package (top-level directory) per line, # comments
+    Default packages when --packages and LNK_PACKAGES are not given
+  .lnkprofiles in source directory
+    Format: JSON with profile_rules: name, hostname glob, when, packages
+    The first rule matching this machine overrides .lnkpackages
+  .lnklocal in source directory
+    Format: gitignore syntax, relative to ~ (a leading ~/ is allowed)
+    Paths on this machine lnk never links over, adopts, or suggests
+  .lnksensitive in source directory
+    Format: gitignore syntax, relative to ~ (a leading ~/ is allowed)
+    Files adopt refuses and lint reports unless encrypted
+  .lnkdirs in source directory
+    Format: JSON with mode, owner, group, and allow (directories under ~)
+    How create makes missing parent directories, and where it may
+  .lnkprotected in source directory
+    Format: gitignore syntax, relative to ~ (a leading ~/ is allowed)
+    Paths lnk never creates, removes, or overwrites, on top of the built-in
+    ~/.ssh/authorized_keys and ~/Library/Keychains/**
+  .lnkprivate.age or .lnkprivate.gpg in source directory
+    Format: encrypted; [ignore], [local-only], [sensitive], [maps], and
+    [protected] sections, each in the format of the file it is named after
+    Decrypted with age or gpg when the configuration is loaded
+  .lnkrequires in a package directory
+    Format: one required package per line, # comments
+    Required packages are selected along with the package
+  lnk-package.json in a package directory
+    Format: JSON with name, description, platforms, commands, and link
+    conditions (when, overrides)
+
+Environment:
+  LNK_IGNORE      Extra ignore patterns, comma-separated (before --ignore)
+  LNK_PACKAGES    Packages to use, comma-separated (--packages overrides it)
+  LNK_NO_COLOR    Set to 1 to disable colored output (also NO_COLOR)
+  LNK_YES         Set to 1 to answer yes to confirmation prompts
+  LNK_LOG_LEVEL   normal (default) or verbose
+  LNK_READ_ONLY   Set to 1 to refuse file system changes (--read-only)
+  LNK_PAGER       Pager for long output (default: $PAGER, then less); cat
+                  turns paging off (--no-pager)
+  LNK_PATH_DISPLAY
+                  How paths are shown, comma-separated: xdg, repo, absolute
+                  (--path-display overrides it)
+  LNK_AGE_IDENTITY
+                  age identity file decrypting .lnkprivate.age (default:
+                  ~/.config/lnk/identity.age, when it exists)
+  LNK_NO_BATCH    Set to 1 to create links one path at a time instead of
+                  with the Linux fast path (symlinkat in open directories)
+  LNK_NO_FETCH    Set to 1 never to check out packages outside a sparse
+                  checkout or download Git LFS objects on demand
+  LNK_PROFILE     Profile in .lnkprofiles to use, whatever its rules say
+                  (LNK_PACKAGES and --packages override it)
+  LNK_TARGET_DIR  Directory links are created in (default: ~); it must exist
+  LNK_CONFIG      lnk's user configuration directory, holding the age
+                  identity and onboarding record (default: $XDG_CONFIG_HOME/lnk)
+  LNK_TEMPLATE_FUNCS
+                  Template functions to turn on, or off with a leading -,
+                  comma-separated (env, onePassword, and pass are off)
+  Flags take precedence over environment variables, and environment variables
+  over files in the source directory.
+`)
+}
+
+// printCommandHelp prints help for a specific command.
+func printCommandHelp(command string) {
+	switch command {
+	case "create":
+		fmt.Print(`Usage: lnk create [flags] <source-dir>
+
+Create symlinks from source directory to home directory.
+
+With --dry-run, create shows what would change: links to create, symlinks that
+would be pointed at a new source (retarget), and conflicts where a file is in
+the way. Links already in place are only counted; --full-plan lists every
+planned link instead.
+
+Sockets, FIFOs, device nodes, and hardlinked files in the source directory are
+never linked. By default each is skipped with a warning.
+
+A regular file at a target path that is byte-identical to its source, as after
+a fresh clone or an adopt on another machine, can be replaced with the link:
+create lists such files and asks first, or replaces them without asking with
+--replace-identical (or --yes). Files that differ are never touched.
+
+Under WSL, packages with "target": "windows" in lnk-package.json are linked into
+the Windows home directory. Windows applications cannot follow symlinks from a
+Windows drive into the WSL file system; lnk warns about them, and
+--windows-links creates them with mklink instead.
+
+Before changing anything, create checks that each file system it links onto
+can hold symlinks (FAT, exFAT, and some network mounts cannot). By default it
+stops with an error; with --symlink-fallback copy the files there are copied
+instead and managed as copies, which 'lnk sync' keeps up to date.
+
+Some settings cannot be honored on every machine: "target": "windows" outside
+WSL, "target": "runtime" without XDG_RUNTIME_DIR, and a .lnkdirs mode, owner,
+or group that Windows or a non-root user cannot apply. create lists them before
+linking and continues without them; --on-unsupported abort stops instead.
+
+With --reload, packages whose links were created run the reload actions in
+their lnk-package.json afterwards, such as {"run": "tmux source-file
+~/.tmux.conf"} or {"signal": "USR1", "process": "kitty"}, so running programs
+pick up the change.
+
+--map SRC:TGT links the contents of SRC into TGT for this run only, in addition
+to the packages. SRC is relative to source-dir and TGT to the home directory,
+unless absolute or starting with ~. Pass the same --map to status and remove.
+A TGT ending in / (or :merge_into) merges SRC into that directory, placing a
+file SRC inside it; :link_as links SRC itself at TGT. Otherwise a file is
+linked as TGT and a directory merged into it; a directory mapped onto a file
+or onto its own link must say which.
+
+Arguments:
+  source-dir    Source directory to link from (required)
+
+Flags:
+      --special-files POLICY
+                skip (default): warn and skip special files
+                error: refuse to create any links while special files exist
+      --symlink-fallback POLICY
+                error (default): stop when a target cannot hold symlinks
+                copy: copy files to targets without symlink support
+      --on-unsupported POLICY
+                degrade (default): list unsupported settings and do without
+                abort: stop before linking when any setting is unsupported
+      --packages LIST
+                Link only these packages, each as if it were source-dir
+      --group LIST
+                Link only the links in these groups
+      --windows-links
+                Create links on Windows drives with mklink (WSL only)
+      --replace-identical
+                Replace files identical to their source with links
+      --reload  Run the reload actions of packages whose links were created
+      --full-plan
+                With --dry-run, list every planned link, not only changes
+      --map SRC:TGT
+                Also link SRC into TGT for this run (repeatable)
+  (all global flags apply)
+
+Examples:
+  lnk create .
+  lnk create ~/git/dotfiles
+  lnk create -n .
+  lnk create -n --full-plan .
+  lnk create --special-files error .
+  lnk create --symlink-fallback copy ~/git/dotfiles
+  lnk create --on-unsupported abort ~/git/dotfiles
+  lnk create --packages shell,nvim ~/git/dotfiles
+  lnk create --group shell,gui ~/git/dotfiles
+  lnk create --windows-links ~/git/dotfiles
+  lnk create --replace-identical ~/git/dotfiles
+  lnk create --reload ~/git/dotfiles
+  lnk create --map ~/projects/foo/config:.config/foo ~/git/dotfiles
+  lnk create --map ~/projects/foo/config:.config/foo:link_as ~/git/dotfiles
+`)
+	case "remove":
+		fmt.Print(`Usage: lnk remove [flags] <source-dir> [path...]
+
+Remove managed symlinks from home directory.
+
+Only links lnk created (recorded in its manifest, or tagged on macOS) are
+removed; links you made into the source directory by hand are skipped unless
+--all is given.
+
+With paths, only those links (or the managed links under those directories)
+are removed; a path that is not a managed link is an error.
+
+Arguments:
+  source-dir    Source directory whose managed links to remove (required)
+  path          Managed links or directories to remove; - reads paths from stdin
+
+Flags:
+      --clean-empty-dirs
+                Also remove empty directories lnk created for this source
+      --managed-only
+                Only remove links lnk created (default)
+      --all     Also remove links into source-dir that lnk did not create
+      --interactive
+                Choose the links to remove from a checklist first
+      --packages LIST
+                Only remove links into these packages
+      --group LIST
+                Only remove links in these groups
+      --map SRC:TGT
+                Also remove links from SRC in TGT (repeatable)
+      --paths-from FILE
+                Read paths from FILE, one per line or NUL-separated
+                (- for stdin)
+  (all global flags apply)
+
+Examples:
+  lnk remove .
+  lnk remove ~/git/dotfiles
+  lnk remove -n .
+  lnk remove --clean-empty-dirs .
+  lnk remove --all ~/git/dotfiles
+  lnk remove --interactive ~/git/dotfiles
+  lnk remove --group gui ~/git/dotfiles
+  lnk remove . ~/.bashrc ~/.config/nvim
+  fd -0 -t l . ~/.config | lnk remove ~/git/dotfiles -
+`)
+	case "status":
+		fmt.Print(`Usage: lnk status [flags] <source-dir>
+
+Show status of managed symlinks in home directory.
+
+Source files with nothing at their target path are listed as unlinked.
+Broken links say why they are broken: source-deleted (the source file is
+gone), parent-missing (the directory that held it is gone),
+permission-denied (the source could not be checked), or symlink-loop (the
+source is a symlink chain that loops or is too long).
+
+With --shallow only the links the manifest records are checked: each
+directory holding them is listed once and nothing else is walked, so status
+stays fast with many thousands of links. Recorded links that are gone are
+listed as missing; unlinked source files are not looked for.
+
+With --as-of WHEN status shows the links and copies the manifest recorded for
+source-dir at that point, and what changed since. lnk keeps a snapshot of the
+manifest after each command that changes it (the last 100). WHEN is an
+operation ID from 'lnk diff-state', a time ("2025-06-01 18:00", "2025-06-01",
+RFC 3339), or a duration ago ("36h", "3d", "2w").
+
+With --remote status also fetches the repository's remote and reports the
+current branch's commits ahead of and behind its upstream, uncommitted
+changes, and stale local branches (deleted from the remote, or merged into
+the upstream), so a machine whose dotfiles drifted from the remote shows up
+next to link drift. With --read-only nothing is fetched.
+
+With --output json status is written as one JSON document with a
+"schema_version" field. New fields may be added within a version; renaming or
+removing one needs a new version. Scripts can pin a version with
+--output json=v1, which keeps working after lnk moves to a newer one.
+
+Arguments:
+  source-dir    Source directory to check (required)
+
+Flags:
+      --shallow
+                Check only recorded links, without walking the target or
+                source directories
+      --as-of WHEN
+                Show the links recorded at a time or operation, and what
+                changed since
+      --remote  Also report commits ahead and behind the remote, uncommitted
+                changes, and stale branches
+      --fail-on CONDITION
+                Exit with an error when CONDITION is found: unlinked
+                (with --shallow: missing links)
+      --packages LIST
+                Only show links and sources for these packages
+      --group LIST
+                Only show links and sources in these groups
+      --map SRC:TGT
+                Also show links and sources of SRC mapped into TGT (repeatable)
+      --output json[=vN]
+                Write status as JSON, optionally pinned to schema version N
+  (all global flags apply)
+
+Examples:
+  lnk status .
+  lnk status ~/git/dotfiles
+  lnk status ~/git/dotfiles | grep ^broken
+  lnk status --fail-on unlinked .
+  lnk status --output json=v1 ~/git/dotfiles | jq '.links[] | select(.state == "broken")'
+  lnk status --shallow --profile-perf ~/git/dotfiles
+  lnk status --as-of 7d ~/git/dotfiles
+  lnk status --remote ~/git/dotfiles
+  lnk status --group secrets ~/git/dotfiles
+`)
+	case "prune":
+		fmt.Print(`Usage: lnk prune [flags] <source-dir>
+
+Remove broken managed symlinks from home directory.
+
+A link is pruned when its source file is missing, or when git records the
+source as deleted even though the file is still on disk. Links whose source
+cannot be checked (permission denied) are reported and left in place.
+
+With --interactive the links found are shown as a checklist, all selected;
+toggle the ones to keep by number, then confirm.
+
+Arguments:
+  source-dir    Source directory whose broken links to prune (required)
+
+Flags:
+      --source SUBDIR   Only prune links into this subdirectory of source-dir
+                        (repeatable)
+      --interactive     Choose the links to prune from a checklist first
+  (all global flags apply)
+
+Examples:
+  lnk prune .
+  lnk prune ~/git/dotfiles
+  lnk prune -n .
+  lnk prune --source private ~/git/dotfiles
+  lnk prune --interactive ~/git/dotfiles
+`)
+	case "adopt":
+		fmt.Print(`Usage: lnk adopt [flags] <source-dir> <path...>
+
+Adopt files into the source directory.
+
+If a file already exists in the source directory with identical content, the
+local file is replaced with a symlink. If the content differs, lnk asks whether
+to keep the local or repository copy (or use --prefer when not interactive).
+
+Files are moved with rename, or across file systems by a chunked copy that is
+synced and checked by SHA-256 before the original is removed, with progress
+shown at a terminal. If adopt is interrupted, rerun it with --resume to finish,
+or run 'lnk undo' to put every file back.
+
+Files in an adopted directory that match the ignore patterns (built-in,
+.lnkignore, and --ignore) are skipped, so junk like .DS_Store and swap files
+never enters the repository. Files named explicitly are always adopted.
+
+Arguments:
+  source-dir    Source directory to move files into (required)
+  path          One or more files or directories to adopt; must be within ~ (required)
+                - reads paths from stdin
+
+Flags:
+      --prefer WHICH    Resolve differing files: repo (keep repository copy)
+                        or local (overwrite repository copy)
+      --no-ignore       Also adopt files in directories that match ignore
+                        patterns
+      --resume          Finish an interrupted adopt of the same paths: files
+                        it adopted are kept, and a copy it was making
+                        continues where it stopped
+      --paths-from FILE Read paths from FILE, one per line or NUL-separated
+                        (- for stdin)
+  (all global flags apply)
+
+Examples:
+  lnk adopt . ~/.bashrc
+  lnk adopt . ~/.bashrc ~/.vimrc
+  lnk adopt ~/git/dotfiles ~/.config/nvim
+  lnk adopt -n . ~/.bashrc
+  lnk adopt --prefer local . ~/.bashrc
+  lnk adopt --no-ignore . ~/.config/nvim
+  lnk adopt --resume ~/git/dotfiles ~/.local/share/app
+  lnk suggest . | sed -n 's/^unmanaged //p' | lnk adopt . -
+`)
+	case "orphan":
+		fmt.Print(`Usage: lnk orphan [flags] <source-dir> <path...>
+
+Remove files from management.
+
+With --to-copy, each symlink is replaced by a copy of its repository file and
+the repository is left as it is. The copies stay under lnk's management:
+create skips them and status reports copies that have fallen out of date.
+This suits applications that stop following symlinks. To link a copy again,
+run 'lnk adopt' on it.
+
+Arguments:
+  source-dir    Source directory that manages the files (required)
+  path          One or more managed symlinks or directories to orphan; must be within ~ (required)
+                - reads paths from stdin
+
+Flags:
+      --paths-from FILE Read paths from FILE, one per line or NUL-separated
+                        (- for stdin)
+      --to-copy         Replace the symlinks with copies of their sources and
+                        keep managing them as copies
+  (all global flags apply)
+
+Examples:
+  lnk orphan . ~/.bashrc
+  lnk orphan . ~/.bashrc ~/.vimrc
+  lnk orphan ~/git/dotfiles ~/.config/nvim
+  lnk orphan -n . ~/.bashrc
+  lnk orphan --paths-from retired.txt ~/git/dotfiles
+  lnk orphan --to-copy . ~/.config/app
+`)
+	case "undo":
+		fmt.Print(`Usage: lnk undo [flags] <source-dir>
+
+Roll back an adopt that was interrupted or could not roll itself back.
+
+adopt records each move and symlink in a journal in the lnk state directory
+while it runs. When adopt fails it rolls back on its own; if that rollback
+fails too, or lnk is killed partway, the journal is kept and this command
+restores the files from it (to finish the adopt instead, rerun it with
+--resume). Steps already undone are skipped, so it is safe to run again.
+Nothing happens when no adopt is unfinished.
+
+Arguments:
+  source-dir    Source directory the interrupted adopt moved files into (required)
+
+Flags:
+  (all global flags apply)
+
+Examples:
+  lnk undo .
+  lnk undo -n ~/git/dotfiles
+`)
+	case "ensure":
+		fmt.Print(`Usage: lnk ensure [flags] <source-dir>
+
+Recreate links that are missing, for running from a login shell.
+
+Without --fast this is 'lnk create'. With --fast only the manifest is read:
+links of ephemeral packages ("target": "runtime" or "ephemeral": true in
+lnk-package.json) that 'lnk create' recorded are recreated if they are
+missing, without walking the source directory. Nothing is printed unless a
+link cannot be restored (or with --verbose).
+
+Arguments:
+  source-dir    Source directory whose links to restore (required)
+
+Flags:
+      --fast
+                Restore only recorded ephemeral links, reading only the manifest
+      --packages LIST
+                Link only these packages (without --fast)
+  (all global flags apply)
+
+Examples:
+  lnk ensure ~/git/dotfiles
+  lnk ensure --fast ~/git/dotfiles
+`)
+	case "clean":
+		fmt.Print(`Usage: lnk clean [flags] <source-dir>
+
+Remove empty directories that lnk created in the home directory.
+
+Only directories recorded when 'lnk create' made them are considered, so
+directories that existed before lnk are never removed.
+
+Arguments:
+  source-dir    Source directory whose created directories to clean (required)
+
+Flags:
+  (all global flags apply)
+
+Examples:
+  lnk clean .
+  lnk clean ~/git/dotfiles
+  lnk clean -n .
+`)
+	case "rehome":
+		fmt.Print(`Usage: lnk rehome [flags] <source-dir>
+
+Point managed links back into the home directory after it moved, as after a
+username change or a migration to a new Mac.
+
+lnk records the home directory its links were made in, and stores the paths
+in its manifest relative to it. rehome finds the managed links that still
+point into the old home and points them at the same path in the current one.
+The old home does not need to exist. Links whose file is missing from the new
+home are left alone with a warning.
+
+Arguments:
+  source-dir    Source directory in its new location (required)
+
+Flags:
+  (all global flags apply)
+
+Examples:
+  lnk rehome -n ~/git/dotfiles
+  lnk rehome ~/git/dotfiles
+`)
+	case "try":
+		fmt.Print(`Usage: lnk try [flags] <source-dir> <src> <tgt>
+
+Preview a mapping before committing to it: plan <src>:<tgt> as create --map
+would, alongside the packages and the mappings saved in .lnkmaps, and show
+what would be linked, what is already linked, and what is in the way. Nothing
+is linked. lnk then asks whether to save the mapping to .lnkmaps, after which
+create, status, remove, and web use it like a --map given every time.
+
+<src> and <tgt> take the same forms as in --map SRC:TGT, including a trailing
+/ or a :merge_into or :link_as mode on <tgt>.
+
+Arguments:
+  source-dir    Source directory (required)
+  src           Directory or file to link, relative to source-dir, absolute,
+                or ~/...
+  tgt           Where to link it, relative to ~, absolute, or ~/...
+
+Flags:
+  -n, --dry-run         Show the plan without offering to save it
+  -y, --yes             Save the mapping without asking
+      --special-files POLICY
+                        Special files in src: skip (default) or error
+  (all global flags apply)
+
+Examples:
+  lnk try . ~/work/cfg ~/.config/work/
+  lnk try -y ~/git/dotfiles nvim ~/.config/nvim:link_as
+`)
+	case "up":
+		fmt.Print(`Usage: lnk up [flags] <source-dir>
+
+Bring the links up to date in one run. lnk up runs these steps in order,
+stopping at the first that fails:
+
+  sync     Pull the latest changes with git, as lnk sync (skipped when
+           source-dir is not in a git repository)
+  create   Link new files, as lnk create
+  prune    Remove broken links, as lnk prune
+  status   Count the links lnk recorded and how many no longer work
+
+Turn steps off in .lnkworkflow in source-dir, for example
+{"up": {"sync": false}}. With --dry-run, every step is a preview.
+
+Arguments:
+  source-dir    Source directory (required)
+
+Flags:
+      --packages LIST   Packages to link (and check out with --sparse)
+      --map SRC:TGT     Also link SRC into TGT (repeatable)
+      --sparse          Check out only the selected packages
+      --force-overwrite Back up and replace managed copies edited locally
+      --replace-identical
+                        Replace files identical to the repository with links
+                        without asking
+      --reload          Run the reload actions of packages sync or create
+                        changed, once at the end
+      --full-plan       With --dry-run, list every link create plans, not only
+                        changes
+      --special-files POLICY
+                        Special files in source: skip (default) or error
+      --symlink-fallback POLICY
+                        Targets without symlink support: error (default) or copy
+      --on-unsupported POLICY
+                        Unsupported settings: degrade (default) or abort
+  (all global flags apply)
+
+Examples:
+  lnk up ~/git/dotfiles
+  lnk up -n .
+  lnk up --yes --sparse ~/git/dotfiles
+`)
+	case "down":
+		fmt.Print(`Usage: lnk down [flags] <source-dir>
+
+Take the links down in one run. lnk down runs these steps in order, stopping
+at the first that fails:
+
+  remove   Remove the managed links, as lnk remove
+  clean    Remove empty directories lnk created, as lnk clean (off unless
+           turned on in .lnkworkflow or with --clean-empty-dirs)
+
+Turn steps on or off in .lnkworkflow in source-dir, for example
+{"down": {"clean": true}}.
+
+Arguments:
+  source-dir    Source directory (required)
+
+Flags:
+      --packages LIST   Packages whose links to remove
+      --map SRC:TGT     Also remove links from SRC in TGT (repeatable)
+      --clean-empty-dirs
+                        Run the clean step too
+      --all             Also remove links into source-dir that lnk did not
+                        create
+  (all global flags apply)
+
+Examples:
+  lnk down ~/git/dotfiles
+  lnk down -n --clean-empty-dirs .
+  lnk down --packages nvim .
+`)
+	case "suggest":
+		fmt.Print(`Usage: lnk suggest [flags] <source-dir>
+
+Find unmanaged dotfiles in well-known locations and offer to adopt them.
 
-	// Use single-row optimization
-	prev := make([]int, lb+1)
-	for j := range prev {
-		prev[j] = j
-	}
+Looks at common files such as ~/.bashrc and ~/.gitconfig and at entries in
+~/.config, skipping symlinks and ignored paths. At a terminal, lnk asks which
+of the listed files to adopt and into which directory of source-dir.
 
-	for i := 1; i <= la; i++ {
-		curr := make([]int, lb+1)
-		curr[0] = i
-		for j := 1; j <= lb; j++ {
-			cost := 1
-			if a[i-1] == b[j-1] {
-				cost = 0
-			}
-			curr[j] = min(curr[j-1]+1, min(prev[j]+1, prev[j-1]+cost))
-		}
-		prev = curr
-	}
-	return prev[lb]
-}
+Arguments:
+  source-dir    Source directory to adopt files into (required)
 
-// printUsage prints the top-level usage message.
-func printUsage() {
-	fmt.Print(`Usage: lnk <command> [flags] <source-dir>
+Flags:
+  (all global flags apply)
 
-An opinionated symlink manager for dotfiles and more
+Examples:
+  lnk suggest .
+  lnk suggest ~/git/dotfiles
+  lnk suggest -n .
+`)
+	case "report":
+		fmt.Print(`Usage: lnk report [flags] <source-dir>
 
-Commands:
-  create <source-dir>           Create symlinks from source to ~
-  remove <source-dir>           Remove managed symlinks
-  status <source-dir>           Show status of managed symlinks
-  prune  <source-dir>           Remove broken symlinks
-  adopt  <source-dir> <path...> Adopt files into source directory
-  orphan <source-dir> <path...> Remove files from management
+Summarize the source directory before linking or committing it.
+
+Shows files and total size per mapping, how many files are ignored and by
+which pattern, the largest files, and potential problems: sockets, FIFOs,
+device nodes, and files over 100 MB.
+
+Arguments:
+  source-dir    Source directory to summarize (required)
 
 Flags:
-      --ignore PATTERN  Additional ignore pattern, repeatable
-  -n, --dry-run         Preview changes without making them
-  -v, --verbose         Enable verbose output
-      --no-color        Disable colored output
-  -V, --version         Show version information
-  -h, --help            Show this help message
+  (all global flags apply)
 
 Examples:
-  lnk create .                        Create links from current directory
-  lnk create ~/git/dotfiles           Create from absolute path
-  lnk create -n .                     Dry-run preview
-  lnk remove .                        Remove links
-  lnk status .                        Show status
-  lnk prune .                         Prune broken symlinks
-  lnk prune ~/git/dotfiles            Prune from specific source
-  lnk adopt . ~/.bashrc ~/.vimrc      Adopt files into current directory
-  lnk adopt ~/dotfiles ~/.bashrc      Adopt with explicit source
-  lnk orphan . ~/.bashrc              Remove file from management
-  lnk create --ignore '*.swp' .       Add ignore pattern
+  lnk report .
+  lnk report ~/git/dotfiles
+  lnk report --ignore '*.iso' .
+`)
+	case "sync":
+		fmt.Print(`Usage: lnk sync [flags] <source-dir>
 
-Config Files:
-  .lnkignore in source directory
-    Format: gitignore syntax
-    Patterns are combined with built-in defaults and --ignore flags
+Pull the latest changes into the source directory with git (fast-forward only).
+
+With --sparse, the checkout is first limited to the selected packages (from
+--packages or .lnkpackages) using git sparse-checkout, so unused packages are
+never materialized on this machine.
+
+Afterwards, links lnk created whose source files the pull deleted are listed
+with the commit that deleted them, and pruned after confirmation (or without
+asking with --yes). Copies made with 'lnk orphan --to-copy' are updated from
+their sources, except copies edited since lnk wrote them, which are reported
+and left alone unless --force-overwrite backs them up and replaces them. Files
+matching keep_local patterns in lnk-package.json are never overwritten; files
+matching merge patterns are merged both ways, so changes made in the copy
+reach the repository.
+
+With --reload, packages with files the pull changed run the reload actions in
+their lnk-package.json.
+
+Arguments:
+  source-dir    Source directory inside a git repository (required)
+
+Flags:
+      --sparse  Check out only the selected packages
+      --force-overwrite
+                Back up and replace copies edited locally
+      --reload  Run the reload actions of packages the pull changed
+      --packages LIST
+                Packages to check out with --sparse
+  (all global flags apply)
+
+Examples:
+  lnk sync ~/git/dotfiles
+  lnk sync --sparse ~/git/dotfiles
+  lnk sync --sparse --packages shell,nvim ~/git/dotfiles
+  lnk sync -n --sparse .
+  lnk sync --yes ~/git/dotfiles
+  lnk sync --force-overwrite ~/git/dotfiles
+  lnk sync --reload ~/git/dotfiles
 `)
-}
+	case "packages":
+		fmt.Print(`Usage: lnk packages list [flags] <source-dir>
 
-// printCommandHelp prints help for a specific command.
-func printCommandHelp(command string) {
-	switch command {
-	case "create":
-		fmt.Print(`Usage: lnk create [flags] <source-dir>
+List the packages (top-level directories) of the source directory.
 
-Create symlinks from source directory to home directory.
+Shows each package's description, supported platforms, required packages, and
+required commands from its lnk-package.json and .lnkrequires files. Packages
+selected by --packages or .lnkpackages, and their dependencies, are marked.
+
+Arguments:
+  source-dir    Source directory whose packages to list (required)
+
+Flags:
+      --packages LIST
+                Packages to mark as selected
+  (all global flags apply)
+
+Examples:
+  lnk packages list .
+  lnk packages list ~/git/dotfiles
+  lnk packages list ~/git/dotfiles | grep ' selected'
+`)
+	case "defaults":
+		fmt.Print(`Usage: lnk defaults apply|diff [flags] <source-dir>
+
+Apply or compare the macOS preferences listed under "defaults" in
+lnk-package.json, using the defaults command.
+
+Actions:
+  apply         Write settings whose current value differs
+  diff          Show settings whose current value differs
+
+Each setting has a domain, key, type (string, bool, int, or float), and value.
+Settings that already match are left alone, so apply can be run repeatedly.
+Settings come from the selected packages whose link conditions hold, or from
+lnk-package.json at the top of source-dir when no packages are selected.
+
+Arguments:
+  source-dir    Source directory with the settings (required)
+
+Flags:
+      --packages LIST
+                Only use settings from these packages
+  (all global flags apply)
+
+Examples:
+  lnk defaults diff ~/git/dotfiles
+  lnk defaults apply ~/git/dotfiles
+  lnk defaults apply -n --packages macos ~/git/dotfiles
+`)
+	case "config":
+		fmt.Print(`Usage: lnk config explain|show [flags] <source-dir>
+       lnk config set|unset|append [flags] <source-dir> <path> [<value>...]
+
+Show where configuration comes from, print it for scripts, or edit it from
+scripts.
+
+Actions:
+  explain       List every source, whether it was found, and what it
+                contributed, then the effective configuration with the
+                origin of each value
+  show          Print the entries of each source as JSON or YAML; with
+                --effective, print the merged configuration instead
+  set           Set path to the values, replacing what it held
+  unset         Remove the values from path, or without values all of it
+  append        Add the values to path, unless they are already there
+
+Sources, in discovery order:
+  built-in      Built-in ignore patterns
+  .lnkignore    Ignore patterns in source-dir
+  .lnkpackages  Default packages in source-dir
+  .lnklocal     Local-only target paths in source-dir
+  .lnksensitive Files that must not be stored in plaintext
+  .lnkdirs      How missing parent directories are created
+  .lnkprotected Target paths lnk never changes, after the built-in ones
+  .lnkprivate   Encrypted entries for the settings above, one source per section
+  LNK_IGNORE    Ignore patterns from the environment
+  LNK_PACKAGES  Packages from the environment (overrides .lnkpackages)
+  --ignore      Ignore patterns from the command line
+  --packages    Packages from the command line (overrides both)
+
+Ignore patterns from all sources are combined; for packages the last source
+that sets them wins. The effective configuration has absolute paths and
+includes packages required through .lnkrequires.
+
+Paths for set, unset, and append:
+  ignore, packages, mappings, local, protected, sensitive
+                .lnkignore, .lnkpackages, .lnkmaps, .lnklocal, .lnkprotected,
+                and .lnksensitive, one value per entry; comments and other
+                entries are kept. Mappings are SRC:TGT[:MODE], as --map takes
+                them, or {"source": ..., "target": ..., "mode": ...}
+  package.<name>.<key>
+                A key in the lnk-package.json of a package, nested keys joined
+                with dots. Values are JSON, or strings when they are not JSON.
+                The file keeps its key order and indentation, and an edit that
+                would make it invalid is refused
+  source.<key>  A key in the lnk-package.json of source-dir itself
+
+Arguments:
+  source-dir    Source directory to explain or edit (required)
+  path          What to edit (set, unset, append)
+  value         Values to set, remove, or append
+
+Flags:
+      --effective
+                Print the merged configuration (show)
+      --output FORMAT
+                Output format for show: json (default) or yaml
+      --ignore PATTERN
+                Include an ignore pattern, as other commands would
+      --packages LIST
+                Include a package selection, as other commands would
+  -n, --dry-run Show an edit without writing it
+  (all global flags apply)
+
+Examples:
+  lnk config explain ~/git/dotfiles
+  lnk config explain --packages shell ~/git/dotfiles
+  lnk config show ~/git/dotfiles
+  lnk config show --effective --output yaml ~/git/dotfiles
+  lnk config set ~/git/dotfiles packages shell nvim
+  lnk config append ~/git/dotfiles ignore '*.bak'
+  lnk config append ~/git/dotfiles mappings '{"source": "work/nvim", "target": ".config/nvim"}'
+  lnk config set ~/git/dotfiles package.nvim.description 'Neovim setup'
+  lnk config append ~/git/dotfiles package.nvim.platforms linux
+  lnk config unset ~/git/dotfiles package.nvim.platforms
+`)
+	case "stats":
+		fmt.Print(`Usage: lnk stats show|enable|disable|reset [flags] <source-dir>
+
+Show or manage usage statistics: how often each command ran, how often it
+failed, and how long it took. Recording is off until you enable it, and the
+statistics never leave this machine. Only command names, exit status, and
+durations are kept, never arguments or paths, in stats.json in the lnk state
+directory ($XDG_STATE_HOME/lnk or ~/.local/state/lnk).
+
+Actions:
+  show          Print the statistics, most used commands first
+  enable        Start recording
+  disable       Stop recording and delete the statistics
+  reset         Clear the statistics and keep recording
+
+Arguments:
+  source-dir    Source directory (required)
+
+Flags:
+  (all global flags apply)
+
+Examples:
+  lnk stats enable ~/git/dotfiles
+  lnk stats show ~/git/dotfiles
+  lnk stats disable ~/git/dotfiles
+`)
+	case "bundle":
+		fmt.Print(`Usage: lnk bundle create|apply [flags] <source-dir> <bundle>
+
+Carry dotfiles to a machine without network access.
+
+create packs the packages this machine links (from --packages, LNK_PACKAGES,
+.lnkprofiles, or .lnkpackages), the packages they require, and lnk's
+configuration files into one file, with lnk-bundle.json listing every file
+and its checksum. Files are packed as stored: encrypted files stay
+encrypted. The name picks the compression: .tar.zst (needs the zstd
+command), .tar.gz, or .tar.
+
+apply checks the bundle, unpacks it into source-dir, and links the packages
+it was made with. Files already in source-dir must match the bundle;
+nothing is written when one differs.
+
+Actions:
+  create        Write the bundle file
+  apply         Unpack a bundle and link it
+
+Arguments:
+  source-dir    Source directory to pack, or to unpack into (required)
+  bundle        Bundle file to write or read (required)
+
+Flags:
+      --packages LIST
+                Pack these packages instead of the ones in use (create)
+  (all global flags apply)
+
+Examples:
+  lnk bundle create ~/git/dotfiles dotfiles.tar.zst
+  lnk bundle create --packages shell,git ~/git/dotfiles shell.tar.gz
+  lnk bundle apply -n ~/dotfiles dotfiles.tar.zst
+  lnk bundle apply ~/dotfiles dotfiles.tar.zst
+`)
+	case "self-update":
+		fmt.Print(`Usage: lnk self-update [flags]
+
+Replace the lnk binary with the newest release from GitHub, for machines where
+lnk was installed by downloading a release rather than with a package manager.
+
+The release archive for this OS and architecture is checked against the
+release's checksums file, whose signature is checked first. A build without
+the release signing key (such as one built from source) refuses to update
+unless --insecure-skip-signature is given. The new binary must run before it
+is renamed over the old one, so a failed or interrupted update leaves the old
+binary in place. A binary installed by Homebrew is left to 'brew upgrade lnk'.
+Development builds are always replaced by the newest release.
+
+Flags:
+      --channel CHANNEL
+                stable (default): releases only
+                prerelease: also release candidates, when newer
+      --insecure-skip-signature
+                Trust the checksums file without checking its signature
+  -n, --dry-run Show the update without installing it
+  (all global flags apply)
+
+Examples:
+  lnk self-update -n
+  lnk self-update
+  sudo lnk self-update              # installed in /usr/local/bin
+  lnk self-update --channel prerelease
+`)
+	case "identity":
+		fmt.Print(`Usage: lnk identity init|rekey [flags] <source-dir>
+
+Set up the keys that decrypt .lnkprivate.age on a new machine.
+
+Actions:
+  init          Make this machine's age identity and SSH key (~/.ssh/id_ed25519),
+                unless they exist, and register their public keys in
+                .lnkrecipients in source-dir under the machine's name. The
+                age identity is $LNK_AGE_IDENTITY, or identity.age in
+                ~/.config/lnk; lnk uses it to decrypt from then on
+  rekey         Decrypt .lnkprivate.age and encrypt it again to every key in
+                .lnkrecipients; run on a machine that can decrypt it
+
+Neither action loads the configuration, so both work before .lnkprivate.age
+can be decrypted. Private keys never enter the repository. Without a terminal,
+ssh-keygen makes the SSH key without a passphrase.
+
+A new machine runs 'lnk identity init' and pushes .lnkrecipients; a machine
+that can decrypt pulls, runs 'lnk identity rekey', and pushes
+.lnkprivate.age; the new machine pulls and can decrypt.
+
+Arguments:
+  source-dir    Source directory holding .lnkrecipients (required)
+
+Flags:
+  -n, --dry-run Show what would be generated and written
+  (all global flags apply)
+
+Examples:
+  lnk identity init -n ~/git/dotfiles
+  lnk identity init ~/git/dotfiles
+  lnk identity rekey ~/git/dotfiles
+`)
+	case "init":
+		fmt.Print(`Usage: lnk init --from-template REPO [flags] <source-dir>
+
+Start a new source directory from a dotfiles template on GitHub.
+
+The newest commit of REPO (OWNER/REPO, optionally with /SUBDIR for a directory
+in it and #REF for a branch or tag) is copied into source-dir without its
+history, then git init starts a history of your own. source-dir must be
+missing or empty.
+
+Files ending in .tmpl are written without the suffix after replacing their
+placeholders: {{.Username}}, {{.Name}}, {{.Email}}, {{.Hostname}}, {{.Home}},
+and {{.Vars.NAME}}. The name and email come from git config --global
+user.name and user.email, or are asked for at a terminal; a template using one
+that is not set fails before anything is written. .Vars holds the template's
+.lnk/data.yaml, overridden by .lnk/data.d/<hostname>.yaml for this machine.
+Templates can call hostname, lookPath, readFile (a file in the template),
+toJSON, toYAML, and indent; env, onePassword, and pass are off unless
+LNK_TEMPLATE_FUNCS turns them on. An lnk-template.json at the top of the
+template may list "mappings" to save to .lnkmaps and "packages" to write to
+.lnkpackages.
+
+Arguments:
+  source-dir    New source directory to create (required)
+
+Flags:
+      --from-template REPO
+                GitHub repository to copy (required)
+  -n, --dry-run Fetch and render the template, but write nothing
+  (all global flags apply)
+
+Examples:
+  lnk init -n --from-template OWNER/REPO ~/dotfiles
+  lnk init --from-template OWNER/REPO ~/dotfiles
+  lnk init --from-template OWNER/REPO/minimal#v2 ~/dotfiles
+`)
+	case "vars":
+		fmt.Print(`Usage: lnk vars list [flags] <source-dir>
+       lnk vars set|unset [flags] <source-dir> <name> [<value>]
+
+List the variables templates use as {{.Vars.NAME}}, or set this machine's
+answer for one.
+
+Variables come from .lnk/data.yaml in source-dir, then .lnk/data.d/<host>.yaml
+for this machine's short and full host names, then the answers in
+.lnk/answers.yaml; each file overrides the ones before it. When a template
+uses a variable none of them sets, lnk asks for it at a terminal and saves the
+answer, so it is asked only once. Answers stay on this machine: .lnk/.gitignore
+keeps answers.yaml out of git, and the file is readable only by you.
+
+A variable whose name ends in password, passphrase, secret, or token is read
+without echo and masked in the list.
+
+Actions:
+  list          Print every variable, its value, and the file that sets it
+  set           Save an answer for name; without a value, ask for it
+  unset         Delete the answer for name, so the data files apply again or
+                templates ask for it next time
+
+Arguments:
+  source-dir    Source directory holding .lnk (required)
+  name          Variable name, as in {{.Vars.NAME}} (set, unset)
+  value         Value to save (set)
+
+Flags:
+  -n, --dry-run Show the change without saving it
+  (all global flags apply)
+
+Examples:
+  lnk vars list ~/dotfiles
+  lnk vars set ~/dotfiles work_email me@work.example
+  lnk vars set ~/dotfiles github_token
+  lnk vars unset ~/dotfiles work_email
+`)
+	case "gitconfig-local":
+		fmt.Print(`Usage: lnk gitconfig-local [flags] <source-dir>
+
+Write machine-specific git settings, such as a work email or signing key, to
+~/.gitconfig.local, and make sure ~/.gitconfig includes it.
+
+The file is rendered from .lnk/gitconfig.local.tmpl in source-dir, with the
+same placeholders as init templates: {{.Username}}, {{.Name}}, {{.Email}},
+{{.Hostname}}, {{.Home}}, and {{.Vars.NAME}} from 'lnk vars'. A variable no
+data file sets is asked for once and saved to .lnk/answers.yaml. A
+~/.gitconfig.local lnk did not write is replaced only after asking.
+
+When ~/.gitconfig has no [include] of the file, one is added; if ~/.gitconfig
+is a link into source-dir, the include goes into the repository's copy. git
+skips an include whose file is missing, so other machines are unaffected.
+
+Arguments:
+  source-dir    Source directory holding .lnk (required)
+
+Flags:
+  -n, --dry-run Show what would be written without writing it
+  -y, --yes     Replace a ~/.gitconfig.local lnk did not write
+  (all global flags apply)
+
+Examples:
+  lnk gitconfig-local -n ~/dotfiles
+  lnk gitconfig-local ~/dotfiles
+
+Template example (.lnk/gitconfig.local.tmpl):
+  [user]
+      email = {{.Vars.work_email}}
+      signingkey = {{.Vars.signing_key}}
+`)
+	case "diff-state":
+		fmt.Print(`Usage: lnk diff-state [flags] [<from> [<to>]]
+
+Show how the links and copies lnk manages changed between two points in the
+manifest history, to find when something stopped being linked. lnk keeps a
+snapshot of the manifest after each command that changes it (the last 100),
+named by an operation ID: the UTC time the command started.
+
+Without arguments, list the recorded operations, newest first.
+
+Arguments:
+  from          Operation ID (or a unique prefix), a time ("2025-06-01 18:00",
+                "2025-06-01", RFC 3339), or a duration ago ("36h", "3d", "2w");
+                a time selects the last operation at or before it
+  to            Same as from; default: now
+
+Examples:
+  lnk diff-state
+  lnk diff-state 7d
+  lnk diff-state 20250601T180000Z 20250603T091500Z
+  lnk diff-state "2025-06-01" "2025-06-08"
+`)
+	case "deploy":
+		fmt.Print(`Usage: lnk deploy --users LIST [flags] <source-dir>
+
+Link a source directory into the homes of several users, for lab, classroom,
+and family machines. Runs as root.
+
+Each user gets the links 'lnk create' would make in their home, with their
+own manifest under ~/.local/state/lnk, so they can later run status, remove,
+and prune themselves. lnk links as each user, with their user and group IDs,
+so everything it creates is theirs and it can only write where they can.
+source-dir must be readable by every user, for example /srv/dotfiles with
+mode 755.
+
+All users are looked up before anything changes. When one user fails, the
+others are still deployed and the command exits 1.
 
 Arguments:
   source-dir    Source directory to link from (required)
 
 Flags:
+      --users LIST
+                Users to deploy to (comma-separated, required)
+      --packages LIST
+                Link only these packages, each as if it were source-dir
+      --special-files POLICY
+                skip (default) or error, as for create
+      --symlink-fallback POLICY
+                error (default) or copy, as for create
+      --on-unsupported POLICY
+                degrade (default) or abort, as for create
   (all global flags apply)
 
 Examples:
-  lnk create .
-  lnk create ~/git/dotfiles
-  lnk create -n .
+  sudo lnk deploy --users alice,bob -n /srv/dotfiles
+  sudo lnk deploy --users alice,bob /srv/dotfiles
+  sudo lnk deploy --users student1,student2 --packages shell /srv/dotfiles
 `)
-	case "remove":
-		fmt.Print(`Usage: lnk remove [flags] <source-dir>
+	case "doctor":
+		fmt.Print(`Usage: lnk doctor [flags] <source-dir>
 
-Remove managed symlinks from home directory.
+Check that this machine meets the requirements of the packages in use.
+
+Warns when a package does not support this platform or needs a command that
+is not installed, according to its lnk-package.json. Checks the selected
+packages and their dependencies, or every package when none are selected.
+Exits 1 when any problem is found.
 
 Arguments:
-  source-dir    Source directory whose managed links to remove (required)
+  source-dir    Source directory to check (required)
 
 Flags:
+      --packages LIST
+                Only check these packages
   (all global flags apply)
 
 Examples:
-  lnk remove .
-  lnk remove ~/git/dotfiles
-  lnk remove -n .
+  lnk doctor .
+  lnk doctor ~/git/dotfiles
+  lnk doctor --packages tmux ~/git/dotfiles
 `)
-	case "status":
-		fmt.Print(`Usage: lnk status [flags] <source-dir>
+	case "lint":
+		fmt.Print(`Usage: lnk lint [flags] <source-dir>
 
-Show status of managed symlinks in home directory.
+Check the source directory itself for mistakes that linking would not report.
+
+Warns about entries of .lnkpackages and .lnkrequires that name no package
+directory, absolute symlinks stored in the source directory, world-writable
+files, secret files (such as id_rsa or *.pem) that other users can read, and
+text files that mix CRLF and LF line endings. Files that will never be linked
+because of your ignore rules are listed for review. Exits 1 when any problem
+is found.
 
 Arguments:
   source-dir    Source directory to check (required)
 
 Flags:
+      --packages LIST
+                List files never linked in these packages only
   (all global flags apply)
 
 Examples:
-  lnk status .
-  lnk status ~/git/dotfiles
-  lnk status ~/git/dotfiles | grep ^broken
+  lnk lint .
+  lnk lint ~/git/dotfiles
+  lnk lint --ignore '*.bak' ~/git/dotfiles
 `)
-	case "prune":
-		fmt.Print(`Usage: lnk prune [flags] <source-dir>
+	case "web":
+		fmt.Print(`Usage: lnk web [flags] <source-dir>
 
-Remove broken managed symlinks from home directory.
+Serve a read-only HTML dashboard of the source directory on localhost.
+
+The page shows mappings, managed links (active and broken), unlinked sources,
+conflicts with a diff of the source and the file in the way, and recent git
+history of the source directory. It is rebuilt on every reload and nothing
+can be changed through it. Only loopback addresses are accepted; to view it
+from another machine, forward the port over SSH:
+
+  ssh -L 7474:localhost:7474 <host>
 
 Arguments:
-  source-dir    Source directory whose broken links to prune (required)
+  source-dir    Source directory to show (required)
 
 Flags:
+      --listen ADDR
+                Loopback address to listen on (default: 127.0.0.1:7474)
+      --packages LIST
+                Only show these packages
+      --map SRC:TGT
+                Also show SRC mapped into TGT (repeatable)
   (all global flags apply)
 
 Examples:
-  lnk prune .
-  lnk prune ~/git/dotfiles
-  lnk prune -n .
+  lnk web .
+  lnk web --listen 127.0.0.1:8080 ~/git/dotfiles
 `)
-	case "adopt":
-		fmt.Print(`Usage: lnk adopt [flags] <source-dir> <path...>
+	case "query":
+		fmt.Print(`Usage: lnk query --stdin-json [flags] <source-dir>
 
-Adopt files into the source directory.
+Answer questions from an editor plugin about source-dir, so it can show lnk's
+view of the dotfiles being edited. Each line of stdin is a JSON request; each
+gets one JSON response line on stdout, in order, until stdin ends. Nothing is
+changed.
+
+Requests name a method, and may carry an "id" that is echoed back:
+
+  {"id": 1, "method": "explain", "path": "~/.bashrc"}
+      Which link a source file or target path belongs to, and its state:
+      linked, unlinked, retarget, conflict, local-only, copy-managed,
+      ignored, or unmanaged
+  {"id": 2, "method": "plan", "mapping": "fonts:.local/share/fonts/"}
+      The links a mapping would make, as --map takes it, and their states
+  {"id": 3, "method": "validate", "file": "~/dotfiles/.lnkmaps", "content": "..."}
+      Problems in the unsaved content of a configuration file, by line
+
+A response has "result", or "error" with a code, message, and hint. The
+configuration is read once at startup; restart query after saving it.
 
 Arguments:
-  source-dir    Source directory to move files into (required)
-  path          One or more files or directories to adopt; must be within ~ (required)
+  source-dir    Source directory to answer for (required)
 
 Flags:
+      --stdin-json
+                Read requests from stdin as JSON lines (required)
+      --packages LIST
+                Only plan these packages
+      --map SRC:TGT
+                Also plan SRC mapped into TGT (repeatable)
   (all global flags apply)
 
 Examples:
-  lnk adopt . ~/.bashrc
-  lnk adopt . ~/.bashrc ~/.vimrc
-  lnk adopt ~/git/dotfiles ~/.config/nvim
-  lnk adopt -n . ~/.bashrc
+  echo '{"method": "explain", "path": "~/.bashrc"}' | lnk query --stdin-json ~/dotfiles
 `)
-	case "orphan":
-		fmt.Print(`Usage: lnk orphan [flags] <source-dir> <path...>
+	case "prompt-status":
+		fmt.Print(`Usage: lnk prompt-status [flags] <source-dir>
 
-Remove files from management.
+Print a single compact token for embedding in a shell prompt:
+
+  lnk:✓    every link lnk recorded for source-dir is intact
+  lnk:N!   N recorded links are missing, replaced, or broken
+  lnk:?    no links are recorded yet (run 'lnk create' once)
+
+Only the links recorded in lnk's manifest are checked, so the home directory
+is never walked and the token is fast enough for every prompt. New source
+files that are not linked yet are not counted; use 'lnk status' for those.
+The token is colored unless --no-color or NO_COLOR is set.
 
 Arguments:
-  source-dir    Source directory that manages the files (required)
-  path          One or more managed symlinks or directories to orphan; must be within ~ (required)
+  source-dir    Source directory to check (required)
 
 Flags:
+      --shell SHELL
+                Mark color escapes as non-printing for bash or zsh prompts;
+                plain (default) writes raw escapes
   (all global flags apply)
 
 Examples:
-  lnk orphan . ~/.bashrc
-  lnk orphan . ~/.bashrc ~/.vimrc
-  lnk orphan ~/git/dotfiles ~/.config/nvim
-  lnk orphan -n . ~/.bashrc
+  PS1='$(lnk prompt-status --shell bash ~/git/dotfiles) \$ '
+  setopt prompt_subst; PROMPT='$(lnk prompt-status --shell zsh ~/git/dotfiles) %# '
+  lnk prompt-status --no-color ~/git/dotfiles
+`)
+	case "shellenv":
+		fmt.Print(`Usage: lnk shellenv [flags] <source-dir>
+
+Print shell code that sets up lnk in an interactive shell. Evaluate it from a
+startup file so setup is one line. The code:
+
+  - exports LNK_PACKAGES, LNK_IGNORE, and LNK_NO_COLOR for the --packages,
+    --ignore, and --no-color flags given to shellenv
+  - adds ~/.local/bin (where packages install commands) to PATH
+  - defines tab completion of lnk's commands, actions, and flags, and of
+    package names, prune --source subdirectories, and managed paths
+  - adds 'lnk prompt-status' for source-dir to the front of the prompt
+
+Evaluating it again, e.g. after re-sourcing the startup file, changes nothing.
+
+Arguments:
+  source-dir    Source directory the prompt checks (required)
+
+Flags:
+      --shell SHELL
+                Shell to generate code for: bash, zsh, or fish
+                (default: the name of $SHELL)
+  (all global flags apply)
+
+Examples:
+  eval "$(lnk shellenv ~/git/dotfiles)"               # ~/.bashrc or ~/.zshrc
+  lnk shellenv --shell fish ~/git/dotfiles | source   # config.fish
+  eval "$(lnk shellenv --packages shell,nvim ~/git/dotfiles)"
+`)
+	case "eval":
+		fmt.Print(`Usage: lnk eval [flags] <expression>
+
+Evaluate a condition expression, as used in the "if" field of a "when"
+condition in lnk-package.json, and show the values it used.
+
+Expressions compare os, arch, hostname, wsl, ssh, mdm, env.NAME (empty when
+unset), and command_exists("cmd") using ==, !=, &&, ||, !, parentheses, and
+double-quoted strings. A string on its own is true when it is non-empty. ssh is
+true in an SSH session, and mdm when device management markers are found.
+
+Exits 0 when the expression is true and 1 when it is false or invalid.
+
+Arguments:
+  expression    Expression to evaluate (required)
+
+Flags:
+  (all global flags apply)
+
+Examples:
+  lnk eval 'os == "linux"'
+  lnk eval 'env.WSL == "1" && os == "linux"'
+  lnk eval 'wsl && command_exists("cmd.exe")'
+  lnk eval '!command_exists("tmux") || hostname == "work-laptop"'
+`)
+	case "detect":
+		fmt.Print(`Usage: lnk detect [flags] <source-dir>
+
+Show the facts profile rules can test on this machine (os, arch, hostname,
+wsl, ssh, mdm), whether each rule in .lnkprofiles matches, and the profile
+selected.
+
+The first rule whose hostname glob and "when" condition match this machine
+selects its packages for every command, ahead of .lnkpackages; LNK_PACKAGES
+and --packages still override it. ssh is true in an SSH session, and mdm when
+device management markers are found (a heuristic).
+
+Arguments:
+  source-dir    Source directory with .lnkprofiles (required)
+
+Flags:
+  (all global flags apply)
+
+Examples:
+  lnk detect .
+  lnk detect ~/git/dotfiles
 `)
 	}
 }
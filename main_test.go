@@ -77,6 +77,12 @@ func TestExtractCommand(t *testing.T) {
 			wantCommand: "",
 			wantRemain:  []string{"--ignore"},
 		},
+		{
+			name:        "prefer value before command",
+			args:        []string{"--prefer", "repo", "adopt", ".", "~/.bashrc"},
+			wantCommand: "adopt",
+			wantRemain:  []string{"--prefer", "repo", ".", "~/.bashrc"},
+		},
 		{
 			name:        "ignore value starts with dash is not skipped",
 			args:        []string{"--ignore", "--dry-run", "create", "."},
@@ -125,7 +125,7 @@ func TestUnknownCommand(t *testing.T) {
 	}{
 		{
 			name:     "typo suggests closest match",
-			args:     []string{"statsu"},
+			args:     []string{"statuss"},
 			wantExit: 2,
 			contains: []string{"unknown command", "status"},
 		},
@@ -226,6 +226,28 @@ func TestStatus(t *testing.T) {
 			wantExit: 0,
 			contains: []string{"Source directory:"},
 		},
+		{
+			name:     "status lists unlinked sources",
+			args:     []string{"status", filepath.Join(sourceDir, "home")},
+			wantExit: 0,
+			contains: []string{"unlinked", ".bashrc"},
+		},
+		{
+			name:     "status fail-on unlinked",
+			args:     []string{"status", "--fail-on", "unlinked", filepath.Join(sourceDir, "home")},
+			wantExit: 1,
+			contains: []string{"unlinked"},
+		},
+		{
+			name: "status fail-on unlinked when all linked",
+			args: []string{"status", "--fail-on", "unlinked", filepath.Join(sourceDir, "home")},
+			setup: func(t *testing.T) {
+				result := runCommand(t, "create", filepath.Join(sourceDir, "home"))
+				assertExitCode(t, result, 0)
+			},
+			wantExit: 0,
+			contains: []string{"active"},
+		},
 	}
 
 	for _, tt := range tests {
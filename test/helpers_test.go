@@ -7,11 +7,13 @@
 //   - assertNotContains(): Check output does not contain text
 //   - assertSymlink(): Verify symlink exists and points correctly
 //   - assertNoSymlink(): Verify path is not a symlink
+//   - snapshotTree(), assertUnchanged(): Verify a command changed nothing
 //   - setupTestEnv(): Create test environment and return cleanup function
 package test
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -279,3 +281,45 @@ func assertNoSymlink(t *testing.T, path string) {
 		t.Errorf("Expected %s to not be a symlink, but it is", path)
 	}
 }
+
+// snapshotTree describes every entry under root (mode, size, modification
+// time, and symlink target), so two snapshots differ if anything changed
+func snapshotTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+
+	snapshot := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		dest, _ := os.Readlink(path)
+		snapshot[path] = fmt.Sprintf("%v %d %d %s", info.Mode(), info.Size(), info.ModTime().UnixNano(), dest)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to snapshot %s: %v", root, err)
+	}
+	return snapshot
+}
+
+// assertUnchanged verifies that two snapshotTree results are identical
+func assertUnchanged(t *testing.T, before, after map[string]string) {
+	t.Helper()
+
+	for path, entry := range after {
+		if was, ok := before[path]; !ok {
+			t.Errorf("%s was created", path)
+		} else if was != entry {
+			t.Errorf("%s changed: %s -> %s", path, was, entry)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			t.Errorf("%s was removed", path)
+		}
+	}
+}
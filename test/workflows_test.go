@@ -274,3 +274,29 @@ func TestIgnorePatterns(t *testing.T) {
 		assertNoSymlink(t, filepath.Join(targetDir, "readonly"))
 	})
 }
+
+// TestSecondRunIsNoop runs create, status, and remove twice each; the second
+// run of each must leave the target directory exactly as the first left it
+func TestSecondRunIsNoop(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	projectRoot := getProjectRoot(t)
+	sourceDir := filepath.Join(projectRoot, "test", "testdata", "dotfiles", "home")
+	targetDir := filepath.Join(projectRoot, "test", "testdata", "target")
+
+	for _, command := range []string{"create", "status", "remove"} {
+		t.Run(command, func(t *testing.T) {
+			result := runCommand(t, command, sourceDir)
+			assertExitCode(t, result, 0)
+			before := snapshotTree(t, targetDir)
+
+			result = runCommand(t, command, sourceDir)
+			assertExitCode(t, result, 0)
+			assertUnchanged(t, before, snapshotTree(t, targetDir))
+			if command == "create" {
+				assertContains(t, result.Stdout, "All symlinks already exist")
+			}
+		})
+	}
+}